@@ -0,0 +1,119 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Alert severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is an operational notification delivered to the configured webhooks
+type Alert struct {
+	Type     string         `json:"type"`
+	Severity string         `json:"severity"`
+	Message  string         `json:"message"`
+	Fields   map[string]any `json:"fields,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+// Notifier delivers alerts to a generic JSON webhook and/or a Slack incoming webhook
+type Notifier struct {
+	webhookURL string
+	slackURL   string
+	client     *http.Client
+	log        *logrus.Logger
+}
+
+// NewNotifier creates a new Notifier. Either URL may be empty to disable that destination.
+func NewNotifier(webhookURL, slackURL string, logger *logrus.Logger) *Notifier {
+	return &Notifier{
+		webhookURL: webhookURL,
+		slackURL:   slackURL,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		log:        logger,
+	}
+}
+
+// Enabled reports whether any alert destination is configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && (n.webhookURL != "" || n.slackURL != "")
+}
+
+// Notify logs the alert and delivers it in the background so callers on the event path never block
+func (n *Notifier) Notify(a Alert) {
+	if n == nil {
+		return
+	}
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+
+	entry := n.log.WithFields(logrus.Fields{
+		"alertType": a.Type,
+		"severity":  a.Severity,
+		"fields":    a.Fields,
+	})
+	if a.Severity == SeverityInfo {
+		entry.Info(a.Message)
+	} else {
+		entry.Warn(a.Message)
+	}
+
+	if !n.Enabled() {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if n.webhookURL != "" {
+			if err := n.post(ctx, n.webhookURL, a); err != nil {
+				n.log.WithError(err).WithField("alertType", a.Type).Error("Failed to deliver alert webhook")
+			}
+		}
+		if n.slackURL != "" {
+			payload := map[string]string{
+				"text": fmt.Sprintf("[%s] %s", a.Severity, a.Message),
+			}
+			if err := n.post(ctx, n.slackURL, payload); err != nil {
+				n.log.WithError(err).WithField("alertType", a.Type).Error("Failed to deliver Slack alert")
+			}
+		}
+	}()
+}
+
+// post sends body as JSON to url
+func (n *Notifier) post(ctx context.Context, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getESLStatusHandler handles GET /admin/esl/status requests. The response is a list with one entry per node.
+func (s *Server) getESLStatusHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	statuses := s.esl.Statuses()
+	for i := range statuses {
+		statuses[i].In(loc)
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+// eslPasswordRequest is the body of POST /admin/esl/password
+type eslPasswordRequest struct {
+	Node      string `json:"node"` // Server or backup address; empty changes every server
+	Password  string `json:"password"`
+	Reconnect bool   `json:"reconnect"` // Reconnect now instead of on the next connection loss
+}
+
+// setESLPasswordHandler handles POST /admin/esl/password requests, rotating the ESL password without a
+// restart. The password itself is never logged or audited.
+func (s *Server) setESLPasswordHandler(c *gin.Context) {
+	var req eslPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is required"})
+		return
+	}
+
+	if err := s.esl.SetPassword(req.Node, req.Password, req.Reconnect); err != nil {
+		if errors.Is(err, esl.ErrUnknownNode) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown ESL node", "node": req.Node})
+			return
+		}
+		s.log.WithError(err).Error("Error changing ESL password")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change ESL password"})
+		return
+	}
+	target := req.Node
+	if target == "" {
+		target = "all"
+	}
+	s.recordAudit(c.Request.Context(), c, "rotate_esl_password", target, map[string]any{"reconnect": req.Reconnect})
+
+	c.JSON(http.StatusOK, gin.H{"node": target, "reconnect": req.Reconnect})
+}
+
+// getSwitchEventsHandler handles GET /admin/esl/switch-events requests (connections and detected restarts)
+func (s *Server) getSwitchEventsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	events, err := s.store.GetSwitchEvents(ctx, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving switch events from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve switch events"})
+		return
+	}
+
+	if events == nil {
+		events = []store.SwitchEvent{}
+	}
+	for i := range events {
+		events[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// maxUptimeDays caps the window of GET /admin/uptime
+const maxUptimeDays = 365
+
+// getUptimeHandler handles GET /admin/uptime requests: availability per component over the last days (default 30)
+func (s *Server) getUptimeHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > maxUptimeDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be an integer between 1 and %d", maxUptimeDays)})
+		return
+	}
+	to := time.Now()
+	from := to.AddDate(0, 0, -days)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetUptimeStats(ctx, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving uptime stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve uptime stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.UptimeStats{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from.In(loc),
+		"to":         to.In(loc),
+		"components": stats,
+	})
+}
+
+// getDataQualityHandler handles GET /admin/data-quality requests. It returns the last scheduled report, or
+// runs the checks now when refresh=true is given or none has run yet.
+func (s *Server) getDataQualityHandler(c *gin.Context) {
+	if s.opts.Quality == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data quality checks are not available"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	report := s.opts.Quality.Last()
+	if report == nil || c.Query("refresh") == "true" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		var err error
+		report, err = s.opts.Quality.Check(ctx)
+		if err != nil {
+			s.log.WithError(err).Error("Error running data quality checks")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run data quality checks"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checked_at": report.CheckedAt.In(loc),
+		"since":      report.Since.In(loc),
+		"issues":     report.Issues,
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxCampaignNameLength = 64
+
+// setCallerIDCampaignRequest is the body of PUT /caller-id-campaigns/:caller_id
+type setCallerIDCampaignRequest struct {
+	Campaign string `json:"campaign" binding:"required"`
+}
+
+// reloadCallerIDCampaigns refreshes the ingest cache after the mappings changed, so new calls are
+// attributed without waiting for the periodic reload
+func (s *Server) reloadCallerIDCampaigns(ctx context.Context) {
+	if s.opts.CallerIDCampaigns == nil {
+		return
+	}
+	if err := s.opts.CallerIDCampaigns.Reload(ctx); err != nil {
+		s.log.WithError(err).Error("Error reloading caller ID campaigns")
+	}
+}
+
+// getCallerIDCampaignsHandler handles GET /caller-id-campaigns requests
+func (s *Server) getCallerIDCampaignsHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	mappings, err := s.store.GetCallerIDCampaigns(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving caller ID campaigns from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve caller ID campaigns"})
+		return
+	}
+
+	if mappings == nil {
+		mappings = []store.CallerIDCampaign{}
+	}
+	for i := range mappings {
+		mappings[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, mappings)
+}
+
+// setCallerIDCampaignHandler handles PUT /caller-id-campaigns/:caller_id requests, mapping the caller ID
+// to a campaign. Calls recorded before the change keep their campaign.
+func (s *Server) setCallerIDCampaignHandler(c *gin.Context) {
+	callerID := store.NormalizeCallerID(c.Param("caller_id"))
+	if callerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "caller_id must contain digits"})
+		return
+	}
+	var req setCallerIDCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Campaign = strings.TrimSpace(req.Campaign)
+	if req.Campaign == "" || len(req.Campaign) > maxCampaignNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaign must be 1 to 64 characters"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	mapping := &store.CallerIDCampaign{CallerID: callerID, Campaign: req.Campaign}
+	if err := s.store.SetCallerIDCampaign(ctx, mapping); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save caller ID campaign"})
+		return
+	}
+	s.recordAudit(ctx, c, "set_caller_id_campaign", callerID, map[string]any{"campaign": req.Campaign})
+	s.reloadCallerIDCampaigns(ctx)
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// deleteCallerIDCampaignHandler handles DELETE /caller-id-campaigns/:caller_id requests
+func (s *Server) deleteCallerIDCampaignHandler(c *gin.Context) {
+	callerID := store.NormalizeCallerID(c.Param("caller_id"))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.store.DeleteCallerIDCampaign(ctx, callerID)
+	if errors.Is(err, store.ErrCallerIDCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Caller ID is not mapped to a campaign"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete caller ID campaign"})
+		return
+	}
+	s.recordAudit(ctx, c, "delete_caller_id_campaign", callerID, nil)
+	s.reloadCallerIDCampaigns(ctx)
+
+	c.Status(http.StatusNoContent)
+}
+
+// getCampaignAttributionStatsHandler handles GET /stats/caller-id-campaigns requests
+func (s *Server) getCampaignAttributionStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetCampaignAttributionStats(ctx, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving campaign attribution stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign attribution stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.CampaignAttributionStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// auditActor identifies the caller for the audit log by role and a short fingerprint of their key
+func (s *Server) auditActor(c *gin.Context) string {
+	key := c.GetHeader("X-API-Key")
+	role := "anonymous"
+	switch {
+	case s.isAdmin(c):
+		role = "admin"
+	case s.isOperator(c):
+		role = "operator"
+	default:
+		return role
+	}
+	return role + ":" + keyFingerprint(key)
+}
+
+// recordAudit writes an audit entry for an action taken by the caller on target. Failures are logged,
+// not returned, so the action's own response is unaffected.
+func (s *Server) recordAudit(ctx context.Context, c *gin.Context, action, target string, details map[string]any) {
+	entry := &store.AuditEntry{
+		Action:     action,
+		Actor:      s.auditActor(c),
+		ClientIP:   c.ClientIP(),
+		Target:     target,
+		Details:    details,
+		OccurredAt: time.Now().UTC(),
+	}
+	if err := s.store.AddAuditEntry(context.WithoutCancel(ctx), entry); err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{"action": action, "target": target}).Error("Error recording audit entry")
+	}
+}
+
+// activeCall loads the call with uuid, responding 404 if it is unknown and 409 if it has already ended
+func (s *Server) activeCall(ctx context.Context, c *gin.Context, uuid string) (*store.Call, bool) {
+	call, err := s.store.GetCallByUUID(ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving active call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve call"})
+		return nil, false
+	}
+	if call.EndTime != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Call has already ended"})
+		return nil, false
+	}
+	return call, true
+}
+
+// getAuditLogHandler handles GET /admin/audit requests
+func (s *Server) getAuditLogHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	entries, err := s.store.GetAuditLog(ctx, c.Query("action"), c.Query("target"), limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving audit log from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+
+	if entries == nil {
+		entries = []store.AuditEntry{}
+	}
+	for i := range entries {
+		entries[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
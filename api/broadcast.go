@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/esl"
+
+	"github.com/gin-gonic/gin"
+)
+
+// broadcastRequest is the body of POST /calls/:uuid/broadcast
+type broadcastRequest struct {
+	File   string `json:"file"`   // Sound file path or URL
+	Text   string `json:"text"`   // Text-to-speech announcement, used instead of file
+	Engine string `json:"engine"` // TTS engine; defaults to flite
+	Voice  string `json:"voice"`  // TTS voice; defaults to kal
+	Leg    string `json:"leg"`    // aleg (default), bleg or both
+}
+
+// broadcastHandler handles POST /calls/:uuid/broadcast requests, playing an announcement into an active
+// call and recording it in the audit log
+func (s *Server) broadcastHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	var req broadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	broadcast := esl.BroadcastRequest{
+		CallUUID:  uuid,
+		File:      req.File,
+		Text:      req.Text,
+		TTSEngine: req.Engine,
+		TTSVoice:  req.Voice,
+		Leg:       req.Leg,
+	}
+	if broadcast.Leg == "" {
+		broadcast.Leg = esl.LegA
+	}
+	if err := broadcast.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, ok := s.activeCall(ctx, c, uuid); !ok {
+		return
+	}
+
+	err := s.esl.Broadcast(ctx, broadcast)
+	if errors.Is(err, esl.ErrESLNotConnected) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+		return
+	}
+
+	details := map[string]any{"leg": broadcast.Leg, "played": err == nil}
+	if req.File != "" {
+		details["file"] = req.File
+	} else {
+		details["text"] = req.Text
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	s.recordAudit(ctx, c, "broadcast", uuid, details)
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"status": "queued", "leg": broadcast.Leg})
+	case errors.Is(err, esl.ErrBroadcastFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()}) // e.g. the channel hung up meanwhile
+	default:
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error broadcasting into call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to broadcast"})
+	}
+}
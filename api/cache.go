@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCacheEntries bounds the cache; expired entries are swept once it is reached
+const maxCacheEntries = 1000
+
+var cacheRequestsCounter = metrics.NewCounterVec("api_stats_cache_requests_total", "Stats cache lookups by result.", "result")
+
+// cacheEntry is a cached response body
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// responseCache is a small TTL cache for expensive aggregate responses. A zero TTL disables it.
+type responseCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// newResponseCache creates a cache holding entries for ttl
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// get returns the unexpired value stored under key
+func (rc *responseCache) get(key string) (any, bool) {
+	if rc.ttl <= 0 {
+		return nil, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		cacheRequestsCounter.With("miss").Inc()
+		return nil, false
+	}
+	cacheRequestsCounter.With("hit").Inc()
+	return entry.value, true
+}
+
+// set stores value under key for the cache TTL
+func (rc *responseCache) set(key string, value any) {
+	if rc.ttl <= 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	now := time.Now()
+	if len(rc.entries) >= maxCacheEntries {
+		for k, entry := range rc.entries {
+			if now.After(entry.expires) {
+				delete(rc.entries, k)
+			}
+		}
+	}
+	if len(rc.entries) >= maxCacheEntries {
+		return // Still full of live entries; serve uncached rather than grow without bound
+	}
+	rc.entries[key] = cacheEntry{value: value, expires: now.Add(rc.ttl)}
+}
+
+// invalidate drops every entry and returns how many were removed
+func (rc *responseCache) invalidate() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	n := len(rc.entries)
+	rc.entries = make(map[string]cacheEntry)
+	return n
+}
+
+// cacheKey identifies a response by path, query parameters, requested time zone, tenant and whether
+// encrypted fields are revealed
+func cacheKey(c *gin.Context) string {
+	key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode() + "#" + c.GetHeader("Accept-Timezone") + "@" + c.GetString(tenantContextKey)
+	if c.GetBool(sealedContextKey) {
+		key += "!sealed"
+	}
+	return key
+}
+
+// serveCached writes a cached response for the request if one exists
+func (s *Server) serveCached(c *gin.Context) bool {
+	value, ok := s.cache.get(cacheKey(c))
+	if ok {
+		c.JSON(http.StatusOK, value)
+	}
+	return ok
+}
+
+// invalidateCacheHandler handles POST /admin/cache/invalidate requests
+func (s *Server) invalidateCacheHandler(c *gin.Context) {
+	removed := s.cache.invalidate()
+	s.log.WithField("removed", removed).Info("Stats cache invalidated")
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
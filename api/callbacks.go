@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getCallbacksHandler handles GET /callbacks requests
+func (s *Server) getCallbacksHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	callbacks, err := s.store.GetCallbacks(ctx, c.Query("status"), from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving callbacks from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve callbacks"})
+		return
+	}
+
+	if callbacks == nil {
+		callbacks = []store.Callback{}
+	}
+	for i := range callbacks {
+		callbacks[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, callbacks)
+}
+
+// getCallCallbackHandler handles GET /calls/:uuid/callback requests, answering whether the caller of a
+// call was called back
+func (s *Server) getCallCallbackHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	callback, err := s.store.GetCallbackForCall(ctx, uuid)
+	if errors.Is(err, store.ErrCallbackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No callback was requested on this call"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving callback from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve callback"})
+		return
+	}
+	callback.In(loc)
+
+	c.JSON(http.StatusOK, callback)
+}
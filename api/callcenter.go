@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getAgentsHandler handles GET /agents requests: the current status and state of every mod_callcenter agent
+func (s *Server) getAgentsHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	agents, err := s.store.GetAgents(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving agents from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve agents"})
+		return
+	}
+
+	if agents == nil {
+		agents = []store.Agent{}
+	}
+	for i := range agents {
+		agents[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, agents)
+}
+
+// getAgentTransitionsHandler handles GET /agents/:name/transitions requests
+func (s *Server) getAgentTransitionsHandler(c *gin.Context) {
+	agent := c.Param("name")
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	transitions, err := s.store.GetAgentTransitions(ctx, agent, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).WithField("agent", agent).Error("Error retrieving agent transitions from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve agent transitions"})
+		return
+	}
+
+	if transitions == nil {
+		transitions = []store.AgentTransition{}
+	}
+	for i := range transitions {
+		transitions[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// getQueuesHandler handles GET /queues requests: live waiting members per queue
+func (s *Server) getQueuesHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	queues, err := s.store.GetQueues(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving queues from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve queues"})
+		return
+	}
+
+	if queues == nil {
+		queues = []store.Queue{}
+	}
+	for i := range queues {
+		queues[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, queues)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/campaign"
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// originateRequest is the body of POST /originate
+type originateRequest struct {
+	Endpoint       string            `json:"endpoint" binding:"required"`
+	Destination    string            `json:"destination"`
+	CallerIDNumber string            `json:"caller_id_number"`
+	CallerIDName   string            `json:"caller_id_name"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	Variables      map[string]string `json:"variables"`
+	Retry          *retryRequest     `json:"retry"`
+	Confirm        *confirmRequest   `json:"confirm"`
+}
+
+// retryRequest is the retry policy of an originate or campaign request
+type retryRequest struct {
+	MaxAttempts     int      `json:"max_attempts"`
+	IntervalSeconds int      `json:"interval_seconds"`
+	OnCauses        []string `json:"on_causes"` // Hangup causes to retry; empty retries any failure
+}
+
+// confirmRequest requires the callee to press a key before the call counts as answered
+type confirmRequest struct {
+	Key            string `json:"key" binding:"required"`
+	Prompt         string `json:"prompt" binding:"required"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// originateResponse is the result of POST /originate. Attempts lists every call placed under a retry policy.
+type originateResponse struct {
+	esl.OriginateResult
+	Attempts []esl.OriginateResult `json:"attempts,omitempty"`
+}
+
+// campaignRequest is the body of POST /campaigns
+type campaignRequest struct {
+	Name           string   `json:"name" binding:"required"`
+	Numbers        []string `json:"numbers" binding:"required"`
+	Endpoint       string   `json:"endpoint" binding:"required"` // Must contain {number}
+	Destination    string   `json:"destination"`
+	CallerIDNumber string   `json:"caller_id_number"`
+	CallerIDName   string   `json:"caller_id_name"`
+	Concurrency    int      `json:"concurrency"` // 1 dials sequentially
+	IntervalMS     int      `json:"interval_ms"` // Minimum gap between call starts
+	TimeoutSeconds int      `json:"timeout_seconds"`
+
+	Retry   *retryRequest   `json:"retry"`
+	Confirm *confirmRequest `json:"confirm"`
+}
+
+// campaignDetail is a campaign with its attempts
+type campaignDetail struct {
+	*store.Campaign
+	Attempts []store.CampaignAttempt `json:"attempts"`
+}
+
+// requireAdmin rejects requests without an admin API key when admin keys are configured
+func (s *Server) requireAdmin(c *gin.Context) {
+	if len(s.opts.AdminKeys) > 0 && !s.isAdmin(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid X-API-Key is required"})
+		return
+	}
+	c.Next()
+}
+
+// requireAdminKey is requireAdmin for endpoints too dangerous to leave open, such as those that dial out:
+// unless admin keys are configured they are refused with 403
+func (s *Server) requireAdminKey(c *gin.Context) {
+	if len(s.opts.AdminKeys) == 0 {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint is disabled until API_ADMIN_KEYS is configured"})
+		return
+	}
+	s.requireAdmin(c)
+}
+
+// originateHandler handles POST /originate requests, waiting for the call to be answered or fail
+func (s *Server) originateHandler(c *gin.Context) {
+	var req originateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TimeoutSeconds > campaign.MaxTimeout {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout_seconds is too large"})
+		return
+	}
+	var policy esl.RetryPolicy
+	if req.Retry != nil {
+		policy = esl.RetryPolicy{
+			MaxAttempts: req.Retry.MaxAttempts,
+			Interval:    time.Duration(req.Retry.IntervalSeconds) * time.Second,
+			OnCauses:    req.Retry.OnCauses,
+		}
+		if err := policy.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	originate := esl.OriginateRequest{
+		Endpoint:       req.Endpoint,
+		Destination:    req.Destination,
+		CallerIDNumber: req.CallerIDNumber,
+		CallerIDName:   req.CallerIDName,
+		Timeout:        time.Duration(req.TimeoutSeconds) * time.Second,
+		Variables:      req.Variables,
+	}
+	if req.Confirm != nil {
+		originate.Confirm = &esl.Confirmation{
+			Key:     req.Confirm.Key,
+			Prompt:  req.Confirm.Prompt,
+			Timeout: time.Duration(req.Confirm.TimeoutSeconds) * time.Second,
+		}
+	}
+	if err := originate.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Every attempt may take up to the bgapi job timeout, plus the waits between them
+	attempts := time.Duration(max(policy.MaxAttempts, 1))
+	timeout := attempts*time.Duration(campaign.MaxTimeout+10)*time.Second + (attempts-1)*policy.Interval
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	results, err := esl.OriginateWithRetry(ctx, s.esl, originate, policy)
+	resp := originateResponse{OriginateResult: results[len(results)-1]}
+	if len(results) > 1 || policy.MaxAttempts > 1 {
+		resp.Attempts = results
+	}
+	switch {
+	case err == nil, errors.Is(err, esl.ErrOriginateFailed):
+		c.JSON(http.StatusOK, resp) // A failed call carries its hangup cause
+	case errors.Is(err, esl.ErrESLNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+	default:
+		s.log.WithError(err).Error("Error originating call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to originate call"})
+	}
+}
+
+// createCampaignHandler handles POST /campaigns requests. The campaign runs in the background.
+func (s *Server) createCampaignHandler(c *gin.Context) {
+	if s.opts.Campaigns == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Campaigns are not available"})
+		return
+	}
+	var req campaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cmp := &store.Campaign{
+		Name:             req.Name,
+		EndpointTemplate: req.Endpoint,
+		Destination:      req.Destination,
+		CallerIDNumber:   req.CallerIDNumber,
+		CallerIDName:     req.CallerIDName,
+		Concurrency:      req.Concurrency,
+		IntervalMS:       req.IntervalMS,
+		TimeoutSeconds:   req.TimeoutSeconds,
+	}
+	if req.Retry != nil {
+		cmp.MaxAttempts = req.Retry.MaxAttempts
+		cmp.RetryIntervalSeconds = req.Retry.IntervalSeconds
+		cmp.RetryCauses = req.Retry.OnCauses
+	}
+	if req.Confirm != nil {
+		cmp.ConfirmKey = req.Confirm.Key
+		cmp.ConfirmPrompt = req.Confirm.Prompt
+		cmp.ConfirmTimeoutSeconds = req.Confirm.TimeoutSeconds
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := s.opts.Campaigns.Start(ctx, cmp, req.Numbers); err != nil {
+		if errors.Is(err, campaign.ErrInvalidCampaign) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		s.log.WithError(err).Error("Error starting campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start campaign"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cmp)
+}
+
+// getCampaignsHandler handles GET /campaigns requests
+func (s *Server) getCampaignsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	campaigns, err := s.store.GetCampaigns(ctx, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving campaigns from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaigns"})
+		return
+	}
+
+	if campaigns == nil {
+		campaigns = []store.Campaign{}
+	}
+	for i := range campaigns {
+		campaigns[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// getCampaignHandler handles GET /campaigns/:id requests, returning the campaign with its attempts
+func (s *Server) getCampaignHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign id must be an integer"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	cmp, err := s.store.GetCampaign(ctx, id)
+	if errors.Is(err, store.ErrCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error retrieving campaign from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign"})
+		return
+	}
+	attempts, err := s.store.GetCampaignAttempts(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error retrieving campaign attempts from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve campaign"})
+		return
+	}
+
+	if attempts == nil {
+		attempts = []store.CampaignAttempt{}
+	}
+	cmp.In(loc)
+	for i := range attempts {
+		attempts[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, campaignDetail{Campaign: cmp, Attempts: attempts})
+}
+
+// cancelCampaignHandler handles POST /campaigns/:id/cancel requests. Calls already in progress are not hung up.
+func (s *Server) cancelCampaignHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign id must be an integer"})
+		return
+	}
+	if s.opts.Campaigns == nil || !s.opts.Campaigns.Cancel(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaign is not running"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "cancelling"})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getConferencesHandler handles GET /conferences requests. live=true (the default) lists running
+// conferences; live=false lists conferences started in the from/to range.
+func (s *Server) getConferencesHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	live, err := strconv.ParseBool(c.DefaultQuery("live", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "live must be true or false"})
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	conferences, err := s.store.GetConferences(ctx, live, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving conferences from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conferences"})
+		return
+	}
+
+	if conferences == nil {
+		conferences = []store.Conference{}
+	}
+	for i := range conferences {
+		conferences[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, conferences)
+}
+
+// getConferenceHandler handles GET /conferences/:id requests, returning the conference with its members
+func (s *Server) getConferenceHandler(c *gin.Context) {
+	id := c.Param("id")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	conf, err := s.store.GetConference(ctx, id)
+	if errors.Is(err, store.ErrConferenceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conference not found"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("conferenceUUID", id).Error("Error retrieving conference from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conference"})
+		return
+	}
+
+	conf.In(loc)
+
+	c.JSON(http.StatusOK, conf)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// getDeadLettersHandler handles GET /admin/dead-letters requests, optionally filtered by stage, event and
+// pending (only events not yet reprocessed successfully)
+func (s *Server) getDeadLettersHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	pending, err := strconv.ParseBool(c.DefaultQuery("pending", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pending must be true or false"})
+		return
+	}
+	filter := store.DeadLetterFilter{
+		Stage:     c.Query("stage"),
+		EventName: c.Query("event"),
+		Pending:   pending,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	letters, err := s.store.GetDeadLetters(ctx, filter, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving dead-letter events from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead-letter events"})
+		return
+	}
+
+	if letters == nil {
+		letters = []store.DeadLetter{}
+	}
+	for i := range letters {
+		letters[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, letters)
+}
+
+// deadLetter loads the dead letter named by the :id parameter, responding 400 or 404 when it cannot
+func (s *Server) deadLetter(ctx context.Context, c *gin.Context) (*store.DeadLetter, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dead-letter id must be an integer"})
+		return nil, false
+	}
+	d, err := s.store.GetDeadLetter(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead-letter event not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("id", id).Error("Error retrieving dead-letter event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead-letter event"})
+		return nil, false
+	}
+	return d, true
+}
+
+// getDeadLetterHandler handles GET /admin/dead-letters/:id requests, returning the event's raw headers and body
+func (s *Server) getDeadLetterHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	d, ok := s.deadLetter(ctx, c)
+	if !ok {
+		return
+	}
+	d.In(loc)
+	c.JSON(http.StatusOK, d)
+}
+
+// reprocessDeadLetterHandler handles POST /admin/dead-letters/:id/reprocess requests, running the event
+// through the handlers again. An event that fails again keeps its place with the new error.
+func (s *Server) reprocessDeadLetterHandler(c *gin.Context) {
+	if s.esl == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL client is not available"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	d, ok := s.deadLetter(ctx, c)
+	if !ok {
+		return
+	}
+	if d.ReprocessedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Dead-letter event has already been reprocessed"})
+		return
+	}
+
+	reprocessErr := s.esl.Reprocess(ctx, d)
+	if errors.Is(reprocessErr, esl.ErrDatabaseUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database unavailable; try again later"})
+		return
+	}
+	if err := s.store.MarkDeadLetterReprocessed(ctx, d.ID, d.AppliedWrites, reprocessErr); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dead-letter event"})
+		return
+	}
+	target := strconv.FormatInt(d.ID, 10)
+	if reprocessErr != nil {
+		s.recordAudit(ctx, c, "reprocess_dead_letter", target, map[string]any{"error": reprocessErr.Error()})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Event failed again", "cause": reprocessErr.Error()})
+		return
+	}
+	s.recordAudit(ctx, c, "reprocess_dead_letter", target, nil)
+	c.JSON(http.StatusOK, gin.H{"status": "reprocessed"})
+}
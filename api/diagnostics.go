@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getDiagnosticsHandler handles GET /admin/diagnostics requests, returning a diagnostic snapshot as plain
+// text. Goroutine stacks are included unless goroutines=false.
+func (s *Server) getDiagnosticsHandler(c *gin.Context) {
+	if s.opts.Diagnostics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Diagnostics are not available"})
+		return
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := s.opts.Diagnostics.Write(c.Writer, c.Query("goroutines") != "false"); err != nil {
+		s.log.WithError(err).Error("Error writing diagnostic snapshot")
+	}
+}
+
+// dumpDiagnosticsHandler handles POST /admin/diagnostics/dump requests, writing a snapshot to the dump
+// directory (or the log) as SIGQUIT does
+func (s *Server) dumpDiagnosticsHandler(c *gin.Context) {
+	if s.opts.Diagnostics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Diagnostics are not available"})
+		return
+	}
+	path, err := s.opts.Diagnostics.Dump()
+	if err != nil {
+		s.log.WithError(err).Error("Error writing diagnostic snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write diagnostic snapshot"})
+		return
+	}
+	s.recordAudit(c.Request.Context(), c, "dump_diagnostics", "diagnostics", nil)
+	if path == "" {
+		c.JSON(http.StatusOK, gin.H{"written_to": "log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"written_to": "file", "path": path})
+}
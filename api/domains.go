@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getDomainsHandler handles GET /domains requests
+func (s *Server) getDomainsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetDomainStats(ctx, "")
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving domain stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve domains"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.DomainStats{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getDomainHandler handles GET /domains/:domain requests
+func (s *Server) getDomainHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+	domain := c.Param("domain")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetDomainStats(ctx, domain)
+	if err != nil {
+		s.log.WithError(err).WithField("domain", domain).Error("Error retrieving domain stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve domain"})
+		return
+	}
+
+	if len(stats) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+	stats[0].In(loc)
+	s.cache.set(cacheKey(c), stats[0])
+
+	c.JSON(http.StatusOK, stats[0])
+}
+
+// getDomainCallsHandler handles GET /domains/:domain/calls requests
+func (s *Server) getDomainCallsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := callFilterFromQuery(c)
+	filter.Domain = c.Param("domain")
+
+	calls, err := s.store.GetCalls(ctx, filter, limit, offset)
+	if err != nil {
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).WithField("domain", filter.Domain).Error("Error retrieving domain calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calls"})
+		return
+	}
+
+	if calls == nil {
+		calls = []store.Call{}
+	}
+	for i := range calls {
+		calls[i].In(loc)
+		s.setTraceLink(&calls[i])
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
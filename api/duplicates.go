@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDuplicateWindow = time.Second
+	maxDuplicateWindow     = time.Minute
+	maxDuplicateGroups     = 1000 // Groups found or merged per request
+)
+
+// duplicateQuery holds the parsed parameters shared by the duplicate endpoints
+type duplicateQuery struct {
+	from, to time.Time
+	window   time.Duration
+	limit    int
+}
+
+// parseDuplicateQuery reads from, to, window (a Go duration, default 1s) and limit (default 100),
+// responding 400 and returning false when one is invalid
+func parseDuplicateQuery(c *gin.Context) (duplicateQuery, bool) {
+	var q duplicateQuery
+	var ok bool
+	if q.from, q.to, ok = parseTimeRange(c); !ok {
+		return q, false
+	}
+	q.window = defaultDuplicateWindow
+	if v := c.Query("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || d > maxDuplicateWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("window must be a duration between 0s and %s", maxDuplicateWindow)})
+			return q, false
+		}
+		q.window = d
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > maxDuplicateGroups {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be an integer between 1 and %d", maxDuplicateGroups)})
+		return q, false
+	}
+	q.limit = limit
+	return q, true
+}
+
+// getDuplicatesHandler handles GET /admin/duplicates requests, listing duplicate call records without changing them
+func (s *Server) getDuplicatesHandler(c *gin.Context) {
+	q, ok := parseDuplicateQuery(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	groups, err := s.store.FindDuplicateCalls(ctx, q.from, q.to, q.window, q.limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving duplicate calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve duplicate calls"})
+		return
+	}
+
+	if groups == nil {
+		groups = []store.DuplicateGroup{}
+	}
+	for i := range groups {
+		groups[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// mergeDuplicatesHandler handles POST /admin/duplicates/merge requests, merging each duplicate group found
+// into its most complete record
+func (s *Server) mergeDuplicatesHandler(c *gin.Context) {
+	q, ok := parseDuplicateQuery(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	groups, err := s.store.FindDuplicateCalls(ctx, q.from, q.to, q.window, q.limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving duplicate calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve duplicate calls"})
+		return
+	}
+
+	merged := []store.DuplicateGroup{}
+	var removed int64
+	for _, g := range groups {
+		var n int64
+		if n, err = s.store.MergeDuplicateCalls(ctx, g); err != nil {
+			break
+		}
+		removed += n
+		g.In(loc)
+		merged = append(merged, g)
+	}
+	s.recordAudit(ctx, c, "merge_duplicates", "calls", map[string]any{
+		"from":    q.from,
+		"to":      q.to,
+		"window":  q.window.String(),
+		"groups":  len(merged),
+		"removed": removed,
+	})
+	if err != nil {
+		s.log.WithError(err).Error("Error merging duplicate calls")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge duplicate calls", "merged": merged, "removed": removed})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"merged": merged, "removed": removed})
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"slices"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sealedContextKey is the gin context key set on requests that see encrypted call fields as stored
+const sealedContextKey = "sealed"
+
+// requestRole returns the role of the request's API key: admin, operator, tenant or anonymous
+func (s *Server) requestRole(c *gin.Context) string {
+	switch {
+	case s.isAdmin(c):
+		return "admin"
+	case s.isOperator(c):
+		return "operator"
+	case c.GetString(tenantContextKey) != "":
+		return "tenant"
+	default:
+		return "anonymous"
+	}
+}
+
+// sealFields keeps encrypted call fields (caller and callee numbers, DTMF digits) encrypted in the
+// responses of roles not listed in DecryptRoles. It must run after scopeTenant.
+func (s *Server) sealFields(c *gin.Context) {
+	if slices.Contains(s.opts.DecryptRoles, s.requestRole(c)) {
+		c.Next()
+		return
+	}
+	c.Set(sealedContextKey, true)
+	c.Request = c.Request.WithContext(store.WithSealedFields(c.Request.Context()))
+	c.Next()
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getFaxesHandler handles GET /faxes requests
+func (s *Server) getFaxesHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	filter := store.FaxFilter{Direction: c.Query("direction")}
+	if filter.Direction != "" && filter.Direction != store.FaxInbound && filter.Direction != store.FaxOutbound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be inbound or outbound"})
+		return
+	}
+	if raw := c.Query("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+			return
+		}
+		filter.Success = &success
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	faxes, err := s.store.GetFaxes(ctx, filter, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving faxes from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve faxes"})
+		return
+	}
+
+	if faxes == nil {
+		faxes = []store.Fax{}
+	}
+	for i := range faxes {
+		faxes[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, faxes)
+}
+
+// getCallFaxesHandler handles GET /calls/:uuid/faxes requests
+func (s *Server) getCallFaxesHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	faxes, err := s.store.GetCallFaxes(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call faxes from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve faxes"})
+		return
+	}
+
+	if faxes == nil {
+		faxes = []store.Fax{}
+	}
+	for i := range faxes {
+		faxes[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, faxes)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// featuresRequest is the body of PUT /extensions/:extension/features. Omitted fields are left unchanged;
+// an empty forward destination turns that forward off.
+type featuresRequest struct {
+	DND             *bool   `json:"dnd"`
+	ForwardAlways   *string `json:"forward_always"`
+	ForwardBusy     *string `json:"forward_busy"`
+	ForwardNoAnswer *string `json:"forward_no_answer"`
+}
+
+// featureChange is one FreeSWITCH db update and the stored field it mirrors
+type featureChange struct {
+	realm string
+	value string
+	apply func(f *store.ExtensionFeatures)
+}
+
+// changes lists the updates needed to move current to the requested state
+func (r featuresRequest) changes(current *store.ExtensionFeatures) []featureChange {
+	var changes []featureChange
+	if r.DND != nil && *r.DND != current.DND {
+		dnd := *r.DND
+		value := ""
+		if dnd {
+			value = "true"
+		}
+		changes = append(changes, featureChange{esl.RealmDND, value, func(f *store.ExtensionFeatures) { f.DND = dnd }})
+	}
+	forwards := []struct {
+		realm     string
+		requested *string
+		field     *string
+	}{
+		{esl.RealmForwardAlways, r.ForwardAlways, &current.ForwardAlways},
+		{esl.RealmForwardBusy, r.ForwardBusy, &current.ForwardBusy},
+		{esl.RealmForwardNoAnswer, r.ForwardNoAnswer, &current.ForwardNoAnswer},
+	}
+	for _, fw := range forwards {
+		if fw.requested == nil || *fw.requested == *fw.field {
+			continue
+		}
+		field, value := fw.field, *fw.requested
+		changes = append(changes, featureChange{fw.realm, value, func(*store.ExtensionFeatures) { *field = value }})
+	}
+	return changes
+}
+
+// getExtensionFeaturesHandler handles GET /extensions/:extension/features requests
+func (s *Server) getExtensionFeaturesHandler(c *gin.Context) {
+	if err := esl.ValidateExtensionFeature(esl.RealmDND, c.Param("extension"), c.Query("domain"), ""); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	features, err := s.store.GetExtensionFeatures(ctx, c.Param("extension"), c.Query("domain"))
+	if err != nil {
+		s.log.WithError(err).WithField("extension", c.Param("extension")).Error("Error retrieving extension features from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve extension features"})
+		return
+	}
+	features.In(loc)
+
+	c.JSON(http.StatusOK, features)
+}
+
+// getExtensionFeaturesListHandler handles GET /extensions requests, listing every configured extension
+func (s *Server) getExtensionFeaturesListHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	list, err := s.store.GetExtensionFeaturesList(ctx, c.Query("domain"), limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving extension features from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve extension features"})
+		return
+	}
+
+	if list == nil {
+		list = []store.ExtensionFeatures{}
+	}
+	for i := range list {
+		list[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// setExtensionFeaturesHandler handles PUT /extensions/:extension/features requests. Each change is pushed
+// to FreeSWITCH first; the stored state reflects only the changes FreeSWITCH accepted.
+func (s *Server) setExtensionFeaturesHandler(c *gin.Context) {
+	extension, domain := c.Param("extension"), c.Query("domain")
+	var req featuresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	current, err := s.store.GetExtensionFeatures(ctx, extension, domain)
+	if err != nil {
+		s.log.WithError(err).WithField("extension", extension).Error("Error retrieving extension features from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve extension features"})
+		return
+	}
+
+	changes := req.changes(current)
+	for _, change := range changes {
+		if err := esl.ValidateExtensionFeature(change.realm, extension, domain, change.value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	details := map[string]any{"domain": domain}
+	var applyErr error
+	for _, change := range changes {
+		if applyErr = s.esl.SetExtensionFeature(ctx, change.realm, extension, domain, change.value); applyErr != nil {
+			details["error"] = applyErr.Error()
+			break
+		}
+		change.apply(current)
+		details[change.realm] = change.value
+	}
+
+	if len(details) > 1 { // At least one change reached FreeSWITCH
+		if err := s.store.SaveExtensionFeatures(context.WithoutCancel(ctx), current); err != nil {
+			s.log.WithError(err).WithField("extension", extension).Error("Error saving extension features")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save extension features"})
+			return
+		}
+	}
+	if len(changes) > 0 {
+		s.recordAudit(ctx, c, "features", extension, details)
+	}
+
+	switch {
+	case applyErr == nil:
+		current.In(loc)
+		c.JSON(http.StatusOK, current)
+	case errors.Is(applyErr, esl.ErrFeatureFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": applyErr.Error()})
+	case errors.Is(applyErr, esl.ErrESLNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+	default:
+		s.log.WithError(applyErr).WithFields(logrus.Fields{
+			"extension": extension,
+			"changes":   len(changes),
+		}).Error("Error updating extension features")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update extension features"})
+	}
+}
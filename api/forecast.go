@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/forecast"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the /stats/capacity-forecast parameters
+const (
+	defaultForecastWeeks = 8
+	maxForecastWeeks     = 52
+	defaultHistoryWeeks  = 26
+	minHistoryWeeks      = 4
+	maxHistoryWeeks      = 156
+)
+
+// weekForecast is the projected peak of one future week
+type weekForecast struct {
+	WeekStart time.Time `json:"week_start"`
+	PeakCalls float64   `json:"peak_calls"`
+	Upper     float64   `json:"upper"`    // 95% upper band; plan capacity against this
+	Channels  int       `json:"channels"` // Upper rounded up to whole channels
+}
+
+// capacityForecast is the response of GET /stats/capacity-forecast
+type capacityForecast struct {
+	Gateway        string             `json:"gateway,omitempty"`
+	Method         string             `json:"method"`
+	Limit          int                `json:"limit,omitempty"` // Configured GATEWAY_LIMITS value, if any
+	History        []store.WeeklyPeak `json:"history"`
+	Trend          *forecast.Trend    `json:"trend,omitempty"` // Linear method only; slope is calls per week
+	Forecast       []weekForecast     `json:"forecast"`
+	ExceedsLimitAt *time.Time         `json:"exceeds_limit_at,omitempty"` // First forecast week whose upper band reaches the limit
+}
+
+// weekStart returns the start of the week (Monday 00:00) containing t in loc
+func weekStart(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// getCapacityForecastHandler handles GET /stats/capacity-forecast requests. The peak concurrent calls of the
+// last complete weeks are projected forward to help size trunks.
+func (s *Server) getCapacityForecastHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	weeks, err := strconv.Atoi(c.DefaultQuery("weeks", strconv.Itoa(defaultForecastWeeks)))
+	if err != nil || weeks < 1 || weeks > maxForecastWeeks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weeks must be between 1 and 52"})
+		return
+	}
+	historyWeeks, err := strconv.Atoi(c.DefaultQuery("history_weeks", strconv.Itoa(defaultHistoryWeeks)))
+	if err != nil || historyWeeks < minHistoryWeeks || historyWeeks > maxHistoryWeeks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "history_weeks must be between 4 and 156"})
+		return
+	}
+	method := c.DefaultQuery("method", forecast.MethodLinear)
+	if method != forecast.MethodLinear && method != forecast.MethodSeasonal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method must be linear or seasonal"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	gateway := c.Query("gateway")
+
+	to := weekStart(time.Now(), loc) // The current week is incomplete
+	from := to.AddDate(0, 0, -7*historyWeeks)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	history, err := s.store.GetWeeklyPeakConcurrency(ctx, from, to, loc.String(), gateway)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving weekly peak concurrency from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve concurrency history"})
+		return
+	}
+
+	series := make([]float64, len(history))
+	for i := range history {
+		history[i].In(loc)
+		series[i] = float64(history[i].PeakCalls)
+	}
+	projections, err := forecast.Project(series, weeks, method)
+	if errors.Is(err, forecast.ErrInsufficientHistory) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).Error("Error forecasting capacity")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to forecast capacity"})
+		return
+	}
+
+	resp := capacityForecast{
+		Gateway:  gateway,
+		Method:   method,
+		History:  history,
+		Forecast: make([]weekForecast, len(projections)),
+	}
+	if method == forecast.MethodLinear {
+		if trend, err := forecast.Fit(series); err == nil {
+			resp.Trend = &trend
+		}
+	}
+	if gateway != "" {
+		for _, u := range s.gateways.Snapshot() {
+			if u.Gateway == gateway {
+				resp.Limit = u.Limit
+			}
+		}
+	}
+	for i, p := range projections {
+		week := to.AddDate(0, 0, 7*i)
+		resp.Forecast[i] = weekForecast{
+			WeekStart: week,
+			PeakCalls: math.Round(p.Peak*10) / 10,
+			Upper:     math.Round(p.Upper*10) / 10,
+			Channels:  int(math.Ceil(p.Upper)),
+		}
+		if resp.Limit > 0 && resp.ExceedsLimitAt == nil && resp.Forecast[i].Channels >= resp.Limit {
+			resp.ExceedsLimitAt = &week
+		}
+	}
+	s.cache.set(cacheKey(c), resp)
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getGatewaysHandler handles GET /gateways requests, listing sofia gateway states
+func (s *Server) getGatewaysHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	gateways, err := s.store.GetGatewayStates(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving gateway states from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve gateway states"})
+		return
+	}
+
+	if gateways == nil {
+		gateways = []store.GatewayState{}
+	}
+	for i := range gateways {
+		gateways[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, gateways)
+}
+
+// getGatewayConcurrencyHandler handles GET /gateways/concurrency requests
+func (s *Server) getGatewayConcurrencyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.gateways.Snapshot())
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// guardrailHint is returned with 422 responses to reads stopped by the read guardrails
+const guardrailHint = "Narrow the query: add a from/to time range or an indexed filter (domain, gateway, node, tag), or page with a smaller offset"
+
+// markAPIRead subjects the request's database reads to the API statement timeout and read guardrails
+func markAPIRead(c *gin.Context) {
+	c.Request = c.Request.WithContext(store.WithAPIRead(c.Request.Context()))
+	c.Next()
+}
+
+// guardrailError writes a 422 with guidance if err comes from the read guardrails or the API statement
+// timeout, reporting whether it did
+func (s *Server) guardrailError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, store.ErrQueryTooBroad):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "hint": guardrailHint})
+	case store.IsStatementTimeout(err):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "query exceeded the API statement timeout", "hint": guardrailHint})
+	default:
+		return false
+	}
+	s.log.WithError(err).WithField("path", c.FullPath()).Warn("API read stopped by guardrails")
+	return true
+}
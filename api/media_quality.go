@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMediaQualityHandler handles GET /calls/quality requests, listing per-call RTP statistics
+func (s *Server) getMediaQualityHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	var filter store.MediaQualityFilter
+	if v := c.Query("max_mos"); v != "" {
+		mos, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_mos must be a number"})
+			return
+		}
+		filter.MaxMOS = &mos
+	}
+	if v := c.Query("min_loss"); v != "" {
+		loss, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_loss must be a number"})
+			return
+		}
+		filter.MinPacketLoss = &loss
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetMediaQuality(ctx, filter, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving media quality from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve media quality"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.MediaQuality{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getCallMediaQualityHandler handles GET /calls/:uuid/quality requests
+func (s *Server) getCallMediaQualityHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	quality, err := s.store.GetCallMediaQuality(ctx, uuid)
+	if errors.Is(err, store.ErrMediaQualityNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No media statistics were recorded for this call"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call media quality from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve media quality"})
+		return
+	}
+
+	quality.In(loc)
+	c.JSON(http.StatusOK, quality)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMessagesHandler handles GET /messages requests
+func (s *Server) getMessagesHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	filter := store.MessageFilter{
+		Direction: c.Query("direction"),
+		Status:    c.Query("status"),
+		Address:   c.Query("address"),
+	}
+	if filter.Direction != "" && filter.Direction != store.MessageInbound && filter.Direction != store.MessageOutbound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be inbound or outbound"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	messages, err := s.store.GetMessages(ctx, filter, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving messages from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+		return
+	}
+
+	if messages == nil {
+		messages = []store.Message{}
+	}
+	for i := range messages {
+		messages[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// getMessageHandler handles GET /messages/:id requests
+func (s *Server) getMessageHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message id must be an integer"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	m, err := s.store.GetMessage(ctx, id)
+	if errors.Is(err, store.ErrMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("messageID", id).Error("Error retrieving message from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve message"})
+		return
+	}
+
+	m.In(loc)
+	c.JSON(http.StatusOK, m)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMissedCallsHandler handles GET /calls/missed requests
+func (s *Server) getMissedCallsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	calls, err := s.store.GetMissedCalls(ctx, s.opts.MissedCallCauses, callFilterFromQuery(c), from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving missed calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve missed calls"})
+		return
+	}
+
+	if calls == nil {
+		calls = []store.Call{}
+	}
+	for i := range calls {
+		calls[i].In(loc)
+		s.setTraceLink(&calls[i])
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/outcome"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setCallOutcomeRequest is the body of PUT /calls/:uuid/outcome
+type setCallOutcomeRequest struct {
+	Outcome string `json:"outcome" binding:"required"`
+}
+
+// setCallOutcomeHandler handles PUT /calls/:uuid/outcome requests, labelling a call from an external system
+// such as a CRM. The label replaces any set by the classifier, which never revisits the call.
+func (s *Server) setCallOutcomeHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	var req setCallOutcomeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	label := strings.TrimSpace(req.Outcome)
+	if !outcome.ValidLabel(label) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be 1 to 64 characters"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.store.SetCallOutcome(ctx, uuid, &label, store.OutcomeSourceAPI)
+	if errors.Is(err, store.ErrCallNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set call outcome"})
+		return
+	}
+	s.recordAudit(ctx, c, "set_outcome", uuid, map[string]any{"outcome": label})
+
+	c.JSON(http.StatusOK, gin.H{"uuid": uuid, "outcome": label, "outcome_source": store.OutcomeSourceAPI})
+}
+
+// getOutcomeStatsHandler handles GET /stats/outcomes requests
+func (s *Server) getOutcomeStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetOutcomeStats(ctx, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving outcome stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve outcome stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.OutcomeStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// parkRequest is the body of POST /calls/:uuid/park
+type parkRequest struct {
+	Lot  string `json:"lot"`  // Defaults to the configured lot
+	Slot int    `json:"slot"` // 0 picks the lowest free slot
+}
+
+// retrieveRequest is the body of POST /parking/:lot/:slot/retrieve
+type retrieveRequest struct {
+	Extension string `json:"extension" binding:"required"`
+	Context   string `json:"context"` // Dialplan context; defaults to the call's context
+}
+
+// parkHandler handles POST /calls/:uuid/park requests, moving an active call into a valet parking slot
+func (s *Server) parkHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	var req parkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Lot == "" {
+		req.Lot = s.opts.ParkingLot
+	}
+	if req.Slot < 0 || (req.Slot > 0 && (req.Slot < s.opts.ParkingSlotMin || req.Slot > s.opts.ParkingSlotMax)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slot is outside the parking range"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, ok := s.activeCall(ctx, c, uuid); !ok {
+		return
+	}
+
+	parked := &store.ParkedCall{UUID: uuid, Lot: req.Lot, Slot: req.Slot}
+	err := s.store.ParkCall(ctx, parked, s.opts.ParkingSlotMin, s.opts.ParkingSlotMax)
+	switch {
+	case errors.Is(err, store.ErrSlotTaken), errors.Is(err, store.ErrNoFreeSlot), errors.Is(err, store.ErrAlreadyParked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error reserving parking slot")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve parking slot"})
+		return
+	}
+
+	err = s.esl.Park(ctx, uuid, parked.Lot, parked.Slot)
+	details := map[string]any{"lot": parked.Lot, "slot": parked.Slot, "parked": err == nil}
+	if err != nil {
+		details["error"] = err.Error()
+		if releaseErr := s.store.ReleaseParkedCall(context.WithoutCancel(ctx), uuid, store.ParkStatusFailed, nil); releaseErr != nil {
+			s.log.WithError(releaseErr).WithField("uuid", uuid).Error("Error releasing parking slot after failed park")
+		}
+	}
+	s.recordAudit(ctx, c, "park", uuid, details)
+
+	switch {
+	case err == nil:
+		loc, ok := requestLocation(c)
+		if !ok {
+			return
+		}
+		parked.In(loc)
+		c.JSON(http.StatusOK, parked)
+	case errors.Is(err, esl.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, esl.ErrTransferFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, esl.ErrESLNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+	default:
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error parking call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to park call"})
+	}
+}
+
+// retrieveHandler handles POST /parking/:lot/:slot/retrieve requests, sending the parked call to an extension
+func (s *Server) retrieveHandler(c *gin.Context) {
+	lot := c.Param("lot")
+	slot, err := strconv.Atoi(c.Param("slot"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot"})
+		return
+	}
+	var req retrieveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	parked, err := s.store.GetParkedCallInSlot(ctx, lot, slot)
+	if errors.Is(err, store.ErrParkingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{"lot": lot, "slot": slot}).Error("Error looking up parked call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve parked call"})
+		return
+	}
+
+	if req.Context == "" {
+		req.Context = "default"
+		if call, err := s.store.GetCallByUUID(ctx, parked.UUID); err == nil && call.Context != nil {
+			req.Context = *call.Context
+		}
+	}
+
+	err = s.esl.Retrieve(ctx, parked.UUID, req.Extension, req.Context)
+	details := map[string]any{"lot": lot, "slot": slot, "extension": req.Extension, "context": req.Context, "retrieved": err == nil}
+	if err != nil {
+		details["error"] = err.Error()
+	} else if releaseErr := s.store.ReleaseParkedCall(context.WithoutCancel(ctx), parked.UUID, store.ParkStatusRetrieved, &req.Extension); releaseErr != nil {
+		s.log.WithError(releaseErr).WithField("uuid", parked.UUID).Error("Error releasing parking slot after retrieval")
+	}
+	s.recordAudit(ctx, c, "retrieve", parked.UUID, details)
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"uuid": parked.UUID, "extension": req.Extension, "context": req.Context})
+	case errors.Is(err, esl.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, esl.ErrTransferFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, esl.ErrESLNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+	default:
+		s.log.WithError(err).WithField("uuid", parked.UUID).Error("Error retrieving parked call")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve parked call"})
+	}
+}
+
+// getParkedCallsHandler handles GET /parking requests
+func (s *Server) getParkedCallsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	parked, err := s.store.GetParkedCalls(ctx, c.Query("lot"), limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving parked calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve parked calls"})
+		return
+	}
+
+	if parked == nil {
+		parked = []store.ParkedCall{}
+	}
+	for i := range parked {
+		parked[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, parked)
+}
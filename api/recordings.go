@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getRecordingsHandler handles GET /recordings requests
+func (s *Server) getRecordingsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	recordings, err := s.store.GetRecordings(ctx, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving recordings from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recordings"})
+		return
+	}
+
+	if recordings == nil {
+		recordings = []store.Recording{}
+	}
+	for i := range recordings {
+		recordings[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, recordings)
+}
+
+// getCallRecordingsHandler handles GET /calls/:uuid/recordings requests
+func (s *Server) getCallRecordingsHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	recordings, err := s.store.GetCallRecordings(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call recordings from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recordings"})
+		return
+	}
+
+	if recordings == nil {
+		recordings = []store.Recording{}
+	}
+	for i := range recordings {
+		recordings[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, recordings)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getRegistrationsHandler handles GET /registrations requests
+func (s *Server) getRegistrationsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	activeOnly, err := strconv.ParseBool(c.DefaultQuery("active", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "active must be true or false"})
+		return
+	}
+	filter := store.RegistrationFilter{
+		User:       c.Query("user"),
+		Realm:      c.Query("realm"),
+		ActiveOnly: activeOnly,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	registrations, err := s.store.GetRegistrations(ctx, filter, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving registrations from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve registrations"})
+		return
+	}
+
+	if registrations == nil {
+		registrations = []store.Registration{}
+	}
+	for i := range registrations {
+		registrations[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, registrations)
+}
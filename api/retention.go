@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getCallEventsHandler handles GET /calls/:uuid/events requests: the raw events still kept for the call
+// and the summaries of those already expired
+func (s *Server) getCallEventsHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	raw, err := s.store.GetRawEvents(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving raw events from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+	summaries, err := s.store.GetCallEventSummaries(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call event summaries from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	if raw == nil {
+		raw = []store.RawEvent{}
+	}
+	for i := range raw {
+		raw[i].In(loc)
+	}
+	if summaries == nil {
+		summaries = []store.CallEventSummary{}
+	}
+	for i := range summaries {
+		summaries[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"raw":     raw,
+		"summary": summaries,
+	})
+}
+
+// retentionPolicy is the retention configuration as reported by GET /admin/retention; 0 keeps a tier forever
+type retentionPolicy struct {
+	IntervalSeconds float64 `json:"interval_seconds"`
+	RawEventDays    float64 `json:"raw_event_days"`
+	CallEventDays   float64 `json:"call_event_days"`
+	CallDays        float64 `json:"call_days"`
+}
+
+// getRetentionHandler handles GET /admin/retention requests: the configured tiers and the last run
+func (s *Server) getRetentionHandler(c *gin.Context) {
+	if s.opts.Retention == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Retention is not available"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	p := s.opts.Retention.Policy()
+	day := 24 * time.Hour
+	resp := gin.H{
+		"policy": retentionPolicy{
+			IntervalSeconds: p.Interval.Seconds(),
+			RawEventDays:    p.RawEvents.Hours() / day.Hours(),
+			CallEventDays:   p.CallEvents.Hours() / day.Hours(),
+			CallDays:        p.Calls.Hours() / day.Hours(),
+		},
+	}
+	if last := s.opts.Retention.Last(); last != nil {
+		report := *last
+		report.RanAt = report.RanAt.In(loc)
+		resp["last_run"] = report
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// runRetentionHandler handles POST /admin/retention/run requests, enforcing every tier now
+func (s *Server) runRetentionHandler(c *gin.Context) {
+	if s.opts.Retention == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Retention is not available"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	report := *s.opts.Retention.Enforce(ctx)
+	report.RanAt = report.RanAt.In(loc)
+	details := map[string]any{"deleted": report.Deleted}
+	if len(report.Errors) > 0 {
+		details["errors"] = report.Errors
+	}
+	s.recordAudit(ctx, c, "run_retention", "retention", details)
+
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	searchParam         = "search" // Query parameter that replays a saved search
+	maxSearchNameLength = 64
+	maxSearchParams     = 50
+)
+
+// saveSearchRequest is the body of PUT /searches/:name
+type saveSearchRequest struct {
+	Params map[string]string `json:"params" binding:"required"`
+}
+
+// searchOwner identifies whose saved searches a request sees: a fingerprint of its API key, or "" for
+// requests without one
+func searchOwner(c *gin.Context) string {
+	key := c.GetHeader("X-API-Key")
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// applySavedSearch expands ?search=<name> into the saved search's parameters before the handler runs.
+// Parameters given explicitly in the request take precedence over saved ones.
+func (s *Server) applySavedSearch(c *gin.Context) {
+	query := c.Request.URL.Query()
+	name := query.Get(searchParam)
+	if name == "" {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	search, err := s.store.GetSearch(ctx, searchOwner(c), name)
+	if errors.Is(err, store.ErrSearchNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Saved search not found", "search": name})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("search", name).Error("Error retrieving saved search from store")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved search"})
+		return
+	}
+
+	query.Del(searchParam)
+	for key, value := range search.Params {
+		if !query.Has(key) {
+			query.Set(key, value)
+		}
+	}
+	c.Request.URL.RawQuery = query.Encode()
+	c.Next()
+}
+
+// getSearchesHandler handles GET /searches requests, listing the caller's saved searches
+func (s *Server) getSearchesHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	searches, err := s.store.GetSearches(ctx, searchOwner(c))
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving saved searches from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved searches"})
+		return
+	}
+
+	if searches == nil {
+		searches = []store.SavedSearch{}
+	}
+	for i := range searches {
+		searches[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// getSearchHandler handles GET /searches/:name requests
+func (s *Server) getSearchHandler(c *gin.Context) {
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	search, err := s.store.GetSearch(ctx, searchOwner(c), c.Param("name"))
+	if errors.Is(err, store.ErrSearchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving saved search from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved search"})
+		return
+	}
+
+	search.In(loc)
+	c.JSON(http.StatusOK, search)
+}
+
+// saveSearchHandler handles PUT /searches/:name requests, creating or replacing the caller's search
+func (s *Server) saveSearchHandler(c *gin.Context) {
+	name := strings.TrimSpace(c.Param("name"))
+	if name == "" || len(name) > maxSearchNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 64 characters"})
+		return
+	}
+	var req saveSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Params) == 0 || len(req.Params) > maxSearchParams {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "params must hold 1 to 50 query parameters"})
+		return
+	}
+	if _, ok := req.Params[searchParam]; ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a saved search cannot refer to another saved search"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	search := &store.SavedSearch{Name: name, Owner: searchOwner(c), Params: req.Params}
+	if err := s.store.SaveSearch(ctx, search); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+// deleteSearchHandler handles DELETE /searches/:name requests
+func (s *Server) deleteSearchHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.store.DeleteSearch(ctx, searchOwner(c), c.Param("name"))
+	if errors.Is(err, store.ErrSearchNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -2,31 +2,117 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"gofreeswitchesl/campaign"
+	"gofreeswitchesl/diagnostics"
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/features"
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/monitor"
+	"gofreeswitchesl/retention"
 	"gofreeswitchesl/store"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// Pagination fallbacks used when Options leaves them unset
 const (
 	defaultLimit  = 10
 	maxLimit      = 100
 	defaultOffset = 0
+
+	defaultQueueServiceLevel = 20 // Seconds
+)
+
+// Parking fallbacks used when Options leaves them unset
+const (
+	defaultParkingLot     = "valet_lot"
+	defaultParkingSlotMin = 5901
+	defaultParkingSlotMax = 5999
 )
 
+// Options configures optional API behaviour. Zero values fall back to the package defaults.
+type Options struct {
+	DefaultLimit  int
+	MaxLimit      int
+	AdminMaxLimit int      // Limit cap for requests authenticated with one of AdminKeys
+	AdminKeys     []string // Keys accepted in the X-API-Key header
+	OperatorKeys  []string // Keys allowed to supervise calls; admin keys are accepted too
+
+	// TenantKeys maps read-only keys to the call domain they are restricted to. Scoping relies on the
+	// database's row-level security policies (store.SetRowLevelSecurity).
+	TenantKeys map[string]string
+
+	// DecryptRoles are the roles (admin, operator, tenant, anonymous) that see encrypted call fields in
+	// plaintext; other requests get them as stored. Irrelevant unless the store encrypts fields.
+	DecryptRoles []string
+
+	StatsCacheTTL time.Duration // How long stats responses are cached; 0 disables caching
+
+	Campaigns *campaign.Manager // Optional; nil disables campaign creation
+
+	Quality *monitor.QualityMonitor // Optional; nil disables GET /admin/data-quality
+
+	Retention *retention.Enforcer // Optional; nil disables the /admin/retention endpoints
+
+	Diagnostics *diagnostics.Dumper // Optional; nil disables the /admin/diagnostics endpoints
+
+	Usage *UsageMeter // Optional; nil disables usage accounting, quotas and GET /admin/usage
+
+	CallerIDCampaigns *esl.CallerIDCampaigns // Optional; reloaded when mappings change through the API
+
+	MissedCallCauses []string // Hangup causes of unanswered inbound calls reported as missed
+
+	SIPTraceURLTemplate string // Deep link to a SIP capture tool; see setTraceLink
+
+	QueueServiceLevel int // Default answer threshold in seconds for queue service-level figures
+
+	// Valet parking lot used when a park request names none, and the slot range it allocates from
+	ParkingLot     string
+	ParkingSlotMin int
+	ParkingSlotMax int
+
+	Features *features.Set // Resolved feature flags, reported by GET /version
+}
+
 // Server handles API requests
 type Server struct {
-	router *gin.Engine
-	store  *store.Store
-	log    *logrus.Logger
+	router   *gin.Engine
+	store    *store.Store
+	gateways *esl.GatewayTracker
+	esl      *esl.Cluster
+	opts     Options
+	cache    *responseCache
+	log      *logrus.Logger
 }
 
 // NewServer creates a new API server
-func NewServer(s *store.Store, logger *logrus.Logger) *Server {
+func NewServer(s *store.Store, eslClient *esl.Cluster, gateways *esl.GatewayTracker, opts Options, logger *logrus.Logger) *Server {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = defaultLimit
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = maxLimit
+	}
+	if opts.AdminMaxLimit < opts.MaxLimit {
+		opts.AdminMaxLimit = opts.MaxLimit
+	}
+	if opts.QueueServiceLevel <= 0 {
+		opts.QueueServiceLevel = defaultQueueServiceLevel
+	}
+	if opts.ParkingLot == "" {
+		opts.ParkingLot = defaultParkingLot
+	}
+	if opts.ParkingSlotMin <= 0 || opts.ParkingSlotMax < opts.ParkingSlotMin {
+		opts.ParkingSlotMin, opts.ParkingSlotMax = defaultParkingSlotMin, defaultParkingSlotMax
+	}
+
 	router := gin.New() // Using gin.New() for more control over middleware
 
 	// Setup logger middleware
@@ -53,10 +139,17 @@ func NewServer(s *store.Store, logger *logrus.Logger) *Server {
 		})
 	}))
 
+	// Carry the caller's W3C trace ID into request contexts, so database writes link back to the trace
+	router.Use(propagateTrace)
+
 	srv := &Server{
-		router: router,
-		store:  s,
-		log:    logger,
+		router:   router,
+		store:    s,
+		gateways: gateways,
+		esl:      eslClient,
+		opts:     opts,
+		cache:    newResponseCache(opts.StatsCacheTTL),
+		log:      logger,
 	}
 
 	srv.setupRoutes()
@@ -65,27 +158,167 @@ func NewServer(s *store.Store, logger *logrus.Logger) *Server {
 
 // setupRoutes defines the API routes
 func (s *Server) setupRoutes() {
-	api := s.router.Group("/api/v1") // Versioning the API
+	api := s.router.Group("/api/v1", s.meterUsage, markAPIRead, s.scopeTenant, s.sealFields, s.applySavedSearch, normalizeUUIDs) // Versioning the API
 	{
+		api.GET("/version", s.getVersionHandler)
 		api.GET("/calls", s.getCallsHandler)
+		api.GET("/calls/stuck", s.getStuckCallsHandler)
+		api.GET("/calls/missed", s.getMissedCallsHandler)
+		api.GET("/calls/quality", s.getMediaQualityHandler)
 		api.GET("/calls/:uuid", s.getCallByUUIDHandler)
+		api.GET("/calls/:uuid/transitions", s.getCallTransitionsHandler)
+		api.GET("/calls/:uuid/dtmf", s.getCallDTMFHandler)
+		api.GET("/calls/:uuid/holds", s.getCallHoldsHandler)
+		api.GET("/calls/:uuid/parks", s.getCallParksHandler)
+		api.GET("/calls/:uuid/transfers", s.getCallTransfersHandler)
+		api.GET("/calls/:uuid/applications", s.getCallApplicationsHandler)
+		api.GET("/calls/:uuid/events", s.requireAdmin, s.getCallEventsHandler)
+		api.GET("/calls/:uuid/recordings", s.getCallRecordingsHandler)
+		api.GET("/calls/:uuid/faxes", s.getCallFaxesHandler)
+		api.GET("/calls/:uuid/quality", s.getCallMediaQualityHandler)
+		api.GET("/calls/:uuid/voicemail", s.getCallVoicemailHandler)
+		api.GET("/voicemail", s.getVoicemailEventsHandler)
+		api.GET("/messages", s.getMessagesHandler)
+		api.GET("/messages/:id", s.getMessageHandler)
+		api.GET("/recordings", s.getRecordingsHandler)
+		api.GET("/faxes", s.getFaxesHandler)
+		api.GET("/registrations", s.getRegistrationsHandler)
+		api.GET("/verto/sessions", s.getVertoSessionsHandler)
+		api.GET("/conferences", s.getConferencesHandler)
+		api.GET("/conferences/:id", s.getConferenceHandler)
+		api.GET("/agents", s.getAgentsHandler)
+		api.GET("/agents/:name/transitions", s.getAgentTransitionsHandler)
+		api.GET("/queues", s.getQueuesHandler)
+		api.GET("/calls/:uuid/callback", s.getCallCallbackHandler)
+		api.GET("/calls/:uuid/flow", s.getCallFlowHandler)
+		api.GET("/callbacks", s.getCallbacksHandler)
+		api.GET("/caller-id-campaigns", s.getCallerIDCampaignsHandler)
+		api.PUT("/caller-id-campaigns/:caller_id", s.requireOperator, s.setCallerIDCampaignHandler)
+		api.DELETE("/caller-id-campaigns/:caller_id", s.requireOperator, s.deleteCallerIDCampaignHandler)
+		api.GET("/searches", s.getSearchesHandler)
+		api.GET("/searches/:name", s.getSearchHandler)
+		api.PUT("/searches/:name", s.saveSearchHandler)
+		api.DELETE("/searches/:name", s.deleteSearchHandler)
+		api.POST("/calls/tags", s.requireOperator, s.tagCallsHandler)
+		api.POST("/calls/:uuid/supervise", s.requireOperator, s.superviseHandler)
+		api.POST("/calls/:uuid/broadcast", s.requireOperator, s.broadcastHandler)
+		api.POST("/calls/:uuid/park", s.requireOperator, s.parkHandler)
+		api.PUT("/calls/:uuid/outcome", s.requireOperator, s.setCallOutcomeHandler)
+		api.GET("/stats/cost", s.getCostStatsHandler)
+		api.GET("/stats/gateways", s.getGatewayStatsHandler)
+		api.GET("/stats/server", s.getServerStatsHandler)
+		api.GET("/stats/queues", s.getQueueStatsHandler)
+		api.GET("/stats/short-calls", s.getShortCallStatsHandler)
+		api.GET("/stats/busy-hour", s.getBusyHourStatsHandler)
+		api.GET("/stats/capacity-forecast", s.getCapacityForecastHandler)
+		api.GET("/stats/destinations", s.getDestinationStatsHandler)
+		api.GET("/stats/voicemail", s.getVoicemailStatsHandler)
+		api.GET("/stats/caller-id-campaigns", s.getCampaignAttributionStatsHandler)
+		api.GET("/stats/business-hours", s.getBusinessHoursStatsHandler)
+		api.GET("/stats/outcomes", s.getOutcomeStatsHandler)
+		api.GET("/gateways", s.getGatewaysHandler)
+		api.GET("/gateways/concurrency", s.getGatewayConcurrencyHandler)
+		api.GET("/domains", s.getDomainsHandler)
+		api.GET("/domains/:domain", s.getDomainHandler)
+		api.GET("/domains/:domain/calls", s.getDomainCallsHandler)
+		api.GET("/campaigns", s.getCampaignsHandler)
+		api.GET("/campaigns/:id", s.getCampaignHandler)
+		api.GET("/parking", s.getParkedCallsHandler)
+		api.GET("/extensions", s.getExtensionFeaturesListHandler)
+		api.GET("/extensions/:extension/features", s.getExtensionFeaturesHandler)
+		api.PUT("/extensions/:extension/features", s.requireOperator, s.setExtensionFeaturesHandler)
+		api.POST("/parking/:lot/:slot/retrieve", s.requireOperator, s.retrieveHandler)
 	}
 
+	// Endpoints that place calls require an admin key, and are refused while none is configured
+	dialing := api.Group("", s.requireAdminKey)
+	{
+		dialing.POST("/originate", s.originateHandler)
+		dialing.POST("/campaigns", s.createCampaignHandler)
+		dialing.POST("/campaigns/:id/cancel", s.cancelCampaignHandler)
+	}
+
+	admin := api.Group("/admin")
+	{
+		admin.GET("/esl/status", s.requireAdmin, s.getESLStatusHandler)
+		admin.POST("/esl/password", s.requireAdminKey, s.setESLPasswordHandler)
+		admin.GET("/esl/switch-events", s.requireAdmin, s.getSwitchEventsHandler)
+		admin.GET("/uptime", s.requireAdmin, s.getUptimeHandler)
+		admin.GET("/data-quality", s.requireAdmin, s.getDataQualityHandler)
+		admin.GET("/retention", s.requireAdmin, s.getRetentionHandler)
+		admin.POST("/retention/run", s.requireAdminKey, s.runRetentionHandler)
+		admin.GET("/duplicates", s.requireAdmin, s.getDuplicatesHandler)
+		admin.POST("/duplicates/merge", s.requireAdminKey, s.mergeDuplicatesHandler)
+		admin.POST("/cache/invalidate", s.requireAdmin, s.invalidateCacheHandler)
+		admin.GET("/audit", s.requireAdmin, s.getAuditLogHandler)
+		admin.GET("/usage", s.requireAdmin, s.getUsageHandler)
+		admin.GET("/dead-letters", s.requireAdmin, s.getDeadLettersHandler)
+		admin.GET("/dead-letters/:id", s.requireAdmin, s.getDeadLetterHandler)
+		admin.POST("/dead-letters/:id/reprocess", s.requireAdmin, s.reprocessDeadLetterHandler)
+		admin.GET("/diagnostics", s.requireAdmin, s.getDiagnosticsHandler)
+		admin.POST("/diagnostics/dump", s.requireAdmin, s.dumpDiagnosticsHandler)
+	}
+
+	// Prometheus metrics endpoint
+	s.router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+
 	// Health check endpoint
-	s.router.GET("/health", func(c *gin.Context) {
+	s.router.GET("/health", s.healthHandler)
+}
+
+// healthHandler handles GET /health requests. It reports DOWN once ESL authentication has been given up
+// on for any node, since that node's events will not be logged until the password is fixed.
+func (s *Server) healthHandler(c *gin.Context) {
+	if s.esl == nil {
 		c.JSON(http.StatusOK, gin.H{"status": "UP"})
-	})
+		return
+	}
+	state := s.esl.State()
+	if state == esl.StateAuthFailed {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "DOWN", "esl": state})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "UP", "esl": state})
 }
 
-// getCallsHandler handles GET /calls requests
-func (s *Server) getCallsHandler(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", strconv.Itoa(defaultLimit))
+// isAdmin reports whether the request carries one of the configured admin API keys
+func (s *Server) isAdmin(c *gin.Context) bool {
+	return matchesKey(c.GetHeader("X-API-Key"), s.opts.AdminKeys)
+}
+
+// isOperator reports whether the request carries an operator or admin API key
+func (s *Server) isOperator(c *gin.Context) bool {
+	return s.isAdmin(c) || matchesKey(c.GetHeader("X-API-Key"), s.opts.OperatorKeys)
+}
+
+// matchesKey reports whether key is one of keys, comparing in constant time
+func matchesKey(key string, keys []string) bool {
+	if key == "" {
+		return false
+	}
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePagination reads the limit and offset query parameters, falling back to defaults for invalid values.
+// Requests with an admin API key may use limits up to AdminMaxLimit for bulk pulls.
+func (s *Server) parsePagination(c *gin.Context) (int, int) {
+	limitStr := c.DefaultQuery("limit", strconv.Itoa(s.opts.DefaultLimit))
 	offsetStr := c.DefaultQuery("offset", strconv.Itoa(defaultOffset))
 
+	limitCap := s.opts.MaxLimit
+	if s.isAdmin(c) {
+		limitCap = s.opts.AdminMaxLimit
+	}
+
 	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > maxLimit {
-		limit = defaultLimit
-		s.log.Warnf("Invalid limit value '%s', using default %d", limitStr, limit)
+	if err != nil || limit <= 0 || limit > limitCap {
+		limit = s.opts.DefaultLimit
+		s.log.Warnf("Invalid limit value '%s' (max %d), using default %d", limitStr, limitCap, limit)
 	}
 
 	offset, err := strconv.Atoi(offsetStr)
@@ -93,12 +326,91 @@ func (s *Server) getCallsHandler(c *gin.Context) {
 		offset = defaultOffset
 		s.log.Warnf("Invalid offset value '%s', using default %d", offsetStr, offset)
 	}
+	return limit, offset
+}
+
+// callFilterFromQuery builds a store.CallFilter from the request's query parameters
+func callFilterFromQuery(c *gin.Context) store.CallFilter {
+	return store.CallFilter{
+		Context:     c.Query("context"),
+		SIPProfile:  c.Query("sip_profile"),
+		Domain:      c.Query("domain"),
+		AccountCode: c.Query("account_code"),
+		UserID:      c.Query("user_id"),
+		Gateway:     c.Query("gateway"),
+		Node:        c.Query("node"),
+		SIPCallID:   c.Query("sip_call_id"),
+		Tag:         c.Query("tag"),
+		Campaign:    c.Query("campaign"),
+		Outcome:     c.Query("outcome"),
+		Corrected:   c.Query("corrected") == "true",
+
+		BusinessHours: optionalBoolQuery(c, "business_hours"),
+		Q850:          optionalIntQuery(c, "q850"),
+		SIPStatus:     optionalIntQuery(c, "sip_status"),
+
+		Variables:    variableQuery(c),
+		VertoSession: c.Query("verto_session"),
+		RetryOf:      c.Query("retry_of"),
+	}
+}
+
+// variableQuery collects var.<name>=<value> query parameters into a channel variable filter
+func variableQuery(c *gin.Context) map[string]string {
+	var vars map[string]string
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, "var.")
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]string)
+		}
+		vars[name] = values[0]
+	}
+	return vars
+}
+
+// optionalIntQuery returns the value of an integer query parameter, or nil when it is absent or invalid
+func optionalIntQuery(c *gin.Context, key string) *int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// optionalBoolQuery returns the value of a true/false query parameter, or nil when it is absent or invalid
+func optionalBoolQuery(c *gin.Context, key string) *bool {
+	switch c.Query(key) {
+	case "true":
+		v := true
+		return &v
+	case "false":
+		v := false
+		return &v
+	}
+	return nil
+}
+
+// getCallsHandler handles GET /calls requests
+func (s *Server) getCallsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	calls, err := s.store.GetCalls(ctx, limit, offset)
+	filter := callFilterFromQuery(c)
+
+	calls, err := s.store.GetCalls(ctx, filter, limit, offset)
 	if err != nil {
+		if s.guardrailError(c, err) {
+			return
+		}
 		s.log.WithError(err).Error("Error retrieving calls from store")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve calls"})
 		return
@@ -107,6 +419,10 @@ func (s *Server) getCallsHandler(c *gin.Context) {
 	if calls == nil { // Ensure we return an empty list, not null, if no calls found
 		calls = []store.Call{}
 	}
+	for i := range calls {
+		calls[i].In(loc)
+		s.setTraceLink(&calls[i])
+	}
 
 	c.JSON(http.StatusOK, calls)
 }
@@ -118,6 +434,10 @@ func (s *Server) getCallByUUIDHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "UUID parameter is required"})
 		return
 	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
@@ -140,6 +460,8 @@ func (s *Server) getCallByUUIDHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
 		return
 	}
+	call.In(loc)
+	s.setTraceLink(call)
 
 	c.JSON(http.StatusOK, call)
 }
@@ -154,3 +476,11 @@ func (s *Server) Start(address string) error {
 func (s *Server) GetRouter() *gin.Engine {
 	return s.router
 }
+
+// propagateTrace stores the trace ID of an incoming traceparent header in the request context
+func propagateTrace(c *gin.Context) {
+	if traceID, ok := metrics.ParseTraceparent(c.GetHeader("traceparent")); ok {
+		c.Request = c.Request.WithContext(metrics.WithTraceID(c.Request.Context(), traceID))
+	}
+	c.Next()
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCostGroupBy = "extension"
+	defaultPrefixLen   = 3
+	maxPrefixLen       = 16
+)
+
+// getCostStatsHandler handles GET /stats/cost requests
+func (s *Server) getCostStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	groupBy := c.DefaultQuery("group_by", defaultCostGroupBy)
+	prefixLenStr := c.DefaultQuery("prefix_len", strconv.Itoa(defaultPrefixLen))
+
+	prefixLen, err := strconv.Atoi(prefixLenStr)
+	if err != nil || prefixLen <= 0 || prefixLen > maxPrefixLen {
+		prefixLen = defaultPrefixLen
+		s.log.Warnf("Invalid prefix_len value '%s', using default %d", prefixLenStr, prefixLen)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	summaries, err := s.store.GetCostSummary(ctx, groupBy, prefixLen)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidGroupBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be one of: extension, domain, destination_prefix"})
+			return
+		}
+		if errors.Is(err, store.ErrEncryptedField) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by=destination_prefix is unavailable while callee numbers are encrypted"})
+			return
+		}
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving cost summary from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve cost summary"})
+		return
+	}
+
+	if summaries == nil {
+		summaries = []store.CostSummary{}
+	}
+	s.cache.set(cacheKey(c), summaries)
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// parseTimeRange reads the RFC3339 from/to query parameters. to defaults to now and from to
+// 24 hours before to. ok is false (and a 400 has been written) if either value is malformed.
+func parseTimeRange(c *gin.Context) (from, to time.Time, ok bool) {
+	to = time.Now()
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+			return from, to, false
+		}
+		to = t
+	}
+	from = to.Add(-24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+			return from, to, false
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// getGatewayStatsHandler handles GET /stats/gateways requests
+func (s *Server) getGatewayStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetGatewayStats(ctx, from, to)
+	if err != nil {
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving gateway stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve gateway stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.GatewayStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getQueueStatsHandler handles GET /stats/queues requests
+func (s *Server) getQueueStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	interval := c.Query("interval")
+	if interval == "none" {
+		interval = ""
+	}
+	slaStr := c.DefaultQuery("sla", strconv.Itoa(s.opts.QueueServiceLevel))
+	sla, err := strconv.Atoi(slaStr)
+	if err != nil || sla < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sla must be a non-negative number of seconds"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetQueueStats(ctx, from, to, interval, loc.String(), c.Query("queue"), sla)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be one of: hour, day, none"})
+			return
+		}
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving queue stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve queue stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.QueueStats{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getServerStatsHandler handles GET /stats/server requests: switch load over time from HEARTBEAT events
+func (s *Server) getServerStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	interval := c.Query("interval")
+	if interval == "none" {
+		interval = ""
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetServerStats(ctx, c.Query("node"), from, to, interval, loc.String())
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be one of: minute, hour, day, none"})
+			return
+		}
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving server stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve server stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.ServerStat{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getShortCallStatsHandler handles GET /stats/short-calls requests
+func (s *Server) getShortCallStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	params := map[string]int{"seconds": 5, "window": 60, "min_calls": 1}
+	for name, def := range params {
+		v, err := strconv.Atoi(c.DefaultQuery(name, strconv.Itoa(def)))
+		if err != nil || v < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive number"})
+			return
+		}
+		params[name] = v
+	}
+	since := time.Now().Add(-time.Duration(params["window"]) * time.Minute)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetShortCallStats(ctx, c.DefaultQuery("group_by", "gateway"), since, params["seconds"], params["min_calls"])
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidGroupBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be one of: gateway, extension"})
+			return
+		}
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving short call stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve short call stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.ShortCallStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// maxBusyHourRange bounds the hour-by-hour scan behind /stats/busy-hour
+const maxBusyHourRange = 93 * 24 * time.Hour
+
+// getBusyHourStatsHandler handles GET /stats/busy-hour requests
+func (s *Server) getBusyHourStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	if to.Sub(from) > maxBusyHourRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time range must not exceed 93 days"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetBusyHourStats(ctx, from, to, loc.String(), c.Query("gateway"))
+	if err != nil {
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving busy hour stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve busy hour stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.BusyHourStats{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getDestinationStatsHandler handles GET /stats/destinations requests
+func (s *Server) getDestinationStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetDestinationStats(ctx, c.DefaultQuery("group_by", "country"), from, to)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidGroupBy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be one of: country, group, country_group"})
+			return
+		}
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving destination stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve destination stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.DestinationStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// getBusinessHoursStatsHandler handles GET /stats/business-hours requests
+func (s *Server) getBusinessHoursStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetBusinessHoursStats(ctx, s.opts.MissedCallCauses, c.Query("domain"), from, to)
+	if err != nil {
+		if s.guardrailError(c, err) {
+			return
+		}
+		s.log.WithError(err).Error("Error retrieving business hours stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve business hours stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.BusinessHoursStats{}
+	}
+	s.cache.set(cacheKey(c), stats)
+
+	c.JSON(http.StatusOK, stats)
+}
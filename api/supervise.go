@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/esl"
+
+	"github.com/gin-gonic/gin"
+)
+
+// superviseTimeout bounds how long the API waits for the supervisor to answer
+const superviseTimeout = 30 * time.Second
+
+// superviseRequest is the body of POST /calls/:uuid/supervise
+type superviseRequest struct {
+	Extension string `json:"extension" binding:"required"` // Supervisor's extension
+	Domain    string `json:"domain"`                       // Defaults to the monitored call's domain
+	Mode      string `json:"mode"`                         // eavesdrop (default), whisper or barge
+}
+
+// requireOperator rejects requests without an operator or admin API key. These endpoints act on live
+// calls, so without any keys configured they are refused with 403.
+func (s *Server) requireOperator(c *gin.Context) {
+	if len(s.opts.AdminKeys)+len(s.opts.OperatorKeys) == 0 {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint is disabled until API_OPERATOR_KEYS or API_ADMIN_KEYS is configured"})
+		return
+	}
+	if !s.isOperator(c) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid X-API-Key is required"})
+		return
+	}
+	c.Next()
+}
+
+// superviseHandler handles POST /calls/:uuid/supervise requests. It rings the supervisor's extension,
+// connects it to the call in the requested mode and records the session in the audit log.
+func (s *Server) superviseHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	var req superviseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = esl.SuperviseEavesdrop
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), superviseTimeout+10*time.Second)
+	defer cancel()
+
+	call, ok := s.activeCall(ctx, c, uuid)
+	if !ok {
+		return
+	}
+	if req.Domain == "" && call.Domain != nil {
+		req.Domain = *call.Domain
+	}
+
+	supervise := esl.SuperviseRequest{
+		CallUUID:  uuid,
+		Extension: req.Extension,
+		Domain:    req.Domain,
+		Mode:      req.Mode,
+	}
+	if err := supervise.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.esl.Supervise(ctx, supervise)
+	if errors.Is(err, esl.ErrESLNotConnected) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ESL not connected"})
+		return
+	}
+
+	s.recordAudit(ctx, c, "supervise_"+req.Mode, uuid, map[string]any{
+		"extension":       req.Extension,
+		"domain":          req.Domain,
+		"supervisor_uuid": result.CallUUID,
+		"connected":       err == nil,
+		"cause":           result.Cause,
+	})
+
+	switch {
+	case err == nil, errors.Is(err, esl.ErrOriginateFailed):
+		c.JSON(http.StatusOK, result) // A supervisor who did not answer carries the hangup cause
+	default:
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error starting supervision")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start supervision"})
+	}
+}
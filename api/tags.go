@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tagBatchSize  = 1000 // Calls updated per statement by POST /calls/tags
+	maxTagsPerOp  = 20
+	maxTagLength  = 64
+	tagOpDeadline = 5 * time.Minute
+)
+
+// tagCallsRequest is the body of POST /calls/tags. The time range is required so a request cannot
+// tag the whole table by accident.
+type tagCallsRequest struct {
+	From         time.Time `json:"from" binding:"required"`
+	To           time.Time `json:"to" binding:"required"`
+	CallerPrefix string    `json:"caller_prefix"`
+	CalleePrefix string    `json:"callee_prefix"`
+	Direction    string    `json:"direction"`
+	Context      string    `json:"context"`
+	SIPProfile   string    `json:"sip_profile"`
+	Domain       string    `json:"domain"`
+	AccountCode  string    `json:"account_code"`
+	UserID       string    `json:"user_id"`
+	Gateway      string    `json:"gateway"`
+	Node         string    `json:"node"`
+	Tag          string    `json:"tag"`
+	Tags         []string  `json:"tags" binding:"required"`
+}
+
+// tagCallsHandler handles POST /calls/tags requests, adding tags to every call matching the filter
+func (s *Server) tagCallsHandler(c *gin.Context) {
+	var req tagCallsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.From.Before(req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	tags, ok := normalizeTags(req.Tags)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags must list 1 to 20 non-empty tags of at most 64 characters"})
+		return
+	}
+
+	filter := store.TagFilter{
+		CallFilter: store.CallFilter{
+			Context:     req.Context,
+			SIPProfile:  req.SIPProfile,
+			Domain:      req.Domain,
+			AccountCode: req.AccountCode,
+			UserID:      req.UserID,
+			Gateway:     req.Gateway,
+			Node:        req.Node,
+			Tag:         req.Tag,
+		},
+		From:         req.From,
+		To:           req.To,
+		CallerPrefix: req.CallerPrefix,
+		CalleePrefix: req.CalleePrefix,
+		Direction:    req.Direction,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), tagOpDeadline)
+	defer cancel()
+
+	matched, tagged, err := s.store.TagCalls(ctx, filter, tags, tagBatchSize)
+	if errors.Is(err, store.ErrEncryptedField) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "caller_prefix and callee_prefix are unavailable while numbers are encrypted"})
+		return
+	}
+	s.recordAudit(ctx, c, "tag_calls", "calls", map[string]any{
+		"tags":    tags,
+		"filter":  req,
+		"matched": matched,
+		"tagged":  tagged,
+	})
+	if err != nil {
+		s.log.WithError(err).Error("Error tagging calls")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag calls", "matched": matched, "tagged": tagged})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags, "matched": matched, "tagged": tagged})
+}
+
+// normalizeTags trims and de-duplicates tags, reporting false if any is empty or too long or there are
+// none or too many
+func normalizeTags(tags []string) ([]string, bool) {
+	if len(tags) == 0 || len(tags) > maxTagsPerOp {
+		return nil, false
+	}
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || len(t) > maxTagLength {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantContextKey is the gin context key holding the tenant of a request authenticated with a tenant key
+const tenantContextKey = "tenant"
+
+// tenantFor returns the tenant domain of key, comparing in constant time
+func (s *Server) tenantFor(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	for k, domain := range s.opts.TenantKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// tenantRoutes are the routes tenant keys may read. Each reads only calls and the tables keyed by them,
+// which row-level security filters; routes reading other tables, or in-memory or switch state, would
+// show a tenant everyone's data.
+var tenantRoutes = map[string]bool{
+	"/api/v1/version":                  true,
+	"/api/v1/calls":                    true,
+	"/api/v1/calls/stuck":              true,
+	"/api/v1/calls/missed":             true,
+	"/api/v1/calls/quality":            true,
+	"/api/v1/calls/:uuid":              true,
+	"/api/v1/calls/:uuid/transitions":  true,
+	"/api/v1/calls/:uuid/dtmf":         true,
+	"/api/v1/calls/:uuid/holds":        true,
+	"/api/v1/calls/:uuid/parks":        true,
+	"/api/v1/calls/:uuid/transfers":    true,
+	"/api/v1/calls/:uuid/applications": true,
+	"/api/v1/calls/:uuid/recordings":   true,
+	"/api/v1/calls/:uuid/faxes":        true,
+	"/api/v1/calls/:uuid/quality":      true,
+	"/api/v1/calls/:uuid/voicemail":    true,
+	"/api/v1/calls/:uuid/callback":     true,
+	"/api/v1/calls/:uuid/flow":         true,
+	"/api/v1/recordings":               true,
+	"/api/v1/faxes":                    true,
+	"/api/v1/voicemail":                true,
+	"/api/v1/callbacks":                true,
+	"/api/v1/searches":                 true, // Saved searches belong to the key
+	"/api/v1/searches/:name":           true,
+	"/api/v1/stats/cost":               true,
+	"/api/v1/stats/gateways":           true,
+	"/api/v1/stats/queues":             true,
+	"/api/v1/stats/short-calls":        true,
+	"/api/v1/stats/busy-hour":          true,
+	"/api/v1/stats/destinations":       true,
+	"/api/v1/stats/voicemail":          true,
+	"/api/v1/stats/business-hours":     true,
+	"/api/v1/stats/outcomes":           true,
+	"/api/v1/domains":                  true,
+	"/api/v1/domains/:domain":          true,
+	"/api/v1/domains/:domain/calls":    true,
+}
+
+// scopeTenant restricts requests carrying a tenant API key to their tenant. The tenant travels in the
+// request context to the database connection, where row-level security filters every read; tenant keys are
+// read-only and limited to tenantRoutes. With tenant keys configured, only admin and operator keys read
+// unscoped, so requests without a known key are refused rather than shown every tenant's rows.
+func (s *Server) scopeTenant(c *gin.Context) {
+	tenant, ok := s.tenantFor(c.GetHeader("X-API-Key"))
+	if !ok {
+		if len(s.opts.TenantKeys) > 0 && !s.isOperator(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid X-API-Key is required"})
+			return
+		}
+		c.Next()
+		return
+	}
+	if c.Request.Method != http.MethodGet || !tenantRoutes[c.FullPath()] {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Tenant keys are read-only and limited to call data"})
+		return
+	}
+	c.Set(tenantContextKey, tenant)
+	c.Request = c.Request.WithContext(store.WithTenant(c.Request.Context(), tenant))
+	c.Next()
+}
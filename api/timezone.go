@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLocation resolves the IANA zone from the tz query parameter, falling back to the
+// Accept-Timezone header and then UTC. ok is false (and a 400 has been written) for unknown zones.
+func requestLocation(c *gin.Context) (loc *time.Location, ok bool) {
+	name := c.Query("tz")
+	if name == "" {
+		name = c.GetHeader("Accept-Timezone")
+	}
+	if name == "" {
+		return time.UTC, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone name, e.g. Europe/Berlin"})
+		return nil, false
+	}
+	return loc, true
+}
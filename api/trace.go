@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gofreeswitchesl/store"
+)
+
+// traceWindow pads the time range passed to SIP capture tools, whose packets may precede CHANNEL_CREATE
+// and follow the hangup by a few seconds
+const traceWindow = 60
+
+// setTraceLink fills call.SIPTraceURL from the configured template. Supported placeholders are {call_id},
+// {uuid}, and {from}/{to} (Unix seconds around the call, padded by a minute). Calls without a SIP Call-ID
+// get no link.
+func (s *Server) setTraceLink(call *store.Call) {
+	if s.opts.SIPTraceURLTemplate == "" || call.SIPCallID == nil {
+		return
+	}
+	from := call.StartTime.Unix() - traceWindow
+	to := call.StartTime.Unix() + traceWindow
+	if call.EndTime != nil {
+		to = call.EndTime.Unix() + traceWindow
+	}
+	link := strings.NewReplacer(
+		"{call_id}", url.QueryEscape(*call.SIPCallID),
+		"{uuid}", url.QueryEscape(call.UUID),
+		"{from}", strconv.FormatInt(from, 10),
+		"{to}", strconv.FormatInt(to, 10),
+	).Replace(s.opts.SIPTraceURLTemplate)
+	call.SIPTraceURL = &link
+}
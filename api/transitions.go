@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStuckState  = "EARLY"
+	defaultStuckMinAge = 300 // Seconds
+)
+
+// getCallTransitionsHandler handles GET /calls/:uuid/transitions requests
+func (s *Server) getCallTransitionsHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	transitions, err := s.store.GetTransitions(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call transitions from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transitions"})
+		return
+	}
+
+	if transitions == nil {
+		transitions = []store.Transition{}
+	}
+	for i := range transitions {
+		transitions[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// getCallDTMFHandler handles GET /calls/:uuid/dtmf requests
+func (s *Server) getCallDTMFHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	digits, err := s.store.GetDTMF(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call DTMF from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve DTMF"})
+		return
+	}
+
+	if digits == nil {
+		digits = []store.DTMF{}
+	}
+	for i := range digits {
+		digits[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, digits)
+}
+
+// getCallHoldsHandler handles GET /calls/:uuid/holds requests
+func (s *Server) getCallHoldsHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	holds, err := s.store.GetHoldIntervals(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call hold intervals from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hold intervals"})
+		return
+	}
+
+	if holds == nil {
+		holds = []store.HoldInterval{}
+	}
+	for i := range holds {
+		holds[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// getCallParksHandler handles GET /calls/:uuid/parks requests
+func (s *Server) getCallParksHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	parks, err := s.store.GetParkIntervals(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call park intervals from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve park intervals"})
+		return
+	}
+
+	if parks == nil {
+		parks = []store.ParkInterval{}
+	}
+	for i := range parks {
+		parks[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, parks)
+}
+
+// getCallTransfersHandler handles GET /calls/:uuid/transfers requests
+func (s *Server) getCallTransfersHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	transfers, err := s.store.GetTransfers(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call transfers from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transfers"})
+		return
+	}
+
+	if transfers == nil {
+		transfers = []store.Transfer{}
+	}
+	for i := range transfers {
+		transfers[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+// getCallApplicationsHandler handles GET /calls/:uuid/applications requests
+func (s *Server) getCallApplicationsHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	apps, err := s.store.GetApplications(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call applications from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve applications"})
+		return
+	}
+
+	if apps == nil {
+		apps = []store.Application{}
+	}
+	for i := range apps {
+		apps[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, apps)
+}
+
+// getStuckCallsHandler handles GET /calls/stuck requests
+func (s *Server) getStuckCallsHandler(c *gin.Context) {
+	state := c.DefaultQuery("state", defaultStuckState)
+	minAgeStr := c.DefaultQuery("min_age", strconv.Itoa(defaultStuckMinAge))
+	limit, _ := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	minAge, err := strconv.Atoi(minAgeStr)
+	if err != nil || minAge < 0 {
+		minAge = defaultStuckMinAge
+		s.log.Warnf("Invalid min_age value '%s', using default %d", minAgeStr, minAge)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stuck, err := s.store.GetStuckCalls(ctx, state, time.Duration(minAge)*time.Second, limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving stuck calls from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stuck calls"})
+		return
+	}
+
+	if stuck == nil {
+		stuck = []store.StuckCall{}
+	}
+	for i := range stuck {
+		stuck[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, stuck)
+}
+
+// getCallFlowHandler handles GET /calls/:uuid/flow requests, returning every call bridged to the call
+// (directly or through transfers) and the bridges between them
+func (s *Server) getCallFlowHandler(c *gin.Context) {
+	uuid := c.Param("uuid")
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	flow, err := s.store.GetCallFlow(ctx, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error retrieving call flow from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve call flow"})
+		return
+	}
+	if len(flow.Calls) == 0 && len(flow.Legs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Call not found"})
+		return
+	}
+
+	if flow.Legs == nil {
+		flow.Legs = []store.CallLeg{}
+	}
+	if flow.Calls == nil {
+		flow.Calls = []store.Call{}
+	}
+	for i := range flow.Calls {
+		flow.Calls[i].In(loc)
+		s.setTraceLink(&flow.Calls[i])
+	}
+	for i := range flow.Legs {
+		flow.Legs[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, flow)
+}
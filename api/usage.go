@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+var quotaRejectionsCounter = metrics.NewCounterVec("api_quota_rejections_total", "API requests refused because the key's monthly quota was used up.", "key")
+
+// anonymousUsage is the usage key of requests without a recognised API key
+const anonymousUsage = "anonymous"
+
+// Quota limits one API key per calendar month (UTC); a zero field is unlimited
+type Quota struct {
+	Requests      int64 `json:"requests,omitempty"`
+	ResponseBytes int64 `json:"response_bytes,omitempty"`
+}
+
+// ParseQuotas parses API_KEY_QUOTAS entries, key=requests or key=requests:bytes, into quotas by key
+// fingerprint so the keys themselves are not kept
+func ParseQuotas(pairs map[string]string) (map[string]Quota, error) {
+	quotas := make(map[string]Quota, len(pairs))
+	for key, value := range pairs {
+		requests, size, _ := strings.Cut(value, ":")
+		var q Quota
+		var err error
+		if q.Requests, err = strconv.ParseInt(strings.TrimSpace(requests), 10, 64); err != nil || q.Requests < 0 {
+			return nil, fmt.Errorf("invalid request quota %q", requests)
+		}
+		if size != "" {
+			if q.ResponseBytes, err = strconv.ParseInt(strings.TrimSpace(size), 10, 64); err != nil || q.ResponseBytes < 0 {
+				return nil, fmt.Errorf("invalid byte quota %q", size)
+			}
+		}
+		quotas[keyFingerprint(key)] = q
+	}
+	return quotas, nil
+}
+
+// keyFingerprint is the short digest of an API key shown in the audit log and usage reports
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// UsageMeter counts API requests and response bytes per key and calendar month, flushing the counts to the
+// store periodically, and refuses requests from keys that have used up their quota. Totals are refreshed
+// from the store on each flush, so with several instances quotas hold to within one flush interval.
+type UsageMeter struct {
+	store  *store.Store
+	quotas map[string]Quota // By key fingerprint
+	log    *logrus.Logger
+
+	flushMu sync.Mutex // Serializes flushes
+
+	mu       sync.Mutex
+	month    time.Time                               // Month totals belongs to
+	totals   map[string]store.APIUsage               // Stored totals for month, by key ID
+	flushing map[string]store.APIUsage               // Counts being written by the current flush, for month
+	pending  map[time.Time]map[string]store.APIUsage // Counts not yet written, by month and key ID
+}
+
+// NewUsageMeter creates a UsageMeter enforcing quotas (see ParseQuotas); call Run to persist the counts
+func NewUsageMeter(s *store.Store, quotas map[string]Quota, logger *logrus.Logger) *UsageMeter {
+	return &UsageMeter{
+		store:    s,
+		quotas:   quotas,
+		log:      logger,
+		month:    store.UsageMonth(time.Now()),
+		totals:   make(map[string]store.APIUsage),
+		flushing: make(map[string]store.APIUsage),
+		pending:  make(map[time.Time]map[string]store.APIUsage),
+	}
+}
+
+// Run loads this month's totals, then flushes the counts every interval until ctx is done, and once more
+// on the way out
+func (m *UsageMeter) Run(ctx context.Context, interval time.Duration) {
+	month := store.UsageMonth(time.Now())
+	if totals, err := m.store.GetAPIUsage(ctx, month); err != nil {
+		m.log.WithError(err).Warn("Failed to load API usage; quotas count from zero until the first flush")
+	} else {
+		m.setTotals(month, totals)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			if err := m.Flush(flushCtx); err != nil {
+				m.log.WithError(err).Error("Failed to flush API usage on shutdown")
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.log.WithError(err).Warn("Failed to flush API usage; keeping the counts for the next flush")
+			}
+		}
+	}
+}
+
+// Flush writes the pending counts to the store and refreshes the totals
+func (m *UsageMeter) Flush(ctx context.Context) error {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[time.Time]map[string]store.APIUsage)
+	if current, ok := pending[m.month]; ok {
+		m.flushing = current
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for month, usage := range pending {
+		counts := make([]store.APIUsage, 0, len(usage))
+		for _, u := range usage {
+			counts = append(counts, u)
+		}
+		totals, err := m.store.AddAPIUsage(ctx, month, counts)
+		if err != nil {
+			m.restore(month, usage)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		m.setTotals(month, totals)
+	}
+	return firstErr
+}
+
+// setTotals records stored totals for month, starting a new month's totals when month is newer. The
+// totals include the counts being flushed, which stop being added separately.
+func (m *UsageMeter) setTotals(month time.Time, totals []store.APIUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if month.After(m.month) {
+		m.month = month
+		m.totals = make(map[string]store.APIUsage)
+	}
+	if !month.Equal(m.month) {
+		return
+	}
+	for _, t := range totals {
+		m.totals[t.KeyID] = t
+	}
+	m.flushing = make(map[string]store.APIUsage)
+}
+
+// restore puts counts that could not be written back into pending
+func (m *UsageMeter) restore(month time.Time, usage map[string]store.APIUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if month.Equal(m.month) {
+		m.flushing = make(map[string]store.APIUsage)
+	}
+	for keyID, u := range usage {
+		m.addPending(month, keyID, u.Role, u.Requests, u.ResponseBytes)
+	}
+}
+
+// addPending adds counts for keyID to pending; m.mu must be held
+func (m *UsageMeter) addPending(month time.Time, keyID, role string, requests, size int64) {
+	byKey, ok := m.pending[month]
+	if !ok {
+		byKey = make(map[string]store.APIUsage)
+		m.pending[month] = byKey
+	}
+	u := byKey[keyID]
+	u.KeyID, u.Role, u.Month = keyID, role, month
+	u.Requests += requests
+	u.ResponseBytes += size
+	byKey[keyID] = u
+}
+
+// record counts one request from keyID and the size of its response
+func (m *UsageMeter) record(keyID, role string, size int64) {
+	month := store.UsageMonth(time.Now())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if month.After(m.month) {
+		m.month = month
+		m.totals = make(map[string]store.APIUsage)
+	}
+	m.addPending(month, keyID, role, 1, size)
+}
+
+// used returns keyID's usage so far this month, including counts not yet flushed
+func (m *UsageMeter) used(keyID string) Quota {
+	month := store.UsageMonth(time.Now())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !month.Equal(m.month) {
+		return Quota{} // The month has just turned over
+	}
+	var used Quota
+	for _, u := range []store.APIUsage{m.totals[keyID], m.flushing[keyID], m.pending[month][keyID]} {
+		used.Requests += u.Requests
+		used.ResponseBytes += u.ResponseBytes
+	}
+	return used
+}
+
+// quotaFor returns the quota of the key with fingerprint fp
+func (m *UsageMeter) quotaFor(fp string) (Quota, bool) {
+	q, ok := m.quotas[fp]
+	return q, ok
+}
+
+// usageKey identifies the API key of a request for usage accounting by role and fingerprint, as in the
+// audit log. Requests without a configured key are counted together as anonymous.
+func (s *Server) usageKey(key string) (keyID, role, fp string) {
+	switch {
+	case matchesKey(key, s.opts.AdminKeys):
+		role = "admin"
+	case matchesKey(key, s.opts.OperatorKeys):
+		role = "operator"
+	default:
+		if _, ok := s.tenantFor(key); !ok {
+			return anonymousUsage, anonymousUsage, ""
+		}
+		role = "tenant"
+	}
+	fp = keyFingerprint(key)
+	return role + ":" + fp, role, fp
+}
+
+// meterUsage counts each API request and its response size against the caller's key, refusing requests
+// with 429 once the key's monthly quota is used up
+func (s *Server) meterUsage(c *gin.Context) {
+	m := s.opts.Usage
+	if m == nil {
+		c.Next()
+		return
+	}
+	keyID, role, fp := s.usageKey(c.GetHeader("X-API-Key"))
+
+	if quota, ok := m.quotaFor(fp); ok {
+		used := m.used(keyID)
+		if (quota.Requests > 0 && used.Requests >= quota.Requests) ||
+			(quota.ResponseBytes > 0 && used.ResponseBytes >= quota.ResponseBytes) {
+			resetsAt := store.UsageMonth(time.Now()).AddDate(0, 1, 0)
+			quotaRejectionsCounter.With(keyID).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(resetsAt).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":     "Monthly API quota exceeded",
+				"quota":     quota,
+				"used":      used,
+				"resets_at": resetsAt,
+			})
+			return
+		}
+		if quota.Requests > 0 {
+			c.Header("X-Quota-Requests-Remaining", strconv.FormatInt(quota.Requests-used.Requests-1, 10))
+		}
+		if quota.ResponseBytes > 0 {
+			c.Header("X-Quota-Bytes-Remaining", strconv.FormatInt(quota.ResponseBytes-used.ResponseBytes, 10))
+		}
+	}
+
+	c.Next()
+	m.record(keyID, role, int64(max(c.Writer.Size(), 0)))
+}
+
+// keyUsage is an entry of GET /admin/usage
+type keyUsage struct {
+	store.APIUsage
+	Quota *Quota `json:"quota,omitempty"`
+}
+
+// getUsageHandler handles GET /admin/usage requests: requests and response bytes per API key for a
+// calendar month (month=YYYY-MM, UTC; default the current month), with each key's quota
+func (s *Server) getUsageHandler(c *gin.Context) {
+	m := s.opts.Usage
+	if m == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API usage accounting is not available"})
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	month := store.UsageMonth(time.Now())
+	if raw := c.Query("month"); raw != "" {
+		parsed, err := time.Parse("2006-01", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month must be formatted as YYYY-MM"})
+			return
+		}
+		month = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// Include the counts not flushed yet; a failure only makes the report slightly stale
+	if err := m.Flush(ctx); err != nil {
+		s.log.WithError(err).Warn("Failed to flush API usage before reporting it")
+	}
+	usage, err := s.store.GetAPIUsage(ctx, month)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving API usage from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API usage"})
+		return
+	}
+
+	keys := make([]keyUsage, 0, len(usage))
+	for _, u := range usage {
+		u.In(loc)
+		entry := keyUsage{APIUsage: u}
+		if _, fp, ok := strings.Cut(u.KeyID, ":"); ok {
+			if q, ok := m.quotaFor(fp); ok {
+				entry.Quota = &q
+			}
+		}
+		keys = append(keys, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"month": month.Format("2006-01"),
+		"keys":  keys,
+	})
+}
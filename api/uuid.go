@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeUUID returns s in canonical lowercase 8-4-4-4-12 form. Upper case, surrounding braces, a
+// "urn:uuid:" prefix and missing hyphens are accepted. ok is false when s is not a UUID.
+func normalizeUUID(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "urn:uuid:")
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	hex := strings.ReplaceAll(s, "-", "")
+	if len(hex) != 32 || (len(s) != 32 && len(s) != 36) {
+		return "", false
+	}
+	if len(s) == 36 && (s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-') {
+		return "", false
+	}
+	for _, r := range hex {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", false
+		}
+	}
+	return hex[0:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:32], true
+}
+
+// isUUIDKey reports whether a path or query parameter carries a call UUID
+func isUUIDKey(key string) bool {
+	return key == "uuid" || strings.HasSuffix(key, "_uuid")
+}
+
+// normalizeUUIDs validates every UUID path parameter (:uuid) and query parameter (uuid, *_uuid) before
+// the handler runs, rewriting them to canonical lowercase form so lookups match however the client
+// formatted them. Malformed values are rejected with 400.
+func normalizeUUIDs(c *gin.Context) {
+	for i, p := range c.Params {
+		if !isUUIDKey(p.Key) {
+			continue
+		}
+		v, ok := normalizeUUID(p.Value)
+		if !ok {
+			rejectUUID(c, p.Key, p.Value)
+			return
+		}
+		c.Params[i].Value = v
+	}
+
+	query := c.Request.URL.Query()
+	changed := false
+	for key, values := range query {
+		if !isUUIDKey(key) {
+			continue
+		}
+		for i, value := range values {
+			if value == "" {
+				continue
+			}
+			v, ok := normalizeUUID(value)
+			if !ok {
+				rejectUUID(c, key, value)
+				return
+			}
+			changed = changed || v != value
+			values[i] = v
+		}
+	}
+	if changed {
+		c.Request.URL.RawQuery = query.Encode()
+	}
+	c.Next()
+}
+
+// rejectUUID aborts the request with a 400 naming the malformed parameter
+func rejectUUID(c *gin.Context, key, value string) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"error":     key + " must be a UUID, e.g. 3f2c9e1a-5b7d-4c8e-9a0f-1b2c3d4e5f60",
+		"parameter": key,
+		"value":     value,
+	})
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"gofreeswitchesl/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionResponse is the build information with the resolved feature flags
+type versionResponse struct {
+	buildinfo.Info
+	Flags map[string]bool `json:"flags"`
+}
+
+// getVersionHandler handles GET /version requests
+func (s *Server) getVersionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, versionResponse{Info: buildinfo.Get(), Flags: s.opts.Features.Map()})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getVertoSessionsHandler handles GET /verto/sessions requests
+func (s *Server) getVertoSessionsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	activeOnly, err := strconv.ParseBool(c.DefaultQuery("active", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "active must be true or false"})
+		return
+	}
+	filter := store.VertoSessionFilter{
+		Login:      c.Query("login"),
+		ActiveOnly: activeOnly,
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	sessions, err := s.store.GetVertoSessions(ctx, filter, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving verto sessions from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve verto sessions"})
+		return
+	}
+
+	if sessions == nil {
+		sessions = []store.VertoSession{}
+	}
+	for i := range sessions {
+		sessions[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getVoicemailEventsHandler handles GET /voicemail requests
+func (s *Server) getVoicemailEventsHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	filter := store.VoicemailFilter{
+		Mailbox: c.Query("mailbox"),
+		Domain:  c.Query("domain"),
+		Kind:    c.Query("kind"),
+	}
+	if filter.Kind != "" && filter.Kind != store.VoicemailLeft && filter.Kind != store.VoicemailRead {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be left or read"})
+		return
+	}
+
+	s.serveVoicemailEvents(c, filter, from, to, limit, offset, loc)
+}
+
+// getCallVoicemailHandler handles GET /calls/:uuid/voicemail requests: voicemail events raised on the call's channel
+func (s *Server) getCallVoicemailHandler(c *gin.Context) {
+	limit, offset := s.parsePagination(c)
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+	filter := store.VoicemailFilter{CallUUID: c.Param("uuid")}
+
+	s.serveVoicemailEvents(c, filter, time.Unix(0, 0), time.Now().Add(time.Hour), limit, offset, loc)
+}
+
+// serveVoicemailEvents writes the voicemail events matching filter in [from, to)
+func (s *Server) serveVoicemailEvents(c *gin.Context, filter store.VoicemailFilter, from, to time.Time, limit, offset int, loc *time.Location) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	events, err := s.store.GetVoicemailEvents(ctx, filter, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving voicemail events from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve voicemail events"})
+		return
+	}
+
+	if events == nil {
+		events = []store.VoicemailEvent{}
+	}
+	for i := range events {
+		events[i].In(loc)
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// getVoicemailStatsHandler handles GET /stats/voicemail requests
+func (s *Server) getVoicemailStatsHandler(c *gin.Context) {
+	if s.serveCached(c) {
+		return
+	}
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+	loc, ok := requestLocation(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := s.store.GetMailboxStats(ctx, from, to, c.Query("domain"))
+	if err != nil {
+		s.log.WithError(err).Error("Error retrieving mailbox stats from store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mailbox stats"})
+		return
+	}
+
+	if stats == nil {
+		stats = []store.MailboxStats{}
+	}
+	for i := range stats {
+		stats[i].In(loc)
+	}
+
+	s.cache.set(cacheKey(c), stats)
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,99 @@
+// Package buildinfo reports which build of the logger is running. The values are set at build time with
+//
+//	go build -ldflags "-X gofreeswitchesl/buildinfo.Version=v1.4.0 -X gofreeswitchesl/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X gofreeswitchesl/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ) -X gofreeswitchesl/buildinfo.Features=spool,rules"
+//
+// and fall back to the VCS information the Go toolchain embeds when they are not.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set with -ldflags -X at build time
+var (
+	Version  = "dev"
+	Commit   = ""
+	Date     = ""
+	Features = "" // Comma-separated feature flags enabled for this build
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	BuildDate string   `json:"build_date,omitempty"`
+	Modified  bool     `json:"modified,omitempty"` // Built from a working tree with uncommitted changes
+	GoVersion string   `json:"go_version"`
+	Features  []string `json:"features"` // Feature flags and build tags
+}
+
+// Get returns the build information
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: Date,
+		GoVersion: runtime.Version(),
+		Features:  splitList(Features),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value // Commit time; the closest the toolchain records to a build date
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		case "-tags":
+			info.Features = append(info.Features, splitList(s.Value)...)
+		}
+	}
+	return info
+}
+
+// String formats the build information on one line
+func (i Info) String() string {
+	var b strings.Builder
+	b.WriteString(i.Version)
+	if i.Commit != "" {
+		b.WriteString(" (" + i.Commit)
+		if i.Modified {
+			b.WriteString(", modified")
+		}
+		b.WriteString(")")
+	}
+	if i.BuildDate != "" {
+		b.WriteString(" built " + i.BuildDate)
+	}
+	b.WriteString(" " + i.GoVersion)
+	if len(i.Features) > 0 {
+		b.WriteString(" features: " + strings.Join(i.Features, ","))
+	}
+	return b.String()
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,172 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTenant is the key of the calendar applied to domains without one of their own
+const DefaultTenant = "default"
+
+// Definition is the JSON form of a business-hours calendar
+type Definition struct {
+	Timezone string              `json:"timezone"` // IANA name, e.g. "Europe/London"; default UTC
+	Hours    map[string][]string `json:"hours"`    // Weekday ("mon".."sun") to "HH:MM-HH:MM" intervals
+	Holidays []string            `json:"holidays"` // "YYYY-MM-DD" dates closed all day
+}
+
+// interval is an opening period within a day, in minutes since midnight
+type interval struct {
+	start, end int
+}
+
+// Calendar holds the business hours of one tenant
+type Calendar struct {
+	loc      *time.Location
+	hours    [7][]interval // Indexed by time.Weekday
+	holidays map[string]bool
+}
+
+// Calendars maps tenants (call domains) to their business-hours calendars
+type Calendars struct {
+	tenants map[string]*Calendar
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Load reads a JSON object of calendars keyed by tenant domain from path. The "default" calendar
+// applies to every domain without its own.
+func Load(path string) (*Calendars, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defs map[string]Definition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse calendars: %w", err)
+	}
+
+	cals := &Calendars{tenants: make(map[string]*Calendar, len(defs))}
+	for tenant, def := range defs {
+		cal, err := compile(def)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %q: %w", tenant, err)
+		}
+		cals.tenants[strings.ToLower(tenant)] = cal
+	}
+	return cals, nil
+}
+
+// compile validates a Definition and converts it to a Calendar
+func compile(def Definition) (*Calendar, error) {
+	cal := &Calendar{loc: time.UTC, holidays: make(map[string]bool, len(def.Holidays))}
+	if def.Timezone != "" {
+		loc, err := time.LoadLocation(def.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+		cal.loc = loc
+	}
+	for day, specs := range def.Hours {
+		wd, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", day)
+		}
+		for _, spec := range specs {
+			iv, err := parseInterval(spec)
+			if err != nil {
+				return nil, err
+			}
+			cal.hours[wd] = append(cal.hours[wd], iv)
+		}
+	}
+	for _, date := range def.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("holiday %q: expected YYYY-MM-DD", date)
+		}
+		cal.holidays[date] = true
+	}
+	return cal, nil
+}
+
+// parseInterval parses "HH:MM-HH:MM". The end may be 24:00; intervals never span midnight.
+func parseInterval(spec string) (interval, error) {
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return interval{}, fmt.Errorf("interval %q: expected HH:MM-HH:MM", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return interval{}, fmt.Errorf("interval %q: %w", spec, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return interval{}, fmt.Errorf("interval %q: %w", spec, err)
+	}
+	if end <= start {
+		return interval{}, fmt.Errorf("interval %q: end must be after start", spec)
+	}
+	return interval{start: start, end: end}, nil
+}
+
+// parseClock returns the minutes since midnight of "HH:MM"
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// Enabled reports whether any calendar has been loaded
+func (c *Calendars) Enabled() bool {
+	return c != nil && len(c.tenants) > 0
+}
+
+// For returns the calendar of domain, falling back to the default calendar
+func (c *Calendars) For(domain string) (*Calendar, bool) {
+	if !c.Enabled() {
+		return nil, false
+	}
+	if cal, ok := c.tenants[strings.ToLower(domain)]; ok {
+		return cal, true
+	}
+	cal, ok := c.tenants[DefaultTenant]
+	return cal, ok
+}
+
+// InHours reports whether t falls within the business hours of domain's calendar. ok is false when
+// no calendar applies to the domain.
+func (c *Calendars) InHours(domain string, t time.Time) (in, ok bool) {
+	cal, ok := c.For(domain)
+	if !ok {
+		return false, false
+	}
+	return cal.InHours(t), true
+}
+
+// InHours reports whether t falls within the calendar's business hours. Holidays are closed all day.
+func (cal *Calendar) InHours(t time.Time) bool {
+	local := t.In(cal.loc)
+	if cal.holidays[local.Format(time.DateOnly)] {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, iv := range cal.hours[local.Weekday()] {
+		if minute >= iv.start && minute < iv.end {
+			return true
+		}
+	}
+	return false
+}
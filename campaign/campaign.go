@@ -0,0 +1,264 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/esl"
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Limits applied to campaign requests
+const (
+	MaxNumbers     = 10000
+	MaxConcurrency = 50
+	MaxTimeout     = 110 // Seconds; originate results must arrive within the bgapi job timeout
+)
+
+// NumberPlaceholder is replaced with each dialled number in the endpoint template
+const NumberPlaceholder = "{number}"
+
+// ErrInvalidCampaign is returned for campaign requests that fail validation
+var ErrInvalidCampaign = errors.New("invalid campaign")
+
+// Dialer places outbound calls; implemented by *esl.Client and *esl.Cluster
+type Dialer interface {
+	Originate(ctx context.Context, req esl.OriginateRequest) (esl.OriginateResult, error)
+}
+
+// Manager runs campaigns in the background and tracks them so they can be cancelled
+type Manager struct {
+	ctx    context.Context // Cancelled on shutdown, stopping every campaign
+	store  *store.Store
+	dialer Dialer
+	log    *logrus.Logger
+
+	mu      sync.Mutex
+	running map[int64]context.CancelFunc
+}
+
+// NewManager creates a Manager. Campaigns stop when ctx is cancelled. Campaigns left running by a
+// previous process are marked interrupted; they are not resumed.
+func NewManager(ctx context.Context, s *store.Store, dialer Dialer, logger *logrus.Logger) *Manager {
+	if n, err := s.InterruptRunningCampaigns(ctx); err == nil && n > 0 {
+		logger.WithField("count", n).Warn("Marked campaigns from a previous run as interrupted")
+	}
+	return &Manager{
+		ctx:     ctx,
+		store:   s,
+		dialer:  dialer,
+		log:     logger,
+		running: make(map[int64]context.CancelFunc),
+	}
+}
+
+// validNumber reports whether number only contains dialable characters
+func validNumber(number string) bool {
+	if number == "" {
+		return false
+	}
+	for _, r := range number {
+		if !(r >= '0' && r <= '9') && !strings.ContainsRune("+*#", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks a campaign request and fills in defaults
+func validate(c *store.Campaign, numbers []string) error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCampaign)
+	}
+	if !strings.Contains(c.EndpointTemplate, NumberPlaceholder) {
+		return fmt.Errorf("%w: endpoint must contain %s", ErrInvalidCampaign, NumberPlaceholder)
+	}
+	if len(numbers) == 0 || len(numbers) > MaxNumbers {
+		return fmt.Errorf("%w: between 1 and %d numbers are required", ErrInvalidCampaign, MaxNumbers)
+	}
+	for _, number := range numbers {
+		if !validNumber(number) {
+			return fmt.Errorf("%w: invalid number %q", ErrInvalidCampaign, number)
+		}
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.Concurrency > MaxConcurrency {
+		return fmt.Errorf("%w: concurrency may not exceed %d", ErrInvalidCampaign, MaxConcurrency)
+	}
+	if c.IntervalMS < 0 {
+		return fmt.Errorf("%w: interval_ms may not be negative", ErrInvalidCampaign)
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = 30
+	}
+	if c.TimeoutSeconds > MaxTimeout {
+		return fmt.Errorf("%w: timeout_seconds may not exceed %d", ErrInvalidCampaign, MaxTimeout)
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 1
+	}
+	if err := policy(c).Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	if c.ConfirmTimeoutSeconds < 0 || c.ConfirmTimeoutSeconds > MaxTimeout {
+		return fmt.Errorf("%w: confirm_timeout_seconds must be between 0 and %d", ErrInvalidCampaign, MaxTimeout)
+	}
+
+	// Render one request up front so malformed templates or caller IDs are rejected before anything is stored
+	if err := request(c, numbers[0]).Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	return nil
+}
+
+// request builds the originate request for one number of c
+func request(c *store.Campaign, number string) esl.OriginateRequest {
+	var confirm *esl.Confirmation
+	if c.ConfirmKey != "" {
+		confirm = &esl.Confirmation{
+			Key:     c.ConfirmKey,
+			Prompt:  c.ConfirmPrompt,
+			Timeout: time.Duration(c.ConfirmTimeoutSeconds) * time.Second,
+		}
+	}
+	return esl.OriginateRequest{
+		Endpoint:       strings.ReplaceAll(c.EndpointTemplate, NumberPlaceholder, number),
+		Destination:    c.Destination,
+		CallerIDNumber: c.CallerIDNumber,
+		CallerIDName:   c.CallerIDName,
+		Timeout:        time.Duration(c.TimeoutSeconds) * time.Second,
+		Variables: map[string]string{
+			"campaign_id": fmt.Sprintf("%d", c.ID),
+		},
+		Confirm: confirm,
+	}
+}
+
+// policy returns the retry policy of c
+func policy(c *store.Campaign) esl.RetryPolicy {
+	return esl.RetryPolicy{
+		MaxAttempts: c.MaxAttempts,
+		Interval:    time.Duration(c.RetryIntervalSeconds) * time.Second,
+		OnCauses:    c.RetryCauses,
+	}
+}
+
+// Start validates and stores c with one attempt per number, then dials them in the background
+func (m *Manager) Start(ctx context.Context, c *store.Campaign, numbers []string) error {
+	if err := validate(c, numbers); err != nil {
+		return err
+	}
+	attempts, err := m.store.CreateCampaign(ctx, c, numbers)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(m.ctx)
+	m.mu.Lock()
+	m.running[c.ID] = cancel
+	m.mu.Unlock()
+
+	go m.run(runCtx, *c, attempts)
+	return nil
+}
+
+// Cancel stops a running campaign, reporting whether it was running
+func (m *Manager) Cancel(id int64) bool {
+	m.mu.Lock()
+	cancel, ok := m.running[id]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// run dials the attempts of c, starting at most one every IntervalMS with at most Concurrency in progress
+func (m *Manager) run(ctx context.Context, c store.Campaign, attempts []store.CampaignAttempt) {
+	log := m.log.WithField("campaignID", c.ID)
+	log.WithField("attempts", len(attempts)).Info("Campaign started")
+
+	slots := make(chan struct{}, c.Concurrency)
+	var wg sync.WaitGroup
+	interval := time.Duration(c.IntervalMS) * time.Millisecond
+
+dial:
+	for i, attempt := range attempts {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				break dial
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			break dial
+		}
+
+		wg.Add(1)
+		go func(attempt store.CampaignAttempt) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			m.dial(ctx, c, attempt)
+		}(attempt)
+	}
+	wg.Wait()
+
+	status := store.CampaignCompleted
+	if ctx.Err() != nil {
+		status = store.CampaignCancelled
+	}
+	// The run context may be cancelled; the final bookkeeping must still happen
+	finishCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.store.FinishCampaign(finishCtx, c.ID, status); err != nil {
+		log.WithError(err).Error("Failed to record campaign result")
+	}
+
+	m.mu.Lock()
+	delete(m.running, c.ID)
+	m.mu.Unlock()
+}
+
+// dial places a single attempt, retrying per the campaign's policy, and records its outcome
+func (m *Manager) dial(ctx context.Context, c store.Campaign, attempt store.CampaignAttempt) {
+	log := m.log.WithFields(logrus.Fields{
+		"campaignID": c.ID,
+		"attemptID":  attempt.ID,
+		"number":     m.store.Redacted(attempt.Number),
+	})
+	bookkeeping := context.Background() // Results are recorded even if the campaign is cancelled mid-call
+
+	_ = m.store.StartCampaignAttempt(bookkeeping, attempt.ID)
+	results, err := esl.OriginateWithRetry(ctx, m.dialer, request(&c, attempt.Number), policy(&c))
+	result := results[len(results)-1]
+
+	status, cause := store.AttemptAnswered, ""
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		status, cause = store.AttemptCancelled, "campaign cancelled"
+	case errors.Is(err, esl.ErrOriginateFailed):
+		status, cause = store.AttemptFailed, result.Cause
+	default:
+		status, cause = store.AttemptFailed, err.Error()
+	}
+	if err := m.store.FinishCampaignAttempt(bookkeeping, attempt.ID, status, result.CallUUID, cause, len(results)); err != nil {
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"status": status,
+		"cause":  cause,
+		"tries":  len(results),
+	}).Info("Campaign attempt finished")
+}
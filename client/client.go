@@ -0,0 +1,158 @@
+// Package client is a Go client for the call logger's HTTP API, following the OpenAPI spec in
+// openapi.yaml. It depends only on the standard library so integrators can import it without pulling in
+// the service.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the /api/v1 endpoints of one logger instance
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sends key in the X-API-Key header of every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces the default HTTP client, which times out after 30 seconds
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// New creates a Client for the logger at baseURL, e.g. "http://logger:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	Message    string // The response's "error" field, or its body if it has none
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api: %d %s", e.StatusCode, e.Message)
+}
+
+// ErrNotFound is matched by errors.Is for 404 responses
+var ErrNotFound = errors.New("not found")
+
+// Is makes errors.Is(err, ErrNotFound) true for 404 responses
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// ListCalls returns calls matching filter, newest first
+func (c *Client) ListCalls(ctx context.Context, filter CallFilter) ([]Call, error) {
+	var calls []Call
+	err := c.get(ctx, "/calls", filter.query(), &calls)
+	return calls, err
+}
+
+// GetCall returns the call with the given UUID; errors.Is(err, ErrNotFound) for unknown calls
+func (c *Client) GetCall(ctx context.Context, uuid string) (*Call, error) {
+	var call Call
+	if err := c.get(ctx, "/calls/"+url.PathEscape(uuid), nil, &call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// GetCostStats returns call counts and cost grouped by groupBy (extension, domain or destination_prefix).
+// prefixLen applies to destination_prefix; 0 uses the server default.
+func (c *Client) GetCostStats(ctx context.Context, groupBy string, prefixLen int) ([]CostStats, error) {
+	q := url.Values{}
+	if groupBy != "" {
+		q.Set("group_by", groupBy)
+	}
+	if prefixLen > 0 {
+		q.Set("prefix_len", strconv.Itoa(prefixLen))
+	}
+	var stats []CostStats
+	err := c.get(ctx, "/stats/cost", q, &stats)
+	return stats, err
+}
+
+// GetServerStats returns FreeSWITCH load samples in [from, to), aggregated by interval (minute, hour, day,
+// or "" for raw samples). An empty node includes every node; zero times use the server defaults.
+func (c *Client) GetServerStats(ctx context.Context, node string, from, to time.Time, interval string) ([]ServerStats, error) {
+	q := url.Values{}
+	setString(q, "node", node)
+	setTime(q, "from", from)
+	setTime(q, "to", to)
+	setString(q, "interval", interval)
+	var stats []ServerStats
+	err := c.get(ctx, "/stats/server", q, &stats)
+	return stats, err
+}
+
+// get performs a GET on path and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+			apiErr.Message = payload.Error
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func setString(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
+
+func setTime(q url.Values, key string, t time.Time) {
+	if !t.IsZero() {
+		q.Set(key, t.Format(time.RFC3339))
+	}
+}
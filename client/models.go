@@ -0,0 +1,180 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Call is a call record, as returned by GET /calls and GET /calls/{uuid}
+type Call struct {
+	ID           int        `json:"id"`
+	UUID         string     `json:"uuid"`
+	Direction    string     `json:"direction"`
+	Caller       string     `json:"caller"`
+	CallerName   *string    `json:"caller_name,omitempty"`
+	Callee       string     `json:"callee"`
+	CalleeName   *string    `json:"callee_name,omitempty"`
+	StartTime    time.Time  `json:"start_time"`
+	RingingTime  *time.Time `json:"ringing_time,omitempty"`
+	EarlyMedia   *time.Time `json:"early_media_time,omitempty"`
+	AnsweredTime *time.Time `json:"answered_time,omitempty"`
+	BridgedTime  *time.Time `json:"bridged_time,omitempty"`
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	Status       *string    `json:"status,omitempty"` // Hangup cause once the call has ended
+	Context      *string    `json:"context,omitempty"`
+	SIPProfile   *string    `json:"sip_profile,omitempty"`
+	Domain       *string    `json:"domain,omitempty"`
+	AccountCode  *string    `json:"account_code,omitempty"`
+	UserID       *string    `json:"user_id,omitempty"`
+	Gateway      *string    `json:"gateway,omitempty"`
+	Node         *string    `json:"node,omitempty"` // FreeSWITCH server that reported the call
+	SIPCallID    *string    `json:"sip_call_id,omitempty"`
+	SIPTraceURL  *string    `json:"sip_trace_url,omitempty"`
+	DestCountry  *string    `json:"destination_country,omitempty"`
+	DestGroup    *string    `json:"destination_group,omitempty"`
+
+	Billsec     *int64    `json:"billsec,omitempty"`
+	Duration    *int64    `json:"duration,omitempty"`
+	Progresssec *int64    `json:"progresssec,omitempty"`
+	PDD         *float64  `json:"pdd,omitempty"`
+	RingTime    *float64  `json:"ring_time,omitempty"`
+	Cost        *float64  `json:"cost,omitempty"`
+	HoldSeconds float64   `json:"hold_seconds"`
+	HoldCount   int       `json:"hold_count"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	TransferredTo    *string  `json:"transferred_to,omitempty"`
+	TransferredBy    *string  `json:"transferred_by,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	CallerIDCampaign *string  `json:"caller_id_campaign,omitempty"`
+	BusinessHours    *bool    `json:"business_hours,omitempty"`
+
+	HangupCauseQ850        *int       `json:"hangup_cause_q850,omitempty"`
+	SIPTermStatus          *int       `json:"sip_term_status,omitempty"`
+	SIPInviteFailureStatus *int       `json:"sip_invite_failure_status,omitempty"`
+	Outcome                *string    `json:"outcome,omitempty"`
+	OutcomeSource          *string    `json:"outcome_source,omitempty"`
+	ClassifiedAt           *time.Time `json:"classified_at,omitempty"`
+
+	SIPHeaders map[string]string `json:"sip_headers,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+
+	VertoSessionID     *string `json:"verto_session_id,omitempty"`
+	VertoClientAddress *string `json:"verto_client_address,omitempty"`
+	VertoUserAgent     *string `json:"verto_user_agent,omitempty"`
+
+	RetryOf      *string `json:"retry_of,omitempty"`
+	RetryAttempt *int    `json:"retry_attempt,omitempty"`
+
+	ParkedSeconds   float64 `json:"parked_seconds"`
+	ParkCount       int     `json:"park_count"`
+	RetrievedBy     *string `json:"retrieved_by,omitempty"`
+	RetrievedByUUID *string `json:"retrieved_by_uuid,omitempty"`
+
+	LateCorrections int        `json:"late_corrections"`
+	LastCorrectedAt *time.Time `json:"last_corrected_at,omitempty"`
+
+	Derived map[string]string `json:"derived,omitempty"`
+	Custom  map[string]any    `json:"custom,omitempty"`
+}
+
+// CallFilter narrows GET /calls. Empty fields are not sent.
+type CallFilter struct {
+	Limit  int
+	Offset int
+
+	Context     string
+	SIPProfile  string
+	Domain      string
+	AccountCode string
+	UserID      string
+	Gateway     string
+	Node        string
+	SIPCallID   string
+	Tag         string
+	Campaign    string
+	Outcome     string
+	Corrected   bool
+	Variables   map[string]string // Captured channel variables, sent as var.<name>=<value>
+}
+
+// query renders the filter as query parameters
+func (f CallFilter) query() url.Values {
+	q := url.Values{}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		q.Set("offset", strconv.Itoa(f.Offset))
+	}
+	setString(q, "context", f.Context)
+	setString(q, "sip_profile", f.SIPProfile)
+	setString(q, "domain", f.Domain)
+	setString(q, "account_code", f.AccountCode)
+	setString(q, "user_id", f.UserID)
+	setString(q, "gateway", f.Gateway)
+	setString(q, "node", f.Node)
+	setString(q, "sip_call_id", f.SIPCallID)
+	setString(q, "tag", f.Tag)
+	setString(q, "campaign", f.Campaign)
+	setString(q, "outcome", f.Outcome)
+	if f.Corrected {
+		q.Set("corrected", "true")
+	}
+	for name, value := range f.Variables {
+		q.Set("var."+name, value)
+	}
+	return q
+}
+
+// CostStats is one group of GET /stats/cost
+type CostStats struct {
+	Key        string  `json:"key"` // Extension, domain or destination prefix, per group_by
+	Calls      int64   `json:"calls"`
+	RatedCalls int64   `json:"rated_calls"`
+	TotalCost  float64 `json:"total_cost"`
+}
+
+// ServerStats is one sample or bucket of GET /stats/server
+type ServerStats struct {
+	Node           string    `json:"node"`
+	Hostname       *string   `json:"hostname,omitempty"`
+	Time           time.Time `json:"time"`
+	Samples        int       `json:"samples"`
+	Sessions       float64   `json:"sessions"`
+	SessionsMax    int       `json:"sessions_max"`
+	MaxSessions    *int      `json:"max_sessions,omitempty"`
+	SessionsPerSec float64   `json:"sessions_per_sec"`
+	SessionsTotal  int64     `json:"sessions_since_startup"`
+	IdleCPU        *float64  `json:"idle_cpu,omitempty"`
+	IdleCPUMin     *float64  `json:"idle_cpu_min,omitempty"`
+	UptimeSeconds  float64   `json:"uptime_seconds"`
+}
+
+// Webhook types
+const (
+	WebhookMissedCall = "missed_call"
+)
+
+// Webhook is the JSON body POSTed to ALERT_WEBHOOK_URL and MISSED_CALL_WEBHOOK_URL. Fields depends on
+// Type; missed_call carries uuid, caller, caller_name, callee, cause, start_time, end_time, node and,
+// when known, domain and business_hours.
+type Webhook struct {
+	Type     string         `json:"type"`
+	Severity string         `json:"severity"` // info, warning or critical
+	Message  string         `json:"message"`
+	Fields   map[string]any `json:"fields,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+// ParseWebhook decodes a webhook request body
+func ParseWebhook(r io.Reader) (*Webhook, error) {
+	var w Webhook
+	if err := json.NewDecoder(r).Decode(&w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
@@ -0,0 +1,105 @@
+// TypeScript client for the call logger's /api/v1 endpoints, following ../../openapi.yaml
+
+import type { Call, CallFilter, CostStats, ServerStats, Webhook } from "./models.js";
+
+export type { Call, CallFilter, CostStats, ServerStats, Webhook } from "./models.js";
+
+export interface ClientOptions {
+  /** Sent in the X-API-Key header of every request */
+  apiKey?: string;
+  /** Defaults to the global fetch */
+  fetch?: typeof fetch;
+}
+
+/** Thrown for non-2xx responses */
+export class APIError extends Error {
+  constructor(
+    readonly status: number,
+    message: string,
+  ) {
+    super(`api: ${status} ${message}`);
+    this.name = "APIError";
+  }
+}
+
+export class Client {
+  private readonly baseURL: string;
+  private readonly apiKey?: string;
+  private readonly fetch: typeof fetch;
+
+  /** baseURL is the logger's address, e.g. "http://logger:8080" */
+  constructor(baseURL: string, options: ClientOptions = {}) {
+    this.baseURL = baseURL.replace(/\/+$/, "") + "/api/v1";
+    this.apiKey = options.apiKey;
+    this.fetch = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  /** Calls matching filter, newest first */
+  listCalls(filter: CallFilter = {}, signal?: AbortSignal): Promise<Call[]> {
+    const { variables, ...params } = filter;
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined && value !== "" && value !== false) {
+        query.set(key, String(value));
+      }
+    }
+    for (const [name, value] of Object.entries(variables ?? {})) {
+      query.set(`var.${name}`, value);
+    }
+    return this.get<Call[]>("/calls", query, signal);
+  }
+
+  /** The call with the given UUID; rejects with an APIError of status 404 for unknown calls */
+  getCall(uuid: string, signal?: AbortSignal): Promise<Call> {
+    return this.get<Call>(`/calls/${encodeURIComponent(uuid)}`, undefined, signal);
+  }
+
+  /** Call counts and cost grouped by extension, domain or destination prefix */
+  getCostStats(
+    groupBy?: "extension" | "domain" | "destination_prefix",
+    prefixLen?: number,
+    signal?: AbortSignal,
+  ): Promise<CostStats[]> {
+    const query = new URLSearchParams();
+    if (groupBy) query.set("group_by", groupBy);
+    if (prefixLen) query.set("prefix_len", String(prefixLen));
+    return this.get<CostStats[]>("/stats/cost", query, signal);
+  }
+
+  /** FreeSWITCH load samples, aggregated when interval is given */
+  getServerStats(
+    params: { node?: string; from?: Date; to?: Date; interval?: "minute" | "hour" | "day" | "none" } = {},
+    signal?: AbortSignal,
+  ): Promise<ServerStats[]> {
+    const query = new URLSearchParams();
+    if (params.node) query.set("node", params.node);
+    if (params.from) query.set("from", params.from.toISOString());
+    if (params.to) query.set("to", params.to.toISOString());
+    if (params.interval) query.set("interval", params.interval);
+    return this.get<ServerStats[]>("/stats/server", query, signal);
+  }
+
+  private async get<T>(path: string, query?: URLSearchParams, signal?: AbortSignal): Promise<T> {
+    const qs = query?.toString();
+    const headers: Record<string, string> = { Accept: "application/json" };
+    if (this.apiKey) headers["X-API-Key"] = this.apiKey;
+
+    const resp = await this.fetch(this.baseURL + path + (qs ? `?${qs}` : ""), { headers, signal });
+    if (!resp.ok) {
+      const body = await resp.text();
+      let message = body.trim();
+      try {
+        message = (JSON.parse(body) as { error?: string }).error ?? message;
+      } catch {
+        // Not JSON; keep the raw body
+      }
+      throw new APIError(resp.status, message);
+    }
+    return (await resp.json()) as T;
+  }
+}
+
+/** Parses a webhook request body */
+export function parseWebhook(body: string): Webhook {
+  return JSON.parse(body) as Webhook;
+}
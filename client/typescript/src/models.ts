@@ -0,0 +1,125 @@
+// Models of the call logger API, matching the schemas in ../../openapi.yaml. Timestamps are RFC 3339 strings.
+
+/** A call record, as returned by GET /calls and GET /calls/{uuid} */
+export interface Call {
+  id: number;
+  uuid: string;
+  direction: string;
+  caller: string;
+  caller_name?: string;
+  callee: string;
+  callee_name?: string;
+  start_time: string;
+  ringing_time?: string;
+  early_media_time?: string;
+  answered_time?: string;
+  bridged_time?: string;
+  end_time?: string;
+  /** Hangup cause once the call has ended */
+  status?: string;
+  context?: string;
+  sip_profile?: string;
+  domain?: string;
+  account_code?: string;
+  user_id?: string;
+  gateway?: string;
+  /** FreeSWITCH server that reported the call */
+  node?: string;
+  sip_call_id?: string;
+  sip_trace_url?: string;
+  destination_country?: string;
+  destination_group?: string;
+  billsec?: number;
+  duration?: number;
+  progresssec?: number;
+  pdd?: number;
+  ring_time?: number;
+  cost?: number;
+  hold_seconds: number;
+  hold_count: number;
+  created_at: string;
+  transferred_to?: string;
+  transferred_by?: string;
+  tags?: string[];
+  caller_id_campaign?: string;
+  business_hours?: boolean;
+  hangup_cause_q850?: number;
+  sip_term_status?: number;
+  sip_invite_failure_status?: number;
+  outcome?: string;
+  outcome_source?: "rules" | "hook" | "api";
+  classified_at?: string;
+  sip_headers?: Record<string, string>;
+  variables?: Record<string, string>;
+  verto_session_id?: string;
+  verto_client_address?: string;
+  verto_user_agent?: string;
+  retry_of?: string;
+  retry_attempt?: number;
+  parked_seconds: number;
+  park_count: number;
+  retrieved_by?: string;
+  retrieved_by_uuid?: string;
+  late_corrections: number;
+  last_corrected_at?: string;
+  derived?: Record<string, string>;
+  custom?: Record<string, unknown>;
+}
+
+/** Filters for GET /calls; unset fields are not sent */
+export interface CallFilter {
+  limit?: number;
+  offset?: number;
+  context?: string;
+  sip_profile?: string;
+  domain?: string;
+  account_code?: string;
+  user_id?: string;
+  gateway?: string;
+  node?: string;
+  sip_call_id?: string;
+  tag?: string;
+  campaign?: string;
+  outcome?: string;
+  corrected?: boolean;
+  /** Captured channel variables, sent as var.<name>=<value> */
+  variables?: Record<string, string>;
+}
+
+/** One group of GET /stats/cost */
+export interface CostStats {
+  /** Extension, domain or destination prefix, per group_by */
+  key: string;
+  calls: number;
+  rated_calls: number;
+  total_cost: number;
+}
+
+/** One sample or bucket of GET /stats/server */
+export interface ServerStats {
+  node: string;
+  hostname?: string;
+  time: string;
+  samples: number;
+  sessions: number;
+  sessions_max: number;
+  max_sessions?: number;
+  sessions_per_sec: number;
+  sessions_since_startup: number;
+  idle_cpu?: number;
+  idle_cpu_min?: number;
+  uptime_seconds: number;
+}
+
+/**
+ * The JSON body POSTed to ALERT_WEBHOOK_URL and MISSED_CALL_WEBHOOK_URL. fields depends on type; missed_call
+ * carries uuid, caller, caller_name, callee, cause, start_time, end_time, node and, when known, domain and
+ * business_hours.
+ */
+export interface Webhook {
+  type: string;
+  severity: "info" | "warning" | "critical";
+  message: string;
+  fields?: Record<string, unknown>;
+  time: string;
+}
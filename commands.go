@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/buildinfo"
+	"gofreeswitchesl/config"
+	"gofreeswitchesl/features"
+	"gofreeswitchesl/store"
+	"gofreeswitchesl/vault"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// subcommands are the maintenance commands that can be run instead of the logger service
+var subcommands = map[string]func(args []string, logger *logrus.Logger) error{
+	"recompute": runRecompute,
+	"export":    runExport,
+	"import":    runImport,
+	"dedupe":    runDedupe,
+	"version":   runVersion,
+}
+
+// runSubcommand runs a command-line subcommand if one was given, reporting whether it handled the invocation
+func runSubcommand(logger *logrus.Logger) bool {
+	if len(os.Args) < 2 {
+		return false
+	}
+	run, ok := subcommands[os.Args[1]]
+	if !ok {
+		return false
+	}
+	if err := run(os.Args[2:], logger); err != nil {
+		logger.WithError(err).Fatalf("%s failed", os.Args[1])
+	}
+	return true
+}
+
+// runVersion prints the build information and the feature flags the current configuration enables
+func runVersion(args []string, logger *logrus.Logger) error {
+	fmt.Println(buildinfo.Get())
+	flags, err := loadFeatures(config.LoadConfig())
+	if err != nil {
+		return err
+	}
+	fmt.Println("flags:", flags)
+	return nil
+}
+
+// loadFeatures resolves the feature flags from the build and FEATURE_FLAGS
+func loadFeatures(cfg *config.Config) (*features.Set, error) {
+	flags, err := features.New(buildinfo.Get().Features, cfg.FeatureFlags)
+	if err != nil {
+		return nil, fmt.Errorf("FEATURE_FLAGS: %w", err)
+	}
+	return flags, nil
+}
+
+// loadFieldCipher builds the field cipher from FIELD_ENCRYPTION_KEY, or from the configured Vault secret
+// when no key is given directly. It returns nil when encryption is not configured.
+func loadFieldCipher(ctx context.Context, cfg *config.Config) (*store.FieldCipher, error) {
+	key := cfg.FieldEncryptionKey
+	if key == "" && cfg.VaultKeyPath != "" {
+		var err error
+		key, err = vault.ReadField(ctx, cfg.VaultAddr, cfg.VaultToken, cfg.VaultKeyPath, cfg.VaultKeyField)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key from vault: %w", err)
+		}
+	}
+	if key == "" {
+		return nil, nil
+	}
+	return store.NewFieldCipher(key)
+}
+
+// spoolPath returns the event spool file of the i-th ESL server. The first server keeps the configured
+// path; the others get it suffixed with their address, so each replays only its own events.
+func spoolPath(path string, i int, addr string) string {
+	if i == 0 {
+		return path
+	}
+	return path + "." + strings.NewReplacer(":", "_", "/", "_", "[", "", "]", "").Replace(addr)
+}
+
+// parseCommandRange parses optional RFC3339 from/to flags. from defaults to the Unix epoch and to to now.
+func parseCommandRange(fromFlag, toFlag string) (from, to time.Time, err error) {
+	from, to = time.Unix(0, 0).UTC(), time.Now().UTC()
+	if fromFlag != "" {
+		if from, err = time.Parse(time.RFC3339, fromFlag); err != nil {
+			return from, to, fmt.Errorf("from: %w", err)
+		}
+	}
+	if toFlag != "" {
+		if to, err = time.Parse(time.RFC3339, toFlag); err != nil {
+			return from, to, fmt.Errorf("to: %w", err)
+		}
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+// loadDatabaseURL returns the database URL from DATABASE_URL_FILE or the DATABASE_URL_VAULT_PATH secret
+// when one is configured, and DATABASE_URL otherwise
+func loadDatabaseURL(ctx context.Context, cfg *config.Config) (string, error) {
+	switch {
+	case cfg.DatabaseURLFile != "":
+		data, err := os.ReadFile(cfg.DatabaseURLFile)
+		if err != nil {
+			return "", fmt.Errorf("read DATABASE_URL_FILE: %w", err)
+		}
+		url := strings.TrimSpace(string(data))
+		if url == "" {
+			return "", errors.New("DATABASE_URL_FILE is empty")
+		}
+		return url, nil
+	case cfg.DatabaseURLVaultPath != "":
+		url, err := vault.ReadField(ctx, cfg.VaultAddr, cfg.VaultToken, cfg.DatabaseURLVaultPath, cfg.DatabaseURLVaultField)
+		if err != nil {
+			return "", fmt.Errorf("read database URL from vault: %w", err)
+		}
+		return url, nil
+	default:
+		return cfg.DatabaseURL, nil
+	}
+}
+
+// watchDatabaseURL re-reads the database URL secret every DATABASE_URL_REFRESH_INTERVAL seconds and,
+// when it changed (a rotated password), connects a new pool and switches the store to it. The old pool
+// is drained: it is closed after DATABASE_POOL_DRAIN_SECONDS, once its connections are returned.
+// A URL that fails to connect is retried on the next check while the current pool stays in use.
+func watchDatabaseURL(ctx context.Context, cfg *config.Config, s *store.Store, logger *logrus.Logger) {
+	if (cfg.DatabaseURLFile == "" && cfg.DatabaseURLVaultPath == "") || cfg.DatabaseURLRefresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(cfg.DatabaseURLRefresh) * time.Second)
+	defer ticker.Stop()
+
+	current := cfg.DatabaseURL
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			url, err := loadDatabaseURL(ctx, cfg)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to refresh the database URL; keeping the current pool")
+				continue
+			}
+			if url == current {
+				continue
+			}
+			next := *cfg
+			next.DatabaseURL = url
+			pool, err := connectPool(ctx, &next, logger)
+			if err != nil {
+				logger.WithError(err).Error("Database URL changed but the new credentials do not connect; keeping the current pool")
+				continue
+			}
+			s.ReplacePool(pool, time.Duration(cfg.DatabasePoolDrain)*time.Second)
+			current = url
+			logger.Info("Database credentials rotated; switched to a new connection pool")
+		}
+	}
+}
+
+// connectPool builds the pool configuration, connects and checks the connection with a ping
+func connectPool(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := newPoolConfig(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := pool.Ping(pingCtx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	return pool, nil
+}
+
+// newPoolConfig parses DATABASE_URL and applies the pooler, row-level security and API statement timeout
+// settings
+func newPoolConfig(cfg *config.Config, logger *logrus.Logger) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("DATABASE_URL: %w", err)
+	}
+	pooled, err := store.ConfigurePooler(poolConfig, cfg.DBPooler, cfg.DBRowLevelSecurity)
+	if err != nil {
+		return nil, fmt.Errorf("DB_POOLER: %w", err)
+	}
+	if pooled {
+		logger.Info("Using a transaction pooler: prepared statement caching disabled")
+	}
+	if cfg.DBRowLevelSecurity {
+		store.ScopeConnections(poolConfig)
+	}
+	switch {
+	case cfg.APIStatementTimeout <= 0:
+	case pooled:
+		logger.Warn("API_STATEMENT_TIMEOUT is not applied behind a transaction pooler")
+	default:
+		store.LimitAPIStatements(poolConfig, time.Duration(cfg.APIStatementTimeout)*time.Second)
+	}
+	return poolConfig, nil
+}
+
+// openCommandStore loads the configuration and opens a store for a subcommand. Ingest-only features
+// (circuit breaker, write limits, async writer) are not used. The returned func closes the pool.
+func openCommandStore(ctx context.Context, logger *logrus.Logger) (*config.Config, *store.Store, func(), error) {
+	cfg := config.LoadConfig()
+
+	var err error
+	if cfg.DatabaseURL, err = loadDatabaseURL(ctx, cfg); err != nil {
+		return nil, nil, nil, err
+	}
+	poolConfig, err := newPoolConfig(cfg, logger)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dbPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	appStore := store.NewStore(dbPool, 0, nil, logger)
+	customColumns, err := store.ParseCustomColumns(cfg.CustomColumns)
+	if err != nil {
+		dbPool.Close()
+		return nil, nil, nil, fmt.Errorf("custom columns: %w", err)
+	}
+	appStore.SetCustomColumns(customColumns)
+	appStore.SetRowLevelSecurity(cfg.DBRowLevelSecurity)
+	appStore.SetLegacyTimeZone(cfg.DBLegacyTimeZone)
+	fieldCipher, err := loadFieldCipher(ctx, cfg)
+	if err != nil {
+		dbPool.Close()
+		return nil, nil, nil, fmt.Errorf("field encryption: %w", err)
+	}
+	appStore.SetFieldCipher(fieldCipher)
+	if err := appStore.InitSchema(ctx); err != nil {
+		dbPool.Close()
+		return nil, nil, nil, fmt.Errorf("initialize schema: %w", err)
+	}
+	return cfg, appStore, dbPool.Close, nil
+}
@@ -4,16 +4,240 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds the application configuration
 type Config struct {
-	ESLAddr     string
-	ESLPass     string
-	DatabaseURL string
-	APIPort     string
+	ESLAddr string
+	ESLPass string
+
+	// Optional secret file holding the ESL password; it replaces ESLPass and is re-read every
+	// ESLPassFileInterval seconds so the password can be rotated without a restart
+	ESLPassFile         string
+	ESLPassFileInterval int
+
+	// FreeSWITCH servers as "host:port" or "host:port password" entries, one connection each; when set
+	// it replaces ESLAddr, and ESLPass is the default password
+	ESLServers []string
+
+	// Optional backup ESL endpoint for active/passive pairs
+	ESLBackupAddr       string
+	ESLBackupPass       string
+	ESLFailoverAfter    int // Consecutive failed connection attempts before switching
+	ESLFailbackInterval int // Seconds between primary probes while on the backup; 0 disables failback
+	DatabaseURL         string
+
+	// Optional secret sources replacing DatabaseURL: a file, or a field of a Vault secret. They are re-read
+	// every DatabaseURLRefresh seconds and the pool is rebuilt when the URL changes, after which the old
+	// pool's connections get DatabasePoolDrain seconds to finish
+	DatabaseURLFile       string
+	DatabaseURLVaultPath  string
+	DatabaseURLVaultField string
+	DatabaseURLRefresh    int
+	DatabasePoolDrain     int
+
+	// Diagnostic snapshots (SIGQUIT or POST /admin/diagnostics/dump) are written to files in DiagDumpDir,
+	// or to the log when empty, and include the last DiagErrorHistory errors logged
+	DiagDumpDir      string
+	DiagErrorHistory int
+
+	APIPort string
+
+	// ESL authentication rejections per endpoint before reconnects slow down to ESLAuthRetryInterval
+	// seconds (0 = retry at the normal rate forever; an interval of 0 stops retrying until restart)
+	ESLAuthMaxFailures   int
+	ESLAuthRetryInterval int
+
+	ESLReadTimeout int // Seconds without any event before the connection is treated as stalled; 0 disables
+
+	// Reconnection backoff: the first retry waits ESLReconnectInitial seconds, each further failure
+	// multiplies the wait by ESLReconnectMultiplier up to ESLReconnectMax, minus up to ESLReconnectJitter
+	// (a fraction) at random
+	ESLReconnectInitial    float64
+	ESLReconnectMax        float64
+	ESLReconnectMultiplier float64
+	ESLReconnectJitter     float64
+
+	// Event names and CUSTOM subclasses ("module::event") to subscribe to; empty uses the built-in list,
+	// ALL subscribes to everything
+	ESLEvents []string
+
+	// Server-side ESL filters as "Header Value" entries, e.g. "Event-Name CHANNEL_CREATE"; empty disables
+	ESLFilters []string
+
+	// inbound (connect to ESLAddr), outbound (accept socket application connections on ESLOutboundListen)
+	// or both
+	ESLMode           string
+	ESLOutboundListen string
+
+	// Seconds after CHANNEL_HANGUP_COMPLETE during which late events still update the call; later ones are discarded
+	LateEventGrace int
+
+	// Flat-rate call rating
+	RatePerMinute    float64
+	BillingIncrement int // Billing increment in seconds
+
+	// Extension directory lookup
+	DirectorySource   string // none, csv, http or ldap
+	DirectoryCSVPath  string
+	DirectoryHTTPURL  string // Must contain an {extension} placeholder
+	DirectoryCacheTTL int    // Seconds to cache HTTP and LDAP lookups
+
+	// LDAP directory: server, bind credentials (empty binds anonymously), the subtree searched and the
+	// attributes holding the extension and the display name
+	DirectoryLDAPURL           string
+	DirectoryLDAPBindDN        string
+	DirectoryLDAPBindPassword  string
+	DirectoryLDAPBaseDN        string
+	DirectoryLDAPAttribute     string
+	DirectoryLDAPNameAttribute string
+
+	PrefixTablePath string   // CSV of "prefix,country,group" rows; empty disables destination classification
+	RulesPath       string   // JSON file of derived-field rules; empty disables them
+	CustomColumns   []string // "variable_x->column TYPE [INDEXED]" mappings
+	SIPHeaders      []string // sip_* channel variables captured into calls.sip_headers; empty disables
+	ChannelVars     []string // Channel variables captured into calls.variables; "prefix*" matches by prefix
+	CalendarsPath   string   // JSON file of per-domain business-hours calendars; empty disables them
+
+	// Post-call outcome classification
+	OutcomeRulesPath string // JSON file of outcome rules; empty disables them
+	OutcomeHookURL   string // Receives calls the rules leave unlabelled; empty disables the hook
+	OutcomeInterval  int    // Seconds between classification runs; 0 disables classification
+	OutcomeLookback  int    // Hours; calls that ended earlier are never classified
+
+	// Alert destinations
+	AlertWebhookURL      string
+	AlertSlackWebhookURL string
+
+	// Per-gateway concurrent call limits
+	GatewayLimits         map[string]int
+	GatewayAlertThreshold float64 // Fraction of the limit at which to alert
+	GatewayDownAlert      bool    // Alert when sofia reports a gateway DOWN
+
+	// Caller-ID campaign attribution: number=campaign pairs seeded into caller_id_campaigns at startup
+	CallerIDCampaigns   map[string]string
+	CallerIDCampaignTTL int // Seconds between reloads of caller_id_campaigns
+
+	// Asynchronous batched store writes
+	AsyncWrites        bool
+	WriteQueueSize     int
+	WriteBatchSize     int
+	WriteFlushInterval int // Milliseconds
+
+	// Event handler worker pool; EventWorkers 0 spawns a goroutine per event
+	EventWorkers   int
+	EventQueueSize int
+	EventQueueFull string // wait or drop
+
+	// Concurrency limits (0 means unlimited)
+	EventMaxHandlers int
+	EventTypeLimits  map[string]int
+	StoreMaxInFlight int
+
+	// Load shedding of low-priority events
+	ShedInFlightThreshold int // 0 disables shedding
+	ShedSustainSeconds    int
+	ShedSampleRate        float64
+	ShedEvents            []string // By default only events nothing is derived from, so shedding loses no stored data
+
+	// Database circuit breaker and event spool
+	DBBreakerThreshold int // Consecutive connectivity failures before opening; 0 disables
+	DBBreakerCooldown  int // Seconds before probing the database again
+	SpoolPath          string
+	DeadLetterPath     string // JSON-lines file for events whose handler panicked; empty disables
+	DeadLetterAttempts int    // Times an event whose writes are transiently rejected is handled before dead_letter_events
+
+	// Repair missed calls with "show channels" when Event-Sequence gaps are detected
+	ReconcileOnSequenceGap bool
+
+	// Recent events and calls remembered to skip duplicate and out-of-order events; 0 disables
+	EventDedupWindow int
+
+	// API pagination
+	APIDefaultLimit  int
+	APIMaxLimit      int
+	APIAdminMaxLimit int      // Cap for requests carrying an admin API key
+	APIAdminKeys     []string // Accepted in the X-API-Key header
+	APIOperatorKeys  []string // Allowed to supervise calls
+
+	// Guardrails on API reads, separate from ingest: statement_timeout in seconds, the largest table a
+	// call listing may scan sequentially, and the deepest row a page may reach; 0 disables each
+	APIStatementTimeout int
+	APIMaxScanRows      int
+	APIMaxRows          int
+
+	// Multi-tenant reads: key=domain pairs of read-only API keys, scoped by row-level security policies
+	APITenantKeys      map[string]string
+	DBRowLevelSecurity bool
+
+	// IANA zone the wall-clock TIMESTAMP values of databases from before the TIMESTAMPTZ migration were
+	// written in; the host's zone by default, as that is what older versions stored
+	DBLegacyTimeZone string
+
+	// Usage accounting per API key: key=requests[:bytes] monthly quotas and how often counts are written
+	// to api_key_usage, in seconds
+	APIKeyQuotas          map[string]string
+	APIUsageFlushInterval int
+
+	// Connection pooler in front of the database: auto (detect from DATABASE_URL), none or transaction
+	// (pgbouncer/Supavisor transaction mode; disables prepared statement caching)
+	DBPooler string
+
+	// Encryption of caller/callee numbers and DTMF digits: a base64 AES-256 key given directly or read from
+	// a Vault secret field; empty disables. Only the listed API roles (admin, operator, tenant, anonymous)
+	// see the plaintext.
+	FieldEncryptionKey   string
+	VaultAddr            string
+	VaultToken           string
+	VaultKeyPath         string
+	VaultKeyField        string
+	FieldDecryptionRoles []string
+
+	StatsCacheTTL int // Seconds to cache stats responses; 0 disables
+
+	// Missed-call classification and follow-up webhook
+	MissedCallCauses     []string
+	MissedCallWebhookURL string
+
+	MissedCallBusinessOnly bool // Only notify missed calls started within their domain's business hours
+
+	SIPTraceURLTemplate string // Deep link from call records to a SIP capture tool
+
+	// Callback tracking: CUSTOM event subclasses and the window for matching outbound calls
+	CallbackOfferSubclass  string
+	CallbackAcceptSubclass string
+	CallbackMatchWindow    int // Minutes
+
+	QueueServiceLevel int // Default answer threshold for queue service-level figures
+
+	// Short-call alarm (one-way audio / carrier trouble proxy)
+	ShortCallSeconds  int
+	ShortCallRatio    float64 // 0 disables the alarm
+	ShortCallMinCalls int
+	ShortCallWindow   int // Minutes
+	ShortCallInterval int // Seconds between checks
+
+	// Scheduled data quality checks
+	DataQualityInterval   int // Seconds between runs; 0 disables the schedule
+	DataQualityLookback   int // Days of calls checked
+	DataQualityStaleHours int // Hours after which a call without end_time counts as stale
+
+	// Tiered retention (days; 0 keeps a tier forever)
+	RetentionInterval   int // Seconds between runs; 0 disables retention
+	RawEventRetention   int // Raw events, then summarized into call_events
+	CallEventRetention  int // Per-call event summaries
+	CallRecordRetention int // Calls and everything keyed by them
+
+	// Valet parking
+	ParkingLot     string
+	ParkingSlotMin int
+	ParkingSlotMax int
+
+	// Feature flag overrides: "name" enables, "-name" disables
+	FeatureFlags []string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -28,10 +252,131 @@ func LoadConfig() *Config {
 	apiPort := getEnv("API_PORT", "8080")
 
 	return &Config{
-		ESLAddr:     eslAddr,
-		ESLPass:     eslPass,
-		DatabaseURL: dbURL,
-		APIPort:     apiPort,
+		ESLAddr:                    eslAddr,
+		ESLPass:                    eslPass,
+		DatabaseURL:                dbURL,
+		DatabaseURLFile:            getEnv("DATABASE_URL_FILE", ""),
+		DatabaseURLVaultPath:       getEnv("DATABASE_URL_VAULT_PATH", ""),
+		DatabaseURLVaultField:      getEnv("DATABASE_URL_VAULT_FIELD", "url"),
+		DatabaseURLRefresh:         getEnvInt("DATABASE_URL_REFRESH_INTERVAL", 60),
+		DatabasePoolDrain:          getEnvInt("DATABASE_POOL_DRAIN_SECONDS", 30),
+		DiagDumpDir:                getEnv("DIAG_DUMP_DIR", ""),
+		DiagErrorHistory:           getEnvInt("DIAG_ERROR_HISTORY", 50),
+		APIPort:                    apiPort,
+		ESLPassFile:                getEnv("ESL_PASS_FILE", ""),
+		ESLPassFileInterval:        getEnvInt("ESL_PASS_FILE_INTERVAL", 30),
+		ESLServers:                 getEnvList("ESL_SERVERS", ""),
+		ESLBackupAddr:              getEnv("ESL_BACKUP_ADDR", ""),
+		ESLBackupPass:              getEnv("ESL_BACKUP_PASS", ""),
+		ESLFailoverAfter:           getEnvInt("ESL_FAILOVER_AFTER", 3),
+		ESLFailbackInterval:        getEnvInt("ESL_FAILBACK_INTERVAL", 60),
+		ESLAuthMaxFailures:         getEnvInt("ESL_AUTH_MAX_FAILURES", 3),
+		ESLAuthRetryInterval:       getEnvInt("ESL_AUTH_RETRY_INTERVAL", 300),
+		ESLReadTimeout:             getEnvInt("ESL_READ_TIMEOUT", 60),
+		ESLReconnectInitial:        getEnvFloat("ESL_RECONNECT_INITIAL", 1),
+		ESLReconnectMax:            getEnvFloat("ESL_RECONNECT_MAX", 60),
+		ESLReconnectMultiplier:     getEnvFloat("ESL_RECONNECT_MULTIPLIER", 2),
+		ESLReconnectJitter:         getEnvFloat("ESL_RECONNECT_JITTER", 0.2),
+		LateEventGrace:             getEnvInt("LATE_EVENT_GRACE", 300),
+		RatePerMinute:              getEnvFloat("RATE_PER_MINUTE", 0),
+		BillingIncrement:           getEnvInt("BILLING_INCREMENT", 60),
+		DirectorySource:            getEnv("DIRECTORY_SOURCE", "none"),
+		DirectoryCSVPath:           getEnv("DIRECTORY_CSV_PATH", "directory.csv"),
+		DirectoryHTTPURL:           getEnv("DIRECTORY_HTTP_URL", ""),
+		DirectoryCacheTTL:          getEnvInt("DIRECTORY_CACHE_TTL", 300),
+		DirectoryLDAPURL:           getEnv("DIRECTORY_LDAP_URL", ""),
+		DirectoryLDAPBindDN:        getEnv("DIRECTORY_LDAP_BIND_DN", ""),
+		DirectoryLDAPBindPassword:  getEnv("DIRECTORY_LDAP_BIND_PASSWORD", ""),
+		DirectoryLDAPBaseDN:        getEnv("DIRECTORY_LDAP_BASE_DN", ""),
+		DirectoryLDAPAttribute:     getEnv("DIRECTORY_LDAP_ATTRIBUTE", "telephoneNumber"),
+		DirectoryLDAPNameAttribute: getEnv("DIRECTORY_LDAP_NAME_ATTRIBUTE", "displayName"),
+		PrefixTablePath:            getEnv("PREFIX_TABLE_CSV", ""),
+		RulesPath:                  getEnv("DERIVED_FIELD_RULES", ""),
+		ESLEvents:                  getEnvList("ESL_EVENTS", ""),
+		ESLFilters:                 getEnvList("ESL_FILTERS", ""),
+		ESLMode:                    getEnv("ESL_MODE", "inbound"),
+		ESLOutboundListen:          getEnv("ESL_OUTBOUND_LISTEN", ":8084"),
+		CustomColumns:              getEnvList("CUSTOM_COLUMNS", ""),
+		ChannelVars:                getEnvList("CHANNEL_VARIABLES", ""),
+		SIPHeaders:                 getEnvList("SIP_HEADERS", "sip_call_id,sip_from_uri,sip_to_uri,sip_req_uri,sip_contact_uri,sip_user_agent,sip_received_ip,sip_received_port,sip_network_ip"),
+		CalendarsPath:              getEnv("BUSINESS_HOURS_CALENDARS", ""),
+		OutcomeRulesPath:           getEnv("OUTCOME_RULES", ""),
+		OutcomeHookURL:             getEnv("OUTCOME_HOOK_URL", ""),
+		OutcomeInterval:            getEnvInt("OUTCOME_INTERVAL", 30),
+		OutcomeLookback:            getEnvInt("OUTCOME_LOOKBACK", 24),
+		AlertWebhookURL:            getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertSlackWebhookURL:       getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+		GatewayLimits:              getEnvIntMap("GATEWAY_LIMITS"),
+		CallerIDCampaigns:          getEnvStringMap("CALLER_ID_CAMPAIGNS"),
+		CallerIDCampaignTTL:        getEnvInt("CALLER_ID_CAMPAIGN_TTL", 60),
+		GatewayAlertThreshold:      getEnvFloat("GATEWAY_ALERT_THRESHOLD", 0.8),
+		GatewayDownAlert:           getEnvBool("GATEWAY_DOWN_ALERT", true),
+		AsyncWrites:                getEnvBool("STORE_ASYNC_WRITES", false),
+		WriteQueueSize:             getEnvInt("STORE_WRITE_QUEUE_SIZE", 10000),
+		WriteBatchSize:             getEnvInt("STORE_WRITE_BATCH_SIZE", 100),
+		WriteFlushInterval:         getEnvInt("STORE_FLUSH_INTERVAL_MS", 200),
+		EventWorkers:               getEnvInt("EVENT_WORKERS", 32),
+		EventQueueSize:             getEnvInt("EVENT_QUEUE_SIZE", 10000),
+		EventQueueFull:             getEnv("EVENT_QUEUE_FULL", "wait"),
+		EventMaxHandlers:           getEnvInt("EVENT_MAX_HANDLERS", 0),
+		EventTypeLimits:            getEnvIntMap("EVENT_TYPE_LIMITS"),
+		StoreMaxInFlight:           getEnvInt("STORE_MAX_INFLIGHT", 0),
+		ShedInFlightThreshold:      getEnvInt("SHED_INFLIGHT_THRESHOLD", 0),
+		ShedSustainSeconds:         getEnvInt("SHED_SUSTAIN_SECONDS", 10),
+		ShedSampleRate:             getEnvFloat("SHED_SAMPLE_RATE", 0),
+		DBPooler:                   getEnv("DB_POOLER", "auto"),
+		DBBreakerThreshold:         getEnvInt("DB_BREAKER_THRESHOLD", 5),
+		DBBreakerCooldown:          getEnvInt("DB_BREAKER_COOLDOWN_SECONDS", 15),
+		SpoolPath:                  getEnv("SPOOL_PATH", "spool/events.jsonl"),
+		DeadLetterPath:             getEnv("DEAD_LETTER_PATH", ""),
+		DeadLetterAttempts:         getEnvInt("DEAD_LETTER_ATTEMPTS", 2),
+		ReconcileOnSequenceGap:     getEnvBool("RECONCILE_ON_SEQUENCE_GAP", false),
+		EventDedupWindow:           getEnvInt("EVENT_DEDUP_WINDOW", 10000),
+		APIDefaultLimit:            getEnvInt("API_DEFAULT_LIMIT", 10),
+		APIMaxLimit:                getEnvInt("API_MAX_LIMIT", 100),
+		APIAdminMaxLimit:           getEnvInt("API_ADMIN_MAX_LIMIT", 10000),
+		APIStatementTimeout:        getEnvInt("API_STATEMENT_TIMEOUT", 15),
+		APIMaxScanRows:             getEnvInt("API_MAX_SCAN_ROWS", 1000000),
+		APIMaxRows:                 getEnvInt("API_MAX_ROWS", 100000),
+		APIAdminKeys:               getEnvList("API_ADMIN_KEYS", ""),
+		APIOperatorKeys:            getEnvList("API_OPERATOR_KEYS", ""),
+		APITenantKeys:              getEnvStringMap("API_TENANT_KEYS"),
+		DBRowLevelSecurity:         getEnvBool("DB_ROW_LEVEL_SECURITY", false),
+		DBLegacyTimeZone:           getEnv("DB_LEGACY_TIMEZONE", hostTimeZone()),
+		APIKeyQuotas:               getEnvStringMap("API_KEY_QUOTAS"),
+		APIUsageFlushInterval:      getEnvInt("API_USAGE_FLUSH_INTERVAL", 10),
+		FieldEncryptionKey:         getEnv("FIELD_ENCRYPTION_KEY", ""),
+		VaultAddr:                  getEnv("VAULT_ADDR", ""),
+		VaultToken:                 getEnv("VAULT_TOKEN", ""),
+		VaultKeyPath:               getEnv("FIELD_ENCRYPTION_VAULT_PATH", ""),
+		VaultKeyField:              getEnv("FIELD_ENCRYPTION_VAULT_FIELD", "key"),
+		FieldDecryptionRoles:       getEnvList("FIELD_DECRYPTION_ROLES", "admin,operator"),
+		StatsCacheTTL:              getEnvInt("STATS_CACHE_TTL", 5),
+		MissedCallCauses:           getEnvList("MISSED_CALL_CAUSES", "NO_ANSWER,ORIGINATOR_CANCEL,NO_USER_RESPONSE,USER_BUSY,CALL_REJECTED"),
+		MissedCallWebhookURL:       getEnv("MISSED_CALL_WEBHOOK_URL", ""),
+		MissedCallBusinessOnly:     getEnvBool("MISSED_CALL_BUSINESS_HOURS_ONLY", false),
+		ParkingLot:                 getEnv("PARKING_LOT", "valet_lot"),
+		SIPTraceURLTemplate:        getEnv("SIP_TRACE_URL_TEMPLATE", ""),
+		CallbackOfferSubclass:      getEnv("CALLBACK_OFFER_SUBCLASS", "callback::offer"),
+		CallbackAcceptSubclass:     getEnv("CALLBACK_ACCEPT_SUBCLASS", "callback::accept"),
+		CallbackMatchWindow:        getEnvInt("CALLBACK_MATCH_WINDOW_MINUTES", 1440),
+		QueueServiceLevel:          getEnvInt("QUEUE_SERVICE_LEVEL_SECONDS", 20),
+		ShortCallSeconds:           getEnvInt("SHORT_CALL_SECONDS", 5),
+		ShortCallRatio:             getEnvFloat("SHORT_CALL_RATIO", 0.3),
+		ShortCallMinCalls:          getEnvInt("SHORT_CALL_MIN_CALLS", 20),
+		ShortCallWindow:            getEnvInt("SHORT_CALL_WINDOW_MINUTES", 15),
+		ShortCallInterval:          getEnvInt("SHORT_CALL_CHECK_INTERVAL", 60),
+		DataQualityInterval:        getEnvInt("DATA_QUALITY_INTERVAL", 3600),
+		DataQualityLookback:        getEnvInt("DATA_QUALITY_LOOKBACK_DAYS", 7),
+		DataQualityStaleHours:      getEnvInt("DATA_QUALITY_STALE_HOURS", 24),
+		RetentionInterval:          getEnvInt("RETENTION_INTERVAL", 3600),
+		RawEventRetention:          getEnvInt("RAW_EVENT_RETENTION_DAYS", 7),
+		CallEventRetention:         getEnvInt("CALL_EVENT_RETENTION_DAYS", 90),
+		CallRecordRetention:        getEnvInt("CALL_RETENTION_DAYS", 0),
+		ParkingSlotMin:             getEnvInt("PARKING_SLOT_MIN", 5901),
+		ParkingSlotMax:             getEnvInt("PARKING_SLOT_MAX", 5999),
+		FeatureFlags:               getEnvList("FEATURE_FLAGS", ""),
+		ShedEvents:                 getEnvList("SHED_EVENTS", "PRESENCE_IN,RE_SCHEDULE,API,MESSAGE_QUERY"),
 	}
 }
 
@@ -44,6 +389,107 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// hostTimeZone returns the IANA name of the host's time zone, from TZ or the /etc/localtime link, or UTC
+// when it cannot be told
+func hostTimeZone() string {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		if tz = strings.TrimPrefix(tz, ":"); tz != "" {
+			return tz
+		}
+		return "UTC"
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return name
+		}
+	}
+	return "UTC"
+}
+
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	valueStr := getEnv(key, strconv.Itoa(defaultValue))
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %q, using default %d", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat retrieves a floating point environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		log.Printf("Invalid number for %s: %q, using default %g", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvBool retrieves a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, strconv.FormatBool(defaultValue))
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using default %t", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList retrieves a comma-separated list environment variable, dropping empty entries
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvIntMap parses a comma-separated list of name=integer pairs, e.g. "carrier_a=30,carrier_b=10".
+// Malformed entries are logged and skipped.
+func getEnvIntMap(key string) map[string]int {
+	result := make(map[string]int)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, valueStr, ok := strings.Cut(pair, "=")
+		value, err := strconv.Atoi(strings.TrimSpace(valueStr))
+		if !ok || err != nil || strings.TrimSpace(name) == "" {
+			log.Printf("Ignoring malformed %s entry: %q", key, pair)
+			continue
+		}
+		result[strings.TrimSpace(name)] = value
+	}
+	return result
+}
+
+// getEnvStringMap parses a comma-separated list of name=value pairs, e.g. "15551230000=spring-promo".
+// Malformed entries are logged and skipped.
+func getEnvStringMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			log.Printf("Ignoring malformed %s entry: %q", key, pair)
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
+
 // GetAPIPortInt returns the API port as an integer
 func (c *Config) GetAPIPortInt() int {
 	port, err := strconv.Atoi(c.APIPort)
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dedupeOptions are the parsed flags of the dedupe command
+type dedupeOptions struct {
+	from, to  time.Time
+	window    time.Duration
+	batchSize int
+	dryRun    bool
+}
+
+// parseDedupeFlags parses the dedupe subcommand arguments
+func parseDedupeFlags(args []string) (dedupeOptions, error) {
+	fs := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	from := fs.String("from", "", "only calls started at or after this RFC3339 time (default: all)")
+	to := fs.String("to", "", "only calls started before this RFC3339 time (default: now)")
+	window := fs.Duration("window", time.Second, "maximum start time difference between calls with the same direction, caller and callee")
+	batchSize := fs.Int("batch", 500, "duplicate groups per batch")
+	dryRun := fs.Bool("dry-run", false, "report duplicates without merging")
+	if err := fs.Parse(args); err != nil {
+		return dedupeOptions{}, err
+	}
+
+	opts := dedupeOptions{
+		window:    *window,
+		batchSize: *batchSize,
+		dryRun:    *dryRun,
+	}
+	if opts.window < 0 {
+		return opts, fmt.Errorf("window must not be negative")
+	}
+	if opts.batchSize <= 0 {
+		return opts, fmt.Errorf("batch must be positive")
+	}
+	var err error
+	opts.from, opts.to, err = parseCommandRange(*from, *to)
+	return opts, err
+}
+
+// runDedupe finds duplicate call records left by past incidents and merges each group into its most
+// complete record
+func runDedupe(args []string, logger *logrus.Logger) error {
+	opts, err := parseDedupeFlags(args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, appStore, closeStore, err := openCommandStore(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	logger.WithFields(logrus.Fields{
+		"from":   opts.from,
+		"to":     opts.to,
+		"window": opts.window.String(),
+		"dryRun": opts.dryRun,
+	}).Info("Starting dedupe")
+
+	started := time.Now()
+	var groups int
+	var removed int64
+	for {
+		found, err := appStore.FindDuplicateCalls(ctx, opts.from, opts.to, opts.window, opts.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			break
+		}
+		var batchRemoved int64
+		for _, g := range found {
+			logger.WithFields(logrus.Fields{
+				"reason":     g.Reason,
+				"keep":       g.Keep,
+				"duplicates": g.Duplicates,
+				"caller":     g.Caller,
+				"callee":     g.Callee,
+				"startTime":  g.StartTime,
+			}).Info("Duplicate calls")
+			if opts.dryRun {
+				continue
+			}
+			n, err := appStore.MergeDuplicateCalls(ctx, g)
+			if err != nil {
+				return err
+			}
+			batchRemoved += n
+		}
+		groups += len(found)
+		removed += batchRemoved
+
+		// A dry run reports one batch; a batch that removed nothing would be found again
+		if opts.dryRun || batchRemoved == 0 {
+			break
+		}
+		logger.WithFields(logrus.Fields{
+			"groups":  groups,
+			"removed": removed,
+		}).Info("Dedupe progress")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"groups":   groups,
+		"removed":  removed,
+		"duration": time.Since(started).String(),
+	}).Info("Dedupe complete")
+	return nil
+}
@@ -0,0 +1,165 @@
+// Package diagnostics writes a snapshot of a running logger for debugging wedged instances: recent
+// errors, the state reported by each component and every goroutine's stack
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/buildinfo"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorLog is a logrus hook that keeps the most recent error-level entries
+type ErrorLog struct {
+	mu      sync.Mutex
+	entries []LoggedError
+	next    int
+	size    int
+}
+
+// LoggedError is an entry kept by ErrorLog
+type LoggedError struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// NewErrorLog keeps the last size errors; a size below one keeps none
+func NewErrorLog(size int) *ErrorLog {
+	return &ErrorLog{entries: make([]LoggedError, max(size, 0))}
+}
+
+// Levels implements logrus.Hook
+func (l *ErrorLog) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire implements logrus.Hook
+func (l *ErrorLog) Fire(entry *logrus.Entry) error {
+	if len(l.entries) == 0 {
+		return nil
+	}
+	logged := LoggedError{Time: entry.Time, Level: entry.Level.String(), Message: entry.Message}
+	if len(entry.Data) > 0 {
+		logged.Fields = make(map[string]string, len(entry.Data))
+		for k, v := range entry.Data {
+			logged.Fields[k] = fmt.Sprint(v) // Errors and other values may not marshal to JSON
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = logged
+	l.next = (l.next + 1) % len(l.entries)
+	l.size = min(l.size+1, len(l.entries))
+	return nil
+}
+
+// Recent returns the kept errors, oldest first
+func (l *ErrorLog) Recent() []LoggedError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]LoggedError, 0, l.size)
+	start := (l.next - l.size + len(l.entries)) % max(len(l.entries), 1)
+	for i := range l.size {
+		recent = append(recent, l.entries[(start+i)%len(l.entries)])
+	}
+	return recent
+}
+
+// Section is a named part of the snapshot; its value is rendered as JSON
+type Section struct {
+	Name  string
+	State func() any
+}
+
+// Dumper writes diagnostic snapshots
+type Dumper struct {
+	errors   *ErrorLog
+	sections []Section
+	dir      string
+	log      *logrus.Logger
+}
+
+// NewDumper creates a Dumper reporting errors and sections, in order. Dump writes to files in dir, or to
+// the log when dir is empty.
+func NewDumper(errors *ErrorLog, dir string, logger *logrus.Logger, sections ...Section) *Dumper {
+	return &Dumper{errors: errors, sections: sections, dir: dir, log: logger}
+}
+
+// Write writes a snapshot to w. Goroutine stacks are included when goroutines is set.
+func (d *Dumper) Write(w io.Writer, goroutines bool) error {
+	fmt.Fprintf(w, "=== Diagnostic snapshot at %s\n", time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "build: %s\n", buildinfo.Get().String())
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(w, "goroutines: %d, heap: %d bytes in use, %d GC cycles\n", runtime.NumGoroutine(), mem.HeapInuse, mem.NumGC)
+
+	for _, s := range d.sections {
+		if err := writeJSON(w, s.Name, s.State()); err != nil {
+			return err
+		}
+	}
+	if d.errors != nil {
+		if err := writeJSON(w, "recent errors", d.errors.Recent()); err != nil {
+			return err
+		}
+	}
+	if !goroutines {
+		return nil
+	}
+	fmt.Fprintf(w, "\n=== goroutines\n")
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// writeJSON writes value under a section heading
+func writeJSON(w io.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	_, err = fmt.Fprintf(w, "\n=== %s\n%s\n", name, data)
+	return err
+}
+
+// Dump writes a full snapshot, including goroutine stacks, to a new file in the dump directory or to the
+// log. It returns the file path, empty when logged.
+func (d *Dumper) Dump() (string, error) {
+	if d.dir == "" {
+		var buf strings.Builder
+		if err := d.Write(&buf, true); err != nil {
+			return "", err
+		}
+		d.log.Warn("Diagnostic snapshot\n" + buf.String())
+		return "", nil
+	}
+
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(d.dir, "diagnostics-"+time.Now().UTC().Format("20060102T150405.000Z")+".txt")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if err := d.Write(f, true); err != nil {
+		f.Close()
+		return path, err
+	}
+	if err := f.Close(); err != nil {
+		return path, err
+	}
+	d.log.WithField("path", path).Warn("Diagnostic snapshot written")
+	return path, nil
+}
@@ -0,0 +1,211 @@
+package directory
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Directory resolves internal extensions to display names
+type Directory interface {
+	Lookup(ctx context.Context, extension string) (string, bool)
+}
+
+// ErrUnknownSource is returned by New for an unsupported DIRECTORY_SOURCE value
+var ErrUnknownSource = errors.New("unknown directory source")
+
+// New builds a Directory for the given source ("csv", "http" or "ldap").
+// An empty source or "none" returns a nil Directory, meaning lookups are disabled.
+func New(source, csvPath, httpURL string, ldap LDAPConfig, cacheTTL time.Duration, logger *logrus.Logger) (Directory, error) {
+	switch source {
+	case "", "none":
+		return nil, nil
+	case "csv":
+		d, err := NewCSVDirectory(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case "http":
+		return NewHTTPDirectory(httpURL, cacheTTL, logger), nil
+	case "ldap":
+		d, err := NewLDAPDirectory(ldap, cacheTTL, logger)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSource, source)
+	}
+}
+
+// CSVDirectory is a static directory loaded from a CSV file of "extension,name" rows
+type CSVDirectory struct {
+	names map[string]string
+}
+
+// NewCSVDirectory loads a CSVDirectory from path. Blank lines and lines starting with '#' are ignored.
+func NewCSVDirectory(path string) (*CSVDirectory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	names := make(map[string]string)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			continue
+		}
+		ext := strings.TrimSpace(record[0])
+		name := strings.TrimSpace(record[1])
+		if ext != "" && name != "" {
+			names[ext] = name
+		}
+	}
+	return &CSVDirectory{names: names}, nil
+}
+
+// Lookup returns the display name for extension
+func (d *CSVDirectory) Lookup(_ context.Context, extension string) (string, bool) {
+	name, ok := d.names[extension]
+	return name, ok
+}
+
+// maxCacheEntries bounds a lookup cache; extensions beyond it evict expired entries, then arbitrary ones
+const maxCacheEntries = 10000
+
+// cacheEntry holds a cached lookup result; negative results are cached too
+type cacheEntry struct {
+	name    string
+	found   bool
+	expires time.Time
+}
+
+// lookupCache caches the results of a remote directory for ttl, falling back to fetch on a miss
+type lookupCache struct {
+	ttl   time.Duration
+	fetch func(ctx context.Context, extension string) (string, bool, error)
+	log   *logrus.Logger
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newLookupCache(ttl time.Duration, fetch func(context.Context, string) (string, bool, error), logger *logrus.Logger) *lookupCache {
+	return &lookupCache{ttl: ttl, fetch: fetch, log: logger, entries: make(map[string]cacheEntry)}
+}
+
+// Lookup returns the display name for extension, consulting the cache first
+func (c *lookupCache) Lookup(ctx context.Context, extension string) (string, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[extension]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.name, entry.found
+	}
+
+	name, found, err := c.fetch(ctx, extension)
+	if err != nil {
+		// Don't cache transport errors so the next call retries
+		c.log.WithError(err).WithField("extension", extension).Warn("Directory lookup failed")
+		return "", false
+	}
+
+	c.mu.Lock()
+	if _, ok := c.entries[extension]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict()
+	}
+	c.entries[extension] = cacheEntry{name: name, found: found, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return name, found
+}
+
+// evict makes room in the full cache: expired entries go first, then arbitrary ones until a tenth of the
+// cache is free, so a stream of distinct extensions cannot grow it without bound. c.mu must be held.
+func (c *lookupCache) evict() {
+	now := time.Now()
+	for ext, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, ext)
+		}
+	}
+	for ext := range c.entries {
+		if len(c.entries) < maxCacheEntries*9/10 {
+			break
+		}
+		delete(c.entries, ext)
+	}
+}
+
+// HTTPDirectory resolves extensions by calling an HTTP endpoint.
+// The URL must contain an "{extension}" placeholder and the endpoint must respond
+// with a JSON object containing a "name" field, or 404 when the extension is unknown.
+type HTTPDirectory struct {
+	*lookupCache
+	urlTemplate string
+	client      *http.Client
+}
+
+// NewHTTPDirectory creates a new HTTPDirectory
+func NewHTTPDirectory(urlTemplate string, cacheTTL time.Duration, logger *logrus.Logger) *HTTPDirectory {
+	d := &HTTPDirectory{
+		urlTemplate: urlTemplate,
+		client:      &http.Client{Timeout: 3 * time.Second},
+	}
+	d.lookupCache = newLookupCache(cacheTTL, d.fetch, logger)
+	return d
+}
+
+// fetch performs a single HTTP lookup
+func (d *HTTPDirectory) fetch(ctx context.Context, extension string) (string, bool, error) {
+	target := strings.ReplaceAll(d.urlTemplate, "{extension}", url.PathEscape(extension))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("directory returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", false, err
+	}
+	return body.Name, body.Name != "", nil
+}
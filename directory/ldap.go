@@ -0,0 +1,333 @@
+package directory
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LDAPConfig locates extensions in an LDAP directory
+type LDAPConfig struct {
+	URL           string // ldap://host[:389] or ldaps://host[:636]
+	BindDN        string // Empty binds anonymously
+	BindPassword  string
+	BaseDN        string // Subtree searched for the extension
+	Attribute     string // Attribute holding the extension, e.g. telephoneNumber
+	NameAttribute string // Attribute holding the display name, e.g. displayName
+}
+
+// maxLDAPMessage bounds the size of a message read from the server
+const maxLDAPMessage = 1 << 20
+
+// BER tags of the LDAPv3 messages used (RFC 4511)
+const (
+	berInteger    = 0x02
+	berOctets     = 0x04
+	berEnumerated = 0x0a
+	berBoolean    = 0x01
+	berSequence   = 0x30
+
+	ldapBindRequest     = 0x60
+	ldapBindResponse    = 0x61
+	ldapUnbindRequest   = 0x42
+	ldapSearchRequest   = 0x63
+	ldapSearchEntry     = 0x64
+	ldapSearchDone      = 0x65
+	ldapSearchReference = 0x73
+	ldapSimpleAuth      = 0x80
+	ldapEqualityMatch   = 0xa3
+
+	ldapSizeLimitExceeded = 4 // Result code of a search that matched more entries than its size limit
+)
+
+// LDAPDirectory resolves extensions with a simple-bind LDAPv3 search, one connection per lookup
+type LDAPDirectory struct {
+	*lookupCache
+	cfg     LDAPConfig
+	address string
+	tls     bool
+	timeout time.Duration
+}
+
+// NewLDAPDirectory creates a new LDAPDirectory
+func NewLDAPDirectory(cfg LDAPConfig, cacheTTL time.Duration, logger *logrus.Logger) (*LDAPDirectory, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	d := &LDAPDirectory{cfg: cfg, address: u.Host, timeout: 3 * time.Second}
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			d.address = net.JoinHostPort(u.Hostname(), "389")
+		}
+	case "ldaps":
+		d.tls = true
+		if u.Port() == "" {
+			d.address = net.JoinHostPort(u.Hostname(), "636")
+		}
+	default:
+		return nil, fmt.Errorf("invalid LDAP URL scheme %q (ldap or ldaps)", u.Scheme)
+	}
+	if u.Hostname() == "" || cfg.BaseDN == "" || cfg.Attribute == "" || cfg.NameAttribute == "" {
+		return nil, errors.New("LDAP directory needs a host, base DN, extension attribute and name attribute")
+	}
+	d.lookupCache = newLookupCache(cacheTTL, d.fetch, logger)
+	return d, nil
+}
+
+// fetch performs a single LDAP lookup: bind, search for the extension, unbind
+func (d *LDAPDirectory) fetch(ctx context.Context, extension string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if d.tls {
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", d.address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", d.address)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+
+	bind := berTLV(ldapBindRequest, berInt(berInteger, 3), berString(berOctets, d.cfg.BindDN),
+		berString(ldapSimpleAuth, d.cfg.BindPassword))
+	if _, err := conn.Write(ldapMessage(1, bind)); err != nil {
+		return "", false, err
+	}
+	tag, op, err := readLDAPMessage(r)
+	if err != nil {
+		return "", false, err
+	}
+	if tag != ldapBindResponse {
+		return "", false, fmt.Errorf("unexpected LDAP response 0x%02x to bind", tag)
+	}
+	if _, err := ldapResult(op, "bind"); err != nil {
+		return "", false, err
+	}
+
+	search := berTLV(ldapSearchRequest,
+		berString(berOctets, d.cfg.BaseDN),
+		berInt(berEnumerated, 2), // wholeSubtree
+		berInt(berEnumerated, 0), // neverDerefAliases
+		berInt(berInteger, 1),    // sizeLimit
+		berInt(berInteger, int(d.timeout/time.Second)),
+		berTLV(berBoolean, []byte{0}), // typesOnly
+		berTLV(ldapEqualityMatch, berString(berOctets, d.cfg.Attribute), berString(berOctets, extension)),
+		berTLV(berSequence, berString(berOctets, d.cfg.NameAttribute)),
+	)
+	if _, err := conn.Write(ldapMessage(2, search)); err != nil {
+		return "", false, err
+	}
+
+	var name string
+	for {
+		tag, op, err := readLDAPMessage(r)
+		if err != nil {
+			return "", false, err
+		}
+		switch tag {
+		case ldapSearchEntry:
+			if name == "" {
+				name = d.entryName(op)
+			}
+		case ldapSearchReference:
+			// Referrals to other servers are not followed
+		case ldapSearchDone:
+			conn.Write(ldapMessage(3, berTLV(ldapUnbindRequest)))
+			// Entries sharing the extension exceed the size limit of 1; the first one returned still counts
+			if code, err := ldapResult(op, "search"); err != nil && (code != ldapSizeLimitExceeded || name == "") {
+				return "", false, err
+			}
+			return name, name != "", nil
+		default:
+			return "", false, fmt.Errorf("unexpected LDAP response 0x%02x to search", tag)
+		}
+	}
+}
+
+// entryName returns the first value of the name attribute in a SearchResultEntry
+func (d *LDAPDirectory) entryName(entry []byte) string {
+	_, _, rest, err := berNext(entry) // objectName
+	if err != nil {
+		return ""
+	}
+	_, attrs, _, err := berNext(rest)
+	if err != nil {
+		return ""
+	}
+	for len(attrs) > 0 {
+		var attr []byte
+		if _, attr, attrs, err = berNext(attrs); err != nil {
+			return ""
+		}
+		_, attrType, vals, err := berNext(attr)
+		if err != nil || string(attrType) != d.cfg.NameAttribute {
+			continue
+		}
+		if _, vals, _, err = berNext(vals); err != nil {
+			return ""
+		}
+		if _, value, _, err := berNext(vals); err == nil {
+			return string(value)
+		}
+	}
+	return ""
+}
+
+// ldapResult returns the result code of the LDAPResult in op, and an error unless it reports success
+func ldapResult(op []byte, what string) (int, error) {
+	_, code, rest, err := berNext(op)
+	if err != nil {
+		return 0, err
+	}
+	n := berUint(code)
+	if n != 0 {
+		var message []byte
+		if _, _, rest, err = berNext(rest); err == nil { // matchedDN
+			_, message, _, _ = berNext(rest)
+		}
+		return n, fmt.Errorf("LDAP %s failed with result code %d: %s", what, n, message)
+	}
+	return 0, nil
+}
+
+// ldapMessage wraps a protocol operation in an LDAPMessage
+func ldapMessage(id int, op []byte) []byte {
+	return berTLV(berSequence, berInt(berInteger, id), op)
+}
+
+// readLDAPMessage reads one LDAPMessage and returns its protocol operation's tag and contents
+func readLDAPMessage(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	if tag != berSequence {
+		return 0, nil, fmt.Errorf("malformed LDAP message tag 0x%02x", tag)
+	}
+	n, err := berReadLength(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	_, _, rest, err := berNext(body) // messageID
+	if err != nil {
+		return 0, nil, err
+	}
+	opTag, op, _, err := berNext(rest)
+	return opTag, op, err
+}
+
+// berReadLength reads a BER length from r
+func berReadLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+	size := int(b & 0x7f)
+	if size == 0 || size > 4 {
+		return 0, errors.New("unsupported LDAP length encoding")
+	}
+	n := 0
+	for range size {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+	if n > maxLDAPMessage {
+		return 0, fmt.Errorf("LDAP message of %d bytes is too large", n)
+	}
+	return n, nil
+}
+
+// berNext splits the first element off data, returning its tag, contents and the rest
+func berNext(data []byte) (tag byte, contents, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	tag, n, i := data[0], int(data[1]), 2
+	if n >= 0x80 {
+		size := n & 0x7f
+		if size == 0 || size > 4 || len(data) < 2+size {
+			return 0, nil, nil, errors.New("malformed LDAP length")
+		}
+		n = 0
+		for _, b := range data[2 : 2+size] {
+			n = n<<8 | int(b)
+		}
+		i += size
+	}
+	if n < 0 || len(data)-i < n {
+		return 0, nil, nil, io.ErrUnexpectedEOF
+	}
+	return tag, data[i : i+n], data[i+n:], nil
+}
+
+// berUint decodes a small non-negative INTEGER or ENUMERATED
+func berUint(contents []byte) int {
+	n := 0
+	for _, b := range contents {
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+// berTLV encodes an element from its tag and the concatenated contents
+func berTLV(tag byte, contents ...[]byte) []byte {
+	var body []byte
+	for _, c := range contents {
+		body = append(body, c...)
+	}
+	out := []byte{tag}
+	switch n := len(body); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	case n < 0x10000:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+// berString encodes an OCTET STRING, or a context-specific string such as simple authentication
+func berString(tag byte, s string) []byte {
+	return berTLV(tag, []byte(s))
+}
+
+// berInt encodes a non-negative INTEGER or ENUMERATED
+func berInt(tag byte, n int) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...) // Keep it positive
+	}
+	return berTLV(tag, b)
+}
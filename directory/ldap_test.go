@@ -0,0 +1,260 @@
+package directory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Server responses in the encoding OpenLDAP and Active Directory send, with four-byte long-form lengths
+const (
+	bindSuccess       = "30840000001802010161840000000f0a0100048400000000048400000000"
+	bindInvalidCreds  = "30840000001802010161840000000f0a0131048400000000048400000000"
+	searchDone        = "30840000001802010265840000000f0a0100048400000000048400000000"
+	searchDoneSizeHit = "30840000001802010265840000000f0a0104048400000000048400000000"
+	searchDoneNoBase  = "30840000002602010265840000001d0a012004840000000004840000000e6e6f2073756368206f626a656374"
+
+	// uid=alice,ou=people,dc=example,dc=com with objectClass inetOrgPerson and displayName "Alice Smith"
+	entryAlice = "30840000009802010264840000008f0484000000257569643d616c6963652c6f753d70656f706c652c64633d6578616d706c652c64633d636f6d30840000005e30840000002a04840000000b6f626a656374436c61737331840000001304840000000d696e65744f7267506572736f6e30840000002804840000000b646973706c61794e616d6531840000001104840000000b416c69636520536d697468"
+	// uid=bob,ou=people,dc=example,dc=com with displayName "Bob Jones"
+	entryBob = "30840000006402010264840000005b0484000000237569643d626f622c6f753d70656f706c652c64633d6578616d706c652c64633d636f6d30840000002c30840000002604840000000b646973706c61794e616d6531840000000f048400000009426f62204a6f6e6573"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBerNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		tag      byte
+		contents string
+		rest     string
+		err      bool
+	}{
+		{name: "short length", data: []byte{0x04, 0x02, 'h', 'i', 0xff}, tag: 0x04, contents: "hi", rest: "\xff"},
+		{name: "one-byte long length", data: append([]byte{0x04, 0x81, 0x03}, "abc"...), tag: 0x04, contents: "abc"},
+		{name: "four-byte long length", data: append([]byte{0x04, 0x84, 0, 0, 0, 0x02}, "ok"...), tag: 0x04, contents: "ok"},
+		{name: "empty contents", data: []byte{0x04, 0x00}, tag: 0x04},
+		{name: "truncated contents", data: []byte{0x04, 0x05, 'a'}, err: true},
+		{name: "truncated length", data: []byte{0x04, 0x84, 0}, err: true},
+		{name: "indefinite length", data: []byte{0x30, 0x80, 0, 0}, err: true},
+		{name: "too short", data: []byte{0x04}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, contents, rest, err := berNext(tt.data)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("berNext(% x) succeeded, want an error", tt.data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("berNext(% x): %v", tt.data, err)
+			}
+			if tag != tt.tag || string(contents) != tt.contents || string(rest) != tt.rest {
+				t.Errorf("berNext(% x) = 0x%02x %q %q, want 0x%02x %q %q", tt.data, tag, contents, rest, tt.tag, tt.contents, tt.rest)
+			}
+		})
+	}
+}
+
+func TestBerTLVRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 0x7f, 0x80, 0xff, 0x100, 0xffff, 0x10000} {
+		body := bytes.Repeat([]byte{'x'}, n)
+		tag, contents, rest, err := berNext(berTLV(berOctets, body))
+		if err != nil || tag != berOctets || !bytes.Equal(contents, body) || len(rest) != 0 {
+			t.Errorf("berNext(berTLV(%d bytes)) = 0x%02x, %d bytes, %d left, %v", n, tag, len(contents), len(rest), err)
+		}
+	}
+	for _, n := range []int{0, 3, 127, 128, 255, 256, 65535} {
+		_, contents, _, err := berNext(berInt(berInteger, n))
+		if err != nil || berUint(contents) != n || contents[0]&0x80 != 0 {
+			t.Errorf("berInt(%d) encodes % x (%v)", n, contents, err)
+		}
+	}
+}
+
+func TestReadLDAPMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		tag     byte
+		err     bool
+	}{
+		{name: "bind response", message: bindSuccess, tag: ldapBindResponse},
+		{name: "search entry", message: entryAlice, tag: ldapSearchEntry},
+		{name: "search done", message: searchDoneSizeHit, tag: ldapSearchDone},
+		{name: "not a sequence", message: "04020000", err: true},
+		{name: "oversized", message: "3084ffffffff", err: true},
+		{name: "truncated", message: bindSuccess[:20], err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, _, err := readLDAPMessage(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.message))))
+			if tt.err {
+				if err == nil {
+					t.Fatal("readLDAPMessage succeeded, want an error")
+				}
+				return
+			}
+			if err != nil || tag != tt.tag {
+				t.Errorf("readLDAPMessage = 0x%02x, %v; want 0x%02x", tag, err, tt.tag)
+			}
+		})
+	}
+}
+
+func TestReadLDAPMessageSequence(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(decodeHex(t, entryAlice+entryBob+searchDoneSizeHit)))
+	var tags []byte
+	for {
+		tag, _, err := readLDAPMessage(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags = append(tags, tag)
+	}
+	if want := []byte{ldapSearchEntry, ldapSearchEntry, ldapSearchDone}; !bytes.Equal(tags, want) {
+		t.Errorf("read tags % x, want % x", tags, want)
+	}
+}
+
+func TestEntryName(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     string
+		attribute string
+		want      string
+	}{
+		{name: "name after another attribute", entry: entryAlice, attribute: "displayName", want: "Alice Smith"},
+		{name: "only attribute", entry: entryBob, attribute: "displayName", want: "Bob Jones"},
+		{name: "other attribute", entry: entryAlice, attribute: "objectClass", want: "inetOrgPerson"},
+		{name: "missing attribute", entry: entryBob, attribute: "cn", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, op, err := readLDAPMessage(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.entry))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &LDAPDirectory{cfg: LDAPConfig{NameAttribute: tt.attribute}}
+			if got := d.entryName(op); got != tt.want {
+				t.Errorf("entryName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPResult(t *testing.T) {
+	tests := []struct {
+		message string
+		code    int
+		err     string
+	}{
+		{message: bindSuccess},
+		{message: bindInvalidCreds, code: 49, err: "result code 49"},
+		{message: searchDoneSizeHit, code: ldapSizeLimitExceeded, err: "result code 4"},
+		{message: searchDoneNoBase, code: 32, err: "no such object"},
+	}
+	for _, tt := range tests {
+		_, op, err := readLDAPMessage(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.message))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := ldapResult(op, "test")
+		if code != tt.code || (err == nil) != (tt.err == "") || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("ldapResult(%s) = %d, %v; want %d, %q", tt.message, code, err, tt.code, tt.err)
+		}
+	}
+}
+
+// serveLDAP answers the bind and search of one lookup with the given responses to the search
+func serveLDAP(t *testing.T, bind string, search ...string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	bindResponse := decodeHex(t, bind)
+	var searchResponses []byte
+	for _, m := range search {
+		searchResponses = append(searchResponses, decodeHex(t, m)...)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if tag, _, err := readLDAPMessage(r); err != nil || tag != ldapBindRequest {
+			return
+		}
+		conn.Write(bindResponse)
+		if tag, _, err := readLDAPMessage(r); err != nil || tag != ldapSearchRequest {
+			return
+		}
+		conn.Write(searchResponses)
+		readLDAPMessage(r) // Unbind
+	}()
+	return ln.Addr().String()
+}
+
+func TestLDAPFetch(t *testing.T) {
+	tests := []struct {
+		name   string
+		bind   string
+		search []string
+		want   string
+		found  bool
+		err    bool
+	}{
+		{name: "found", bind: bindSuccess, search: []string{entryAlice, searchDone}, want: "Alice Smith", found: true},
+		{name: "shared extension exceeds size limit", bind: bindSuccess, search: []string{entryAlice, searchDoneSizeHit}, want: "Alice Smith", found: true},
+		{name: "not found", bind: bindSuccess, search: []string{searchDone}},
+		{name: "no such base", bind: bindSuccess, search: []string{searchDoneNoBase}, err: true},
+		{name: "bind refused", bind: bindInvalidCreds, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serveLDAP(t, tt.bind, tt.search...)
+			d, err := NewLDAPDirectory(LDAPConfig{
+				URL:           "ldap://" + addr,
+				BaseDN:        "ou=people,dc=example,dc=com",
+				Attribute:     "telephoneNumber",
+				NameAttribute: "displayName",
+			}, time.Minute, logrus.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			name, found, err := d.fetch(context.Background(), "1001")
+			if (err != nil) != tt.err {
+				t.Fatalf("fetch error = %v, want error %v", err, tt.err)
+			}
+			if name != tt.want || found != tt.found {
+				t.Errorf("fetch = %q, %v; want %q, %v", name, found, tt.want, tt.found)
+			}
+		})
+	}
+}
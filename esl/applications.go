@@ -0,0 +1,47 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleApplication records a dialplan application from CHANNEL_EXECUTE (completed false) or
+// CHANNEL_EXECUTE_COMPLETE (completed true). It does nothing unless application tracing is enabled.
+func (c *Client) handleApplication(ctx context.Context, msg *goesl.Message, uuid string, completed bool) {
+	if !c.traceApps {
+		return
+	}
+	name := msg.GetHeader("Application")
+	if name == "" {
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	a := &store.Application{
+		UUID:    uuid,
+		AppUUID: optionalHeader(msg, "Application-UUID"),
+		Name:    name,
+		Data:    optionalHeader(msg, "Application-Data"),
+	}
+	if completed {
+		a.CompletedAt = &at
+		a.Response = optionalHeader(msg, "Application-Response")
+		err = c.store.CompleteApplication(ctx, a)
+	} else {
+		a.StartedAt = &at
+		err = c.store.StartApplication(ctx, a)
+	}
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":        uuid,
+			"application": name,
+		}).Error("Failed to record dialplan application")
+	}
+}
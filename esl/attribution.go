@@ -0,0 +1,87 @@
+package esl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CallerIDCampaigns caches the caller_id_campaigns table so calls can be attributed at CHANNEL_CREATE
+// without a database lookup. Reload after changing the table; Run also reloads periodically so mappings
+// changed by another instance are picked up.
+type CallerIDCampaigns struct {
+	store *store.Store
+	log   *logrus.Logger
+
+	mu       sync.RWMutex
+	byNumber map[string]string // Normalized caller ID -> campaign
+}
+
+// NewCallerIDCampaigns creates an empty cache; call Reload to fill it
+func NewCallerIDCampaigns(s *store.Store, logger *logrus.Logger) *CallerIDCampaigns {
+	return &CallerIDCampaigns{store: s, log: logger, byNumber: make(map[string]string)}
+}
+
+// Reload replaces the cached mappings with the contents of the table
+func (t *CallerIDCampaigns) Reload(ctx context.Context) error {
+	mappings, err := t.store.GetCallerIDCampaigns(ctx)
+	if err != nil {
+		return err
+	}
+	byNumber := make(map[string]string, len(mappings))
+	for _, m := range mappings {
+		byNumber[m.CallerID] = m.Campaign
+	}
+
+	t.mu.Lock()
+	t.byNumber = byNumber
+	t.mu.Unlock()
+	return nil
+}
+
+// Run reloads the mappings every interval until ctx is cancelled
+func (t *CallerIDCampaigns) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Reload(ctx); err != nil {
+				t.log.WithError(err).Error("Failed to reload caller ID campaigns")
+			}
+		}
+	}
+}
+
+// Lookup returns the campaign number is mapped to
+func (t *CallerIDCampaigns) Lookup(number string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	campaign, ok := t.byNumber[store.NormalizeCallerID(number)]
+	return campaign, ok
+}
+
+// Attribute returns the campaign of a call: outbound calls by the caller ID they present, inbound calls
+// by the number dialled, so callbacks to a campaign number are credited to it. A nil table attributes nothing.
+func (t *CallerIDCampaigns) Attribute(call *store.Call) *string {
+	if t == nil {
+		return nil
+	}
+	number := call.Caller
+	if call.Direction == "inbound" {
+		number = call.Callee
+	}
+	if campaign, ok := t.Lookup(number); ok {
+		return &campaign
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package esl
+
+import (
+	"strings"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	authFailuresCounter = metrics.NewCounterVec("esl_auth_failures_total", "ESL connection attempts rejected because of a wrong password.", "node")
+	authFailedGauge     = metrics.NewGaugeVec("esl_auth_failed", "Whether ESL retries are suspended after repeated authentication failures (1) or not (0).")
+)
+
+// AuthPolicy controls how repeated authentication failures are handled. Unlike network failures they
+// do not fix themselves, so once every endpoint has rejected the password MaxFailures times in a row the
+// client stops retrying at the normal rate, reports auth_failed and alerts.
+type AuthPolicy struct {
+	MaxFailures   int           // Consecutive rejections per endpoint before giving up; 0 never gives up
+	RetryInterval time.Duration // Retry interval once given up; 0 waits for a restart
+}
+
+// isAuthError reports whether err is goesl rejecting the ESL password
+func isAuthError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Could not authenticate")
+}
+
+// noteAuthResult tracks consecutive authentication failures of the endpoint at addr. A nil or non-auth
+// error resets the endpoint's count.
+func (c *Client) noteAuthResult(addr string, err error) {
+	if !isAuthError(err) {
+		c.statusMu.Lock()
+		delete(c.status.authFailures, addr)
+		wasFailed := c.status.authFailed
+		c.status.authFailed = false
+		c.statusMu.Unlock()
+		if wasFailed && err == nil {
+			authFailedGauge.With().Set(0)
+			c.log.WithField("node", addr).Info("ESL authentication succeeded, retries resumed")
+		}
+		return
+	}
+
+	authFailuresCounter.With(addr).Inc()
+	if c.auth.MaxFailures <= 0 {
+		return
+	}
+
+	c.statusMu.Lock()
+	if c.status.authFailures == nil {
+		c.status.authFailures = make(map[string]int)
+	}
+	c.status.authFailures[addr]++
+	blocked := true
+	for _, ep := range c.endpoints {
+		if c.status.authFailures[ep.addr] < c.auth.MaxFailures {
+			blocked = false
+			break
+		}
+	}
+	newlyBlocked := blocked && !c.status.authFailed
+	c.status.authFailed = c.status.authFailed || blocked
+	c.statusMu.Unlock()
+
+	if !newlyBlocked {
+		return
+	}
+	authFailedGauge.With().Set(1)
+	c.log.WithFields(logrus.Fields{
+		"node":          addr,
+		"maxFailures":   c.auth.MaxFailures,
+		"retryInterval": c.auth.RetryInterval,
+	}).Error("ESL authentication failed repeatedly; check ESL_PASS. Suspending regular reconnection attempts")
+	c.notifier.Notify(alert.Alert{
+		Type:     "esl_auth_failed",
+		Severity: alert.SeverityCritical,
+		Message:  "FreeSWITCH rejected the ESL password on every endpoint; event logging has stopped",
+		Fields:   map[string]any{"node": addr, "failures": c.auth.MaxFailures},
+	})
+}
+
+// authBlocked reports whether reconnection attempts are suspended after repeated authentication failures
+func (c *Client) authBlocked() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.status.authFailed
+}
@@ -0,0 +1,62 @@
+package esl
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"gofreeswitchesl/metrics"
+)
+
+var (
+	reconnectAttemptsCounter = metrics.NewCounterVec("esl_reconnect_attempts_total", "ESL reconnection attempts, by result (success or failure).", "node", "result")
+	reconnectDelayGauge      = metrics.NewGaugeVec("esl_reconnect_backoff_seconds", "Delay before the next ESL reconnection attempt; 0 when connected.", "node")
+)
+
+// BackoffPolicy spaces out reconnection attempts: the delay starts at Initial, grows by Multiplier after
+// each consecutive failure up to Max, and is reduced by a random fraction of up to Jitter so that several
+// loggers reconnecting to the same switch spread out. Zero fields use DefaultBackoff's values.
+type BackoffPolicy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64 // 0 to 1
+}
+
+// DefaultBackoff is used for unset BackoffPolicy fields
+var DefaultBackoff = BackoffPolicy{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+
+// backoff tracks consecutive failures under a BackoffPolicy. It is used only by the reconnection manager.
+type backoff struct {
+	policy   BackoffPolicy
+	failures int
+}
+
+func newBackoff(p BackoffPolicy) backoff {
+	if p.Initial <= 0 {
+		p.Initial = DefaultBackoff.Initial
+	}
+	if p.Max <= 0 {
+		p.Max = DefaultBackoff.Max
+	}
+	p.Max = max(p.Max, p.Initial)
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultBackoff.Multiplier
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	return backoff{policy: p}
+}
+
+// next records a failure and returns the delay before the following attempt
+func (b *backoff) next() time.Duration {
+	delay := float64(b.policy.Initial) * math.Pow(b.policy.Multiplier, float64(b.failures))
+	delay = min(delay, float64(b.policy.Max))
+	b.failures++
+	delay -= delay * b.policy.Jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+// reset clears the failure count after a successful attempt
+func (b *backoff) reset() {
+	b.failures = 0
+}
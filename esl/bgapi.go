@@ -0,0 +1,115 @@
+package esl
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0x19/goesl"
+)
+
+// jobTimeout is how long a bgapi callback is kept waiting for its BACKGROUND_JOB event
+const jobTimeout = 2 * time.Minute
+
+// jobCallback receives the body of a BACKGROUND_JOB event
+type jobCallback func(body string)
+
+// pendingJob is a bgapi command awaiting its result
+type pendingJob struct {
+	callback jobCallback
+	issued   time.Time
+}
+
+// jobRegistry correlates bgapi commands with their BACKGROUND_JOB results by Job-UUID
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]pendingJob
+}
+
+// add registers callback for jobUUID, discarding jobs that never completed
+func (r *jobRegistry) add(jobUUID string, callback jobCallback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobs == nil {
+		r.jobs = make(map[string]pendingJob)
+	}
+	for id, job := range r.jobs {
+		if time.Since(job.issued) > jobTimeout {
+			delete(r.jobs, id)
+		}
+	}
+	r.jobs[jobUUID] = pendingJob{callback: callback, issued: time.Now()}
+}
+
+// len returns the number of jobs awaiting a result
+func (r *jobRegistry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.jobs)
+}
+
+// take removes and returns the callback for jobUUID
+func (r *jobRegistry) take(jobUUID string) (jobCallback, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobUUID]
+	if ok {
+		delete(r.jobs, jobUUID)
+	}
+	return job.callback, ok
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// bgapi runs command in the background on FreeSWITCH. If callback is non-nil it is invoked with the
+// job output when the matching BACKGROUND_JOB event arrives. The Job-UUID is returned.
+func (c *Client) bgapi(command string, callback jobCallback) (string, error) {
+	conn := c.currentConn()
+	if conn == nil {
+		return "", ErrESLNotConnected
+	}
+	jobUUID := newUUID()
+	if callback != nil {
+		c.jobs.add(jobUUID, callback)
+	}
+	// Supplying our own Job-UUID lets us correlate the result without parsing the command/reply
+	if err := conn.Send(fmt.Sprintf("bgapi %s\nJob-UUID: %s", command, jobUUID)); err != nil {
+		c.jobs.take(jobUUID)
+		return "", err
+	}
+	return jobUUID, nil
+}
+
+// bgapiWait runs command in the background and waits for its output or for ctx to end
+func (c *Client) bgapiWait(ctx context.Context, command string) (string, error) {
+	done := make(chan string, 1)
+	if _, err := c.bgapi(command, func(body string) { done <- body }); err != nil {
+		return "", err
+	}
+	select {
+	case body := <-done:
+		return body, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// handleBackgroundJob dispatches a BACKGROUND_JOB result to its registered callback
+func (c *Client) handleBackgroundJob(msg *goesl.Message) {
+	jobUUID := msg.GetHeader("Job-UUID")
+	callback, ok := c.jobs.take(jobUUID)
+	if !ok {
+		return
+	}
+	callback(strings.TrimSpace(string(msg.Body)))
+}
@@ -0,0 +1,114 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Default text-to-speech engine and voice used when a BroadcastRequest carries text
+const (
+	defaultTTSEngine = "flite"
+	defaultTTSVoice  = "kal"
+)
+
+// Legs a broadcast can be played to
+const (
+	LegA    = "aleg"
+	LegB    = "bleg"
+	LegBoth = "both"
+)
+
+var (
+	ErrInvalidBroadcast = errors.New("invalid broadcast request")
+	ErrBroadcastFailed  = errors.New("broadcast failed")
+)
+
+// BroadcastRequest plays an audio file or a text-to-speech announcement into an active call
+type BroadcastRequest struct {
+	CallUUID  string
+	File      string // Sound file path or URL; mutually exclusive with Text
+	Text      string // Spoken with TTSEngine/TTSVoice
+	TTSEngine string // Defaults to flite
+	TTSVoice  string // Defaults to kal
+	Leg       string // LegA (default), LegB or LegBoth
+}
+
+// command renders the uuid_broadcast command for the request
+func (r BroadcastRequest) command() (string, error) {
+	if !callUUIDPattern.MatchString(r.CallUUID) {
+		return "", fmt.Errorf("%w: call uuid", ErrInvalidBroadcast)
+	}
+	leg := r.Leg
+	if leg == "" {
+		leg = LegA
+	}
+	if leg != LegA && leg != LegB && leg != LegBoth {
+		return "", fmt.Errorf("%w: leg %q", ErrInvalidBroadcast, leg)
+	}
+
+	var media string
+	switch {
+	case r.File != "" && r.Text != "":
+		return "", fmt.Errorf("%w: file and text are mutually exclusive", ErrInvalidBroadcast)
+	case r.File != "":
+		// uuid_broadcast splits its arguments on whitespace
+		if strings.ContainsAny(r.File, " \t\r\n") {
+			return "", fmt.Errorf("%w: file", ErrInvalidBroadcast)
+		}
+		media = r.File
+	case r.Text != "":
+		engine, voice := r.TTSEngine, r.TTSVoice
+		if engine == "" {
+			engine = defaultTTSEngine
+		}
+		if voice == "" {
+			voice = defaultTTSVoice
+		}
+		for _, part := range []string{engine, voice, r.Text} {
+			if strings.ContainsAny(part, "|\r\n") {
+				return "", fmt.Errorf("%w: text", ErrInvalidBroadcast)
+			}
+		}
+		if strings.ContainsAny(engine+voice, " \t") {
+			return "", fmt.Errorf("%w: tts engine or voice", ErrInvalidBroadcast)
+		}
+		// Quoted so uuid_broadcast, which splits its arguments on whitespace, keeps the text in one piece
+		media = quoteArg(fmt.Sprintf("speak::%s|%s|%s", engine, voice, r.Text))
+	default:
+		return "", fmt.Errorf("%w: file or text is required", ErrInvalidBroadcast)
+	}
+	return fmt.Sprintf("uuid_broadcast %s %s %s", r.CallUUID, media, leg), nil
+}
+
+// argQuoter escapes the characters FreeSWITCH unescapes inside a single-quoted API argument
+var argQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteArg single-quotes an API command argument so FreeSWITCH reads it as one argument, spaces included
+func quoteArg(arg string) string {
+	return "'" + argQuoter.Replace(arg) + "'"
+}
+
+// Validate reports whether the request can be rendered into a safe uuid_broadcast command
+func (r BroadcastRequest) Validate() error {
+	_, err := r.command()
+	return err
+}
+
+// Broadcast queues the announcement on the call. It returns once FreeSWITCH has accepted or rejected
+// the broadcast, not when playback ends; a rejection (e.g. no such channel) returns ErrBroadcastFailed.
+func (c *Client) Broadcast(ctx context.Context, req BroadcastRequest) error {
+	command, err := req.command()
+	if err != nil {
+		return err
+	}
+	body, err := c.bgapiWait(ctx, command)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(body, "+OK") {
+		return fmt.Errorf("%w: %s", ErrBroadcastFailed, strings.TrimSpace(strings.TrimPrefix(body, "-ERR")))
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package esl
+
+import (
+	"time"
+
+	"gofreeswitchesl/calendar"
+	"gofreeswitchesl/store"
+)
+
+// businessHours reports whether the call started within its domain's business hours, or nil when no
+// calendar applies to the domain
+func businessHours(cals *calendar.Calendars, domain *string, start time.Time) *bool {
+	var d string
+	if domain != nil {
+		d = *domain
+	}
+	in, ok := cals.InHours(d, start)
+	if !ok {
+		return nil
+	}
+	return &in
+}
+
+// stampBusinessHours sets call.BusinessHours from the configured calendars
+func (c *Client) stampBusinessHours(call *store.Call) {
+	call.BusinessHours = businessHours(c.calendars, call.Domain, call.StartTime)
+}
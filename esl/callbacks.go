@@ -0,0 +1,104 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// CallbackPolicy configures callback tracking. Offers and acceptances are CUSTOM events fired on the
+// original call (e.g. with the event dialplan application); an empty subclass disables that event.
+type CallbackPolicy struct {
+	OfferSubclass  string
+	AcceptSubclass string
+	MatchWindow    time.Duration // How long after acceptance an outbound call to the number counts as the callback
+}
+
+// callbackNumber is the number to call back: an explicit Callback-Number header or callback_number
+// variable, falling back to the caller ID
+func callbackNumber(msg *goesl.Message) string {
+	for _, header := range []string{"Callback-Number", "variable_callback_number", "Caller-Caller-ID-Number"} {
+		if v := msg.GetHeader(header); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// callbackQueue is the queue the callback was offered from, if any
+func callbackQueue(msg *goesl.Message) *string {
+	if q := optionalHeader(msg, "Callback-Queue"); q != nil {
+		return q
+	}
+	return optionalHeader(msg, "variable_cc_queue")
+}
+
+// handleCustomEvent dispatches CUSTOM events by subclass
+func (c *Client) handleCustomEvent(ctx context.Context, msg *goesl.Message, uuid string) {
+	subclass := msg.GetHeader("Event-Subclass")
+	if subclass == "" {
+		return
+	}
+	switch subclass {
+	case c.callbacks.OfferSubclass, c.callbacks.AcceptSubclass:
+		c.handleCallbackEvent(ctx, msg, uuid, subclass == c.callbacks.AcceptSubclass)
+	case "sofia::transferor":
+		c.handleTransferor(ctx, msg, uuid)
+	case "sofia::transferee":
+		c.handleTransferee(ctx, msg, uuid)
+	case "spandsp::txfaxresult":
+		c.handleFaxResult(ctx, msg, uuid, false)
+	case "spandsp::rxfaxresult":
+		c.handleFaxResult(ctx, msg, uuid, true)
+	case "valet_parking::info":
+		c.handleValetEvent(ctx, msg, uuid)
+	}
+}
+
+// handleCallbackEvent records a callback offer or acceptance on the call uuid
+func (c *Client) handleCallbackEvent(ctx context.Context, msg *goesl.Message, uuid string, accepted bool) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	number := callbackNumber(msg)
+	if number == "" {
+		c.log.WithField("uuid", uuid).Warn("Callback event without a number, ignoring")
+		return
+	}
+	queue := callbackQueue(msg)
+
+	if accepted {
+		err = c.store.RecordCallbackAcceptance(ctx, uuid, number, queue, at)
+	} else {
+		err = c.store.RecordCallbackOffer(ctx, uuid, number, queue, at)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record callback event")
+		return
+	}
+	c.log.WithFields(logrus.Fields{
+		"uuid":     uuid,
+		"number":   c.store.Redacted(number),
+		"accepted": accepted,
+	}).Info("Recorded callback event")
+}
+
+// linkCallback links a new outbound call to the callback it fulfils, either named by its callback_of
+// variable or matched on the dialled number
+func (c *Client) linkCallback(ctx context.Context, msg *goesl.Message, call *store.Call) {
+	if call.Direction != "outbound" || (c.callbacks.AcceptSubclass == "" && c.callbacks.OfferSubclass == "") {
+		return
+	}
+	originalUUID := msg.GetHeader("variable_callback_of")
+	if originalUUID == "" && c.callbacks.MatchWindow <= 0 {
+		return
+	}
+	if err := c.store.LinkCallbackCall(ctx, call.UUID, originalUUID, call.Callee, call.StartTime, c.callbacks.MatchWindow); err != nil {
+		c.log.WithError(err).WithField("uuid", call.UUID).Error("Failed to link callback call")
+	}
+}
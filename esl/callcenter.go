@@ -0,0 +1,93 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleCallcenterEvent records a mod_callcenter callcenter::info event: agent status and state changes,
+// members joining and leaving queues, agents answering members, and queue member counts
+func (c *Client) handleCallcenterEvent(ctx context.Context, msg *goesl.Message) {
+	action := msg.GetHeader("CC-Action")
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	queue := msg.GetHeader("CC-Queue")
+	agent := msg.GetHeader("CC-Agent")
+	member := firstHeader(msg, "CC-Member-Session-UUID", "Unique-ID") // The member's channel, as in queue_calls
+
+	switch action {
+	case "agent-status-change":
+		if agent == "" {
+			return
+		}
+		err = c.store.SetAgentValue(ctx, agent, store.AgentStatus, msg.GetHeader("CC-Agent-Status"), at)
+	case "agent-state-change":
+		if agent == "" {
+			return
+		}
+		err = c.store.SetAgentValue(ctx, agent, store.AgentState, msg.GetHeader("CC-Agent-State"), at)
+	case "member-queue-start":
+		if queue == "" || member == "" {
+			return
+		}
+		err = c.store.JoinQueue(ctx, &store.QueueCall{
+			UUID:      member,
+			Queue:     queue,
+			JoinedAt:  epochOr(msg, "CC-Member-Joined-Time", at),
+			CIDNumber: optionalHeader(msg, "CC-Member-CID-Number"),
+			CIDName:   optionalHeader(msg, "CC-Member-CID-Name"),
+		})
+	case "bridge-agent-start":
+		if member == "" || agent == "" {
+			return
+		}
+		err = c.store.BridgeQueueCall(ctx, member, agent, epochOr(msg, "CC-Agent-Answered-Time", at))
+	case "member-queue-end":
+		if member == "" {
+			return
+		}
+		qc := &store.QueueCall{
+			UUID:       member,
+			Agent:      optionalHeader(msg, "CC-Agent"),
+			AnsweredAt: epochHeader(msg, "CC-Agent-Answered-Time"),
+			LeftAt:     epochOr(msg, "CC-Member-Leaving-Time", at),
+		}
+		qc.Outcome = cancelOutcome(qc.AnsweredAt != nil, msg.GetHeader("CC-Cancel-Reason"))
+		if qc.AnsweredAt != nil {
+			qc.LeftAt = *qc.AnsweredAt // Same convention as the hangup-time record
+		}
+		err = c.store.EndQueueCall(ctx, qc)
+	case "members-count":
+		count, convErr := strconv.Atoi(msg.GetHeader("CC-Count"))
+		if queue == "" || convErr != nil {
+			return
+		}
+		err = c.store.SetQueueCount(ctx, queue, count, at)
+	default:
+		return // agent-offering, bridge-agent-end/fail and others add nothing the tables keep
+	}
+
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"action": action,
+			"queue":  queue,
+			"agent":  agent,
+		}).Error("Failed to record callcenter event")
+	}
+}
+
+// epochOr parses a Unix-seconds header, falling back to def when it is absent
+func epochOr(msg *goesl.Message, name string, def time.Time) time.Time {
+	if t := epochHeader(msg, name); t != nil {
+		return *t
+	}
+	return def
+}
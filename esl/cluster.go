@@ -0,0 +1,160 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"gofreeswitchesl/store"
+)
+
+// Server is one FreeSWITCH event socket to connect to
+type Server struct {
+	Addr string // host:port
+	Pass string
+}
+
+// ParseServers parses ESL_SERVERS entries of the form "host:port" or "host:port password". Entries without
+// a password use defaultPass.
+func ParseServers(entries []string, defaultPass string) ([]Server, error) {
+	servers := make([]Server, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		addr, pass, _ := strings.Cut(strings.TrimSpace(e), " ")
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("server %q: expected host:port", e)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("server %q listed twice", addr)
+		}
+		seen[addr] = true
+		if pass = strings.TrimSpace(pass); pass == "" {
+			pass = defaultPass
+		}
+		servers = append(servers, Server{Addr: addr, Pass: pass})
+	}
+	return servers, nil
+}
+
+// Cluster runs one Client, and so one connection, per FreeSWITCH server. Commands about a call are sent to
+// the server that recorded it; others go to the first server.
+type Cluster struct {
+	clients []*Client
+	store   *store.Store
+}
+
+// NewCluster groups clients, which must share the store. The first client is the default for commands
+// that are not tied to a call.
+func NewCluster(s *store.Store, clients ...*Client) *Cluster {
+	return &Cluster{clients: clients, store: s}
+}
+
+// Start starts every client
+func (cl *Cluster) Start(ctx context.Context) error {
+	var errs []error
+	for _, c := range cl.clients {
+		if err := c.Start(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.node(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every connection
+func (cl *Cluster) Close() error {
+	var errs []error
+	for _, c := range cl.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.node(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Statuses returns the connection status of each node, in configuration order
+func (cl *Cluster) Statuses() []Status {
+	statuses := make([]Status, len(cl.clients))
+	for i, c := range cl.clients {
+		statuses[i] = c.Status()
+	}
+	return statuses
+}
+
+// State summarizes the nodes' states: auth_failed if any node gave up on authentication, connected if any
+// node is connected, disconnected otherwise
+func (cl *Cluster) State() string {
+	state := StateDisconnected
+	for _, st := range cl.Statuses() {
+		switch st.State {
+		case StateAuthFailed:
+			return StateAuthFailed
+		case StateConnected:
+			state = StateConnected
+		}
+	}
+	return state
+}
+
+// forCall returns the client connected to the server that recorded the call, falling back to the first
+// client for unknown calls and calls recorded before their node was stored
+func (cl *Cluster) forCall(ctx context.Context, callUUID string) *Client {
+	if len(cl.clients) == 1 {
+		return cl.clients[0]
+	}
+	node, err := cl.store.GetCallNode(ctx, callUUID)
+	if err == nil && node != "" {
+		for _, c := range cl.clients {
+			if c.serves(node) {
+				return c
+			}
+		}
+	}
+	return cl.clients[0]
+}
+
+// Park parks a call on the server handling it; see Client.Park
+func (cl *Cluster) Park(ctx context.Context, callUUID, lot string, slot int) error {
+	return cl.forCall(ctx, callUUID).Park(ctx, callUUID, lot, slot)
+}
+
+// Retrieve bridges a parked call on the server handling it; see Client.Retrieve
+func (cl *Cluster) Retrieve(ctx context.Context, callUUID, extension, dialplanContext string) error {
+	return cl.forCall(ctx, callUUID).Retrieve(ctx, callUUID, extension, dialplanContext)
+}
+
+// Broadcast plays audio into a call on the server handling it; see Client.Broadcast
+func (cl *Cluster) Broadcast(ctx context.Context, req BroadcastRequest) error {
+	return cl.forCall(ctx, req.CallUUID).Broadcast(ctx, req)
+}
+
+// Supervise places the supervisor leg on the server handling the monitored call; see Client.Supervise
+func (cl *Cluster) Supervise(ctx context.Context, req SuperviseRequest) (OriginateResult, error) {
+	return cl.forCall(ctx, req.CallUUID).Supervise(ctx, req)
+}
+
+// Originate places a call through the first connected server; see Client.Originate
+func (cl *Cluster) Originate(ctx context.Context, req OriginateRequest) (OriginateResult, error) {
+	for _, c := range cl.clients {
+		if c.Status().Connected {
+			return c.Originate(ctx, req)
+		}
+	}
+	return cl.clients[0].Originate(ctx, req)
+}
+
+// SetExtensionFeature applies a feature change on every server, since the extension may register with any
+// of them; see Client.SetExtensionFeature
+func (cl *Cluster) SetExtensionFeature(ctx context.Context, realm, extension, domain, value string) error {
+	if len(cl.clients) == 1 {
+		return cl.clients[0].SetExtensionFeature(ctx, realm, extension, domain, value)
+	}
+	var errs []error
+	for _, c := range cl.clients {
+		if err := c.SetExtensionFeature(ctx, realm, extension, domain, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.node(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleConferenceEvent records a conference::maintenance event. Only room lifecycle, membership and
+// mute actions are persisted; the many other actions (floor changes, talking, DTMF) are ignored.
+func (c *Client) handleConferenceEvent(ctx context.Context, msg *goesl.Message) {
+	action := msg.GetHeader("Action")
+	confUUID := msg.GetHeader("Conference-Unique-ID")
+	if confUUID == "" {
+		c.log.WithField("action", action).Debug("conference::maintenance without Conference-Unique-ID, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	conf := &store.Conference{
+		ConferenceUUID: confUUID,
+		Name:           msg.GetHeader("Conference-Name"),
+		Profile:        optionalHeader(msg, "Conference-Profile-Name"),
+		StartedAt:      at,
+	}
+
+	switch action {
+	case "conference-create":
+		err = c.store.StartConference(ctx, conf)
+	case "conference-destroy":
+		err = c.store.EndConference(ctx, confUUID, at)
+	case "add-member", "del-member", "mute-member", "unmute-member":
+		memberID, convErr := strconv.Atoi(msg.GetHeader("Member-ID"))
+		if convErr != nil {
+			c.log.WithFields(logrus.Fields{
+				"action":         action,
+				"conferenceUUID": confUUID,
+			}).Warn("Conference member event without valid Member-ID, skipping")
+			return
+		}
+		switch action {
+		case "add-member":
+			size, _ := strconv.Atoi(msg.GetHeader("Conference-Size"))
+			err = c.store.AddConferenceMember(ctx, conf, &store.ConferenceMember{
+				ConferenceUUID: confUUID,
+				MemberID:       memberID,
+				CallUUID:       optionalHeader(msg, "Unique-ID"),
+				CallerNumber:   optionalHeader(msg, "Caller-Caller-ID-Number"),
+				CallerName:     optionalHeader(msg, "Caller-Caller-ID-Name"),
+				JoinedAt:       at,
+			}, size)
+		case "del-member":
+			err = c.store.RemoveConferenceMember(ctx, confUUID, memberID, at)
+		default:
+			err = c.store.SetConferenceMemberMute(ctx, confUUID, memberID, action == "mute-member", at)
+		}
+	default:
+		return
+	}
+
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"action":         action,
+			"conferenceUUID": confUUID,
+		}).Error("Failed to record conference event")
+	}
+}
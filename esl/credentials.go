@@ -0,0 +1,144 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+var passwordRotationsCounter = metrics.NewCounterVec("esl_password_rotations_total", "ESL passwords changed at runtime.", "node")
+
+// ErrUnknownNode is returned when a password change names a node no client connects to
+var ErrUnknownNode = errors.New("unknown ESL node")
+
+// SetPassword changes the password of the endpoint at addr, or of every endpoint when addr is empty, and
+// reports whether any endpoint matched. The new password is used from the next connection attempt: at
+// once if the client is disconnected or has given up on authentication, otherwise on the next reconnect.
+// With reconnect set the current connection is closed so that happens now.
+func (c *Client) SetPassword(addr, pass string, reconnect bool) bool {
+	return c.changePassword(func(ep endpoint) bool { return addr == "" || ep.addr == addr }, pass, reconnect)
+}
+
+// ReplacePassword changes every endpoint using old to pass; see SetPassword
+func (c *Client) ReplacePassword(old, pass string, reconnect bool) bool {
+	return c.changePassword(func(ep endpoint) bool { return ep.pass == old }, pass, reconnect)
+}
+
+// changePassword sets pass on the endpoints matching match and reports whether any did
+func (c *Client) changePassword(match func(endpoint) bool, pass string, reconnect bool) bool {
+	var changed []string
+	matched := false
+	c.credMu.Lock()
+	for i := range c.endpoints {
+		if !match(c.endpoints[i]) {
+			continue
+		}
+		matched = true
+		if c.endpoints[i].pass != pass {
+			c.endpoints[i].pass = pass
+			changed = append(changed, c.endpoints[i].addr)
+		}
+	}
+	c.credMu.Unlock()
+	if !matched {
+		return false
+	}
+
+	for _, addr := range changed {
+		passwordRotationsCounter.With(addr).Inc()
+	}
+	if len(changed) > 0 {
+		c.clearAuthFailures()
+		c.log.WithField("nodes", changed).Info("ESL password changed")
+	}
+
+	switch {
+	case c.State() == ConnDisconnected:
+		// Retry now instead of waiting out the backoff, which may be the long auth retry interval
+		select {
+		case c.retryNow <- struct{}{}:
+		default:
+		}
+	case reconnect:
+		if conn := c.currentConn(); conn != nil {
+			c.log.WithField("node", c.node()).Info("Reconnecting to apply the new ESL password")
+			conn.Close() // The event loop sees the read error and triggers a reconnect
+		}
+	}
+	return true
+}
+
+// clearAuthFailures forgets past authentication failures so a suspended client retries at the normal rate
+func (c *Client) clearAuthFailures() {
+	c.statusMu.Lock()
+	wasFailed := c.status.authFailed
+	c.status.authFailures = nil
+	c.status.authFailed = false
+	c.statusMu.Unlock()
+	if wasFailed {
+		authFailedGauge.With().Set(0)
+	}
+}
+
+// SetPassword changes the password of node (a server or backup address), or of every server when node is
+// empty; see Client.SetPassword. It returns ErrUnknownNode when no client connects to node.
+func (cl *Cluster) SetPassword(node, pass string, reconnect bool) error {
+	matched := false
+	for _, c := range cl.clients {
+		if c.SetPassword(node, pass, reconnect) {
+			matched = true
+		}
+	}
+	if !matched {
+		return ErrUnknownNode
+	}
+	return nil
+}
+
+// ReadPasswordFile reads an ESL password from a secret file, ignoring surrounding whitespace
+func ReadPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	pass := strings.TrimSpace(string(data))
+	if pass == "" {
+		return "", errors.New("password file is empty")
+	}
+	return pass, nil
+}
+
+// WatchPasswordFile re-reads the password file at path every interval until ctx is cancelled. When its
+// contents change, every endpoint still using the previous contents (current at start) switches to the
+// new password on its next reconnect; endpoints with their own passwords are left alone.
+func (cl *Cluster) WatchPasswordFile(ctx context.Context, path, current string, interval time.Duration, log *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pass, err := ReadPasswordFile(path)
+			if err != nil {
+				log.WithError(err).WithField("path", path).Warn("Failed to read ESL password file; keeping the current password")
+				continue
+			}
+			if pass == current {
+				continue
+			}
+			for _, c := range cl.clients {
+				c.ReplacePassword(current, pass, false)
+			}
+			current = pass
+			log.WithField("path", path).Info("ESL password file changed; new password used from the next reconnect")
+		}
+	}
+}
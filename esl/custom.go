@@ -0,0 +1,109 @@
+package esl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/0x19/goesl"
+)
+
+// recordCustomColumns copies the channel variables mapped by CUSTOM_COLUMNS into their call columns
+func (c *Client) recordCustomColumns(ctx context.Context, msg *goesl.Message, uuid string) {
+	cols := c.store.CustomColumns()
+	if len(cols) == 0 {
+		return
+	}
+	values := make(map[string]string, len(cols))
+	for _, col := range cols {
+		if v := msg.GetHeader(col.Variable); v != "" {
+			values[col.Name] = v
+		}
+	}
+	if err := c.store.SetCallCustomFields(ctx, uuid, values); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to store custom column values")
+	}
+}
+
+// variableSelector picks channel variables off events by exact name, or by prefix for entries ending in *.
+// Names are kept without the variable_ prefix.
+type variableSelector struct {
+	names    []string
+	prefixes []string
+}
+
+// newVariableSelector parses SIP_HEADERS/CHANNEL_VARIABLES entries, with or without the variable_ prefix
+func newVariableSelector(entries []string) variableSelector {
+	var s variableSelector
+	for _, entry := range entries {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), "variable_")
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if prefix != "" {
+				s.prefixes = append(s.prefixes, prefix)
+			}
+		} else if entry != "" {
+			s.names = append(s.names, entry)
+		}
+	}
+	return s
+}
+
+// empty reports whether the selector picks nothing
+func (s variableSelector) empty() bool {
+	return len(s.names) == 0 && len(s.prefixes) == 0
+}
+
+// collect returns the selected, non-empty channel variables of msg keyed by name, or nil if there are none
+func (s variableSelector) collect(msg *goesl.Message) map[string]string {
+	vars := make(map[string]string)
+	for _, name := range s.names {
+		if v := msg.GetHeader("variable_" + name); v != "" {
+			vars[name] = v
+		}
+	}
+	if len(s.prefixes) > 0 {
+		for header, v := range msg.Headers {
+			name, ok := strings.CutPrefix(header, "variable_")
+			if !ok || v == "" {
+				continue
+			}
+			for _, prefix := range s.prefixes {
+				if strings.HasPrefix(name, prefix) {
+					vars[name] = v
+					break
+				}
+			}
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	return vars
+}
+
+// recordSIPHeaders merges the SIP_HEADERS channel variables present on the event into the call's sip_headers
+func (c *Client) recordSIPHeaders(ctx context.Context, msg *goesl.Message, uuid string) {
+	if c.sipHeaders.empty() {
+		return
+	}
+	headers := c.sipHeaders.collect(msg)
+	if headers == nil {
+		return
+	}
+	if err := c.store.MergeCallSIPHeaders(ctx, uuid, headers); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to store SIP headers")
+	}
+}
+
+// recordChannelVariables merges the CHANNEL_VARIABLES present on the event into the call's variables
+func (c *Client) recordChannelVariables(ctx context.Context, msg *goesl.Message, uuid string) {
+	if c.channelVars.empty() {
+		return
+	}
+	vars := c.channelVars.collect(msg)
+	if vars == nil {
+		return
+	}
+	if err := c.store.MergeCallVariables(ctx, uuid, vars); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to store channel variables")
+	}
+}
@@ -0,0 +1,100 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+var deadLetteredCounter = metrics.NewCounterVec("esl_events_dead_lettered_total", "Events set aside in dead_letter_events, by stage.", "node", "stage")
+
+// ErrDatabaseUnavailable is returned when an event's writes could not reach the database; the event is
+// fine and should be handled again once the database is back
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// process handles msg once, skipping the writes applied on earlier attempts at it (see
+// store.WithWriteTracking), and returns the writes applied so far. When it cannot be handled it returns the
+// dead-letter stage that failed and the error, or ErrDatabaseUnavailable with no stage when the database
+// was unreachable.
+func (c *Client) process(ctx context.Context, msg *goesl.Message, applied []string) (string, []string, error) {
+	if trackedEvents[msg.GetHeader("Event-Name")] {
+		if _, err := eventTime(msg); err != nil {
+			return store.DeadLetterParse, applied, err
+		}
+	}
+
+	tracked := store.WithWriteTracking(ctx, applied)
+	if err := c.safeHandleEvent(tracked, msg); err != nil {
+		return store.DeadLetterPanic, store.AppliedWrites(tracked), err
+	}
+	if store.WriteUnavailable(tracked) {
+		return "", store.AppliedWrites(tracked), ErrDatabaseUnavailable
+	}
+	if err := store.WriteRejected(tracked); err != nil {
+		return store.DeadLetterPersist, store.AppliedWrites(tracked), err
+	}
+	return "", store.AppliedWrites(tracked), nil
+}
+
+// deadLetterEvent stores msg in dead_letter_events with the error that stopped it and the writes of it
+// that went through
+func (c *Client) deadLetterEvent(ctx context.Context, msg *goesl.Message, applied []string, stage string, cause error, attempts int) {
+	eventName := msg.GetHeader("Event-Name")
+	uuid := msg.GetHeader("Unique-ID")
+	deadLetteredCounter.With(c.node(), stage).Inc()
+	c.log.WithError(cause).WithFields(logrus.Fields{
+		"eventName": eventName,
+		"uuid":      uuid,
+		"stage":     stage,
+		"attempts":  attempts,
+	}).Error("Event could not be handled; recording it in dead_letter_events")
+
+	err := c.store.AddDeadLetter(context.WithoutCancel(ctx), &store.DeadLetter{
+		Node:          c.node(),
+		EventName:     eventName,
+		UUID:          uuid,
+		Stage:         stage,
+		Error:         cause.Error(),
+		Headers:       msg.Headers,
+		Body:          string(msg.Body),
+		Attempts:      attempts,
+		AppliedWrites: applied,
+		FailedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		c.log.WithError(err).WithField("eventName", eventName).Error("Failed to record dead-letter event")
+	}
+}
+
+// Reprocess handles a dead-lettered event again, once, skipping the writes that went through before and
+// adding those that go through now to d.AppliedWrites. It returns ErrDatabaseUnavailable when the database
+// could not be reached, or the error that stopped the event again; it does not record a new dead letter.
+func (c *Client) Reprocess(ctx context.Context, d *store.DeadLetter) error {
+	msg := &goesl.Message{Headers: c.store.OpenHeaders(d.Headers), Body: []byte(d.Body)} // Read sealed for some roles
+	stage, applied, err := c.process(ctx, msg, d.AppliedWrites)
+	d.AppliedWrites = applied
+	if err != nil && stage != "" {
+		return fmt.Errorf("%s: %w", stage, err)
+	}
+	return err
+}
+
+// Reprocess handles a dead-lettered event again on the client for the node that received it, or the
+// first client when that node is no longer configured; see Client.Reprocess
+func (cl *Cluster) Reprocess(ctx context.Context, d *store.DeadLetter) error {
+	client := cl.clients[0]
+	for _, c := range cl.clients {
+		if c.serves(d.Node) {
+			client = c
+			break
+		}
+	}
+	return client.Reprocess(ctx, d)
+}
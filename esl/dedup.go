@@ -0,0 +1,142 @@
+package esl
+
+import (
+	"strconv"
+	"sync"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	duplicateEventsCounter  = metrics.NewCounterVec("esl_events_duplicate_total", "Events skipped because the same event (Event-UUID, or Core-UUID and Event-Sequence) was already received.", "node", "event")
+	outOfOrderEventsCounter = metrics.NewCounterVec("esl_events_out_of_order_total", "Events older (by Event-Sequence) than one already handled for the same call, by action (skipped, or handled for critical events).", "node", "event", "action")
+)
+
+// recentMap is a map that keeps at most size keys, forgetting the oldest insertions first
+type recentMap[V any] struct {
+	values map[string]V
+	order  []string // Ring of keys in insertion order
+	next   int
+}
+
+func newRecentMap[V any](size int) *recentMap[V] {
+	return &recentMap[V]{values: make(map[string]V, size), order: make([]string, size)}
+}
+
+func (m *recentMap[V]) get(key string) (V, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *recentMap[V]) set(key string, v V) {
+	if _, ok := m.values[key]; !ok {
+		if old := m.order[m.next]; old != "" {
+			delete(m.values, old)
+		}
+		m.order[m.next] = key
+		m.next = (m.next + 1) % len(m.order)
+	}
+	m.values[key] = v
+}
+
+// eventGuard recognizes events received twice, e.g. on both the inbound and an outbound socket, and
+// events arriving after a later event of the same call was handled. It remembers the last size events
+// and calls; a nil guard admits everything.
+type eventGuard struct {
+	mu   sync.Mutex
+	seen *recentMap[struct{}] // Keys of recently received events
+	last *recentMap[uint64]   // Highest Event-Sequence handled per Core-UUID and Unique-ID
+}
+
+func newEventGuard(size int) *eventGuard {
+	if size <= 0 {
+		return nil
+	}
+	return &eventGuard{seen: newRecentMap[struct{}](size), last: newRecentMap[uint64](size)}
+}
+
+// eventKey identifies one event of a switch: its Event-UUID, or its Event-Sequence on that switch. Events
+// with neither have no key and are never taken for duplicates.
+func eventKey(msg *goesl.Message) string {
+	if id := msg.GetHeader("Event-UUID"); id != "" {
+		return id
+	}
+	if seq := msg.GetHeader("Event-Sequence"); seq != "" {
+		return msg.GetHeader("Core-UUID") + "/" + seq
+	}
+	return ""
+}
+
+// duplicate records msg and reports whether it was already received
+func (g *eventGuard) duplicate(msg *goesl.Message) bool {
+	key := eventKey(msg)
+	if g == nil || key == "" {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen.get(key); ok {
+		return true
+	}
+	g.seen.set(key, struct{}{})
+	return false
+}
+
+// stale records the Event-Sequence of msg for its call and reports whether a later event of the call
+// was already handled
+func (g *eventGuard) stale(msg *goesl.Message, uuid string) bool {
+	if g == nil || uuid == "" {
+		return false
+	}
+	seq, err := strconv.ParseUint(msg.GetHeader("Event-Sequence"), 10, 64)
+	if err != nil {
+		return false
+	}
+	key := msg.GetHeader("Core-UUID") + "/" + uuid // Sequences restart with the switch
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if last, ok := g.last.get(key); ok && seq < last {
+		return true
+	}
+	g.last.set(key, seq)
+	return false
+}
+
+// skipDuplicate reports whether msg was already received and should not be dispatched again
+func (c *Client) skipDuplicate(msg *goesl.Message, eventName string) bool {
+	if !c.guard.duplicate(msg) {
+		return false
+	}
+	duplicateEventsCounter.With(c.node(), eventName).Inc()
+	c.log.WithFields(logrus.Fields{
+		"eventName": eventName,
+		"uuid":      msg.GetHeader("Unique-ID"),
+		"sequence":  msg.GetHeader("Event-Sequence"),
+	}).Debug("Skipping duplicate ESL event")
+	return true
+}
+
+// skipOutOfOrder reports whether msg arrived after a later event of its call was handled and should be
+// skipped, so a stale event cannot undo the later one (e.g. a CHANNEL_HOLD reopening a hold after its
+// CHANNEL_UNHOLD). Critical events are still handled, since losing one loses or orphans the call record.
+func (c *Client) skipOutOfOrder(msg *goesl.Message, eventName string) bool {
+	uuid := msg.GetHeader("Unique-ID")
+	if !c.guard.stale(msg, uuid) {
+		return false
+	}
+	action := "skipped"
+	if criticalEvents[eventName] {
+		action = "handled"
+	}
+	outOfOrderEventsCounter.With(c.node(), eventName, action).Inc()
+	c.log.WithFields(logrus.Fields{
+		"eventName": eventName,
+		"uuid":      uuid,
+		"sequence":  msg.GetHeader("Event-Sequence"),
+		"action":    action,
+	}).Warn("ESL event arrived after a later event of the same call")
+	return action == "skipped"
+}
@@ -0,0 +1,49 @@
+package esl
+
+// Diagnostics is a Client's internal state, for diagnostic snapshots
+type Diagnostics struct {
+	Status
+	Mode             Mode  `json:"mode"`
+	HandlersInFlight int64 `json:"handlers_in_flight"`     // Queued or running
+	QueueDepths      []int `json:"queue_depths,omitempty"` // Events waiting, per worker
+	QueueCapacity    int   `json:"queue_capacity,omitempty"`
+	Shedding         bool  `json:"shedding"`
+	SpoolDepth       int   `json:"spool_depth"`
+	PendingJobs      int   `json:"pending_jobs"` // bgapi commands awaiting their BACKGROUND_JOB
+}
+
+// Diagnostics returns a snapshot of the client's connection, pipeline and spool
+func (c *Client) Diagnostics() Diagnostics {
+	d := Diagnostics{
+		Status:           c.Status(),
+		Mode:             c.mode,
+		HandlersInFlight: c.shedder.inFlight.Load(),
+		PendingJobs:      c.jobs.len(),
+	}
+	if d.Mode == "" {
+		d.Mode = ModeInbound
+	}
+	if c.pool != nil {
+		d.QueueDepths = make([]int, len(c.pool.shards))
+		for i, queue := range c.pool.shards {
+			d.QueueDepths[i] = len(queue)
+			d.QueueCapacity += cap(queue)
+		}
+	}
+	c.shedder.mu.Lock()
+	d.Shedding = c.shedder.active
+	c.shedder.mu.Unlock()
+	if c.spool != nil {
+		d.SpoolDepth = c.spool.Len()
+	}
+	return d
+}
+
+// Diagnostics returns every client's diagnostics, in configuration order
+func (cl *Cluster) Diagnostics() []Diagnostics {
+	diags := make([]Diagnostics, len(cl.clients))
+	for i, c := range cl.clients {
+		diags[i] = c.Diagnostics()
+	}
+	return diags
+}
@@ -0,0 +1,46 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// dtmfSampleRate is the clock DTMF-Duration is expressed in (RFC 2833 timestamp units)
+const dtmfSampleRate = 8000
+
+// handleDTMF records a digit from a DTMF event
+func (c *Client) handleDTMF(ctx context.Context, msg *goesl.Message, uuid string) {
+	digit := msg.GetHeader("DTMF-Digit")
+	if digit == "" {
+		c.log.WithField("uuid", uuid).Warn("DTMF event has no digit, skipping")
+		return
+	}
+	ts, err := eventTime(msg)
+	if err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to read timestamp for DTMF event")
+		return
+	}
+
+	d := &store.DTMF{
+		UUID:      uuid,
+		Digit:     digit,
+		Source:    optionalHeader(msg, "DTMF-Source"),
+		EventTime: ts,
+	}
+	if samples, err := strconv.Atoi(msg.GetHeader("DTMF-Duration")); err == nil && samples > 0 {
+		ms := samples * 1000 / dtmfSampleRate
+		d.DurationMS = &ms
+	}
+
+	if err := c.store.AddDTMF(ctx, d); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":  uuid,
+			"digit": c.store.Redacted(digit),
+		}).Error("Failed to record DTMF digit")
+	}
+}
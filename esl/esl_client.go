@@ -5,8 +5,16 @@ import (
 	"errors"
 	"net"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/calendar"
+	"gofreeswitchesl/directory"
+	"gofreeswitchesl/prefixes"
+	"gofreeswitchesl/rating"
+	"gofreeswitchesl/rules"
 	"gofreeswitchesl/store"
 
 	"github.com/0x19/goesl"
@@ -15,46 +23,223 @@ import (
 
 // Client wraps the goesl client and handles ESL events
 type Client struct {
-	conn      *goesl.Client
 	log       *logrus.Logger
 	store     *store.Store
-	addr      string // Expected format: "host:port"
-	pass      string
-	reconnect chan struct{}
+	rater     *rating.Rater
+	directory directory.Directory // Optional; nil disables extension name lookups
+	prefixes  *prefixes.Table     // Optional; classifies destinations by country and group
+	rules     *rules.Engine       // Optional; sets derived fields on call records
+	gateways  *GatewayTracker
+	spool     *Spool // Optional; holds events while the database circuit breaker is open
+	notifier  *alert.Notifier
+	missed    MissedCallPolicy
+	callbacks CallbackPolicy
+	sequence  sequenceTracker
+	guard     *eventGuard // Optional; skips duplicate and out-of-order events
+	events    subscription
+
+	coreMu          sync.Mutex
+	coreUUID        string // Core-UUID of the connected switch, used to detect restarts
+	coreChecked     bool   // Whether coreUUID has been verified on the current connection
+	reconcileOnGap  bool
+	limiter         *limiter
+	pool            *workerPool // Optional; nil spawns a goroutine per event
+	shedder         *shedder
+	credMu          sync.RWMutex // Guards endpoint passwords, which change when the password is rotated
+	endpoints       []endpoint   // Primary first, then the optional backup
+	active          atomic.Int32 // Index into endpoints of the endpoint in use
+	failover        FailoverPolicy
+	auth            AuthPolicy
+	connectFailures atomic.Int32 // Consecutive failed attempts on the active endpoint
+	reconnect       chan struct{}
+	retryNow        chan struct{} // Cancels a pending backoff, e.g. after a password change
+	backoff         backoff       // Spacing of reconnection attempts; owned by the reconnection manager
+	jobs            jobRegistry   // bgapi commands awaiting BACKGROUND_JOB results
+	deadLetter      *Spool        // Optional; receives events whose handler panicked
+	persistAttempts int           // Times an event is handled before a transiently rejected write dead-letters it
+
+	readTimeout time.Duration // Silence after which the connection counts as stalled; 0 disables
+	traceApps   bool          // Whether dialplan applications are recorded
+	rawEvents   bool          // Whether events are stored verbatim in raw_events
+
+	closed    closedCalls   // Calls closed by CHANNEL_HANGUP_COMPLETE, for late-event handling
+	lateGrace time.Duration // How long after close events are still merged
+
+	sipHeaders  variableSelector    // Channel variables captured into sip_headers
+	channelVars variableSelector    // Channel variables captured into variables
+	attributor  *CallerIDCampaigns  // Optional; stamps caller-ID campaigns on new calls
+	calendars   *calendar.Calendars // Optional; flags calls started within business hours
+
+	mode           Mode
+	outboundListen string // Address accepting socket application connections in outbound mode
+
+	connMu    sync.Mutex
+	conn      *goesl.Client // Non-nil only while connected; guarded by connMu
+	connState ConnState     // Guarded by connMu; see ConnState for the transitions
+
+	statusMu sync.Mutex
+	status   connStatus
 }
 
 var ErrESLNotConnected = errors.New("ESL client not connected") // Custom error
 
+var ErrMissingTimestamp = errors.New("event has no Event-Date-Timestamp")
+
+// Options holds the optional collaborators and tuning for a Client. Nil fields disable the related feature.
+type Options struct {
+	Rater     *rating.Rater
+	Directory directory.Directory
+	Prefixes  *prefixes.Table
+	Rules     *rules.Engine
+	Gateways  *GatewayTracker
+	Spool     *Spool
+	Notifier  *alert.Notifier
+	Limits    Limits
+	Failover  FailoverPolicy
+	Auth      AuthPolicy
+	Reconnect BackoffPolicy
+
+	MissedCalls MissedCallPolicy
+	Callbacks   CallbackPolicy
+
+	// ReconcileOnGap requests "show channels" and repairs the call table when Event-Sequence gaps are seen
+	ReconcileOnGap bool
+
+	// DedupWindow is how many recent events and calls are remembered to skip events received twice (by
+	// Event-UUID, or Core-UUID and Event-Sequence) and events older than one already handled for their call;
+	// 0 disables both
+	DedupWindow int
+
+	// DeadLetter, when set, receives the raw events whose handler panicked
+	DeadLetter *Spool
+
+	// DeadLetterAttempts is how many times an event whose writes PostgreSQL rejects for a passing reason
+	// (see store.IsTransient) is handled before it is set aside in dead_letter_events; below 1 means 1.
+	// Other rejections set it aside at once.
+	DeadLetterAttempts int
+
+	// ReadTimeout recycles the connection when no event arrives for this long; 0 disables stall detection
+	ReadTimeout time.Duration
+
+	// TraceApplications records CHANNEL_EXECUTE/CHANNEL_EXECUTE_COMPLETE in call_applications
+	TraceApplications bool
+
+	// RawEvents stores every handled event verbatim in raw_events, for the retention tiers to summarize
+	RawEvents bool
+
+	// SIPHeaders are the sip_* channel variables copied into the call's sip_headers
+	SIPHeaders []string
+
+	// ChannelVariables are the channel variables copied into the call's variables; entries ending in * match
+	// by prefix
+	ChannelVariables []string
+
+	// CallerIDCampaigns attributes calls to caller-ID campaigns; nil disables attribution
+	CallerIDCampaigns *CallerIDCampaigns
+
+	// Calendars flags calls started within their tenant's business hours; nil leaves business_hours unset
+	Calendars *calendar.Calendars
+
+	// Events are the event names and CUSTOM subclasses (entries containing "::") to subscribe to; empty
+	// subscribes to DefaultEvents, "ALL" to everything
+	Events []string
+
+	// Filters are applied on the switch after subscribing so only matching events are sent; see Filter
+	Filters []Filter
+
+	// LateEventGrace is how long after CHANNEL_HANGUP_COMPLETE events for the call are still merged
+	// (flagging the call as corrected); later events are discarded. 0 discards every late event.
+	LateEventGrace time.Duration
+
+	// Mode selects the inbound client, the outbound socket listener or both; empty means inbound
+	Mode Mode
+
+	// OutboundListen is the address the outbound listener accepts FreeSWITCH socket connections on
+	OutboundListen string
+}
+
 // NewClient creates a new ESL client
-func NewClient(addr, pass string, s *store.Store, logger *logrus.Logger) *Client {
+func NewClient(addr, pass string, s *store.Store, opts Options, logger *logrus.Logger) *Client {
 	return &Client{
-		log:       logger,
-		store:     s,
-		addr:      addr,
-		pass:      pass,
-		reconnect: make(chan struct{}, 1), // Buffered channel to prevent blocking on initial signal
+		log:             logger,
+		store:           s,
+		rater:           opts.Rater,
+		directory:       opts.Directory,
+		prefixes:        opts.Prefixes,
+		rules:           opts.Rules,
+		gateways:        opts.Gateways,
+		spool:           opts.Spool,
+		notifier:        opts.Notifier,
+		missed:          opts.MissedCalls,
+		callbacks:       opts.Callbacks,
+		events:          newSubscription(opts.Events, opts.Filters, opts.Callbacks),
+		reconcileOnGap:  opts.ReconcileOnGap,
+		deadLetter:      opts.DeadLetter,
+		persistAttempts: max(opts.DeadLetterAttempts, 1),
+		limiter:         newLimiter(opts.Limits),
+		pool:            newWorkerPool(opts.Limits),
+		shedder:         newShedder(opts.Limits.Shed),
+		endpoints:       newEndpoints(addr, pass, opts.Failover),
+		failover:        opts.Failover,
+		auth:            opts.Auth,
+		backoff:         newBackoff(opts.Reconnect),
+		readTimeout:     opts.ReadTimeout,
+		traceApps:       opts.TraceApplications,
+		rawEvents:       opts.RawEvents,
+		lateGrace:       opts.LateEventGrace,
+		sipHeaders:      newVariableSelector(opts.SIPHeaders),
+		channelVars:     newVariableSelector(opts.ChannelVariables),
+		attributor:      opts.CallerIDCampaigns,
+		calendars:       opts.Calendars,
+		mode:            opts.Mode,
+		outboundListen:  opts.OutboundListen,
+		reconnect:       make(chan struct{}, 1), // Buffered channel to prevent blocking on initial signal
+		retryNow:        make(chan struct{}, 1),
 	}
 }
 
-// connect establishes a connection to FreeSWITCH ESL
+// connect establishes a connection to FreeSWITCH ESL, closing the previous connection if there is one
 func (c *Client) connect(_ context.Context) error {
-	host, portStr, err := net.SplitHostPort(c.addr)
+	old, err := c.beginConnect()
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		old.Close()
+	}
+
+	ep := c.endpoint()
+	host, portStr, err := net.SplitHostPort(ep.addr)
 	if err != nil {
 		c.log.WithError(err).Error("Invalid ESL_ADDR format. Expected host:port")
+		c.finishConnect(nil)
 		return err
 	}
 	port, err := strconv.ParseUint(portStr, 10, 16)
 	if err != nil {
 		c.log.WithError(err).Error("Invalid ESL_PORT in ESL_ADDR.")
+		c.finishConnect(nil)
 		return err
 	}
 
-	client, err := goesl.NewClient(host, uint(port), c.pass, 10) // Attempting to match (string, uint, string, int)
+	client, err := goesl.NewClient(host, uint(port), ep.pass, 10) // Attempting to match (string, uint, string, int)
+	c.noteAuthResult(ep.addr, err)
 	if err != nil {
-		c.log.WithError(err).Error("Failed to connect to FreeSWITCH ESL")
+		c.log.WithError(err).WithField("node", ep.addr).Error("Failed to connect to FreeSWITCH ESL")
+		c.finishConnect(nil)
+		c.markError(err, true)
+		c.noteConnectFailure()
+		return err
+	}
+	c.connectFailures.Store(0)
+	c.extendReadDeadline(client)
+	if err := c.finishConnect(client); err != nil {
 		return err
 	}
-	c.conn = client
+	activeEndpointGauge.With(ep.addr, ep.role).Set(1)
+	c.sequence.reset()
+	c.resetCoreUUID()
+	c.markConnected()
 	go client.Handle() // Start background handler for incoming events
 	c.log.Info("Successfully connected to FreeSWITCH ESL and started handler")
 	return nil
@@ -63,6 +248,17 @@ func (c *Client) connect(_ context.Context) error {
 // Start connects to FreeSWITCH and starts handling events
 func (c *Client) Start(ctx context.Context) error {
 	c.log.Info("Starting ESL client...")
+	connStateGauge.With(c.node(), c.State().String()).Set(1)
+
+	c.startWorkers(ctx)
+	if c.mode.outbound() {
+		go c.listenOutbound(ctx)
+	}
+	go c.statusLoop(ctx)
+	go c.spoolLoop(ctx)
+	if !c.mode.inbound() {
+		return nil
+	}
 
 	// Initial connection attempt
 	if err := c.connect(ctx); err != nil {
@@ -77,48 +273,97 @@ func (c *Client) Start(ctx context.Context) error {
 
 	go c.eventLoop(ctx)
 	go c.reconnectionManager(ctx)
+	go c.failbackLoop(ctx)
 
 	return nil
 }
 
-// reconnectionManager handles attempts to reconnect to ESL if the connection is lost.
+// reconnectionManager handles attempts to reconnect to ESL if the connection is lost. Consecutive failed
+// attempts are spaced out by the reconnect backoff; reconnect signals arriving while a retry is pending
+// are absorbed rather than triggering extra attempts.
 func (c *Client) reconnectionManager(ctx context.Context) {
-	ticker := time.NewTicker(15 * time.Second) // Retry every 15 seconds
+	ticker := time.NewTicker(15 * time.Second) // Watchdog for a lost connection nobody signalled
 	defer ticker.Stop()
 
+	var retry *time.Timer // Pending backoff; nil when no attempt is scheduled
+	var retryC <-chan time.Time
+	attempt := func() {
+		retry, retryC = nil, nil
+		if delay, ok := c.reconnectOnce(ctx); ok {
+			retry = time.NewTimer(delay)
+			retryC = retry.C
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
+			if retry != nil {
+				retry.Stop()
+			}
 			c.log.Info("Reconnection manager stopping due to context cancellation.")
 			return
 		case <-c.reconnect:
-			c.log.Info("Attempting to reconnect to ESL...")
-			if c.conn != nil {
-				c.conn.Close() // Close existing connection before creating a new one
-				c.conn = nil
+			if retry == nil {
+				attempt()
 			}
-			if err := c.connect(ctx); err != nil {
-				c.log.WithError(err).Error("ESL reconnection attempt failed. Will retry.")
-				go func() {
-					time.Sleep(5 * time.Second)
-					c.reconnect <- struct{}{}
-				}()
-			} else {
-				c.log.Info("ESL reconnected successfully.")
-				if err := c.subscribeToEvents(); err != nil {
-					c.log.WithError(err).Error("Failed to subscribe to ESL events after reconnection")
-					c.reconnect <- struct{}{}
-				}
+		case <-retryC:
+			attempt()
+		case <-c.retryNow:
+			if retry != nil {
+				retry.Stop()
 			}
+			c.backoff.reset()
+			attempt()
 		case <-ticker.C:
-			if c.conn == nil {
+			if retry == nil && c.State() == ConnDisconnected && !c.authBlocked() {
 				c.log.Warn("ESL connection is nil, triggering reconnect.")
-				c.reconnect <- struct{}{}
+				attempt()
 			}
 		}
 	}
 }
 
+// reconnectOnce replaces the connection and subscribes again. After a failure it returns the delay before
+// the next attempt; ok is false when no retry should be scheduled, because the attempt succeeded or
+// authentication has been given up on.
+func (c *Client) reconnectOnce(ctx context.Context) (delay time.Duration, ok bool) {
+	c.log.WithField("attempt", c.backoff.failures+1).Info("Attempting to reconnect to ESL...")
+	err := c.connect(ctx) // Closes the existing connection before creating a new one
+	if errors.Is(err, ErrClientClosed) {
+		return 0, false
+	}
+	if err == nil {
+		if err = c.subscribeToEvents(); err != nil {
+			c.log.WithError(err).Error("Failed to subscribe to ESL events after reconnection")
+		}
+	}
+	if err == nil {
+		reconnectAttemptsCounter.With(c.node(), "success").Inc()
+		reconnectDelayGauge.With(c.node()).Set(0)
+		c.backoff.reset()
+		c.log.Info("ESL reconnected successfully.")
+		c.markReconnected()
+		return 0, false
+	}
+
+	reconnectAttemptsCounter.With(c.node(), "failure").Inc()
+	delay = c.backoff.next()
+	if c.authBlocked() {
+		if c.auth.RetryInterval <= 0 {
+			c.log.Error("ESL authentication rejected; not retrying until restarted")
+			return 0, false
+		}
+		delay = c.auth.RetryInterval
+	}
+	reconnectDelayGauge.With(c.node()).Set(delay.Seconds())
+	c.log.WithError(err).WithFields(logrus.Fields{
+		"attempt": c.backoff.failures,
+		"retryIn": delay.Round(time.Millisecond),
+	}).Error("ESL reconnection attempt failed. Will retry.")
+	return delay, true
+}
+
 // eventLoop listens for and processes ESL events
 func (c *Client) eventLoop(ctx context.Context) {
 	for {
@@ -127,15 +372,30 @@ func (c *Client) eventLoop(ctx context.Context) {
 			c.log.Info("ESL event loop stopping due to context cancellation.")
 			return
 		default:
-			if c.conn == nil {
-				c.log.Warn("ESL connection not available, pausing event processing.")
+			conn := c.currentConn()
+			if conn == nil {
+				if c.State() == ConnDraining {
+					c.log.Info("ESL event loop stopping, client is closed.")
+					return
+				}
+				// Debug only: the reconnection manager logs its attempts
+				c.log.Debug("ESL connection not available, pausing event processing.")
 				time.Sleep(5 * time.Second) // Wait before checking connection again
 				continue
 			}
 
-			msg, err := c.conn.ReadMessage()
+			msg, err := conn.ReadMessage()
 			if err != nil {
-				c.log.WithError(err).Error("Error reading ESL message")
+				if !c.dropConn(conn) {
+					continue // Replaced by a reconnect or closed; nothing to recover
+				}
+				if isStall(err) {
+					stallsCounter.With(c.node()).Inc()
+					c.log.WithField("readTimeout", c.readTimeout).Warn("No ESL events received within the read timeout; recycling connection")
+				} else {
+					c.log.WithError(err).Error("Error reading ESL message")
+				}
+				c.markError(err, true)
 				c.reconnect <- struct{}{}
 				time.Sleep(1 * time.Second)
 				continue
@@ -144,68 +404,257 @@ func (c *Client) eventLoop(ctx context.Context) {
 			if msg == nil {
 				continue // Should not happen with ReadMessage, but good practice
 			}
-
-			go c.handleEvent(ctx, msg) // Handle event in a new goroutine
+			c.extendReadDeadline(conn)
+			c.countEvent()
+			c.checkSequence(msg)
+			c.checkCoreUUID(msg)
+			c.dispatch(ctx, msg)
 		}
 	}
 }
 
-// subscribeToEvents subscribes to necessary ESL events
+// dispatch hands an event read from any connection to the worker pool, or a goroutine of its own when the
+// pool is disabled, spooling it instead while the database circuit is open or events of its call wait in
+// the spool, and dropping it when it is a duplicate, out of order or shed
+func (c *Client) dispatch(ctx context.Context, msg *goesl.Message) {
+	eventName := msg.GetHeader("Event-Name")
+	if c.skipDuplicate(msg, eventName) {
+		return
+	}
+	// Checked here, in the order events are read, since handlers racing on goroutines of their own would
+	// reach the guard in scheduling order
+	if c.skipOutOfOrder(msg, eventName) {
+		return
+	}
+	if c.store.CircuitOpen() {
+		// Database is down: hold tracked events on disk instead of spawning handlers that would fail
+		c.spoolEvent(msg, nil, eventName, "circuit_open")
+		return
+	}
+	if c.holdBehindSpool(msg, eventName) {
+		return
+	}
+	if !c.admit(eventName) {
+		return
+	}
+
+	if c.pool != nil {
+		c.enqueue(ctx, msg, eventName)
+		return
+	}
+
+	// Handle event in a new goroutine once its event type and the pool have a free handler slot. Waiting
+	// here, in the read loop, applies backpressure to the socket, so capped handlers never leave goroutines
+	// parked behind a busy event type.
+	if !c.limiter.acquireEvent(ctx, eventName) {
+		return
+	}
+	if !c.limiter.acquireGlobal(ctx) {
+		c.limiter.releaseEvent(eventName)
+		return
+	}
+	c.shedder.begin()
+	inFlightGauge.With(c.node()).Set(float64(c.shedder.inFlight.Load()))
+	go c.handleDispatched(ctx, msg, eventName, true)
+}
+
+// handleDispatched handles an admitted event under its per-event limit, on a worker or its own goroutine.
+// It ends the shedder bracket begun at dispatch. When holdsSlots, dispatch already reserved the event's
+// per-event and global slots, which are released here; workers reserve the per-event slot themselves.
+func (c *Client) handleDispatched(ctx context.Context, msg *goesl.Message, eventName string, holdsSlots bool) {
+	queueDepthGauge.With(c.node(), stageHandling).Add(1)
+	defer queueDepthGauge.With(c.node(), stageHandling).Add(-1)
+	defer c.shedder.end()
+
+	if holdsSlots {
+		defer c.limiter.releaseGlobal()
+	} else if !c.limiter.acquireEvent(ctx, eventName) {
+		return
+	}
+	defer c.limiter.releaseEvent(eventName)
+
+	c.handleOrSpool(ctx, msg, nil)
+}
+
+// subscribeToEvents subscribes to the configured ESL events
 func (c *Client) subscribeToEvents() error {
-	if c.conn == nil {
+	conn := c.currentConn()
+	if conn == nil {
 		return ErrESLNotConnected // Use custom error
 	}
-	// Subscribe to ALL events for debugging
-	if err := c.conn.Send("event json ALL"); err != nil {
+	if err := conn.Send(c.events.command()); err != nil {
 		c.log.WithError(err).Error("Failed to send event subscription command to ESL")
 		return err
 	}
-	c.log.Info("Subscribed to ALL ESL events (debug mode)")
+	for _, cmd := range c.events.filterCommands() {
+		if err := conn.Send(cmd); err != nil {
+			c.log.WithError(err).WithField("command", cmd).Error("Failed to send event filter command to ESL")
+			return err
+		}
+	}
+	if len(c.events.filters) > 0 {
+		c.log.WithField("filters", c.events.filterCommands()).Info("Applied ESL event filters")
+	}
+	if c.events.all {
+		c.log.Info("Subscribed to ALL ESL events")
+		return nil
+	}
+	c.log.WithFields(logrus.Fields{
+		"events":     c.events.events,
+		"subclasses": c.events.subclasses,
+	}).Info("Subscribed to ESL events")
 	return nil
 }
 
+// trackedEvents are the events this client persists; they are logged at INFO when received
+var trackedEvents = map[string]bool{
+	"CHANNEL_CREATE":          true,
+	"CHANNEL_HANGUP":          true,
+	"CHANNEL_HANGUP_COMPLETE": true,
+	"CHANNEL_PROGRESS":        true,
+	"CHANNEL_PROGRESS_MEDIA":  true,
+	"CHANNEL_ANSWER":          true,
+	"CHANNEL_BRIDGE":          true,
+	"CHANNEL_UNBRIDGE":        true,
+	"CHANNEL_STATE":           true,
+	"CHANNEL_CALLSTATE":       true,
+	"DTMF":                    true,
+	"RECORD_START":            true,
+	"RECORD_STOP":             true,
+	"MESSAGE":                 true,
+	"CHANNEL_HOLD":            true,
+	"CHANNEL_UNHOLD":          true,
+	"CHANNEL_UUID":            true,
+	"CHANNEL_PARK":            true,
+	"CHANNEL_UNPARK":          true,
+}
+
 // handleEvent processes a single ESL event
 func (c *Client) handleEvent(ctx context.Context, msg *goesl.Message) {
 	eventName := msg.GetHeader("Event-Name")
 	uuid := msg.GetHeader("Unique-ID")
 
+	if eventName == "BACKGROUND_JOB" {
+		c.handleBackgroundJob(msg)
+		return
+	}
+	c.recordRawEvent(ctx, msg, eventName)
+
+	if trackedEvents[eventName] {
+		c.observeLag(msg)
+		start := time.Now()
+		defer func() {
+			handleDuration.With(c.node(), eventName).Observe(time.Since(start).Seconds())
+		}()
+	}
+
+	if eventName == "CUSTOM" && c.handleSwitchCustomEvent(ctx, msg) {
+		return
+	}
+	if eventName == "MESSAGE" {
+		c.handleMessageEvent(ctx, msg) // Messages belong to no channel
+		return
+	}
+	if eventName == "HEARTBEAT" {
+		c.handleHeartbeat(ctx, msg)
+		return
+	}
+
 	if uuid == "" {
 		// Only log relevant events with no Unique-ID at info, skip debug logs for others
-		if eventName == "CHANNEL_CREATE" || eventName == "CHANNEL_HANGUP" {
+		if trackedEvents[eventName] {
 			c.log.WithField("eventName", eventName).Info("Received relevant event with no Unique-ID, skipping")
 		}
 		return
 	}
 
-	// Log full message for relevant events at INFO level for visibility
-	if eventName == "CHANNEL_CREATE" || eventName == "CHANNEL_HANGUP" {
-		c.log.WithFields(logrus.Fields{
-			"eventName":   eventName,
-			"uuid":        uuid,
-			"fullMessage": msg.String(), // msg.String() provides a well-formatted representation
-		}).Info("Attempting to process ESL event")
+	if !c.admitLate(ctx, msg, eventName, uuid) {
+		return
+	}
+
+	// Log full message for relevant events at DEBUG level, unless encrypted fields would be logged in plaintext
+	if trackedEvents[eventName] {
+		entry := c.log.WithFields(logrus.Fields{
+			"eventName": eventName,
+			"uuid":      uuid,
+		})
+		if !c.store.FieldsEncrypted() {
+			entry = entry.WithField("fullMessage", msg.String()) // msg.String() provides a well-formatted representation
+		}
+		entry.Debug("Attempting to process ESL event")
 	}
 
 	switch eventName {
 	case "CHANNEL_CREATE":
 		c.handleChannelCreate(ctx, msg, uuid)
+		c.recordCustomColumns(ctx, msg, uuid)
+		c.recordSIPHeaders(ctx, msg, uuid)
+		c.recordChannelVariables(ctx, msg, uuid)
+		c.recordVerto(ctx, msg, uuid)
 	case "CHANNEL_HANGUP":
 		c.handleChannelHangup(ctx, msg, uuid)
+		c.handleHold(ctx, msg, uuid, false)
+		c.handlePark(ctx, msg, uuid, false)
+	case "CHANNEL_HANGUP_COMPLETE":
+		c.handleChannelHangupComplete(ctx, msg, uuid)
+		c.recordCustomColumns(ctx, msg, uuid) // Picks up variables set by the dialplan during the call
+		c.recordSIPHeaders(ctx, msg, uuid)    // Outbound legs only learn the far end's headers after CHANNEL_CREATE
+		c.recordChannelVariables(ctx, msg, uuid)
+		c.recordPickup(ctx, msg, uuid)
+	case "CHANNEL_PROGRESS":
+		c.handleCallTimestamp(ctx, msg, uuid, store.TimestampRinging)
+	case "CHANNEL_PROGRESS_MEDIA":
+		// 183 with SDP: ringing has begun (if no 180 came first) and early media is flowing
+		c.handleCallTimestamp(ctx, msg, uuid, store.TimestampRinging)
+		c.handleCallTimestamp(ctx, msg, uuid, store.TimestampEarlyMedia)
+	case "CHANNEL_ANSWER":
+		c.handleCallTimestamp(ctx, msg, uuid, store.TimestampAnswered)
+	case "CHANNEL_BRIDGE":
+		c.handleCallTimestamp(ctx, msg, uuid, store.TimestampBridged)
+		c.recordGateway(ctx, msg, uuid)
+		c.recordBridge(ctx, msg, uuid)
+	case "CHANNEL_UNBRIDGE":
+		c.handleChannelUnbridge(ctx, msg, uuid)
+	case "CHANNEL_HOLD":
+		c.handleHold(ctx, msg, uuid, true)
+	case "CHANNEL_UNHOLD":
+		c.handleHold(ctx, msg, uuid, false)
+	case "CHANNEL_UUID":
+		c.handleChannelUUID(ctx, msg, uuid)
+	case "CHANNEL_PARK":
+		c.handlePark(ctx, msg, uuid, true)
+	case "CHANNEL_UNPARK":
+		c.handlePark(ctx, msg, uuid, false)
+	case "CHANNEL_EXECUTE":
+		c.handleApplication(ctx, msg, uuid, false)
+	case "CHANNEL_EXECUTE_COMPLETE":
+		c.handleApplication(ctx, msg, uuid, true)
+	case "CHANNEL_STATE", "CHANNEL_CALLSTATE":
+		c.handleStateTransition(ctx, msg, uuid)
+	case "DTMF":
+		c.handleDTMF(ctx, msg, uuid)
+	case "RECORD_START", "RECORD_STOP":
+		c.handleRecordEvent(ctx, msg, uuid)
+	case "CUSTOM":
+		c.handleCustomEvent(ctx, msg, uuid)
 	default:
 		// Already logged at debug if it's not one of the above
 	}
+	c.applyRules(ctx, msg, uuid)
 }
 
 // handleChannelCreate handles the CHANNEL_CREATE event
 func (c *Client) handleChannelCreate(ctx context.Context, msg *goesl.Message, uuid string) {
 	c.log.WithField("uuid", uuid).Info("Handling CHANNEL_CREATE event")
+	gateway := gatewayName(msg)
+	c.gateways.Start(uuid, gateway)
 
 	startTimeStr := msg.GetHeader("Event-Date-Timestamp")
 	if startTimeStr == "" {
 		c.log.WithField("uuid", uuid).Error("Event-Date-Timestamp is missing for CHANNEL_CREATE")
 		return
 	}
-	startTimeUnix, err := strconv.ParseInt(startTimeStr, 10, 64)
+	startTime, err := parseMicroTimestamp(startTimeStr)
 	if err != nil {
 		c.log.WithError(err).WithFields(logrus.Fields{
 			"uuid":           uuid,
@@ -219,16 +668,55 @@ func (c *Client) handleChannelCreate(ctx context.Context, msg *goesl.Message, uu
 		Direction: msg.GetHeader("Call-Direction"),
 		Caller:    msg.GetHeader("Caller-Caller-ID-Number"),
 		Callee:    msg.GetHeader("Caller-Destination-Number"),
-		StartTime: time.Unix(startTimeUnix/1000000, (startTimeUnix%1000000)*1000), // Convert microseconds to Time
+		StartTime: startTime,
+	}
+	node := c.node()
+	call.Node = &node
+	call.CallerName = c.resolveName(ctx, call.Caller, msg.GetHeader("Caller-Caller-ID-Name"))
+	call.Context = optionalHeader(msg, "Caller-Context")
+	call.SIPProfile = optionalHeader(msg, "variable_sofia_profile_name")
+	call.Domain = callDomain(msg)
+	if gateway != "" {
+		call.Gateway = &gateway
+	}
+	call.AccountCode = optionalHeader(msg, "variable_accountcode")
+	call.SIPCallID = optionalHeader(msg, "variable_sip_call_id")
+	call.UserID = optionalHeader(msg, "variable_user_id")
+	if call.UserID == nil {
+		// Registered directory users carry user_name even when no explicit user_id is configured
+		call.UserID = optionalHeader(msg, "variable_user_name")
 	}
+	stampVertoIdentity(msg, call)
+	call.CalleeName = c.resolveName(ctx, call.Callee, "")
+	if dest, ok := c.prefixes.Lookup(call.Callee); ok {
+		call.DestCountry = &dest.Country
+		if dest.Group != "" {
+			call.DestGroup = &dest.Group
+		}
+	}
+	call.CallerIDCampaign = c.attributor.Attribute(call)
+	call.RetryOf = optionalHeader(msg, "variable_retry_of")
+	call.RetryAttempt = intHeader(msg, "variable_retry_attempt")
+	c.stampBusinessHours(call)
 
 	// Log the call object before attempting to save
 	c.log.WithFields(logrus.Fields{
-		"uuid":      call.UUID,
-		"direction": call.Direction,
-		"caller":    call.Caller,
-		"callee":    call.Callee,
-		"startTime": call.StartTime,
+		"uuid":        call.UUID,
+		"direction":   call.Direction,
+		"caller":      c.store.Redacted(call.Caller),
+		"callerName":  call.CallerName,
+		"callee":      c.store.Redacted(call.Callee),
+		"calleeName":  call.CalleeName,
+		"context":     call.Context,
+		"sipProfile":  call.SIPProfile,
+		"domain":      call.Domain,
+		"accountCode": call.AccountCode,
+		"userID":      call.UserID,
+		"gateway":     call.Gateway,
+		"sipCallID":   call.SIPCallID,
+		"destCountry": call.DestCountry,
+		"node":        node,
+		"startTime":   call.StartTime,
 	}).Info("Parsed call data for CHANNEL_CREATE")
 
 	if err := c.store.CreateCall(ctx, call); err != nil {
@@ -236,18 +724,20 @@ func (c *Client) handleChannelCreate(ctx context.Context, msg *goesl.Message, uu
 	} else {
 		c.log.WithField("uuid", uuid).Info("Successfully created call record from CHANNEL_CREATE")
 	}
+	c.linkCallback(ctx, msg, call)
 }
 
 // handleChannelHangup handles the CHANNEL_HANGUP event
 func (c *Client) handleChannelHangup(ctx context.Context, msg *goesl.Message, uuid string) {
 	c.log.WithField("uuid", uuid).Info("Handling CHANNEL_HANGUP event")
+	c.gateways.End(uuid)
 
 	hangupTimeStr := msg.GetHeader("Event-Date-Timestamp")
 	if hangupTimeStr == "" {
 		c.log.WithField("uuid", uuid).Error("Event-Date-Timestamp is missing for CHANNEL_HANGUP")
 		return
 	}
-	hangupTimeUnix, err := strconv.ParseInt(hangupTimeStr, 10, 64)
+	endTime, err := parseMicroTimestamp(hangupTimeStr)
 	if err != nil {
 		c.log.WithError(err).WithFields(logrus.Fields{
 			"uuid":           uuid,
@@ -255,29 +745,161 @@ func (c *Client) handleChannelHangup(ctx context.Context, msg *goesl.Message, uu
 		}).Error("Failed to parse hangup time for CHANNEL_HANGUP")
 		return
 	}
-	endTime := time.Unix(hangupTimeUnix/1000000, (hangupTimeUnix%1000000)*1000)
 	status := msg.GetHeader("Hangup-Cause")
 
+	var cost *float64
+	if c.rater.Enabled() {
+		v := c.rater.Cost(billableSeconds(msg, endTime))
+		cost = &v
+	}
+
 	// Log the data before attempting to update
 	c.log.WithFields(logrus.Fields{
 		"uuid":    uuid,
 		"endTime": endTime,
 		"status":  status,
+		"cost":    cost,
 	}).Info("Parsed hangup data for CHANNEL_HANGUP")
 
-	if err := c.store.UpdateCallHangup(ctx, uuid, endTime, status); err != nil {
+	if err := c.store.UpdateCallHangup(ctx, uuid, endTime, status, cost); err != nil {
 		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to update call record from CHANNEL_HANGUP")
 	} else {
 		c.log.WithField("uuid", uuid).Info("Successfully updated call record from CHANNEL_HANGUP")
 	}
+
+	c.backfillProgressTimestamps(ctx, msg, uuid)
+	c.recordGateway(ctx, msg, uuid)
+	c.recordQueueCall(ctx, msg, uuid, endTime)
+	c.notifyMissedCall(msg, uuid, endTime, status)
+
+	if sipCallID := msg.GetHeader("variable_sip_call_id"); sipCallID != "" {
+		if err := c.store.SetCallSIPCallID(ctx, uuid, sipCallID); err != nil {
+			c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record SIP Call-ID from CHANNEL_HANGUP")
+		}
+	}
+
+	// A parked caller who gives up frees their slot
+	if err := c.store.ReleaseParkedCall(ctx, uuid, store.ParkStatusHungUp, nil); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to release parking slot for CHANNEL_HANGUP")
+	}
+}
+
+// handleChannelHangupComplete handles the CHANNEL_HANGUP_COMPLETE event, which carries the billing
+// variables FreeSWITCH finalizes only after CHANNEL_HANGUP
+func (c *Client) handleChannelHangupComplete(ctx context.Context, msg *goesl.Message, uuid string) {
+	c.log.WithField("uuid", uuid).Info("Handling CHANNEL_HANGUP_COMPLETE event")
+	defer c.closed.close(uuid, time.Now(), c.lateGrace+closedRetention) // Later events are late
+	c.recordMediaQuality(ctx, msg, uuid)
+	c.recordHangupCodes(ctx, msg, uuid)
+
+	billsec, err := strconv.ParseInt(msg.GetHeader("variable_billsec"), 10, 64)
+	if err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Missing or invalid variable_billsec for CHANNEL_HANGUP_COMPLETE")
+		return
+	}
+	duration, _ := strconv.ParseInt(msg.GetHeader("variable_duration"), 10, 64)
+	progresssec, _ := strconv.ParseInt(msg.GetHeader("variable_progresssec"), 10, 64)
+
+	var cost *float64
+	if c.rater.Enabled() {
+		v := c.rater.Cost(billsec)
+		cost = &v
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"uuid":        uuid,
+		"billsec":     billsec,
+		"duration":    duration,
+		"progresssec": progresssec,
+		"cost":        cost,
+	}).Info("Parsed billing data for CHANNEL_HANGUP_COMPLETE")
+
+	if err := c.store.UpdateCallBilling(ctx, uuid, billsec, duration, progresssec, cost); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to update call billing from CHANNEL_HANGUP_COMPLETE")
+	}
 }
 
-// Close gracefully closes the ESL connection
+// resolveName returns the display name for number. A caller ID name supplied by FreeSWITCH is
+// preferred unless it is empty or merely repeats the number, in which case the directory is consulted.
+func (c *Client) resolveName(ctx context.Context, number, headerName string) *string {
+	if headerName != "" && headerName != number && headerName != "Outbound Call" {
+		return &headerName
+	}
+	if c.directory != nil && number != "" {
+		if name, ok := c.directory.Lookup(ctx, number); ok {
+			return &name
+		}
+	}
+	return nil
+}
+
+// optionalHeader returns a pointer to the header value, or nil if the header is absent or empty
+func optionalHeader(msg *goesl.Message, name string) *string {
+	if v := msg.GetHeader(name); v != "" {
+		return &v
+	}
+	return nil
+}
+
+// callDomain derives the SIP domain of a call. The directory domain_name is authoritative;
+// for calls that never touched the directory the request/To/From hosts are used in turn.
+func callDomain(msg *goesl.Message) *string {
+	for _, header := range []string{
+		"variable_domain_name",
+		"variable_sip_req_host",
+		"variable_sip_to_host",
+		"variable_sip_from_host",
+	} {
+		if v := optionalHeader(msg, header); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// parseMicroTimestamp converts an ESL microsecond epoch timestamp header into a UTC time.Time
+func parseMicroTimestamp(value string) (time.Time, error) {
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(micros).UTC(), nil
+}
+
+// eventTime returns the Event-Date-Timestamp of msg
+func eventTime(msg *goesl.Message) (time.Time, error) {
+	value := msg.GetHeader("Event-Date-Timestamp")
+	if value == "" {
+		return time.Time{}, ErrMissingTimestamp
+	}
+	return parseMicroTimestamp(value)
+}
+
+// billableSeconds returns the answered duration of a call, or 0 if it was never answered
+func billableSeconds(msg *goesl.Message, endTime time.Time) int64 {
+	answeredStr := msg.GetHeader("Caller-Channel-Answered-Time")
+	if answeredStr == "" || answeredStr == "0" {
+		return 0
+	}
+	answeredAt, err := parseMicroTimestamp(answeredStr)
+	if err != nil || !endTime.After(answeredAt) {
+		return 0
+	}
+	return int64(endTime.Sub(answeredAt).Seconds())
+}
+
+// Close gracefully closes the ESL connection. The client moves to Draining, stops reading events and
+// reconnecting, and waits up to drainTimeout for events already read to be handled.
 func (c *Client) Close() error {
 	c.log.Info("Closing ESL client connection...")
-	if c.conn != nil {
-		return c.conn.Close()
+	var err error
+	if conn := c.drain(); conn != nil {
+		err = conn.Close()
+	} else {
+		c.log.Info("ESL connection already closed or not established.")
 	}
-	c.log.Info("ESL connection already closed or not established.")
-	return nil
+	if !c.waitHandlers(drainTimeout) {
+		c.log.WithField("inFlight", c.shedder.inFlight.Load()).Warn("ESL event handlers still running after the drain timeout")
+	}
+	return err
 }
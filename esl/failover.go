@@ -0,0 +1,148 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Endpoint roles
+const (
+	rolePrimary = "primary"
+	roleBackup  = "backup"
+)
+
+var activeEndpointGauge = metrics.NewGaugeVec("esl_active_endpoint", "1 for the ESL endpoint currently in use, 0 otherwise.", "node", "role")
+var failoversCounter = metrics.NewCounterVec("esl_failovers_total", "Switches between the primary and backup ESL endpoints.", "to_role")
+
+// FailoverPolicy configures an optional backup ESL endpoint for active/passive FreeSWITCH pairs.
+// An empty BackupAddr disables failover.
+type FailoverPolicy struct {
+	BackupAddr       string
+	BackupPass       string        // Defaults to the primary password
+	After            int           // Consecutive failed connection attempts before switching endpoints
+	FailbackInterval time.Duration // How often the primary is probed while on the backup; 0 disables failback
+}
+
+// endpoint is an ESL address and its password
+type endpoint struct {
+	addr string // Expected format: "host:port"
+	pass string
+	role string
+}
+
+// newEndpoints builds the primary endpoint and, if configured, the backup
+func newEndpoints(addr, pass string, policy FailoverPolicy) []endpoint {
+	endpoints := []endpoint{{addr: addr, pass: pass, role: rolePrimary}}
+	if policy.BackupAddr != "" {
+		backupPass := policy.BackupPass
+		if backupPass == "" {
+			backupPass = pass
+		}
+		endpoints = append(endpoints, endpoint{addr: policy.BackupAddr, pass: backupPass, role: roleBackup})
+	}
+	return endpoints
+}
+
+// endpoint returns the endpoint currently in use
+func (c *Client) endpoint() endpoint {
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+	return c.endpoints[c.active.Load()]
+}
+
+// node returns the address of the ESL endpoint currently in use. It identifies the node in metrics and records.
+func (c *Client) node() string {
+	return c.endpoint().addr
+}
+
+// serves reports whether node is one of the client's endpoints
+func (c *Client) serves(node string) bool {
+	for _, ep := range c.endpoints {
+		if ep.addr == node {
+			return true
+		}
+	}
+	return false
+}
+
+// switchEndpoint makes endpoints[index] the active endpoint
+func (c *Client) switchEndpoint(index int, reason string) {
+	from := c.endpoint()
+	c.active.Store(int32(index))
+	to := c.endpoint()
+	c.connectFailures.Store(0)
+	c.forgetCoreUUID() // The other switch has its own Core-UUID
+
+	activeEndpointGauge.With(from.addr, from.role).Set(0)
+	activeEndpointGauge.With(to.addr, to.role).Set(1)
+	failoversCounter.With(to.role).Inc()
+
+	c.log.WithFields(logrus.Fields{
+		"from":   from.addr,
+		"to":     to.addr,
+		"role":   to.role,
+		"reason": reason,
+	}).Warn("Switching ESL endpoint")
+	c.notifier.Notify(alert.Alert{
+		Type:     "esl_failover",
+		Severity: alert.SeverityWarning,
+		Message:  fmt.Sprintf("ESL switched from %s to %s endpoint %s: %s", from.role, to.role, to.addr, reason),
+		Fields: map[string]any{
+			"from": from.addr,
+			"to":   to.addr,
+			"role": to.role,
+		},
+	})
+}
+
+// noteConnectFailure counts a failed connection attempt and fails over to the other endpoint once
+// the policy threshold is reached. Called from the connection path only.
+func (c *Client) noteConnectFailure() {
+	if len(c.endpoints) < 2 {
+		return
+	}
+	failures := c.connectFailures.Add(1)
+	if int(failures) < max(c.failover.After, 1) {
+		return
+	}
+	next := (int(c.active.Load()) + 1) % len(c.endpoints)
+	c.switchEndpoint(next, fmt.Sprintf("%d consecutive connection failures", failures))
+}
+
+// failbackLoop periodically checks whether the primary accepts connections again while the backup is
+// in use and, if so, drops the backup connection so the reconnection manager returns to the primary.
+func (c *Client) failbackLoop(ctx context.Context) {
+	if len(c.endpoints) < 2 || c.failover.FailbackInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.failover.FailbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.active.Load() == 0 {
+				continue
+			}
+			conn, err := net.DialTimeout("tcp", c.endpoints[0].addr, 3*time.Second)
+			if err != nil {
+				continue
+			}
+			conn.Close()
+
+			c.switchEndpoint(0, "primary is reachable again")
+			if conn := c.currentConn(); conn != nil {
+				conn.Close() // The event loop sees the read error and triggers a reconnect to the primary
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleFaxResult stores the outcome of a txfax (spandsp::txfaxresult) or rxfax (spandsp::rxfaxresult)
+func (c *Client) handleFaxResult(ctx context.Context, msg *goesl.Message, uuid string, inbound bool) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	f := &store.Fax{
+		UUID:            uuid,
+		Direction:       store.FaxOutbound,
+		Success:         msg.GetHeader("fax-success") == "1",
+		ResultCode:      intHeader(msg, "fax-result-code"),
+		ResultText:      optionalHeader(msg, "fax-result-text"),
+		TotalPages:      intHeader(msg, "fax-document-total-pages"),
+		RemoteStationID: optionalHeader(msg, "fax-remote-station-id"),
+		LocalStationID:  optionalHeader(msg, "fax-local-station-id"),
+		TransferRate:    intHeader(msg, "fax-transfer-rate"),
+		OccurredAt:      at,
+	}
+	if inbound {
+		f.Direction = store.FaxInbound
+	}
+	f.Pages, _ = strconv.Atoi(msg.GetHeader("fax-document-transferred-pages"))
+	// The file is the txfax/rxfax argument, which mod_spandsp also exposes as fax_filename on newer versions
+	if path := firstHeader(msg, "fax-filename", "variable_fax_filename", "variable_fax_file"); path != "" {
+		f.FilePath = &path
+	}
+	if ecm := msg.GetHeader("fax-ecm-used"); ecm != "" {
+		used := ecm == "on" || ecm == "1" || ecm == "true"
+		f.ECMUsed = &used
+	}
+
+	if err := c.store.AddFax(ctx, f); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record fax result")
+		return
+	}
+	c.log.WithFields(logrus.Fields{
+		"uuid":      uuid,
+		"direction": f.Direction,
+		"success":   f.Success,
+		"pages":     f.Pages,
+	}).Info("Recorded fax result")
+}
@@ -0,0 +1,76 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FreeSWITCH core db realms holding per-extension feature state. Dialplans read them with
+// ${db(select/<realm>/<extension>@<domain>)}.
+const (
+	RealmDND             = "dnd"
+	RealmForwardAlways   = "cfwd_always"
+	RealmForwardBusy     = "cfwd_busy"
+	RealmForwardNoAnswer = "cfwd_noanswer"
+)
+
+var (
+	ErrInvalidFeature = errors.New("invalid feature request")
+	ErrFeatureFailed  = errors.New("feature update failed")
+)
+
+// featureKey is the db key of extension, qualified by domain when one is given
+func featureKey(extension, domain string) (string, error) {
+	if !extensionPattern.MatchString(extension) {
+		return "", fmt.Errorf("%w: extension", ErrInvalidFeature)
+	}
+	if domain == "" {
+		return extension, nil
+	}
+	if !domainPattern.MatchString(domain) {
+		return "", fmt.Errorf("%w: domain", ErrInvalidFeature)
+	}
+	return extension + "@" + domain, nil
+}
+
+// featureCommand renders the db command storing value for the extension in realm
+func featureCommand(realm, extension, domain, value string) (string, error) {
+	key, err := featureKey(extension, domain)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return fmt.Sprintf("db delete/%s/%s", realm, key), nil
+	}
+	// db separates its arguments with '/', so values are restricted to dialable characters
+	if !extensionPattern.MatchString(value) {
+		return "", fmt.Errorf("%w: value for %s", ErrInvalidFeature, realm)
+	}
+	return fmt.Sprintf("db insert/%s/%s/%s", realm, key, value), nil
+}
+
+// ValidateExtensionFeature reports whether SetExtensionFeature would accept the arguments
+func ValidateExtensionFeature(realm, extension, domain, value string) error {
+	_, err := featureCommand(realm, extension, domain, value)
+	return err
+}
+
+// SetExtensionFeature stores value for the extension in realm of the FreeSWITCH core db. An empty value
+// deletes the entry, which dialplans treat as the feature being off.
+func (c *Client) SetExtensionFeature(ctx context.Context, realm, extension, domain, value string) error {
+	command, err := featureCommand(realm, extension, domain, value)
+	if err != nil {
+		return err
+	}
+
+	body, err := c.bgapiWait(ctx, command)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(body, "+OK") {
+		return fmt.Errorf("%w: %s", ErrFeatureFailed, strings.TrimSpace(strings.TrimPrefix(body, "-ERR")))
+	}
+	return nil
+}
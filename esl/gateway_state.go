@@ -0,0 +1,110 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+var gatewayUpGauge = metrics.NewGaugeVec("gateway_up", "Whether a sofia gateway is UP (1) or DOWN (0) according to sofia::gateway_state.", "gateway")
+
+// gatewayStatus maps a sofia gateway registration state and ping status to UP or DOWN. Transitional
+// states (TRYING, REGISTER, UNREGISTER) are inconclusive and return "".
+func gatewayStatus(state, ping string) string {
+	if ping == "DOWN" {
+		return store.GatewayDown
+	}
+	switch state {
+	case "REGED", "NOREG", "UP":
+		return store.GatewayUp
+	case "FAILED", "FAIL_WAIT", "UNREGED", "EXPIRED", "TIMEOUT", "DOWN":
+		return store.GatewayDown
+	}
+	if ping == "UP" {
+		return store.GatewayUp
+	}
+	return ""
+}
+
+// handleGatewayState records a sofia::gateway_state event and alerts when a gateway goes down
+func (c *Client) handleGatewayState(ctx context.Context, msg *goesl.Message) {
+	name := msg.GetHeader("Gateway")
+	if name == "" {
+		c.log.Warn("sofia::gateway_state without Gateway, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	state := msg.GetHeader("State")
+	ping := msg.GetHeader("Ping-Status")
+	g := &store.GatewayState{
+		Name:       name,
+		State:      state,
+		PingStatus: optionalHeader(msg, "Ping-Status"),
+		Phrase:     optionalHeader(msg, "Phrase"),
+		UpdatedAt:  at,
+	}
+	status := gatewayStatus(state, ping)
+	if status != "" {
+		g.Status = &status
+	}
+
+	if err := c.store.SetGatewayState(ctx, g); err != nil {
+		c.log.WithError(err).WithField("gateway", name).Error("Failed to record gateway state")
+	}
+	if status != "" {
+		c.gateways.ObserveStatus(name, status, state, msg.GetHeader("Phrase"))
+	}
+}
+
+// ObserveStatus tracks a gateway's UP/DOWN status, alerting when it goes down if down alerts are enabled
+func (t *GatewayTracker) ObserveStatus(gateway, status, state, phrase string) {
+	if t == nil {
+		return
+	}
+	up := status == store.GatewayUp
+	if up {
+		gatewayUpGauge.With(gateway).Set(1)
+	} else {
+		gatewayUpGauge.With(gateway).Set(0)
+	}
+
+	t.mu.Lock()
+	prev := t.status[gateway]
+	t.status[gateway] = status
+	t.mu.Unlock()
+
+	if prev == status || !t.alertDown {
+		return
+	}
+	if !up {
+		t.notifier.Notify(alert.Alert{
+			Type:     "gateway_down",
+			Severity: alert.SeverityCritical,
+			Message:  fmt.Sprintf("Gateway %s is DOWN (%s)", gateway, state),
+			Fields: map[string]any{
+				"gateway": gateway,
+				"state":   state,
+				"phrase":  phrase,
+			},
+		})
+	} else if prev == store.GatewayDown {
+		t.notifier.Notify(alert.Alert{
+			Type:     "gateway_up",
+			Severity: alert.SeverityInfo,
+			Message:  fmt.Sprintf("Gateway %s is UP again (%s)", gateway, state),
+			Fields: map[string]any{
+				"gateway": gateway,
+				"state":   state,
+			},
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gofreeswitchesl/alert"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// GatewayUsage is a point-in-time view of concurrent calls on a gateway
+type GatewayUsage struct {
+	Gateway     string  `json:"gateway"`
+	Active      int     `json:"active"`
+	Limit       int     `json:"limit,omitempty"`
+	Utilization float64 `json:"utilization,omitempty"`
+}
+
+// GatewayTracker counts concurrent calls per gateway and alerts when a configured limit is approached.
+// An alert fires once when utilization reaches the threshold and re-arms after usage drops below it.
+type GatewayTracker struct {
+	mu        sync.Mutex
+	limits    map[string]int
+	threshold float64
+	active    map[string]int    // gateway -> concurrent calls
+	channels  map[string]string // uuid -> gateway, so each channel is only released once
+	alerted   map[string]bool
+	status    map[string]string // gateway -> UP or DOWN from sofia::gateway_state
+	alertDown bool              // Alert when a gateway goes DOWN
+	notifier  *alert.Notifier
+	log       *logrus.Logger
+}
+
+// NewGatewayTracker creates a new GatewayTracker. threshold is the fraction (0-1] of a limit at which to alert;
+// alertDown enables alerts when sofia reports a gateway DOWN.
+func NewGatewayTracker(limits map[string]int, threshold float64, alertDown bool, notifier *alert.Notifier, logger *logrus.Logger) *GatewayTracker {
+	if threshold <= 0 || threshold > 1 {
+		threshold = 1
+	}
+	return &GatewayTracker{
+		limits:    limits,
+		threshold: threshold,
+		active:    make(map[string]int),
+		channels:  make(map[string]string),
+		alerted:   make(map[string]bool),
+		status:    make(map[string]string),
+		alertDown: alertDown,
+		notifier:  notifier,
+		log:       logger,
+	}
+}
+
+// Start records a new channel on gateway
+func (t *GatewayTracker) Start(uuid, gateway string) {
+	if t == nil || gateway == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.channels[uuid]; exists {
+		return
+	}
+	t.channels[uuid] = gateway
+	t.active[gateway]++
+
+	limit, ok := t.limits[gateway]
+	if !ok || limit <= 0 {
+		return
+	}
+	active := t.active[gateway]
+	if float64(active) >= t.threshold*float64(limit) && !t.alerted[gateway] {
+		t.alerted[gateway] = true
+		severity := alert.SeverityWarning
+		if active >= limit {
+			severity = alert.SeverityCritical
+		}
+		t.notifier.Notify(alert.Alert{
+			Type:     "gateway_concurrency",
+			Severity: severity,
+			Message:  fmt.Sprintf("Gateway %s at %d of %d concurrent calls", gateway, active, limit),
+			Fields: map[string]any{
+				"gateway": gateway,
+				"active":  active,
+				"limit":   limit,
+			},
+		})
+	}
+}
+
+// End releases the channel identified by uuid, if it was tracked
+func (t *GatewayTracker) End(uuid string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	gateway, ok := t.channels[uuid]
+	if !ok {
+		return
+	}
+	delete(t.channels, uuid)
+	t.active[gateway]--
+	if t.active[gateway] <= 0 {
+		delete(t.active, gateway)
+	}
+
+	if limit := t.limits[gateway]; limit > 0 && float64(t.active[gateway]) < t.threshold*float64(limit) && t.alerted[gateway] {
+		t.alerted[gateway] = false
+		t.log.WithField("gateway", gateway).Info("Gateway concurrency back below alert threshold")
+	}
+}
+
+// Rename moves the channel tracked under oldUUID to newUUID after a UUID change
+func (t *GatewayTracker) Rename(oldUUID, newUUID string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	gateway, ok := t.channels[oldUUID]
+	if !ok {
+		return
+	}
+	delete(t.channels, oldUUID)
+	if _, exists := t.channels[newUUID]; exists {
+		// Both channels were counted; release the one going away
+		t.active[gateway]--
+		if t.active[gateway] <= 0 {
+			delete(t.active, gateway)
+		}
+		return
+	}
+	t.channels[newUUID] = gateway
+}
+
+// Snapshot returns current usage for every gateway with active calls or a configured limit
+func (t *GatewayTracker) Snapshot() []GatewayUsage {
+	usage := []GatewayUsage{}
+	if t == nil {
+		return usage
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for gw, limit := range t.limits {
+		seen[gw] = true
+		u := GatewayUsage{Gateway: gw, Active: t.active[gw], Limit: limit}
+		if limit > 0 {
+			u.Utilization = float64(u.Active) / float64(limit)
+		}
+		usage = append(usage, u)
+	}
+	for gw, active := range t.active {
+		if !seen[gw] {
+			usage = append(usage, GatewayUsage{Gateway: gw, Active: active})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Gateway < usage[j].Gateway })
+	return usage
+}
+
+// gatewayName returns the sofia gateway a channel was routed through, if any. The sip_gateway
+// variables are set on gateway legs; for the A-leg the gateway is parsed from the bridge dial string.
+func gatewayName(msg *goesl.Message) string {
+	if gw := msg.GetHeader("variable_sip_gateway_name"); gw != "" {
+		return gw
+	}
+	if gw := msg.GetHeader("variable_sip_gateway"); gw != "" {
+		return gw
+	}
+	for _, header := range []string{
+		"Channel-Name",
+		"Other-Leg-Channel-Name",
+		"variable_last_bridge_to_channel_name",
+		"variable_last_arg",
+		"variable_current_application_data",
+	} {
+		if gw := gatewayFromDialString(msg.GetHeader(header)); gw != "" {
+			return gw
+		}
+	}
+	return ""
+}
+
+// gatewayFromDialString extracts the gateway from the first "sofia/gateway/<name>/..." target in a
+// bridge dial string, which may carry {var=...} prefixes and several targets separated by , or |
+func gatewayFromDialString(dial string) string {
+	const marker = "sofia/gateway/"
+	idx := strings.Index(dial, marker)
+	if idx < 0 {
+		return ""
+	}
+	rest := dial[idx+len(marker):]
+	if end := strings.IndexAny(rest, "/,|"); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// recordGateway attributes the call to a gateway once it becomes known (typically at bridge or hangup on the A-leg)
+func (c *Client) recordGateway(ctx context.Context, msg *goesl.Message, uuid string) {
+	gateway := gatewayName(msg)
+	if gateway == "" {
+		return
+	}
+	if err := c.store.SetCallGateway(ctx, uuid, gateway); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record call gateway")
+	}
+}
@@ -0,0 +1,30 @@
+package esl
+
+import (
+	"context"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// recordHangupCodes stores the Q.850 cause and SIP status codes of a CHANNEL_HANGUP_COMPLETE event.
+// Channels that never spoke SIP (loopback, conferences) only carry the Q.850 cause.
+func (c *Client) recordHangupCodes(ctx context.Context, msg *goesl.Message, uuid string) {
+	q850 := intHeader(msg, "variable_hangup_cause_q850")
+	termStatus := intHeader(msg, "variable_sip_term_status")
+	inviteFailure := intHeader(msg, "variable_sip_invite_failure_status")
+	if q850 == nil && termStatus == nil && inviteFailure == nil {
+		return
+	}
+
+	c.log.WithFields(logrus.Fields{
+		"uuid":                   uuid,
+		"q850":                   q850,
+		"sipTermStatus":          termStatus,
+		"sipInviteFailureStatus": inviteFailure,
+	}).Debug("Parsed hangup codes for CHANNEL_HANGUP_COMPLETE")
+
+	if err := c.store.SetCallHangupCodes(ctx, uuid, q850, termStatus, inviteFailure); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to store hangup codes from CHANNEL_HANGUP_COMPLETE")
+	}
+}
@@ -0,0 +1,39 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+// handleHeartbeat stores the switch load figures carried by a HEARTBEAT event (every 20 seconds by default)
+func (c *Client) handleHeartbeat(ctx context.Context, msg *goesl.Message) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	h := &store.Heartbeat{
+		Node:        c.node(),
+		Hostname:    optionalHeader(msg, "FreeSWITCH-Hostname"),
+		CoreUUID:    optionalHeader(msg, "Core-UUID"),
+		Version:     optionalHeader(msg, "FreeSWITCH-Version"),
+		MaxSessions: intHeader(msg, "Max-Sessions"),
+		SessionPeak: intHeader(msg, "Session-Peak-Max"),
+		RecordedAt:  at,
+	}
+	h.UptimeMS, _ = strconv.ParseInt(msg.GetHeader("Uptime-msec"), 10, 64)
+	h.SessionCount, _ = strconv.Atoi(msg.GetHeader("Session-Count"))
+	h.SessionsPerSec, _ = strconv.ParseFloat(msg.GetHeader("Session-Per-Sec"), 64)
+	h.SessionsSinceStartup, _ = strconv.ParseInt(msg.GetHeader("Session-Since-Startup"), 10, 64)
+	if idle, err := strconv.ParseFloat(msg.GetHeader("Idle-CPU"), 64); err == nil {
+		h.IdleCPU = &idle
+	}
+
+	if err := c.store.AddHeartbeat(ctx, h); err != nil {
+		c.log.WithError(err).WithField("node", h.Node).Error("Failed to record heartbeat")
+	}
+}
@@ -0,0 +1,25 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"github.com/0x19/goesl"
+)
+
+// handleHold opens or closes a hold interval from CHANNEL_HOLD and CHANNEL_UNHOLD. Hangup also ends
+// the hold, since a call can be torn down while held.
+func (c *Client) handleHold(ctx context.Context, msg *goesl.Message, uuid string, held bool) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if held {
+		err = c.store.StartHold(ctx, uuid, at)
+	} else {
+		err = c.store.EndHold(ctx, uuid, at)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).WithField("held", held).Error("Failed to record hold state")
+	}
+}
@@ -0,0 +1,99 @@
+package esl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// closedRetention is how long past the grace window a closed call is remembered, so that events
+// arriving even later are recognized and discarded rather than reopening the record
+const closedRetention = time.Hour
+
+var lateEventsCounter = metrics.NewCounterVec("esl_late_events_total", "Events received after their call was closed, by whether they were merged or discarded.", "node", "outcome")
+
+// teardownEvents follow CHANNEL_HANGUP_COMPLETE in the normal course of a hangup and are not corrections
+var teardownEvents = map[string]bool{
+	"CHANNEL_STATE":            true,
+	"CHANNEL_CALLSTATE":        true,
+	"CHANNEL_DESTROY":          true,
+	"CHANNEL_EXECUTE":          true,
+	"CHANNEL_EXECUTE_COMPLETE": true,
+}
+
+// closedCalls remembers when calls were closed by CHANNEL_HANGUP_COMPLETE
+type closedCalls struct {
+	mu        sync.Mutex
+	at        map[string]time.Time // uuid -> when the close was handled
+	lastPrune time.Time
+}
+
+// close records that uuid was closed at now, forgetting calls closed longer ago than keep
+func (t *closedCalls) close(uuid string, now time.Time, keep time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.at == nil {
+		t.at = make(map[string]time.Time)
+	}
+	if _, ok := t.at[uuid]; !ok {
+		t.at[uuid] = now
+	}
+	if now.Sub(t.lastPrune) < time.Minute {
+		return
+	}
+	t.lastPrune = now
+	for id, closed := range t.at {
+		if now.Sub(closed) > keep {
+			delete(t.at, id)
+		}
+	}
+}
+
+// closedAt returns when uuid was closed, if it is still remembered
+func (t *closedCalls) closedAt(uuid string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.at[uuid]
+	return at, ok
+}
+
+// admitLate decides what to do with an event for a call that may already be closed. Events within
+// the grace window are merged and the call is flagged as corrected; later ones are discarded.
+// It returns false when the event must be dropped.
+func (c *Client) admitLate(ctx context.Context, msg *goesl.Message, eventName, uuid string) bool {
+	if teardownEvents[eventName] {
+		return true
+	}
+	closed, ok := c.closed.closedAt(uuid)
+	if !ok {
+		return true
+	}
+	late := time.Since(closed)
+	fields := logrus.Fields{
+		"uuid":      uuid,
+		"eventName": eventName,
+		"late":      late.String(),
+	}
+	if late > c.lateGrace {
+		lateEventsCounter.With(c.node(), "discarded").Inc()
+		c.log.WithFields(fields).Warn("Discarding event received after the late-event grace window")
+		return false
+	}
+
+	lateEventsCounter.With(c.node(), "merged").Inc()
+	c.log.WithFields(fields).Info("Merging late event into closed call")
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if err := c.store.MarkCallCorrected(ctx, uuid, at); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to flag late correction")
+	}
+	return true
+}
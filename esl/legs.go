@@ -0,0 +1,52 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// bridgePair returns the two channels of a CHANNEL_BRIDGE or CHANNEL_UNBRIDGE event. The Bridge-A/B headers
+// name the originating and originated legs; older switches only send Other-Leg-Unique-ID.
+func bridgePair(msg *goesl.Message, uuid string) (a, b string) {
+	a, b = msg.GetHeader("Bridge-A-Unique-ID"), msg.GetHeader("Bridge-B-Unique-ID")
+	if a != "" && b != "" {
+		return a, b
+	}
+	return uuid, msg.GetHeader("Other-Leg-Unique-ID")
+}
+
+// recordBridge links the two legs of a CHANNEL_BRIDGE event
+func (c *Client) recordBridge(ctx context.Context, msg *goesl.Message, uuid string) {
+	a, b := bridgePair(msg, uuid)
+	if b == "" || a == b {
+		c.log.WithField("uuid", uuid).Warn("CHANNEL_BRIDGE without an other leg, not linking")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if err := c.store.AddCallLeg(ctx, a, b, at); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record call leg from CHANNEL_BRIDGE")
+		return
+	}
+	c.log.WithFields(logrus.Fields{"aLeg": a, "bLeg": b}).Info("Linked call legs from CHANNEL_BRIDGE")
+}
+
+// handleChannelUnbridge records the end of a bridge
+func (c *Client) handleChannelUnbridge(ctx context.Context, msg *goesl.Message, uuid string) {
+	a, b := bridgePair(msg, uuid)
+	if b == "" || a == b {
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if err := c.store.EndCallLeg(ctx, a, b, at); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to end call leg from CHANNEL_UNBRIDGE")
+	}
+}
@@ -0,0 +1,68 @@
+package esl
+
+import "context"
+
+// Limits caps event handling concurrency. Zero values mean unlimited.
+type Limits struct {
+	Workers     int             // Handler goroutines, each owning a shard of the calls; 0 spawns one per event
+	QueueSize   int             // Events waiting for a worker, split evenly between the workers
+	QueueFull   QueueFullPolicy // What to do with events that find the queue full
+	MaxHandlers int             // Concurrent handler goroutines across all event types, when Workers is 0
+	PerEvent    map[string]int  // Concurrent handlers per Event-Name
+	Shed        ShedPolicy      // Load shedding of low-priority events under sustained overload
+}
+
+// limiter enforces Limits with channel semaphores
+type limiter struct {
+	global   chan struct{}
+	perEvent map[string]chan struct{}
+}
+
+// newLimiter builds a limiter from l
+func newLimiter(l Limits) *limiter {
+	lim := &limiter{perEvent: make(map[string]chan struct{})}
+	if l.MaxHandlers > 0 {
+		lim.global = make(chan struct{}, l.MaxHandlers)
+	}
+	for event, n := range l.PerEvent {
+		if n > 0 {
+			lim.perEvent[event] = make(chan struct{}, n)
+		}
+	}
+	return lim
+}
+
+// acquire blocks until a slot in sem is free or ctx is done. A nil sem is unlimited.
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if sem == nil {
+		return true
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot acquired from sem
+func release(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
+
+// acquireGlobal reserves a handler slot; it is called from the read loop so a full pool applies backpressure
+func (l *limiter) acquireGlobal(ctx context.Context) bool { return acquire(ctx, l.global) }
+
+// releaseGlobal frees a handler slot
+func (l *limiter) releaseGlobal() { release(l.global) }
+
+// acquireEvent reserves a slot for the given event type; without workers it is called from the read loop
+// before acquireGlobal, so a busy event type holds back the socket rather than piling up goroutines
+func (l *limiter) acquireEvent(ctx context.Context, eventName string) bool {
+	return acquire(ctx, l.perEvent[eventName])
+}
+
+// releaseEvent frees a slot for the given event type
+func (l *limiter) releaseEvent(eventName string) { release(l.perEvent[eventName]) }
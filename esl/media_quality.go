@@ -0,0 +1,64 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+// floatHeader returns the header value parsed as a float, or nil if absent or invalid
+func floatHeader(msg *goesl.Message, name string) *float64 {
+	v, err := strconv.ParseFloat(msg.GetHeader(name), 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// int64Header returns the header value parsed as an int64, or nil if absent or invalid
+func int64Header(msg *goesl.Message, name string) *int64 {
+	v, err := strconv.ParseInt(msg.GetHeader(name), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// recordMediaQuality stores the rtp_audio_* statistics FreeSWITCH sets on a channel at hangup. Calls
+// that never carried RTP have none and are skipped.
+func (c *Client) recordMediaQuality(ctx context.Context, msg *goesl.Message, uuid string) {
+	q := &store.MediaQuality{
+		UUID:              uuid,
+		MOS:               floatHeader(msg, "variable_rtp_audio_in_mos"),
+		QualityPercentage: floatHeader(msg, "variable_rtp_audio_in_quality_percentage"),
+		JitterMinVariance: floatHeader(msg, "variable_rtp_audio_in_jitter_min_variance"),
+		JitterMaxVariance: floatHeader(msg, "variable_rtp_audio_in_jitter_max_variance"),
+		JitterLossRate:    floatHeader(msg, "variable_rtp_audio_in_jitter_loss_rate"),
+		JitterBurstRate:   floatHeader(msg, "variable_rtp_audio_in_jitter_burst_rate"),
+		MeanInterval:      floatHeader(msg, "variable_rtp_audio_in_mean_interval"),
+		Flaws:             int64Header(msg, "variable_rtp_audio_in_flaw_total"),
+		PacketsIn:         int64Header(msg, "variable_rtp_audio_in_packet_count"),
+		PacketsOut:        int64Header(msg, "variable_rtp_audio_out_packet_count"),
+		PacketsLost:       int64Header(msg, "variable_rtp_audio_in_skip_packet_count"),
+	}
+	if q.MOS == nil && q.PacketsIn == nil {
+		return
+	}
+	if q.PacketsIn != nil && q.PacketsLost != nil && *q.PacketsIn+*q.PacketsLost > 0 {
+		loss := 100 * float64(*q.PacketsLost) / float64(*q.PacketsIn+*q.PacketsLost)
+		q.PacketLoss = &loss
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	q.RecordedAt = at
+
+	if err := c.store.SetMediaQuality(ctx, q); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record media quality")
+	}
+}
@@ -0,0 +1,59 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleMessageEvent records a mod_sms MESSAGE event: either a message or a delivery report for one
+func (c *Client) handleMessageEvent(ctx context.Context, msg *goesl.Message) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	messageID := firstHeader(msg, "Nonce", "Message-ID", "sip_call_id", "Call-ID")
+
+	if failure := msg.GetHeader("Delivery-Failure"); failure != "" {
+		if messageID == "" {
+			c.log.Debug("Delivery report without a message id, skipping")
+			return
+		}
+		status := store.MessageDelivered
+		if failure == "true" {
+			status = store.MessageFailed
+		}
+		if err := c.store.SetMessageStatus(ctx, messageID, status, optionalHeader(msg, "Delivery-Result-Code"), at); err != nil {
+			c.log.WithError(err).WithField("messageID", messageID).Error("Failed to record message delivery status")
+		}
+		return
+	}
+
+	m := &store.Message{
+		Direction:   store.MessageOutbound,
+		Proto:       optionalHeader(msg, "proto"),
+		From:        firstHeader(msg, "from", "from_user"),
+		To:          firstHeader(msg, "to", "to_user"),
+		Body:        string(msg.Body),
+		ContentType: optionalHeader(msg, "type"),
+		Status:      store.MessageSent,
+		CreatedAt:   at,
+	}
+	if messageID != "" {
+		m.MessageID = &messageID
+	}
+	if msg.GetHeader("from_sip_ip") != "" { // Set by sofia for messages arriving from the network
+		m.Direction, m.Status = store.MessageInbound, store.MessageReceived
+	}
+
+	if err := c.store.AddMessage(ctx, m); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"from": m.From,
+			"to":   m.To,
+		}).Error("Failed to record message")
+	}
+}
@@ -0,0 +1,80 @@
+package esl
+
+import (
+	"fmt"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/calendar"
+
+	"github.com/0x19/goesl"
+)
+
+// MissedCallPolicy decides which unanswered inbound calls are missed calls and where to report them
+type MissedCallPolicy struct {
+	Causes   []string        // Hangup causes that count as missed
+	Notifier *alert.Notifier // Optional dedicated missed-call webhook
+
+	// BusinessHoursOnly suppresses notifications for calls started outside their domain's business hours.
+	// Calls without a matching calendar are always notified.
+	BusinessHoursOnly bool
+	Calendars         *calendar.Calendars
+}
+
+// isMissed reports whether msg is the hangup of an unanswered inbound call with a missed-call cause
+func (p MissedCallPolicy) isMissed(msg *goesl.Message, cause string) bool {
+	if msg.GetHeader("Call-Direction") != "inbound" {
+		return false
+	}
+	if answered := msg.GetHeader("Caller-Channel-Answered-Time"); answered != "" && answered != "0" {
+		return false
+	}
+	for _, c := range p.Causes {
+		if c == cause {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyMissedCall fires the missed-call webhook for a hangup that qualifies as a missed call
+func (c *Client) notifyMissedCall(msg *goesl.Message, uuid string, endTime time.Time, cause string) {
+	if !c.missed.Notifier.Enabled() || !c.missed.isMissed(msg, cause) {
+		return
+	}
+
+	caller := msg.GetHeader("Caller-Caller-ID-Number")
+	fields := map[string]any{
+		"uuid":        uuid,
+		"caller":      caller,
+		"caller_name": msg.GetHeader("Caller-Caller-ID-Name"),
+		"callee":      msg.GetHeader("Caller-Destination-Number"),
+		"cause":       cause,
+		"end_time":    endTime,
+		"node":        c.node(),
+	}
+	start, err := parseMicroTimestamp(msg.GetHeader("Caller-Channel-Created-Time"))
+	if err == nil {
+		fields["start_time"] = start
+	} else {
+		start = endTime
+	}
+	domain := callDomain(msg)
+	if domain != nil {
+		fields["domain"] = *domain
+	}
+	if in := businessHours(c.missed.Calendars, domain, start); in != nil {
+		if !*in && c.missed.BusinessHoursOnly {
+			c.log.WithField("uuid", uuid).Debug("Missed call outside business hours; not notifying")
+			return
+		}
+		fields["business_hours"] = *in
+	}
+
+	c.missed.Notifier.Notify(alert.Alert{
+		Type:     "missed_call",
+		Severity: alert.SeverityInfo,
+		Message:  fmt.Sprintf("Missed call from %s (%s)", caller, cause),
+		Fields:   fields,
+	})
+}
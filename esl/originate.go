@@ -0,0 +1,223 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultOriginateTimeout is used when OriginateRequest.Timeout is unset
+const defaultOriginateTimeout = 30 * time.Second
+
+// Limits applied to retry policies
+const (
+	MaxOriginateAttempts = 10
+	MaxRetryInterval     = 10 * time.Minute
+)
+
+var (
+	ErrInvalidOriginate = errors.New("invalid originate request")
+	ErrOriginateFailed  = errors.New("originate failed")
+)
+
+// OriginateRequest describes an outbound call placed with the originate API
+type OriginateRequest struct {
+	Endpoint       string // Dial string, e.g. sofia/gateway/carrier_a/15551234567
+	Destination    string // Dialplan extension ("1000 XML default") or application ("&playback(file.wav)"); defaults to &park()
+	CallerIDNumber string
+	CallerIDName   string
+	Timeout        time.Duration     // How long to wait for answer
+	Variables      map[string]string // Extra channel variables
+	Confirm        *Confirmation     // Require the callee to press a key; nil accepts any answer
+}
+
+// Confirmation makes an originate succeed only once the callee presses Key after hearing Prompt
+// (FreeSWITCH group_confirm), so answering machines and voicemail don't count as answered
+type Confirmation struct {
+	Key     string        // DTMF digit to press, e.g. "1"
+	Prompt  string        // Sound played to the callee on answer
+	Timeout time.Duration // How long to wait for the key; 0 uses the FreeSWITCH default
+}
+
+// RetryPolicy controls re-dialling a failed originate. Each attempt is a separate call.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts including the first; 0 or 1 disables retries
+	Interval    time.Duration // Wait between attempts
+	OnCauses    []string      // Hangup causes worth retrying, e.g. NO_ANSWER; empty retries any failure
+}
+
+// OriginateResult is the outcome of an originate attempt. CallUUID is assigned before dialing, so it is set
+// even when the call fails and can be used to find the call record.
+type OriginateResult struct {
+	CallUUID string `json:"call_uuid"`
+	Cause    string `json:"cause,omitempty"`   // Hangup cause when the originate failed
+	Attempt  int    `json:"attempt,omitempty"` // 1-based attempt number under a retry policy
+}
+
+// Originator places a single outbound call; implemented by *Client
+type Originator interface {
+	Originate(ctx context.Context, req OriginateRequest) (OriginateResult, error)
+}
+
+// unsafeOriginateChars would break out of the originate command or its {var=value} block
+const unsafeOriginateChars = "\r\n{}',"
+
+// command renders the originate command for callUUID
+func (r OriginateRequest) command(callUUID string) (string, error) {
+	if r.Endpoint == "" || strings.ContainsAny(r.Endpoint, unsafeOriginateChars+" ") {
+		return "", fmt.Errorf("%w: endpoint", ErrInvalidOriginate)
+	}
+	destination := r.Destination
+	if destination == "" {
+		destination = "&park()"
+	}
+	if strings.ContainsAny(destination, "\r\n") {
+		return "", fmt.Errorf("%w: destination", ErrInvalidOriginate)
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultOriginateTimeout
+	}
+
+	vars := map[string]string{
+		"origination_uuid":   callUUID,
+		"originate_timeout":  fmt.Sprintf("%d", int(timeout.Seconds())),
+		"ignore_early_media": "true",
+	}
+	if r.CallerIDNumber != "" {
+		vars["origination_caller_id_number"] = r.CallerIDNumber
+	}
+	if r.CallerIDName != "" {
+		vars["origination_caller_id_name"] = r.CallerIDName
+	}
+	if r.Confirm != nil {
+		if len(r.Confirm.Key) != 1 || !strings.Contains("0123456789*#", r.Confirm.Key) {
+			return "", fmt.Errorf("%w: confirm key must be one DTMF digit", ErrInvalidOriginate)
+		}
+		if r.Confirm.Prompt == "" {
+			return "", fmt.Errorf("%w: confirm prompt is required", ErrInvalidOriginate)
+		}
+		vars["group_confirm_key"] = r.Confirm.Key
+		vars["group_confirm_file"] = r.Confirm.Prompt
+		if r.Confirm.Timeout > 0 {
+			vars["group_confirm_read_timeout"] = fmt.Sprintf("%d", r.Confirm.Timeout.Milliseconds())
+		}
+	}
+	for k, v := range r.Variables {
+		vars[k] = v
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k, v := range vars {
+		if k == "" || strings.ContainsAny(k, unsafeOriginateChars+"= ") || strings.ContainsAny(v, unsafeOriginateChars) {
+			return "", fmt.Errorf("%w: variable %q", ErrInvalidOriginate, k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s='%s'", k, vars[k])
+	}
+	return fmt.Sprintf("originate {%s}%s %s", strings.Join(pairs, ","), r.Endpoint, destination), nil
+}
+
+// Validate reports whether the request can be rendered into a safe originate command
+func (r OriginateRequest) Validate() error {
+	_, err := r.command(newUUID())
+	return err
+}
+
+// Validate reports whether the policy is within the supported limits
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxOriginateAttempts {
+		return fmt.Errorf("%w: max attempts must be between 1 and %d", ErrInvalidOriginate, MaxOriginateAttempts)
+	}
+	if p.Interval < 0 || p.Interval > MaxRetryInterval {
+		return fmt.Errorf("%w: retry interval may not exceed %s", ErrInvalidOriginate, MaxRetryInterval)
+	}
+	for _, cause := range p.OnCauses {
+		if cause == "" || strings.ContainsAny(cause, unsafeOriginateChars+" ") {
+			return fmt.Errorf("%w: retry cause %q", ErrInvalidOriginate, cause)
+		}
+	}
+	return nil
+}
+
+// attempts returns the total number of attempts allowed by the policy
+func (p RetryPolicy) attempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// retries reports whether a call that failed with cause should be dialled again
+func (p RetryPolicy) retries(cause string) bool {
+	if len(p.OnCauses) == 0 {
+		return true
+	}
+	for _, c := range p.OnCauses {
+		if strings.EqualFold(c, cause) {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginateWithRetry places req through o, dialling again per policy until a call is answered, fails with a
+// cause the policy does not retry, or the attempts run out. Retries carry retry_of (the first attempt's
+// UUID) and retry_attempt channel variables, which link their call records to the first. It returns every
+// attempt's result in order, with the error of the last one.
+func OriginateWithRetry(ctx context.Context, o Originator, req OriginateRequest, policy RetryPolicy) ([]OriginateResult, error) {
+	attempts := policy.attempts()
+	results := make([]OriginateResult, 0, attempts)
+	for n := 1; ; n++ {
+		attemptReq := req
+		if attempts > 1 {
+			attemptReq.Variables = make(map[string]string, len(req.Variables)+2)
+			for k, v := range req.Variables {
+				attemptReq.Variables[k] = v
+			}
+			attemptReq.Variables["retry_attempt"] = fmt.Sprintf("%d", n)
+			if n > 1 {
+				attemptReq.Variables["retry_of"] = results[0].CallUUID
+			}
+		}
+
+		result, err := o.Originate(ctx, attemptReq)
+		if attempts > 1 {
+			result.Attempt = n
+		}
+		results = append(results, result)
+		if err == nil || n >= attempts || !errors.Is(err, ErrOriginateFailed) || !policy.retries(result.Cause) {
+			return results, err
+		}
+
+		select {
+		case <-time.After(policy.Interval):
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+}
+
+// Originate places an outbound call and waits until it is answered or fails. A failed call returns
+// ErrOriginateFailed with the hangup cause in the result.
+func (c *Client) Originate(ctx context.Context, req OriginateRequest) (OriginateResult, error) {
+	result := OriginateResult{CallUUID: newUUID()}
+	command, err := req.command(result.CallUUID)
+	if err != nil {
+		return result, err
+	}
+
+	body, err := c.bgapiWait(ctx, command)
+	if err != nil {
+		return result, err
+	}
+	if strings.HasPrefix(body, "+OK") {
+		return result, nil
+	}
+	result.Cause = strings.TrimSpace(strings.TrimPrefix(body, "-ERR"))
+	return result, fmt.Errorf("%w: %s", ErrOriginateFailed, result.Cause)
+}
@@ -0,0 +1,181 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+)
+
+// Mode selects how the client talks to FreeSWITCH
+type Mode string
+
+const (
+	ModeInbound  Mode = "inbound"  // Connect to the switch's event socket (ESL_ADDR)
+	ModeOutbound Mode = "outbound" // Accept connections from the switch's socket dialplan application
+	ModeBoth     Mode = "both"     // Both; point them at different switches or events are handled twice
+)
+
+var outboundConnsGauge = metrics.NewGaugeVec("esl_outbound_connections", "Outbound socket connections from FreeSWITCH currently open.", "listen")
+
+// ParseMode parses an ESL_MODE value; empty means inbound
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModeInbound, nil
+	case ModeInbound, ModeOutbound, ModeBoth:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown ESL mode %q (expected inbound, outbound or both)", s)
+	}
+}
+
+// inbound reports whether the client connects to the switch's event socket
+func (m Mode) inbound() bool {
+	return m != ModeOutbound
+}
+
+// outbound reports whether the client accepts socket application connections
+func (m Mode) outbound() bool {
+	return m == ModeOutbound || m == ModeBoth
+}
+
+// listenOutbound accepts outbound socket connections from FreeSWITCH until ctx is cancelled. Each
+// connection carries the events of the one channel that ran the socket application.
+func (c *Client) listenOutbound(ctx context.Context) {
+	// The server is built directly rather than with goesl.NewOutboundServer, which installs a signal
+	// handler that exits the process
+	srv := &goesl.OutboundServer{Addr: c.outboundListen, Proto: "tcp", Conns: make(chan goesl.SocketConnection)}
+	failed := make(chan error, 1)
+	go func() {
+		failed <- srv.Start()
+	}()
+	c.log.WithField("listen", c.outboundListen).Info("Accepting outbound ESL connections from FreeSWITCH")
+	if !c.mode.inbound() {
+		c.markConnected() // The listener is the only link to the switch, so it stands for the connection status
+	}
+
+	// goesl hands connections over by value, before their mutex is first used; forward them as pointers so
+	// each handler works on a single copy
+	conns := make(chan *goesl.SocketConnection)
+	go pipeByPointer(ctx, srv.Conns, conns)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if srv.Listener != nil {
+				srv.Stop()
+			}
+			c.log.Info("Outbound ESL listener stopping due to context cancellation.")
+			return
+		case err := <-failed:
+			if !errors.Is(err, net.ErrClosed) {
+				c.log.WithError(err).WithField("listen", c.outboundListen).Error("Outbound ESL listener stopped")
+				c.markError(fmt.Errorf("outbound listener: %w", err), !c.mode.inbound())
+			}
+			return
+		case conn := <-conns:
+			go c.serveOutbound(ctx, conn)
+		}
+	}
+}
+
+// pipeByPointer forwards values received on in to out as pointers to copies until ctx is cancelled
+func pipeByPointer[T any](ctx context.Context, in <-chan T, out chan<- *T) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v := <-in:
+			select {
+			case out <- &v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// serveOutbound takes over one socket application connection: it requests the channel data, subscribes to
+// the channel's events and dispatches them like inbound events until the channel hangs up and the switch
+// closes the connection
+func (c *Client) serveOutbound(ctx context.Context, conn *goesl.SocketConnection) {
+	closed := false // Whether the read side has already reported the connection closed
+	defer func() {
+		conn.Close()
+		if !closed {
+			go drainOutbound(conn)
+		}
+	}()
+	logger := c.log.WithField("remote", conn.OriginatorAddr().String())
+
+	gauge := outboundConnsGauge.With(c.outboundListen)
+	gauge.Add(1)
+	defer gauge.Add(-1)
+
+	// connect answers with the channel data; linger keeps the socket open after hangup so the final
+	// events (CHANNEL_HANGUP_COMPLETE) are still delivered
+	if err := conn.Send("connect"); err != nil {
+		logger.WithError(err).Error("Failed to send connect on outbound ESL connection")
+		return
+	}
+	data, err := conn.ReadMessage()
+	if err != nil {
+		closed = true
+		logger.WithError(err).Error("Failed to read channel data on outbound ESL connection")
+		return
+	}
+	uuid := data.GetHeader("Unique-ID")
+	if uuid == "" {
+		logger.Warn("Outbound ESL connection sent no channel data; closing")
+		return
+	}
+	logger = logger.WithField("uuid", uuid)
+	for _, cmd := range []string{"myevents json", "linger"} {
+		if err := conn.Send(cmd); err != nil {
+			logger.WithError(err).WithField("command", cmd).Error("Failed to send command on outbound ESL connection")
+			return
+		}
+	}
+	logger.Debug("Outbound ESL connection established")
+
+	// The socket application runs after CHANNEL_CREATE was fired, so the channel data stands in for it
+	create := &goesl.Message{Headers: make(map[string]string, len(data.Headers)+1)}
+	for k, v := range data.Headers {
+		create.Headers[k] = v
+	}
+	create.Headers["Event-Name"] = "CHANNEL_CREATE"
+	c.countEvent()
+	c.dispatch(ctx, create)
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			closed = true
+			logger.WithError(err).Debug("Outbound ESL connection closed")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if msg.GetHeader("Event-Name") == "" {
+			continue // Command replies
+		}
+		c.countEvent()
+		c.dispatch(ctx, msg)
+	}
+}
+
+// drainOutbound reads a closed connection until goesl's reader reports the error, so its goroutines exit
+func drainOutbound(conn *goesl.SocketConnection) {
+	for {
+		if _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
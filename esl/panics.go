@@ -0,0 +1,46 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+var panicsCounter = metrics.NewCounterVec("esl_handler_panics_total", "Event handler panics recovered without stopping the process.", "node", "event")
+
+// safeHandleEvent runs handleEvent, recovering a panic so one malformed or unexpected event cannot take down
+// the process. The panic is logged with its stack and event context, counted, and the raw event is written
+// to the dead-letter spool when one is configured. The panic is returned as an error.
+func (c *Client) safeHandleEvent(ctx context.Context, msg *goesl.Message) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		eventName := msg.GetHeader("Event-Name")
+		panicsCounter.With(c.node(), eventName).Inc()
+		c.log.WithFields(logrus.Fields{
+			"eventName": eventName,
+			"uuid":      msg.GetHeader("Unique-ID"),
+			"subclass":  msg.GetHeader("Event-Subclass"),
+			"sequence":  msg.GetHeader("Event-Sequence"),
+			"panic":     fmt.Sprint(r),
+			"stack":     string(debug.Stack()),
+		}).Error("Recovered panic in ESL event handler")
+		err = fmt.Errorf("handler panicked: %v", r)
+
+		if c.deadLetter == nil {
+			return
+		}
+		if err := c.deadLetter.Append(c.sealedMessage(msg), nil); err != nil {
+			c.log.WithError(err).WithField("eventName", eventName).Error("Failed to write panicking event to dead-letter spool")
+		}
+	}()
+	c.handleEvent(ctx, msg)
+	return nil
+}
@@ -0,0 +1,51 @@
+package esl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidTransfer = errors.New("invalid transfer request")
+	ErrTransferFailed  = errors.New("transfer failed")
+)
+
+var lotPattern = regexp.MustCompile(`^[0-9A-Za-z_.-]{1,64}$`)
+
+// Park moves the call into slot of the valet parking lot with uuid_transfer. A call bridged to another
+// leg is unbridged and the other leg hangs up per its dialplan (typically the agent who parked it).
+func (c *Client) Park(ctx context.Context, callUUID, lot string, slot int) error {
+	if !callUUIDPattern.MatchString(callUUID) {
+		return fmt.Errorf("%w: call uuid", ErrInvalidTransfer)
+	}
+	if !lotPattern.MatchString(lot) || slot <= 0 {
+		return fmt.Errorf("%w: lot or slot", ErrInvalidTransfer)
+	}
+	return c.transfer(ctx, fmt.Sprintf("uuid_transfer %s 'valet_park:%s %d' inline", callUUID, lot, slot))
+}
+
+// Retrieve transfers a parked call to extension in the XML dialplan context
+func (c *Client) Retrieve(ctx context.Context, callUUID, extension, dialplanContext string) error {
+	if !callUUIDPattern.MatchString(callUUID) {
+		return fmt.Errorf("%w: call uuid", ErrInvalidTransfer)
+	}
+	if !extensionPattern.MatchString(extension) || !lotPattern.MatchString(dialplanContext) {
+		return fmt.Errorf("%w: extension or context", ErrInvalidTransfer)
+	}
+	return c.transfer(ctx, fmt.Sprintf("uuid_transfer %s %s XML %s", callUUID, extension, dialplanContext))
+}
+
+// transfer runs a uuid_transfer command and maps a refusal to ErrTransferFailed
+func (c *Client) transfer(ctx context.Context, command string) error {
+	body, err := c.bgapiWait(ctx, command)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(body, "+OK") {
+		return fmt.Errorf("%w: %s", ErrTransferFailed, strings.TrimSpace(strings.TrimPrefix(body, "-ERR")))
+	}
+	return nil
+}
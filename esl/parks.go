@@ -0,0 +1,69 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handlePark opens or closes a park interval from CHANNEL_PARK and CHANNEL_UNPARK. Hangup also ends the
+// park, since a caller can hang up while parked.
+func (c *Client) handlePark(ctx context.Context, msg *goesl.Message, uuid string, parked bool) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if parked {
+		err = c.store.StartPark(ctx, uuid, nil, nil, at)
+	} else {
+		err = c.store.EndPark(ctx, uuid, at, nil)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).WithField("parked", parked).Error("Failed to record park state")
+	}
+}
+
+// handleValetEvent records a call entering or leaving a mod_valet_parking lot from valet_parking::info.
+// Action is hold when the call is parked, bridge when another channel (Bridge-To-UUID) retrieves it and
+// exit when it leaves the lot otherwise.
+func (c *Client) handleValetEvent(ctx context.Context, msg *goesl.Message, uuid string) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	action := msg.GetHeader("Action")
+	switch action {
+	case "hold":
+		err = c.store.StartPark(ctx, uuid, optionalHeader(msg, "Valet-Lot-Name"), optionalHeader(msg, "Valet-Extension"), at)
+	case "bridge":
+		retriever := optionalHeader(msg, "Bridge-To-UUID")
+		if retriever != nil && *retriever == uuid {
+			retriever = nil
+		}
+		err = c.store.EndPark(ctx, uuid, at, retriever)
+	case "exit":
+		err = c.store.EndPark(ctx, uuid, at, nil)
+	default:
+		return
+	}
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":   uuid,
+			"action": action,
+		}).Error("Failed to record valet parking event")
+	}
+}
+
+// recordPickup records the channel that picked up a ringing call with the pickup or intercept apps, which
+// set intercepted_by on the call taken over
+func (c *Client) recordPickup(ctx context.Context, msg *goesl.Message, uuid string) {
+	retriever := msg.GetHeader("variable_intercepted_by")
+	if retriever == "" || retriever == uuid {
+		return
+	}
+	if err := c.store.SetCallRetrievedBy(ctx, uuid, retriever); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record call pickup")
+	}
+}
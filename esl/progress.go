@@ -0,0 +1,67 @@
+package esl
+
+import (
+	"context"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleCallTimestamp records a call-progress timestamp (ringing, answered, bridged) from the event time
+func (c *Client) handleCallTimestamp(ctx context.Context, msg *goesl.Message, uuid string, column store.CallTimestamp) {
+	eventName := msg.GetHeader("Event-Name")
+	tsStr := msg.GetHeader("Event-Date-Timestamp")
+	if tsStr == "" {
+		c.log.WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Error("Event-Date-Timestamp is missing for call-progress event")
+		return
+	}
+	ts, err := parseMicroTimestamp(tsStr)
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":           uuid,
+			"eventName":      eventName,
+			"timestampValue": tsStr,
+		}).Error("Failed to parse call-progress timestamp")
+		return
+	}
+
+	if err := c.store.SetCallTimestamp(ctx, uuid, column, ts); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Error("Failed to record call-progress timestamp")
+	}
+}
+
+// backfillProgressTimestamps fills any call-progress timestamps that were missed (e.g. while
+// disconnected) from the Caller-Channel-*-Time headers carried on the hangup event.
+func (c *Client) backfillProgressTimestamps(ctx context.Context, msg *goesl.Message, uuid string) {
+	headers := []struct {
+		column store.CallTimestamp
+		header string
+	}{
+		{store.TimestampRinging, "Caller-Channel-Progress-Time"},
+		{store.TimestampRinging, "Caller-Channel-Progress-Media-Time"},
+		{store.TimestampEarlyMedia, "Caller-Channel-Progress-Media-Time"},
+		{store.TimestampAnswered, "Caller-Channel-Answered-Time"},
+		{store.TimestampBridged, "Caller-Channel-Bridged-Time"},
+	}
+	for _, h := range headers {
+		value := msg.GetHeader(h.header)
+		if value == "" || value == "0" {
+			continue
+		}
+		ts, err := parseMicroTimestamp(value)
+		if err != nil {
+			continue
+		}
+		if err := c.store.SetCallTimestamp(ctx, uuid, h.column, ts); err != nil {
+			c.log.WithError(err).WithField("uuid", uuid).Warn("Failed to backfill call-progress timestamp")
+		}
+	}
+}
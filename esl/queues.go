@@ -0,0 +1,70 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+// queueOutcome classifies how a member left a mod_callcenter queue from its cc_* channel variables
+func queueOutcome(msg *goesl.Message) string {
+	return cancelOutcome(msg.GetHeader("variable_cc_queue_answered_epoch") != "", msg.GetHeader("variable_cc_cancel_reason"))
+}
+
+// cancelOutcome classifies a member's exit from whether an agent answered and mod_callcenter's cancel reason
+func cancelOutcome(answered bool, cancelReason string) string {
+	if answered {
+		return store.QueueOutcomeAnswered
+	}
+	switch cancelReason {
+	case "TIMEOUT", "NO_AGENT_TIMEOUT":
+		return store.QueueOutcomeTimeout
+	case "BREAK_OUT", "EXIT_WITH_KEY":
+		return store.QueueOutcomeExited
+	default:
+		return store.QueueOutcomeAbandoned // The caller hung up while waiting
+	}
+}
+
+// epochHeader parses a Unix-seconds channel variable, returning nil if it is absent or zero
+func epochHeader(msg *goesl.Message, name string) *time.Time {
+	secs, err := strconv.ParseInt(msg.GetHeader(name), 10, 64)
+	if err != nil || secs <= 0 {
+		return nil
+	}
+	t := time.Unix(secs, 0).UTC()
+	return &t
+}
+
+// recordQueueCall stores the queue wait of a call that passed through mod_callcenter, read from the
+// cc_* variables on its hangup event
+func (c *Client) recordQueueCall(ctx context.Context, msg *goesl.Message, uuid string, endTime time.Time) {
+	queue := msg.GetHeader("variable_cc_queue")
+	joined := epochHeader(msg, "variable_cc_queue_joined_epoch")
+	if queue == "" || joined == nil {
+		return
+	}
+
+	qc := &store.QueueCall{
+		UUID:       uuid,
+		Queue:      queue,
+		Agent:      optionalHeader(msg, "variable_cc_agent"),
+		JoinedAt:   *joined,
+		AnsweredAt: epochHeader(msg, "variable_cc_queue_answered_epoch"),
+		Outcome:    queueOutcome(msg),
+	}
+	qc.LeftAt = endTime
+	if qc.AnsweredAt != nil {
+		qc.LeftAt = *qc.AnsweredAt
+	} else if left := epochHeader(msg, "variable_cc_queue_canceled_epoch"); left != nil {
+		qc.LeftAt = *left
+	}
+
+	if err := c.store.RecordQueueCall(ctx, qc); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record queue call")
+	}
+}
@@ -0,0 +1,37 @@
+package esl
+
+import (
+	"context"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+// recordRawEvent stores msg verbatim in raw_events when raw event capture is enabled
+func (c *Client) recordRawEvent(ctx context.Context, msg *goesl.Message, eventName string) {
+	if !c.rawEvents {
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	node := c.node()
+	e := &store.RawEvent{
+		UUID:      optionalHeader(msg, "Unique-ID"),
+		EventName: eventName,
+		Subclass:  msg.GetHeader("Event-Subclass"),
+		Node:      &node,
+		EventTime: at,
+		Headers:   msg.Headers,
+	}
+	if len(msg.Body) > 0 {
+		body := string(msg.Body)
+		e.Body = &body
+	}
+	if err := c.store.AddRawEvent(ctx, e); err != nil {
+		c.log.WithError(err).WithField("eventName", eventName).Error("Failed to record raw event")
+	}
+}
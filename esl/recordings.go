@@ -0,0 +1,51 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleRecordEvent records the start or stop of a call recording from RECORD_START/RECORD_STOP
+func (c *Client) handleRecordEvent(ctx context.Context, msg *goesl.Message, uuid string) {
+	eventName := msg.GetHeader("Event-Name")
+	path := msg.GetHeader("Record-File-Path")
+	if path == "" {
+		c.log.WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Warn("Recording event has no Record-File-Path, skipping")
+		return
+	}
+	ts, err := eventTime(msg)
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Error("Failed to read timestamp for recording event")
+		return
+	}
+
+	if eventName == "RECORD_START" {
+		err = c.store.StartRecording(ctx, uuid, path, ts)
+	} else {
+		var durationMS *int64
+		// record_ms is set by mod_dptools when the recording stops; older versions only set record_seconds
+		if ms, perr := strconv.ParseInt(msg.GetHeader("variable_record_ms"), 10, 64); perr == nil {
+			durationMS = &ms
+		} else if secs, perr := strconv.ParseInt(msg.GetHeader("variable_record_seconds"), 10, 64); perr == nil {
+			ms := secs * 1000
+			durationMS = &ms
+		}
+		err = c.store.StopRecording(ctx, uuid, path, ts, durationMS)
+	}
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+			"path":      path,
+		}).Error("Failed to record recording event")
+	}
+}
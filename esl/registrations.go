@@ -0,0 +1,111 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleSwitchCustomEvent handles CUSTOM events that describe the switch rather than a single call, which
+// may carry no Unique-ID. It reports whether the event was handled.
+func (c *Client) handleSwitchCustomEvent(ctx context.Context, msg *goesl.Message) bool {
+	switch subclass := msg.GetHeader("Event-Subclass"); subclass {
+	case "sofia::register":
+		c.handleRegister(ctx, msg)
+	case "sofia::unregister":
+		c.handleRegistrationEnd(ctx, msg, store.RegistrationUnregistered)
+	case "sofia::expire":
+		c.handleRegistrationEnd(ctx, msg, store.RegistrationExpired)
+	case "conference::maintenance":
+		c.handleConferenceEvent(ctx, msg)
+	case "callcenter::info":
+		c.handleCallcenterEvent(ctx, msg)
+	case "vm::maintenance":
+		c.handleVoicemailEvent(ctx, msg)
+	case "sofia::gateway_state":
+		c.handleGatewayState(ctx, msg)
+	case "verto::login":
+		c.handleVertoLogin(msg)
+	case "verto::client_connect":
+		c.handleVertoConnect(ctx, msg)
+	case "verto::client_disconnect":
+		c.handleVertoDisconnect(ctx, msg)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleRegister records a REGISTER reported by sofia::register
+func (c *Client) handleRegister(ctx context.Context, msg *goesl.Message) {
+	callID := msg.GetHeader("call-id")
+	user := firstHeader(msg, "from-user", "username")
+	if callID == "" || user == "" {
+		c.log.WithField("callID", callID).Warn("sofia::register without call-id or user, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+
+	r := &store.Registration{
+		CallID:      callID,
+		Profile:     optionalHeader(msg, "profile-name"),
+		User:        user,
+		Realm:       optionalHeader(msg, "from-host"),
+		Contact:     optionalHeader(msg, "contact"),
+		NetworkIP:   optionalHeader(msg, "network-ip"),
+		NetworkPort: optionalHeader(msg, "network-port"),
+		UserAgent:   optionalHeader(msg, "user-agent"),
+		RefreshedAt: at,
+	}
+	if r.Realm == nil {
+		r.Realm = optionalHeader(msg, "realm")
+	}
+	if secs, err := strconv.Atoi(msg.GetHeader("expires")); err == nil && secs > 0 {
+		expires := at.Add(time.Duration(secs) * time.Second)
+		r.ExpiresAt = &expires
+	}
+
+	if err := c.store.UpsertRegistration(ctx, r); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"callID": callID,
+			"user":   user,
+		}).Error("Failed to record registration")
+	}
+}
+
+// handleRegistrationEnd closes a registration reported by sofia::unregister or sofia::expire
+func (c *Client) handleRegistrationEnd(ctx context.Context, msg *goesl.Message, status string) {
+	callID := msg.GetHeader("call-id")
+	if callID == "" {
+		c.log.WithField("status", status).Warn("Registration end event without call-id, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if err := c.store.EndRegistration(ctx, callID, status, at); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"callID": callID,
+			"status": status,
+		}).Error("Failed to end registration")
+	}
+}
+
+// firstHeader returns the first non-empty value among headers
+func firstHeader(msg *goesl.Message, headers ...string) string {
+	for _, h := range headers {
+		if v := msg.GetHeader(h); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package esl
+
+import (
+	"context"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleChannelUUID re-keys the call record when a channel's UUID changes, so later events for the new
+// UUID (including its hangup) update the existing record instead of leaving it orphaned
+func (c *Client) handleChannelUUID(ctx context.Context, msg *goesl.Message, uuid string) {
+	oldUUID := msg.GetHeader("Old-Unique-ID")
+	if oldUUID == "" || oldUUID == uuid {
+		c.log.WithField("uuid", uuid).Warn("CHANNEL_UUID without a distinct Old-Unique-ID, skipping")
+		return
+	}
+	fields := logrus.Fields{
+		"oldUUID": oldUUID,
+		"uuid":    uuid,
+	}
+	c.gateways.Rename(oldUUID, uuid)
+	if err := c.store.RenameCall(ctx, oldUUID, uuid); err != nil {
+		c.log.WithError(err).WithFields(fields).Error("Failed to rename call after UUID change")
+		return
+	}
+	c.log.WithFields(fields).Info("Renamed call after UUID change")
+}
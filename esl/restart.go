@@ -0,0 +1,115 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// RestartStatus is the hangup status given to calls left open when FreeSWITCH restarted
+const RestartStatus = "SWITCH_RESTART"
+
+// checkCoreUUID compares the Core-UUID of msg with the one known for this connection. The first event
+// after each (re)connect is checked against the last recorded Core-UUID in the background.
+func (c *Client) checkCoreUUID(msg *goesl.Message) {
+	coreUUID := msg.GetHeader("Core-UUID")
+	if coreUUID == "" {
+		return
+	}
+
+	c.coreMu.Lock()
+	if c.coreChecked && c.coreUUID == coreUUID {
+		c.coreMu.Unlock()
+		return
+	}
+	previous := c.coreUUID
+	c.coreUUID = coreUUID
+	c.coreChecked = true
+	c.coreMu.Unlock()
+
+	seenAt, err := eventTime(msg)
+	if err != nil {
+		seenAt = time.Now().UTC()
+	}
+	go c.handleCoreUUID(previous, coreUUID, seenAt)
+}
+
+// handleCoreUUID records the connection and, if the Core-UUID changed, closes calls orphaned by the restart
+func (c *Client) handleCoreUUID(previous, current string, seenAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if previous == "" {
+		last, err := c.store.GetLastCoreUUID(ctx, c.node())
+		if err != nil {
+			c.log.WithError(err).Error("Failed to load last Core-UUID, restart detection skipped for this connection")
+			return
+		}
+		previous = last
+	}
+
+	event := &store.SwitchEvent{
+		Node:       c.node(),
+		EventType:  store.SwitchEventConnected,
+		CoreUUID:   current,
+		OccurredAt: seenAt,
+	}
+	switch previous {
+	case "":
+		event.EventType = store.SwitchEventFirstSeen
+	case current:
+	default:
+		event.EventType = store.SwitchEventRestart
+		event.PreviousCoreUUID = &previous
+
+		// Calls that started before the new core's first event can never receive a hangup
+		closed, err := c.store.CloseOrphanedCalls(ctx, c.node(), []string{}, seenAt, RestartStatus)
+		if err != nil {
+			c.log.WithError(err).Error("Failed to close calls orphaned by FreeSWITCH restart")
+		}
+		event.ClosedCalls = closed
+
+		c.log.WithFields(logrus.Fields{
+			"previousCoreUUID": previous,
+			"coreUUID":         current,
+			"closedCalls":      closed,
+		}).Warn("FreeSWITCH restart detected (Core-UUID changed)")
+		c.notifier.Notify(alert.Alert{
+			Type:     "switch_restart",
+			Severity: alert.SeverityWarning,
+			Message:  fmt.Sprintf("FreeSWITCH node %s restarted; %d active calls closed", c.node(), closed),
+			Fields: map[string]any{
+				"node":               c.node(),
+				"core_uuid":          current,
+				"previous_core_uuid": previous,
+				"closed_calls":       closed,
+			},
+		})
+	}
+
+	if err := c.store.AddSwitchEvent(ctx, event); err != nil {
+		c.log.WithError(err).WithField("type", event.EventType).Error("Failed to record switch event")
+	}
+}
+
+// forgetCoreUUID discards the known Core-UUID so the next one is compared with the last recorded value
+// for the node instead, e.g. after switching to a different endpoint
+func (c *Client) forgetCoreUUID() {
+	c.coreMu.Lock()
+	c.coreUUID = ""
+	c.coreChecked = false
+	c.coreMu.Unlock()
+}
+
+// resetCoreUUID makes the next event re-check the Core-UUID, e.g. after a reconnect
+func (c *Client) resetCoreUUID() {
+	c.coreMu.Lock()
+	c.coreChecked = false
+	c.coreMu.Unlock()
+}
@@ -0,0 +1,29 @@
+package esl
+
+import (
+	"context"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// applyRules evaluates the derived-field rules against an event and stores any fields they set on the call
+func (c *Client) applyRules(ctx context.Context, msg *goesl.Message, uuid string) {
+	if !c.rules.Enabled() {
+		return
+	}
+	eventName := msg.GetHeader("Event-Name")
+	fields, errs := c.rules.Evaluate(eventName, msg.Headers)
+	for _, err := range errs {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Warn("Derived-field rule failed")
+	}
+	if len(fields) == 0 {
+		return
+	}
+	if err := c.store.MergeCallDerived(ctx, uuid, fields); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to store derived fields")
+	}
+}
@@ -0,0 +1,206 @@
+package esl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	gapAlertInterval  = time.Minute      // Minimum time between sequence-gap alerts
+	reconcileInterval = 30 * time.Second // Minimum time between gap-triggered reconciliations
+)
+
+// ReconciledStatus is the hangup status given to calls closed because FreeSWITCH no longer knows them
+const ReconciledStatus = "RECONCILED_NO_HANGUP"
+
+var (
+	sequenceGapsCounter = metrics.NewCounterVec("esl_event_sequence_gaps_total", "Gaps detected in the Event-Sequence header.", "node")
+	missedEventsCounter = metrics.NewCounterVec("esl_events_missed_total", "Events missing according to Event-Sequence gaps.", "node")
+)
+
+// sequenceTracker follows the Event-Sequence header of a single node. Event-Sequence is global to the
+// switch, so gaps are only meaningful while subscribed to all events.
+type sequenceTracker struct {
+	mu            sync.Mutex
+	last          uint64
+	seen          bool
+	lastAlert     time.Time
+	lastReconcile time.Time
+}
+
+// observe records seq and returns how many events were skipped since the previous one
+func (t *sequenceTracker) observe(seq uint64) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	defer func() {
+		t.last = seq
+		t.seen = true
+	}()
+	if !t.seen || seq <= t.last {
+		// First event on this connection, or the counter went backwards (FreeSWITCH restarted)
+		return 0
+	}
+	return seq - t.last - 1
+}
+
+// reset forgets the last sequence, e.g. after a reconnect
+func (t *sequenceTracker) reset() {
+	t.mu.Lock()
+	t.seen = false
+	t.mu.Unlock()
+}
+
+// due reports whether at least interval has passed since *last, updating it if so
+func (t *sequenceTracker) due(last *time.Time, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(*last) < interval {
+		return false
+	}
+	*last = time.Now()
+	return true
+}
+
+// checkSequence detects dropped events from the Event-Sequence header. It does nothing unless every event is
+// received, since a narrower subscription or a filter skips sequence numbers by design.
+func (c *Client) checkSequence(msg *goesl.Message) {
+	if !c.events.complete() {
+		return
+	}
+	seqStr := msg.GetHeader("Event-Sequence")
+	if seqStr == "" {
+		return // Command replies and api responses carry no sequence
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return
+	}
+
+	missed := c.sequence.observe(seq)
+	if missed == 0 {
+		return
+	}
+
+	sequenceGapsCounter.With(c.node()).Inc()
+	missedEventsCounter.With(c.node()).Add(float64(missed))
+	c.log.WithFields(logrus.Fields{
+		"sequence": seq,
+		"missed":   missed,
+	}).Warn("Event-Sequence gap detected, events were dropped on the socket")
+
+	if c.sequence.due(&c.sequence.lastAlert, gapAlertInterval) {
+		c.notifier.Notify(alert.Alert{
+			Type:     "esl_sequence_gap",
+			Severity: alert.SeverityWarning,
+			Message:  fmt.Sprintf("ESL node %s missed %d events (Event-Sequence gap at %d)", c.node(), missed, seq),
+			Fields: map[string]any{
+				"node":     c.node(),
+				"sequence": seq,
+				"missed":   missed,
+			},
+		})
+	}
+
+	if c.reconcileOnGap && c.sequence.due(&c.sequence.lastReconcile, reconcileInterval) {
+		if err := c.Reconcile(); err != nil {
+			c.log.WithError(err).Error("Failed to start reconciliation after sequence gap")
+		}
+	}
+}
+
+// channelRow is the subset of "show channels as json" output used for reconciliation
+type channelRow struct {
+	UUID         string `json:"uuid"`
+	Direction    string `json:"direction"`
+	CreatedEpoch string `json:"created_epoch"`
+	CIDNum       string `json:"cid_num"`
+	CIDName      string `json:"cid_name"`
+	Dest         string `json:"dest"`
+	Context      string `json:"context"`
+}
+
+// Reconcile compares the switch's active channels with the database: channels we never recorded are
+// inserted, and calls we still consider active but the switch no longer has are closed out.
+func (c *Client) Reconcile() error {
+	issued := time.Now().UTC()
+	_, err := c.bgapi("show channels as json", func(body string) {
+		c.applyReconciliation(body, issued)
+	})
+	if err == nil {
+		c.log.Info("Requested active channel list for reconciliation")
+	}
+	return err
+}
+
+// applyReconciliation processes the "show channels as json" output
+func (c *Client) applyReconciliation(body string, issued time.Time) {
+	var result struct {
+		RowCount int          `json:"row_count"`
+		Rows     []channelRow `json:"rows"`
+	}
+	// An idle switch answers "0 total." instead of JSON. Anything else that is not JSON, e.g. an -ERR or a
+	// timeout reply, says nothing about the channels and must not close every open call.
+	switch trimmed := strings.TrimSpace(body); {
+	case trimmed == "0 total.":
+	case strings.HasPrefix(trimmed, "{"):
+		if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
+			c.log.WithError(err).Error("Failed to parse show channels output; skipping reconciliation")
+			return
+		}
+	default:
+		c.log.WithField("reply", trimmed).Error("Unexpected show channels output; skipping reconciliation")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	node := c.node()
+	active := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		active = append(active, row.UUID)
+		call := &store.Call{
+			Node:      &node,
+			UUID:      row.UUID,
+			Direction: row.Direction,
+			Caller:    row.CIDNum,
+			Callee:    row.Dest,
+			StartTime: issued,
+		}
+		if epoch, err := strconv.ParseInt(row.CreatedEpoch, 10, 64); err == nil {
+			call.StartTime = time.Unix(epoch, 0).UTC()
+		}
+		if row.CIDName != "" {
+			call.CallerName = &row.CIDName
+		}
+		if row.Context != "" {
+			call.Context = &row.Context
+		}
+		if err := c.store.EnsureCall(ctx, call); err != nil {
+			c.log.WithError(err).WithField("uuid", row.UUID).Error("Failed to insert missed call during reconciliation")
+		}
+	}
+
+	closed, err := c.store.CloseOrphanedCalls(ctx, c.node(), active, issued, ReconciledStatus)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to close orphaned calls during reconciliation")
+		return
+	}
+	c.log.WithFields(logrus.Fields{
+		"activeChannels": len(active),
+		"closedCalls":    closed,
+	}).Info("Reconciliation with switch channel list complete")
+}
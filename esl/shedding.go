@@ -0,0 +1,124 @@
+package esl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gofreeswitchesl/metrics"
+)
+
+var (
+	shedCounter   = metrics.NewCounterVec("esl_events_shed_total", "Low-priority events dropped by load shedding.", "node", "event")
+	sheddingGauge = metrics.NewGaugeVec("esl_shedding_active", "Whether load shedding is currently active (1) or not (0).", "node")
+	inFlightGauge = metrics.NewGaugeVec("esl_handlers_in_flight", "Event handlers currently running or waiting for a per-event slot.", "node")
+)
+
+// criticalEvents are never shed, regardless of configuration
+var criticalEvents = map[string]bool{
+	"CHANNEL_CREATE":          true,
+	"CHANNEL_ANSWER":          true,
+	"CHANNEL_HANGUP":          true,
+	"CHANNEL_HANGUP_COMPLETE": true,
+	"CHANNEL_UUID":            true, // A dropped rename leaves an orphan record
+}
+
+// ShedPolicy controls dropping of low-priority events under sustained overload
+type ShedPolicy struct {
+	InFlightThreshold int           // Handlers in flight that count as overload; 0 disables shedding
+	Sustain           time.Duration // How long overload must last before shedding starts
+	SampleRate        float64       // Fraction of low-priority events still processed while shedding (0 drops all)
+	LowPriority       []string      // Event names eligible for shedding
+}
+
+// shedder tracks overload and decides which events to drop
+type shedder struct {
+	policy      ShedPolicy
+	lowPriority map[string]bool
+	keepEvery   uint64 // Keep one in keepEvery low-priority events while shedding; 0 keeps none
+
+	inFlight atomic.Int64
+	sampled  atomic.Uint64
+
+	mu              sync.Mutex
+	overloadedSince time.Time
+	active          bool
+}
+
+// newShedder builds a shedder from p
+func newShedder(p ShedPolicy) *shedder {
+	s := &shedder{policy: p, lowPriority: make(map[string]bool)}
+	for _, name := range p.LowPriority {
+		if !criticalEvents[name] {
+			s.lowPriority[name] = true
+		}
+	}
+	if p.SampleRate > 0 {
+		s.keepEvery = uint64(1/p.SampleRate + 0.5)
+		if s.keepEvery < 1 {
+			s.keepEvery = 1
+		}
+	}
+	return s
+}
+
+// begin and end bracket a handler so overload can be measured
+func (s *shedder) begin() { s.inFlight.Add(1) }
+func (s *shedder) end()   { s.inFlight.Add(-1) }
+
+// update re-evaluates the overload state and reports whether shedding is active and whether it changed
+func (s *shedder) update(now time.Time) (active, changed bool) {
+	if s.policy.InFlightThreshold <= 0 {
+		return false, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	was := s.active
+	if s.inFlight.Load() >= int64(s.policy.InFlightThreshold) {
+		if s.overloadedSince.IsZero() {
+			s.overloadedSince = now
+		}
+		s.active = now.Sub(s.overloadedSince) >= s.policy.Sustain
+	} else {
+		s.overloadedSince = time.Time{}
+		s.active = false
+	}
+	return s.active, s.active != was
+}
+
+// shouldShed reports whether eventName should be dropped right now
+func (s *shedder) shouldShed(eventName string) bool {
+	if !s.lowPriority[eventName] {
+		return false
+	}
+	s.mu.Lock()
+	active := s.active
+	s.mu.Unlock()
+	if !active {
+		return false
+	}
+	if s.keepEvery == 0 {
+		return true
+	}
+	return s.sampled.Add(1)%s.keepEvery != 0
+}
+
+// admit decides whether a freshly read event should be handled, recording shedding metrics and state changes
+func (c *Client) admit(eventName string) bool {
+	if active, changed := c.shedder.update(time.Now()); changed {
+		if active {
+			sheddingGauge.With(c.node()).Set(1)
+			c.log.WithField("inFlight", c.shedder.inFlight.Load()).Warn("Sustained overload, shedding low-priority events")
+		} else {
+			sheddingGauge.With(c.node()).Set(0)
+			c.log.Info("Overload cleared, load shedding stopped")
+		}
+	}
+	if c.shedder.shouldShed(eventName) {
+		shedCounter.With(c.node(), eventName).Inc()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,378 @@
+package esl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// spooledEvent is the on-disk representation of an ESL message
+type spooledEvent struct {
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body,omitempty"`
+	Applied []string          `json:"applied,omitempty"` // Writes that went through before it was spooled
+}
+
+// Spool is an append-only JSON-lines file of raw events held while the database is unavailable
+type Spool struct {
+	path string
+
+	mu    sync.Mutex
+	count int
+	calls map[string]int // Spooled events per Unique-ID, including those being replayed
+}
+
+// NewSpool opens (or creates) the spool file at path, counting any events left from a previous run
+func NewSpool(path string) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	s := &Spool{path: path, calls: make(map[string]int)}
+
+	// Recover events from a drain interrupted by a crash by putting them back in front of the spool
+	if err := s.recoverDraining(); err != nil {
+		return nil, err
+	}
+
+	return s, s.recount()
+}
+
+// recount counts the events in the spool file, in total and per call; s.mu must be held
+func (s *Spool) recount() error {
+	s.count = 0
+	s.calls = make(map[string]int)
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = readLines(f, func(line []byte) {
+		s.count++
+		var ev spooledEvent
+		if json.Unmarshal(line, &ev) == nil && ev.Headers["Unique-ID"] != "" {
+			s.calls[ev.Headers["Unique-ID"]]++
+		}
+	})
+	return err
+}
+
+// readLines passes each line of r to fn, however long, and returns the number of bytes consumed by the
+// lines passed
+func readLines(r io.Reader, fn func([]byte)) (int64, error) {
+	br := bufio.NewReader(r)
+	var offset int64
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				fn(line) // Unterminated last line
+				offset += int64(len(line))
+			}
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		fn(line[:len(line)-1])
+		offset += int64(len(line))
+	}
+}
+
+// recoverDraining merges a leftover ".draining" file back into the spool, preserving order
+func (s *Spool) recoverDraining() error {
+	drainPath := s.path + ".draining"
+	leftover, err := os.ReadFile(drainPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(s.path, append(leftover, current...), 0o600); err != nil {
+		return err
+	}
+	return os.Remove(drainPath)
+}
+
+// Append writes msg to the end of the spool, with the writes of it that were applied already (see
+// store.AppliedWrites) so its replay skips them
+func (s *Spool) Append(msg *goesl.Message, applied []string) error {
+	data, err := json.Marshal(spooledEvent{Headers: msg.Headers, Body: msg.Body, Applied: applied})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(msg, data)
+}
+
+// AppendIfHeld writes msg to the end of the spool when events of its call are still spooled, so it is
+// replayed after them rather than handled ahead of them, and reports whether it did
+func (s *Spool) AppendIfHeld(msg *goesl.Message) (bool, error) {
+	uuid := msg.GetHeader("Unique-ID")
+	if uuid == "" {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls[uuid] == 0 {
+		return false, nil
+	}
+	data, err := json.Marshal(spooledEvent{Headers: msg.Headers, Body: msg.Body})
+	if err != nil {
+		return false, err
+	}
+	return true, s.write(msg, data)
+}
+
+// write appends the encoded msg to the spool file; s.mu must be held
+func (s *Spool) write(msg *goesl.Message, data []byte) error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil { // Spooled events must survive a crash before the database is back
+		return err
+	}
+	s.count++
+	if uuid := msg.GetHeader("Unique-ID"); uuid != "" {
+		s.calls[uuid]++
+	}
+	return nil
+}
+
+// released forgets a replayed event of the call uuid
+func (s *Spool) released(uuid string) {
+	if uuid == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls[uuid]--; s.calls[uuid] <= 0 {
+		delete(s.calls, uuid)
+	}
+}
+
+// Len returns the number of spooled events
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Drain reads every spooled event in order, passes it to fn with its applied writes and empties the spool.
+// Events appended while draining are kept for the next drain. A call's events are held (see
+// AppendIfHeld) until its last spooled event has been passed to fn.
+func (s *Spool) Drain(fn func(*goesl.Message, []string)) (int, error) {
+	s.mu.Lock()
+	if s.count == 0 {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	// Move the current file aside so appends can continue while we replay
+	drainPath := s.path + ".draining"
+	if err := os.Rename(s.path, drainPath); err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	s.count = 0
+	s.mu.Unlock()
+
+	f, err := os.Open(drainPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	offset, err := readLines(f, func(line []byte) {
+		var ev spooledEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return // Skip a torn line from a crash mid-write
+		}
+		fn(&goesl.Message{Headers: ev.Headers, Body: ev.Body}, ev.Applied)
+		s.released(ev.Headers["Unique-ID"])
+		n++
+	})
+	if err != nil {
+		// Put the events not read back in front of the spool and count them again, so their calls'
+		// later events are held behind them rather than forever
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return n, errors.Join(err, s.requeue(f, offset), s.recount())
+	}
+	return n, os.Remove(drainPath)
+}
+
+// requeue moves what follows offset in the draining file f in front of the spool and removes f; s.mu must
+// be held
+func (s *Spool) requeue(f *os.File, offset int64) error {
+	leftover, err := io.ReadAll(io.NewSectionReader(f, offset, 1<<62))
+	if err != nil {
+		return err
+	}
+	if len(leftover) > 0 && leftover[len(leftover)-1] != '\n' {
+		leftover = append(leftover, '\n') // Keep a torn last line apart from the next event
+	}
+	current, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(s.path, append(leftover, current...), 0o600); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
+
+// spoolReplayInterval is how often the spool is checked for replay once the database is back
+const spoolReplayInterval = 2 * time.Second
+
+// persistRetryBackoff is the wait before handling an event again after a transient write rejection,
+// doubling with each attempt
+const persistRetryBackoff = 100 * time.Millisecond
+
+var (
+	spooledCounter = metrics.NewCounterVec("esl_events_spooled_total", "Events written to the disk spool while the database was unavailable.", "node")
+	droppedCounter = metrics.NewCounterVec("esl_events_dropped_total", "Tracked events dropped without being processed.", "node", "reason")
+	spoolDepth     = metrics.NewGaugeVec("esl_spool_depth", "Events waiting in the disk spool.", "node")
+
+	failedWritesCounter = metrics.NewCounterVec("esl_events_write_failed_total", "Events whose writes failed because the database was unreachable; they are spooled for replay.", "node")
+)
+
+// spoolEvent holds a tracked event on disk while the database is unavailable, with the writes of it that
+// were applied already. reason labels the drop when no spool is configured.
+func (c *Client) spoolEvent(msg *goesl.Message, applied []string, eventName, reason string) {
+	if !trackedEvents[eventName] {
+		return
+	}
+	if c.spool == nil {
+		droppedCounter.With(c.node(), reason).Inc()
+		return
+	}
+	if err := c.spool.Append(c.sealedMessage(msg), applied); err != nil {
+		c.log.WithError(err).WithField("eventName", eventName).Error("Failed to spool event")
+		droppedCounter.With(c.node(), "spool_error").Inc()
+		return
+	}
+	spooledCounter.With(c.node()).Inc()
+	spoolDepth.With(c.node()).Set(float64(c.spool.Len()))
+}
+
+// sealedMessage returns msg with its sensitive headers encrypted for the spool file, when field encryption
+// is on (see store.SealHeaders)
+func (c *Client) sealedMessage(msg *goesl.Message) *goesl.Message {
+	if !c.store.FieldsEncrypted() {
+		return msg
+	}
+	return &goesl.Message{Headers: c.store.SealHeaders(msg.Headers), Body: msg.Body}
+}
+
+// holdBehindSpool spools a tracked event whose call still has events waiting in the spool, so the call's
+// events are replayed in the order they were received, and reports whether it did
+func (c *Client) holdBehindSpool(msg *goesl.Message, eventName string) bool {
+	if c.spool == nil || !trackedEvents[eventName] {
+		return false
+	}
+	held, err := c.spool.AppendIfHeld(c.sealedMessage(msg))
+	if err != nil {
+		c.log.WithError(err).WithField("eventName", eventName).Error("Failed to spool event behind its call's spooled events; handling it now")
+		return false
+	}
+	if held {
+		spooledCounter.With(c.node()).Inc()
+		spoolDepth.With(c.node()).Set(float64(c.spool.Len()))
+	}
+	return held
+}
+
+// handleOrSpool handles msg, skipping the writes already applied on an earlier attempt, and when one of
+// its writes failed because the database was unreachable, spools it for replay. This covers the events in
+// flight when the database goes away, before the circuit breaker opens, and replayed events that fail
+// again. The spooled event carries the writes that went through, so its replay makes only the others
+// rather than inserting their rows twice. Events that cannot be handled for any other reason are set
+// aside in dead_letter_events.
+func (c *Client) handleOrSpool(ctx context.Context, msg *goesl.Message, applied []string) {
+	var stage string
+	var err error
+	attempts := 0
+	for backoff := persistRetryBackoff; ; backoff *= 2 {
+		attempts++
+		stage, applied, err = c.process(ctx, msg, applied)
+		if stage != store.DeadLetterPersist || !store.IsTransient(err) || attempts >= c.persistAttempts {
+			break // Only writes rejected for a passing reason, such as a deadlock, are worth another attempt
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+
+	eventName := msg.GetHeader("Event-Name")
+	switch {
+	case errors.Is(err, ErrDatabaseUnavailable):
+		c.log.WithFields(logrus.Fields{
+			"eventName": eventName,
+			"uuid":      msg.GetHeader("Unique-ID"),
+		}).Warn("Database unavailable while handling event; spooling it for replay")
+		failedWritesCounter.With(c.node()).Inc()
+		c.spoolEvent(msg, applied, eventName, "write_failed")
+	case err != nil:
+		c.deadLetterEvent(ctx, msg, applied, stage, err, attempts)
+	}
+}
+
+// spoolLoop replays spooled events, in order, once the database circuit breaker has closed
+func (c *Client) spoolLoop(ctx context.Context) {
+	if c.spool == nil {
+		return
+	}
+	ticker := time.NewTicker(spoolReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.store.CircuitOpen() || c.spool.Len() == 0 {
+				continue
+			}
+			c.log.WithField("events", c.spool.Len()).Info("Database available, replaying spooled events")
+			n, err := c.spool.Drain(func(msg *goesl.Message, applied []string) {
+				msg.Headers = c.store.OpenHeaders(msg.Headers)
+				c.handleOrSpool(ctx, msg, applied) // Spooled again if the database goes away during the replay
+			})
+			if err != nil {
+				c.log.WithError(err).Error("Error replaying spooled events")
+			}
+			spoolDepth.With(c.node()).Set(float64(c.spool.Len()))
+			c.log.WithField("events", n).Info("Finished replaying spooled events")
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package esl
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+)
+
+var stallsCounter = metrics.NewCounterVec("esl_stalls_total", "ESL connections recycled because no event arrived within the read timeout.", "node")
+
+// extendReadDeadline pushes the connection's read deadline readTimeout into the future. FreeSWITCH sends
+// HEARTBEAT every 20 seconds by default, so an open connection that stays silent for longer has stalled;
+// the deadline makes the pending read fail instead of blocking forever.
+func (c *Client) extendReadDeadline(conn *goesl.Client) {
+	if c.readTimeout <= 0 {
+		return
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+		c.log.WithError(err).Warn("Failed to set ESL read deadline")
+	}
+}
+
+// isStall reports whether a read error was caused by the read deadline expiring
+func isStall(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,159 @@
+package esl
+
+import (
+	"errors"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+)
+
+// Connection states reported by Status
+const (
+	StateDisconnected = "disconnected"
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateDraining     = "draining"
+	StateAuthFailed   = "auth_failed" // Every endpoint rejected the password; retries are suspended
+)
+
+// ConnState is a state of the Client's connection to FreeSWITCH. The transitions are:
+//
+//	Disconnected -> Connecting    a connection attempt starts
+//	Connecting   -> Connected     the attempt succeeds
+//	Connecting   -> Disconnected  the attempt fails
+//	Connected    -> Disconnected  the connection is lost (read error, stall or failback)
+//	any          -> Draining      Close is called; no further attempts are made
+//
+// The state and the connection it refers to change together under Client.connMu, so a goroutine that
+// obtained the connection through currentConn never sees it paired with another state.
+type ConnState int
+
+const (
+	ConnDisconnected ConnState = iota
+	ConnConnecting
+	ConnConnected
+	ConnDraining
+)
+
+// String returns the state name used in Status and the esl_connection_state metric
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnecting:
+		return StateConnecting
+	case ConnConnected:
+		return StateConnected
+	case ConnDraining:
+		return StateDraining
+	default:
+		return StateDisconnected
+	}
+}
+
+// drainTimeout bounds how long Close waits for in-flight event handlers
+const drainTimeout = 10 * time.Second
+
+var connStateGauge = metrics.NewGaugeVec("esl_connection_state", "Current ESL connection state (1 for the current state, 0 otherwise).", "node", "state")
+
+// ErrClientClosed is returned by connection attempts made after Close
+var ErrClientClosed = errors.New("ESL client closed")
+
+// State returns the current connection state
+func (c *Client) State() ConnState {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.connState
+}
+
+// setState records a transition. connMu must be held.
+func (c *Client) setState(s ConnState) {
+	if c.connState == s {
+		return
+	}
+	connStateGauge.With(c.node(), c.connState.String()).Set(0)
+	c.connState = s
+	connStateGauge.With(c.node(), s.String()).Set(1)
+}
+
+// currentConn returns the connection while connected and nil otherwise
+func (c *Client) currentConn() *goesl.Client {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.connState != ConnConnected {
+		return nil
+	}
+	return c.conn
+}
+
+// beginConnect moves the client to Connecting and detaches the current connection, if any, for the caller
+// to close. It fails with ErrClientClosed once the client is draining.
+func (c *Client) beginConnect() (*goesl.Client, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.connState == ConnDraining {
+		return nil, ErrClientClosed
+	}
+	old := c.conn
+	c.conn = nil
+	c.setState(ConnConnecting)
+	return old, nil
+}
+
+// finishConnect ends a connection attempt: Connected with conn on success, Disconnected when conn is nil.
+// If Close was called during the attempt, conn is closed and ErrClientClosed returned.
+func (c *Client) finishConnect(conn *goesl.Client) error {
+	c.connMu.Lock()
+	if c.connState == ConnDraining {
+		c.connMu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+		return ErrClientClosed
+	}
+	c.conn = conn
+	if conn == nil {
+		c.setState(ConnDisconnected)
+	} else {
+		c.setState(ConnConnected)
+	}
+	c.connMu.Unlock()
+	return nil
+}
+
+// dropConn marks conn as lost and closes it. It reports false, leaving the state alone, when conn has
+// already been replaced or the client is draining.
+func (c *Client) dropConn(conn *goesl.Client) bool {
+	c.connMu.Lock()
+	if c.connState != ConnConnected || c.conn != conn {
+		c.connMu.Unlock()
+		return false
+	}
+	c.conn = nil
+	c.setState(ConnDisconnected)
+	c.connMu.Unlock()
+	conn.Close()
+	return true
+}
+
+// drain moves the client to Draining and detaches the connection for the caller to close
+func (c *Client) drain() *goesl.Client {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	conn := c.conn
+	c.conn = nil
+	c.setState(ConnDraining)
+	return conn
+}
+
+// waitHandlers waits up to timeout for in-flight event handlers to finish and reports whether they did
+func (c *Client) waitHandlers(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for c.shedder.inFlight.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
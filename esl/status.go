@@ -0,0 +1,212 @@
+package esl
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+)
+
+// statusInterval is how often the events/sec rate and connection gauges are refreshed
+const statusInterval = 5 * time.Second
+
+var (
+	connectedGauge      = metrics.NewGaugeVec("esl_connected", "Whether the ESL connection is up (1) or down (0).", "node")
+	connectedSinceGauge = metrics.NewGaugeVec("esl_connected_since_seconds", "Unix time the current ESL connection was established.", "node")
+	reconnectsCounter   = metrics.NewCounterVec("esl_reconnects_total", "Successful ESL reconnections after the initial connection.", "node")
+	eventsCounter       = metrics.NewCounterVec("esl_events_total", "ESL messages read from the connection.", "node")
+	eventRateGauge      = metrics.NewGaugeVec("esl_events_per_second", "ESL messages per second over the last sampling interval.", "node")
+
+	// Pipeline observability: how far behind the switch we are, and how much work is queued at each stage
+	eventLagHistogram = metrics.NewHistogramVec("esl_event_lag_seconds", "Delay between Event-Date-Timestamp and the start of event handling.", metrics.DefaultLagBuckets, "node")
+	handleDuration    = metrics.NewHistogramVec("esl_event_handle_seconds", "Time spent handling a single event, including database writes.", metrics.DefaultLagBuckets, "node", "event")
+	queueDepthGauge   = metrics.NewGaugeVec("esl_pipeline_queue_depth", "Events waiting or in progress at each pipeline stage.", "node", "stage")
+)
+
+// Pipeline stages reported by esl_pipeline_queue_depth
+const (
+	stageHandling = "handling" // Events dispatched to a handler goroutine and not yet finished
+)
+
+// Status describes the health of the ESL connection
+type Status struct {
+	Node           string     `json:"node"`
+	Role           string     `json:"role"` // primary or backup
+	Connected      bool       `json:"connected"`
+	State          string     `json:"state"` // disconnected, connecting, connected, draining or auth_failed
+	ConnectedSince *time.Time `json:"connected_since,omitempty"`
+	ReconnectCount int64      `json:"reconnect_count"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	EventsTotal    uint64     `json:"events_total"`
+	EventsPerSec   float64    `json:"events_per_sec"`
+}
+
+// In converts the status timestamps to loc
+func (s *Status) In(loc *time.Location) {
+	if s.ConnectedSince != nil {
+		t := s.ConnectedSince.In(loc)
+		s.ConnectedSince = &t
+	}
+	if s.LastErrorAt != nil {
+		t := s.LastErrorAt.In(loc)
+		s.LastErrorAt = &t
+	}
+}
+
+// connStatus holds the mutable connection statistics behind Status
+type connStatus struct {
+	connectedSince time.Time // Zero while disconnected
+	reconnects     int64
+	lastError      string
+	lastErrorAt    time.Time
+	eventsPerSec   float64
+	events         atomic.Uint64
+	health         string         // Last state recorded in health_transitions
+	authFailures   map[string]int // Consecutive authentication failures per endpoint address
+	authFailed     bool           // Retries suspended; see AuthPolicy
+}
+
+// markConnected records a successful connection
+func (c *Client) markConnected() {
+	now := time.Now()
+	c.statusMu.Lock()
+	c.status.connectedSince = now
+	changed := c.setHealth(store.HealthUp)
+	c.statusMu.Unlock()
+
+	connectedGauge.With(c.node()).Set(1)
+	connectedSinceGauge.With(c.node()).Set(float64(now.Unix()))
+	if changed {
+		c.recordHealth(store.HealthUp, nil, now)
+	}
+}
+
+// markReconnected records a successful reconnection after a prior connection loss
+func (c *Client) markReconnected() {
+	c.statusMu.Lock()
+	c.status.reconnects++
+	c.statusMu.Unlock()
+
+	reconnectsCounter.With(c.node()).Inc()
+}
+
+// markError records a connection-level error and, if disconnected is true, marks the connection down
+func (c *Client) markError(err error, disconnected bool) {
+	now := time.Now()
+	changed := false
+	c.statusMu.Lock()
+	c.status.lastError = err.Error()
+	c.status.lastErrorAt = now
+	if disconnected {
+		c.status.connectedSince = time.Time{}
+		changed = c.setHealth(store.HealthDown)
+	}
+	c.statusMu.Unlock()
+
+	if disconnected {
+		connectedGauge.With(c.node()).Set(0)
+	}
+	if changed {
+		reason := err.Error()
+		c.recordHealth(store.HealthDown, &reason, now)
+	}
+}
+
+// setHealth updates the recorded health state and reports whether it changed. statusMu must be held.
+func (c *Client) setHealth(state string) bool {
+	if c.status.health == state {
+		return false
+	}
+	c.status.health = state
+	return true
+}
+
+// recordHealth persists an ESL health transition without blocking the connection loop
+func (c *Client) recordHealth(state string, reason *string, at time.Time) {
+	go c.store.RecordHealth(context.Background(), store.HealthTransition{
+		Component:  store.ComponentESL,
+		Instance:   c.node(),
+		State:      state,
+		Reason:     reason,
+		OccurredAt: at,
+	})
+}
+
+// countEvent records that a message was read from the connection
+func (c *Client) countEvent() {
+	c.status.events.Add(1)
+	eventsCounter.With(c.node()).Inc()
+}
+
+// observeLag records the delay between when FreeSWITCH generated msg and now
+func (c *Client) observeLag(msg *goesl.Message) {
+	ts, err := eventTime(msg)
+	if err != nil {
+		return
+	}
+	lag := time.Since(ts).Seconds()
+	if lag < 0 {
+		lag = 0 // Clock skew between the switch and this host
+	}
+	eventLagHistogram.With(c.node()).Observe(lag)
+}
+
+// Status returns a snapshot of the connection health
+func (c *Client) Status() Status {
+	state := c.State()
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	st := Status{
+		Node:           c.node(),
+		Role:           c.endpoint().role,
+		Connected:      state == ConnConnected,
+		State:          state.String(),
+		ReconnectCount: c.status.reconnects,
+		LastError:      c.status.lastError,
+		EventsTotal:    c.status.events.Load(),
+		EventsPerSec:   c.status.eventsPerSec,
+	}
+	switch {
+	case st.Connected:
+		if !c.status.connectedSince.IsZero() {
+			since := c.status.connectedSince
+			st.ConnectedSince = &since
+		}
+	case state != ConnDraining && c.status.authFailed:
+		st.State = StateAuthFailed
+	}
+	if !c.status.lastErrorAt.IsZero() {
+		at := c.status.lastErrorAt
+		st.LastErrorAt = &at
+	}
+	return st
+}
+
+// statusLoop periodically samples the event rate
+func (c *Client) statusLoop(ctx context.Context) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	last := c.status.events.Load()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := c.status.events.Load()
+			rate := float64(current-last) / statusInterval.Seconds()
+			last = current
+
+			c.statusMu.Lock()
+			c.status.eventsPerSec = rate
+			c.statusMu.Unlock()
+			eventRateGauge.With(c.node()).Set(rate)
+		}
+	}
+}
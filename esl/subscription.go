@@ -0,0 +1,156 @@
+package esl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllEvents subscribes to every event the switch emits
+const AllEvents = "ALL"
+
+// DefaultEvents are the events and CUSTOM subclasses this client handles, subscribed to when ESL_EVENTS is
+// unset. Entries containing "::" are CUSTOM subclasses.
+var DefaultEvents = []string{
+	"CHANNEL_CREATE",
+	"CHANNEL_HANGUP",
+	"CHANNEL_HANGUP_COMPLETE",
+	"CHANNEL_PROGRESS",
+	"CHANNEL_PROGRESS_MEDIA",
+	"CHANNEL_ANSWER",
+	"CHANNEL_BRIDGE",
+	"CHANNEL_UNBRIDGE",
+	"CHANNEL_STATE",
+	"CHANNEL_CALLSTATE",
+	"CHANNEL_HOLD",
+	"CHANNEL_UNHOLD",
+	"CHANNEL_UUID",
+	"CHANNEL_PARK",
+	"CHANNEL_UNPARK",
+	"CHANNEL_EXECUTE",
+	"CHANNEL_EXECUTE_COMPLETE",
+	"DTMF",
+	"RECORD_START",
+	"RECORD_STOP",
+	"MESSAGE",
+	"HEARTBEAT",
+	"sofia::register",
+	"sofia::unregister",
+	"sofia::expire",
+	"sofia::gateway_state",
+	"sofia::transferor",
+	"sofia::transferee",
+	"conference::maintenance",
+	"callcenter::info",
+	"vm::maintenance",
+	"verto::login",
+	"verto::client_connect",
+	"verto::client_disconnect",
+	"spandsp::txfaxresult",
+	"spandsp::rxfaxresult",
+	"valet_parking::info",
+}
+
+// requiredEvents are always subscribed and never filtered out: bgapi results and switch heartbeats drive
+// internal state
+var requiredEvents = []string{"BACKGROUND_JOB", "HEARTBEAT"}
+
+// Filter is a server-side ESL filter: once any is set, the switch only sends events whose Header equals
+// Value for at least one filter
+type Filter struct {
+	Header string
+	Value  string
+}
+
+// ParseFilters parses "Header Value" entries, e.g. "Event-Name CHANNEL_CREATE" or
+// "variable_domain_name example.com"
+func ParseFilters(entries []string) ([]Filter, error) {
+	filters := make([]Filter, 0, len(entries))
+	for _, e := range entries {
+		header, value, ok := strings.Cut(strings.TrimSpace(e), " ")
+		value = strings.TrimSpace(value)
+		if !ok || header == "" || value == "" {
+			return nil, fmt.Errorf("filter %q: expected \"Header Value\"", e)
+		}
+		filters = append(filters, Filter{Header: header, Value: value})
+	}
+	return filters, nil
+}
+
+// subscription is the set of events requested from the switch
+type subscription struct {
+	all        bool
+	events     []string // Event names, in the configured order
+	subclasses []string // CUSTOM subclasses
+	filters    []Filter // Server-side filters applied after subscribing
+}
+
+// newSubscription builds the subscription for the configured events, falling back to DefaultEvents. The
+// callback subclasses and requiredEvents are always included, and let through the filters when any are set.
+func newSubscription(events []string, filters []Filter, callbacks CallbackPolicy) subscription {
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	sub := subscription{filters: filters}
+	if len(filters) > 0 {
+		for _, e := range requiredEvents {
+			sub.filters = append(sub.filters, Filter{Header: "Event-Name", Value: e})
+		}
+	}
+	seen := make(map[string]bool)
+	add := func(e string) {
+		e = strings.TrimSpace(e)
+		if !strings.Contains(e, "::") {
+			e = strings.ToUpper(e) // Subclass names are case sensitive, event names are not
+		}
+		if e == "" || seen[e] {
+			return
+		}
+		seen[e] = true
+		switch {
+		case e == AllEvents:
+			sub.all = true
+		case strings.Contains(e, "::"):
+			sub.subclasses = append(sub.subclasses, e)
+		case e == "CUSTOM":
+			// Implied by the subclasses; a bare CUSTOM would subscribe to none of them
+		default:
+			sub.events = append(sub.events, e)
+		}
+	}
+	for _, e := range events {
+		add(e)
+	}
+	for _, e := range requiredEvents {
+		add(e)
+	}
+	add(callbacks.OfferSubclass)
+	add(callbacks.AcceptSubclass)
+	return sub
+}
+
+// complete reports whether every event of the switch is received, which Event-Sequence gap detection needs
+func (s subscription) complete() bool {
+	return s.all && len(s.filters) == 0
+}
+
+// filterCommands returns the "filter" commands for the subscription's filters
+func (s subscription) filterCommands() []string {
+	cmds := make([]string, len(s.filters))
+	for i, f := range s.filters {
+		cmds[i] = "filter " + f.Header + " " + f.Value
+	}
+	return cmds
+}
+
+// command returns the "event json" command for the subscription. Subclasses follow the CUSTOM keyword.
+func (s subscription) command() string {
+	if s.all {
+		return "event json " + AllEvents // Includes every CUSTOM subclass
+	}
+	parts := append([]string{"event", "json"}, s.events...)
+	if len(s.subclasses) > 0 {
+		parts = append(parts, "CUSTOM")
+		parts = append(parts, s.subclasses...)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,84 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Supervision modes. Eavesdrop listens only, whisper lets the supervisor speak to the monitored
+// channel without the other party hearing, and barge joins the supervisor to both parties.
+const (
+	SuperviseEavesdrop = "eavesdrop"
+	SuperviseWhisper   = "whisper"
+	SuperviseBarge     = "barge"
+)
+
+var (
+	callUUIDPattern  = regexp.MustCompile(`^[0-9A-Za-z-]{1,64}$`)
+	extensionPattern = regexp.MustCompile(`^[0-9A-Za-z_.+*#-]{1,64}$`)
+	domainPattern    = regexp.MustCompile(`^[0-9A-Za-z.-]{1,253}$`)
+)
+
+// SuperviseRequest rings a supervisor's extension and, once answered, connects it to an active call
+type SuperviseRequest struct {
+	CallUUID   string // Channel to monitor
+	Extension  string // Supervisor extension, dialled as user/<extension>[@<domain>]
+	Domain     string // Optional directory domain of the extension
+	Mode       string // SuperviseEavesdrop, SuperviseWhisper or SuperviseBarge
+	CallerName string // Shown on the supervisor's phone; defaults to "Supervise <mode>"
+}
+
+// originate converts the request into the originate that places the supervisor leg
+func (r SuperviseRequest) originate() (OriginateRequest, error) {
+	if !callUUIDPattern.MatchString(r.CallUUID) {
+		return OriginateRequest{}, fmt.Errorf("%w: call uuid", ErrInvalidOriginate)
+	}
+	if !extensionPattern.MatchString(r.Extension) {
+		return OriginateRequest{}, fmt.Errorf("%w: extension", ErrInvalidOriginate)
+	}
+	endpoint := "user/" + r.Extension
+	if r.Domain != "" {
+		if !domainPattern.MatchString(r.Domain) {
+			return OriginateRequest{}, fmt.Errorf("%w: domain", ErrInvalidOriginate)
+		}
+		endpoint += "@" + r.Domain
+	}
+
+	req := OriginateRequest{
+		Endpoint:     endpoint,
+		CallerIDName: r.CallerName,
+		Variables:    map[string]string{"supervise_mode": r.Mode, "supervise_target_uuid": r.CallUUID},
+	}
+	if req.CallerIDName == "" {
+		req.CallerIDName = "Supervise " + r.Mode
+	}
+	switch r.Mode {
+	case SuperviseEavesdrop:
+		req.Destination = fmt.Sprintf("&eavesdrop(%s)", r.CallUUID)
+	case SuperviseWhisper:
+		req.Destination = fmt.Sprintf("&eavesdrop(%s)", r.CallUUID)
+		req.Variables["eavesdrop_whisper_aleg"] = "true"
+	case SuperviseBarge:
+		req.Destination = fmt.Sprintf("&three_way(%s)", r.CallUUID)
+	default:
+		return OriginateRequest{}, fmt.Errorf("%w: mode %q", ErrInvalidOriginate, r.Mode)
+	}
+	return req, req.Validate()
+}
+
+// Validate reports whether the request can be turned into a supervisor originate
+func (r SuperviseRequest) Validate() error {
+	_, err := r.originate()
+	return err
+}
+
+// Supervise rings the supervisor and waits until they answer (and are connected to the call) or the
+// originate fails. The result's CallUUID is the supervisor leg.
+func (c *Client) Supervise(ctx context.Context, req SuperviseRequest) (OriginateResult, error) {
+	originate, err := req.originate()
+	if err != nil {
+		return OriginateResult{}, err
+	}
+	return c.Originate(ctx, originate)
+}
@@ -0,0 +1,73 @@
+package esl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleTransferor records a SIP transfer from sofia::transferor, fired on the channel that sent the
+// REFER. The att_xfer_* headers are only present for attended transfers.
+func (c *Client) handleTransferor(ctx context.Context, msg *goesl.Message, uuid string) {
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	t := &store.Transfer{
+		TransferorUUID: uuid,
+		TransfereeUUID: optionalHeader(msg, "att_xfer_original_peer_uuid"),
+		TargetUUID:     optionalHeader(msg, "att_xfer_destination_peer_uuid"),
+		Kind:           store.TransferBlind,
+		OccurredAt:     at,
+	}
+	if msg.GetHeader("att_xfer_replaced_call_id") != "" {
+		t.Kind = store.TransferAttended
+	}
+	if t.TransfereeUUID == nil {
+		// Blind transfer: the transferee is the leg bridged to the transferor
+		t.TransfereeUUID = optionalHeader(msg, "Other-Leg-Unique-ID")
+		if t.TransfereeUUID == nil {
+			t.TransfereeUUID = optionalHeader(msg, "variable_signal_bond")
+		}
+	}
+	if dest := referToUser(msg.GetHeader("variable_sip_refer_to")); dest != "" {
+		t.Destination = &dest
+	}
+
+	if err := c.store.AddTransfer(ctx, t); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid": uuid,
+			"kind": t.Kind,
+		}).Error("Failed to record transfer")
+	}
+}
+
+// handleTransferee records the new destination of a transferred call from sofia::transferee, fired on
+// the channel being moved
+func (c *Client) handleTransferee(ctx context.Context, msg *goesl.Message, uuid string) {
+	dest := referToUser(msg.GetHeader("variable_sip_refer_to"))
+	if dest == "" {
+		dest = msg.GetHeader("Caller-Destination-Number")
+	}
+	if dest == "" {
+		return
+	}
+	if err := c.store.SetCallTransferred(ctx, uuid, dest); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record transfer destination")
+	}
+}
+
+// referToUser extracts the user part of a Refer-To value such as "<sip:1002@pbx.example.com?Replaces=...>"
+func referToUser(referTo string) string {
+	v := strings.Trim(strings.TrimSpace(referTo), "<>")
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "sips:"), "sip:")
+	if i := strings.IndexAny(v, "@;?>"); i >= 0 {
+		v = v[:i]
+	}
+	return v
+}
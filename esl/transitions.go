@@ -0,0 +1,44 @@
+package esl
+
+import (
+	"context"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleStateTransition records CHANNEL_STATE and CHANNEL_CALLSTATE transitions
+func (c *Client) handleStateTransition(ctx context.Context, msg *goesl.Message, uuid string) {
+	eventName := msg.GetHeader("Event-Name")
+	ts, err := eventTime(msg)
+	if err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Error("Failed to read timestamp for state transition")
+		return
+	}
+
+	t := &store.Transition{UUID: uuid, EventTime: ts}
+	if eventName == "CHANNEL_CALLSTATE" {
+		t.Kind = store.TransitionCallState
+		t.ToState = msg.GetHeader("Channel-Call-State")
+		t.FromState = optionalHeader(msg, "Original-Channel-Call-State")
+	} else {
+		t.Kind = store.TransitionState
+		t.ToState = msg.GetHeader("Channel-State")
+	}
+	if t.ToState == "" {
+		c.log.WithFields(logrus.Fields{
+			"uuid":      uuid,
+			"eventName": eventName,
+		}).Warn("State transition event has no target state, skipping")
+		return
+	}
+
+	if err := c.store.AddTransition(ctx, t); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record state transition")
+	}
+}
@@ -0,0 +1,116 @@
+package esl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+var vertoLoginsCounter = metrics.NewCounterVec("verto_logins_total", "mod_verto login attempts by result (success or failure).", "node", "result")
+
+// handleVertoLogin counts a verto::login attempt
+func (c *Client) handleVertoLogin(msg *goesl.Message) {
+	result := "failure"
+	if msg.GetHeader("verto_success") == "1" || strings.EqualFold(msg.GetHeader("verto_success"), "true") {
+		result = "success"
+	}
+	vertoLoginsCounter.With(c.node(), result).Inc()
+	if result == "failure" {
+		c.log.WithFields(logrus.Fields{
+			"login":  msg.GetHeader("verto_login"),
+			"reason": msg.GetHeader("verto_result_txt"),
+		}).Warn("Verto login failed")
+	}
+}
+
+// handleVertoConnect opens a verto session reported by verto::client_connect
+func (c *Client) handleVertoConnect(ctx context.Context, msg *goesl.Message) {
+	login := msg.GetHeader("verto_login")
+	if login == "" {
+		c.log.Warn("verto::client_connect without verto_login, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	node := c.node()
+	v := &store.VertoSession{
+		SessionID:     optionalHeader(msg, "verto_sessid"),
+		Login:         login,
+		ClientAddress: optionalHeader(msg, "verto_client_address"),
+		UserAgent:     vertoUserAgent(msg, "verto_user_agent", "verto_userAgent"),
+		Node:          &node,
+		ConnectedAt:   at,
+	}
+	if err := c.store.OpenVertoSession(ctx, v); err != nil {
+		c.log.WithError(err).WithField("login", login).Error("Failed to record verto session")
+	}
+}
+
+// handleVertoDisconnect closes the verto session reported by verto::client_disconnect
+func (c *Client) handleVertoDisconnect(ctx context.Context, msg *goesl.Message) {
+	login := msg.GetHeader("verto_login")
+	if login == "" {
+		c.log.Warn("verto::client_disconnect without verto_login, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	if err := c.store.CloseVertoSession(ctx, login, optionalHeader(msg, "verto_client_address"), at); err != nil {
+		c.log.WithError(err).WithField("login", login).Error("Failed to close verto session")
+	}
+}
+
+// isVertoChannel reports whether msg describes a channel created by mod_verto
+func isVertoChannel(msg *goesl.Message) bool {
+	return strings.HasPrefix(msg.GetHeader("Channel-Name"), "verto.rtc/") || msg.GetHeader("variable_verto_user") != ""
+}
+
+// vertoUserAgent returns the first non-empty user agent header of msg, or nil
+func vertoUserAgent(msg *goesl.Message, headers ...string) *string {
+	if ua := firstHeader(msg, headers...); ua != "" {
+		return &ua
+	}
+	return nil
+}
+
+// stampVertoIdentity fills the user and domain of a verto call from the logged-in verto user, since these
+// channels carry no SIP or directory variables
+func stampVertoIdentity(msg *goesl.Message, call *store.Call) {
+	if !isVertoChannel(msg) {
+		return
+	}
+	user, host, _ := strings.Cut(msg.GetHeader("variable_verto_user"), "@")
+	if call.UserID == nil && user != "" {
+		call.UserID = &user
+	}
+	if call.Domain == nil {
+		if h := firstHeader(msg, "variable_verto_host"); h != "" {
+			call.Domain = &h
+		} else if host != "" {
+			call.Domain = &host
+		}
+	}
+}
+
+// recordVerto stores the verto session id, client address and user agent of a verto call
+func (c *Client) recordVerto(ctx context.Context, msg *goesl.Message, uuid string) {
+	if !isVertoChannel(msg) {
+		return
+	}
+	sessionID := optionalHeader(msg, "variable_jsock_uuid_str")
+	clientAddress := optionalHeader(msg, "variable_verto_client_address")
+	userAgent := vertoUserAgent(msg, "variable_verto_user_agent", "variable_verto_dvar_userAgent")
+	if err := c.store.SetCallVerto(ctx, uuid, sessionID, clientAddress, userAgent, msg.GetHeader("variable_verto_user")); err != nil {
+		c.log.WithError(err).WithField("uuid", uuid).Error("Failed to record verto call metadata")
+	}
+}
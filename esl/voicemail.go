@@ -0,0 +1,60 @@
+package esl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/0x19/goesl"
+	"github.com/sirupsen/logrus"
+)
+
+// handleVoicemailEvent records a mod_voicemail vm::maintenance event for its mailbox
+func (c *Client) handleVoicemailEvent(ctx context.Context, msg *goesl.Message) {
+	action := msg.GetHeader("VM-Action")
+	mailbox := msg.GetHeader("VM-User")
+	if action == "" || mailbox == "" {
+		c.log.WithField("action", action).Debug("vm::maintenance without VM-Action or VM-User, skipping")
+		return
+	}
+	at, err := eventTime(msg)
+	if err != nil {
+		at = time.Now().UTC()
+	}
+
+	e := &store.VoicemailEvent{
+		Mailbox:      mailbox,
+		Domain:       msg.GetHeader("VM-Domain"),
+		Action:       action,
+		MessageUUID:  optionalHeader(msg, "VM-UUID"),
+		CallUUID:     optionalHeader(msg, "Unique-ID"),
+		CallerNumber: optionalHeader(msg, "VM-Caller-ID-Number"),
+		CallerName:   optionalHeader(msg, "VM-Caller-ID-Name"),
+		DurationSec:  intHeader(msg, "VM-Message-Len"),
+		NewMessages:  intHeader(msg, "VM-Total-New"),
+		SavedMsgs:    intHeader(msg, "VM-Total-Saved"),
+		EventTime:    at,
+	}
+	if action == "leave-message" {
+		kind := store.VoicemailLeft
+		e.Kind = &kind
+	}
+
+	if err := c.store.AddVoicemailEvent(ctx, e); err != nil {
+		c.log.WithError(err).WithFields(logrus.Fields{
+			"action":  action,
+			"mailbox": mailbox,
+		}).Error("Failed to record voicemail event")
+	}
+}
+
+// intHeader parses an integer header, returning nil if it is absent or malformed
+func intHeader(msg *goesl.Message, name string) *int {
+	v, err := strconv.Atoi(msg.GetHeader(name))
+	if err != nil {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,143 @@
+package esl
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync/atomic"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/0x19/goesl"
+)
+
+var (
+	queueFullCounter   = metrics.NewCounterVec("esl_event_queue_full_total", "Events that found the handler queue full, by action (deferred: the read loop waited for space; dropped).", "node", "event", "action")
+	queueCapacityGauge = metrics.NewGaugeVec("esl_event_queue_capacity", "Capacity of the event handler queue.", "node")
+)
+
+// stageQueued is the esl_pipeline_queue_depth stage of events waiting for a worker
+const stageQueued = "queued"
+
+// QueueFullPolicy decides what happens to an event that finds the handler queue full
+type QueueFullPolicy string
+
+const (
+	QueueFullWait QueueFullPolicy = "wait" // The read loop waits for space, applying backpressure to the socket
+	QueueFullDrop QueueFullPolicy = "drop" // Events are dropped, except critical ones, which still wait
+)
+
+// ParseQueueFullPolicy parses an EVENT_QUEUE_FULL value; empty means wait
+func ParseQueueFullPolicy(s string) (QueueFullPolicy, error) {
+	switch p := QueueFullPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return QueueFullWait, nil
+	case QueueFullWait, QueueFullDrop:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown queue full policy %q (expected wait or drop)", s)
+	}
+}
+
+// workerPool is a fixed set of handler goroutines, each fed from its own bounded queue. Events are
+// sharded by Unique-ID so the events of one channel are handled one at a time and in the order they
+// were read, while different channels are handled in parallel. Events without a Unique-ID are spread
+// round-robin.
+type workerPool struct {
+	shards []chan *goesl.Message
+	full   QueueFullPolicy
+	next   atomic.Uint64 // Round-robin position for events without a Unique-ID
+}
+
+// newWorkerPool builds the pool described by l, or returns nil when l.Workers is 0 and every event gets
+// its own goroutine. The queue size is split evenly between the workers.
+func newWorkerPool(l Limits) *workerPool {
+	if l.Workers <= 0 {
+		return nil
+	}
+	p := &workerPool{shards: make([]chan *goesl.Message, l.Workers), full: l.QueueFull}
+	perShard := max(l.QueueSize/l.Workers, 1)
+	for i := range p.shards {
+		p.shards[i] = make(chan *goesl.Message, perShard)
+	}
+	return p
+}
+
+// shard returns the queue for msg
+func (p *workerPool) shard(msg *goesl.Message) chan *goesl.Message {
+	uuid := msg.GetHeader("Unique-ID")
+	if uuid == "" {
+		return p.shards[p.next.Add(1)%uint64(len(p.shards))]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(uuid))
+	return p.shards[h.Sum32()%uint32(len(p.shards))]
+}
+
+// startWorkers starts one worker per shard; they stop when ctx is done
+func (c *Client) startWorkers(ctx context.Context) {
+	if c.pool == nil {
+		return
+	}
+	queueCapacityGauge.With(c.node()).Set(float64(len(c.pool.shards) * cap(c.pool.shards[0])))
+	for _, queue := range c.pool.shards {
+		go c.worker(ctx, queue)
+	}
+}
+
+// worker handles the events of one shard in order until ctx is done, then discards what is left
+func (c *Client) worker(ctx context.Context, queue chan *goesl.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case <-queue:
+					queueDepthGauge.With(c.node(), stageQueued).Add(-1)
+					c.shedder.end()
+				default:
+					return
+				}
+			}
+		case msg := <-queue:
+			queueDepthGauge.With(c.node(), stageQueued).Add(-1)
+			c.handleDispatched(ctx, msg, msg.GetHeader("Event-Name"), false)
+		}
+	}
+}
+
+// enqueue hands msg to its shard's worker. When the shard's queue is full the event is dropped or the caller waits,
+// depending on the pool's QueueFullPolicy; critical events always wait.
+func (c *Client) enqueue(ctx context.Context, msg *goesl.Message, eventName string) {
+	// Counted before the send so a worker taking the event at once never sees the counts go negative
+	c.shedder.begin()
+	queueDepthGauge.With(c.node(), stageQueued).Add(1)
+	if !c.offer(ctx, msg, eventName) {
+		queueDepthGauge.With(c.node(), stageQueued).Add(-1)
+		c.shedder.end()
+		return
+	}
+	inFlightGauge.With(c.node()).Set(float64(c.shedder.inFlight.Load()))
+}
+
+// offer puts msg on its shard's queue and reports whether it was queued
+func (c *Client) offer(ctx context.Context, msg *goesl.Message, eventName string) bool {
+	queue := c.pool.shard(msg)
+	select {
+	case queue <- msg:
+		return true
+	default:
+	}
+	if c.pool.full == QueueFullDrop && !criticalEvents[eventName] {
+		queueFullCounter.With(c.node(), eventName, "dropped").Inc()
+		return false
+	}
+	queueFullCounter.With(c.node(), eventName, "deferred").Inc()
+	select {
+	case queue <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,87 @@
+// Package features gates optional subsystems behind named flags so they can ship disabled and be turned on
+// per deployment. A flag's state comes from its default, then from the build's feature list
+// (buildinfo.Features), then from the FEATURE_FLAGS setting, each overriding the last.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Known flags
+const (
+	Webhooks = "webhooks" // Alert and missed-call webhook delivery, and the outcome hook
+	Rating   = "rating"   // Call costing from RATE_PER_MINUTE
+
+	Applications = "applications" // Dialplan application trace from CHANNEL_EXECUTE
+	RawEvents    = "raw_events"   // Verbatim event capture for the raw retention tier
+)
+
+// Flag describes a known feature flag
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Known lists every flag the application understands
+var Known = []Flag{
+	{Name: Webhooks, Description: "Deliver alerts and missed-call notifications to the configured webhooks and call the outcome hook", Default: true},
+	{Name: Rating, Description: "Rate call costs from RATE_PER_MINUTE and BILLING_INCREMENT", Default: true},
+	{Name: Applications, Description: "Record the dialplan applications each call runs in call_applications", Default: false},
+	{Name: RawEvents, Description: "Store every handled ESL event verbatim in raw_events", Default: false},
+}
+
+// Set is the resolved state of every known flag
+type Set struct {
+	enabled map[string]bool
+}
+
+// New resolves the flags. build names flags enabled at build time; entries that are not flag names (build
+// tags, for instance) are ignored. overrides are "name" to enable or "-name" to disable, and must name known flags.
+func New(build, overrides []string) (*Set, error) {
+	s := &Set{enabled: make(map[string]bool, len(Known))}
+	for _, f := range Known {
+		s.enabled[f.Name] = f.Default
+	}
+	for _, name := range build {
+		if _, ok := s.enabled[name]; ok {
+			s.enabled[name] = true
+		}
+	}
+	for _, o := range overrides {
+		name, on := strings.TrimPrefix(o, "-"), !strings.HasPrefix(o, "-")
+		if _, ok := s.enabled[name]; !ok {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		s.enabled[name] = on
+	}
+	return s, nil
+}
+
+// Enabled reports whether the named flag is on. A nil Set has every flag off.
+func (s *Set) Enabled(name string) bool {
+	return s != nil && s.enabled[name]
+}
+
+// Map returns the state of every known flag
+func (s *Set) Map() map[string]bool {
+	m := make(map[string]bool, len(Known))
+	for _, f := range Known {
+		m[f.Name] = s.Enabled(f.Name)
+	}
+	return m
+}
+
+// String lists the enabled flags, sorted and comma-separated
+func (s *Set) String() string {
+	var on []string
+	for name, enabled := range s.Map() {
+		if enabled {
+			on = append(on, name)
+		}
+	}
+	sort.Strings(on)
+	return strings.Join(on, ",")
+}
@@ -0,0 +1,122 @@
+package forecast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Forecasting methods
+const (
+	MethodLinear   = "linear"   // Least-squares trend through the history
+	MethodSeasonal = "seasonal" // Average of the same week in previous years
+)
+
+// SeasonLength is the number of weekly periods in a year for the seasonal method
+const SeasonLength = 52
+
+// ErrInsufficientHistory is returned when the history is too short for the method
+var ErrInsufficientHistory = errors.New("insufficient history")
+
+// bandZ scales the residual standard deviation into a one-sided 95% upper band
+const bandZ = 1.645
+
+// Projection is the forecast for one future period
+type Projection struct {
+	Peak  float64 `json:"peak"`  // Expected peak
+	Upper float64 `json:"upper"` // Peak plus a 95% band from the history's deviation around the model
+}
+
+// Trend is a least-squares line fitted to a series indexed 0, 1, 2, ...
+type Trend struct {
+	Intercept float64 `json:"intercept"`
+	Slope     float64 `json:"slope"`    // Change per period
+	Residual  float64 `json:"residual"` // Standard deviation of the series around the line
+}
+
+// At returns the value of the trend line at period i
+func (t Trend) At(i float64) float64 {
+	return t.Intercept + t.Slope*i
+}
+
+// Fit fits a least-squares line to series, which needs at least two values
+func Fit(series []float64) (Trend, error) {
+	n := float64(len(series))
+	if len(series) < 2 {
+		return Trend{}, fmt.Errorf("%w: at least 2 periods are required", ErrInsufficientHistory)
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range series {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	t := Trend{}
+	t.Slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	t.Intercept = (sumY - t.Slope*sumX) / n
+
+	var ss float64
+	for i, y := range series {
+		d := y - t.At(float64(i))
+		ss += d * d
+	}
+	if len(series) > 2 {
+		t.Residual = math.Sqrt(ss / (n - 2))
+	}
+	return t, nil
+}
+
+// Project forecasts the next periods values of series with method. Negative projections are clamped to zero.
+func Project(series []float64, periods int, method string) ([]Projection, error) {
+	switch method {
+	case MethodLinear, "":
+		t, err := Fit(series)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]Projection, periods)
+		for i := range out {
+			peak := math.Max(t.At(float64(len(series)+i)), 0)
+			out[i] = Projection{Peak: peak, Upper: peak + bandZ*t.Residual}
+		}
+		return out, nil
+	case MethodSeasonal:
+		return seasonal(series, periods)
+	default:
+		return nil, fmt.Errorf("unknown forecasting method %q", method)
+	}
+}
+
+// seasonal projects each future period as the mean of the same period in earlier seasons, with a band from
+// the spread of those values
+func seasonal(series []float64, periods int) ([]Projection, error) {
+	if len(series) < SeasonLength {
+		return nil, fmt.Errorf("%w: at least %d periods are required", ErrInsufficientHistory, SeasonLength)
+	}
+	out := make([]Projection, periods)
+	for i := range out {
+		var values []float64
+		for j := len(series) + i - SeasonLength; j >= 0; j -= SeasonLength {
+			if j < len(series) {
+				values = append(values, series[j])
+			}
+		}
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		mean := sum / float64(len(values))
+		var ss float64
+		for _, v := range values {
+			ss += (v - mean) * (v - mean)
+		}
+		var spread float64
+		if len(values) > 1 {
+			spread = math.Sqrt(ss / float64(len(values)-1))
+		}
+		out[i] = Projection{Peak: mean, Upper: mean + bandZ*spread}
+	}
+	return out, nil
+}
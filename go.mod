@@ -4,6 +4,7 @@ go 1.24.2
 
 require (
 	github.com/0x19/goesl v0.0.0-20230805100056-48992ef4fdb1
+	github.com/expr-lang/expr v1.17.8
 	github.com/gin-gonic/gin v1.10.1
 	github.com/jackc/pgx/v5 v5.7.5
 	github.com/joho/godotenv v1.5.1
@@ -9,9 +9,22 @@ import (
 	"syscall"
 	"time"
 
+	"gofreeswitchesl/alert"
 	"gofreeswitchesl/api"
+	"gofreeswitchesl/buildinfo"
+	"gofreeswitchesl/calendar"
+	"gofreeswitchesl/campaign"
 	"gofreeswitchesl/config"
+	"gofreeswitchesl/diagnostics"
+	"gofreeswitchesl/directory"
 	"gofreeswitchesl/esl"
+	"gofreeswitchesl/features"
+	"gofreeswitchesl/monitor"
+	"gofreeswitchesl/outcome"
+	"gofreeswitchesl/prefixes"
+	"gofreeswitchesl/rating"
+	"gofreeswitchesl/retention"
+	"gofreeswitchesl/rules"
 	"gofreeswitchesl/store"
 	"gofreeswitchesl/utils"
 
@@ -22,13 +35,23 @@ import (
 func main() {
 	// Initialize logger
 	logger := utils.NewLogger()
-	logger.Info("Application starting...")
+	if runSubcommand(logger) {
+		return
+	}
+	logger.WithField("build", buildinfo.Get().String()).Info("Application starting...")
 
 	// Load configuration
 	cfg := config.LoadConfig()
+	recentErrors := diagnostics.NewErrorLog(cfg.DiagErrorHistory)
+	logger.AddHook(recentErrors)
+	flags, err := loadFeatures(cfg)
+	if err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
 	logger.WithFields(logrus.Fields{
 		"esl_addr": cfg.ESLAddr,
 		"api_port": cfg.APIPort,
+		"features": flags.String(),
 		// Avoid logging sensitive info like passwords or full DSNs in production
 	}).Info("Configuration loaded")
 
@@ -41,31 +64,331 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Initialize Database Connection
-	dbPool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if len(cfg.APITenantKeys) > 0 && !cfg.DBRowLevelSecurity {
+		logger.Fatal("API_TENANT_KEYS requires DB_ROW_LEVEL_SECURITY=true")
+	}
+	if cfg.DatabaseURL, err = loadDatabaseURL(ctx, cfg); err != nil {
+		logger.Fatalf("Invalid database configuration: %v", err)
+	}
+	poolConfig, err := newPoolConfig(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Invalid database configuration: %v", err)
+	}
+	dbPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		logger.Fatalf("Unable to connect to database: %v\n", err)
 	}
-	defer dbPool.Close()
 
 	logger.Info("Successfully connected to PostgreSQL database.")
 
 	// Initialize Store
-	appStore := store.NewStore(dbPool, logger)
+	breaker := store.NewBreaker(cfg.DBBreakerThreshold, time.Duration(cfg.DBBreakerCooldown)*time.Second)
+	appStore := store.NewStore(dbPool, cfg.StoreMaxInFlight, breaker, logger)
+	defer appStore.Close() // Closes the pool in use, which differs from dbPool after credential rotation
+	go watchDatabaseURL(ctx, cfg, appStore, logger)
+	go appStore.RunHealthProbe(ctx, time.Second)
+	customColumns, err := store.ParseCustomColumns(cfg.CustomColumns)
+	if err != nil {
+		logger.Fatalf("Invalid CUSTOM_COLUMNS: %v", err)
+	}
+	appStore.SetCustomColumns(customColumns)
+	appStore.SetRowLevelSecurity(cfg.DBRowLevelSecurity)
+	appStore.SetLegacyTimeZone(cfg.DBLegacyTimeZone)
+	appStore.SetReadLimits(store.ReadLimits{MaxScanRows: int64(cfg.APIMaxScanRows), MaxRows: cfg.APIMaxRows})
+	fieldCipher, err := loadFieldCipher(ctx, cfg)
+	if err != nil {
+		logger.Fatalf("Invalid field encryption configuration: %v", err)
+	}
+	appStore.SetFieldCipher(fieldCipher)
 
 	// Initialize database schema (idempotent)
 	if err := appStore.InitSchema(ctx); err != nil {
 		logger.Fatalf("Failed to initialize database schema: %v", err)
 	}
+	if len(cfg.APITenantKeys) > 0 {
+		if err := appStore.CheckRowLevelSecurity(ctx); err != nil {
+			logger.WithError(err).Fatal("API_TENANT_KEYS cannot be isolated; connect as a role without SUPERUSER or BYPASSRLS")
+		}
+	}
+
+	// Optionally batch ingest writes through an async writer
+	var storeWriter *store.Writer
+	if cfg.AsyncWrites {
+		storeWriter = store.NewWriter(appStore, cfg.WriteQueueSize, cfg.WriteBatchSize,
+			time.Duration(cfg.WriteFlushInterval)*time.Millisecond)
+		go storeWriter.Run()
+		logger.Info("Asynchronous store writer enabled")
+	}
 
 	// Initialize ESL Client
-	eslClient := esl.NewClient(cfg.ESLAddr, cfg.ESLPass, appStore, logger)
+	eslFilters, err := esl.ParseFilters(cfg.ESLFilters)
+	if err != nil {
+		logger.Fatalf("Invalid ESL_FILTERS: %v", err)
+	}
+	eslMode, err := esl.ParseMode(cfg.ESLMode)
+	if err != nil {
+		logger.Fatalf("Invalid ESL_MODE: %v", err)
+	}
+	if cfg.ESLPassFile != "" {
+		if cfg.ESLPass, err = esl.ReadPasswordFile(cfg.ESLPassFile); err != nil {
+			logger.Fatalf("Failed to read ESL_PASS_FILE: %v", err)
+		}
+	}
+	eslServers := []esl.Server{{Addr: cfg.ESLAddr, Pass: cfg.ESLPass}}
+	if len(cfg.ESLServers) > 0 {
+		if eslServers, err = esl.ParseServers(cfg.ESLServers, cfg.ESLPass); err != nil {
+			logger.Fatalf("Invalid ESL_SERVERS: %v", err)
+		}
+		if eslMode == esl.ModeOutbound {
+			logger.Fatal("ESL_SERVERS requires ESL_MODE=inbound or both")
+		}
+	}
+	var rater *rating.Rater // Nil rates nothing
+	if flags.Enabled(features.Rating) {
+		rater = rating.NewRater(cfg.RatePerMinute, cfg.BillingIncrement)
+	}
+	dir, err := directory.New(cfg.DirectorySource, cfg.DirectoryCSVPath, cfg.DirectoryHTTPURL, directory.LDAPConfig{
+		URL:           cfg.DirectoryLDAPURL,
+		BindDN:        cfg.DirectoryLDAPBindDN,
+		BindPassword:  cfg.DirectoryLDAPBindPassword,
+		BaseDN:        cfg.DirectoryLDAPBaseDN,
+		Attribute:     cfg.DirectoryLDAPAttribute,
+		NameAttribute: cfg.DirectoryLDAPNameAttribute,
+	}, time.Duration(cfg.DirectoryCacheTTL)*time.Second, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize extension directory: %v", err)
+	}
+	var prefixTable *prefixes.Table
+	if cfg.PrefixTablePath != "" {
+		prefixTable, err = prefixes.NewCSVTable(cfg.PrefixTablePath)
+		if err != nil {
+			logger.Fatalf("Failed to load destination prefix table: %v", err)
+		}
+	}
+	var ruleEngine *rules.Engine
+	if cfg.RulesPath != "" {
+		ruleEngine, err = rules.Load(cfg.RulesPath)
+		if err != nil {
+			logger.Fatalf("Failed to load derived-field rules: %v", err)
+		}
+	}
+	var calendars *calendar.Calendars
+	if cfg.CalendarsPath != "" {
+		calendars, err = calendar.Load(cfg.CalendarsPath)
+		if err != nil {
+			logger.Fatalf("Failed to load business-hours calendars: %v", err)
+		}
+	}
+	var outcomeRules *outcome.Rules
+	if cfg.OutcomeRulesPath != "" {
+		outcomeRules, err = outcome.LoadRules(cfg.OutcomeRulesPath)
+		if err != nil {
+			logger.Fatalf("Failed to load outcome rules: %v", err)
+		}
+	}
+	// With webhooks off, notifiers only log and the outcome hook is not called
+	alertWebhook, alertSlack, missedCallWebhook := cfg.AlertWebhookURL, cfg.AlertSlackWebhookURL, cfg.MissedCallWebhookURL
+	outcomeHook := cfg.OutcomeHookURL
+	if !flags.Enabled(features.Webhooks) {
+		alertWebhook, alertSlack, missedCallWebhook, outcomeHook = "", "", "", ""
+	}
+	notifier := alert.NewNotifier(alertWebhook, alertSlack, logger)
+	gateways := esl.NewGatewayTracker(cfg.GatewayLimits, cfg.GatewayAlertThreshold, cfg.GatewayDownAlert, notifier, logger)
+	shortCalls := monitor.NewShortCallMonitor(appStore, monitor.ShortCallPolicy{
+		ShortSeconds: cfg.ShortCallSeconds,
+		Ratio:        cfg.ShortCallRatio,
+		MinAnswered:  cfg.ShortCallMinCalls,
+		Window:       time.Duration(cfg.ShortCallWindow) * time.Minute,
+		Interval:     time.Duration(cfg.ShortCallInterval) * time.Second,
+	}, notifier, logger)
+	go shortCalls.Run(ctx)
+	quality := monitor.NewQualityMonitor(appStore, monitor.QualityPolicy{
+		Interval:   time.Duration(cfg.DataQualityInterval) * time.Second,
+		Lookback:   time.Duration(cfg.DataQualityLookback) * 24 * time.Hour,
+		StaleAfter: time.Duration(cfg.DataQualityStaleHours) * time.Hour,
+	}, notifier, logger)
+	go quality.Run(ctx)
+	classifier := outcome.NewClassifier(appStore, outcomeRules, outcome.Policy{
+		Interval: time.Duration(cfg.OutcomeInterval) * time.Second,
+		Lookback: time.Duration(cfg.OutcomeLookback) * time.Hour,
+		HookURL:  outcomeHook,
+	}, logger)
+	go classifier.Run(ctx)
+	retainer := retention.NewEnforcer(appStore, retention.Policy{
+		Interval:   time.Duration(cfg.RetentionInterval) * time.Second,
+		RawEvents:  time.Duration(cfg.RawEventRetention) * 24 * time.Hour,
+		CallEvents: time.Duration(cfg.CallEventRetention) * 24 * time.Hour,
+		Calls:      time.Duration(cfg.CallRecordRetention) * 24 * time.Hour,
+	}, logger)
+	go retainer.Run(ctx)
+	queueFull, err := esl.ParseQueueFullPolicy(cfg.EventQueueFull)
+	if err != nil {
+		logger.Fatalf("Invalid EVENT_QUEUE_FULL: %v", err)
+	}
+	limits := esl.Limits{
+		Workers:     cfg.EventWorkers,
+		QueueSize:   cfg.EventQueueSize,
+		QueueFull:   queueFull,
+		MaxHandlers: cfg.EventMaxHandlers,
+		PerEvent:    cfg.EventTypeLimits,
+		Shed: esl.ShedPolicy{
+			InFlightThreshold: cfg.ShedInFlightThreshold,
+			Sustain:           time.Duration(cfg.ShedSustainSeconds) * time.Second,
+			SampleRate:        cfg.ShedSampleRate,
+			LowPriority:       cfg.ShedEvents,
+		},
+	}
+	callerIDCampaigns := esl.NewCallerIDCampaigns(appStore, logger)
+	for number, name := range cfg.CallerIDCampaigns {
+		if err := appStore.SetCallerIDCampaign(ctx, &store.CallerIDCampaign{CallerID: number, Campaign: name}); err != nil {
+			logger.Fatalf("Failed to seed CALLER_ID_CAMPAIGNS: %v", err)
+		}
+	}
+	if err := callerIDCampaigns.Reload(ctx); err != nil {
+		logger.Fatalf("Failed to load caller ID campaigns: %v", err)
+	}
+	go callerIDCampaigns.Run(ctx, time.Duration(cfg.CallerIDCampaignTTL)*time.Second)
+	var deadLetter *esl.Spool
+	if cfg.DeadLetterPath != "" {
+		deadLetter, err = esl.NewSpool(cfg.DeadLetterPath)
+		if err != nil {
+			logger.Fatalf("Failed to open dead-letter spool: %v", err)
+		}
+	}
+	eslOpts := esl.Options{
+		Rater:     rater,
+		Directory: dir,
+		Prefixes:  prefixTable,
+		Rules:     ruleEngine,
+		Gateways:  gateways,
+		Notifier:  notifier,
+		Limits:    limits,
+		Callbacks: esl.CallbackPolicy{
+			OfferSubclass:  cfg.CallbackOfferSubclass,
+			AcceptSubclass: cfg.CallbackAcceptSubclass,
+			MatchWindow:    time.Duration(cfg.CallbackMatchWindow) * time.Minute,
+		},
+		MissedCalls: esl.MissedCallPolicy{
+			Causes:   cfg.MissedCallCauses,
+			Notifier: alert.NewNotifier(missedCallWebhook, "", logger),
+
+			BusinessHoursOnly: cfg.MissedCallBusinessOnly,
+			Calendars:         calendars,
+		},
+		Failover: esl.FailoverPolicy{
+			BackupAddr:       cfg.ESLBackupAddr,
+			BackupPass:       cfg.ESLBackupPass,
+			After:            cfg.ESLFailoverAfter,
+			FailbackInterval: time.Duration(cfg.ESLFailbackInterval) * time.Second,
+		},
+		Auth: esl.AuthPolicy{
+			MaxFailures:   cfg.ESLAuthMaxFailures,
+			RetryInterval: time.Duration(cfg.ESLAuthRetryInterval) * time.Second,
+		},
+		Reconnect: esl.BackoffPolicy{
+			Initial:    time.Duration(cfg.ESLReconnectInitial * float64(time.Second)),
+			Max:        time.Duration(cfg.ESLReconnectMax * float64(time.Second)),
+			Multiplier: cfg.ESLReconnectMultiplier,
+			Jitter:     cfg.ESLReconnectJitter,
+		},
+
+		ReconcileOnGap:     cfg.ReconcileOnSequenceGap,
+		DedupWindow:        cfg.EventDedupWindow,
+		DeadLetter:         deadLetter,
+		DeadLetterAttempts: cfg.DeadLetterAttempts,
+		ReadTimeout:        time.Duration(cfg.ESLReadTimeout) * time.Second,
+		Events:             cfg.ESLEvents,
+		Filters:            eslFilters,
+		LateEventGrace:     time.Duration(cfg.LateEventGrace) * time.Second,
+		Mode:               eslMode,
+		OutboundListen:     cfg.ESLOutboundListen,
+
+		TraceApplications: flags.Enabled(features.Applications),
+		RawEvents:         flags.Enabled(features.RawEvents),
+		SIPHeaders:        cfg.SIPHeaders,
+		ChannelVariables:  cfg.ChannelVars,
+		CallerIDCampaigns: callerIDCampaigns,
+		Calendars:         calendars,
+	}
+	eslClients := make([]*esl.Client, len(eslServers))
+	for i, srv := range eslServers {
+		opts := eslOpts
+		if i > 0 {
+			// The backup endpoint and the outbound listener belong to the first server
+			opts.Failover = esl.FailoverPolicy{}
+			opts.Mode = esl.ModeInbound
+		}
+		if cfg.SpoolPath != "" {
+			if opts.Spool, err = esl.NewSpool(spoolPath(cfg.SpoolPath, i, srv.Addr)); err != nil {
+				logger.Fatalf("Failed to open event spool: %v", err)
+			}
+		}
+		eslClients[i] = esl.NewClient(srv.Addr, srv.Pass, appStore, opts, logger)
+	}
+	eslClient := esl.NewCluster(appStore, eslClients...)
 	if err := eslClient.Start(ctx); err != nil {
 		// Log non-fatal error, as ESL client has internal retry logic
 		logger.WithError(err).Error("ESL client failed to start initially, will attempt reconnection in background.")
 	}
+	if cfg.ESLPassFile != "" && cfg.ESLPassFileInterval > 0 {
+		go eslClient.WatchPasswordFile(ctx, cfg.ESLPassFile, cfg.ESLPass, time.Duration(cfg.ESLPassFileInterval)*time.Second, logger)
+	}
+
+	// SIGQUIT writes a diagnostic snapshot instead of Go's default stack dump and exit
+	dumper := diagnostics.NewDumper(recentErrors, cfg.DiagDumpDir, logger,
+		diagnostics.Section{Name: "esl", State: func() any { return eslClient.Diagnostics() }},
+		diagnostics.Section{Name: "store", State: func() any { return appStore.Diagnostics() }},
+	)
+	quitChan := make(chan os.Signal, 1)
+	signal.Notify(quitChan, syscall.SIGQUIT)
+	go func() {
+		for range quitChan {
+			if _, err := dumper.Dump(); err != nil {
+				logger.WithError(err).Error("Failed to write diagnostic snapshot")
+			}
+		}
+	}()
+
+	// Outbound campaigns dial through the ESL client
+	campaigns := campaign.NewManager(ctx, appStore, eslClient, logger)
+
+	// API usage per key, persisted in api_key_usage and checked against the monthly quotas
+	quotas, err := api.ParseQuotas(cfg.APIKeyQuotas)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid API_KEY_QUOTAS")
+	}
+	usage := api.NewUsageMeter(appStore, quotas, logger)
+	go usage.Run(ctx, time.Duration(max(cfg.APIUsageFlushInterval, 1))*time.Second)
 
 	// Initialize API Server
-	apiServer := api.NewServer(appStore, logger)
+	apiServer := api.NewServer(appStore, eslClient, gateways, api.Options{
+		DefaultLimit:  cfg.APIDefaultLimit,
+		MaxLimit:      cfg.APIMaxLimit,
+		AdminMaxLimit: cfg.APIAdminMaxLimit,
+		AdminKeys:     cfg.APIAdminKeys,
+		OperatorKeys:  cfg.APIOperatorKeys,
+		TenantKeys:    cfg.APITenantKeys,
+		DecryptRoles:  cfg.FieldDecryptionRoles,
+		StatsCacheTTL: time.Duration(cfg.StatsCacheTTL) * time.Second,
+		Campaigns:     campaigns,
+		Quality:       quality,
+		Retention:     retainer,
+		Diagnostics:   dumper,
+		Usage:         usage,
+
+		CallerIDCampaigns: callerIDCampaigns,
+
+		MissedCallCauses: cfg.MissedCallCauses,
+
+		SIPTraceURLTemplate: cfg.SIPTraceURLTemplate,
+		QueueServiceLevel:   cfg.QueueServiceLevel,
+
+		ParkingLot:     cfg.ParkingLot,
+		ParkingSlotMin: cfg.ParkingSlotMin,
+		ParkingSlotMax: cfg.ParkingSlotMax,
+
+		Features: flags,
+	}, logger)
 	apiAddr := fmt.Sprintf(":%s", cfg.APIPort)
 
 	httpServer := &http.Server{
@@ -79,6 +402,7 @@ func main() {
 	// Start API server in a goroutine
 	go func() {
 		logger.Infof("API server listening on %s", apiAddr)
+		appStore.RecordHealth(ctx, store.HealthTransition{Component: store.ComponentAPI, State: store.HealthUp, OccurredAt: time.Now()})
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatalf("Could not listen on %s: %v\n", apiAddr, err)
 		}
@@ -99,13 +423,22 @@ func main() {
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.WithError(err).Error("API server shutdown error")
 	}
+	stopped := "shutdown"
+	appStore.RecordHealth(shutdownCtx, store.HealthTransition{Component: store.ComponentAPI, State: store.HealthDown, Reason: &stopped, OccurredAt: time.Now()})
 
-	// Close ESL client connection
+	// Close ESL client connections
 	if err := eslClient.Close(); err != nil {
 		logger.WithError(err).Error("ESL client close error")
 	}
 
-	// Database pool is closed by defer dbPool.Close()
+	// Flush queued writes before the database pool is closed
+	if storeWriter != nil {
+		if err := storeWriter.Close(shutdownCtx); err != nil {
+			logger.WithError(err).Error("Store writer flush error")
+		}
+	}
+
+	// Database pool is closed by defer appStore.Close()
 
 	logger.Info("Application shut down gracefully.")
 }
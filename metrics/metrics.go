@@ -0,0 +1,416 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collector is implemented by every metric family that can be written in Prometheus text format. With
+// openMetrics set it writes the OpenMetrics text format instead, which adds exemplars.
+type collector interface {
+	name() string
+	write(w io.Writer, openMetrics bool)
+}
+
+// Registry holds metric families and renders them in the Prometheus text exposition format
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default is the process-wide registry exposed on /metrics
+var Default = NewRegistry()
+
+// register adds c to the registry, panicking on duplicate names as that is always a programming error
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write writes all registered metrics to w in Prometheus text format, sorted by name
+func (r *Registry) Write(w io.Writer) {
+	r.write(w, false)
+}
+
+// WriteOpenMetrics writes all registered metrics to w in OpenMetrics text format, sorted by name.
+// Histogram buckets carry their latest exemplar.
+func (r *Registry) WriteOpenMetrics(w io.Writer) {
+	r.write(w, true)
+	fmt.Fprint(w, "# EOF\n")
+}
+
+func (r *Registry) write(w io.Writer, openMetrics bool) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w, openMetrics)
+	}
+}
+
+// Handler returns an http.Handler serving the registry in Prometheus text format, or in OpenMetrics format
+// (with exemplars) to scrapers that accept it
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text") {
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+			r.WriteOpenMetrics(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// family holds the metadata and labelled children shared by all metric types
+type family struct {
+	metricName string
+	help       string
+	kind       string
+	labelNames []string
+
+	mu       sync.Mutex
+	children map[string]*child
+}
+
+// child is a single labelled series within a family
+type child struct {
+	labelValues []string
+	value       any // *Gauge, *Counter or *Histogram
+}
+
+func (f *family) name() string { return f.metricName }
+
+// get returns the child for labelValues, creating it with newValue if needed
+func (f *family) get(labelValues []string, newValue func() any) any {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c.value
+	}
+	c := &child{labelValues: append([]string(nil), labelValues...), value: newValue()}
+	f.children[key] = c
+	return c.value
+}
+
+// sortedChildren returns the family's children in a stable order
+func (f *family) sortedChildren() []*child {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.children))
+	for k := range f.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	children := make([]*child, 0, len(keys))
+	for _, k := range keys {
+		children = append(children, f.children[k])
+	}
+	return children
+}
+
+// writeHeader writes the HELP and TYPE lines. OpenMetrics names counter families without the _total
+// suffix their samples carry.
+func (f *family) writeHeader(w io.Writer, openMetrics bool) {
+	name := f.metricName
+	if openMetrics && f.kind == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	help := helpEscaper.Replace(f.help)
+	if openMetrics {
+		help = labelEscaper.Replace(f.help) // OpenMetrics escapes HELP text like a label value
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+}
+
+// formatLabels renders {a="x",b="y"} for the given names and values, plus any extra pairs
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeLabel(&b, name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		writeLabel(&b, extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes a label value for the exposition formats, which only escape backslash, double
+// quote and line feed; everything else, including non-ASCII text, is written as is
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpEscaper escapes HELP text for the Prometheus text format, which leaves double quotes alone
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// writeLabel writes name="value" with value escaped for the exposition formats
+func writeLabel(b *strings.Builder, name, value string) {
+	b.WriteString(name)
+	b.WriteString(`="`)
+	labelEscaper.WriteString(b, strings.ToValidUTF8(value, "\uFFFD"))
+	b.WriteByte('"')
+}
+
+// formatFloat renders a sample value the way Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Add adds delta (which may be negative) to the gauge
+func (g *Gauge) Add(delta float64) {
+	g.mu.Lock()
+	g.value += delta
+	g.mu.Unlock()
+}
+
+// Value returns the current gauge value
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// GaugeVec is a family of gauges partitioned by labels
+type GaugeVec struct {
+	family
+}
+
+// NewGaugeVec creates and registers a GaugeVec on the Default registry
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	v := &GaugeVec{family{metricName: name, help: help, kind: "gauge", labelNames: labelNames, children: make(map[string]*child)}}
+	Default.register(v)
+	return v
+}
+
+// With returns the gauge for the given label values
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return v.get(labelValues, func() any { return &Gauge{} }).(*Gauge)
+}
+
+func (v *GaugeVec) write(w io.Writer, openMetrics bool) {
+	v.writeHeader(w, openMetrics)
+	for _, c := range v.sortedChildren() {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, formatLabels(v.labelNames, c.labelValues), formatFloat(c.value.(*Gauge).Value()))
+	}
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by delta; negative values are ignored
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.value += delta
+	c.mu.Unlock()
+}
+
+// Value returns the current counter value
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// CounterVec is a family of counters partitioned by labels
+type CounterVec struct {
+	family
+}
+
+// NewCounterVec creates and registers a CounterVec on the Default registry
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{family{metricName: name, help: help, kind: "counter", labelNames: labelNames, children: make(map[string]*child)}}
+	Default.register(v)
+	return v
+}
+
+// With returns the counter for the given label values
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return v.get(labelValues, func() any { return &Counter{} }).(*Counter)
+}
+
+func (v *CounterVec) write(w io.Writer, openMetrics bool) {
+	v.writeHeader(w, openMetrics)
+	sample := v.metricName
+	if openMetrics && !strings.HasSuffix(sample, "_total") {
+		sample += "_total"
+	}
+	for _, c := range v.sortedChildren() {
+		fmt.Fprintf(w, "%s%s %s\n", sample, formatLabels(v.labelNames, c.labelValues), formatFloat(c.value.(*Counter).Value()))
+	}
+}
+
+// DefaultLagBuckets are histogram buckets in seconds suited to event pipeline latency
+var DefaultLagBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram samples observations into cumulative buckets
+type Histogram struct {
+	mu        sync.Mutex
+	buckets   []float64
+	counts    []uint64
+	sum       float64
+	count     uint64
+	exemplars []*exemplar // Latest exemplar per bucket, the last entry being +Inf; nil until one is observed
+}
+
+// exemplar links an observation to the trace that produced it
+type exemplar struct {
+	traceID string
+	value   float64
+	at      time.Time
+}
+
+// Observe records a single observation
+func (h *Histogram) Observe(v float64) {
+	h.ObserveWithExemplar(v, "")
+}
+
+// ObserveWithExemplar records an observation and, if traceID is not empty, keeps it as the exemplar of
+// the lowest bucket containing v
+func (h *Histogram) ObserveWithExemplar(v float64, traceID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	bucket := len(h.buckets)
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+			bucket = min(bucket, i)
+		}
+	}
+	h.sum += v
+	h.count++
+
+	if traceID == "" {
+		return
+	}
+	if h.exemplars == nil {
+		h.exemplars = make([]*exemplar, len(h.buckets)+1)
+	}
+	h.exemplars[bucket] = &exemplar{traceID: traceID, value: v, at: time.Now()}
+}
+
+// exemplarSuffix renders the OpenMetrics exemplar of bucket i, or "" if it has none
+func (h *Histogram) exemplarSuffix(i int) string {
+	if h.exemplars == nil || h.exemplars[i] == nil {
+		return ""
+	}
+	e := h.exemplars[i]
+	ts := float64(e.at.UnixNano()) / 1e9
+	var labels strings.Builder
+	writeLabel(&labels, "trace_id", e.traceID)
+	return fmt.Sprintf(" # {%s} %s %s", labels.String(), formatFloat(e.value), strconv.FormatFloat(ts, 'f', 3, 64))
+}
+
+// HistogramVec is a family of histograms partitioned by labels
+type HistogramVec struct {
+	family
+	buckets []float64
+}
+
+// NewHistogramVec creates and registers a HistogramVec on the Default registry. buckets must be sorted ascending.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	v := &HistogramVec{
+		family:  family{metricName: name, help: help, kind: "histogram", labelNames: labelNames, children: make(map[string]*child)},
+		buckets: buckets,
+	}
+	Default.register(v)
+	return v
+}
+
+// With returns the histogram for the given label values
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return v.get(labelValues, func() any {
+		return &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets))}
+	}).(*Histogram)
+}
+
+func (v *HistogramVec) write(w io.Writer, openMetrics bool) {
+	v.writeHeader(w, openMetrics)
+	for _, c := range v.sortedChildren() {
+		h := c.value.(*Histogram)
+		h.mu.Lock()
+		suffix := func(int) string { return "" }
+		if openMetrics {
+			suffix = h.exemplarSuffix
+		}
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d%s\n", v.metricName, formatLabels(v.labelNames, c.labelValues, "le", formatFloat(upper)), h.counts[i], suffix(i))
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d%s\n", v.metricName, formatLabels(v.labelNames, c.labelValues, "le", "+Inf"), h.count, suffix(len(h.buckets)))
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.metricName, formatLabels(v.labelNames, c.labelValues), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metricName, formatLabels(v.labelNames, c.labelValues), h.count)
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFamily builds a family without registering it on Default, so tests can use their own registries
+func newFamily(name, help, kind string, labelNames ...string) family {
+	return family{metricName: name, help: help, kind: kind, labelNames: labelNames, children: make(map[string]*child)}
+}
+
+func render(r *Registry, openMetrics bool) string {
+	var b strings.Builder
+	if openMetrics {
+		r.WriteOpenMetrics(&b)
+	} else {
+		r.Write(&b)
+	}
+	return b.String()
+}
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		extra  []string
+		want   string
+	}{
+		{name: "plain", values: []string{"answered"}, want: `{status="answered"}`},
+		{name: "double quote", values: []string{`say "hi"`}, want: `{status="say \"hi\""}`},
+		{name: "backslash", values: []string{`C:\calls`}, want: `{status="C:\\calls"}`},
+		{name: "line feed", values: []string{"two\nlines"}, want: `{status="two\nlines"}`},
+		{name: "escaped sequence stays literal", values: []string{`\n`}, want: `{status="\\n"}`},
+		{name: "non-ASCII left as is", values: []string{"Zürich 北京"}, want: `{status="Zürich 北京"}`},
+		{name: "invalid UTF-8 replaced", values: []string{"bad\xffbyte"}, want: "{status=\"bad\uFFFDbyte\"}"},
+		{name: "tab and carriage return left as is", values: []string{"a\tb\rc"}, want: "{status=\"a\tb\rc\"}"},
+		{name: "extra pair", values: []string{"answered"}, extra: []string{"le", "0.5"}, want: `{status="answered",le="0.5"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLabels([]string{"status"}, tt.values, tt.extra...); got != tt.want {
+				t.Errorf("formatLabels(%q) = %s, want %s", tt.values, got, tt.want)
+			}
+		})
+	}
+
+	if got := formatLabels(nil, nil); got != "" {
+		t.Errorf("formatLabels without labels = %q, want empty", got)
+	}
+	if got := formatLabels(nil, nil, "le", "+Inf"); got != `{le="+Inf"}` {
+		t.Errorf("formatLabels with only extra pairs = %s", got)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := map[float64]string{0: "0", 1: "1", 0.005: "0.005", 2.5: "2.5", 1e21: "1e+21"}
+	for v, want := range tests {
+		if got := formatFloat(v); got != want {
+			t.Errorf("formatFloat(%v) = %s, want %s", v, got, want)
+		}
+	}
+	for v, want := range map[float64]string{math.Inf(1): "+Inf", math.Inf(-1): "-Inf"} {
+		if got := formatFloat(v); got != want {
+			t.Errorf("formatFloat(%v) = %s, want %s", v, got, want)
+		}
+	}
+	if got := formatFloat(math.NaN()); got != "NaN" {
+		t.Errorf("formatFloat(NaN) = %s, want NaN", got)
+	}
+}
+
+func TestHelpEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.register(&GaugeVec{newFamily("esl_test_gauge", "Calls \"up\" at C:\\pbx\nper node", "gauge")})
+
+	text := render(r, false)
+	if want := "# HELP esl_test_gauge Calls \"up\" at C:\\\\pbx\\nper node\n"; !strings.HasPrefix(text, want) {
+		t.Errorf("text format HELP:\n%s\nwant prefix:\n%s", text, want)
+	}
+	om := render(r, true)
+	if want := "# HELP esl_test_gauge Calls \\\"up\\\" at C:\\\\pbx\\nper node\n"; !strings.HasPrefix(om, want) {
+		t.Errorf("OpenMetrics HELP:\n%s\nwant prefix:\n%s", om, want)
+	}
+}
+
+func TestGaugeExposition(t *testing.T) {
+	r := NewRegistry()
+	v := &GaugeVec{newFamily("esl_active_calls", "Active calls", "gauge", "node")}
+	r.register(v)
+	v.With("b").Set(3)
+	v.With("a").Add(2)
+	v.With("a").Add(-0.5)
+
+	want := "# HELP esl_active_calls Active calls\n" +
+		"# TYPE esl_active_calls gauge\n" +
+		"esl_active_calls{node=\"a\"} 1.5\n" +
+		"esl_active_calls{node=\"b\"} 3\n"
+	if got := render(r, false); got != want {
+		t.Errorf("text format:\n%s\nwant:\n%s", got, want)
+	}
+	if got := render(r, true); got != want+"# EOF\n" {
+		t.Errorf("OpenMetrics:\n%s\nwant:\n%s# EOF\n", got, want)
+	}
+}
+
+func TestCounterExposition(t *testing.T) {
+	r := NewRegistry()
+	total := &CounterVec{newFamily("esl_events_total", "Events received", "counter", "event")}
+	bare := &CounterVec{newFamily("esl_reconnects", "Reconnects", "counter")}
+	r.register(total)
+	r.register(bare)
+	total.With("CHANNEL_ANSWER").Inc()
+	total.With("CHANNEL_ANSWER").Add(2)
+	total.With("CHANNEL_ANSWER").Add(-5) // Ignored, counters only go up
+	bare.With().Inc()
+
+	text := "# HELP esl_events_total Events received\n" +
+		"# TYPE esl_events_total counter\n" +
+		"esl_events_total{event=\"CHANNEL_ANSWER\"} 3\n" +
+		"# HELP esl_reconnects Reconnects\n" +
+		"# TYPE esl_reconnects counter\n" +
+		"esl_reconnects 1\n"
+	if got := render(r, false); got != text {
+		t.Errorf("text format:\n%s\nwant:\n%s", got, text)
+	}
+
+	// OpenMetrics names the family without _total and requires it on every sample
+	om := "# HELP esl_events Events received\n" +
+		"# TYPE esl_events counter\n" +
+		"esl_events_total{event=\"CHANNEL_ANSWER\"} 3\n" +
+		"# HELP esl_reconnects Reconnects\n" +
+		"# TYPE esl_reconnects counter\n" +
+		"esl_reconnects_total 1\n" +
+		"# EOF\n"
+	if got := render(r, true); got != om {
+		t.Errorf("OpenMetrics:\n%s\nwant:\n%s", got, om)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	r := NewRegistry()
+	v := &HistogramVec{family: newFamily("esl_event_lag_seconds", "Event lag", "histogram", "event"), buckets: []float64{0.1, 1}}
+	r.register(v)
+	h := v.With("CHANNEL_HANGUP")
+	h.Observe(0.05)
+	h.Observe(0.1) // Upper bounds are inclusive
+	h.Observe(0.5)
+	h.Observe(3)
+
+	text := "# HELP esl_event_lag_seconds Event lag\n" +
+		"# TYPE esl_event_lag_seconds histogram\n" +
+		"esl_event_lag_seconds_bucket{event=\"CHANNEL_HANGUP\",le=\"0.1\"} 2\n" +
+		"esl_event_lag_seconds_bucket{event=\"CHANNEL_HANGUP\",le=\"1\"} 3\n" +
+		"esl_event_lag_seconds_bucket{event=\"CHANNEL_HANGUP\",le=\"+Inf\"} 4\n" +
+		"esl_event_lag_seconds_sum{event=\"CHANNEL_HANGUP\"} 3.65\n" +
+		"esl_event_lag_seconds_count{event=\"CHANNEL_HANGUP\"} 4\n"
+	if got := render(r, false); got != text {
+		t.Errorf("text format:\n%s\nwant:\n%s", got, text)
+	}
+	if got := render(r, true); got != text+"# EOF\n" {
+		t.Errorf("OpenMetrics without exemplars:\n%s\nwant:\n%s# EOF\n", got, text)
+	}
+}
+
+func TestHistogramExemplars(t *testing.T) {
+	r := NewRegistry()
+	v := &HistogramVec{family: newFamily("esl_event_lag_seconds", "Event lag", "histogram"), buckets: []float64{0.1, 1}}
+	r.register(v)
+	h := v.With()
+	h.ObserveWithExemplar(0.05, "first")
+	h.ObserveWithExemplar(0.07, "latest") // Replaces the exemplar of the same bucket
+	h.ObserveWithExemplar(0.5, "")        // Counted without an exemplar
+	h.ObserveWithExemplar(7, `odd"id`)
+	at := time.Unix(1700000000, 250_000_000)
+	for _, e := range h.exemplars {
+		if e != nil {
+			e.at = at
+		}
+	}
+
+	om := "# HELP esl_event_lag_seconds Event lag\n" +
+		"# TYPE esl_event_lag_seconds histogram\n" +
+		"esl_event_lag_seconds_bucket{le=\"0.1\"} 2 # {trace_id=\"latest\"} 0.07 1700000000.250\n" +
+		"esl_event_lag_seconds_bucket{le=\"1\"} 3\n" +
+		"esl_event_lag_seconds_bucket{le=\"+Inf\"} 4 # {trace_id=\"odd\\\"id\"} 7 1700000000.250\n" +
+		"esl_event_lag_seconds_sum 7.62\n" +
+		"esl_event_lag_seconds_count 4\n" +
+		"# EOF\n"
+	if got := render(r, true); got != om {
+		t.Errorf("OpenMetrics:\n%s\nwant:\n%s", got, om)
+	}
+	// The Prometheus text format has no exemplars
+	if got := render(r, false); strings.Contains(got, "trace_id") {
+		t.Errorf("text format carries exemplars:\n%s", got)
+	}
+}
+
+func TestHandlerNegotiation(t *testing.T) {
+	r := NewRegistry()
+	v := &CounterVec{newFamily("esl_events_total", "Events received", "counter")}
+	r.register(v)
+	v.With().Inc()
+
+	tests := []struct {
+		accept      string
+		contentType string
+		sample      string
+	}{
+		{accept: "", contentType: "text/plain; version=0.0.4; charset=utf-8", sample: "# TYPE esl_events_total counter\n"},
+		{accept: "application/openmetrics-text;version=1.0.0,text/plain;q=0.5", contentType: "application/openmetrics-text; version=1.0.0; charset=utf-8", sample: "# TYPE esl_events counter\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("Accept %q: Content-Type %q, want %q", tt.accept, got, tt.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), tt.sample) {
+			t.Errorf("Accept %q: body lacks %q:\n%s", tt.accept, tt.sample, rec.Body.String())
+		}
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.register(&GaugeVec{newFamily("esl_dup", "Dup", "gauge")})
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate name did not panic")
+		}
+	}()
+	r.register(&CounterVec{newFamily("esl_dup", "Dup", "counter")})
+}
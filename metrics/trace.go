@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+)
+
+type traceIDKey struct{}
+
+// WithTraceID returns a context carrying traceID, which histograms observed under it record as exemplars
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceID returns the trace ID carried by ctx, or "" if there is none
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// ParseTraceparent returns the trace ID of a W3C traceparent header ("00-<trace-id>-<span-id>-<flags>")
+func ParseTraceparent(header string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", false
+	}
+	traceID := strings.ToLower(parts[1])
+	if strings.Trim(traceID, "0") == "" || strings.Trim(traceID, "0123456789abcdef") != "" {
+		return "", false // All-zero IDs are invalid
+	}
+	return traceID, true
+}
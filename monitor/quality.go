@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// qualitySamples is the number of offending records kept per check in a report
+const qualitySamples = 10
+
+var qualityIssuesGauge = metrics.NewGaugeVec("data_quality_issues", "Records failing each data quality check at the last run.", "check")
+
+// QualityPolicy configures the scheduled data quality checks
+type QualityPolicy struct {
+	Interval   time.Duration // How often to run the checks; 0 disables the schedule (the API can still run them)
+	Lookback   time.Duration // Only calls started this recently are checked
+	StaleAfter time.Duration // Calls still open this long after starting count as stale
+}
+
+// QualityReport is the outcome of one run of the data quality checks
+type QualityReport struct {
+	CheckedAt time.Time            `json:"checked_at"`
+	Since     time.Time            `json:"since"`
+	Issues    []store.QualityIssue `json:"issues"`
+}
+
+// QualityMonitor periodically scans stored calls for data quality issues, the usual symptom of an event
+// parsing regression. Each check alerts once when it starts failing and re-arms when it comes back clean.
+type QualityMonitor struct {
+	store    *store.Store
+	policy   QualityPolicy
+	notifier *alert.Notifier
+	log      *logrus.Logger
+
+	mu      sync.Mutex
+	last    *QualityReport
+	alerted map[string]bool // check -> currently alerting
+}
+
+// NewQualityMonitor creates a new QualityMonitor
+func NewQualityMonitor(s *store.Store, policy QualityPolicy, notifier *alert.Notifier, logger *logrus.Logger) *QualityMonitor {
+	return &QualityMonitor{
+		store:    s,
+		policy:   policy,
+		notifier: notifier,
+		alerted:  make(map[string]bool),
+		log:      logger,
+	}
+}
+
+// Run checks every policy.Interval until ctx is cancelled. It returns at once if the schedule is disabled.
+func (m *QualityMonitor) Run(ctx context.Context) {
+	if m.policy.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Check(ctx); err != nil {
+				m.log.WithError(err).Error("Data quality check failed")
+			}
+		}
+	}
+}
+
+// Last returns the report of the most recent run, or nil if the checks have not run yet
+func (m *QualityMonitor) Last() *QualityReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Check runs the checks now, updating the metrics and alerting on checks that started failing
+func (m *QualityMonitor) Check(ctx context.Context) (*QualityReport, error) {
+	now := time.Now().UTC()
+	report := &QualityReport{CheckedAt: now, Since: now.Add(-m.policy.Lookback)}
+	issues, err := m.store.GetQualityIssues(ctx, report.Since, now.Add(-m.policy.StaleAfter), qualitySamples)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = issues
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = report
+	for _, issue := range issues {
+		qualityIssuesGauge.With(issue.Check).Set(float64(issue.Count))
+		if issue.Count == 0 {
+			if m.alerted[issue.Check] {
+				delete(m.alerted, issue.Check)
+				m.log.WithField("check", issue.Check).Info("Data quality check clean again")
+			}
+			continue
+		}
+		if m.alerted[issue.Check] {
+			continue
+		}
+		m.alerted[issue.Check] = true
+		m.notifier.Notify(alert.Alert{
+			Type:     "data_quality",
+			Severity: alert.SeverityWarning,
+			Message:  fmt.Sprintf("%d records fail the %s data quality check", issue.Count, issue.Check),
+			Fields: map[string]any{
+				"check":   issue.Check,
+				"count":   issue.Count,
+				"samples": issue.Samples,
+				"since":   report.Since,
+			},
+		})
+	}
+	return report, nil
+}
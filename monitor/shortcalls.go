@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gofreeswitchesl/alert"
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+var shortCallRatioGauge = metrics.NewGaugeVec("short_call_ratio", "Share of answered calls per gateway that ended within SHORT_CALL_SECONDS over the alarm window.", "gateway")
+
+// ShortCallPolicy configures the short-call alarm. A spike in answered calls that end within a few
+// seconds is the classic symptom of one-way audio or a misbehaving carrier.
+type ShortCallPolicy struct {
+	ShortSeconds int           // Answered calls at most this long count as short
+	Ratio        float64       // Alert when the short share reaches this fraction (0-1]; 0 disables the alarm
+	MinAnswered  int           // Ignore gateways/extensions with fewer answered calls in the window
+	Window       time.Duration // How far back each check looks
+	Interval     time.Duration // How often to check
+}
+
+// ShortCallMonitor periodically checks short-call ratios per gateway and extension. Each alert fires once
+// when a ratio reaches the threshold and re-arms after it drops back below.
+type ShortCallMonitor struct {
+	store    *store.Store
+	policy   ShortCallPolicy
+	notifier *alert.Notifier
+	alerted  map[string]bool // "<dimension>:<key>" -> currently alerting
+	log      *logrus.Logger
+}
+
+// NewShortCallMonitor creates a new ShortCallMonitor
+func NewShortCallMonitor(s *store.Store, policy ShortCallPolicy, notifier *alert.Notifier, logger *logrus.Logger) *ShortCallMonitor {
+	return &ShortCallMonitor{
+		store:    s,
+		policy:   policy,
+		notifier: notifier,
+		alerted:  make(map[string]bool),
+		log:      logger,
+	}
+}
+
+// Run checks ratios every policy.Interval until ctx is cancelled. It returns at once if the alarm is disabled.
+func (m *ShortCallMonitor) Run(ctx context.Context) {
+	if m.policy.Ratio <= 0 || m.policy.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, dimension := range []string{"gateway", "extension"} {
+				m.check(ctx, dimension)
+			}
+		}
+	}
+}
+
+// check evaluates one dimension, alerting on new breaches and re-arming recovered keys
+func (m *ShortCallMonitor) check(ctx context.Context, dimension string) {
+	stats, err := m.store.GetShortCallStats(ctx, dimension, time.Now().Add(-m.policy.Window), m.policy.ShortSeconds, m.policy.MinAnswered)
+	if err != nil {
+		m.log.WithError(err).WithField("dimension", dimension).Error("Short call check failed")
+		return
+	}
+
+	breaching := make(map[string]bool)
+	for _, sc := range stats {
+		if dimension == "gateway" {
+			shortCallRatioGauge.With(sc.Key).Set(sc.Ratio)
+		}
+		if sc.Ratio < m.policy.Ratio {
+			continue
+		}
+		id := dimension + ":" + sc.Key
+		breaching[id] = true
+		if m.alerted[id] {
+			continue
+		}
+		m.alerted[id] = true
+		m.notifier.Notify(alert.Alert{
+			Type:     "short_calls",
+			Severity: alert.SeverityWarning,
+			Message: fmt.Sprintf("%.0f%% of answered calls on %s %s lasted %ds or less (possible one-way audio)",
+				sc.Ratio*100, dimension, sc.Key, m.policy.ShortSeconds),
+			Fields: map[string]any{
+				"dimension":      dimension,
+				"key":            sc.Key,
+				"answered":       sc.Answered,
+				"short":          sc.Short,
+				"ratio":          sc.Ratio,
+				"short_seconds":  m.policy.ShortSeconds,
+				"window_minutes": m.policy.Window.Minutes(),
+			},
+		})
+	}
+
+	prefix := dimension + ":"
+	for id := range m.alerted {
+		if len(id) > len(prefix) && id[:len(prefix)] == prefix && !breaching[id] {
+			delete(m.alerted, id)
+			m.log.WithField("key", id).Info("Short call ratio back below alert threshold")
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package outcome
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxLabelLength is the longest outcome label accepted from rules, the hook or the API
+const MaxLabelLength = 64
+
+const (
+	batchSize = 200              // Calls classified per query
+	settle    = 30 * time.Second // Wait after a call ends so CHANNEL_HANGUP_COMPLETE billing is stored first
+)
+
+// Rule assigns an outcome label to completed calls matching its condition, written in expr-lang syntax
+// (https://expr-lang.org), e.g. `direction == "inbound" && billsec < 5 && !answered`
+type Rule struct {
+	Outcome string `json:"outcome"`
+	When    string `json:"when"` // Boolean expression; empty always matches
+}
+
+// Rules is an ordered set of compiled outcome rules; the first match wins
+type Rules struct {
+	defs     []Rule
+	programs []*vm.Program
+}
+
+// newEnv returns the expression environment for a completed call. Missing values are empty or zero.
+func newEnv(call *store.Call) map[string]any {
+	str := func(p *string) string {
+		if p == nil {
+			return ""
+		}
+		return *p
+	}
+	num := func(p *int64) int64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	orEmpty := func(m map[string]string) map[string]string {
+		if m == nil {
+			return map[string]string{}
+		}
+		return m
+	}
+	return map[string]any{
+		"direction":           call.Direction,
+		"caller":              call.Caller,
+		"caller_name":         str(call.CallerName),
+		"callee":              call.Callee,
+		"callee_name":         str(call.CalleeName),
+		"status":              str(call.Status),
+		"q850":                intValue(call.HangupCauseQ850),
+		"sip_status":          intValue(call.SIPTermStatus),
+		"context":             str(call.Context),
+		"domain":              str(call.Domain),
+		"gateway":             str(call.Gateway),
+		"account_code":        str(call.AccountCode),
+		"user_id":             str(call.UserID),
+		"destination_country": str(call.DestCountry),
+		"destination_group":   str(call.DestGroup),
+		"campaign":            str(call.CallerIDCampaign),
+		"billsec":             num(call.Billsec),
+		"duration":            num(call.Duration),
+		"answered":            call.AnsweredTime != nil,
+		"business_hours":      call.BusinessHours != nil && *call.BusinessHours,
+		"tags":                call.Tags,
+		"derived":             orEmpty(call.Derived),
+		"sip_headers":         orEmpty(call.SIPHeaders),
+		"variables":           orEmpty(call.Variables),
+	}
+}
+
+// intValue returns *p, or 0 when p is nil
+func intValue(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+// LoadRules reads a JSON array of outcome rules from path and compiles them
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defs []Rule
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse outcome rules: %w", err)
+	}
+
+	env := newEnv(&store.Call{})
+	r := &Rules{defs: defs, programs: make([]*vm.Program, len(defs))}
+	for i, def := range defs {
+		def.Outcome = strings.TrimSpace(def.Outcome)
+		r.defs[i].Outcome = def.Outcome
+		if !ValidLabel(def.Outcome) {
+			return nil, fmt.Errorf("rule %d: outcome must be 1 to %d characters", i+1, MaxLabelLength)
+		}
+		if def.When == "" {
+			continue
+		}
+		program, err := expr.Compile(def.When, expr.Env(env), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): when: %w", i+1, def.Outcome, err)
+		}
+		r.programs[i] = program
+	}
+	return r, nil
+}
+
+// Enabled reports whether any rules are loaded
+func (r *Rules) Enabled() bool {
+	return r != nil && len(r.defs) > 0
+}
+
+// Classify returns the outcome of the first rule matching call. Rules that fail at run time are skipped
+// and reported in errs.
+func (r *Rules) Classify(call *store.Call) (outcome string, ok bool, errs []error) {
+	if !r.Enabled() {
+		return "", false, nil
+	}
+	env := newEnv(call)
+	for i, def := range r.defs {
+		if r.programs[i] != nil {
+			out, err := expr.Run(r.programs[i], env)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %d (%s): %w", i+1, def.Outcome, err))
+				continue
+			}
+			if matched, _ := out.(bool); !matched {
+				continue
+			}
+		}
+		return def.Outcome, true, errs
+	}
+	return "", false, errs
+}
+
+// ValidLabel reports whether label is an acceptable outcome label
+func ValidLabel(label string) bool {
+	label = strings.TrimSpace(label)
+	return label != "" && len(label) <= MaxLabelLength
+}
+
+// hookResponse is the body expected from OUTCOME_HOOK_URL. An empty outcome leaves the call unlabelled.
+type hookResponse struct {
+	Outcome string `json:"outcome"`
+}
+
+// Policy configures the classifier
+type Policy struct {
+	Interval time.Duration // How often to look for newly completed calls; 0 disables classification
+	Lookback time.Duration // Calls that ended longer ago than this are never classified
+	HookURL  string        // Receives each call the rules leave unlabelled; empty disables the hook
+}
+
+// Classifier labels completed calls with an outcome, first from the embedded rules, then from the HTTP hook
+type Classifier struct {
+	store  *store.Store
+	rules  *Rules
+	policy Policy
+	client *http.Client
+	log    *logrus.Logger
+}
+
+// NewClassifier creates a new Classifier. rules may be nil.
+func NewClassifier(s *store.Store, rules *Rules, policy Policy, logger *logrus.Logger) *Classifier {
+	return &Classifier{
+		store:  s,
+		rules:  rules,
+		policy: policy,
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    logger,
+	}
+}
+
+// Enabled reports whether any classification source is configured
+func (c *Classifier) Enabled() bool {
+	return c.policy.Interval > 0 && (c.rules.Enabled() || c.policy.HookURL != "")
+}
+
+// Run classifies completed calls every policy.Interval until ctx is cancelled. It returns at once when
+// classification is disabled.
+func (c *Classifier) Run(ctx context.Context) {
+	if !c.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(c.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.classifyPending(ctx)
+		}
+	}
+}
+
+// classifyPending classifies every call that ended within the lookback window and has settled. Calls the
+// hook could not be reached for are left unclassified and retried on the next run.
+func (c *Classifier) classifyPending(ctx context.Context) {
+	now := time.Now()
+	since, before := now.Add(-c.policy.Lookback), now.Add(-settle)
+	var labelled, unlabelled, failed int
+	for {
+		calls, err := c.store.GetUnclassifiedCalls(ctx, since, before, batchSize)
+		if err != nil || len(calls) == 0 {
+			break
+		}
+		batchFailed := 0
+		for i := range calls {
+			call := &calls[i]
+			outcome, source, err := c.classify(ctx, call)
+			if err != nil {
+				c.log.WithError(err).WithField("uuid", call.UUID).Warn("Outcome hook failed; will retry")
+				batchFailed++
+				continue
+			}
+			if err := c.store.SetCallOutcome(ctx, call.UUID, outcome, source); err != nil {
+				batchFailed++
+				continue
+			}
+			if outcome != nil {
+				labelled++
+			} else {
+				unlabelled++
+			}
+		}
+		failed += batchFailed
+		// Failed calls would be fetched again, so leave the rest for the next run
+		if len(calls) < batchSize || batchFailed > 0 {
+			break
+		}
+	}
+
+	if labelled+unlabelled+failed > 0 {
+		c.log.WithFields(logrus.Fields{
+			"labelled":   labelled,
+			"unlabelled": unlabelled,
+			"failed":     failed,
+		}).Info("Classified call outcomes")
+	}
+}
+
+// classify returns the outcome of call and its source, or a nil outcome when neither the rules nor the hook
+// assign one. An error means the hook could not be reached and the call should be retried.
+func (c *Classifier) classify(ctx context.Context, call *store.Call) (*string, string, error) {
+	outcome, ok, errs := c.rules.Classify(call)
+	for _, err := range errs {
+		c.log.WithError(err).WithField("uuid", call.UUID).Warn("Outcome rule failed")
+	}
+	if ok {
+		return &outcome, store.OutcomeSourceRules, nil
+	}
+	if c.policy.HookURL == "" {
+		return nil, "", nil
+	}
+
+	outcome, err := c.callHook(ctx, call)
+	if err != nil {
+		return nil, "", err
+	}
+	if outcome == "" {
+		return nil, "", nil
+	}
+	if !ValidLabel(outcome) {
+		c.log.WithFields(logrus.Fields{
+			"uuid":    call.UUID,
+			"outcome": outcome,
+		}).Warn("Outcome hook returned an invalid label; ignoring")
+		return nil, "", nil
+	}
+	return &outcome, store.OutcomeSourceHook, nil
+}
+
+// callHook posts the call record to the hook and returns the outcome it answers with
+func (c *Classifier) callHook(ctx context.Context, call *store.Call) (string, error) {
+	body, err := json.Marshal(call)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.policy.HookURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return "", nil
+	case resp.StatusCode >= 500:
+		return "", fmt.Errorf("outcome hook returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		// The hook rejected this call; retrying would not help
+		c.log.WithFields(logrus.Fields{
+			"uuid":   call.UUID,
+			"status": resp.Status,
+		}).Warn("Outcome hook rejected call; leaving it unlabelled")
+		return "", nil
+	}
+
+	var out hookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode outcome hook response: %w", err)
+	}
+	return strings.TrimSpace(out.Outcome), nil
+}
@@ -0,0 +1,90 @@
+package prefixes
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strings"
+)
+
+// Destination is the classification of a dialled number
+type Destination struct {
+	Country string // e.g. ISO 3166 code
+	Group   string // Custom grouping such as mobile, fixed or premium; may be empty
+}
+
+// Table maps destination number prefixes to a Destination using longest-prefix match
+type Table struct {
+	entries map[string]Destination
+	maxLen  int
+}
+
+// NewCSVTable loads a Table from a CSV file of "prefix,country,group" rows, with prefixes in
+// international format without the leading + (e.g. "447,GB,mobile"). The group column is optional.
+// Blank lines and lines starting with '#' are ignored.
+func NewCSVTable(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	t := &Table{entries: make(map[string]Destination)}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			continue
+		}
+		prefix := normalize(record[0])
+		d := Destination{Country: strings.TrimSpace(record[1])}
+		if len(record) > 2 {
+			d.Group = strings.TrimSpace(record[2])
+		}
+		if prefix == "" || d.Country == "" {
+			continue
+		}
+		t.entries[prefix] = d
+		t.maxLen = max(t.maxLen, len(prefix))
+	}
+	return t, nil
+}
+
+// Enabled reports whether a table has been loaded
+func (t *Table) Enabled() bool {
+	return t != nil && len(t.entries) > 0
+}
+
+// Lookup returns the Destination of the longest prefix matching number. Numbers may be given with
+// a leading + or 00 international prefix.
+func (t *Table) Lookup(number string) (Destination, bool) {
+	if !t.Enabled() {
+		return Destination{}, false
+	}
+	n := normalize(number)
+	for l := min(len(n), t.maxLen); l > 0; l-- {
+		if d, ok := t.entries[n[:l]]; ok {
+			return d, true
+		}
+	}
+	return Destination{}, false
+}
+
+// normalize strips whitespace and the international prefix from number
+func normalize(number string) string {
+	n := strings.TrimSpace(number)
+	if strings.HasPrefix(n, "+") {
+		return n[1:]
+	}
+	return strings.TrimPrefix(n, "00")
+}
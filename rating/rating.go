@@ -0,0 +1,35 @@
+package rating
+
+import "math"
+
+// Rater computes call charges from a flat per-minute rate
+type Rater struct {
+	ratePerMinute float64
+	increment     int64 // Billing increment in seconds
+}
+
+// NewRater creates a new Rater. An increment below one second is treated as per-second billing.
+func NewRater(ratePerMinute float64, incrementSecs int) *Rater {
+	if incrementSecs < 1 {
+		incrementSecs = 1
+	}
+	return &Rater{
+		ratePerMinute: ratePerMinute,
+		increment:     int64(incrementSecs),
+	}
+}
+
+// Enabled reports whether a rate has been configured
+func (r *Rater) Enabled() bool {
+	return r != nil && r.ratePerMinute > 0
+}
+
+// Cost returns the charge for the given billable seconds, rounded up to the billing increment
+func (r *Rater) Cost(billsec int64) float64 {
+	if !r.Enabled() || billsec <= 0 {
+		return 0
+	}
+	units := (billsec + r.increment - 1) / r.increment
+	cost := float64(units*r.increment) / 60 * r.ratePerMinute
+	return math.Round(cost*10000) / 10000 // Matches NUMERIC(12,4) column precision
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"gofreeswitchesl/calendar"
+	"gofreeswitchesl/features"
+	"gofreeswitchesl/outcome"
+	"gofreeswitchesl/prefixes"
+	"gofreeswitchesl/rating"
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recomputableFields are the stored derived fields the recompute command can rebuild. Numbers are stored as
+// received, so there are no normalized numbers to rebuild.
+var recomputableFields = map[string]bool{
+	"cost":           true, // From answered_time, end_time and the configured rate
+	"destination":    true, // destination_country/destination_group from PREFIX_TABLE_CSV
+	"business_hours": true, // business_hours from BUSINESS_HOURS_CALENDARS
+	"durations":      true, // billsec/duration from the call timestamps, where CHANNEL_HANGUP_COMPLETE never set them
+	"outcome":        true, // outcome (disposition) from OUTCOME_RULES; hook and API labels are kept
+}
+
+// recomputeOptions are the parsed flags of the recompute command
+type recomputeOptions struct {
+	fields    []string
+	from, to  time.Time
+	batchSize int
+	dryRun    bool
+}
+
+// parseRecomputeFlags parses the recompute subcommand arguments
+func parseRecomputeFlags(args []string) (recomputeOptions, error) {
+	fs := flag.NewFlagSet("recompute", flag.ContinueOnError)
+	fields := fs.String("fields", "cost", "comma-separated derived fields to recompute (supported: cost, destination, business_hours, durations, outcome)")
+	from := fs.String("from", "", "only calls started at or after this RFC3339 time (default: all)")
+	to := fs.String("to", "", "only calls started before this RFC3339 time (default: now)")
+	batchSize := fs.Int("batch", 1000, "rows per batch")
+	dryRun := fs.Bool("dry-run", false, "compute and report without writing")
+	if err := fs.Parse(args); err != nil {
+		return recomputeOptions{}, err
+	}
+
+	opts := recomputeOptions{
+		batchSize: *batchSize,
+		dryRun:    *dryRun,
+	}
+	for _, field := range strings.Split(*fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !recomputableFields[field] {
+			return opts, fmt.Errorf("field %q cannot be recomputed", field)
+		}
+		opts.fields = append(opts.fields, field)
+	}
+	if len(opts.fields) == 0 {
+		return opts, fmt.Errorf("no fields to recompute")
+	}
+	if opts.batchSize <= 0 {
+		return opts, fmt.Errorf("batch must be positive")
+	}
+	var err error
+	opts.from, opts.to, err = parseCommandRange(*from, *to)
+	return opts, err
+}
+
+// runRecompute re-derives stored fields of historical calls after rating configuration changes
+func runRecompute(args []string, logger *logrus.Logger) error {
+	opts, err := parseRecomputeFlags(args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, appStore, closeStore, err := openCommandStore(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	rater := rating.NewRater(cfg.RatePerMinute, cfg.BillingIncrement)
+	if slices.Contains(opts.fields, "cost") {
+		flags, err := loadFeatures(cfg)
+		if err != nil {
+			return err
+		}
+		if !flags.Enabled(features.Rating) {
+			return fmt.Errorf("cost recompute requires the %s feature flag", features.Rating)
+		}
+	}
+	var prefixTable *prefixes.Table
+	if slices.Contains(opts.fields, "destination") {
+		if cfg.PrefixTablePath == "" {
+			return fmt.Errorf("destination recompute requires PREFIX_TABLE_CSV")
+		}
+		if prefixTable, err = prefixes.NewCSVTable(cfg.PrefixTablePath); err != nil {
+			return fmt.Errorf("load prefix table: %w", err)
+		}
+	}
+
+	var calendars *calendar.Calendars
+	if slices.Contains(opts.fields, "business_hours") {
+		if cfg.CalendarsPath == "" {
+			return fmt.Errorf("business_hours recompute requires BUSINESS_HOURS_CALENDARS")
+		}
+		if calendars, err = calendar.Load(cfg.CalendarsPath); err != nil {
+			return fmt.Errorf("load calendars: %w", err)
+		}
+	}
+
+	var outcomeRules *outcome.Rules
+	if slices.Contains(opts.fields, "outcome") {
+		if cfg.OutcomeRulesPath == "" {
+			return fmt.Errorf("outcome recompute requires OUTCOME_RULES")
+		}
+		if outcomeRules, err = outcome.LoadRules(cfg.OutcomeRulesPath); err != nil {
+			return fmt.Errorf("load outcome rules: %w", err)
+		}
+	}
+
+	total, err := appStore.CountFinishedCalls(ctx, opts.from, opts.to)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"fields": opts.fields,
+		"from":   opts.from,
+		"to":     opts.to,
+		"total":  total,
+		"dryRun": opts.dryRun,
+	}).Info("Starting recompute")
+
+	started := time.Now()
+	var processed, updated int64
+	lastID := 0
+	for {
+		calls, err := appStore.GetCallsAfter(ctx, lastID, opts.from, opts.to, true, opts.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(calls) == 0 {
+			break
+		}
+		lastID = calls[len(calls)-1].ID
+
+		ids := make([]int, len(calls))
+		for i := range calls {
+			ids[i] = calls[i].ID
+		}
+		for _, field := range opts.fields {
+			var n int64
+			switch field {
+			case "cost":
+				costs := make([]*float64, len(calls))
+				for i := range calls {
+					costs[i] = recomputeCost(rater, &calls[i])
+				}
+				if !opts.dryRun {
+					n, err = appStore.UpdateCallCosts(ctx, ids, costs)
+				}
+			case "destination":
+				countries := make([]*string, len(calls))
+				groups := make([]*string, len(calls))
+				for i := range calls {
+					countries[i], groups[i] = recomputeDestination(prefixTable, &calls[i])
+				}
+				if !opts.dryRun {
+					n, err = appStore.UpdateCallDestinations(ctx, ids, countries, groups)
+				}
+			case "business_hours":
+				flags := make([]*bool, len(calls))
+				for i := range calls {
+					flags[i] = recomputeBusinessHours(calendars, &calls[i])
+				}
+				if !opts.dryRun {
+					n, err = appStore.UpdateCallBusinessHours(ctx, ids, flags)
+				}
+			case "durations":
+				billsecs := make([]*int64, len(calls))
+				durations := make([]*int64, len(calls))
+				for i := range calls {
+					billsecs[i], durations[i] = recomputeDurations(&calls[i])
+				}
+				if !opts.dryRun {
+					n, err = appStore.UpdateCallDurations(ctx, ids, billsecs, durations)
+				}
+			case "outcome":
+				outcomes := make([]*string, len(calls))
+				for i := range calls {
+					outcomes[i] = recomputeOutcome(outcomeRules, &calls[i], logger)
+				}
+				if !opts.dryRun {
+					n, err = appStore.UpdateCallOutcomes(ctx, ids, outcomes)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			updated += n
+		}
+		processed += int64(len(calls))
+
+		logger.WithFields(logrus.Fields{
+			"processed": processed,
+			"total":     total,
+			"percent":   fmt.Sprintf("%.1f", 100*float64(processed)/float64(max(total, 1))),
+			"updated":   updated,
+			"lastID":    lastID,
+			"rowsPerS":  fmt.Sprintf("%.0f", float64(processed)/time.Since(started).Seconds()),
+		}).Info("Recompute progress")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"processed": processed,
+		"updated":   updated,
+		"duration":  time.Since(started).String(),
+	}).Info("Recompute complete")
+	return nil
+}
+
+// recomputeCost derives the cost of a finished call the same way CHANNEL_HANGUP does at ingest time
+func recomputeCost(rater *rating.Rater, call *store.Call) *float64 {
+	if !rater.Enabled() {
+		return nil
+	}
+	var billsec int64
+	if call.Billsec != nil {
+		billsec = *call.Billsec // Finalized by FreeSWITCH at CHANNEL_HANGUP_COMPLETE
+	} else if call.AnsweredTime != nil && call.EndTime != nil && call.EndTime.After(*call.AnsweredTime) {
+		billsec = int64(call.EndTime.Sub(*call.AnsweredTime).Seconds())
+	}
+	cost := rater.Cost(billsec)
+	return &cost
+}
+
+// recomputeDestination classifies the callee the same way CHANNEL_CREATE does at ingest time
+func recomputeDestination(table *prefixes.Table, call *store.Call) (country, group *string) {
+	dest, ok := table.Lookup(call.Callee)
+	if !ok {
+		return nil, nil
+	}
+	country = &dest.Country
+	if dest.Group != "" {
+		group = &dest.Group
+	}
+	return country, group
+}
+
+// recomputeBusinessHours flags the call the same way CHANNEL_CREATE does at ingest time
+func recomputeBusinessHours(cals *calendar.Calendars, call *store.Call) *bool {
+	var domain string
+	if call.Domain != nil {
+		domain = *call.Domain
+	}
+	in, ok := cals.InHours(domain, call.StartTime)
+	if !ok {
+		return nil
+	}
+	return &in
+}
+
+// recomputeDurations derives the billable and total seconds of a finished call from its timestamps, for calls
+// whose CHANNEL_HANGUP_COMPLETE figures were never recorded; it returns nils for the others
+func recomputeDurations(call *store.Call) (billsec, duration *int64) {
+	if call.Billsec != nil || call.EndTime == nil {
+		return nil, nil
+	}
+	total := int64(max(call.EndTime.Sub(call.StartTime).Seconds(), 0))
+	var billed int64
+	if call.AnsweredTime != nil && call.EndTime.After(*call.AnsweredTime) {
+		billed = int64(call.EndTime.Sub(*call.AnsweredTime).Seconds())
+	}
+	return &billed, &total
+}
+
+// recomputeOutcome labels the call with the outcome rules the same way the classifier does
+func recomputeOutcome(rules *outcome.Rules, call *store.Call, logger *logrus.Logger) *string {
+	label, ok, errs := rules.Classify(call)
+	for _, err := range errs {
+		logger.WithError(err).WithField("uuid", call.UUID).Warn("Outcome rule failed")
+	}
+	if !ok {
+		return nil
+	}
+	return &label
+}
@@ -0,0 +1,139 @@
+// Package retention enforces the tiered retention of stored events: raw ESL events are kept in full for a
+// short time, then summarized per call into call_events, which outlive them; call records are kept longest.
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// batchSize is the number of rows removed per statement, keeping each transaction short
+const batchSize = 5000
+
+// Retention tiers, as reported in Report and the retention_deleted_total metric
+const (
+	TierRawEvents  = "raw_events"
+	TierCallEvents = "call_events"
+	TierCalls      = "calls"
+)
+
+var deletedCounter = metrics.NewCounterVec("retention_deleted_total", "Rows removed by retention, by tier.", "tier")
+
+// Policy configures how long each tier is kept. A zero age keeps the tier forever.
+type Policy struct {
+	Interval   time.Duration // How often retention runs; 0 disables the schedule
+	RawEvents  time.Duration // Raw events older than this are summarized into call_events and removed
+	CallEvents time.Duration // Event summaries whose last event is older than this are removed
+	Calls      time.Duration // Calls started longer ago than this are removed with everything keyed by them
+}
+
+// Report is the outcome of one retention run
+type Report struct {
+	RanAt   time.Time         `json:"ran_at"`
+	Deleted map[string]int64  `json:"deleted"` // Rows removed per tier
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// Enforcer applies a retention Policy on a schedule
+type Enforcer struct {
+	store  *store.Store
+	policy Policy
+	log    *logrus.Logger
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewEnforcer creates a new Enforcer
+func NewEnforcer(s *store.Store, policy Policy, logger *logrus.Logger) *Enforcer {
+	return &Enforcer{store: s, policy: policy, log: logger}
+}
+
+// Policy returns the configured policy
+func (e *Enforcer) Policy() Policy {
+	return e.policy
+}
+
+// Run enforces the policy every policy.Interval until ctx is cancelled. It returns at once if the schedule
+// is disabled.
+func (e *Enforcer) Run(ctx context.Context) {
+	if e.policy.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(e.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Enforce(ctx)
+		}
+	}
+}
+
+// Last returns the report of the most recent run, or nil if retention has not run yet
+func (e *Enforcer) Last() *Report {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.last
+}
+
+// Enforce applies every tier now, from the most detailed to the least. A failing tier is reported and does
+// not stop the others.
+func (e *Enforcer) Enforce(ctx context.Context) *Report {
+	now := time.Now().UTC()
+	report := &Report{RanAt: now, Deleted: make(map[string]int64)}
+	tiers := []struct {
+		name  string
+		age   time.Duration
+		prune func(context.Context, time.Time, int) (int64, error)
+	}{
+		{TierRawEvents, e.policy.RawEvents, e.store.SummarizeRawEvents},
+		{TierCallEvents, e.policy.CallEvents, e.store.PruneCallEvents},
+		{TierCalls, e.policy.Calls, e.store.PruneCalls},
+	}
+	for _, t := range tiers {
+		if t.age <= 0 {
+			continue
+		}
+		deleted, err := drain(ctx, now.Add(-t.age), t.prune)
+		report.Deleted[t.name] = deleted
+		deletedCounter.With(t.name).Add(float64(deleted))
+		if err != nil {
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+			report.Errors[t.name] = err.Error()
+			e.log.WithError(err).WithField("tier", t.name).Error("Retention failed")
+		}
+	}
+
+	e.mu.Lock()
+	e.last = report
+	e.mu.Unlock()
+	return report
+}
+
+// drain calls prune in batches until fewer than batchSize rows are removed or ctx is cancelled
+func drain(ctx context.Context, before time.Time, prune func(context.Context, time.Time, int) (int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := prune(ctx, before, batchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if deleted < batchSize {
+			return total, nil
+		}
+	}
+	return total, ctx.Err()
+}
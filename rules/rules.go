@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Rule sets a derived field on the call record when its condition holds for an event.
+// Conditions and value expressions use expr-lang syntax (https://expr-lang.org), e.g.
+// `callee matches "^1[0-9]{3}$"` or `vars["sip_h_X-Campaign"] != ""`.
+type Rule struct {
+	Field     string   `json:"field"`
+	When      string   `json:"when"`                 // Boolean expression; empty always matches
+	Value     string   `json:"value,omitempty"`      // Literal value to set
+	ValueExpr string   `json:"value_expr,omitempty"` // Expression whose result is set instead of Value
+	Events    []string `json:"events,omitempty"`     // Events the rule applies to; default CHANNEL_CREATE
+}
+
+// compiledRule is a Rule with its expressions compiled
+type compiledRule struct {
+	Rule
+	when   *vm.Program
+	value  *vm.Program
+	events map[string]bool
+}
+
+// Engine evaluates derived-field rules against ESL events
+type Engine struct {
+	rules []compiledRule
+}
+
+// envFields are the call attributes exposed to expressions, mapped to the event header they are read from
+var envFields = map[string]string{
+	"uuid":         "Unique-ID",
+	"direction":    "Call-Direction",
+	"caller":       "Caller-Caller-ID-Number",
+	"caller_name":  "Caller-Caller-ID-Name",
+	"callee":       "Caller-Destination-Number",
+	"context":      "Caller-Context",
+	"domain":       "variable_domain_name",
+	"gateway":      "variable_sip_gateway_name",
+	"account_code": "variable_accountcode",
+	"user_id":      "variable_user_id",
+	"sip_profile":  "variable_sofia_profile_name",
+	"hangup_cause": "Hangup-Cause",
+}
+
+// newEnv returns the expression environment for an event. Missing values are empty strings.
+func newEnv(eventName string, headers map[string]string) map[string]any {
+	env := make(map[string]any, len(envFields)+3)
+	for name, header := range envFields {
+		env[name] = headers[header]
+	}
+	vars := make(map[string]string)
+	for k, v := range headers {
+		if name, ok := strings.CutPrefix(k, "variable_"); ok {
+			vars[name] = v
+		}
+	}
+	env["event"] = eventName
+	env["vars"] = vars
+	env["headers"] = headers
+	return env
+}
+
+// Load reads a JSON array of rules from path and compiles them. Rules are applied in file order;
+// when several rules set the same field for an event, the last match wins.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defs []Rule
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	return New(defs)
+}
+
+// New compiles rules into an Engine
+func New(defs []Rule) (*Engine, error) {
+	env := newEnv("", map[string]string{})
+	e := &Engine{}
+	for i, def := range defs {
+		if def.Field == "" {
+			return nil, fmt.Errorf("rule %d: field is required", i+1)
+		}
+		r := compiledRule{Rule: def, events: make(map[string]bool)}
+		if def.When != "" {
+			program, err := expr.Compile(def.When, expr.Env(env), expr.AsBool())
+			if err != nil {
+				return nil, fmt.Errorf("rule %d (%s): when: %w", i+1, def.Field, err)
+			}
+			r.when = program
+		}
+		if def.ValueExpr != "" {
+			program, err := expr.Compile(def.ValueExpr, expr.Env(env))
+			if err != nil {
+				return nil, fmt.Errorf("rule %d (%s): value_expr: %w", i+1, def.Field, err)
+			}
+			r.value = program
+		}
+		events := def.Events
+		if len(events) == 0 {
+			events = []string{"CHANNEL_CREATE"}
+		}
+		for _, name := range events {
+			r.events[name] = true
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Enabled reports whether any rules are loaded
+func (e *Engine) Enabled() bool {
+	return e != nil && len(e.rules) > 0
+}
+
+// Evaluate returns the derived fields set by the rules matching an event, or nil if none match.
+// Rules that fail at run time are skipped and reported in errs.
+func (e *Engine) Evaluate(eventName string, headers map[string]string) (fields map[string]string, errs []error) {
+	if !e.Enabled() {
+		return nil, nil
+	}
+	var env map[string]any
+	for _, r := range e.rules {
+		if !r.events[eventName] {
+			continue
+		}
+		if env == nil {
+			env = newEnv(eventName, headers)
+		}
+		if r.when != nil {
+			out, err := expr.Run(r.when, env)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.Field, err))
+				continue
+			}
+			if matched, _ := out.(bool); !matched {
+				continue
+			}
+		}
+		value := r.Value
+		if r.value != nil {
+			out, err := expr.Run(r.value, env)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.Field, err))
+				continue
+			}
+			value = fmt.Sprint(out)
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[r.Field] = value
+	}
+	return fields, errs
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Application is one dialplan application run on a call, from CHANNEL_EXECUTE and CHANNEL_EXECUTE_COMPLETE
+type Application struct {
+	ID          int64      `json:"id"`
+	UUID        string     `json:"uuid"`
+	AppUUID     *string    `json:"application_uuid,omitempty"` // Application-UUID, pairing the start and completion events
+	Name        string     `json:"application"`
+	Data        *string    `json:"data,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Response    *string    `json:"response,omitempty"` // Application-Response, e.g. _none_ or an error
+}
+
+// StartApplication records the start of an application run
+func (s *Store) StartApplication(ctx context.Context, a *Application) error {
+	return s.write(ctx, writeOp{
+		name: "start_application",
+		uuid: a.UUID,
+		query: `
+			INSERT INTO call_applications (uuid, app_uuid, application, data, started_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (uuid, app_uuid) DO UPDATE SET started_at = EXCLUDED.started_at`,
+		args: []any{a.UUID, a.AppUUID, a.Name, a.Data, a.StartedAt},
+	})
+}
+
+// CompleteApplication records the completion of an application run. Runs without an Application-UUID
+// cannot be paired and are stored as a separate row.
+func (s *Store) CompleteApplication(ctx context.Context, a *Application) error {
+	return s.write(ctx, writeOp{
+		name: "complete_application",
+		uuid: a.UUID,
+		query: `
+			INSERT INTO call_applications (uuid, app_uuid, application, data, completed_at, response)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (uuid, app_uuid) DO UPDATE
+			SET completed_at = EXCLUDED.completed_at, response = EXCLUDED.response`,
+		args: []any{a.UUID, a.AppUUID, a.Name, a.Data, a.CompletedAt, a.Response},
+	})
+}
+
+// GetApplications returns the applications run on a call in execution order
+func (s *Store) GetApplications(ctx context.Context, uuid string) ([]Application, error) {
+	query := `
+		SELECT id, uuid, app_uuid, application, data, started_at, completed_at, response
+		FROM call_applications
+		WHERE uuid = $1
+		ORDER BY COALESCE(started_at, completed_at), id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call applications")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []Application
+	for rows.Next() {
+		var a Application
+		if err := rows.Scan(&a.ID, &a.UUID, &a.AppUUID, &a.Name, &a.Data, &a.StartedAt, &a.CompletedAt, &a.Response); err != nil {
+			s.log.WithError(err).Error("Error scanning call application row")
+			return nil, err
+		}
+		apps = append(apps, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call application rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(apps),
+	}).Info("Retrieved call applications")
+	return apps, nil
+}
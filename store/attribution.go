@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCallerIDCampaignNotFound is returned when a caller ID is not mapped to a campaign
+var ErrCallerIDCampaignNotFound = errors.New("caller ID campaign not found")
+
+// CallerIDCampaign maps an outbound caller ID to the marketing campaign that presents it. Outbound calls
+// from the number and inbound calls to it (callbacks) are attributed to the campaign.
+type CallerIDCampaign struct {
+	CallerID  string    `json:"caller_id"` // Digits only
+	Campaign  string    `json:"campaign"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CampaignAttributionStats summarizes the calls attributed to one caller-ID campaign
+type CampaignAttributionStats struct {
+	Campaign          string  `json:"campaign"`
+	OutboundCalls     int64   `json:"outbound_calls"`
+	OutboundAnswered  int64   `json:"outbound_answered"`
+	Callbacks         int64   `json:"callbacks"` // Inbound calls to one of the campaign's caller IDs
+	CallbacksAnswered int64   `json:"callbacks_answered"`
+	UniqueCallers     int64   `json:"unique_callers"` // Distinct numbers that called back
+	CallbackRate      float64 `json:"callback_rate"`  // Callbacks per answered outbound call
+	TotalBillable     float64 `json:"total_billable_seconds"`
+	TotalCost         float64 `json:"total_cost"`
+}
+
+// NormalizeCallerID reduces a phone number to its digits, so "+1 (555) 123-0000" and "15551230000" match
+func NormalizeCallerID(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+// SetCallerIDCampaign maps callerID to campaign, replacing any existing mapping. Calls already recorded
+// keep the campaign they were stamped with.
+func (s *Store) SetCallerIDCampaign(ctx context.Context, m *CallerIDCampaign) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	m.CallerID = NormalizeCallerID(m.CallerID)
+	err := s.db.QueryRow(ctxTimeout, `
+		INSERT INTO caller_id_campaigns (caller_id, campaign)
+		VALUES ($1, $2)
+		ON CONFLICT (caller_id) DO UPDATE SET campaign = EXCLUDED.campaign, updated_at = now()
+		RETURNING created_at, updated_at`,
+		m.CallerID, m.Campaign,
+	).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		s.log.WithError(err).WithField("callerID", m.CallerID).Error("Error saving caller ID campaign")
+		return err
+	}
+	return nil
+}
+
+// GetCallerIDCampaigns returns every caller ID campaign mapping by caller ID
+func (s *Store) GetCallerIDCampaigns(ctx context.Context) ([]CallerIDCampaign, error) {
+	query := `
+		SELECT caller_id, campaign, created_at, updated_at
+		FROM caller_id_campaigns
+		ORDER BY caller_id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting caller ID campaigns")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []CallerIDCampaign
+	for rows.Next() {
+		var m CallerIDCampaign
+		if err := rows.Scan(&m.CallerID, &m.Campaign, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning caller ID campaign row")
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating caller ID campaign rows")
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// DeleteCallerIDCampaign removes the mapping of callerID
+func (s *Store) DeleteCallerIDCampaign(ctx context.Context, callerID string) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctxTimeout, `DELETE FROM caller_id_campaigns WHERE caller_id = $1`, NormalizeCallerID(callerID))
+	if err != nil {
+		s.log.WithError(err).WithField("callerID", callerID).Error("Error deleting caller ID campaign")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCallerIDCampaignNotFound
+	}
+	return nil
+}
+
+// GetCampaignAttributionStats returns per-campaign outbound and callback figures for calls started in [from, to)
+func (s *Store) GetCampaignAttributionStats(ctx context.Context, from, to time.Time) ([]CampaignAttributionStats, error) {
+	query := `
+		SELECT caller_id_campaign,
+			COUNT(*) FILTER (WHERE direction = 'outbound'),
+			COUNT(answered_time) FILTER (WHERE direction = 'outbound'),
+			COUNT(*) FILTER (WHERE direction = 'inbound'),
+			COUNT(answered_time) FILTER (WHERE direction = 'inbound'),
+			COUNT(DISTINCT caller) FILTER (WHERE direction = 'inbound'),
+			COALESCE(SUM(COALESCE(billsec, EXTRACT(EPOCH FROM (end_time - answered_time)))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(SUM(cost), 0)::float8
+		FROM calls
+		WHERE caller_id_campaign IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY 1
+		ORDER BY 1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting campaign attribution stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []CampaignAttributionStats
+	for rows.Next() {
+		var cs CampaignAttributionStats
+		if err := rows.Scan(&cs.Campaign, &cs.OutboundCalls, &cs.OutboundAnswered, &cs.Callbacks, &cs.CallbacksAnswered,
+			&cs.UniqueCallers, &cs.TotalBillable, &cs.TotalCost); err != nil {
+			s.log.WithError(err).Error("Error scanning campaign attribution stats row")
+			return nil, err
+		}
+		if cs.OutboundAnswered > 0 {
+			cs.CallbackRate = float64(cs.Callbacks) / float64(cs.OutboundAnswered)
+		}
+		stats = append(stats, cs)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating campaign attribution stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(stats),
+	}).Info("Retrieved campaign attribution stats")
+	return stats, nil
+}
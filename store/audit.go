@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuditEntry records an operator action taken through the API
+type AuditEntry struct {
+	ID         int64          `json:"id"`
+	Action     string         `json:"action"`
+	Actor      string         `json:"actor"` // Role and key fingerprint of the caller, never the key itself
+	ClientIP   string         `json:"client_ip"`
+	Target     string         `json:"target"` // Usually the call UUID acted on
+	Details    map[string]any `json:"details,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// AddAuditEntry appends an entry to the audit log
+func (s *Store) AddAuditEntry(ctx context.Context, e *AuditEntry) error {
+	return s.write(ctx, writeOp{
+		name: "add_audit_entry",
+		uuid: e.Target,
+		query: `
+			INSERT INTO audit_log (action, actor, client_ip, target, details, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+		args: []any{e.Action, e.Actor, e.ClientIP, e.Target, e.Details, e.OccurredAt},
+	})
+}
+
+// GetAuditLog returns audit entries, newest first. An empty action or target matches all entries.
+func (s *Store) GetAuditLog(ctx context.Context, action, target string, limit, offset int) ([]AuditEntry, error) {
+	query := `
+		SELECT id, action, actor, client_ip, target, details, occurred_at
+		FROM audit_log
+		WHERE ($1 = '' OR action = $1) AND ($2 = '' OR target = $2)
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, action, target, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting audit log")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Actor, &e.ClientIP, &e.Target, &e.Details, &e.OccurredAt); err != nil {
+			s.log.WithError(err).Error("Error scanning audit log row")
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating audit log rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"action": action,
+		"target": target,
+		"limit":  limit,
+		"count":  len(entries),
+	}).Info("Retrieved audit log")
+	return entries, nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned for writes attempted while the database circuit breaker is open
+var ErrCircuitOpen = errors.New("database circuit breaker open")
+
+var breakerOpenGauge = metrics.NewGaugeVec("store_circuit_open", "Whether the database circuit breaker is open (1) or closed (0).")
+
+// Breaker is a database circuit breaker. It opens after threshold consecutive connectivity
+// failures and closes again once a health probe succeeds after the cooldown.
+// Errors reported by PostgreSQL itself (constraint violations etc.) do not count as failures.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+}
+
+// NewBreaker creates a Breaker. A threshold below one disables the breaker.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Open reports whether the breaker is open
+func (b *Breaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// record updates the breaker with the outcome of a database operation and reports whether its state changed
+func (b *Breaker) record(err error) (changed bool) {
+	if b == nil || b.threshold < 1 {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if err != nil && errors.As(err, &pgErr) {
+		return false // The server answered; connectivity is fine
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		if b.open {
+			b.open = false
+			breakerOpenGauge.With().Set(0)
+			return true
+		}
+		return false
+	}
+
+	b.failures++
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+		breakerOpenGauge.With().Set(1)
+		return true
+	}
+	if b.open {
+		b.openedAt = time.Now() // A failed probe restarts the cooldown
+	}
+	return false
+}
+
+// probeDue reports whether the breaker is open and its cooldown has elapsed
+func (b *Breaker) probeDue() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open && time.Since(b.openedAt) >= b.cooldown
+}
+
+// CircuitOpen reports whether ingest writes are currently being refused
+func (s *Store) CircuitOpen() bool {
+	return s.breaker.Open()
+}
+
+// recordResult feeds an operation result into the breaker, logging state changes
+func (s *Store) recordResult(err error) {
+	if !s.breaker.record(err) {
+		return
+	}
+	t := HealthTransition{Component: ComponentDatabase, State: HealthUp, OccurredAt: time.Now()}
+	if s.breaker.Open() {
+		s.log.WithError(err).Error("Database circuit breaker opened, ingest writes suspended")
+		reason := err.Error()
+		t.State, t.Reason = HealthDown, &reason
+	} else {
+		s.log.Info("Database circuit breaker closed, ingest writes resumed")
+	}
+	go s.RecordHealth(context.Background(), t) // Queued until the database accepts it
+}
+
+// RunHealthProbe pings the database while the breaker is open so it can close without live traffic.
+// It is meant to run in its own goroutine until ctx is cancelled.
+func (s *Store) RunHealthProbe(ctx context.Context, interval time.Duration) {
+	if s.breaker == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.breaker.probeDue() {
+				continue
+			}
+			pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+			err := s.db.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				s.log.WithError(err).WithFields(logrus.Fields{"probe": "ping"}).Warn("Database health probe failed")
+			}
+			s.recordResult(err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BusyHourStats is the busiest clock hour of one day and the day's totals. Traffic is measured in Erlangs:
+// channel-seconds occupied during the hour divided by 3600, counted from seizure (start) to hangup.
+type BusyHourStats struct {
+	Date             string    `json:"date"` // YYYY-MM-DD in the requested time zone
+	BusyHourStart    time.Time `json:"busy_hour_start"`
+	BusyHourAttempts int64     `json:"busy_hour_call_attempts"` // BHCA: calls started during the busy hour
+	BusyHourErlangs  float64   `json:"busy_hour_erlangs"`
+	PeakHourAttempts int64     `json:"peak_hour_call_attempts"` // Most calls started in any hour of the day
+	CallAttempts     int64     `json:"call_attempts"`
+	ErlangHours      float64   `json:"erlang_hours"` // Total traffic carried over the day
+}
+
+// GetBusyHourStats returns the busy hour of each day in [from, to), bucketed by clock hours in time zone tz.
+// Calls still in progress count up to now. If gateway is non-empty only calls on that gateway are counted.
+func (s *Store) GetBusyHourStats(ctx context.Context, from, to time.Time, tz, gateway string) ([]BusyHourStats, error) {
+	query := `
+		WITH hours AS (
+			SELECT h AS hour_start, h + interval '1 hour' AS hour_end
+			FROM generate_series(date_trunc('hour', $1::timestamptz AT TIME ZONE $3), $2::timestamptz AT TIME ZONE $3, interval '1 hour') AS h
+			WHERE h < $2::timestamptz AT TIME ZONE $3
+		),
+		occupancy AS (
+			SELECT start_time AT TIME ZONE $3 AS seized, COALESCE(end_time, now()) AT TIME ZONE $3 AS released
+			FROM calls
+			WHERE start_time < $2 AND COALESCE(end_time, now()) > $1 AND ($4 = '' OR gateway = $4)
+		),
+		hourly AS (
+			SELECT h.hour_start,
+				COUNT(o.seized) FILTER (WHERE o.seized >= h.hour_start) AS attempts,
+				COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(o.released, h.hour_end) - GREATEST(o.seized, h.hour_start)))), 0)::float8 / 3600 AS erlangs
+			FROM hours h
+			LEFT JOIN occupancy o ON o.seized < h.hour_end AND o.released > h.hour_start
+			GROUP BY h.hour_start
+		),
+		ranked AS (
+			SELECT hour_start, attempts, erlangs,
+				date_trunc('day', hour_start) AS day,
+				ROW_NUMBER() OVER (PARTITION BY date_trunc('day', hour_start) ORDER BY erlangs DESC, attempts DESC, hour_start) AS rank,
+				MAX(attempts) OVER (PARTITION BY date_trunc('day', hour_start)) AS peak_attempts,
+				SUM(attempts) OVER (PARTITION BY date_trunc('day', hour_start)) AS day_attempts,
+				SUM(erlangs) OVER (PARTITION BY date_trunc('day', hour_start)) AS day_erlangs
+			FROM hourly
+		)
+		SELECT to_char(day, 'YYYY-MM-DD'), hour_start AT TIME ZONE $3, attempts, erlangs,
+			peak_attempts, day_attempts::bigint, day_erlangs
+		FROM ranked
+		WHERE rank = 1
+		ORDER BY day`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, tz, gateway)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting busy hour stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []BusyHourStats
+	for rows.Next() {
+		var bh BusyHourStats
+		if err := rows.Scan(&bh.Date, &bh.BusyHourStart, &bh.BusyHourAttempts, &bh.BusyHourErlangs,
+			&bh.PeakHourAttempts, &bh.CallAttempts, &bh.ErlangHours); err != nil {
+			s.log.WithError(err).Error("Error scanning busy hour stats row")
+			return nil, err
+		}
+		stats = append(stats, bh)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating busy hour stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":    from,
+		"to":      to,
+		"gateway": gateway,
+		"days":    len(stats),
+	}).Info("Retrieved busy hour stats")
+	return stats, nil
+}
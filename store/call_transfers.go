@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Transfer kinds
+const (
+	TransferBlind    = "blind"    // REFER without Replaces: the transferee is sent to a new destination
+	TransferAttended = "attended" // REFER with Replaces: the transferee replaces the transferor's consultation call
+)
+
+// Transfer is a SIP transfer reported by sofia::transferor
+type Transfer struct {
+	ID             int64     `json:"id"`
+	TransferorUUID string    `json:"transferor_uuid"`           // Channel that sent the REFER
+	TransfereeUUID *string   `json:"transferee_uuid,omitempty"` // Channel that was moved
+	TargetUUID     *string   `json:"target_uuid,omitempty"`     // Consultation leg the transferee was connected to (attended only)
+	Kind           string    `json:"kind"`
+	Destination    *string   `json:"destination,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// AddTransfer records a transfer and marks the transferred call with its new destination and the
+// transferor. A call transferred several times keeps the last destination.
+func (s *Store) AddTransfer(ctx context.Context, t *Transfer) error {
+	return s.write(ctx, writeOp{
+		name: "add_transfer",
+		uuid: t.TransferorUUID,
+		query: `
+			WITH added AS (
+				INSERT INTO call_transfers (transferor_uuid, transferee_uuid, target_uuid, kind, destination, occurred_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING transferee_uuid
+			)
+			UPDATE calls SET transferred_to = COALESCE($5, transferred_to), transferred_by = $1
+			FROM added
+			WHERE calls.uuid = added.transferee_uuid`,
+		args: []any{t.TransferorUUID, t.TransfereeUUID, t.TargetUUID, t.Kind, t.Destination, t.OccurredAt},
+	})
+}
+
+// SetCallTransferred records the destination a call was transferred to, as reported on the transferee's
+// own channel. It fills in calls whose transferor event did not name the transferee.
+func (s *Store) SetCallTransferred(ctx context.Context, uuid, destination string) error {
+	return s.write(ctx, writeOp{
+		name:  "set_call_transferred",
+		uuid:  uuid,
+		query: `UPDATE calls SET transferred_to = $2 WHERE uuid = $1`,
+		args:  []any{uuid, destination},
+	})
+}
+
+// GetTransfers returns the transfers a call took part in as transferor, transferee or target, in order
+func (s *Store) GetTransfers(ctx context.Context, uuid string) ([]Transfer, error) {
+	query := `
+		SELECT id, transferor_uuid, transferee_uuid, target_uuid, kind, destination, occurred_at
+		FROM call_transfers
+		WHERE transferor_uuid = $1 OR transferee_uuid = $1 OR target_uuid = $1
+		ORDER BY occurred_at, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call transfers")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []Transfer
+	for rows.Next() {
+		var t Transfer
+		if err := rows.Scan(&t.ID, &t.TransferorUUID, &t.TransfereeUUID, &t.TargetUUID, &t.Kind, &t.Destination, &t.OccurredAt); err != nil {
+			s.log.WithError(err).Error("Error scanning call transfer row")
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call transfer rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(transfers),
+	}).Info("Retrieved call transfers")
+	return transfers, nil
+}
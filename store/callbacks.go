@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Callback statuses
+const (
+	CallbackOffered    = "offered"     // The caller was offered a callback
+	CallbackAccepted   = "accepted"    // The caller asked to be called back
+	CallbackCalledBack = "called_back" // An outbound call to the caller was placed
+)
+
+// ErrCallbackNotFound is returned when a call has no callback request
+var ErrCallbackNotFound = errors.New("callback not found")
+
+// Callback tracks a callback offered on, or requested from, an inbound call and the outbound call that
+// followed it
+type Callback struct {
+	ID                 int64      `json:"id"`
+	OriginalUUID       string     `json:"original_uuid"`
+	Number             string     `json:"number"`
+	Queue              *string    `json:"queue,omitempty"`
+	Status             string     `json:"status"`
+	OfferedAt          *time.Time `json:"offered_at,omitempty"`
+	AcceptedAt         *time.Time `json:"accepted_at,omitempty"`
+	CallbackUUID       *string    `json:"callback_uuid,omitempty"`
+	CallbackStartedAt  *time.Time `json:"callback_started_at,omitempty"`
+	CallbackAnsweredAt *time.Time `json:"callback_answered_at,omitempty"` // From the callback's call record
+}
+
+// RecordCallbackOffer stores that a callback was offered on the original call
+func (s *Store) RecordCallbackOffer(ctx context.Context, originalUUID, number string, queue *string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "record_callback_offer",
+		uuid: originalUUID,
+		query: `
+			INSERT INTO callbacks (original_uuid, number, queue, status, offered_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (original_uuid) DO UPDATE SET
+				offered_at = COALESCE(callbacks.offered_at, EXCLUDED.offered_at),
+				queue = COALESCE(callbacks.queue, EXCLUDED.queue)`,
+		args: []any{originalUUID, s.sealNumber(number), queue, CallbackOffered, at},
+	})
+}
+
+// RecordCallbackAcceptance stores that the caller of the original call accepted a callback to number
+func (s *Store) RecordCallbackAcceptance(ctx context.Context, originalUUID, number string, queue *string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "record_callback_acceptance",
+		uuid: originalUUID,
+		query: `
+			INSERT INTO callbacks (original_uuid, number, queue, status, accepted_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (original_uuid) DO UPDATE SET
+				number = EXCLUDED.number,
+				queue = COALESCE(EXCLUDED.queue, callbacks.queue),
+				status = CASE WHEN callbacks.status = $6 THEN callbacks.status ELSE EXCLUDED.status END,
+				accepted_at = EXCLUDED.accepted_at`,
+		args: []any{originalUUID, s.sealNumber(number), queue, CallbackAccepted, at, CallbackCalledBack},
+	})
+}
+
+// LinkCallbackCall links an outbound call to the callback it fulfils. With originalUUID set (the call
+// carried callback_of) that callback is linked; otherwise the oldest accepted, unlinked callback to number
+// accepted within window before startedAt is.
+func (s *Store) LinkCallbackCall(ctx context.Context, callbackUUID, originalUUID, number string, startedAt time.Time, window time.Duration) error {
+	return s.write(ctx, writeOp{
+		name: "link_callback_call",
+		uuid: callbackUUID,
+		query: `
+			UPDATE callbacks
+			SET status = $1, callback_uuid = $2, callback_started_at = $3
+			WHERE id = (
+				SELECT id FROM callbacks
+				WHERE callback_uuid IS NULL AND (
+					original_uuid = $4 OR
+					($4 = '' AND number = $5 AND status = $6 AND accepted_at BETWEEN $3::timestamptz - $7::float8 * interval '1 second' AND $3)
+				)
+				ORDER BY accepted_at
+				LIMIT 1
+			)`,
+		args: []any{CallbackCalledBack, callbackUUID, startedAt, originalUUID, s.sealNumber(number), CallbackAccepted, window.Seconds()},
+	})
+}
+
+// callbackColumns lists the callbacks columns read by scanCallback, joined with the callback's call as c
+const callbackColumns = `cb.id, cb.original_uuid, cb.number, cb.queue, cb.status, cb.offered_at, cb.accepted_at,
+	cb.callback_uuid, cb.callback_started_at, c.answered_time`
+
+func scanCallback(row pgx.Row, cb *Callback) error {
+	return row.Scan(&cb.ID, &cb.OriginalUUID, &cb.Number, &cb.Queue, &cb.Status, &cb.OfferedAt, &cb.AcceptedAt,
+		&cb.CallbackUUID, &cb.CallbackStartedAt, &cb.CallbackAnsweredAt)
+}
+
+// GetCallbackForCall returns the callback requested on the original call, or ErrCallbackNotFound
+func (s *Store) GetCallbackForCall(ctx context.Context, originalUUID string) (*Callback, error) {
+	query := `
+		SELECT ` + callbackColumns + `
+		FROM callbacks cb
+		LEFT JOIN calls c ON c.uuid = cb.callback_uuid
+		WHERE cb.original_uuid = $1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var cb Callback
+	err := scanCallback(s.db.QueryRow(ctxTimeout, query, originalUUID), &cb)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCallbackNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", originalUUID).Error("Error getting callback")
+		return nil, err
+	}
+	cb.Number = s.openField(ctx, cb.Number)
+	return &cb, nil
+}
+
+// GetCallbacks returns callbacks offered or accepted in [from, to), newest first. An empty status
+// matches every status.
+func (s *Store) GetCallbacks(ctx context.Context, status string, from, to time.Time, limit, offset int) ([]Callback, error) {
+	query := `
+		SELECT ` + callbackColumns + `
+		FROM callbacks cb
+		LEFT JOIN calls c ON c.uuid = cb.callback_uuid
+		WHERE COALESCE(cb.accepted_at, cb.offered_at) >= $1 AND COALESCE(cb.accepted_at, cb.offered_at) < $2
+			AND ($3 = '' OR cb.status = $3)
+		ORDER BY COALESCE(cb.accepted_at, cb.offered_at) DESC, cb.id DESC
+		LIMIT $4 OFFSET $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, status, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting callbacks")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var callbacks []Callback
+	for rows.Next() {
+		var cb Callback
+		if err := scanCallback(rows, &cb); err != nil {
+			s.log.WithError(err).Error("Error scanning callback row")
+			return nil, err
+		}
+		cb.Number = s.openField(ctx, cb.Number)
+		callbacks = append(callbacks, cb)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating callback rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"status": status,
+		"count":  len(callbacks),
+	}).Info("Retrieved callbacks")
+	return callbacks, nil
+}
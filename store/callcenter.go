@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of agent transition recorded in agent_transitions
+const (
+	AgentStatus = "status" // Availability set by the agent or supervisor: Available, On Break, Logged Out...
+	AgentState  = "state"  // Work state driven by mod_callcenter: Waiting, Receiving, In a queue call, Idle
+)
+
+// agentColumns maps an agent transition kind to its columns in agents
+var agentColumns = map[string][2]string{
+	AgentStatus: {"status", "status_changed_at"},
+	AgentState:  {"state", "state_changed_at"},
+}
+
+// Agent is the current status and state of a mod_callcenter agent
+type Agent struct {
+	Name            string     `json:"name"`
+	Status          *string    `json:"status,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	State           *string    `json:"state,omitempty"`
+	StateChangedAt  *time.Time `json:"state_changed_at,omitempty"`
+}
+
+// AgentTransition is one change of an agent's status or state
+type AgentTransition struct {
+	ID        int64     `json:"id"`
+	Agent     string    `json:"agent"`
+	Kind      string    `json:"kind"`
+	FromValue *string   `json:"from_value,omitempty"`
+	ToValue   string    `json:"to_value"`
+	EventTime time.Time `json:"event_time"`
+}
+
+// Queue is the live view of a mod_callcenter queue
+type Queue struct {
+	Name          string     `json:"name"`
+	Waiting       int        `json:"waiting"`                  // Members waiting, counted from queue_calls
+	OldestJoined  *time.Time `json:"oldest_joined,omitempty"`  // Join time of the longest-waiting member
+	ReportedCount *int       `json:"reported_count,omitempty"` // Last members-count reported by mod_callcenter
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// SetAgentValue records an agent status or state change (kind is AgentStatus or AgentState). A transition
+// is only recorded when the value actually changes.
+func (s *Store) SetAgentValue(ctx context.Context, agent, kind, value string, at time.Time) error {
+	cols, ok := agentColumns[kind]
+	if !ok {
+		return fmt.Errorf("unknown agent transition kind %q", kind)
+	}
+	return s.write(ctx, writeOp{
+		name: "set_agent_" + kind,
+		uuid: agent,
+		query: `
+			WITH prev AS (
+				SELECT ` + cols[0] + ` AS value FROM agents WHERE name = $1
+			), upsert AS (
+				INSERT INTO agents (name, ` + cols[0] + `, ` + cols[1] + `)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (name) DO UPDATE SET ` + cols[0] + ` = EXCLUDED.` + cols[0] + `, ` + cols[1] + ` = EXCLUDED.` + cols[1] + `
+				WHERE agents.` + cols[0] + ` IS DISTINCT FROM EXCLUDED.` + cols[0] + `
+			)
+			INSERT INTO agent_transitions (agent, kind, from_value, to_value, event_time)
+			SELECT $1, $4, (SELECT value FROM prev), $2, $3
+			WHERE (SELECT value FROM prev) IS DISTINCT FROM $2`,
+		args: []any{agent, value, at, kind},
+	})
+}
+
+// JoinQueue records a member entering a queue. The row stays in outcome waiting until EndQueueCall or the
+// hangup-time RecordQueueCall settles it.
+func (s *Store) JoinQueue(ctx context.Context, qc *QueueCall) error {
+	return s.write(ctx, writeOp{
+		name: "join_queue",
+		uuid: qc.UUID,
+		query: `
+			INSERT INTO queue_calls (uuid, queue, joined_at, outcome, caller_number, caller_name)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (uuid) DO NOTHING`,
+		args: []any{qc.UUID, qc.Queue, qc.JoinedAt, QueueOutcomeWaiting, s.sealOptionalNumber(qc.CIDNumber), qc.CIDName},
+	})
+}
+
+// BridgeQueueCall records an agent answering a waiting member
+func (s *Store) BridgeQueueCall(ctx context.Context, uuid, agent string, answeredAt time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "bridge_queue_call",
+		uuid: uuid,
+		query: `
+			UPDATE queue_calls
+			SET agent = $2, answered_at = COALESCE(answered_at, $3)
+			WHERE uuid = $1`,
+		args:         []any{uuid, agent, answeredAt},
+		warnIfNoRows: true,
+	})
+}
+
+// EndQueueCall settles a member's wait when it leaves the queue
+func (s *Store) EndQueueCall(ctx context.Context, qc *QueueCall) error {
+	return s.write(ctx, writeOp{
+		name: "end_queue_call",
+		uuid: qc.UUID,
+		query: `
+			UPDATE queue_calls
+			SET outcome = $2, left_at = $3, agent = COALESCE($4, agent), answered_at = COALESCE($5, answered_at)
+			WHERE uuid = $1`,
+		args:         []any{qc.UUID, qc.Outcome, qc.LeftAt, qc.Agent, qc.AnsweredAt},
+		warnIfNoRows: true,
+	})
+}
+
+// SetQueueCount records the members-count mod_callcenter reports for a queue
+func (s *Store) SetQueueCount(ctx context.Context, queue string, count int, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "set_queue_count",
+		uuid: queue,
+		query: `
+			INSERT INTO queues (name, reported_count, updated_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE SET reported_count = EXCLUDED.reported_count, updated_at = EXCLUDED.updated_at
+			WHERE queues.updated_at <= EXCLUDED.updated_at`,
+		args: []any{queue, count, at},
+	})
+}
+
+// GetAgents returns the current status and state of every agent seen, by name
+func (s *Store) GetAgents(ctx context.Context) ([]Agent, error) {
+	query := `
+		SELECT name, status, status_changed_at, state, state_changed_at
+		FROM agents
+		ORDER BY name`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting agents")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []Agent
+	for rows.Next() {
+		var a Agent
+		if err := rows.Scan(&a.Name, &a.Status, &a.StatusChangedAt, &a.State, &a.StateChangedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning agent row")
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating agent rows")
+		return nil, err
+	}
+	return agents, nil
+}
+
+// GetAgentTransitions returns an agent's status and state changes in [from, to) in event order
+func (s *Store) GetAgentTransitions(ctx context.Context, agent string, from, to time.Time, limit, offset int) ([]AgentTransition, error) {
+	query := `
+		SELECT id, agent, kind, from_value, to_value, event_time
+		FROM agent_transitions
+		WHERE agent = $1 AND event_time >= $2 AND event_time < $3
+		ORDER BY event_time, id
+		LIMIT $4 OFFSET $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, agent, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).WithField("agent", agent).Error("Error getting agent transitions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []AgentTransition
+	for rows.Next() {
+		var t AgentTransition
+		if err := rows.Scan(&t.ID, &t.Agent, &t.Kind, &t.FromValue, &t.ToValue, &t.EventTime); err != nil {
+			s.log.WithError(err).Error("Error scanning agent transition row")
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating agent transition rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"agent": agent,
+		"count": len(transitions),
+	}).Info("Retrieved agent transitions")
+	return transitions, nil
+}
+
+// GetQueues returns every known queue with its waiting members
+func (s *Store) GetQueues(ctx context.Context) ([]Queue, error) {
+	query := `
+		WITH waiting AS (
+			SELECT queue, COUNT(*) AS waiting, MIN(joined_at) AS oldest
+			FROM queue_calls
+			WHERE outcome = 'waiting'
+			GROUP BY queue
+		)
+		SELECT COALESCE(q.name, w.queue), COALESCE(w.waiting, 0), w.oldest, q.reported_count, q.updated_at
+		FROM queues q
+		FULL JOIN waiting w ON w.queue = q.name
+		ORDER BY 1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting queues")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queues []Queue
+	for rows.Next() {
+		var q Queue
+		if err := rows.Scan(&q.Name, &q.Waiting, &q.OldestJoined, &q.ReportedCount, &q.UpdatedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning queue row")
+			return nil, err
+		}
+		queues = append(queues, q)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating queue rows")
+		return nil, err
+	}
+	return queues, nil
+}
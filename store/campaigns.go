@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCampaignNotFound is returned when a campaign id does not exist
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// Campaign statuses
+const (
+	CampaignRunning     = "running"
+	CampaignCompleted   = "completed"
+	CampaignCancelled   = "cancelled"
+	CampaignInterrupted = "interrupted" // The process stopped while the campaign was running
+)
+
+// Campaign attempt statuses
+const (
+	AttemptPending   = "pending"
+	AttemptDialing   = "dialing"
+	AttemptAnswered  = "answered"
+	AttemptFailed    = "failed"
+	AttemptCancelled = "cancelled"
+)
+
+// Campaign is a batch of outbound calls dialled with shared settings and pacing
+type Campaign struct {
+	ID               int64            `json:"id"`
+	Name             string           `json:"name"`
+	EndpointTemplate string           `json:"endpoint"` // Dial string with a {number} placeholder
+	Destination      string           `json:"destination,omitempty"`
+	CallerIDNumber   string           `json:"caller_id_number,omitempty"`
+	CallerIDName     string           `json:"caller_id_name,omitempty"`
+	Concurrency      int              `json:"concurrency"`
+	IntervalMS       int              `json:"interval_ms"`
+	TimeoutSeconds   int              `json:"timeout_seconds"`
+	Status           string           `json:"status"`
+	CreatedAt        time.Time        `json:"created_at"`
+	FinishedAt       *time.Time       `json:"finished_at,omitempty"`
+	AttemptCounts    map[string]int64 `json:"attempt_counts,omitempty"` // Attempts per status
+
+	// Retry policy: each number is dialled up to MaxAttempts times while it fails with one of RetryCauses
+	// (any cause when empty)
+	MaxAttempts          int      `json:"max_attempts"`
+	RetryIntervalSeconds int      `json:"retry_interval_seconds"`
+	RetryCauses          []string `json:"retry_causes"`
+	// Answer confirmation: calls only count as answered once the callee presses ConfirmKey
+	ConfirmKey            string `json:"confirm_key,omitempty"`
+	ConfirmPrompt         string `json:"confirm_prompt,omitempty"`
+	ConfirmTimeoutSeconds int    `json:"confirm_timeout_seconds,omitempty"`
+}
+
+// CampaignAttempt is a single dialled number of a campaign
+type CampaignAttempt struct {
+	ID         int64      `json:"id"`
+	CampaignID int64      `json:"campaign_id"`
+	Number     string     `json:"number"`
+	Status     string     `json:"status"`
+	CallUUID   *string    `json:"call_uuid,omitempty"`
+	Cause      *string    `json:"cause,omitempty"`
+	Tries      int        `json:"tries"` // Calls placed for the number; CallUUID is the last
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// campaignColumns is the column list shared by campaign queries
+const campaignColumns = `id, name, endpoint_template, destination, caller_id_number, caller_id_name,
+	concurrency, interval_ms, timeout_seconds, status, created_at, finished_at,
+	max_attempts, retry_interval_seconds, retry_causes, confirm_key, confirm_prompt, confirm_timeout_seconds`
+
+// scanCampaign scans a row selected with campaignColumns into c
+func scanCampaign(row pgx.Row, c *Campaign) error {
+	return row.Scan(&c.ID, &c.Name, &c.EndpointTemplate, &c.Destination, &c.CallerIDNumber, &c.CallerIDName,
+		&c.Concurrency, &c.IntervalMS, &c.TimeoutSeconds, &c.Status, &c.CreatedAt, &c.FinishedAt,
+		&c.MaxAttempts, &c.RetryIntervalSeconds, &c.RetryCauses, &c.ConfirmKey, &c.ConfirmPrompt, &c.ConfirmTimeoutSeconds)
+}
+
+// CreateCampaign inserts a running campaign with one pending attempt per number, setting c.ID and c.CreatedAt.
+// The attempts are returned in the order of numbers.
+func (s *Store) CreateCampaign(ctx context.Context, c *Campaign, numbers []string) ([]CampaignAttempt, error) {
+	sealed := make([]string, len(numbers))
+	for i, number := range numbers {
+		sealed[i] = s.sealNumber(number)
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctxTimeout)
+	if err != nil {
+		s.log.WithError(err).Error("Error starting campaign transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctxTimeout) // No-op after Commit
+
+	c.Status = CampaignRunning
+	err = tx.QueryRow(ctxTimeout, `
+		INSERT INTO campaigns (name, endpoint_template, destination, caller_id_number, caller_id_name,
+			concurrency, interval_ms, timeout_seconds, status,
+			max_attempts, retry_interval_seconds, retry_causes, confirm_key, confirm_prompt, confirm_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, '{}'::text[]), $13, $14, $15)
+		RETURNING id, created_at`,
+		c.Name, c.EndpointTemplate, c.Destination, c.CallerIDNumber, c.CallerIDName,
+		c.Concurrency, c.IntervalMS, c.TimeoutSeconds, c.Status,
+		c.MaxAttempts, c.RetryIntervalSeconds, c.RetryCauses, c.ConfirmKey, c.ConfirmPrompt, c.ConfirmTimeoutSeconds,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		s.log.WithError(err).Error("Error creating campaign")
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctxTimeout, `
+		INSERT INTO campaign_attempts (campaign_id, number, status)
+		SELECT $1, number, $2 FROM unnest($3::text[]) WITH ORDINALITY AS n(number, ord)
+		ORDER BY ord
+		RETURNING id, number`, c.ID, AttemptPending, sealed)
+	if err != nil {
+		s.log.WithError(err).Error("Error creating campaign attempts")
+		return nil, err
+	}
+	attempts := make([]CampaignAttempt, 0, len(numbers))
+	for rows.Next() {
+		a := CampaignAttempt{CampaignID: c.ID, Status: AttemptPending}
+		if err := rows.Scan(&a.ID, &a.Number); err != nil {
+			rows.Close()
+			s.log.WithError(err).Error("Error scanning campaign attempt row")
+			return nil, err
+		}
+		a.Number = s.openField(context.Background(), a.Number) // The dialer needs the number whoever created the campaign
+		attempts = append(attempts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating campaign attempt rows")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctxTimeout); err != nil {
+		s.log.WithError(err).Error("Error committing campaign")
+		return nil, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"campaignID": c.ID,
+		"name":       c.Name,
+		"attempts":   len(attempts),
+	}).Info("Campaign created")
+	return attempts, nil
+}
+
+// StartCampaignAttempt marks an attempt as dialing
+func (s *Store) StartCampaignAttempt(ctx context.Context, attemptID int64) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(ctxTimeout, `
+		UPDATE campaign_attempts
+		SET status = $1, started_at = now()
+		WHERE id = $2`, AttemptDialing, attemptID)
+	if err != nil {
+		s.log.WithError(err).WithField("attemptID", attemptID).Error("Error starting campaign attempt")
+	}
+	return err
+}
+
+// FinishCampaignAttempt records the outcome of an attempt, the number of calls it placed and the UUID of the
+// last one. callUUID and cause may be empty.
+func (s *Store) FinishCampaignAttempt(ctx context.Context, attemptID int64, status, callUUID, cause string, tries int) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.db.Exec(ctxTimeout, `
+		UPDATE campaign_attempts
+		SET status = $1, call_uuid = NULLIF($2, ''), cause = NULLIF($3, ''), tries = $4, finished_at = now()
+		WHERE id = $5`, status, callUUID, cause, tries, attemptID)
+	if err != nil {
+		s.log.WithError(err).WithField("attemptID", attemptID).Error("Error finishing campaign attempt")
+	}
+	return err
+}
+
+// FinishCampaign sets the final status of a campaign and cancels any attempts that never started
+func (s *Store) FinishCampaign(ctx context.Context, id int64, status string) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.db.Exec(ctxTimeout, `
+		UPDATE campaign_attempts
+		SET status = $1, finished_at = now()
+		WHERE campaign_id = $2 AND status = $3`, AttemptCancelled, id, AttemptPending); err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error cancelling pending campaign attempts")
+		return err
+	}
+	if _, err := s.db.Exec(ctxTimeout, `
+		UPDATE campaigns
+		SET status = $1, finished_at = now()
+		WHERE id = $2`, status, id); err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error finishing campaign")
+		return err
+	}
+	s.log.WithFields(logrus.Fields{
+		"campaignID": id,
+		"status":     status,
+	}).Info("Campaign finished")
+	return nil
+}
+
+// InterruptRunningCampaigns marks campaigns left running by a previous process as interrupted
+func (s *Store) InterruptRunningCampaigns(ctx context.Context) (int64, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, `
+		UPDATE campaigns
+		SET status = $1, finished_at = now()
+		WHERE status = $2`, CampaignInterrupted, CampaignRunning)
+	if err != nil {
+		s.log.WithError(err).Error("Error interrupting stale campaigns")
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// GetCampaigns returns campaigns newest first
+func (s *Store) GetCampaigns(ctx context.Context, limit, offset int) ([]Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		ORDER BY id DESC
+		LIMIT $1 OFFSET $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting campaigns")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []Campaign
+	for rows.Next() {
+		var c Campaign
+		if err := scanCampaign(rows, &c); err != nil {
+			s.log.WithError(err).Error("Error scanning campaign row")
+			return nil, err
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating campaign rows")
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// GetCampaign returns a campaign with its per-status attempt counts
+func (s *Store) GetCampaign(ctx context.Context, id int64) (*Campaign, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var c Campaign
+	err := scanCampaign(s.db.QueryRow(ctxTimeout, `SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`, id), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error getting campaign")
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctxTimeout, `
+		SELECT status, COUNT(*)
+		FROM campaign_attempts
+		WHERE campaign_id = $1
+		GROUP BY status`, id)
+	if err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error counting campaign attempts")
+		return nil, err
+	}
+	defer rows.Close()
+
+	c.AttemptCounts = make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			s.log.WithError(err).Error("Error scanning campaign attempt count row")
+			return nil, err
+		}
+		c.AttemptCounts[status] = count
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating campaign attempt count rows")
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCampaignAttempts returns the attempts of a campaign in dialling order
+func (s *Store) GetCampaignAttempts(ctx context.Context, id int64) ([]CampaignAttempt, error) {
+	query := `
+		SELECT id, campaign_id, number, status, call_uuid, cause, tries, started_at, finished_at
+		FROM campaign_attempts
+		WHERE campaign_id = $1
+		ORDER BY id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, id)
+	if err != nil {
+		s.log.WithError(err).WithField("campaignID", id).Error("Error getting campaign attempts")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []CampaignAttempt
+	for rows.Next() {
+		var a CampaignAttempt
+		if err := rows.Scan(&a.ID, &a.CampaignID, &a.Number, &a.Status, &a.CallUUID, &a.Cause, &a.Tries, &a.StartedAt, &a.FinishedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning campaign attempt row")
+			return nil, err
+		}
+		a.Number = s.openField(ctx, a.Number)
+		attempts = append(attempts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating campaign attempt rows")
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrConferenceNotFound is returned when a conference UUID does not exist
+var ErrConferenceNotFound = errors.New("conference not found")
+
+// Conference is a mod_conference room instance from creation to destruction
+type Conference struct {
+	ID             int64              `json:"id"`
+	ConferenceUUID string             `json:"conference_uuid"`
+	Name           string             `json:"name"`
+	Profile        *string            `json:"profile,omitempty"`
+	StartedAt      time.Time          `json:"started_at"`
+	EndedAt        *time.Time         `json:"ended_at,omitempty"` // Nil while live
+	MaxMembers     int                `json:"max_members"`
+	ActiveMembers  int                `json:"active_members"`
+	Members        []ConferenceMember `json:"members,omitempty"` // Only set by GetConference
+}
+
+// ConferenceMember is one channel's participation in a conference
+type ConferenceMember struct {
+	ID             int64      `json:"id"`
+	ConferenceUUID string     `json:"conference_uuid"`
+	MemberID       int        `json:"member_id"`
+	CallUUID       *string    `json:"call_uuid,omitempty"`
+	CallerNumber   *string    `json:"caller_number,omitempty"`
+	CallerName     *string    `json:"caller_name,omitempty"`
+	JoinedAt       time.Time  `json:"joined_at"`
+	LeftAt         *time.Time `json:"left_at,omitempty"`
+	MutedAt        *time.Time `json:"muted_at,omitempty"` // Start of the current mute, nil when unmuted
+	MutedSeconds   float64    `json:"muted_seconds"`      // Total of completed mutes
+	MuteCount      int        `json:"mute_count"`
+}
+
+// conferenceColumns is the column list shared by conference queries
+const conferenceColumns = `c.id, c.conference_uuid, c.name, c.profile, c.started_at, c.ended_at, c.max_members,
+	(SELECT COUNT(*) FROM conference_members m WHERE m.conference_uuid = c.conference_uuid AND m.left_at IS NULL)`
+
+// scanConference scans a row selected with conferenceColumns into c
+func scanConference(row pgx.Row, c *Conference) error {
+	return row.Scan(&c.ID, &c.ConferenceUUID, &c.Name, &c.Profile, &c.StartedAt, &c.EndedAt, &c.MaxMembers, &c.ActiveMembers)
+}
+
+// StartConference records a conference-create. A conference already known (e.g. created by an earlier
+// add-member) keeps its row but its start time moves back if this event is earlier.
+func (s *Store) StartConference(ctx context.Context, c *Conference) error {
+	return s.write(ctx, writeOp{
+		name: "start_conference",
+		uuid: c.ConferenceUUID,
+		query: `
+			INSERT INTO conferences (conference_uuid, name, profile, started_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (conference_uuid) DO UPDATE SET
+				profile = COALESCE(EXCLUDED.profile, conferences.profile),
+				started_at = LEAST(conferences.started_at, EXCLUDED.started_at)`,
+		args: []any{c.ConferenceUUID, c.Name, c.Profile, c.StartedAt},
+	})
+}
+
+// EndConference records a conference-destroy, closing any members still marked as present
+func (s *Store) EndConference(ctx context.Context, conferenceUUID string, at time.Time) error {
+	if err := s.write(ctx, writeOp{
+		name: "end_conference_members",
+		uuid: conferenceUUID,
+		query: `
+			UPDATE conference_members
+			SET left_at = $1,
+				muted_seconds = muted_seconds + COALESCE(EXTRACT(EPOCH FROM ($1 - muted_at))::float8, 0),
+				muted_at = NULL
+			WHERE conference_uuid = $2 AND left_at IS NULL`,
+		args: []any{at, conferenceUUID},
+	}); err != nil {
+		return err
+	}
+	return s.write(ctx, writeOp{
+		name: "end_conference",
+		uuid: conferenceUUID,
+		query: `
+			UPDATE conferences
+			SET ended_at = $1
+			WHERE conference_uuid = $2 AND ended_at IS NULL`,
+		args:         []any{at, conferenceUUID},
+		warnIfNoRows: true,
+	})
+}
+
+// AddConferenceMember records an add-member, creating the conference if its conference-create was missed.
+// size is the conference size after the join, used to track the peak member count.
+func (s *Store) AddConferenceMember(ctx context.Context, c *Conference, m *ConferenceMember, size int) error {
+	return s.write(ctx, writeOp{
+		name: "add_conference_member",
+		uuid: m.ConferenceUUID,
+		query: `
+			WITH conference AS (
+				INSERT INTO conferences (conference_uuid, name, profile, started_at, max_members)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (conference_uuid) DO UPDATE SET
+					max_members = GREATEST(conferences.max_members, EXCLUDED.max_members)
+			)
+			INSERT INTO conference_members (conference_uuid, member_id, call_uuid, caller_number, caller_name, joined_at)
+			VALUES ($1, $6, $7, $8, $9, $4)
+			ON CONFLICT (conference_uuid, member_id) DO NOTHING`,
+		args: []any{c.ConferenceUUID, c.Name, c.Profile, m.JoinedAt, size,
+			m.MemberID, m.CallUUID, s.sealOptionalNumber(m.CallerNumber), m.CallerName},
+	})
+}
+
+// RemoveConferenceMember records a del-member, closing any open mute
+func (s *Store) RemoveConferenceMember(ctx context.Context, conferenceUUID string, memberID int, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "remove_conference_member",
+		uuid: conferenceUUID,
+		query: `
+			UPDATE conference_members
+			SET left_at = $1,
+				muted_seconds = muted_seconds + COALESCE(EXTRACT(EPOCH FROM ($1 - muted_at))::float8, 0),
+				muted_at = NULL
+			WHERE conference_uuid = $2 AND member_id = $3 AND left_at IS NULL`,
+		args:         []any{at, conferenceUUID, memberID},
+		warnIfNoRows: true,
+	})
+}
+
+// SetConferenceMemberMute records a mute-member or unmute-member. Repeated mutes keep the first start time.
+func (s *Store) SetConferenceMemberMute(ctx context.Context, conferenceUUID string, memberID int, muted bool, at time.Time) error {
+	query := `
+		UPDATE conference_members
+		SET muted_at = $1, mute_count = mute_count + 1
+		WHERE conference_uuid = $2 AND member_id = $3 AND left_at IS NULL AND muted_at IS NULL`
+	if !muted {
+		query = `
+			UPDATE conference_members
+			SET muted_seconds = muted_seconds + EXTRACT(EPOCH FROM ($1 - muted_at))::float8, muted_at = NULL
+			WHERE conference_uuid = $2 AND member_id = $3 AND muted_at IS NOT NULL`
+	}
+	return s.write(ctx, writeOp{
+		name:  "set_conference_member_mute",
+		uuid:  conferenceUUID,
+		query: query,
+		args:  []any{at, conferenceUUID, memberID},
+	})
+}
+
+// GetConferences returns conferences newest first. With live set only conferences still running are
+// returned; otherwise conferences started in [from, to).
+func (s *Store) GetConferences(ctx context.Context, live bool, from, to time.Time, limit, offset int) ([]Conference, error) {
+	query := `
+		SELECT ` + conferenceColumns + `
+		FROM conferences c
+		WHERE ($1 AND c.ended_at IS NULL) OR (NOT $1 AND c.started_at >= $2 AND c.started_at < $3)
+		ORDER BY c.started_at DESC, c.id DESC
+		LIMIT $4 OFFSET $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, live, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting conferences")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conferences []Conference
+	for rows.Next() {
+		var c Conference
+		if err := scanConference(rows, &c); err != nil {
+			s.log.WithError(err).Error("Error scanning conference row")
+			return nil, err
+		}
+		conferences = append(conferences, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating conference rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"live":  live,
+		"count": len(conferences),
+	}).Info("Retrieved conferences")
+	return conferences, nil
+}
+
+// GetConference returns a conference with all of its members in join order
+func (s *Store) GetConference(ctx context.Context, conferenceUUID string) (*Conference, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var c Conference
+	err := scanConference(s.db.QueryRow(ctxTimeout, `SELECT `+conferenceColumns+` FROM conferences c WHERE c.conference_uuid = $1`, conferenceUUID), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConferenceNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("conferenceUUID", conferenceUUID).Error("Error getting conference")
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctxTimeout, `
+		SELECT id, conference_uuid, member_id, call_uuid, caller_number, caller_name, joined_at, left_at,
+			muted_at, muted_seconds, mute_count
+		FROM conference_members
+		WHERE conference_uuid = $1
+		ORDER BY joined_at, member_id`, conferenceUUID)
+	if err != nil {
+		s.log.WithError(err).WithField("conferenceUUID", conferenceUUID).Error("Error getting conference members")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m ConferenceMember
+		if err := rows.Scan(&m.ID, &m.ConferenceUUID, &m.MemberID, &m.CallUUID, &m.CallerNumber, &m.CallerName,
+			&m.JoinedAt, &m.LeftAt, &m.MutedAt, &m.MutedSeconds, &m.MuteCount); err != nil {
+			s.log.WithError(err).Error("Error scanning conference member row")
+			return nil, err
+		}
+		m.CallerNumber = s.openOptionalField(ctx, m.CallerNumber)
+		c.Members = append(c.Members, m)
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating conference member rows")
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCustomColumn is returned for a malformed or conflicting custom column mapping
+var ErrInvalidCustomColumn = errors.New("invalid custom column")
+
+// CustomColumn maps a channel variable to a typed column on the calls table
+type CustomColumn struct {
+	Variable string // Event header, e.g. variable_customer_id
+	Name     string // Column name
+	Type     string // One of customColumnTypes
+	Indexed  bool
+}
+
+// customColumnTypes are the SQL types a custom column may have
+var customColumnTypes = map[string]bool{
+	"TEXT":    true,
+	"INTEGER": true,
+	"BIGINT":  true,
+	"NUMERIC": true,
+	"BOOLEAN": true,
+}
+
+// customColumnPattern restricts custom column names to plain lower-case identifiers
+var customColumnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// ParseCustomColumns parses mappings of the form "variable_customer_id->customer_id TEXT", optionally
+// followed by INDEXED. Column names must not clash with each other or with built-in calls columns.
+func ParseCustomColumns(specs []string) ([]CustomColumn, error) {
+	reserved := make(map[string]bool)
+	for _, name := range strings.Split(callColumns, ",") {
+		reserved[strings.TrimSpace(name)] = true
+	}
+
+	var cols []CustomColumn
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		variable, target, ok := strings.Cut(spec, "->")
+		fields := strings.Fields(target)
+		if !ok || strings.TrimSpace(variable) == "" || len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%w: %q (expected \"variable_name->column TYPE [INDEXED]\")", ErrInvalidCustomColumn, spec)
+		}
+		col := CustomColumn{
+			Variable: strings.TrimSpace(variable),
+			Name:     strings.ToLower(fields[0]),
+			Type:     strings.ToUpper(fields[1]),
+		}
+		if len(fields) == 3 {
+			if !strings.EqualFold(fields[2], "INDEXED") {
+				return nil, fmt.Errorf("%w: %q: unknown option %q", ErrInvalidCustomColumn, spec, fields[2])
+			}
+			col.Indexed = true
+		}
+		if !customColumnPattern.MatchString(col.Name) || reserved[col.Name] || seen[col.Name] {
+			return nil, fmt.Errorf("%w: %q: column name %q is invalid or already used", ErrInvalidCustomColumn, spec, col.Name)
+		}
+		if !customColumnTypes[col.Type] {
+			return nil, fmt.Errorf("%w: %q: unsupported type %q", ErrInvalidCustomColumn, spec, col.Type)
+		}
+		seen[col.Name] = true
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// SetCustomColumns configures the custom columns InitSchema creates and SetCallCustomFields populates.
+// It must be called before InitSchema.
+func (s *Store) SetCustomColumns(cols []CustomColumn) {
+	s.customColumns = cols
+}
+
+// CustomColumns returns the configured custom columns
+func (s *Store) CustomColumns() []CustomColumn {
+	return s.customColumns
+}
+
+// customSchemaStatements returns the DDL adding the configured custom columns and their indexes
+func (s *Store) customSchemaStatements() []string {
+	var stmts []string
+	for _, col := range s.customColumns {
+		stmts = append(stmts, fmt.Sprintf(`ALTER TABLE calls ADD COLUMN IF NOT EXISTS %s %s`, col.Name, col.Type))
+		if col.Indexed {
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS calls_custom_%[1]s_idx ON calls (%[1]s)`, col.Name))
+		}
+	}
+	return stmts
+}
+
+// convert parses raw into the Go value for the column type
+func (col CustomColumn) convert(raw string) (any, error) {
+	switch col.Type {
+	case "INTEGER", "BIGINT":
+		return strconv.ParseInt(raw, 10, 64)
+	case "NUMERIC":
+		return strconv.ParseFloat(raw, 64)
+	case "BOOLEAN":
+		return strconv.ParseBool(raw)
+	default:
+		return raw, nil
+	}
+}
+
+// SetCallCustomFields stores channel variable values in the custom columns. values is keyed by column
+// name; columns without a value keep their current contents. Values that do not parse as the column
+// type are logged and skipped.
+func (s *Store) SetCallCustomFields(ctx context.Context, uuid string, values map[string]string) error {
+	var sets []string
+	var args []any
+	for _, col := range s.customColumns {
+		raw, ok := values[col.Name]
+		if !ok || raw == "" {
+			continue
+		}
+		v, err := col.convert(raw)
+		if err != nil {
+			s.log.WithError(err).WithField("uuid", uuid).Warnf("Ignoring value %q for custom column %s %s", raw, col.Name, col.Type)
+			continue
+		}
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col.Name, len(args)))
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, uuid)
+
+	return s.write(ctx, writeOp{
+		name:         "set_custom_fields",
+		uuid:         uuid,
+		query:        fmt.Sprintf(`UPDATE calls SET %s WHERE uuid = $%d`, strings.Join(sets, ", "), len(args)),
+		args:         args,
+		warnIfNoRows: true,
+	})
+}
+
+// getCallCustomFields returns the custom column values of a call keyed by column name
+func (s *Store) getCallCustomFields(ctx context.Context, uuid string) (map[string]any, error) {
+	pairs := make([]string, len(s.customColumns))
+	for i, col := range s.customColumns {
+		pairs[i] = fmt.Sprintf("'%[1]s', %[1]s", col.Name)
+	}
+	query := fmt.Sprintf(`SELECT jsonb_strip_nulls(jsonb_build_object(%s)) FROM calls WHERE uuid = $1`, strings.Join(pairs, ", "))
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var fields map[string]any
+	if err := s.db.QueryRow(ctxTimeout, query, uuid).Scan(&fields); err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call custom fields")
+		return nil, err
+	}
+	return fields, nil
+}
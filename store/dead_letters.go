@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Dead-letter stages: where handling an event failed
+const (
+	DeadLetterParse   = "parse"   // The event could not be interpreted
+	DeadLetterPersist = "persist" // PostgreSQL rejected one of its writes
+	DeadLetterPanic   = "panic"   // Its handler panicked
+)
+
+// DeadLetter is an event set aside because it could not be handled, with its raw payload so it can be
+// reprocessed once the cause is fixed
+type DeadLetter struct {
+	ID             int64             `json:"id"`
+	Node           string            `json:"node"`
+	EventName      string            `json:"event_name"`
+	UUID           string            `json:"uuid,omitempty"`
+	Stage          string            `json:"stage"`
+	Error          string            `json:"error"`
+	Headers        map[string]string `json:"headers"`
+	Body           string            `json:"body,omitempty"`
+	Attempts       int               `json:"attempts"`
+	AppliedWrites  []string          `json:"applied_writes,omitempty"` // Writes that went through; see AppliedWrites
+	FailedAt       time.Time         `json:"failed_at"`
+	ReprocessedAt  *time.Time        `json:"reprocessed_at,omitempty"`
+	ReprocessError *string           `json:"reprocess_error,omitempty"`
+}
+
+// In converts the dead letter's timestamps to loc
+func (d *DeadLetter) In(loc *time.Location) {
+	d.FailedAt = d.FailedAt.In(loc)
+	if d.ReprocessedAt != nil {
+		t := d.ReprocessedAt.In(loc)
+		d.ReprocessedAt = &t
+	}
+}
+
+// DeadLetterFilter narrows GetDeadLetters; empty fields match everything
+type DeadLetterFilter struct {
+	Stage     string
+	EventName string
+	Pending   bool // Only events not yet reprocessed successfully
+}
+
+// AddDeadLetter stores an event that could not be handled
+func (s *Store) AddDeadLetter(ctx context.Context, d *DeadLetter) error {
+	return s.write(ctx, writeOp{
+		name: "add_dead_letter",
+		uuid: d.UUID,
+		query: `
+			INSERT INTO dead_letter_events (node, event_name, uuid, stage, error, headers, body, attempts, applied_writes, failed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		args: []any{d.Node, d.EventName, d.UUID, d.Stage, d.Error, s.SealHeaders(d.Headers), d.Body, d.Attempts, d.AppliedWrites, d.FailedAt},
+	})
+}
+
+const deadLetterColumns = `id, node, event_name, uuid, stage, error, headers, body, attempts,
+	COALESCE(applied_writes, '{}'), failed_at, reprocessed_at, reprocess_error`
+
+// scanDeadLetter scans a row selected with deadLetterColumns
+func scanDeadLetter(row interface{ Scan(...any) error }) (DeadLetter, error) {
+	var d DeadLetter
+	err := row.Scan(&d.ID, &d.Node, &d.EventName, &d.UUID, &d.Stage, &d.Error, &d.Headers, &d.Body, &d.Attempts,
+		&d.AppliedWrites, &d.FailedAt, &d.ReprocessedAt, &d.ReprocessError)
+	return d, err
+}
+
+// GetDeadLetters returns dead-lettered events, newest first
+func (s *Store) GetDeadLetters(ctx context.Context, f DeadLetterFilter, limit, offset int) ([]DeadLetter, error) {
+	query := `
+		SELECT ` + deadLetterColumns + `
+		FROM dead_letter_events
+		WHERE ($1 = '' OR stage = $1) AND ($2 = '' OR event_name = $2) AND (NOT $3 OR reprocessed_at IS NULL)
+		ORDER BY failed_at DESC, id DESC
+		LIMIT $4 OFFSET $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, f.Stage, f.EventName, f.Pending, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting dead-letter events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			s.log.WithError(err).Error("Error scanning dead-letter event row")
+			return nil, err
+		}
+		d.Headers = s.openHeaders(ctx, d.Headers)
+		letters = append(letters, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating dead-letter event rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"stage":   f.Stage,
+		"event":   f.EventName,
+		"pending": f.Pending,
+		"count":   len(letters),
+	}).Info("Retrieved dead-letter events")
+	return letters, nil
+}
+
+// GetDeadLetter returns one dead-lettered event, or pgx.ErrNoRows
+func (s *Store) GetDeadLetter(ctx context.Context, id int64) (*DeadLetter, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	d, err := scanDeadLetter(s.db.QueryRow(ctxTimeout, `SELECT `+deadLetterColumns+` FROM dead_letter_events WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	d.Headers = s.openHeaders(ctx, d.Headers)
+	return &d, nil
+}
+
+// MarkDeadLetterReprocessed records the outcome of reprocessing a dead-lettered event: on success it is no
+// longer pending; on failure the attempt is counted and its error kept with the writes applied so far
+func (s *Store) MarkDeadLetterReprocessed(ctx context.Context, id int64, applied []string, reprocessErr error) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var err error
+	if reprocessErr == nil {
+		_, err = s.db.Exec(ctxTimeout, `
+			UPDATE dead_letter_events SET reprocessed_at = now(), reprocess_error = NULL, attempts = attempts + 1
+			WHERE id = $1`, id)
+	} else {
+		_, err = s.db.Exec(ctxTimeout, `
+			UPDATE dead_letter_events SET reprocess_error = $2, applied_writes = $3, attempts = attempts + 1
+			WHERE id = $1`,
+			id, reprocessErr.Error(), applied)
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("id", id).Error("Error updating dead-letter event")
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package store
+
+// Diagnostics is the store's internal state, for diagnostic snapshots
+type Diagnostics struct {
+	Pool           PoolStats `json:"pool"`
+	CircuitOpen    bool      `json:"circuit_open"`
+	WritesInFlight int       `json:"writes_in_flight"`
+	WriterQueue    *int      `json:"writer_queue,omitempty"` // Writes waiting for a flush; nil without STORE_ASYNC_WRITES
+}
+
+// PoolStats describes the database connection pool
+type PoolStats struct {
+	MaxConns             int32  `json:"max_conns"`
+	TotalConns           int32  `json:"total_conns"`
+	AcquiredConns        int32  `json:"acquired_conns"`
+	IdleConns            int32  `json:"idle_conns"`
+	ConstructingConns    int32  `json:"constructing_conns"`
+	AcquireCount         int64  `json:"acquire_count"`
+	EmptyAcquireCount    int64  `json:"empty_acquire_count"` // Acquires that had to wait for a connection
+	CanceledAcquireCount int64  `json:"canceled_acquire_count"`
+	AcquireDuration      string `json:"acquire_duration"` // Total time spent waiting for connections
+}
+
+// Diagnostics returns a snapshot of the pool, circuit breaker and write queues
+func (s *Store) Diagnostics() Diagnostics {
+	stat := s.db.current.Load().Stat()
+	d := Diagnostics{
+		Pool: PoolStats{
+			MaxConns:             stat.MaxConns(),
+			TotalConns:           stat.TotalConns(),
+			AcquiredConns:        stat.AcquiredConns(),
+			IdleConns:            stat.IdleConns(),
+			ConstructingConns:    stat.ConstructingConns(),
+			AcquireCount:         stat.AcquireCount(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			AcquireDuration:      stat.AcquireDuration().String(),
+		},
+		CircuitOpen:    s.CircuitOpen(),
+		WritesInFlight: len(s.inFlight),
+	}
+	if s.writer != nil {
+		queued := len(s.writer.ops)
+		d.WriterQueue = &queued
+	}
+	return d
+}
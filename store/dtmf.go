@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DTMF is a single digit received on a call
+type DTMF struct {
+	ID         int64     `json:"id"`
+	UUID       string    `json:"uuid"`
+	Digit      string    `json:"digit"`
+	DurationMS *int      `json:"duration_ms,omitempty"`
+	Source     *string   `json:"source,omitempty"` // How the digit arrived, e.g. RTP (RFC 2833), INBAND_AUDIO, SIP INFO
+	EventTime  time.Time `json:"event_time"`
+}
+
+// AddDTMF records a DTMF digit for a call. The digit is encrypted when field encryption is enabled.
+func (s *Store) AddDTMF(ctx context.Context, d *DTMF) error {
+	return s.write(ctx, writeOp{
+		name: "add_dtmf",
+		uuid: d.UUID,
+		query: `
+			INSERT INTO call_dtmf (uuid, digit, duration_ms, source, event_time)
+			VALUES ($1, $2, $3, $4, $5)`,
+		args: []any{d.UUID, s.cipher.seal(d.Digit, false), d.DurationMS, d.Source, d.EventTime},
+	})
+}
+
+// GetDTMF returns the digits received on a call in event order
+func (s *Store) GetDTMF(ctx context.Context, uuid string) ([]DTMF, error) {
+	query := `
+		SELECT id, uuid, digit, duration_ms, source, event_time
+		FROM call_dtmf
+		WHERE uuid = $1
+		ORDER BY event_time, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call DTMF")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digits []DTMF
+	for rows.Next() {
+		var d DTMF
+		if err := rows.Scan(&d.ID, &d.UUID, &d.Digit, &d.DurationMS, &d.Source, &d.EventTime); err != nil {
+			s.log.WithError(err).Error("Error scanning call DTMF row")
+			return nil, err
+		}
+		d.Digit = s.openField(ctx, d.Digit)
+		digits = append(digits, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call DTMF rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(digits),
+	}).Info("Retrieved call DTMF")
+	return digits, nil
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons a set of call records was judged to be duplicates
+const (
+	DuplicateUUID      = "uuid"      // The same UUID spelled differently, e.g. upper case from an import
+	DuplicateSignature = "signature" // Same direction, caller and callee, starting within the window
+)
+
+// DuplicateGroup is a set of call records describing the same call. Keep is the most complete record,
+// into which the Duplicates are merged.
+type DuplicateGroup struct {
+	Reason     string    `json:"reason"`
+	Keep       string    `json:"keep"`
+	Duplicates []string  `json:"duplicates"`
+	Caller     string    `json:"caller"`
+	Callee     string    `json:"callee"`
+	StartTime  time.Time `json:"start_time"`
+}
+
+// callCompleteness scores how much of a call record is filled in; the highest-scoring record of a group is kept
+const callCompleteness = `num_nonnulls(caller_name, callee_name, ringing_time, early_media_time, answered_time,
+	bridged_time, end_time, status, context, sip_profile, domain, account_code, user_id, gateway, node, sip_call_id,
+	destination_country, destination_group, derived, billsec, duration, progresssec, cost)`
+
+// mergedCallColumns are the nullable call columns a merge fills from a duplicate when the kept record lacks them
+var mergedCallColumns = []string{
+	"caller_name", "callee_name", "ringing_time", "early_media_time", "answered_time", "bridged_time", "end_time",
+	"status", "context", "sip_profile", "domain", "account_code", "user_id", "gateway", "node", "sip_call_id",
+	"destination_country", "destination_group", "billsec", "duration", "progresssec", "cost",
+	"transferred_to", "transferred_by", "last_corrected_at", "caller_id_campaign",
+	"business_hours", "outcome", "outcome_source", "classified_at",
+	"hangup_cause_q850", "sip_term_status", "sip_invite_failure_status",
+	"verto_session_id", "verto_client_address", "verto_user_agent", "retry_of", "retry_attempt",
+	"retrieved_by", "retrieved_by_uuid",
+}
+
+// callReference is a column in another table holding a call UUID. Unique references are only moved when
+// the kept call has no row of its own; otherwise the duplicate's row is dropped.
+type callReference struct {
+	table, column string
+	unique        bool
+}
+
+// callReferences lists every column a merge re-points from a duplicate to the kept call
+var callReferences = []callReference{
+	{"call_transitions", "uuid", false},
+	{"call_dtmf", "uuid", false},
+	{"hold_intervals", "uuid", false},
+	{"call_applications", "uuid", false},
+	{"recordings", "uuid", false},
+	{"faxes", "uuid", false},
+	{"parked_calls", "uuid", false},
+	{"call_transfers", "transferor_uuid", false},
+	{"call_transfers", "transferee_uuid", false},
+	{"call_transfers", "target_uuid", false},
+	{"call_legs", "a_uuid", false},
+	{"call_legs", "b_uuid", false},
+	{"voicemail_events", "call_uuid", false},
+	{"conference_members", "call_uuid", false},
+	{"campaign_attempts", "call_uuid", false},
+	{"callbacks", "callback_uuid", false},
+	{"calls", "transferred_by", false},
+	{"calls", "retry_of", false},
+	{"calls", "retrieved_by_uuid", false},
+	{"park_intervals", "uuid", false},
+	{"park_intervals", "retrieved_by_uuid", false},
+	{"raw_events", "uuid", false},
+	{"callbacks", "original_uuid", true},
+	{"queue_calls", "uuid", true},
+	{"call_quality", "uuid", true},
+}
+
+// FindDuplicateCalls returns the groups of duplicate call records started in [from, to), oldest first.
+// Records whose UUIDs differ only in case are grouped first; the best record of each UUID then joins a
+// signature group when another call with the same direction, caller and callee starts within window of it.
+// Merging the groups in the order returned leaves one record per call.
+func (s *Store) FindDuplicateCalls(ctx context.Context, from, to time.Time, window time.Duration, limit int) ([]DuplicateGroup, error) {
+	query := `
+		WITH scored AS (
+			SELECT id, uuid, lower(uuid) AS uuid_key, direction, caller, callee, start_time,
+				` + callCompleteness + ` AS score
+			FROM calls
+			WHERE start_time >= $1 AND start_time < $2
+		), by_uuid AS (
+			SELECT 'uuid' AS reason, array_agg(uuid ORDER BY score DESC, id) AS uuids,
+				min(caller) AS caller, min(callee) AS callee, min(start_time) AS start_time
+			FROM scored
+			GROUP BY uuid_key
+			HAVING count(*) > 1
+		), best AS (
+			SELECT DISTINCT ON (uuid_key) *
+			FROM scored
+			ORDER BY uuid_key, score DESC, id
+		), gaps AS (
+			SELECT *, start_time - lag(start_time) OVER (PARTITION BY direction, caller, callee ORDER BY start_time, id) AS gap
+			FROM best
+		), clustered AS (
+			SELECT *, count(*) FILTER (WHERE gap IS NULL OR gap > make_interval(secs => $3))
+				OVER (PARTITION BY direction, caller, callee ORDER BY start_time, id) AS cluster
+			FROM gaps
+		), by_signature AS (
+			SELECT 'signature' AS reason, array_agg(uuid ORDER BY score DESC, id) AS uuids,
+				caller, callee, min(start_time) AS start_time
+			FROM clustered
+			GROUP BY direction, caller, callee, cluster
+			HAVING count(*) > 1
+		)
+		SELECT reason, uuids, caller, callee, start_time
+		FROM (SELECT * FROM by_uuid UNION ALL SELECT * FROM by_signature) groups
+		ORDER BY reason = 'signature', start_time
+		LIMIT $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, window.Seconds(), limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting duplicate calls")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []DuplicateGroup
+	for rows.Next() {
+		var g DuplicateGroup
+		var uuids []string
+		if err := rows.Scan(&g.Reason, &uuids, &g.Caller, &g.Callee, &g.StartTime); err != nil {
+			s.log.WithError(err).Error("Error scanning duplicate call row")
+			return nil, err
+		}
+		g.Keep, g.Duplicates = uuids[0], uuids[1:]
+		g.Caller, g.Callee = s.openField(ctx, g.Caller), s.openField(ctx, g.Callee)
+		groups = append(groups, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating duplicate call rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":   from,
+		"to":     to,
+		"window": window.String(),
+		"count":  len(groups),
+	}).Info("Retrieved duplicate calls")
+	return groups, nil
+}
+
+// mergeSets returns the SET clauses merging duplicate d into kept call k
+func (s *Store) mergeSets() []string {
+	sets := make([]string, 0, len(mergedCallColumns)+len(s.customColumns)+10)
+	for _, col := range mergedCallColumns {
+		sets = append(sets, fmt.Sprintf("%[1]s = COALESCE(k.%[1]s, d.%[1]s)", col))
+	}
+	for _, col := range s.customColumns {
+		sets = append(sets, fmt.Sprintf("%[1]s = COALESCE(k.%[1]s, d.%[1]s)", col.Name))
+	}
+	sets = append(sets,
+		"start_time = LEAST(k.start_time, d.start_time)",
+		"derived = CASE WHEN k.derived IS NULL AND d.derived IS NULL THEN NULL ELSE COALESCE(d.derived, '{}'::jsonb) || COALESCE(k.derived, '{}'::jsonb) END",
+		"sip_headers = CASE WHEN k.sip_headers IS NULL AND d.sip_headers IS NULL THEN NULL ELSE COALESCE(d.sip_headers, '{}'::jsonb) || COALESCE(k.sip_headers, '{}'::jsonb) END",
+		"variables = CASE WHEN k.variables IS NULL AND d.variables IS NULL THEN NULL ELSE COALESCE(d.variables, '{}'::jsonb) || COALESCE(k.variables, '{}'::jsonb) END",
+		"tags = ARRAY(SELECT DISTINCT t FROM unnest(k.tags || d.tags) AS t ORDER BY t)",
+		"hold_seconds = GREATEST(k.hold_seconds, d.hold_seconds)",
+		"hold_count = GREATEST(k.hold_count, d.hold_count)",
+		"parked_seconds = GREATEST(k.parked_seconds, d.parked_seconds)",
+		"park_count = GREATEST(k.park_count, d.park_count)",
+		"late_corrections = k.late_corrections + d.late_corrections",
+	)
+	return sets
+}
+
+// MergeDuplicateCalls merges the group's duplicates into the kept record in one transaction and deletes
+// them. Columns the kept record lacks are taken from the duplicates in order; tags are combined, derived
+// fields keep the kept record's values, and rows in other tables referring to a duplicate are moved to
+// the kept call. Duplicates that no longer exist are skipped. It returns the number of records removed.
+func (s *Store) MergeDuplicateCalls(ctx context.Context, g DuplicateGroup) (int64, error) {
+	mergeQuery := `
+		UPDATE calls AS k
+		SET ` + strings.Join(s.mergeSets(), ",\n\t\t\t") + `
+		FROM calls AS d
+		WHERE k.uuid = $1 AND d.uuid = $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctxTimeout)
+	if err != nil {
+		s.log.WithError(err).Error("Error starting duplicate merge transaction")
+		return 0, err
+	}
+	defer tx.Rollback(ctxTimeout) // No-op after Commit
+
+	var removed int64
+	for _, dup := range g.Duplicates {
+		if _, err := tx.Exec(ctxTimeout, mergeQuery, g.Keep, dup); err != nil {
+			s.log.WithError(err).WithField("uuid", dup).Error("Error merging duplicate call")
+			return 0, err
+		}
+		for _, ref := range callReferences {
+			if ref.unique {
+				_, err = tx.Exec(ctxTimeout, fmt.Sprintf(`
+					WITH moved AS (
+						UPDATE %[1]s SET %[2]s = $1
+						WHERE %[2]s = $2 AND NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[2]s = $1)
+						RETURNING 1
+					)
+					DELETE FROM %[1]s WHERE %[2]s = $2 AND NOT EXISTS (SELECT 1 FROM moved)`, ref.table, ref.column), g.Keep, dup)
+			} else {
+				_, err = tx.Exec(ctxTimeout, fmt.Sprintf(`UPDATE %[1]s SET %[2]s = $1 WHERE %[2]s = $2`, ref.table, ref.column), g.Keep, dup)
+			}
+			if err != nil {
+				s.log.WithError(err).WithFields(logrus.Fields{
+					"uuid":  dup,
+					"table": ref.table,
+				}).Error("Error moving duplicate call references")
+				return 0, err
+			}
+		}
+		tag, err := tx.Exec(ctxTimeout, `DELETE FROM calls WHERE uuid = $1`, dup)
+		if err != nil {
+			s.log.WithError(err).WithField("uuid", dup).Error("Error deleting duplicate call")
+			return 0, err
+		}
+		removed += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctxTimeout); err != nil {
+		s.log.WithError(err).Error("Error committing duplicate merge")
+		return 0, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"keep":    g.Keep,
+		"reason":  g.Reason,
+		"removed": removed,
+	}).Info("Merged duplicate calls")
+	return removed, nil
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks an encrypted column value; the rest is base64(nonce || ciphertext)
+const sealedPrefix = "enc:v1:"
+
+// ErrInvalidFieldKey is returned for an encryption key that is not 32 bytes of base64
+var ErrInvalidFieldKey = errors.New("field encryption key must be 32 bytes, base64-encoded")
+
+// ErrEncryptedField is returned by queries that need the plaintext of an encrypted field inside the
+// database, such as grouping by number prefix
+var ErrEncryptedField = errors.New("query not supported on encrypted fields")
+
+// FieldCipher encrypts sensitive call fields with AES-256-GCM: caller and callee numbers (and the
+// retrieving extension copied from them) and DTMF digits. Numbers are encrypted deterministically, with a
+// nonce derived from the value, so equal numbers still group and match in SQL; DTMF digits, of which there
+// are only sixteen, get random nonces. Values written before encryption was enabled are read back as is.
+type FieldCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte // HMAC key deriving deterministic nonces, itself derived from the encryption key
+}
+
+// NewFieldCipher creates a FieldCipher from a base64-encoded 32-byte key
+func NewFieldCipher(encodedKey string) (*FieldCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidFieldKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("field-cipher nonce key"))
+	return &FieldCipher{aead: aead, nonceKey: mac.Sum(nil)}, nil
+}
+
+// seal encrypts value. Deterministic sealing maps equal values to equal ciphertexts. A nil cipher and
+// empty values pass through unchanged.
+func (f *FieldCipher) seal(value string, deterministic bool) string {
+	if f == nil || value == "" {
+		return value
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if deterministic {
+		mac := hmac.New(sha256.New, f.nonceKey)
+		mac.Write([]byte(value))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("field cipher: reading random nonce: %v", err)) // crypto/rand never fails on supported platforms
+	}
+	sealed := f.aead.Seal(nonce, nonce, []byte(value), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// open decrypts a value produced by seal. Values without the sealed prefix are returned unchanged.
+func (f *FieldCipher) open(value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, sealedPrefix)
+	if f == nil || !ok {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < f.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:f.aead.NonceSize()], sealed[f.aead.NonceSize():]
+	plain, err := f.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt field: %w", err)
+	}
+	return string(plain), nil
+}
+
+// SetFieldCipher enables encryption of sensitive call fields. It must be called before any writes; nil
+// disables encryption.
+func (s *Store) SetFieldCipher(f *FieldCipher) {
+	s.cipher = f
+}
+
+// FieldsEncrypted reports whether sensitive call fields are encrypted
+func (s *Store) FieldsEncrypted() bool {
+	return s.cipher != nil
+}
+
+type sealedKey struct{}
+
+// WithSealedFields returns a context whose reads return encrypted fields as stored instead of decrypting
+// them, for callers not authorized to see the plaintext
+func WithSealedFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sealedKey{}, true)
+}
+
+// fieldsSealed reports whether ctx was marked by WithSealedFields
+func fieldsSealed(ctx context.Context) bool {
+	sealed, _ := ctx.Value(sealedKey{}).(bool)
+	return sealed
+}
+
+// sealNumber encrypts a caller or callee number for storage
+func (s *Store) sealNumber(number string) string {
+	return s.cipher.seal(number, true)
+}
+
+// openField decrypts value for a read made with ctx. Unauthorized reads and values that fail to decrypt
+// are returned as stored.
+func (s *Store) openField(ctx context.Context, value string) string {
+	if s.cipher == nil || fieldsSealed(ctx) {
+		return value
+	}
+	plain, err := s.cipher.open(value)
+	if err != nil {
+		s.log.WithError(err).Warn("Error decrypting call field")
+		return value
+	}
+	return plain
+}
+
+// openCall decrypts the encrypted fields of a call read with ctx
+func (s *Store) openCall(ctx context.Context, call *Call) {
+	if s.cipher == nil {
+		return
+	}
+	call.Caller = s.openField(ctx, call.Caller)
+	call.Callee = s.openField(ctx, call.Callee)
+	if call.RetrievedBy != nil {
+		v := s.openField(ctx, *call.RetrievedBy)
+		call.RetrievedBy = &v
+	}
+}
+
+// sensitiveHeader reports whether an ESL event header may carry a phone number or DTMF digits: the caller
+// profiles (Caller-*, Other-Leg-*), channel variables, and the number headers of DTMF, voicemail, callcenter
+// and callback events
+func sensitiveHeader(name string) bool {
+	switch name {
+	case "DTMF-Digit", "VM-Caller-ID-Number", "CC-Member-CID-Number", "Callback-Number":
+		return true
+	}
+	return strings.HasPrefix(name, "Caller-") || strings.HasPrefix(name, "Other-Leg-") ||
+		strings.HasPrefix(name, "variable_")
+}
+
+// SealHeaders returns a copy of the headers of an ESL event with the values of sensitive headers
+// encrypted, for keeping whole events (raw events, dead letters, the disk spool). Without a cipher the
+// headers are returned as is.
+func (s *Store) SealHeaders(headers map[string]string) map[string]string {
+	if s.cipher == nil || headers == nil {
+		return headers
+	}
+	sealed := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if sensitiveHeader(name) {
+			value = s.cipher.seal(value, false)
+		}
+		sealed[name] = value
+	}
+	return sealed
+}
+
+// OpenHeaders returns a copy of headers sealed by SealHeaders with their values decrypted, for handling
+// the event again. Values that fail to decrypt are kept as stored.
+func (s *Store) OpenHeaders(headers map[string]string) map[string]string {
+	return s.openHeaders(context.Background(), headers)
+}
+
+// openHeaders decrypts headers sealed by SealHeaders for a read made with ctx
+func (s *Store) openHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	if s.cipher == nil || headers == nil || fieldsSealed(ctx) {
+		return headers
+	}
+	opened := make(map[string]string, len(headers))
+	for name, value := range headers {
+		opened[name] = s.openField(ctx, value)
+	}
+	return opened
+}
+
+// sealOptionalNumber encrypts an optional caller number for storage
+func (s *Store) sealOptionalNumber(number *string) *string {
+	if number == nil || s.cipher == nil {
+		return number
+	}
+	sealed := s.sealNumber(*number)
+	return &sealed
+}
+
+// openOptionalField decrypts an optional encrypted field for a read made with ctx
+func (s *Store) openOptionalField(ctx context.Context, value *string) *string {
+	if value == nil || s.cipher == nil {
+		return value
+	}
+	opened := s.openField(ctx, *value)
+	return &opened
+}
+
+// Redacted returns value for a log entry, or a placeholder while sensitive fields are encrypted, so logs do
+// not keep in plaintext what the database encrypts
+func (s *Store) Redacted(value string) string {
+	if s.cipher == nil || value == "" {
+		return value
+	}
+	return "[encrypted]"
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Fax directions: outbound faxes come from txfax, inbound from rxfax
+const (
+	FaxInbound  = "inbound"
+	FaxOutbound = "outbound"
+)
+
+// Fax is one fax transmission reported by mod_spandsp when txfax or rxfax finishes
+type Fax struct {
+	ID              int64     `json:"id"`
+	UUID            string    `json:"uuid"`
+	Direction       string    `json:"direction"`
+	Success         bool      `json:"success"`
+	ResultCode      *int      `json:"result_code,omitempty"`
+	ResultText      *string   `json:"result_text,omitempty"`
+	Pages           int       `json:"pages"` // Pages transferred
+	TotalPages      *int      `json:"total_pages,omitempty"`
+	RemoteStationID *string   `json:"remote_station_id,omitempty"`
+	LocalStationID  *string   `json:"local_station_id,omitempty"`
+	FilePath        *string   `json:"file_path,omitempty"`
+	TransferRate    *int      `json:"transfer_rate,omitempty"` // bps
+	ECMUsed         *bool     `json:"ecm_used,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
+
+// FaxFilter narrows fax queries; empty fields match everything
+type FaxFilter struct {
+	Direction string
+	Success   *bool
+}
+
+// faxColumns is the column list shared by fax queries
+const faxColumns = `id, uuid, direction, success, result_code, result_text, pages, total_pages, remote_station_id,
+	local_station_id, file_path, transfer_rate, ecm_used, occurred_at`
+
+// scanFax scans a row selected with faxColumns into f
+func scanFax(row pgx.Row, f *Fax) error {
+	return row.Scan(&f.ID, &f.UUID, &f.Direction, &f.Success, &f.ResultCode, &f.ResultText, &f.Pages, &f.TotalPages,
+		&f.RemoteStationID, &f.LocalStationID, &f.FilePath, &f.TransferRate, &f.ECMUsed, &f.OccurredAt)
+}
+
+// AddFax records a finished fax transmission
+func (s *Store) AddFax(ctx context.Context, f *Fax) error {
+	return s.write(ctx, writeOp{
+		name: "add_fax",
+		uuid: f.UUID,
+		query: `
+			INSERT INTO faxes (uuid, direction, success, result_code, result_text, pages, total_pages,
+				remote_station_id, local_station_id, file_path, transfer_rate, ecm_used, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		args: []any{f.UUID, f.Direction, f.Success, f.ResultCode, f.ResultText, f.Pages, f.TotalPages,
+			f.RemoteStationID, f.LocalStationID, f.FilePath, f.TransferRate, f.ECMUsed, f.OccurredAt},
+	})
+}
+
+// GetCallFaxes returns the faxes sent or received on a call in time order
+func (s *Store) GetCallFaxes(ctx context.Context, uuid string) ([]Fax, error) {
+	query := `
+		SELECT ` + faxColumns + `
+		FROM faxes
+		WHERE uuid = $1
+		ORDER BY occurred_at, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call faxes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var faxes []Fax
+	for rows.Next() {
+		var f Fax
+		if err := scanFax(rows, &f); err != nil {
+			s.log.WithError(err).Error("Error scanning fax row")
+			return nil, err
+		}
+		faxes = append(faxes, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating fax rows")
+		return nil, err
+	}
+	return faxes, nil
+}
+
+// GetFaxes returns faxes finished in [from, to) matching filter, newest first
+func (s *Store) GetFaxes(ctx context.Context, filter FaxFilter, from, to time.Time, limit, offset int) ([]Fax, error) {
+	query := `
+		SELECT ` + faxColumns + `
+		FROM faxes
+		WHERE occurred_at >= $1 AND occurred_at < $2
+			AND ($3 = '' OR direction = $3) AND ($4::boolean IS NULL OR success = $4)
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $5 OFFSET $6`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, filter.Direction, filter.Success, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting faxes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var faxes []Fax
+	for rows.Next() {
+		var f Fax
+		if err := scanFax(rows, &f); err != nil {
+			s.log.WithError(err).Error("Error scanning fax row")
+			return nil, err
+		}
+		faxes = append(faxes, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating fax rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(faxes),
+	}).Info("Retrieved faxes")
+	return faxes, nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ExtensionFeatures is the do-not-disturb and call-forward state of an extension.
+// An empty forward destination means that forward is off.
+type ExtensionFeatures struct {
+	Extension       string     `json:"extension"`
+	Domain          string     `json:"domain"`
+	DND             bool       `json:"dnd"`
+	ForwardAlways   string     `json:"forward_always"`
+	ForwardBusy     string     `json:"forward_busy"`
+	ForwardNoAnswer string     `json:"forward_no_answer"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"` // Unset for extensions never configured
+}
+
+// featureColumns lists the extension_features columns read by scanFeatures
+const featureColumns = `extension, domain, dnd, forward_always, forward_busy, forward_no_answer, updated_at`
+
+func scanFeatures(row pgx.Row, f *ExtensionFeatures) error {
+	return row.Scan(&f.Extension, &f.Domain, &f.DND, &f.ForwardAlways, &f.ForwardBusy, &f.ForwardNoAnswer, &f.UpdatedAt)
+}
+
+// GetExtensionFeatures returns the stored state of extension in domain. Extensions without a stored
+// state are returned with every feature off.
+func (s *Store) GetExtensionFeatures(ctx context.Context, extension, domain string) (*ExtensionFeatures, error) {
+	query := `
+		SELECT ` + featureColumns + `
+		FROM extension_features
+		WHERE extension = $1 AND domain = $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	f := ExtensionFeatures{Extension: extension, Domain: domain}
+	err := scanFeatures(s.db.QueryRow(ctxTimeout, query, extension, domain), &f)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &f, nil
+	}
+	if err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{"extension": extension, "domain": domain}).Error("Error getting extension features")
+		return nil, err
+	}
+	return &f, nil
+}
+
+// SaveExtensionFeatures stores the full feature state of an extension, setting f.UpdatedAt
+func (s *Store) SaveExtensionFeatures(ctx context.Context, f *ExtensionFeatures) error {
+	query := `
+		INSERT INTO extension_features (extension, domain, dnd, forward_always, forward_busy, forward_no_answer, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (extension, domain) DO UPDATE SET
+			dnd = EXCLUDED.dnd,
+			forward_always = EXCLUDED.forward_always,
+			forward_busy = EXCLUDED.forward_busy,
+			forward_no_answer = EXCLUDED.forward_no_answer,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var updatedAt time.Time
+	err := s.db.QueryRow(ctxTimeout, query, f.Extension, f.Domain, f.DND, f.ForwardAlways, f.ForwardBusy, f.ForwardNoAnswer).Scan(&updatedAt)
+	if err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{"extension": f.Extension, "domain": f.Domain}).Error("Error saving extension features")
+		return err
+	}
+	f.UpdatedAt = &updatedAt
+
+	s.log.WithFields(logrus.Fields{
+		"extension": f.Extension,
+		"domain":    f.Domain,
+		"dnd":       f.DND,
+	}).Info("Saved extension features")
+	return nil
+}
+
+// GetExtensionFeaturesList returns stored feature states, optionally limited to one domain
+func (s *Store) GetExtensionFeaturesList(ctx context.Context, domain string, limit, offset int) ([]ExtensionFeatures, error) {
+	query := `
+		SELECT ` + featureColumns + `
+		FROM extension_features
+		WHERE ($1 = '' OR domain = $1)
+		ORDER BY domain, extension
+		LIMIT $2 OFFSET $3`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, domain, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting extension features")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ExtensionFeatures
+	for rows.Next() {
+		var f ExtensionFeatures
+		if err := scanFeatures(rows, &f); err != nil {
+			s.log.WithError(err).Error("Error scanning extension features row")
+			return nil, err
+		}
+		list = append(list, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating extension features rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"domain": domain,
+		"count":  len(list),
+	}).Info("Retrieved extension features")
+	return list, nil
+}
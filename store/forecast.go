@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WeeklyPeak is the highest number of calls in progress at once during one week
+type WeeklyPeak struct {
+	WeekStart time.Time  `json:"week_start"`
+	PeakCalls int64      `json:"peak_calls"`
+	PeakAt    *time.Time `json:"peak_at,omitempty"` // When the peak was first reached; unset for idle weeks
+}
+
+// GetWeeklyPeakConcurrency returns the peak concurrent calls of every week in [from, to), with weeks starting
+// on Monday in time zone tz; from should be a week start. Calls still in progress count up to now. If gateway
+// is non-empty only calls on that gateway are counted. Weeks without calls have a zero peak.
+func (s *Store) GetWeeklyPeakConcurrency(ctx context.Context, from, to time.Time, tz, gateway string) ([]WeeklyPeak, error) {
+	query := `
+		WITH weeks AS (
+			SELECT w FROM generate_series($1::timestamptz AT TIME ZONE $3, ($2::timestamptz AT TIME ZONE $3) - interval '1 week', interval '1 week') AS w
+		),
+		spans AS (
+			SELECT start_time, COALESCE(end_time, now()) AS end_time
+			FROM calls
+			WHERE start_time < $2 AND COALESCE(end_time, now()) > $1 AND ($4 = '' OR gateway = $4)
+		),
+		changes AS (
+			SELECT start_time AS t, 1 AS delta FROM spans
+			UNION ALL
+			SELECT end_time, -1 FROM spans
+			UNION ALL
+			SELECT w AT TIME ZONE $3, 0 FROM weeks -- Carries calls spanning a whole week into it
+		),
+		running AS (
+			-- Hangups sort before seizures at the same instant, so back-to-back calls don't overlap
+			SELECT t, SUM(delta) OVER (ORDER BY t, delta ROWS UNBOUNDED PRECEDING) AS concurrent
+			FROM changes
+		),
+		ranked AS (
+			SELECT date_trunc('week', t AT TIME ZONE $3) AS week, concurrent, t,
+				ROW_NUMBER() OVER (PARTITION BY date_trunc('week', t AT TIME ZONE $3) ORDER BY concurrent DESC, t) AS rank
+			FROM running
+			WHERE t >= $1 AND t < $2
+		)
+		SELECT weeks.w AT TIME ZONE $3, COALESCE(r.concurrent, 0)::bigint, CASE WHEN r.concurrent > 0 THEN r.t END
+		FROM weeks
+		LEFT JOIN ranked r ON r.week = weeks.w AND r.rank = 1
+		ORDER BY weeks.w`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, tz, gateway)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting weekly peak concurrency")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var peaks []WeeklyPeak
+	for rows.Next() {
+		var p WeeklyPeak
+		if err := rows.Scan(&p.WeekStart, &p.PeakCalls, &p.PeakAt); err != nil {
+			s.log.WithError(err).Error("Error scanning weekly peak concurrency row")
+			return nil, err
+		}
+		peaks = append(peaks, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating weekly peak concurrency rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":    from,
+		"to":      to,
+		"gateway": gateway,
+		"weeks":   len(peaks),
+	}).Info("Retrieved weekly peak concurrency")
+	return peaks, nil
+}
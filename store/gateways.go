@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Gateway availability derived from sofia::gateway_state
+const (
+	GatewayUp   = "UP"
+	GatewayDown = "DOWN"
+)
+
+// GatewayState is the last reported registration and ping state of a sofia gateway
+type GatewayState struct {
+	Name            string     `json:"name"`
+	State           string     `json:"state"` // Registration state, e.g. REGED, NOREG, TRYING, FAILED, FAIL_WAIT
+	PingStatus      *string    `json:"ping_status,omitempty"`
+	Status          *string    `json:"status,omitempty"` // UP or DOWN; unset until a conclusive state is seen
+	Phrase          *string    `json:"phrase,omitempty"` // SIP reason of the last registration attempt
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	Failures        int        `json:"failures"` // Times the gateway went DOWN
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// SetGatewayState records a gateway state change. A nil Status (a transitional state such as TRYING)
+// keeps the previous UP/DOWN status; a change to DOWN counts as a failure.
+func (s *Store) SetGatewayState(ctx context.Context, g *GatewayState) error {
+	return s.write(ctx, writeOp{
+		name: "set_gateway_state",
+		uuid: g.Name,
+		query: `
+			INSERT INTO gateways AS g (name, state, ping_status, status, phrase, status_changed_at, failures, updated_at)
+			VALUES ($1, $2, $3, $4, $5, CASE WHEN $4::text IS NULL THEN NULL ELSE $6::timestamptz END,
+				CASE WHEN $4 = 'DOWN' THEN 1 ELSE 0 END, $6)
+			ON CONFLICT (name) DO UPDATE SET
+				state = EXCLUDED.state,
+				ping_status = COALESCE(EXCLUDED.ping_status, g.ping_status),
+				status = COALESCE(EXCLUDED.status, g.status),
+				phrase = COALESCE(EXCLUDED.phrase, g.phrase),
+				status_changed_at = CASE WHEN EXCLUDED.status IS DISTINCT FROM g.status AND EXCLUDED.status IS NOT NULL
+					THEN EXCLUDED.updated_at ELSE g.status_changed_at END,
+				failures = g.failures + CASE WHEN EXCLUDED.status = 'DOWN' AND g.status IS DISTINCT FROM 'DOWN' THEN 1 ELSE 0 END,
+				updated_at = EXCLUDED.updated_at`,
+		args: []any{g.Name, g.State, g.PingStatus, g.Status, g.Phrase, g.UpdatedAt},
+	})
+}
+
+// GetGatewayStates returns every gateway that has reported a state, by name
+func (s *Store) GetGatewayStates(ctx context.Context) ([]GatewayState, error) {
+	query := `
+		SELECT name, state, ping_status, status, phrase, status_changed_at, failures, updated_at
+		FROM gateways
+		ORDER BY name`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting gateway states")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gateways []GatewayState
+	for rows.Next() {
+		var g GatewayState
+		if err := rows.Scan(&g.Name, &g.State, &g.PingStatus, &g.Status, &g.Phrase, &g.StatusChangedAt, &g.Failures, &g.UpdatedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning gateway state row")
+			return nil, err
+		}
+		gateways = append(gateways, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating gateway state rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"count": len(gateways),
+	}).Info("Retrieved gateway states")
+	return gateways, nil
+}
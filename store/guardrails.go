@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrQueryTooBroad is returned for API reads rejected before running because they would scan or return
+// too many rows
+var ErrQueryTooBroad = errors.New("query too broad")
+
+// ReadLimits are the guardrails on API-driven reads, so a careless dashboard query cannot hold database
+// connections and I/O that ingestion needs. Zero fields disable the respective check.
+type ReadLimits struct {
+	MaxScanRows int64 // Reject list queries planned as a sequential scan over a table with more rows
+	MaxRows     int   // Reject pages reaching past this many rows (offset + limit)
+}
+
+// SetReadLimits configures the guardrails applied to reads made with a WithAPIRead context
+func (s *Store) SetReadLimits(l ReadLimits) {
+	s.readLimits = l
+}
+
+type apiReadKey struct{}
+
+// WithAPIRead marks ctx as an API request, subject to the read guardrails and the API statement timeout
+func WithAPIRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, apiReadKey{}, true)
+}
+
+// isAPIRead reports whether ctx was marked by WithAPIRead
+func isAPIRead(ctx context.Context) bool {
+	read, _ := ctx.Value(apiReadKey{}).(bool)
+	return read
+}
+
+// LimitAPIStatements makes the pool apply timeout as statement_timeout to connections acquired with a
+// WithAPIRead context, and restore the connection's default for all others, so ingest writes keep their
+// own timeouts. It chains any BeforeAcquire hook already set. Not usable behind a transaction pooler,
+// where the setting would outlive the request.
+func LimitAPIStatements(cfg *pgxpool.Config, timeout time.Duration) {
+	next := cfg.BeforeAcquire
+	ms := strconv.FormatInt(timeout.Milliseconds(), 10)
+	cfg.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+		var err error
+		if isAPIRead(ctx) {
+			_, err = conn.Exec(ctx, `SELECT set_config('statement_timeout', $1, false)`, ms)
+		} else {
+			_, err = conn.Exec(ctx, `RESET statement_timeout`)
+		}
+		if err != nil {
+			return false
+		}
+		return next == nil || next(ctx, conn)
+	}
+}
+
+// IsStatementTimeout reports whether err is PostgreSQL cancelling a statement that ran past
+// statement_timeout
+func IsStatementTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014" // query_canceled
+}
+
+// checkPage rejects API reads whose page reaches past ReadLimits.MaxRows
+func (s *Store) checkPage(ctx context.Context, limit, offset int) error {
+	if !isAPIRead(ctx) || s.readLimits.MaxRows <= 0 || offset+limit <= s.readLimits.MaxRows {
+		return nil
+	}
+	return fmt.Errorf("%w: offset %d + limit %d exceeds the %d-row maximum", ErrQueryTooBroad, offset, limit, s.readLimits.MaxRows)
+}
+
+// planNode is the part of EXPLAIN (FORMAT JSON) output inspected by checkPlan
+type planNode struct {
+	NodeType string     `json:"Node Type"`
+	Relation string     `json:"Relation Name"`
+	Plans    []planNode `json:"Plans"`
+}
+
+// checkPlan rejects API reads the planner would run as a sequential scan of a table larger than
+// ReadLimits.MaxScanRows, i.e. filters no index can narrow
+func (s *Store) checkPlan(ctx context.Context, query string, args ...any) error {
+	if !isAPIRead(ctx) || s.readLimits.MaxScanRows <= 0 {
+		return nil
+	}
+	var out string
+	if err := s.db.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&out); err != nil {
+		return err
+	}
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(out), &plans); err != nil {
+		return fmt.Errorf("parse query plan: %w", err)
+	}
+
+	var scanned []string
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		if (n.NodeType == "Seq Scan" || n.NodeType == "Parallel Seq Scan") && n.Relation != "" {
+			scanned = append(scanned, n.Relation)
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	for _, p := range plans {
+		walk(p.Plan)
+	}
+
+	for _, table := range scanned {
+		var rows float64
+		if err := s.db.QueryRow(ctx, `SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)`, table).Scan(&rows); err != nil {
+			return err
+		}
+		if int64(rows) > s.readLimits.MaxScanRows {
+			return fmt.Errorf("%w: the filters would scan all ~%d rows of %s (limit %d)", ErrQueryTooBroad, int64(rows), table, s.readLimits.MaxScanRows)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Components whose health is recorded in health_transitions
+const (
+	ComponentESL      = "esl"      // Instance is the node name
+	ComponentDatabase = "database" // Transitions follow the circuit breaker, so none are recorded without one
+	ComponentAPI      = "api"
+)
+
+// Health states recorded in health_transitions
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// maxPendingHealth caps transitions held in memory while the database is unreachable
+const maxPendingHealth = 1000
+
+// HealthTransition is a component changing between up and down
+type HealthTransition struct {
+	Component  string
+	Instance   string
+	State      string
+	Reason     *string
+	OccurredAt time.Time
+}
+
+// healthLog queues health transitions that could not be written yet, most importantly the database's own
+// down transition, which is only writable once the database is back.
+type healthLog struct {
+	mu      sync.Mutex
+	pending []HealthTransition
+}
+
+// RecordHealth records a health transition. Transitions are written in order; any that fail are kept
+// and retried with the next one.
+func (s *Store) RecordHealth(ctx context.Context, t HealthTransition) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	s.health.pending = append(s.health.pending, t)
+	if n := len(s.health.pending) - maxPendingHealth; n > 0 {
+		s.health.pending = s.health.pending[n:] // Drop the oldest
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for len(s.health.pending) > 0 {
+		p := s.health.pending[0]
+		_, err := s.db.Exec(ctxTimeout, `
+			INSERT INTO health_transitions (component, instance, state, reason, occurred_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			p.Component, p.Instance, p.State, p.Reason, p.OccurredAt)
+		if err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{
+				"component": p.Component,
+				"pending":   len(s.health.pending),
+			}).Warn("Error recording health transition, will retry with the next one")
+			return
+		}
+		s.health.pending = s.health.pending[1:]
+	}
+}
+
+// UptimeStats is the availability of one component instance over a window
+type UptimeStats struct {
+	Component       string    `json:"component"`
+	Instance        string    `json:"instance,omitempty"`
+	State           string    `json:"state"` // Latest recorded state
+	Since           time.Time `json:"since"` // When the latest state was entered
+	UpSeconds       float64   `json:"up_seconds"`
+	DownSeconds     float64   `json:"down_seconds"`
+	Outages         int64     `json:"outages"`          // Down transitions inside the window
+	AvailabilityPct *float64  `json:"availability_pct"` // Nil when nothing was observed in the window
+}
+
+// GetUptimeStats returns per-component availability over [from, to). Time before a component's first
+// recorded transition is not observed and counts neither up nor down.
+func (s *Store) GetUptimeStats(ctx context.Context, from, to time.Time) ([]UptimeStats, error) {
+	query := `
+		WITH spans AS (
+			SELECT component, instance, state, occurred_at,
+				LEAD(occurred_at) OVER (PARTITION BY component, instance ORDER BY occurred_at, id) AS next_at
+			FROM health_transitions
+			WHERE occurred_at < $2
+		),
+		clipped AS (
+			SELECT component, instance, state, occurred_at,
+				EXTRACT(EPOCH FROM LEAST(COALESCE(next_at, $2), $2) - GREATEST(occurred_at, $1))::float8 AS secs
+			FROM spans
+			WHERE COALESCE(next_at, $2) > $1
+		)
+		SELECT component, instance,
+			(ARRAY_AGG(state ORDER BY occurred_at DESC))[1],
+			MAX(occurred_at),
+			COALESCE(SUM(secs) FILTER (WHERE state = 'up'), 0),
+			COALESCE(SUM(secs) FILTER (WHERE state = 'down'), 0),
+			COUNT(*) FILTER (WHERE state = 'down' AND occurred_at >= $1)
+		FROM clipped
+		GROUP BY component, instance
+		ORDER BY component, instance`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting uptime stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []UptimeStats
+	for rows.Next() {
+		var u UptimeStats
+		if err := rows.Scan(&u.Component, &u.Instance, &u.State, &u.Since, &u.UpSeconds, &u.DownSeconds, &u.Outages); err != nil {
+			s.log.WithError(err).Error("Error scanning uptime stats row")
+			return nil, err
+		}
+		if observed := u.UpSeconds + u.DownSeconds; observed > 0 {
+			pct := u.UpSeconds / observed * 100
+			u.AvailabilityPct = &pct
+		}
+		stats = append(stats, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating uptime stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(stats),
+	}).Info("Retrieved uptime stats")
+	return stats, nil
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HoldInterval is one period a call spent on hold
+type HoldInterval struct {
+	ID        int64      `json:"id"`
+	UUID      string     `json:"uuid"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"` // Nil while the call is still on hold
+	Seconds   *float64   `json:"seconds,omitempty"`
+}
+
+// StartHold opens a hold interval for a call and counts it, unless the call is already on hold
+func (s *Store) StartHold(ctx context.Context, uuid string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "start_hold",
+		uuid: uuid,
+		query: `
+			WITH opened AS (
+				INSERT INTO hold_intervals (uuid, started_at)
+				SELECT $1, $2
+				WHERE NOT EXISTS (SELECT 1 FROM hold_intervals WHERE uuid = $1 AND ended_at IS NULL)
+				RETURNING uuid
+			)
+			UPDATE calls SET hold_count = hold_count + 1
+			WHERE uuid IN (SELECT uuid FROM opened)`,
+		args: []any{uuid, at},
+	})
+}
+
+// EndHold closes the open hold interval of a call and adds its length to the call's total hold time.
+// It does nothing when the call is not on hold, so it is safe to call at hangup.
+func (s *Store) EndHold(ctx context.Context, uuid string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "end_hold",
+		uuid: uuid,
+		query: `
+			WITH closed AS (
+				UPDATE hold_intervals SET ended_at = GREATEST($2::timestamptz, started_at)
+				WHERE uuid = $1 AND ended_at IS NULL
+				RETURNING uuid, EXTRACT(EPOCH FROM (ended_at - started_at))::float8 AS seconds
+			)
+			UPDATE calls SET hold_seconds = hold_seconds + closed.seconds
+			FROM closed
+			WHERE calls.uuid = closed.uuid`,
+		args: []any{uuid, at},
+	})
+}
+
+// GetHoldIntervals returns the hold intervals of a call in order
+func (s *Store) GetHoldIntervals(ctx context.Context, uuid string) ([]HoldInterval, error) {
+	query := `
+		SELECT id, uuid, started_at, ended_at, EXTRACT(EPOCH FROM (ended_at - started_at))::float8
+		FROM hold_intervals
+		WHERE uuid = $1
+		ORDER BY started_at, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call hold intervals")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []HoldInterval
+	for rows.Next() {
+		var h HoldInterval
+		if err := rows.Scan(&h.ID, &h.UUID, &h.StartedAt, &h.EndedAt, &h.Seconds); err != nil {
+			s.log.WithError(err).Error("Error scanning hold interval row")
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating hold interval rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(holds),
+	}).Info("Retrieved call hold intervals")
+	return holds, nil
+}
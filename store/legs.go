@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxFlowLegs caps how many bridges GetCallFlow follows, guarding against runaway transfer chains
+const maxFlowLegs = 500
+
+// CallLeg is one bridge between two channels. A channel bridged several times (e.g. after a transfer)
+// has one row per bridge.
+type CallLeg struct {
+	ID          int64      `json:"id"`
+	AUUID       string     `json:"a_uuid"`
+	BUUID       string     `json:"b_uuid"`
+	BridgedAt   time.Time  `json:"bridged_at"`
+	UnbridgedAt *time.Time `json:"unbridged_at,omitempty"`
+}
+
+// CallFlow is every call record and bridge connected to a call
+type CallFlow struct {
+	Calls []Call    `json:"calls"`
+	Legs  []CallLeg `json:"legs"`
+}
+
+// AddCallLeg records a bridge between two channels. A repeated bridge event for a pair that is still
+// bridged is ignored.
+func (s *Store) AddCallLeg(ctx context.Context, aUUID, bUUID string, bridgedAt time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "add_call_leg",
+		uuid: aUUID,
+		query: `
+			INSERT INTO call_legs (a_uuid, b_uuid, bridged_at)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (
+				SELECT 1 FROM call_legs
+				WHERE unbridged_at IS NULL AND ((a_uuid = $1 AND b_uuid = $2) OR (a_uuid = $2 AND b_uuid = $1))
+			)`,
+		args: []any{aUUID, bUUID, bridgedAt},
+	})
+}
+
+// EndCallLeg records that the bridge between two channels ended, in either direction
+func (s *Store) EndCallLeg(ctx context.Context, aUUID, bUUID string, unbridgedAt time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "end_call_leg",
+		uuid: aUUID,
+		query: `
+			UPDATE call_legs
+			SET unbridged_at = $3
+			WHERE unbridged_at IS NULL AND ((a_uuid = $1 AND b_uuid = $2) OR (a_uuid = $2 AND b_uuid = $1))`,
+		args:         []any{aUUID, bUUID, unbridgedAt},
+		warnIfNoRows: true,
+	})
+}
+
+// GetCallFlow returns every bridge reachable from uuid, following legs in both directions, together with
+// the call records of the channels involved. A call that was never bridged yields just its own record.
+func (s *Store) GetCallFlow(ctx context.Context, uuid string) (*CallFlow, error) {
+	legQuery := `
+		WITH RECURSIVE flow(uuid) AS (
+			SELECT $1::text
+			UNION
+			SELECT CASE WHEN l.a_uuid = f.uuid THEN l.b_uuid ELSE l.a_uuid END
+			FROM call_legs l
+			JOIN flow f ON l.a_uuid = f.uuid OR l.b_uuid = f.uuid
+		)
+		SELECT DISTINCT l.id, l.a_uuid, l.b_uuid, l.bridged_at, l.unbridged_at
+		FROM call_legs l
+		JOIN flow f ON l.a_uuid = f.uuid
+		ORDER BY l.bridged_at, l.id
+		LIMIT $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, legQuery, uuid, maxFlowLegs)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call legs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	flow := &CallFlow{}
+	uuids := []string{uuid}
+	seen := map[string]bool{uuid: true}
+	for rows.Next() {
+		var leg CallLeg
+		if err := rows.Scan(&leg.ID, &leg.AUUID, &leg.BUUID, &leg.BridgedAt, &leg.UnbridgedAt); err != nil {
+			s.log.WithError(err).WithField("uuid", uuid).Error("Error scanning call leg row")
+			return nil, err
+		}
+		flow.Legs = append(flow.Legs, leg)
+		for _, id := range []string{leg.AUUID, leg.BUUID} {
+			if !seen[id] {
+				seen[id] = true
+				uuids = append(uuids, id)
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error iterating call leg rows")
+		return nil, err
+	}
+
+	callRows, err := s.db.Query(ctxTimeout, `
+		SELECT `+callColumns+`
+		FROM calls
+		WHERE uuid = ANY($1)
+		ORDER BY start_time, id`, uuids)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call flow records")
+		return nil, err
+	}
+	defer callRows.Close()
+
+	for callRows.Next() {
+		var call Call
+		if err := scanCall(callRows, &call); err != nil {
+			s.log.WithError(err).WithField("uuid", uuid).Error("Error scanning call flow row")
+			return nil, err
+		}
+		s.openCall(ctx, &call)
+		flow.Calls = append(flow.Calls, call)
+	}
+	if err = callRows.Err(); err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error iterating call flow rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"calls": len(flow.Calls),
+		"legs":  len(flow.Legs),
+	}).Info("Retrieved call flow")
+	return flow, nil
+}
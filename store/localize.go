@@ -0,0 +1,251 @@
+package store
+
+import "time"
+
+// inLocation converts an optional timestamp to loc
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(loc)
+	return &local
+}
+
+// In converts the call's timestamps to loc
+func (c *Call) In(loc *time.Location) {
+	c.StartTime = c.StartTime.In(loc)
+	c.RingingTime = inLocation(c.RingingTime, loc)
+	c.EarlyMedia = inLocation(c.EarlyMedia, loc)
+	c.AnsweredTime = inLocation(c.AnsweredTime, loc)
+	c.BridgedTime = inLocation(c.BridgedTime, loc)
+	c.EndTime = inLocation(c.EndTime, loc)
+	c.CreatedAt = c.CreatedAt.In(loc)
+	c.LastCorrectedAt = inLocation(c.LastCorrectedAt, loc)
+	c.ClassifiedAt = inLocation(c.ClassifiedAt, loc)
+}
+
+// In converts the transition's timestamp to loc
+func (t *Transition) In(loc *time.Location) {
+	t.EventTime = t.EventTime.In(loc)
+}
+
+// In converts the stuck call's timestamps to loc
+func (sc *StuckCall) In(loc *time.Location) {
+	sc.Since = sc.Since.In(loc)
+	sc.StartTime = sc.StartTime.In(loc)
+}
+
+// In converts the domain's last call time to loc
+func (ds *DomainStats) In(loc *time.Location) {
+	ds.LastCallAt = ds.LastCallAt.In(loc)
+}
+
+// In converts the campaign's timestamps to loc
+func (c *Campaign) In(loc *time.Location) {
+	c.CreatedAt = c.CreatedAt.In(loc)
+	c.FinishedAt = inLocation(c.FinishedAt, loc)
+}
+
+// In converts the attempt's timestamps to loc
+func (a *CampaignAttempt) In(loc *time.Location) {
+	a.StartedAt = inLocation(a.StartedAt, loc)
+	a.FinishedAt = inLocation(a.FinishedAt, loc)
+}
+
+// In converts the switch event's timestamp to loc
+func (e *SwitchEvent) In(loc *time.Location) {
+	e.OccurredAt = e.OccurredAt.In(loc)
+}
+
+// In converts the audit entry's timestamp to loc
+func (e *AuditEntry) In(loc *time.Location) {
+	e.OccurredAt = e.OccurredAt.In(loc)
+}
+
+// In converts the parked call's timestamps to loc
+func (p *ParkedCall) In(loc *time.Location) {
+	p.ParkedAt = p.ParkedAt.In(loc)
+	p.ReleasedAt = inLocation(p.ReleasedAt, loc)
+}
+
+// In converts the feature state's update time to loc
+func (f *ExtensionFeatures) In(loc *time.Location) {
+	f.UpdatedAt = inLocation(f.UpdatedAt, loc)
+}
+
+// In converts the queue stats interval start to loc
+func (qs *QueueStats) In(loc *time.Location) {
+	qs.IntervalStart = inLocation(qs.IntervalStart, loc)
+}
+
+// In converts the callback's timestamps to loc
+func (cb *Callback) In(loc *time.Location) {
+	cb.OfferedAt = inLocation(cb.OfferedAt, loc)
+	cb.AcceptedAt = inLocation(cb.AcceptedAt, loc)
+	cb.CallbackStartedAt = inLocation(cb.CallbackStartedAt, loc)
+	cb.CallbackAnsweredAt = inLocation(cb.CallbackAnsweredAt, loc)
+}
+
+// In converts the leg's timestamps to loc
+func (l *CallLeg) In(loc *time.Location) {
+	l.BridgedAt = l.BridgedAt.In(loc)
+	l.UnbridgedAt = inLocation(l.UnbridgedAt, loc)
+}
+
+// In converts the busy hour start to loc
+func (bh *BusyHourStats) In(loc *time.Location) {
+	bh.BusyHourStart = bh.BusyHourStart.In(loc)
+}
+
+// In converts the event time to loc
+func (e *RawEvent) In(loc *time.Location) {
+	e.EventTime = e.EventTime.In(loc)
+}
+
+// In converts the summary's timestamps to loc
+func (e *CallEventSummary) In(loc *time.Location) {
+	e.FirstAt = e.FirstAt.In(loc)
+	e.LastAt = e.LastAt.In(loc)
+}
+
+// In converts the week's timestamps to loc
+func (p *WeeklyPeak) In(loc *time.Location) {
+	p.WeekStart = p.WeekStart.In(loc)
+	p.PeakAt = inLocation(p.PeakAt, loc)
+}
+
+// In converts the digit's timestamp to loc
+func (d *DTMF) In(loc *time.Location) {
+	d.EventTime = d.EventTime.In(loc)
+}
+
+// In converts the caller ID campaign's timestamps to loc
+func (m *CallerIDCampaign) In(loc *time.Location) {
+	m.CreatedAt = m.CreatedAt.In(loc)
+	m.UpdatedAt = m.UpdatedAt.In(loc)
+}
+
+// In converts the duplicate group's start time to loc
+func (g *DuplicateGroup) In(loc *time.Location) {
+	g.StartTime = g.StartTime.In(loc)
+}
+
+// In converts the gateway's timestamps to loc
+func (g *GatewayState) In(loc *time.Location) {
+	g.StatusChangedAt = inLocation(g.StatusChangedAt, loc)
+	g.UpdatedAt = g.UpdatedAt.In(loc)
+}
+
+// In converts the saved search's timestamps to loc
+func (ss *SavedSearch) In(loc *time.Location) {
+	ss.CreatedAt = ss.CreatedAt.In(loc)
+	ss.UpdatedAt = ss.UpdatedAt.In(loc)
+}
+
+// In converts the sample or bucket time to loc
+func (st *ServerStat) In(loc *time.Location) {
+	st.Time = st.Time.In(loc)
+}
+
+// In converts the application's timestamps to loc
+func (a *Application) In(loc *time.Location) {
+	a.StartedAt = inLocation(a.StartedAt, loc)
+	a.CompletedAt = inLocation(a.CompletedAt, loc)
+}
+
+// In converts the transfer time to loc
+func (t *Transfer) In(loc *time.Location) {
+	t.OccurredAt = t.OccurredAt.In(loc)
+}
+
+// In converts the hold interval's timestamps to loc
+func (h *HoldInterval) In(loc *time.Location) {
+	h.StartedAt = h.StartedAt.In(loc)
+	h.EndedAt = inLocation(h.EndedAt, loc)
+}
+
+// In converts the park interval's timestamps to loc
+func (p *ParkInterval) In(loc *time.Location) {
+	p.ParkedAt = p.ParkedAt.In(loc)
+	p.UnparkedAt = inLocation(p.UnparkedAt, loc)
+}
+
+// In converts the fax's timestamps to loc
+func (f *Fax) In(loc *time.Location) {
+	f.OccurredAt = f.OccurredAt.In(loc)
+}
+
+// In converts the media quality's timestamp to loc
+func (q *MediaQuality) In(loc *time.Location) {
+	q.RecordedAt = q.RecordedAt.In(loc)
+}
+
+// In converts the recording's timestamps to loc
+func (r *Recording) In(loc *time.Location) {
+	r.StartedAt = inLocation(r.StartedAt, loc)
+	r.StoppedAt = inLocation(r.StoppedAt, loc)
+}
+
+// In converts the registration's timestamps to loc
+func (r *Registration) In(loc *time.Location) {
+	r.RegisteredAt = r.RegisteredAt.In(loc)
+	r.RefreshedAt = r.RefreshedAt.In(loc)
+	r.ExpiresAt = inLocation(r.ExpiresAt, loc)
+	r.EndedAt = inLocation(r.EndedAt, loc)
+}
+
+// In converts the verto session's timestamps to loc
+func (v *VertoSession) In(loc *time.Location) {
+	v.ConnectedAt = v.ConnectedAt.In(loc)
+	v.DisconnectedAt = inLocation(v.DisconnectedAt, loc)
+}
+
+// In converts the conference's and its members' timestamps to loc
+func (c *Conference) In(loc *time.Location) {
+	c.StartedAt = c.StartedAt.In(loc)
+	c.EndedAt = inLocation(c.EndedAt, loc)
+	for i := range c.Members {
+		m := &c.Members[i]
+		m.JoinedAt = m.JoinedAt.In(loc)
+		m.LeftAt = inLocation(m.LeftAt, loc)
+		m.MutedAt = inLocation(m.MutedAt, loc)
+	}
+}
+
+// In converts the uptime stats' state change time to loc
+func (u *UptimeStats) In(loc *time.Location) {
+	u.Since = u.Since.In(loc)
+}
+
+// In converts the agent's change times to loc
+func (a *Agent) In(loc *time.Location) {
+	a.StatusChangedAt = inLocation(a.StatusChangedAt, loc)
+	a.StateChangedAt = inLocation(a.StateChangedAt, loc)
+}
+
+// In converts the agent transition's timestamp to loc
+func (t *AgentTransition) In(loc *time.Location) {
+	t.EventTime = t.EventTime.In(loc)
+}
+
+// In converts the queue's timestamps to loc
+func (q *Queue) In(loc *time.Location) {
+	q.OldestJoined = inLocation(q.OldestJoined, loc)
+	q.UpdatedAt = inLocation(q.UpdatedAt, loc)
+}
+
+// In converts the voicemail event's timestamp to loc
+func (e *VoicemailEvent) In(loc *time.Location) {
+	e.EventTime = e.EventTime.In(loc)
+}
+
+// In converts the mailbox's last message time to loc
+func (m *MailboxStats) In(loc *time.Location) {
+	m.LastLeftAt = inLocation(m.LastLeftAt, loc)
+}
+
+// In converts the message's timestamps to loc
+func (m *Message) In(loc *time.Location) {
+	m.CreatedAt = m.CreatedAt.In(loc)
+	m.UpdatedAt = m.UpdatedAt.In(loc)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrMediaQualityNotFound is returned when no RTP statistics were recorded for a call
+var ErrMediaQualityNotFound = errors.New("media quality not found")
+
+// MediaQuality holds the inbound RTP audio statistics FreeSWITCH reports for a call at hangup
+type MediaQuality struct {
+	UUID              string    `json:"uuid"`
+	MOS               *float64  `json:"mos,omitempty"` // 1-5, estimated from loss and jitter
+	QualityPercentage *float64  `json:"quality_percentage,omitempty"`
+	JitterMinVariance *float64  `json:"jitter_min_variance,omitempty"`
+	JitterMaxVariance *float64  `json:"jitter_max_variance,omitempty"`
+	JitterLossRate    *float64  `json:"jitter_loss_rate,omitempty"`
+	JitterBurstRate   *float64  `json:"jitter_burst_rate,omitempty"`
+	MeanInterval      *float64  `json:"mean_interval,omitempty"` // ms between packets
+	Flaws             *int64    `json:"flaws,omitempty"`
+	PacketsIn         *int64    `json:"packets_in,omitempty"`
+	PacketsOut        *int64    `json:"packets_out,omitempty"`
+	PacketsLost       *int64    `json:"packets_lost,omitempty"` // Skipped (missing) inbound packets
+	PacketLoss        *float64  `json:"packet_loss,omitempty"`  // Percent of expected inbound packets lost
+	RecordedAt        time.Time `json:"recorded_at"`
+}
+
+// MediaQualityFilter narrows media quality queries; nil fields match everything
+type MediaQualityFilter struct {
+	MaxMOS        *float64 // Calls with a MOS at or below this
+	MinPacketLoss *float64 // Calls losing at least this percent of packets
+}
+
+// mediaQualityColumns is the column list shared by media quality queries
+const mediaQualityColumns = `uuid, mos, quality_percentage, jitter_min_variance, jitter_max_variance, jitter_loss_rate,
+	jitter_burst_rate, mean_interval, flaws, packets_in, packets_out, packets_lost, packet_loss, recorded_at`
+
+// scanMediaQuality scans a row selected with mediaQualityColumns into q
+func scanMediaQuality(row pgx.Row, q *MediaQuality) error {
+	return row.Scan(&q.UUID, &q.MOS, &q.QualityPercentage, &q.JitterMinVariance, &q.JitterMaxVariance, &q.JitterLossRate,
+		&q.JitterBurstRate, &q.MeanInterval, &q.Flaws, &q.PacketsIn, &q.PacketsOut, &q.PacketsLost, &q.PacketLoss, &q.RecordedAt)
+}
+
+// SetMediaQuality stores the RTP statistics of a call, replacing any recorded earlier
+func (s *Store) SetMediaQuality(ctx context.Context, q *MediaQuality) error {
+	return s.write(ctx, writeOp{
+		name: "set_media_quality",
+		uuid: q.UUID,
+		query: `
+			INSERT INTO call_quality (` + mediaQualityColumns + `)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (uuid) DO UPDATE SET
+				mos = EXCLUDED.mos, quality_percentage = EXCLUDED.quality_percentage,
+				jitter_min_variance = EXCLUDED.jitter_min_variance, jitter_max_variance = EXCLUDED.jitter_max_variance,
+				jitter_loss_rate = EXCLUDED.jitter_loss_rate, jitter_burst_rate = EXCLUDED.jitter_burst_rate,
+				mean_interval = EXCLUDED.mean_interval, flaws = EXCLUDED.flaws,
+				packets_in = EXCLUDED.packets_in, packets_out = EXCLUDED.packets_out,
+				packets_lost = EXCLUDED.packets_lost, packet_loss = EXCLUDED.packet_loss,
+				recorded_at = EXCLUDED.recorded_at`,
+		args: []any{q.UUID, q.MOS, q.QualityPercentage, q.JitterMinVariance, q.JitterMaxVariance, q.JitterLossRate,
+			q.JitterBurstRate, q.MeanInterval, q.Flaws, q.PacketsIn, q.PacketsOut, q.PacketsLost, q.PacketLoss, q.RecordedAt},
+	})
+}
+
+// GetCallMediaQuality returns the RTP statistics of a call, or ErrMediaQualityNotFound
+func (s *Store) GetCallMediaQuality(ctx context.Context, uuid string) (*MediaQuality, error) {
+	query := `
+		SELECT ` + mediaQualityColumns + `
+		FROM call_quality
+		WHERE uuid = $1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var q MediaQuality
+	err := scanMediaQuality(s.db.QueryRow(ctxTimeout, query, uuid), &q)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMediaQualityNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call media quality")
+		return nil, err
+	}
+	return &q, nil
+}
+
+// GetMediaQuality returns the RTP statistics recorded in [from, to) matching filter, newest first
+func (s *Store) GetMediaQuality(ctx context.Context, filter MediaQualityFilter, from, to time.Time, limit, offset int) ([]MediaQuality, error) {
+	query := `
+		SELECT ` + mediaQualityColumns + `
+		FROM call_quality
+		WHERE recorded_at >= $1 AND recorded_at < $2
+			AND ($3::float8 IS NULL OR mos <= $3) AND ($4::float8 IS NULL OR packet_loss >= $4)
+		ORDER BY recorded_at DESC
+		LIMIT $5 OFFSET $6`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, filter.MaxMOS, filter.MinPacketLoss, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting media quality")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []MediaQuality
+	for rows.Next() {
+		var q MediaQuality
+		if err := scanMediaQuality(rows, &q); err != nil {
+			s.log.WithError(err).Error("Error scanning media quality row")
+			return nil, err
+		}
+		stats = append(stats, q)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating media quality rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(stats),
+	}).Info("Retrieved media quality")
+	return stats, nil
+}
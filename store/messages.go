@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrMessageNotFound is returned when a message id does not exist
+var ErrMessageNotFound = errors.New("message not found")
+
+// Message directions
+const (
+	MessageInbound  = "inbound"  // Received from the network
+	MessageOutbound = "outbound" // Sent by FreeSWITCH
+)
+
+// Message delivery statuses
+const (
+	MessageReceived  = "received"
+	MessageSent      = "sent"
+	MessageDelivered = "delivered" // Delivery report without failure
+	MessageFailed    = "failed"    // Delivery report with failure
+)
+
+// Message is an SMS or SIP MESSAGE handled by mod_sms
+type Message struct {
+	ID          int64     `json:"id"`
+	MessageID   *string   `json:"message_id,omitempty"` // Correlates delivery reports
+	Direction   string    `json:"direction"`
+	Proto       *string   `json:"proto,omitempty"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Body        string    `json:"body"`
+	ContentType *string   `json:"content_type,omitempty"`
+	Status      string    `json:"status"`
+	StatusCode  *string   `json:"status_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MessageFilter narrows message queries; empty fields match everything
+type MessageFilter struct {
+	Direction string
+	Status    string
+	Address   string // Matches either the sender or the recipient
+}
+
+// messageColumns is the column list shared by message queries
+const messageColumns = `id, message_id, direction, proto, from_addr, to_addr, body, content_type, status, status_code,
+	created_at, updated_at`
+
+// scanMessage scans a row selected with messageColumns into m
+func scanMessage(row pgx.Row, m *Message) error {
+	return row.Scan(&m.ID, &m.MessageID, &m.Direction, &m.Proto, &m.From, &m.To, &m.Body, &m.ContentType,
+		&m.Status, &m.StatusCode, &m.CreatedAt, &m.UpdatedAt)
+}
+
+// AddMessage records a message. A message whose message_id is already stored is ignored.
+func (s *Store) AddMessage(ctx context.Context, m *Message) error {
+	return s.write(ctx, writeOp{
+		name: "add_message",
+		query: `
+			INSERT INTO messages (message_id, direction, proto, from_addr, to_addr, body, content_type, status,
+				created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+			ON CONFLICT (message_id) WHERE message_id IS NOT NULL DO NOTHING`,
+		args: []any{m.MessageID, m.Direction, m.Proto, m.From, m.To, m.Body, m.ContentType, m.Status, m.CreatedAt},
+	})
+}
+
+// SetMessageStatus records a delivery report for the message with messageID
+func (s *Store) SetMessageStatus(ctx context.Context, messageID, status string, code *string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "set_message_status",
+		query: `
+			UPDATE messages
+			SET status = $2, status_code = $3, updated_at = $4
+			WHERE message_id = $1`,
+		args:         []any{messageID, status, code, at},
+		warnIfNoRows: true,
+	})
+}
+
+// GetMessages returns messages created in [from, to) matching filter, newest first
+func (s *Store) GetMessages(ctx context.Context, filter MessageFilter, from, to time.Time, limit, offset int) ([]Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE created_at >= $1 AND created_at < $2
+			AND ($3 = '' OR direction = $3) AND ($4 = '' OR status = $4)
+			AND ($5 = '' OR from_addr = $5 OR to_addr = $5)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6 OFFSET $7`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, filter.Direction, filter.Status, filter.Address, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting messages")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := scanMessage(rows, &m); err != nil {
+			s.log.WithError(err).Error("Error scanning message row")
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating message rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(messages),
+	}).Info("Retrieved messages")
+	return messages, nil
+}
+
+// GetMessage returns the message with the given id
+func (s *Store) GetMessage(ctx context.Context, id int64) (*Message, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var m Message
+	err := scanMessage(s.db.QueryRow(ctxTimeout, `SELECT `+messageColumns+` FROM messages WHERE id = $1`, id), &m)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("messageID", id).Error("Error getting message")
+		return nil, err
+	}
+	return &m, nil
+}
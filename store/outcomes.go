@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCallNotFound is returned when no call has the given UUID
+var ErrCallNotFound = errors.New("call not found")
+
+// Sources of a call's outcome label
+const (
+	OutcomeSourceRules = "rules" // Embedded OUTCOME_RULES
+	OutcomeSourceHook  = "hook"  // OUTCOME_HOOK_URL response
+	OutcomeSourceAPI   = "api"   // PUT /calls/{uuid}/outcome
+)
+
+// OutcomeStats summarizes the calls classified with one outcome label
+type OutcomeStats struct {
+	Outcome       string  `json:"outcome"`
+	Calls         int64   `json:"calls"`
+	AnsweredCalls int64   `json:"answered_calls"`
+	TotalBillable float64 `json:"total_billable_seconds"`
+	TotalCost     float64 `json:"total_cost"`
+}
+
+// GetUnclassifiedCalls returns up to limit calls that ended in [since, before) and have not been classified,
+// oldest first
+func (s *Store) GetUnclassifiedCalls(ctx context.Context, since, before time.Time, limit int) ([]Call, error) {
+	query := `
+		SELECT ` + callColumns + `
+		FROM calls
+		WHERE classified_at IS NULL AND end_time >= $1 AND end_time < $2
+		ORDER BY end_time, id
+		LIMIT $3`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, since, before, limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting unclassified calls")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []Call
+	for rows.Next() {
+		var call Call
+		if err := scanCall(rows, &call); err != nil {
+			s.log.WithError(err).Error("Error scanning unclassified call row")
+			return nil, err
+		}
+		s.openCall(ctx, &call)
+		calls = append(calls, call)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating unclassified call rows")
+		return nil, err
+	}
+	return calls, nil
+}
+
+// SetCallOutcome records the outcome of a call and marks it classified. A nil outcome marks the call as
+// classified without a label, so the classifier does not pick it up again.
+func (s *Store) SetCallOutcome(ctx context.Context, uuid string, outcome *string, source string) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var src *string
+	if outcome != nil {
+		src = &source
+	}
+	tag, err := s.db.Exec(ctxTimeout, `
+		UPDATE calls
+		SET outcome = $1, outcome_source = $2, classified_at = now()
+		WHERE uuid = $3`,
+		outcome, src, uuid,
+	)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error setting call outcome")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCallNotFound
+	}
+	return nil
+}
+
+// GetOutcomeStats returns per-outcome call figures for classified calls started in [from, to)
+func (s *Store) GetOutcomeStats(ctx context.Context, from, to time.Time) ([]OutcomeStats, error) {
+	query := `
+		SELECT outcome, COUNT(*), COUNT(answered_time),
+			COALESCE(SUM(COALESCE(billsec, EXTRACT(EPOCH FROM (end_time - answered_time)))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(SUM(cost), 0)::float8
+		FROM calls
+		WHERE outcome IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY outcome
+		ORDER BY COUNT(*) DESC, outcome`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting outcome stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []OutcomeStats
+	for rows.Next() {
+		var st OutcomeStats
+		if err := rows.Scan(&st.Outcome, &st.Calls, &st.AnsweredCalls, &st.TotalBillable, &st.TotalCost); err != nil {
+			s.log.WithError(err).Error("Error scanning outcome stats row")
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating outcome stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(stats),
+	}).Info("Retrieved outcome stats")
+	return stats, nil
+}
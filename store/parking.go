@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+)
+
+// Parked call statuses
+const (
+	ParkStatusParked    = "parked"
+	ParkStatusRetrieved = "retrieved"
+	ParkStatusHungUp    = "hungup" // The caller hung up while parked
+	ParkStatusFailed    = "failed" // FreeSWITCH refused the park; the slot was never occupied
+)
+
+var (
+	ErrSlotTaken       = errors.New("parking slot is taken")
+	ErrNoFreeSlot      = errors.New("no free parking slot")
+	ErrAlreadyParked   = errors.New("call is already parked")
+	ErrParkingNotFound = errors.New("no call parked in slot")
+)
+
+// ParkedCall is a call held in a valet parking lot slot
+type ParkedCall struct {
+	ID          int64      `json:"id"`
+	UUID        string     `json:"uuid"`
+	Lot         string     `json:"lot"`
+	Slot        int        `json:"slot"`
+	Status      string     `json:"status"`
+	Caller      string     `json:"caller"`
+	Callee      string     `json:"callee"`
+	ParkedAt    time.Time  `json:"parked_at"`
+	ReleasedAt  *time.Time `json:"released_at,omitempty"`
+	RetrievedTo *string    `json:"retrieved_to,omitempty"`
+}
+
+// ParkCall reserves a slot in lot for the call. If p.Slot is zero the lowest free slot between minSlot and
+// maxSlot is used. The assigned slot is written back to p.
+func (s *Store) ParkCall(ctx context.Context, p *ParkedCall, minSlot, maxSlot int) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var err error
+	if p.Slot != 0 {
+		err = s.db.QueryRow(ctxTimeout, `
+			INSERT INTO parked_calls (uuid, lot, slot, status, parked_at)
+			VALUES ($1, $2, $3, $4, now())
+			RETURNING id, slot, parked_at`,
+			p.UUID, p.Lot, p.Slot, ParkStatusParked,
+		).Scan(&p.ID, &p.Slot, &p.ParkedAt)
+	} else {
+		err = s.db.QueryRow(ctxTimeout, `
+			INSERT INTO parked_calls (uuid, lot, slot, status, parked_at)
+			SELECT $1, $2, candidate.n, $3, now()
+			FROM generate_series($4::int, $5::int) AS candidate(n)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM parked_calls p WHERE p.lot = $2 AND p.slot = candidate.n AND p.status = $3
+			)
+			ORDER BY candidate.n
+			LIMIT 1
+			RETURNING id, slot, parked_at`,
+			p.UUID, p.Lot, ParkStatusParked, minSlot, maxSlot,
+		).Scan(&p.ID, &p.Slot, &p.ParkedAt)
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrNoFreeSlot
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		if pgErr.ConstraintName == "parked_calls_active_uuid_idx" {
+			return ErrAlreadyParked
+		}
+		return ErrSlotTaken // Includes losing a race for an automatically chosen slot
+	case err != nil:
+		s.log.WithError(err).WithField("uuid", p.UUID).Error("Error reserving parking slot")
+		return err
+	}
+	p.Status = ParkStatusParked
+
+	s.log.WithFields(logrus.Fields{
+		"uuid": p.UUID,
+		"lot":  p.Lot,
+		"slot": p.Slot,
+	}).Info("Reserved parking slot")
+	return nil
+}
+
+// ReleaseParkedCall ends the active parking of the call with the given status. retrievedTo records the
+// extension a retrieved call was sent to. Calls that are not parked are left untouched.
+func (s *Store) ReleaseParkedCall(ctx context.Context, uuid, status string, retrievedTo *string) error {
+	return s.write(ctx, writeOp{
+		name: "release_parked_call",
+		uuid: uuid,
+		query: `
+			UPDATE parked_calls
+			SET status = $2, released_at = now(), retrieved_to = $3
+			WHERE uuid = $1 AND status = $4`,
+		args: []any{uuid, status, retrievedTo, ParkStatusParked},
+	})
+}
+
+// parkedCallColumns lists the parked_calls columns read by scanParkedCall, joined with calls as c
+const parkedCallColumns = `p.id, p.uuid, p.lot, p.slot, p.status, COALESCE(c.caller, ''), COALESCE(c.callee, ''),
+	p.parked_at, p.released_at, p.retrieved_to`
+
+func scanParkedCall(row pgx.Row, p *ParkedCall) error {
+	return row.Scan(&p.ID, &p.UUID, &p.Lot, &p.Slot, &p.Status, &p.Caller, &p.Callee,
+		&p.ParkedAt, &p.ReleasedAt, &p.RetrievedTo)
+}
+
+// GetParkedCallInSlot returns the call currently parked in lot/slot, or ErrParkingNotFound
+func (s *Store) GetParkedCallInSlot(ctx context.Context, lot string, slot int) (*ParkedCall, error) {
+	query := `
+		SELECT ` + parkedCallColumns + `
+		FROM parked_calls p
+		LEFT JOIN calls c ON c.uuid = p.uuid
+		WHERE p.lot = $1 AND p.slot = $2 AND p.status = $3`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var p ParkedCall
+	err := scanParkedCall(s.db.QueryRow(ctxTimeout, query, lot, slot, ParkStatusParked), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrParkingNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{"lot": lot, "slot": slot}).Error("Error getting parked call")
+		return nil, err
+	}
+	p.Caller, p.Callee = s.openField(ctx, p.Caller), s.openField(ctx, p.Callee)
+	return &p, nil
+}
+
+// GetParkedCalls returns the calls currently parked, optionally limited to one lot, ordered by lot and slot
+func (s *Store) GetParkedCalls(ctx context.Context, lot string, limit, offset int) ([]ParkedCall, error) {
+	query := `
+		SELECT ` + parkedCallColumns + `
+		FROM parked_calls p
+		LEFT JOIN calls c ON c.uuid = p.uuid
+		WHERE p.status = $1 AND ($2 = '' OR p.lot = $2)
+		ORDER BY p.lot, p.slot
+		LIMIT $3 OFFSET $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, ParkStatusParked, lot, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting parked calls")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parked []ParkedCall
+	for rows.Next() {
+		var p ParkedCall
+		if err := scanParkedCall(rows, &p); err != nil {
+			s.log.WithError(err).Error("Error scanning parked call row")
+			return nil, err
+		}
+		p.Caller, p.Callee = s.openField(ctx, p.Caller), s.openField(ctx, p.Callee)
+		parked = append(parked, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating parked call rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"lot":   lot,
+		"count": len(parked),
+	}).Info("Retrieved parked calls")
+	return parked, nil
+}
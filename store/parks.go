@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ParkInterval is one period a call spent parked, with the park app (CHANNEL_PARK) or in a valet lot
+type ParkInterval struct {
+	ID              int64      `json:"id"`
+	UUID            string     `json:"uuid"`
+	Lot             *string    `json:"lot,omitempty"`       // Valet lot; unset for the park app
+	Extension       *string    `json:"extension,omitempty"` // Valet slot extension
+	ParkedAt        time.Time  `json:"parked_at"`
+	UnparkedAt      *time.Time `json:"unparked_at,omitempty"` // Nil while the call is still parked
+	Seconds         *float64   `json:"seconds,omitempty"`
+	RetrievedByUUID *string    `json:"retrieved_by_uuid,omitempty"` // Channel that picked the call up
+}
+
+// StartPark opens a park interval for a call and counts it, unless the call is already parked
+func (s *Store) StartPark(ctx context.Context, uuid string, lot, extension *string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "start_park",
+		uuid: uuid,
+		query: `
+			WITH opened AS (
+				INSERT INTO park_intervals (uuid, lot, extension, parked_at)
+				SELECT $1, $2, $3, $4
+				WHERE NOT EXISTS (SELECT 1 FROM park_intervals WHERE uuid = $1 AND unparked_at IS NULL)
+				RETURNING uuid
+			)
+			UPDATE calls SET park_count = park_count + 1
+			WHERE uuid IN (SELECT uuid FROM opened)`,
+		args: []any{uuid, lot, extension, at},
+	})
+}
+
+// EndPark closes the open park interval of a call and adds its length to the call's total parked time.
+// retrievedBy is the UUID of the channel that picked the call up, if known; the caller number of that
+// channel is recorded as the retrieving extension. It does nothing when the call is not parked, so it is
+// safe to call at hangup.
+func (s *Store) EndPark(ctx context.Context, uuid string, at time.Time, retrievedBy *string) error {
+	return s.write(ctx, writeOp{
+		name: "end_park",
+		uuid: uuid,
+		query: `
+			WITH closed AS (
+				UPDATE park_intervals SET unparked_at = GREATEST($2::timestamptz, parked_at), retrieved_by_uuid = $3
+				WHERE uuid = $1 AND unparked_at IS NULL
+				RETURNING uuid, EXTRACT(EPOCH FROM (unparked_at - parked_at))::float8 AS seconds
+			)
+			UPDATE calls SET parked_seconds = parked_seconds + closed.seconds,
+				retrieved_by_uuid = COALESCE($3, calls.retrieved_by_uuid),
+				retrieved_by = COALESCE((SELECT caller FROM calls r WHERE r.uuid = $3), calls.retrieved_by)
+			FROM closed
+			WHERE calls.uuid = closed.uuid`,
+		args: []any{uuid, at, retrievedBy},
+	})
+}
+
+// SetCallRetrievedBy records that the channel retrievedBy picked up a ringing call (pickup or intercept)
+func (s *Store) SetCallRetrievedBy(ctx context.Context, uuid, retrievedBy string) error {
+	return s.write(ctx, writeOp{
+		name: "set_call_retrieved_by",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET retrieved_by_uuid = $2,
+				retrieved_by = COALESCE((SELECT caller FROM calls r WHERE r.uuid = $2), retrieved_by)
+			WHERE uuid = $1`,
+		args:         []any{uuid, retrievedBy},
+		warnIfNoRows: true,
+	})
+}
+
+// GetParkIntervals returns the park intervals of a call in order
+func (s *Store) GetParkIntervals(ctx context.Context, uuid string) ([]ParkInterval, error) {
+	query := `
+		SELECT id, uuid, lot, extension, parked_at, unparked_at,
+			EXTRACT(EPOCH FROM (unparked_at - parked_at))::float8, retrieved_by_uuid
+		FROM park_intervals
+		WHERE uuid = $1
+		ORDER BY parked_at, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call park intervals")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parks []ParkInterval
+	for rows.Next() {
+		var p ParkInterval
+		if err := rows.Scan(&p.ID, &p.UUID, &p.Lot, &p.Extension, &p.ParkedAt, &p.UnparkedAt, &p.Seconds, &p.RetrievedByUUID); err != nil {
+			s.log.WithError(err).Error("Error scanning park interval row")
+			return nil, err
+		}
+		parks = append(parks, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating park interval rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(parks),
+	}).Info("Retrieved call park intervals")
+	return parks, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var poolReplacementsCounter = metrics.NewCounterVec("db_pool_replacements_total", "Database pools rebuilt at runtime, e.g. after credential rotation.")
+
+// swapPool is the database pool behind a Store. The pool can be replaced while queries run; each call
+// uses the pool current when it starts.
+type swapPool struct {
+	current atomic.Pointer[pgxpool.Pool]
+}
+
+func newSwapPool(pool *pgxpool.Pool) *swapPool {
+	sp := &swapPool{}
+	sp.current.Store(pool)
+	return sp
+}
+
+// The query methods delegate to the pool current at the time of the call
+
+func (sp *swapPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return sp.current.Load().Exec(ctx, sql, args...)
+}
+
+func (sp *swapPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return sp.current.Load().Query(ctx, sql, args...)
+}
+
+func (sp *swapPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return sp.current.Load().QueryRow(ctx, sql, args...)
+}
+
+func (sp *swapPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return sp.current.Load().Begin(ctx)
+}
+
+func (sp *swapPool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sp.current.Load().SendBatch(ctx, b)
+}
+
+func (sp *swapPool) Ping(ctx context.Context) error {
+	return sp.current.Load().Ping(ctx)
+}
+
+// ReplacePool switches the store to pool, e.g. one built with rotated credentials. The previous pool
+// keeps serving queries that already hold one of its connections; after grace it is closed, which waits
+// for those connections to be returned.
+func (s *Store) ReplacePool(pool *pgxpool.Pool, grace time.Duration) {
+	old := s.db.current.Swap(pool)
+	poolReplacementsCounter.With().Inc()
+	if old == nil || old == pool {
+		return
+	}
+	go func() {
+		time.Sleep(grace)
+		old.Close()
+		s.log.Info("Previous database pool drained and closed")
+	}()
+}
+
+// Close closes the current database pool
+func (s *Store) Close() {
+	s.db.current.Load().Close()
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pooler modes for ConfigurePooler
+const (
+	PoolerAuto        = "auto"        // Detect transaction poolers from the DSN
+	PoolerNone        = "none"        // Direct or session-pooled connection
+	PoolerTransaction = "transaction" // pgbouncer, Supavisor or RDS Proxy in transaction mode
+)
+
+// ErrPoolerRowLevelSecurity is returned when row-level security is combined with transaction pooling: the
+// tenant setting is applied per session, and a transaction pooler hands sessions to other clients
+var ErrPoolerRowLevelSecurity = errors.New("row-level security needs a direct or session-pooled connection, not a transaction pooler")
+
+// supabaseTransactionPort is the port of Supabase's transaction-mode pooler
+const supabaseTransactionPort = 6543
+
+// ConfigurePooler adapts cfg to a transaction-pooling proxy, which may run consecutive statements of one
+// client on different server connections, so named prepared statements from pgx's statement cache are
+// not found ("prepared statement does not exist") or collide ("already exists"). Queries then use the
+// simple protocol and nothing is cached. In auto mode a pooler is assumed for the pgbouncer=true DSN
+// parameter, a Supabase pooler host or port 6543. A query exec mode set explicitly in the DSN is kept.
+// It reports whether transaction pooling is in effect.
+func ConfigurePooler(cfg *pgxpool.Config, mode string, rowLevelSecurity bool) (bool, error) {
+	// pgbouncer=true is a hint for other drivers; pgx would send it to the server as a setting
+	hint := strings.EqualFold(cfg.ConnConfig.RuntimeParams["pgbouncer"], "true")
+	delete(cfg.ConnConfig.RuntimeParams, "pgbouncer")
+
+	var pooled bool
+	switch strings.ToLower(mode) {
+	case PoolerNone:
+	case PoolerTransaction:
+		pooled = true
+	case "", PoolerAuto:
+		pooled = hint || strings.Contains(cfg.ConnConfig.Host, "pooler.supabase.com") ||
+			cfg.ConnConfig.Port == supabaseTransactionPort
+	default:
+		return false, fmt.Errorf("unknown pooler mode %q (expected auto, none or transaction)", mode)
+	}
+	if !pooled {
+		return false, nil
+	}
+	if rowLevelSecurity {
+		return true, ErrPoolerRowLevelSecurity
+	}
+
+	if _, explicit := dsnParam(cfg.ConnString(), "default_query_exec_mode"); !explicit {
+		cfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+	cfg.ConnConfig.StatementCacheCapacity = 0
+	cfg.ConnConfig.DescriptionCacheCapacity = 0
+	return true, nil
+}
+
+// dsnParam returns the value of a parameter in a URL or keyword/value connection string
+func dsnParam(dsn, name string) (string, bool) {
+	if i := strings.IndexByte(dsn, '?'); i >= 0 && strings.Contains(dsn, "://") {
+		for _, kv := range strings.Split(dsn[i+1:], "&") {
+			if k, v, _ := strings.Cut(kv, "="); k == name {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	for _, field := range strings.Fields(dsn) {
+		if k, v, ok := strings.Cut(field, "="); ok && k == name {
+			return strings.Trim(v, "'"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Data quality checks
+const (
+	QualityEndBeforeStart   = "end_before_start"  // end_time earlier than start_time
+	QualityNegativeDuration = "negative_duration" // Negative billsec, duration or progresssec
+	QualityStaleOpenCall    = "stale_open_call"   // No end_time long after the call started
+	QualityOrphanedLeg      = "orphaned_leg"      // call_legs row whose A- or B-leg has no call record
+)
+
+// qualityChecks maps each check to a query selecting the offending call UUIDs and their start time, for
+// calls started at or after $1. Checks marked stale also take the stale-call cut-off as $2.
+var qualityChecks = []struct {
+	name  string
+	stale bool
+	query string
+}{
+	{QualityEndBeforeStart, false, `
+		SELECT uuid, start_time FROM calls
+		WHERE start_time >= $1 AND end_time < start_time`},
+	{QualityNegativeDuration, false, `
+		SELECT uuid, start_time FROM calls
+		WHERE start_time >= $1 AND (billsec < 0 OR duration < 0 OR progresssec < 0)`},
+	{QualityStaleOpenCall, true, `
+		SELECT uuid, start_time FROM calls
+		WHERE start_time >= $1 AND start_time < $2 AND end_time IS NULL`},
+	{QualityOrphanedLeg, false, `
+		SELECT l.a_uuid || ' -> ' || l.b_uuid, l.bridged_at FROM call_legs l
+		WHERE l.bridged_at >= $1
+			AND (NOT EXISTS (SELECT 1 FROM calls WHERE uuid = l.a_uuid) OR NOT EXISTS (SELECT 1 FROM calls WHERE uuid = l.b_uuid))`},
+}
+
+// QualityIssue is the result of one data quality check
+type QualityIssue struct {
+	Check   string   `json:"check"`
+	Count   int64    `json:"count"`
+	Samples []string `json:"samples"` // Most recent offending call UUIDs (leg pairs for orphaned_leg)
+}
+
+// GetQualityIssues runs every data quality check over calls started since since. Open calls that started
+// before staleBefore count as stale. Up to samples offending records are returned per check.
+func (s *Store) GetQualityIssues(ctx context.Context, since, staleBefore time.Time, samples int) ([]QualityIssue, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	issues := make([]QualityIssue, 0, len(qualityChecks))
+	for _, check := range qualityChecks {
+		issue := QualityIssue{Check: check.name}
+		args := []any{since}
+		if check.stale {
+			args = append(args, staleBefore)
+		}
+		args = append(args, samples)
+		err := s.db.QueryRow(ctxTimeout, fmt.Sprintf(`
+			WITH bad (id, at) AS (%s)
+			SELECT (SELECT COUNT(*) FROM bad), ARRAY(SELECT id FROM bad ORDER BY at DESC LIMIT $%d)`,
+			check.query, len(args)),
+			args...,
+		).Scan(&issue.Count, &issue.Samples)
+		if err != nil {
+			s.log.WithError(err).WithField("check", check.name).Error("Error running data quality check")
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"since":  since,
+		"checks": len(issues),
+	}).Info("Ran data quality checks")
+	return issues, nil
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Outcomes of a queue member's wait
+const (
+	QueueOutcomeAnswered  = "answered"
+	QueueOutcomeAbandoned = "abandoned" // Caller hung up while waiting
+	QueueOutcomeTimeout   = "timeout"   // Queue or no-agent timeout expired
+	QueueOutcomeExited    = "exited"    // Caller left the queue with a key or break-out
+	QueueOutcomeWaiting   = "waiting"   // Still in the queue; set from callcenter::info events
+)
+
+// ErrInvalidInterval is returned for a stats interval other than hour, day or none
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// QueueCall is one caller's stay in a mod_callcenter queue
+type QueueCall struct {
+	UUID       string     `json:"uuid"`
+	Queue      string     `json:"queue"`
+	Agent      *string    `json:"agent,omitempty"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+	LeftAt     time.Time  `json:"left_at"` // Answer, cancel or hangup time; NULL in the table while waiting
+	Outcome    string     `json:"outcome"`
+	CIDNumber  *string    `json:"caller_number,omitempty"`
+	CIDName    *string    `json:"caller_name,omitempty"`
+}
+
+// RecordQueueCall stores or replaces the queue wait of a call
+func (s *Store) RecordQueueCall(ctx context.Context, qc *QueueCall) error {
+	return s.write(ctx, writeOp{
+		name: "record_queue_call",
+		uuid: qc.UUID,
+		query: `
+			INSERT INTO queue_calls (uuid, queue, agent, joined_at, answered_at, left_at, outcome)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (uuid) DO UPDATE SET
+				queue = EXCLUDED.queue, agent = EXCLUDED.agent, joined_at = EXCLUDED.joined_at,
+				answered_at = EXCLUDED.answered_at, left_at = EXCLUDED.left_at, outcome = EXCLUDED.outcome`,
+		args: []any{qc.UUID, qc.Queue, qc.Agent, qc.JoinedAt, qc.AnsweredAt, qc.LeftAt, qc.Outcome},
+	})
+}
+
+// QueueStats summarises waits in one queue, optionally within one interval
+type QueueStats struct {
+	Queue           string     `json:"queue"`
+	IntervalStart   *time.Time `json:"interval_start,omitempty"`
+	Offered         int64      `json:"offered"`
+	Answered        int64      `json:"answered"`
+	Abandoned       int64      `json:"abandoned"`
+	TimedOut        int64      `json:"timed_out"`
+	Exited          int64      `json:"exited"`
+	AbandonRate     float64    `json:"abandon_rate"`  // Abandoned / offered, 0-1
+	ServiceLevel    float64    `json:"service_level"` // Answered within the threshold / offered, 0-1
+	AvgWait         float64    `json:"avg_wait_seconds"`
+	WaitP50         float64    `json:"wait_p50_seconds"` // Percentiles of time to answer, answered calls only
+	WaitP90         float64    `json:"wait_p90_seconds"`
+	WaitP95         float64    `json:"wait_p95_seconds"`
+	AvgAbandonWait  float64    `json:"avg_abandon_wait_seconds"`
+	ServiceLevelSec int        `json:"service_level_seconds"`
+}
+
+// queueIntervals are the accepted bucket sizes, passed to date_trunc
+var queueIntervals = map[string]bool{"hour": true, "day": true}
+
+// GetQueueStats returns wait-time, abandonment and service-level figures per queue for callers who joined
+// in [from, to). interval is "hour", "day" or "" for one row per queue; buckets follow tz. queue narrows the
+// result to one queue when set.
+func (s *Store) GetQueueStats(ctx context.Context, from, to time.Time, interval, tz, queue string, slaSeconds int) ([]QueueStats, error) {
+	if interval != "" && !queueIntervals[interval] {
+		return nil, ErrInvalidInterval
+	}
+
+	query := `
+		WITH waits AS (
+			SELECT queue, outcome,
+				CASE WHEN $4 = '' THEN NULL ELSE date_trunc($4, joined_at AT TIME ZONE $5) AT TIME ZONE $5 END AS bucket,
+				EXTRACT(EPOCH FROM (left_at - joined_at))::float8 AS wait
+			FROM queue_calls
+			WHERE joined_at >= $1 AND joined_at < $2 AND ($3 = '' OR queue = $3) AND outcome <> 'waiting'
+		)
+		SELECT queue, bucket,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE outcome = 'answered'),
+			COUNT(*) FILTER (WHERE outcome = 'abandoned'),
+			COUNT(*) FILTER (WHERE outcome = 'timeout'),
+			COUNT(*) FILTER (WHERE outcome = 'exited'),
+			COUNT(*) FILTER (WHERE outcome = 'answered' AND wait <= $6),
+			COALESCE(AVG(wait), 0)::float8,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY wait) FILTER (WHERE outcome = 'answered'), 0)::float8,
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY wait) FILTER (WHERE outcome = 'answered'), 0)::float8,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY wait) FILTER (WHERE outcome = 'answered'), 0)::float8,
+			COALESCE(AVG(wait) FILTER (WHERE outcome = 'abandoned'), 0)::float8
+		FROM waits
+		GROUP BY queue, bucket
+		ORDER BY queue, bucket`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, queue, interval, tz, slaSeconds)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting queue stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []QueueStats
+	for rows.Next() {
+		qs := QueueStats{ServiceLevelSec: slaSeconds}
+		var withinSLA int64
+		if err := rows.Scan(&qs.Queue, &qs.IntervalStart, &qs.Offered, &qs.Answered, &qs.Abandoned, &qs.TimedOut,
+			&qs.Exited, &withinSLA, &qs.AvgWait, &qs.WaitP50, &qs.WaitP90, &qs.WaitP95, &qs.AvgAbandonWait); err != nil {
+			s.log.WithError(err).Error("Error scanning queue stats row")
+			return nil, err
+		}
+		if qs.Offered > 0 {
+			qs.AbandonRate = float64(qs.Abandoned) / float64(qs.Offered)
+			qs.ServiceLevel = float64(withinSLA) / float64(qs.Offered)
+		}
+		stats = append(stats, qs)
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating queue stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":     from,
+		"to":       to,
+		"interval": interval,
+		"count":    len(stats),
+	}).Info("Retrieved queue stats")
+	return stats, nil
+}
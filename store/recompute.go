@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CountFinishedCalls returns the number of finished calls started in [from, to)
+func (s *Store) CountFinishedCalls(ctx context.Context, from, to time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM calls
+		WHERE end_time IS NOT NULL AND start_time >= $1 AND start_time < $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var count int64
+	if err := s.db.QueryRow(ctxTimeout, query, from, to).Scan(&count); err != nil {
+		s.log.WithError(err).Error("Error counting finished calls")
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetCallsAfter returns up to limit calls started in [from, to) with an id greater than afterID,
+// ordered by id, for keyset-paginated batch processing. finishedOnly skips calls still in progress.
+func (s *Store) GetCallsAfter(ctx context.Context, afterID int, from, to time.Time, finishedOnly bool, limit int) ([]Call, error) {
+	query := `
+		SELECT ` + callColumns + `
+		FROM calls
+		WHERE id > $1 AND start_time >= $2 AND start_time < $3 AND (NOT $4 OR end_time IS NOT NULL)
+		ORDER BY id
+		LIMIT $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, afterID, from, to, finishedOnly, limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting calls for batch processing")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []Call
+	for rows.Next() {
+		var call Call
+		if err := scanCall(rows, &call); err != nil {
+			s.log.WithError(err).Error("Error scanning call row")
+			return nil, err
+		}
+		s.openCall(ctx, &call)
+		calls = append(calls, call)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call rows")
+		return nil, err
+	}
+	return calls, nil
+}
+
+// UpdateCallCosts sets the cost of each call in ids to the matching entry of costs (nil clears it).
+// Rows whose cost is unchanged are skipped; the number of rows changed is returned.
+func (s *Store) UpdateCallCosts(ctx context.Context, ids []int, costs []*float64) (int64, error) {
+	query := `
+		UPDATE calls
+		SET cost = v.cost
+		FROM unnest($1::int[], $2::numeric[]) AS v(id, cost)
+		WHERE calls.id = v.id AND calls.cost IS DISTINCT FROM v.cost`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, query, ids, costs)
+	if err != nil {
+		s.log.WithError(err).Error("Error updating call costs")
+		return 0, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"batch":   len(ids),
+		"updated": cmdTag.RowsAffected(),
+	}).Debug("Updated call costs")
+	return cmdTag.RowsAffected(), nil
+}
+
+// UpdateCallDestinations sets the destination country and group of each call in ids to the matching entries
+// (nil clears them). Rows that are unchanged are skipped; the number of rows changed is returned.
+func (s *Store) UpdateCallDestinations(ctx context.Context, ids []int, countries, groups []*string) (int64, error) {
+	query := `
+		UPDATE calls
+		SET destination_country = v.country, destination_group = v.grp
+		FROM unnest($1::int[], $2::text[], $3::text[]) AS v(id, country, grp)
+		WHERE calls.id = v.id
+			AND (calls.destination_country IS DISTINCT FROM v.country OR calls.destination_group IS DISTINCT FROM v.grp)`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, query, ids, countries, groups)
+	if err != nil {
+		s.log.WithError(err).Error("Error updating call destinations")
+		return 0, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"batch":   len(ids),
+		"updated": cmdTag.RowsAffected(),
+	}).Debug("Updated call destinations")
+	return cmdTag.RowsAffected(), nil
+}
+
+// UpdateCallBusinessHours sets the business-hours flag of each call in ids to the matching entry (nil clears it).
+// Rows that are unchanged are skipped; the number of rows changed is returned.
+func (s *Store) UpdateCallBusinessHours(ctx context.Context, ids []int, flags []*bool) (int64, error) {
+	query := `
+		UPDATE calls
+		SET business_hours = v.flag
+		FROM unnest($1::int[], $2::boolean[]) AS v(id, flag)
+		WHERE calls.id = v.id AND calls.business_hours IS DISTINCT FROM v.flag`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, query, ids, flags)
+	if err != nil {
+		s.log.WithError(err).Error("Error updating call business hours")
+		return 0, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"batch":   len(ids),
+		"updated": cmdTag.RowsAffected(),
+	}).Debug("Updated call business hours")
+	return cmdTag.RowsAffected(), nil
+}
+
+// UpdateCallDurations fills in the billable and total seconds of each call in ids whose CHANNEL_HANGUP_COMPLETE
+// figures were never recorded. Calls FreeSWITCH finalized are left alone; the number of rows changed is returned.
+func (s *Store) UpdateCallDurations(ctx context.Context, ids []int, billsecs, durations []*int64) (int64, error) {
+	query := `
+		UPDATE calls
+		SET billsec = v.billsec, duration = v.duration
+		FROM unnest($1::int[], $2::int[], $3::int[]) AS v(id, billsec, duration)
+		WHERE calls.id = v.id AND calls.billsec IS NULL AND v.billsec IS NOT NULL`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, query, ids, billsecs, durations)
+	if err != nil {
+		s.log.WithError(err).Error("Error updating call durations")
+		return 0, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"batch":   len(ids),
+		"updated": cmdTag.RowsAffected(),
+	}).Debug("Updated call durations")
+	return cmdTag.RowsAffected(), nil
+}
+
+// UpdateCallOutcomes sets the rules outcome of each call in ids to the matching entry of outcomes (nil clears
+// a label set by the rules). Labels from the hook or the API are kept, and unlabelled calls the rules do not
+// match are left for the classifier; the number of rows changed is returned.
+func (s *Store) UpdateCallOutcomes(ctx context.Context, ids []int, outcomes []*string) (int64, error) {
+	query := `
+		UPDATE calls
+		SET outcome = v.outcome,
+			outcome_source = CASE WHEN v.outcome IS NULL THEN NULL ELSE $3 END,
+			classified_at = now()
+		FROM unnest($1::int[], $2::text[]) AS v(id, outcome)
+		WHERE calls.id = v.id AND calls.outcome IS DISTINCT FROM v.outcome
+			AND (calls.outcome_source = $3 OR (calls.outcome IS NULL AND v.outcome IS NOT NULL))`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmdTag, err := s.db.Exec(ctxTimeout, query, ids, outcomes, OutcomeSourceRules)
+	if err != nil {
+		s.log.WithError(err).Error("Error updating call outcomes")
+		return 0, err
+	}
+	s.log.WithFields(logrus.Fields{
+		"batch":   len(ids),
+		"updated": cmdTag.RowsAffected(),
+	}).Debug("Updated call outcomes")
+	return cmdTag.RowsAffected(), nil
+}
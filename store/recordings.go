@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Recording is a file recorded on a call between RECORD_START and RECORD_STOP
+type Recording struct {
+	ID         int64      `json:"id"`
+	UUID       string     `json:"uuid"`
+	FilePath   string     `json:"file_path"`
+	StartedAt  *time.Time `json:"started_at,omitempty"` // Nil if RECORD_START was missed
+	StoppedAt  *time.Time `json:"stopped_at,omitempty"` // Nil while recording
+	DurationMS *int64     `json:"duration_ms,omitempty"`
+}
+
+// recordingColumns is the column list shared by recording queries
+const recordingColumns = `id, uuid, file_path, started_at, stopped_at, duration_ms`
+
+// scanRecording scans a row selected with recordingColumns into r
+func scanRecording(row pgx.Row, r *Recording) error {
+	return row.Scan(&r.ID, &r.UUID, &r.FilePath, &r.StartedAt, &r.StoppedAt, &r.DurationMS)
+}
+
+// StartRecording records that a call started recording to filePath
+func (s *Store) StartRecording(ctx context.Context, uuid, filePath string, startedAt time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "start_recording",
+		uuid: uuid,
+		query: `
+			INSERT INTO recordings (uuid, file_path, started_at)
+			VALUES ($1, $2, $3)`,
+		args: []any{uuid, filePath, startedAt},
+	})
+}
+
+// StopRecording closes the open recording of uuid to filePath. durationMS may be nil, in which case it is
+// derived from the start time. If no open recording exists (RECORD_START was missed) a row without a start
+// time is created so the file is still known.
+func (s *Store) StopRecording(ctx context.Context, uuid, filePath string, stoppedAt time.Time, durationMS *int64) error {
+	return s.write(ctx, writeOp{
+		name: "stop_recording",
+		uuid: uuid,
+		query: `
+			WITH stopped AS (
+				UPDATE recordings
+				SET stopped_at = $3,
+					duration_ms = COALESCE($4, (EXTRACT(EPOCH FROM ($3 - started_at)) * 1000)::bigint)
+				WHERE id = (
+					SELECT id FROM recordings
+					WHERE uuid = $1 AND file_path = $2 AND stopped_at IS NULL
+					ORDER BY started_at DESC
+					LIMIT 1
+				)
+				RETURNING id
+			)
+			INSERT INTO recordings (uuid, file_path, stopped_at, duration_ms)
+			SELECT $1, $2, $3, $4
+			WHERE NOT EXISTS (SELECT 1 FROM stopped)`,
+		args: []any{uuid, filePath, stoppedAt, durationMS},
+	})
+}
+
+// GetCallRecordings returns the recordings of a call in start order
+func (s *Store) GetCallRecordings(ctx context.Context, uuid string) ([]Recording, error) {
+	query := `
+		SELECT ` + recordingColumns + `
+		FROM recordings
+		WHERE uuid = $1
+		ORDER BY COALESCE(started_at, stopped_at), id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call recordings")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recordings []Recording
+	for rows.Next() {
+		var r Recording
+		if err := scanRecording(rows, &r); err != nil {
+			s.log.WithError(err).Error("Error scanning recording row")
+			return nil, err
+		}
+		recordings = append(recordings, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating recording rows")
+		return nil, err
+	}
+	return recordings, nil
+}
+
+// GetRecordings returns recordings started (or, without a start time, stopped) in [from, to), newest first
+func (s *Store) GetRecordings(ctx context.Context, from, to time.Time, limit, offset int) ([]Recording, error) {
+	query := `
+		SELECT ` + recordingColumns + `
+		FROM recordings
+		WHERE COALESCE(started_at, stopped_at) >= $1 AND COALESCE(started_at, stopped_at) < $2
+		ORDER BY COALESCE(started_at, stopped_at) DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting recordings")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recordings []Recording
+	for rows.Next() {
+		var r Recording
+		if err := scanRecording(rows, &r); err != nil {
+			s.log.WithError(err).Error("Error scanning recording row")
+			return nil, err
+		}
+		recordings = append(recordings, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating recording rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(recordings),
+	}).Info("Retrieved recordings")
+	return recordings, nil
+}
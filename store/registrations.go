@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Registration statuses
+const (
+	RegistrationActive       = "registered"
+	RegistrationUnregistered = "unregistered"
+	RegistrationExpired      = "expired"
+)
+
+// Registration is a SIP registration seen through sofia::register, keyed by its SIP Call-ID
+type Registration struct {
+	ID           int64      `json:"id"`
+	CallID       string     `json:"call_id"`
+	Profile      *string    `json:"profile,omitempty"`
+	User         string     `json:"user"`
+	Realm        *string    `json:"realm,omitempty"`
+	Contact      *string    `json:"contact,omitempty"`
+	NetworkIP    *string    `json:"network_ip,omitempty"`
+	NetworkPort  *string    `json:"network_port,omitempty"`
+	UserAgent    *string    `json:"user_agent,omitempty"`
+	Status       string     `json:"status"`
+	RegisteredAt time.Time  `json:"registered_at"` // First REGISTER with this Call-ID
+	RefreshedAt  time.Time  `json:"refreshed_at"`  // Latest REGISTER
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+}
+
+// RegistrationFilter narrows GetRegistrations results. Empty fields are ignored.
+type RegistrationFilter struct {
+	User       string
+	Realm      string
+	ActiveOnly bool // Only registered, unexpired entries
+}
+
+// registrationColumns is the column list shared by registration queries
+const registrationColumns = `id, call_id, profile, sip_user, realm, contact, network_ip, network_port, user_agent,
+	status, registered_at, refreshed_at, expires_at, ended_at`
+
+// scanRegistration scans a row selected with registrationColumns into r
+func scanRegistration(row pgx.Row, r *Registration) error {
+	return row.Scan(&r.ID, &r.CallID, &r.Profile, &r.User, &r.Realm, &r.Contact, &r.NetworkIP, &r.NetworkPort,
+		&r.UserAgent, &r.Status, &r.RegisteredAt, &r.RefreshedAt, &r.ExpiresAt, &r.EndedAt)
+}
+
+// UpsertRegistration records a REGISTER, creating the registration or refreshing an existing one with the
+// same Call-ID. r.RefreshedAt is the time of the REGISTER.
+func (s *Store) UpsertRegistration(ctx context.Context, r *Registration) error {
+	return s.write(ctx, writeOp{
+		name: "upsert_registration",
+		uuid: r.CallID,
+		query: `
+			INSERT INTO registrations (call_id, profile, sip_user, realm, contact, network_ip, network_port, user_agent,
+				status, registered_at, refreshed_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11)
+			ON CONFLICT (call_id) DO UPDATE SET
+				profile = EXCLUDED.profile, sip_user = EXCLUDED.sip_user, realm = EXCLUDED.realm,
+				contact = EXCLUDED.contact, network_ip = EXCLUDED.network_ip, network_port = EXCLUDED.network_port,
+				user_agent = EXCLUDED.user_agent, status = EXCLUDED.status,
+				registered_at = CASE WHEN registrations.status = EXCLUDED.status THEN registrations.registered_at ELSE EXCLUDED.registered_at END,
+				refreshed_at = EXCLUDED.refreshed_at, expires_at = EXCLUDED.expires_at, ended_at = NULL`,
+		args: []any{r.CallID, r.Profile, r.User, r.Realm, r.Contact, r.NetworkIP, r.NetworkPort, r.UserAgent,
+			RegistrationActive, r.RefreshedAt, r.ExpiresAt},
+	})
+}
+
+// EndRegistration marks the active registration with callID as unregistered or expired
+func (s *Store) EndRegistration(ctx context.Context, callID, status string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "end_registration",
+		uuid: callID,
+		query: `
+			UPDATE registrations
+			SET status = $1, ended_at = $2
+			WHERE call_id = $3 AND status = $4`,
+		args:         []any{status, at, callID, RegistrationActive},
+		warnIfNoRows: true,
+	})
+}
+
+// GetRegistrations returns registrations matching filter, most recently refreshed first. Registrations
+// still marked registered whose expiry has passed (a missed sofia::expire) count as inactive.
+func (s *Store) GetRegistrations(ctx context.Context, filter RegistrationFilter, limit, offset int) ([]Registration, error) {
+	query := `
+		SELECT ` + registrationColumns + `
+		FROM registrations
+		WHERE ($1 = '' OR sip_user = $1) AND ($2 = '' OR realm = $2)
+			AND (NOT $3 OR (status = 'registered' AND (expires_at IS NULL OR expires_at > now())))
+		ORDER BY refreshed_at DESC, id DESC
+		LIMIT $4 OFFSET $5`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, filter.User, filter.Realm, filter.ActiveOnly, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting registrations")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registrations []Registration
+	for rows.Next() {
+		var r Registration
+		if err := scanRegistration(rows, &r); err != nil {
+			s.log.WithError(err).Error("Error scanning registration row")
+			return nil, err
+		}
+		registrations = append(registrations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating registration rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"user":       filter.User,
+		"realm":      filter.Realm,
+		"activeOnly": filter.ActiveOnly,
+		"count":      len(registrations),
+	}).Info("Retrieved registrations")
+	return registrations, nil
+}
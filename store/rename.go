@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RenameCall re-keys a call whose channel UUID changed (CHANNEL_UUID, e.g. after uuid_bridge or an
+// attended transfer replacing a leg) from oldUUID to newUUID, together with every row referring to it.
+// When a record already exists under newUUID, the old record is merged into it as a duplicate would be.
+// The rename is a single statement so it stays ordered with the surrounding ingest writes.
+func (s *Store) RenameCall(ctx context.Context, oldUUID, newUUID string) error {
+	ctes := []string{
+		`merged AS (
+			UPDATE calls AS k
+			SET ` + strings.Join(s.mergeSets(), ",\n\t\t\t\t") + `
+			FROM calls AS d
+			WHERE k.uuid = $2 AND d.uuid = $1
+		)`,
+		`dropped AS (
+			DELETE FROM calls WHERE uuid = $1 AND EXISTS (SELECT 1 FROM calls WHERE uuid = $2)
+		)`,
+	}
+	for i, ref := range callReferences {
+		guard := ""
+		if ref.table == "calls" {
+			guard = " AND uuid NOT IN ($1, $2)" // Leave the renamed and merged records to the CTEs above
+		}
+		if ref.unique {
+			ctes = append(ctes,
+				fmt.Sprintf(`ref%[1]d AS (
+			UPDATE %[2]s SET %[3]s = $2
+			WHERE %[3]s = $1 AND NOT EXISTS (SELECT 1 FROM %[2]s WHERE %[3]s = $2)
+		)`, i, ref.table, ref.column),
+				fmt.Sprintf(`ref%[1]d_dropped AS (
+			DELETE FROM %[2]s WHERE %[3]s = $1 AND EXISTS (SELECT 1 FROM %[2]s WHERE %[3]s = $2)
+		)`, i, ref.table, ref.column))
+			continue
+		}
+		ctes = append(ctes, fmt.Sprintf(`ref%[1]d AS (
+			UPDATE %[2]s SET %[3]s = $2 WHERE %[3]s = $1%[4]s
+		)`, i, ref.table, ref.column, guard))
+	}
+
+	return s.write(ctx, writeOp{
+		name: "rename_call",
+		uuid: newUUID,
+		query: `
+		WITH ` + strings.Join(ctes, ",\n\t\t") + `
+		UPDATE calls SET uuid = $2
+		WHERE uuid = $1 AND NOT EXISTS (SELECT 1 FROM calls WHERE uuid = $2)`,
+		args: []any{oldUUID, newUUID},
+	})
+}
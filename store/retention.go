@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RawEvent is an ESL event kept verbatim for debugging. Raw events are summarized into call_events and
+// dropped once they age out of their retention tier.
+type RawEvent struct {
+	ID        int64             `json:"id"`
+	UUID      *string           `json:"uuid,omitempty"`
+	EventName string            `json:"event_name"`
+	Subclass  string            `json:"subclass,omitempty"`
+	Node      *string           `json:"node,omitempty"`
+	EventTime time.Time         `json:"event_time"`
+	Headers   map[string]string `json:"headers"`
+	Body      *string           `json:"body,omitempty"`
+}
+
+// CallEventSummary counts the events of one name (and CUSTOM subclass) a call received, kept after the raw
+// events themselves have expired
+type CallEventSummary struct {
+	UUID      string    `json:"uuid"`
+	EventName string    `json:"event_name"`
+	Subclass  string    `json:"subclass,omitempty"`
+	Count     int64     `json:"count"`
+	FirstAt   time.Time `json:"first_at"`
+	LastAt    time.Time `json:"last_at"`
+}
+
+// AddRawEvent records an ESL event verbatim
+func (s *Store) AddRawEvent(ctx context.Context, e *RawEvent) error {
+	var uuid string
+	if e.UUID != nil {
+		uuid = *e.UUID
+	}
+	return s.write(ctx, writeOp{
+		name: "add_raw_event",
+		uuid: uuid,
+		query: `
+			INSERT INTO raw_events (uuid, event_name, subclass, node, event_time, headers, body)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		args: []any{e.UUID, e.EventName, e.Subclass, e.Node, e.EventTime, s.SealHeaders(e.Headers), e.Body},
+	})
+}
+
+// GetRawEvents returns the raw events still kept for a call in event order
+func (s *Store) GetRawEvents(ctx context.Context, uuid string) ([]RawEvent, error) {
+	query := `
+		SELECT id, uuid, event_name, subclass, node, event_time, headers, body
+		FROM raw_events
+		WHERE uuid = $1
+		ORDER BY event_time, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting raw events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []RawEvent
+	for rows.Next() {
+		var e RawEvent
+		if err := rows.Scan(&e.ID, &e.UUID, &e.EventName, &e.Subclass, &e.Node, &e.EventTime, &e.Headers, &e.Body); err != nil {
+			s.log.WithError(err).Error("Error scanning raw event row")
+			return nil, err
+		}
+		e.Headers = s.openHeaders(ctx, e.Headers)
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating raw event rows")
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetCallEventSummaries returns the summarized events of a call, earliest first
+func (s *Store) GetCallEventSummaries(ctx context.Context, uuid string) ([]CallEventSummary, error) {
+	query := `
+		SELECT uuid, event_name, subclass, count, first_at, last_at
+		FROM call_events
+		WHERE uuid = $1
+		ORDER BY first_at, event_name, subclass`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call event summaries")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []CallEventSummary
+	for rows.Next() {
+		var e CallEventSummary
+		if err := rows.Scan(&e.UUID, &e.EventName, &e.Subclass, &e.Count, &e.FirstAt, &e.LastAt); err != nil {
+			s.log.WithError(err).Error("Error scanning call event summary row")
+			return nil, err
+		}
+		summaries = append(summaries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call event summary rows")
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// SummarizeRawEvents moves up to limit raw events older than before into call_events, in one statement so
+// an interrupted run loses nothing. Events without a call are dropped. It returns the raw events removed.
+func (s *Store) SummarizeRawEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM raw_events
+			WHERE id IN (
+				SELECT id FROM raw_events
+				WHERE event_time < $1
+				ORDER BY id
+				LIMIT $2
+			)
+			RETURNING uuid, event_name, subclass, event_time
+		), summarized AS (
+			INSERT INTO call_events (uuid, event_name, subclass, count, first_at, last_at)
+			SELECT uuid, event_name, subclass, count(*), min(event_time), max(event_time)
+			FROM expired
+			WHERE uuid IS NOT NULL
+			GROUP BY uuid, event_name, subclass
+			ON CONFLICT (uuid, event_name, subclass) DO UPDATE
+			SET count = call_events.count + EXCLUDED.count,
+				first_at = LEAST(call_events.first_at, EXCLUDED.first_at),
+				last_at = GREATEST(call_events.last_at, EXCLUDED.last_at)
+		)
+		SELECT count(*) FROM expired`
+
+	return s.prune(ctx, "raw_events", query, before, limit)
+}
+
+// PruneCallEvents deletes up to limit event summaries whose last event is older than before
+func (s *Store) PruneCallEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM call_events
+			WHERE (uuid, event_name, subclass) IN (
+				SELECT uuid, event_name, subclass FROM call_events
+				WHERE last_at < $1
+				LIMIT $2
+			)
+			RETURNING 1
+		)
+		SELECT count(*) FROM expired`
+
+	return s.prune(ctx, "call_events", query, before, limit)
+}
+
+// PruneCalls deletes up to limit calls started before before, together with the rows of every table
+// keyed by their UUID. References from newer calls are left in place.
+func (s *Store) PruneCalls(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deletes []string
+	for i, ref := range callReferences {
+		if ref.table == "calls" {
+			continue
+		}
+		deletes = append(deletes, fmt.Sprintf(`
+			d%d AS (DELETE FROM %s WHERE %s IN (SELECT uuid FROM expired))`, i, ref.table, ref.column))
+	}
+	query := `
+		WITH expired AS (
+			DELETE FROM calls
+			WHERE id IN (
+				SELECT id FROM calls
+				WHERE start_time < $1
+				ORDER BY id
+				LIMIT $2
+			)
+			RETURNING uuid
+		),` + strings.Join(deletes, ",") + `,
+		summaries AS (DELETE FROM call_events WHERE uuid IN (SELECT uuid FROM expired))
+		SELECT count(*) FROM expired`
+
+	return s.prune(ctx, "calls", query, before, limit)
+}
+
+// prune runs a retention statement returning the number of rows it removed
+func (s *Store) prune(ctx context.Context, tier, query string, before time.Time, limit int) (int64, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	var deleted int64
+	if err := s.db.QueryRow(ctxTimeout, query, before, limit).Scan(&deleted); err != nil {
+		s.log.WithError(err).WithField("tier", tier).Error("Error enforcing retention")
+		return 0, err
+	}
+	if deleted > 0 {
+		s.log.WithFields(logrus.Fields{
+			"tier":    tier,
+			"before":  before,
+			"deleted": deleted,
+		}).Info("Enforced retention")
+	}
+	return deleted, nil
+}
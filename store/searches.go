@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSearchNotFound is returned when a saved search name does not exist for the owner
+var ErrSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a named set of query parameters that can be replayed on the list and stats endpoints
+type SavedSearch struct {
+	Name      string            `json:"name"`
+	Owner     string            `json:"-"` // Fingerprint of the API key that saved it; searches are private to it
+	Params    map[string]string `json:"params"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// SaveSearch creates or replaces the owner's search with the same name
+func (s *Store) SaveSearch(ctx context.Context, search *SavedSearch) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRow(ctxTimeout, `
+		INSERT INTO saved_searches (owner, name, params)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (owner, name) DO UPDATE SET params = EXCLUDED.params, updated_at = now()
+		RETURNING created_at, updated_at`,
+		search.Owner, search.Name, search.Params,
+	).Scan(&search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		s.log.WithError(err).WithField("name", search.Name).Error("Error saving search")
+		return err
+	}
+	return nil
+}
+
+// GetSearch returns the owner's search with the given name
+func (s *Store) GetSearch(ctx context.Context, owner, name string) (*SavedSearch, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	search := SavedSearch{Owner: owner}
+	err := s.db.QueryRow(ctxTimeout, `
+		SELECT name, params, created_at, updated_at
+		FROM saved_searches
+		WHERE owner = $1 AND name = $2`, owner, name,
+	).Scan(&search.Name, &search.Params, &search.CreatedAt, &search.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSearchNotFound
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("name", name).Error("Error getting saved search")
+		return nil, err
+	}
+	return &search, nil
+}
+
+// GetSearches returns the owner's saved searches by name
+func (s *Store) GetSearches(ctx context.Context, owner string) ([]SavedSearch, error) {
+	query := `
+		SELECT name, params, created_at, updated_at
+		FROM saved_searches
+		WHERE owner = $1
+		ORDER BY name`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, owner)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting saved searches")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		search := SavedSearch{Owner: owner}
+		if err := rows.Scan(&search.Name, &search.Params, &search.CreatedAt, &search.UpdatedAt); err != nil {
+			s.log.WithError(err).Error("Error scanning saved search row")
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating saved search rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"count": len(searches),
+	}).Info("Retrieved saved searches")
+	return searches, nil
+}
+
+// DeleteSearch removes the owner's search with the given name
+func (s *Store) DeleteSearch(ctx context.Context, owner, name string) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := s.db.Exec(ctxTimeout, `DELETE FROM saved_searches WHERE owner = $1 AND name = $2`, owner, name)
+	if err != nil {
+		s.log.WithError(err).WithField("name", name).Error("Error deleting saved search")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSearchNotFound
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// serverStatIntervals are the buckets GetServerStats can aggregate heartbeats into
+var serverStatIntervals = map[string]bool{"minute": true, "hour": true, "day": true}
+
+// Heartbeat is one FreeSWITCH HEARTBEAT sample
+type Heartbeat struct {
+	Node                 string
+	Hostname             *string
+	CoreUUID             *string
+	Version              *string
+	UptimeMS             int64
+	SessionCount         int
+	MaxSessions          *int
+	SessionsPerSec       float64
+	SessionsSinceStartup int64
+	SessionPeak          *int // Session-Peak-Max: highest concurrent sessions since startup
+	IdleCPU              *float64
+	RecordedAt           time.Time
+}
+
+// ServerStat is a HEARTBEAT sample, or the aggregate of a bucket of samples when an interval is requested
+type ServerStat struct {
+	Node           string    `json:"node"`
+	Hostname       *string   `json:"hostname,omitempty"`
+	Time           time.Time `json:"time"` // Sample time, or bucket start when aggregated
+	Samples        int       `json:"samples"`
+	Sessions       float64   `json:"sessions"`               // Average concurrent sessions
+	SessionsMax    int       `json:"sessions_max"`           // Highest concurrent sessions sampled
+	MaxSessions    *int      `json:"max_sessions,omitempty"` // Configured session limit
+	SessionsPerSec float64   `json:"sessions_per_sec"`
+	SessionsTotal  int64     `json:"sessions_since_startup"`
+	IdleCPU        *float64  `json:"idle_cpu,omitempty"`     // Average idle CPU percentage
+	IdleCPUMin     *float64  `json:"idle_cpu_min,omitempty"` // Lowest idle CPU percentage sampled
+	UptimeSeconds  float64   `json:"uptime_seconds"`
+}
+
+// AddHeartbeat stores a HEARTBEAT sample
+func (s *Store) AddHeartbeat(ctx context.Context, h *Heartbeat) error {
+	return s.write(ctx, writeOp{
+		name: "add_heartbeat",
+		uuid: h.Node,
+		query: `
+			INSERT INTO server_stats (node, hostname, core_uuid, version, uptime_ms, session_count, max_sessions,
+				sessions_per_sec, sessions_since_startup, session_peak, idle_cpu, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		args: []any{h.Node, h.Hostname, h.CoreUUID, h.Version, h.UptimeMS, h.SessionCount, h.MaxSessions,
+			h.SessionsPerSec, h.SessionsSinceStartup, h.SessionPeak, h.IdleCPU, h.RecordedAt},
+	})
+}
+
+// GetServerStats returns heartbeats recorded in [from, to), oldest first. With interval set to minute,
+// hour or day, samples are aggregated into buckets aligned in time zone tz. node narrows the result to one
+// ESL endpoint when set.
+func (s *Store) GetServerStats(ctx context.Context, node string, from, to time.Time, interval, tz string) ([]ServerStat, error) {
+	if interval != "" && !serverStatIntervals[interval] {
+		return nil, ErrInvalidInterval
+	}
+
+	query := `
+		WITH samples AS (
+			SELECT *,
+				CASE WHEN $4 = '' THEN recorded_at ELSE date_trunc($4, recorded_at AT TIME ZONE $5) AT TIME ZONE $5 END AS bucket
+			FROM server_stats
+			WHERE recorded_at >= $1 AND recorded_at < $2 AND ($3 = '' OR node = $3)
+		)
+		SELECT node, max(hostname), bucket, COUNT(*),
+			AVG(session_count)::float8, MAX(session_count), MAX(max_sessions),
+			AVG(sessions_per_sec)::float8, MAX(sessions_since_startup),
+			AVG(idle_cpu)::float8, MIN(idle_cpu)::float8, (MAX(uptime_ms) / 1000.0)::float8
+		FROM samples
+		GROUP BY node, bucket
+		ORDER BY bucket, node`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, node, interval, tz)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting server stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ServerStat
+	for rows.Next() {
+		var st ServerStat
+		if err := rows.Scan(&st.Node, &st.Hostname, &st.Time, &st.Samples,
+			&st.Sessions, &st.SessionsMax, &st.MaxSessions,
+			&st.SessionsPerSec, &st.SessionsTotal,
+			&st.IdleCPU, &st.IdleCPUMin, &st.UptimeSeconds); err != nil {
+			s.log.WithError(err).Error("Error scanning server stats row")
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating server stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"node":     node,
+		"from":     from,
+		"to":       to,
+		"interval": interval,
+		"count":    len(stats),
+	}).Info("Retrieved server stats")
+	return stats, nil
+}
@@ -0,0 +1,475 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidGroupBy is returned when a stats query is asked to group by an unsupported dimension
+var ErrInvalidGroupBy = errors.New("invalid group_by value")
+
+// CostSummary is an aggregated cost bucket for charge-back reporting
+type CostSummary struct {
+	Key        string  `json:"key"`
+	Calls      int64   `json:"calls"`
+	RatedCalls int64   `json:"rated_calls"`
+	TotalCost  float64 `json:"total_cost"`
+}
+
+// costGroupExpr maps a group_by value to the SQL expression used as the grouping key
+func costGroupExpr(groupBy string, prefixLen int) (string, error) {
+	switch groupBy {
+	case "extension":
+		return "caller", nil
+	case "domain":
+		return "COALESCE(domain, '')", nil
+	case "destination_prefix":
+		return fmt.Sprintf("LEFT(callee, %d)", prefixLen), nil
+	default:
+		return "", ErrInvalidGroupBy
+	}
+}
+
+// GetCostSummary aggregates call cost grouped by extension or destination prefix.
+// prefixLen is only used when grouping by destination_prefix.
+func (s *Store) GetCostSummary(ctx context.Context, groupBy string, prefixLen int) ([]CostSummary, error) {
+	groupExpr, err := costGroupExpr(groupBy, prefixLen)
+	if err != nil {
+		return nil, err
+	}
+	if groupBy == "destination_prefix" && s.cipher != nil {
+		return nil, ErrEncryptedField // Prefixes of ciphertext mean nothing
+	}
+
+	query := `
+		SELECT ` + groupExpr + ` AS key, COUNT(*), COUNT(cost), COALESCE(SUM(cost), 0)
+		FROM calls
+		GROUP BY 1
+		ORDER BY 4 DESC, 1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting cost summary")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []CostSummary
+	for rows.Next() {
+		var cs CostSummary
+		if err := rows.Scan(&cs.Key, &cs.Calls, &cs.RatedCalls, &cs.TotalCost); err != nil {
+			s.log.WithError(err).Error("Error scanning cost summary row")
+			return nil, err
+		}
+		if groupBy == "extension" {
+			cs.Key = s.openField(ctx, cs.Key)
+		}
+		summaries = append(summaries, cs)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating cost summary rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"groupBy": groupBy,
+		"count":   len(summaries),
+	}).Info("Retrieved cost summary")
+	return summaries, nil
+}
+
+// DomainStats summarises call activity for a single SIP domain
+type DomainStats struct {
+	Domain          string    `json:"domain"`
+	Calls           int64     `json:"calls"`
+	CompletedCalls  int64     `json:"completed_calls"`
+	TotalDurationS  float64   `json:"total_duration_seconds"`
+	AverageDuration float64   `json:"average_duration_seconds"`
+	TotalCost       float64   `json:"total_cost"`
+	LastCallAt      time.Time `json:"last_call_at"`
+}
+
+// GetDomainStats returns per-domain call statistics. If domain is non-empty only that domain is returned.
+// Calls without a known domain are excluded.
+func (s *Store) GetDomainStats(ctx context.Context, domain string) ([]DomainStats, error) {
+	query := `
+		SELECT domain,
+			COUNT(*),
+			COUNT(end_time),
+			COALESCE(SUM(EXTRACT(EPOCH FROM (end_time - start_time))), 0)::float8,
+			COALESCE(AVG(EXTRACT(EPOCH FROM (end_time - start_time))), 0)::float8,
+			COALESCE(SUM(cost), 0),
+			MAX(start_time)
+		FROM calls
+		WHERE domain IS NOT NULL AND ($1 = '' OR domain = $1)
+		GROUP BY domain
+		ORDER BY COUNT(*) DESC, domain`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, domain)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting domain stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DomainStats
+	for rows.Next() {
+		var ds DomainStats
+		if err := rows.Scan(&ds.Domain, &ds.Calls, &ds.CompletedCalls, &ds.TotalDurationS,
+			&ds.AverageDuration, &ds.TotalCost, &ds.LastCallAt); err != nil {
+			s.log.WithError(err).Error("Error scanning domain stats row")
+			return nil, err
+		}
+		stats = append(stats, ds)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating domain stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"domain": domain,
+		"count":  len(stats),
+	}).Info("Retrieved domain stats")
+	return stats, nil
+}
+
+// GatewayStats summarises call performance on a single gateway
+type GatewayStats struct {
+	Gateway       string           `json:"gateway"`
+	Calls         int64            `json:"calls"`
+	AnsweredCalls int64            `json:"answered_calls"`
+	ASR           float64          `json:"asr"`         // Answer-seizure ratio, 0-1
+	ACD           float64          `json:"acd_seconds"` // Average answered call duration
+	TotalBillable float64          `json:"total_billable_seconds"`
+	AvgPDD        float64          `json:"avg_pdd_seconds"`
+	AvgRingTime   float64          `json:"avg_ring_seconds"`
+	HangupCauses  map[string]int64 `json:"hangup_causes"`
+	SIPStatuses   map[string]int64 `json:"sip_statuses"` // sip_term_status code to count; calls without one are omitted
+}
+
+// GetGatewayStats returns volume, ASR, ACD and hangup-cause and SIP status breakdowns per gateway for calls started in [from, to)
+func (s *Store) GetGatewayStats(ctx context.Context, from, to time.Time) ([]GatewayStats, error) {
+	summaryQuery := `
+		SELECT gateway,
+			COUNT(*),
+			COUNT(answered_time),
+			COALESCE(AVG(EXTRACT(EPOCH FROM (end_time - answered_time))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (end_time - answered_time))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(AVG(pdd), 0)::float8,
+			COALESCE(AVG(ring_time), 0)::float8
+		FROM calls
+		WHERE gateway IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY gateway
+		ORDER BY COUNT(*) DESC, gateway`
+
+	causeQuery := `
+		SELECT gateway, COALESCE(status, 'UNKNOWN'), COUNT(*)
+		FROM calls
+		WHERE gateway IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY 1, 2`
+
+	sipStatusQuery := `
+		SELECT gateway, sip_term_status::text, COUNT(*)
+		FROM calls
+		WHERE gateway IS NOT NULL AND sip_term_status IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY 1, 2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, summaryQuery, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting gateway stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []GatewayStats
+	index := make(map[string]int)
+	for rows.Next() {
+		gs := GatewayStats{HangupCauses: make(map[string]int64), SIPStatuses: make(map[string]int64)}
+		if err := rows.Scan(&gs.Gateway, &gs.Calls, &gs.AnsweredCalls, &gs.ACD, &gs.TotalBillable, &gs.AvgPDD, &gs.AvgRingTime); err != nil {
+			s.log.WithError(err).Error("Error scanning gateway stats row")
+			return nil, err
+		}
+		if gs.Calls > 0 {
+			gs.ASR = float64(gs.AnsweredCalls) / float64(gs.Calls)
+		}
+		index[gs.Gateway] = len(stats)
+		stats = append(stats, gs)
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating gateway stats rows")
+		return nil, err
+	}
+
+	causeRows, err := s.db.Query(ctxTimeout, causeQuery, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting gateway hangup causes")
+		return nil, err
+	}
+	defer causeRows.Close()
+
+	for causeRows.Next() {
+		var gateway, cause string
+		var count int64
+		if err := causeRows.Scan(&gateway, &cause, &count); err != nil {
+			s.log.WithError(err).Error("Error scanning gateway hangup cause row")
+			return nil, err
+		}
+		if i, ok := index[gateway]; ok {
+			stats[i].HangupCauses[cause] = count
+		}
+	}
+	if err = causeRows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating gateway hangup cause rows")
+		return nil, err
+	}
+
+	statusRows, err := s.db.Query(ctxTimeout, sipStatusQuery, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting gateway SIP statuses")
+		return nil, err
+	}
+	defer statusRows.Close()
+
+	for statusRows.Next() {
+		var gateway, status string
+		var count int64
+		if err := statusRows.Scan(&gateway, &status, &count); err != nil {
+			s.log.WithError(err).Error("Error scanning gateway SIP status row")
+			return nil, err
+		}
+		if i, ok := index[gateway]; ok {
+			stats[i].SIPStatuses[status] = count
+		}
+	}
+	if err = statusRows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating gateway SIP status rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(stats),
+	}).Info("Retrieved gateway stats")
+	return stats, nil
+}
+
+// GetMissedCalls returns finished, unanswered inbound calls started in [from, to) whose hangup cause is one
+// of causes, newest first. filter narrows the result further.
+func (s *Store) GetMissedCalls(ctx context.Context, causes []string, filter CallFilter, from, to time.Time, limit, offset int) ([]Call, error) {
+	where, args := filter.where(nil)
+	if where == "" {
+		where = "WHERE TRUE"
+	}
+	args = append(args, causes, from, to, limit, offset)
+	n := len(args)
+	query := fmt.Sprintf(`
+		SELECT `+callColumns+`
+		FROM calls
+		%s AND direction = 'inbound' AND answered_time IS NULL AND end_time IS NOT NULL
+			AND status = ANY($%d) AND start_time >= $%d AND start_time < $%d
+		ORDER BY start_time DESC
+		LIMIT $%d OFFSET $%d`, where, n-4, n-3, n-2, n-1, n)
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, args...)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting missed calls")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []Call
+	for rows.Next() {
+		var call Call
+		if err := scanCall(rows, &call); err != nil {
+			s.log.WithError(err).Error("Error scanning missed call row")
+			return nil, err
+		}
+		s.openCall(ctx, &call)
+		calls = append(calls, call)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating missed call rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"from":  from,
+		"to":    to,
+		"count": len(calls),
+	}).Info("Retrieved missed calls")
+	return calls, nil
+}
+
+// ShortCallStats is the share of answered calls on a gateway or extension that ended within a few seconds
+type ShortCallStats struct {
+	Key      string  `json:"key"`
+	Answered int64   `json:"answered"`
+	Short    int64   `json:"short"`
+	Ratio    float64 `json:"ratio"` // Short / answered, 0-1
+}
+
+// shortCallGroupExpr maps a short-call dimension to its grouping column
+func shortCallGroupExpr(groupBy string) (string, error) {
+	switch groupBy {
+	case "gateway":
+		return "gateway", nil
+	case "extension":
+		return "caller", nil
+	default:
+		return "", ErrInvalidGroupBy
+	}
+}
+
+// GetShortCallStats counts answered calls that ended since since, and how many of them lasted at most
+// shortSeconds, grouped by gateway or extension. Only groups with at least minAnswered calls are returned,
+// highest ratio first. The duration is billsec when known, otherwise end time minus answer time.
+func (s *Store) GetShortCallStats(ctx context.Context, groupBy string, since time.Time, shortSeconds, minAnswered int) ([]ShortCallStats, error) {
+	groupExpr, err := shortCallGroupExpr(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT key, answered, short
+		FROM (
+			SELECT %[1]s AS key,
+				COUNT(*) AS answered,
+				COUNT(*) FILTER (WHERE COALESCE(billsec, EXTRACT(EPOCH FROM (end_time - answered_time))) <= $2) AS short
+			FROM calls
+			WHERE answered_time IS NOT NULL AND end_time >= $1 AND %[1]s IS NOT NULL
+			GROUP BY %[1]s
+			HAVING COUNT(*) >= $3
+		) grouped
+		ORDER BY short::float8 / answered DESC, key`, groupExpr)
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, since, shortSeconds, minAnswered)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting short call stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ShortCallStats
+	for rows.Next() {
+		var sc ShortCallStats
+		if err := rows.Scan(&sc.Key, &sc.Answered, &sc.Short); err != nil {
+			s.log.WithError(err).Error("Error scanning short call stats row")
+			return nil, err
+		}
+		if sc.Answered > 0 {
+			sc.Ratio = float64(sc.Short) / float64(sc.Answered)
+		}
+		stats = append(stats, sc)
+	}
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating short call stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"groupBy": groupBy,
+		"since":   since,
+		"count":   len(stats),
+	}).Debug("Retrieved short call stats")
+	return stats, nil
+}
+
+// DestinationStats summarises traffic to one destination country and/or prefix group
+type DestinationStats struct {
+	Country       *string `json:"country,omitempty"`
+	Group         *string `json:"group,omitempty"`
+	Calls         int64   `json:"calls"`
+	AnsweredCalls int64   `json:"answered_calls"`
+	ASR           float64 `json:"asr"`         // Answer-seizure ratio, 0-1
+	ACD           float64 `json:"acd_seconds"` // Average answered call duration
+	TotalBillable float64 `json:"total_billable_seconds"`
+	TotalCost     float64 `json:"total_cost"`
+}
+
+// destinationGroupColumns maps a destination group_by value to the columns it groups on
+var destinationGroupColumns = map[string][2]string{
+	"country":       {"destination_country", "NULL::text"},
+	"group":         {"NULL::text", "destination_group"},
+	"country_group": {"destination_country", "destination_group"},
+}
+
+// GetDestinationStats returns volume, ASR, ACD and cost per destination country, prefix group or both
+// for calls started in [from, to). Calls whose destination was not classified are excluded.
+func (s *Store) GetDestinationStats(ctx context.Context, groupBy string, from, to time.Time) ([]DestinationStats, error) {
+	cols, ok := destinationGroupColumns[groupBy]
+	if !ok {
+		return nil, ErrInvalidGroupBy
+	}
+	query := fmt.Sprintf(`
+		SELECT %[1]s, %[2]s,
+			COUNT(*),
+			COUNT(answered_time),
+			COALESCE(AVG(COALESCE(billsec, EXTRACT(EPOCH FROM (end_time - answered_time)))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(SUM(COALESCE(billsec, EXTRACT(EPOCH FROM (end_time - answered_time)))) FILTER (WHERE answered_time IS NOT NULL AND end_time IS NOT NULL), 0)::float8,
+			COALESCE(SUM(cost), 0)::float8
+		FROM calls
+		WHERE destination_country IS NOT NULL AND start_time >= $1 AND start_time < $2
+		GROUP BY 1, 2
+		ORDER BY COUNT(*) DESC, 1, 2`, cols[0], cols[1])
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting destination stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DestinationStats
+	for rows.Next() {
+		var ds DestinationStats
+		if err := rows.Scan(&ds.Country, &ds.Group, &ds.Calls, &ds.AnsweredCalls, &ds.ACD,
+			&ds.TotalBillable, &ds.TotalCost); err != nil {
+			s.log.WithError(err).Error("Error scanning destination stats row")
+			return nil, err
+		}
+		if ds.Calls > 0 {
+			ds.ASR = float64(ds.AnsweredCalls) / float64(ds.Calls)
+		}
+		stats = append(stats, ds)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating destination stats rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"groupBy": groupBy,
+		"from":    from,
+		"to":      to,
+		"count":   len(stats),
+	}).Info("Retrieved destination stats")
+	return stats, nil
+}
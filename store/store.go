@@ -2,48 +2,212 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
 
 // Call represents a call record in the database
 type Call struct {
-	ID        int        `json:"id"`
-	UUID      string     `json:"uuid"`
-	Direction string     `json:"direction"`
-	Caller    string     `json:"caller"`
-	Callee    string     `json:"callee"`
-	StartTime time.Time  `json:"start_time"`
-	EndTime   *time.Time `json:"end_time,omitempty"`
-	Status    *string    `json:"status,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID           int        `json:"id"`
+	UUID         string     `json:"uuid"`
+	Direction    string     `json:"direction"`
+	Caller       string     `json:"caller"`
+	CallerName   *string    `json:"caller_name,omitempty"`
+	Callee       string     `json:"callee"`
+	CalleeName   *string    `json:"callee_name,omitempty"`
+	StartTime    time.Time  `json:"start_time"`
+	RingingTime  *time.Time `json:"ringing_time,omitempty"`
+	EarlyMedia   *time.Time `json:"early_media_time,omitempty"`
+	AnsweredTime *time.Time `json:"answered_time,omitempty"`
+	BridgedTime  *time.Time `json:"bridged_time,omitempty"`
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	Status       *string    `json:"status,omitempty"`
+	Context      *string    `json:"context,omitempty"`
+	SIPProfile   *string    `json:"sip_profile,omitempty"`
+	Domain       *string    `json:"domain,omitempty"`
+	AccountCode  *string    `json:"account_code,omitempty"`
+	UserID       *string    `json:"user_id,omitempty"`
+	Gateway      *string    `json:"gateway,omitempty"`
+	Node         *string    `json:"node,omitempty"` // ESL endpoint that reported the call
+	SIPCallID    *string    `json:"sip_call_id,omitempty"`
+	SIPTraceURL  *string    `json:"sip_trace_url,omitempty"` // Set by the API from SIP_TRACE_URL_TEMPLATE; not stored
+	DestCountry  *string    `json:"destination_country,omitempty"`
+	DestGroup    *string    `json:"destination_group,omitempty"`
+	// Billing durations in seconds, finalized by FreeSWITCH at CHANNEL_HANGUP_COMPLETE
+	Billsec     *int64    `json:"billsec,omitempty"`
+	Duration    *int64    `json:"duration,omitempty"`
+	Progresssec *int64    `json:"progresssec,omitempty"`
+	PDD         *float64  `json:"pdd,omitempty"`       // Seconds from start to first progress; derived by the database
+	RingTime    *float64  `json:"ring_time,omitempty"` // Seconds from first progress to answer or hangup; derived by the database
+	Cost        *float64  `json:"cost,omitempty"`
+	HoldSeconds float64   `json:"hold_seconds"` // Total time on hold; see HoldInterval
+	HoldCount   int       `json:"hold_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Set when the call was moved by a SIP transfer; see Transfer
+	TransferredTo *string `json:"transferred_to,omitempty"` // Last destination the call was transferred to
+	TransferredBy *string `json:"transferred_by,omitempty"` // UUID of the channel that transferred it
+	// Labels applied through POST /calls/tags
+	Tags []string `json:"tags,omitempty"`
+	// Caller-ID campaign of an outbound call from, or an inbound call to, a mapped number; see CallerIDCampaign
+	CallerIDCampaign *string `json:"caller_id_campaign,omitempty"`
+	// Whether the call started within its tenant's business hours; unset without a matching calendar
+	BusinessHours *bool `json:"business_hours,omitempty"`
+	// Hangup codes from CHANNEL_HANGUP_COMPLETE: the Q.850 cause value and the final SIP response sent or
+	// received, plus the failure response to an outbound INVITE
+	HangupCauseQ850        *int `json:"hangup_cause_q850,omitempty"`
+	SIPTermStatus          *int `json:"sip_term_status,omitempty"`
+	SIPInviteFailureStatus *int `json:"sip_invite_failure_status,omitempty"`
+	// Outcome label from the post-call classifier or the API; see SetCallOutcome
+	Outcome       *string    `json:"outcome,omitempty"`
+	OutcomeSource *string    `json:"outcome_source,omitempty"` // rules, hook or api
+	ClassifiedAt  *time.Time `json:"classified_at,omitempty"`
+	// sip_* channel variables selected by SIP_HEADERS, keyed by variable name
+	SIPHeaders map[string]string `json:"sip_headers,omitempty"`
+	// Channel variables selected by CHANNEL_VARIABLES, keyed by variable name
+	Variables map[string]string `json:"variables,omitempty"`
+	// mod_verto (WebRTC) metadata: the client's websocket session id, address and browser user agent
+	VertoSessionID     *string `json:"verto_session_id,omitempty"`
+	VertoClientAddress *string `json:"verto_client_address,omitempty"`
+	VertoUserAgent     *string `json:"verto_user_agent,omitempty"`
+	// Originate retries: the UUID of the first attempt and this call's 1-based attempt number
+	RetryOf      *string `json:"retry_of,omitempty"`
+	RetryAttempt *int    `json:"retry_attempt,omitempty"`
+	// Total time parked and number of parks (see ParkInterval), and the extension and channel that
+	// retrieved the call from a valet lot or picked it up while ringing
+	ParkedSeconds   float64 `json:"parked_seconds"`
+	ParkCount       int     `json:"park_count"`
+	RetrievedBy     *string `json:"retrieved_by,omitempty"`
+	RetrievedByUUID *string `json:"retrieved_by_uuid,omitempty"`
+	// Events merged after CHANNEL_HANGUP_COMPLETE within the late-event grace window
+	LateCorrections int        `json:"late_corrections"`
+	LastCorrectedAt *time.Time `json:"last_corrected_at,omitempty"`
+	// Custom fields set by DERIVED_FIELD_RULES and CUSTOM_COLUMNS (the latter only on single-call lookups)
+	Derived map[string]string `json:"derived,omitempty"`
+	Custom  map[string]any    `json:"custom,omitempty"`
+}
+
+// callColumns is the column list shared by all queries returning full call records
+const callColumns = `id, uuid, direction, caller, caller_name, callee, callee_name,
+	start_time, ringing_time, early_media_time, answered_time, bridged_time, end_time, status,
+	context, sip_profile, domain, account_code, user_id, gateway, node, sip_call_id,
+	destination_country, destination_group, derived,
+	billsec, duration, progresssec, pdd, ring_time, cost, hold_seconds, hold_count, created_at,
+	transferred_to, transferred_by, tags, late_corrections, last_corrected_at, sip_headers, caller_id_campaign,
+	business_hours, outcome, outcome_source, classified_at,
+	hangup_cause_q850, sip_term_status, sip_invite_failure_status, variables,
+	verto_session_id, verto_client_address, verto_user_agent, retry_of, retry_attempt,
+	parked_seconds, park_count, retrieved_by, retrieved_by_uuid`
+
+// scanCall scans a row selected with callColumns into call
+func scanCall(row pgx.Row, call *Call) error {
+	return row.Scan(
+		&call.ID, &call.UUID, &call.Direction, &call.Caller, &call.CallerName, &call.Callee, &call.CalleeName,
+		&call.StartTime, &call.RingingTime, &call.EarlyMedia, &call.AnsweredTime, &call.BridgedTime, &call.EndTime, &call.Status,
+		&call.Context, &call.SIPProfile, &call.Domain,
+		&call.AccountCode, &call.UserID, &call.Gateway, &call.Node, &call.SIPCallID,
+		&call.DestCountry, &call.DestGroup, &call.Derived,
+		&call.Billsec, &call.Duration, &call.Progresssec, &call.PDD, &call.RingTime, &call.Cost,
+		&call.HoldSeconds, &call.HoldCount, &call.CreatedAt,
+		&call.TransferredTo, &call.TransferredBy, &call.Tags, &call.LateCorrections, &call.LastCorrectedAt,
+		&call.SIPHeaders, &call.CallerIDCampaign, &call.BusinessHours,
+		&call.Outcome, &call.OutcomeSource, &call.ClassifiedAt,
+		&call.HangupCauseQ850, &call.SIPTermStatus, &call.SIPInviteFailureStatus, &call.Variables,
+		&call.VertoSessionID, &call.VertoClientAddress, &call.VertoUserAgent, &call.RetryOf, &call.RetryAttempt,
+		&call.ParkedSeconds, &call.ParkCount, &call.RetrievedBy, &call.RetrievedByUUID,
+	)
 }
 
 // Store handles database operations
 type Store struct {
-	db  *pgxpool.Pool
-	log *logrus.Logger
+	db     *swapPool
+	log    *logrus.Logger
+	writer *Writer // Optional; when set, ingest writes are queued instead of executed inline
+
+	inFlight chan struct{} // Caps concurrent ingest writes; nil means unlimited
+	breaker  *Breaker      // Optional database circuit breaker
+
+	customColumns    []CustomColumn // Integrator-defined columns populated from channel variables
+	cipher           *FieldCipher   // Optional; encrypts caller/callee numbers and DTMF digits
+	rowLevelSecurity bool           // Whether InitSchema enables the tenant policies
+	legacyTimeZone   string         // Zone of wall-clock TIMESTAMP values InitSchema converts; UTC when empty
+	readLimits       ReadLimits     // Guardrails on API reads
+	health           healthLog      // Health transitions waiting to be written
+}
+
+// NewStore creates a new Store. maxInFlight caps concurrent ingest writes (0 for unlimited);
+// breaker may be nil to disable circuit breaking.
+func NewStore(db *pgxpool.Pool, maxInFlight int, breaker *Breaker, logger *logrus.Logger) *Store {
+	s := &Store{db: newSwapPool(db), log: logger, breaker: breaker}
+	if maxInFlight > 0 {
+		s.inFlight = make(chan struct{}, maxInFlight)
+	}
+	return s
+}
+
+// acquire reserves an in-flight write slot, blocking until one is free or ctx is done
+func (s *Store) acquire(ctx context.Context) error {
+	if s.inFlight == nil {
+		return nil
+	}
+	select {
+	case s.inFlight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// NewStore creates a new Store
-func NewStore(db *pgxpool.Pool, logger *logrus.Logger) *Store {
-	return &Store{db: db, log: logger}
+// release frees an in-flight write slot
+func (s *Store) release() {
+	if s.inFlight != nil {
+		<-s.inFlight
+	}
 }
 
-// CreateCall inserts a new call record into the database
+// CreateCall inserts a new call record into the database.
+// When writes are asynchronous the record is queued and call.ID/CreatedAt are left unset.
 func (s *Store) CreateCall(ctx context.Context, call *Call) error {
 	query := `
-		INSERT INTO calls (uuid, direction, caller, callee, start_time)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at`
+		INSERT INTO calls (uuid, direction, caller, caller_name, callee, callee_name, start_time,
+			context, sip_profile, domain, account_code, user_id, gateway, node, sip_call_id,
+			destination_country, destination_group, caller_id_campaign, business_hours, retry_of, retry_attempt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`
+	args := []any{
+		call.UUID, call.Direction, s.sealNumber(call.Caller), call.CallerName, s.sealNumber(call.Callee), call.CalleeName, call.StartTime,
+		call.Context, call.SIPProfile, call.Domain, call.AccountCode, call.UserID, call.Gateway, call.Node, call.SIPCallID,
+		call.DestCountry, call.DestGroup, call.CallerIDCampaign, call.BusinessHours, call.RetryOf, call.RetryAttempt,
+	}
+
+	if s.writer != nil {
+		return s.writer.enqueue(ctx, writeOp{name: "create_call", uuid: call.UUID, query: query, args: args})
+	}
+	key, applied := startWrite(ctx, "create_call")
+	if applied {
+		return nil // Went through on an earlier attempt at the event
+	}
+	if s.breaker.Open() {
+		noteWriteResult(ctx, key, ErrCircuitOpen)
+		return ErrCircuitOpen
+	}
+	if err := s.acquire(ctx); err != nil {
+		noteWriteResult(ctx, key, err)
+		return err
+	}
+	defer s.release()
 
 	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	row := s.db.QueryRow(ctxTimeout, query, call.UUID, call.Direction, call.Caller, call.Callee, call.StartTime)
-	err := row.Scan(&call.ID, &call.CreatedAt)
+	err := s.db.QueryRow(ctxTimeout, query+" RETURNING id, created_at", args...).Scan(&call.ID, &call.CreatedAt)
+	s.recordResult(err)
+	noteWriteResult(ctx, key, err)
 	if err != nil {
 		s.log.WithError(err).Error("Error creating call record")
 		return err
@@ -55,45 +219,311 @@ func (s *Store) CreateCall(ctx context.Context, call *Call) error {
 	return nil
 }
 
-// UpdateCallHangup updates a call record with hangup information
-func (s *Store) UpdateCallHangup(ctx context.Context, uuid string, endTime time.Time, status string) error {
+// EnsureCall inserts call unless a record with the same UUID already exists.
+// Used when repairing calls whose CHANNEL_CREATE was missed.
+func (s *Store) EnsureCall(ctx context.Context, call *Call) error {
+	return s.write(ctx, writeOp{
+		name: "ensure_call",
+		uuid: call.UUID,
+		query: `
+			INSERT INTO calls (uuid, direction, caller, caller_name, callee, start_time, context, node)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (uuid) DO NOTHING`,
+		args: []any{call.UUID, call.Direction, s.sealNumber(call.Caller), call.CallerName, s.sealNumber(call.Callee), call.StartTime, call.Context, call.Node},
+	})
+}
+
+// CloseOrphanedCalls ends calls of node that are still open in the database, started before startedBefore,
+// and are not in activeUUIDs. Calls recorded without a node are included. It returns the number of calls closed.
+func (s *Store) CloseOrphanedCalls(ctx context.Context, node string, activeUUIDs []string, startedBefore time.Time, status string) (int64, error) {
 	query := `
 		UPDATE calls
-		SET end_time = $1, status = $2
-		WHERE uuid = $3`
+		SET end_time = now(), status = $1
+		WHERE end_time IS NULL AND start_time < $2 AND NOT (uuid = ANY($3)) AND (node IS NULL OR node = $4)`
 
-	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cmdTag, err := s.db.Exec(ctxTimeout, query, endTime, status, uuid)
+	cmdTag, err := s.db.Exec(ctxTimeout, query, status, startedBefore, activeUUIDs, node)
 	if err != nil {
-		s.log.WithError(err).WithField("uuid", uuid).Error("Error updating call record for hangup")
-		return err
+		s.log.WithError(err).Error("Error closing orphaned calls")
+		return 0, err
 	}
-	if cmdTag.RowsAffected() == 0 {
-		s.log.WithField("uuid", uuid).Warn("No call record found to update for hangup")
-		// Depending on requirements, this might be an error or just a warning.
-		// For now, logging as a warning.
+	if n := cmdTag.RowsAffected(); n > 0 {
+		s.log.WithFields(logrus.Fields{
+			"closed": n,
+			"node":   node,
+			"status": status,
+		}).Warn("Closed orphaned call records")
 	}
-	s.log.WithFields(logrus.Fields{
-		"uuid":   uuid,
-		"status": status,
-	}).Info("Call record updated with hangup info")
-	return nil
+	return cmdTag.RowsAffected(), nil
 }
 
-// GetCalls retrieves a list of calls with pagination
-func (s *Store) GetCalls(ctx context.Context, limit, offset int) ([]Call, error) {
-	query := `
-		SELECT id, uuid, direction, caller, callee, start_time, end_time, status, created_at
+// UpdateCallHangup updates a call record with hangup information.
+// cost may be nil when rating is disabled.
+func (s *Store) UpdateCallHangup(ctx context.Context, uuid string, endTime time.Time, status string, cost *float64) error {
+	return s.write(ctx, writeOp{
+		name: "update_call_hangup",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET end_time = $1, status = $2,
+				cost = CASE WHEN billsec IS NULL THEN $3 ELSE cost END -- Keep cost finalized at HANGUP_COMPLETE
+			WHERE uuid = $4`,
+		args:         []any{endTime, status, cost, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// SetCallSIPCallID records the SIP Call-ID of a call, unless one is already set. Outbound legs only
+// learn their Call-ID once the INVITE has been sent, after CHANNEL_CREATE.
+func (s *Store) SetCallSIPCallID(ctx context.Context, uuid, sipCallID string) error {
+	return s.write(ctx, writeOp{
+		name: "set_call_sip_call_id",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET sip_call_id = COALESCE(sip_call_id, $1)
+			WHERE uuid = $2`,
+		args: []any{sipCallID, uuid},
+	})
+}
+
+// UpdateCallBilling stores the finalized billing durations from CHANNEL_HANGUP_COMPLETE. A non-nil cost
+// replaces the one estimated at CHANNEL_HANGUP.
+func (s *Store) UpdateCallBilling(ctx context.Context, uuid string, billsec, duration, progresssec int64, cost *float64) error {
+	return s.write(ctx, writeOp{
+		name: "update_call_billing",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET billsec = $1, duration = $2, progresssec = $3, cost = COALESCE($4, cost)
+			WHERE uuid = $5`,
+		args:         []any{billsec, duration, progresssec, cost, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// SetCallHangupCodes stores the Q.850 cause and SIP status codes from CHANNEL_HANGUP_COMPLETE. Nil codes
+// leave the stored value unchanged.
+func (s *Store) SetCallHangupCodes(ctx context.Context, uuid string, q850, sipTermStatus, sipInviteFailureStatus *int) error {
+	return s.write(ctx, writeOp{
+		name: "set_call_hangup_codes",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET hangup_cause_q850 = COALESCE($1, hangup_cause_q850),
+				sip_term_status = COALESCE($2, sip_term_status),
+				sip_invite_failure_status = COALESCE($3, sip_invite_failure_status)
+			WHERE uuid = $4`,
+		args:         []any{q850, sipTermStatus, sipInviteFailureStatus, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// MarkCallCorrected flags a closed call as having received a late event at t
+func (s *Store) MarkCallCorrected(ctx context.Context, uuid string, t time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "mark_call_corrected",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET late_corrections = late_corrections + 1,
+				last_corrected_at = GREATEST(last_corrected_at, $1)
+			WHERE uuid = $2`,
+		args:         []any{t, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// MergeCallSIPHeaders merges captured SIP headers into the call's sip_headers, overwriting existing keys
+func (s *Store) MergeCallSIPHeaders(ctx context.Context, uuid string, headers map[string]string) error {
+	return s.write(ctx, writeOp{
+		name: "merge_call_sip_headers",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET sip_headers = COALESCE(sip_headers, '{}'::jsonb) || $1::jsonb
+			WHERE uuid = $2`,
+		args:         []any{headers, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// MergeCallVariables merges captured channel variables into the call's variables, overwriting existing keys
+func (s *Store) MergeCallVariables(ctx context.Context, uuid string, vars map[string]string) error {
+	return s.write(ctx, writeOp{
+		name: "merge_call_variables",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET variables = COALESCE(variables, '{}'::jsonb) || $1::jsonb
+			WHERE uuid = $2`,
+		args:         []any{vars, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// MergeCallDerived merges fields into the call's derived fields, overwriting existing keys
+func (s *Store) MergeCallDerived(ctx context.Context, uuid string, fields map[string]string) error {
+	return s.write(ctx, writeOp{
+		name: "merge_call_derived",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET derived = COALESCE(derived, '{}'::jsonb) || $1::jsonb
+			WHERE uuid = $2`,
+		args:         []any{fields, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// CallFilter narrows GetCalls results. Empty fields are ignored.
+type CallFilter struct {
+	Context     string
+	SIPProfile  string
+	Domain      string
+	AccountCode string
+	UserID      string
+	Gateway     string
+	Node        string
+	SIPCallID   string
+	Tag         string // Calls carrying this tag
+	Campaign    string // Calls attributed to this caller-ID campaign
+	Corrected   bool   // Only calls that received late corrections
+	Outcome     string // Calls classified with this outcome label
+
+	BusinessHours *bool // Calls started inside (true) or outside (false) business hours
+	Q850          *int  // Calls with this Q.850 hangup cause value
+	SIPStatus     *int  // Calls whose final SIP response (sip_term_status) was this code
+
+	Variables map[string]string // Calls whose captured channel variables include every pair
+
+	VertoSession string // Calls from this verto session id
+	RetryOf      string // Retries of the originate whose first attempt had this UUID
+}
+
+// where builds a SQL WHERE clause for the filter, appending bind values to args
+func (f CallFilter) where(args []any) (string, []any) {
+	var conds []string
+	add := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	add("context", f.Context)
+	add("sip_profile", f.SIPProfile)
+	add("domain", f.Domain)
+	add("account_code", f.AccountCode)
+	add("user_id", f.UserID)
+	add("gateway", f.Gateway)
+	add("node", f.Node)
+	add("sip_call_id", f.SIPCallID)
+	add("caller_id_campaign", f.Campaign)
+	add("outcome", f.Outcome)
+	add("verto_session_id", f.VertoSession)
+	add("retry_of", f.RetryOf)
+	if f.Tag != "" {
+		args = append(args, f.Tag)
+		conds = append(conds, fmt.Sprintf("$%d = ANY(tags)", len(args)))
+	}
+	if f.Corrected {
+		conds = append(conds, "late_corrections > 0")
+	}
+	if f.BusinessHours != nil {
+		args = append(args, *f.BusinessHours)
+		conds = append(conds, fmt.Sprintf("business_hours = $%d", len(args)))
+	}
+	if f.Q850 != nil {
+		args = append(args, *f.Q850)
+		conds = append(conds, fmt.Sprintf("hangup_cause_q850 = $%d", len(args)))
+	}
+	if f.SIPStatus != nil {
+		args = append(args, *f.SIPStatus)
+		conds = append(conds, fmt.Sprintf("sip_term_status = $%d", len(args)))
+	}
+	if len(f.Variables) > 0 {
+		args = append(args, f.Variables)
+		conds = append(conds, fmt.Sprintf("variables @> $%d::jsonb", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// SetCallGateway records the gateway a call was routed through, unless one is already set
+func (s *Store) SetCallGateway(ctx context.Context, uuid, gateway string) error {
+	return s.write(ctx, writeOp{
+		name: "set_call_gateway",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET gateway = COALESCE(gateway, $1)
+			WHERE uuid = $2`,
+		args: []any{gateway, uuid},
+	})
+}
+
+// CallTimestamp identifies one of the call-progress timestamp columns
+type CallTimestamp string
+
+// Call-progress timestamp columns
+const (
+	TimestampRinging    CallTimestamp = "ringing_time"
+	TimestampEarlyMedia CallTimestamp = "early_media_time"
+	TimestampAnswered   CallTimestamp = "answered_time"
+	TimestampBridged    CallTimestamp = "bridged_time"
+)
+
+// ErrUnknownTimestamp is returned when SetCallTimestamp is given an unsupported column
+var ErrUnknownTimestamp = errors.New("unknown call timestamp column")
+
+// SetCallTimestamp records a call-progress timestamp. The first recorded value wins, so repeated
+// events (e.g. a second CHANNEL_PROGRESS after re-INVITE) don't move the timestamp forward.
+func (s *Store) SetCallTimestamp(ctx context.Context, uuid string, column CallTimestamp, t time.Time) error {
+	switch column {
+	case TimestampRinging, TimestampEarlyMedia, TimestampAnswered, TimestampBridged:
+	default:
+		return ErrUnknownTimestamp
+	}
+
+	return s.write(ctx, writeOp{
+		name: "set_" + string(column),
+		uuid: uuid,
+		query: fmt.Sprintf(`
+			UPDATE calls
+			SET %[1]s = COALESCE(%[1]s, $1)
+			WHERE uuid = $2`, column),
+		args:         []any{t, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// GetCalls retrieves a filtered list of calls with pagination
+func (s *Store) GetCalls(ctx context.Context, filter CallFilter, limit, offset int) ([]Call, error) {
+	where, args := filter.where(nil)
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT `+callColumns+`
 		FROM calls
+		%s
 		ORDER BY start_time DESC
-		LIMIT $1 OFFSET $2`
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
 
+	if err := s.checkPage(ctx, limit, offset); err != nil {
+		return nil, err
+	}
 	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
+	if err := s.checkPlan(ctxTimeout, query, args...); err != nil {
+		return nil, err
+	}
 
-	rows, err := s.db.Query(ctxTimeout, query, limit, offset)
+	rows, err := s.db.Query(ctxTimeout, query, args...)
 	if err != nil {
 		s.log.WithError(err).Error("Error getting calls")
 		return nil, err
@@ -103,13 +533,11 @@ func (s *Store) GetCalls(ctx context.Context, limit, offset int) ([]Call, error)
 	var calls []Call
 	for rows.Next() {
 		var call Call
-		if err := rows.Scan(
-			&call.ID, &call.UUID, &call.Direction, &call.Caller, &call.Callee,
-			&call.StartTime, &call.EndTime, &call.Status, &call.CreatedAt,
-		); err != nil {
+		if err := scanCall(rows, &call); err != nil {
 			s.log.WithError(err).Error("Error scanning call row")
 			return nil, err
 		}
+		s.openCall(ctx, &call)
 		calls = append(calls, call)
 	}
 
@@ -129,7 +557,7 @@ func (s *Store) GetCalls(ctx context.Context, limit, offset int) ([]Call, error)
 // GetCallByUUID retrieves a single call by its UUID
 func (s *Store) GetCallByUUID(ctx context.Context, uuid string) (*Call, error) {
 	query := `
-		SELECT id, uuid, direction, caller, callee, start_time, end_time, status, created_at
+		SELECT ` + callColumns + `
 		FROM calls
 		WHERE uuid = $1`
 
@@ -137,41 +565,581 @@ func (s *Store) GetCallByUUID(ctx context.Context, uuid string) (*Call, error) {
 	defer cancel()
 
 	var call Call
-	err := s.db.QueryRow(ctxTimeout, query, uuid).Scan(
-		&call.ID, &call.UUID, &call.Direction, &call.Caller, &call.Callee,
-		&call.StartTime, &call.EndTime, &call.Status, &call.CreatedAt,
-	)
+	err := scanCall(s.db.QueryRow(ctxTimeout, query, uuid), &call)
 	if err != nil {
 		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call by UUID")
 		return nil, err // Consider pgx.ErrNoRows specifically if needed
 	}
+	s.openCall(ctx, &call)
+	if len(s.customColumns) > 0 {
+		if call.Custom, err = s.getCallCustomFields(ctx, uuid); err != nil {
+			return nil, err
+		}
+	}
 	s.log.WithField("uuid", uuid).Info("Retrieved call by UUID")
 	return &call, nil
 }
 
-// InitSchema creates the calls table if it doesn't exist.
-// This is a basic implementation; for production, use migrations.
-func (s *Store) InitSchema(ctx context.Context) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS calls (
+// GetCallNode returns the node (ESL endpoint) that recorded the call, or "" if none was recorded
+func (s *Store) GetCallNode(ctx context.Context, uuid string) (string, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var node *string
+	if err := s.db.QueryRow(ctxTimeout, `SELECT node FROM calls WHERE uuid = $1`, uuid).Scan(&node); err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", nil
+	}
+	return *node, nil
+}
+
+// schemaStatements are applied in order by InitSchema. Each statement must be idempotent;
+// new columns are added with ALTER TABLE ... ADD COLUMN IF NOT EXISTS so existing databases are upgraded in place.
+var schemaStatements = []string{
+	`CREATE TABLE IF NOT EXISTS calls (
 		id         SERIAL PRIMARY KEY,
 		uuid       TEXT UNIQUE NOT NULL,
 		direction  TEXT NOT NULL,
 		caller     TEXT NOT NULL,
 		callee     TEXT NOT NULL,
-		start_time TIMESTAMP NOT NULL,
-		end_time   TIMESTAMP,
+		start_time TIMESTAMPTZ(6) NOT NULL,
+		end_time   TIMESTAMPTZ(6),
 		status     TEXT,
-		created_at TIMESTAMP DEFAULT now()
-	);`
+		created_at TIMESTAMPTZ(6) DEFAULT now()
+	)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS cost NUMERIC(12,4)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS caller_name TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS callee_name TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS context TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS sip_profile TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS domain TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS account_code TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS user_id TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS ringing_time TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS answered_time TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS bridged_time TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS gateway TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS node TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS sip_call_id TEXT`,
+	`CREATE INDEX IF NOT EXISTS calls_sip_call_id_idx ON calls (sip_call_id)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS billsec INTEGER`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS duration INTEGER`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS progresssec INTEGER`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS early_media_time TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS destination_country TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS destination_group TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS derived JSONB`,
+	`CREATE TABLE IF NOT EXISTS call_transitions (
+		id         BIGSERIAL PRIMARY KEY,
+		uuid       TEXT NOT NULL,
+		kind       TEXT NOT NULL,
+		from_state TEXT,
+		to_state   TEXT NOT NULL,
+		event_time TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_transitions_uuid_idx ON call_transitions (uuid, event_time)`,
+	`CREATE TABLE IF NOT EXISTS call_dtmf (
+		id          BIGSERIAL PRIMARY KEY,
+		uuid        TEXT NOT NULL,
+		digit       TEXT NOT NULL,
+		duration_ms INTEGER,
+		source      TEXT,
+		event_time  TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_dtmf_uuid_idx ON call_dtmf (uuid, event_time)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS hold_seconds DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS hold_count INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS hold_intervals (
+		id         BIGSERIAL PRIMARY KEY,
+		uuid       TEXT NOT NULL,
+		started_at TIMESTAMPTZ(6) NOT NULL,
+		ended_at   TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS hold_intervals_uuid_idx ON hold_intervals (uuid, started_at)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS transferred_to TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS transferred_by TEXT`,
+	`CREATE TABLE IF NOT EXISTS call_transfers (
+		id              BIGSERIAL PRIMARY KEY,
+		transferor_uuid TEXT NOT NULL,
+		transferee_uuid TEXT,
+		target_uuid     TEXT,
+		kind            TEXT NOT NULL,
+		destination     TEXT,
+		occurred_at     TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_transfers_transferor_idx ON call_transfers (transferor_uuid)`,
+	`CREATE INDEX IF NOT EXISTS call_transfers_transferee_idx ON call_transfers (transferee_uuid)`,
+	`CREATE INDEX IF NOT EXISTS call_transfers_target_idx ON call_transfers (target_uuid)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	`CREATE INDEX IF NOT EXISTS calls_tags_idx ON calls USING GIN (tags)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS late_corrections INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS last_corrected_at TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS sip_headers JSONB`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS caller_id_campaign TEXT`,
+	`CREATE INDEX IF NOT EXISTS calls_caller_id_campaign_idx ON calls (caller_id_campaign, start_time) WHERE caller_id_campaign IS NOT NULL`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS business_hours BOOLEAN`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS outcome TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS outcome_source TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS classified_at TIMESTAMPTZ(6)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS variables JSONB`,
+	`CREATE INDEX IF NOT EXISTS calls_variables_idx ON calls USING GIN (variables jsonb_path_ops)`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS hangup_cause_q850 INTEGER`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS sip_term_status INTEGER`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS sip_invite_failure_status INTEGER`,
+	`CREATE INDEX IF NOT EXISTS calls_outcome_idx ON calls (outcome, start_time) WHERE outcome IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS calls_unclassified_idx ON calls (end_time) WHERE classified_at IS NULL`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS verto_session_id TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS verto_client_address TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS verto_user_agent TEXT`,
+	`CREATE INDEX IF NOT EXISTS calls_verto_session_idx ON calls (verto_session_id) WHERE verto_session_id IS NOT NULL`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS retry_of TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS retry_attempt INTEGER`,
+	`CREATE INDEX IF NOT EXISTS calls_retry_of_idx ON calls (retry_of) WHERE retry_of IS NOT NULL`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS parked_seconds DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS park_count INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS retrieved_by TEXT`,
+	`ALTER TABLE calls ADD COLUMN IF NOT EXISTS retrieved_by_uuid TEXT`,
+	`CREATE TABLE IF NOT EXISTS park_intervals (
+		id                BIGSERIAL PRIMARY KEY,
+		uuid              TEXT NOT NULL,
+		lot               TEXT,
+		extension         TEXT,
+		parked_at         TIMESTAMPTZ(6) NOT NULL,
+		unparked_at       TIMESTAMPTZ(6),
+		retrieved_by_uuid TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS park_intervals_uuid_idx ON park_intervals (uuid, parked_at)`,
+	`CREATE TABLE IF NOT EXISTS raw_events (
+		id         BIGSERIAL PRIMARY KEY,
+		uuid       TEXT,
+		event_name TEXT NOT NULL,
+		subclass   TEXT NOT NULL DEFAULT '',
+		node       TEXT,
+		event_time TIMESTAMPTZ(6) NOT NULL,
+		headers    JSONB NOT NULL,
+		body       TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS raw_events_uuid_idx ON raw_events (uuid, event_time) WHERE uuid IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS raw_events_time_idx ON raw_events (event_time)`,
+	`CREATE TABLE IF NOT EXISTS call_events (
+		uuid       TEXT NOT NULL,
+		event_name TEXT NOT NULL,
+		subclass   TEXT NOT NULL DEFAULT '',
+		count      BIGINT NOT NULL,
+		first_at   TIMESTAMPTZ(6) NOT NULL,
+		last_at    TIMESTAMPTZ(6) NOT NULL,
+		PRIMARY KEY (uuid, event_name, subclass)
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_events_last_idx ON call_events (last_at)`,
+	`CREATE TABLE IF NOT EXISTS caller_id_campaigns (
+		caller_id  TEXT PRIMARY KEY,
+		campaign   TEXT NOT NULL,
+		created_at TIMESTAMPTZ(6) NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ(6) NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS gateways (
+		name              TEXT PRIMARY KEY,
+		state             TEXT NOT NULL,
+		ping_status       TEXT,
+		status            TEXT,
+		phrase            TEXT,
+		status_changed_at TIMESTAMPTZ(6),
+		failures          INTEGER NOT NULL DEFAULT 0,
+		updated_at        TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS server_stats (
+		id                     BIGSERIAL PRIMARY KEY,
+		node                   TEXT NOT NULL,
+		hostname               TEXT,
+		core_uuid              TEXT,
+		version                TEXT,
+		uptime_ms              BIGINT NOT NULL,
+		session_count          INTEGER NOT NULL,
+		max_sessions           INTEGER,
+		sessions_per_sec       DOUBLE PRECISION NOT NULL,
+		sessions_since_startup BIGINT NOT NULL,
+		session_peak           INTEGER,
+		idle_cpu               DOUBLE PRECISION,
+		recorded_at            TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS server_stats_recorded_idx ON server_stats (recorded_at, node)`,
+	`CREATE TABLE IF NOT EXISTS saved_searches (
+		id         BIGSERIAL PRIMARY KEY,
+		owner      TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		params     JSONB NOT NULL,
+		created_at TIMESTAMPTZ(6) NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ(6) NOT NULL DEFAULT now(),
+		UNIQUE (owner, name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS call_applications (
+		id           BIGSERIAL PRIMARY KEY,
+		uuid         TEXT NOT NULL,
+		app_uuid     TEXT,
+		application  TEXT NOT NULL,
+		data         TEXT,
+		started_at   TIMESTAMPTZ(6),
+		completed_at TIMESTAMPTZ(6),
+		response     TEXT
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS call_applications_app_uuid_idx ON call_applications (uuid, app_uuid)`,
+	`CREATE TABLE IF NOT EXISTS health_transitions (
+		id          BIGSERIAL PRIMARY KEY,
+		component   TEXT NOT NULL,
+		instance    TEXT NOT NULL DEFAULT '',
+		state       TEXT NOT NULL,
+		reason      TEXT,
+		occurred_at TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS health_transitions_component_idx ON health_transitions (component, instance, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS messages (
+		id           BIGSERIAL PRIMARY KEY,
+		message_id   TEXT,
+		direction    TEXT NOT NULL,
+		proto        TEXT,
+		from_addr    TEXT NOT NULL,
+		to_addr      TEXT NOT NULL,
+		body         TEXT NOT NULL,
+		content_type TEXT,
+		status       TEXT NOT NULL,
+		status_code  TEXT,
+		created_at   TIMESTAMPTZ(6) NOT NULL,
+		updated_at   TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS messages_message_id_idx ON messages (message_id) WHERE message_id IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS messages_created_at_idx ON messages (created_at)`,
+	`CREATE TABLE IF NOT EXISTS voicemail_events (
+		id             BIGSERIAL PRIMARY KEY,
+		mailbox        TEXT NOT NULL,
+		domain         TEXT NOT NULL DEFAULT '',
+		action         TEXT NOT NULL,
+		kind           TEXT,
+		message_uuid   TEXT,
+		call_uuid      TEXT,
+		caller_number  TEXT,
+		caller_name    TEXT,
+		duration_sec   INTEGER,
+		new_messages   INTEGER,
+		saved_messages INTEGER,
+		read_count     INTEGER NOT NULL DEFAULT 0,
+		event_time     TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS voicemail_events_mailbox_idx ON voicemail_events (mailbox, domain, event_time)`,
+	`CREATE INDEX IF NOT EXISTS voicemail_events_time_idx ON voicemail_events (event_time)`,
+	`CREATE INDEX IF NOT EXISTS voicemail_events_call_idx ON voicemail_events (call_uuid) WHERE call_uuid IS NOT NULL`,
+	`CREATE TABLE IF NOT EXISTS conferences (
+		id              BIGSERIAL PRIMARY KEY,
+		conference_uuid TEXT NOT NULL UNIQUE,
+		name            TEXT NOT NULL,
+		profile         TEXT,
+		started_at      TIMESTAMPTZ(6) NOT NULL,
+		ended_at        TIMESTAMPTZ(6),
+		max_members     INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS conferences_started_at_idx ON conferences (started_at)`,
+	`CREATE TABLE IF NOT EXISTS conference_members (
+		id              BIGSERIAL PRIMARY KEY,
+		conference_uuid TEXT NOT NULL,
+		member_id       INTEGER NOT NULL,
+		call_uuid       TEXT,
+		caller_number   TEXT,
+		caller_name     TEXT,
+		joined_at       TIMESTAMPTZ(6) NOT NULL,
+		left_at         TIMESTAMPTZ(6),
+		muted_at        TIMESTAMPTZ(6),
+		muted_seconds   DOUBLE PRECISION NOT NULL DEFAULT 0,
+		mute_count      INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (conference_uuid, member_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS registrations (
+		id            BIGSERIAL PRIMARY KEY,
+		call_id       TEXT NOT NULL UNIQUE,
+		profile       TEXT,
+		sip_user      TEXT NOT NULL,
+		realm         TEXT,
+		contact       TEXT,
+		network_ip    TEXT,
+		network_port  TEXT,
+		user_agent    TEXT,
+		status        TEXT NOT NULL,
+		registered_at TIMESTAMPTZ(6) NOT NULL,
+		refreshed_at  TIMESTAMPTZ(6) NOT NULL,
+		expires_at    TIMESTAMPTZ(6),
+		ended_at      TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS registrations_user_idx ON registrations (sip_user, realm)`,
+	`CREATE TABLE IF NOT EXISTS verto_sessions (
+		id              BIGSERIAL PRIMARY KEY,
+		session_id      TEXT,
+		login           TEXT NOT NULL,
+		client_address  TEXT,
+		user_agent      TEXT,
+		node            TEXT,
+		connected_at    TIMESTAMPTZ(6) NOT NULL,
+		disconnected_at TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS verto_sessions_open_idx ON verto_sessions (login, client_address) WHERE disconnected_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS verto_sessions_connected_idx ON verto_sessions (connected_at)`,
+	`CREATE TABLE IF NOT EXISTS recordings (
+		id          BIGSERIAL PRIMARY KEY,
+		uuid        TEXT NOT NULL,
+		file_path   TEXT NOT NULL,
+		started_at  TIMESTAMPTZ(6),
+		stopped_at  TIMESTAMPTZ(6),
+		duration_ms BIGINT
+	)`,
+	`CREATE INDEX IF NOT EXISTS recordings_uuid_idx ON recordings (uuid)`,
+	`CREATE INDEX IF NOT EXISTS recordings_time_idx ON recordings (COALESCE(started_at, stopped_at))`,
+	`CREATE TABLE IF NOT EXISTS faxes (
+		id                BIGSERIAL PRIMARY KEY,
+		uuid              TEXT NOT NULL,
+		direction         TEXT NOT NULL,
+		success           BOOLEAN NOT NULL,
+		result_code       INTEGER,
+		result_text       TEXT,
+		pages             INTEGER NOT NULL DEFAULT 0,
+		total_pages       INTEGER,
+		remote_station_id TEXT,
+		local_station_id  TEXT,
+		file_path         TEXT,
+		transfer_rate     INTEGER,
+		ecm_used          BOOLEAN,
+		occurred_at       TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS faxes_uuid_idx ON faxes (uuid)`,
+	`CREATE INDEX IF NOT EXISTS faxes_time_idx ON faxes (occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS call_quality (
+		uuid                TEXT PRIMARY KEY,
+		mos                 DOUBLE PRECISION,
+		quality_percentage  DOUBLE PRECISION,
+		jitter_min_variance DOUBLE PRECISION,
+		jitter_max_variance DOUBLE PRECISION,
+		jitter_loss_rate    DOUBLE PRECISION,
+		jitter_burst_rate   DOUBLE PRECISION,
+		mean_interval       DOUBLE PRECISION,
+		flaws               BIGINT,
+		packets_in          BIGINT,
+		packets_out         BIGINT,
+		packets_lost        BIGINT,
+		packet_loss         DOUBLE PRECISION,
+		recorded_at         TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_quality_time_idx ON call_quality (recorded_at)`,
+	`CREATE TABLE IF NOT EXISTS call_legs (
+		id           BIGSERIAL PRIMARY KEY,
+		a_uuid       TEXT NOT NULL,
+		b_uuid       TEXT NOT NULL,
+		bridged_at   TIMESTAMPTZ(6) NOT NULL,
+		unbridged_at TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS call_legs_a_idx ON call_legs (a_uuid)`,
+	`CREATE INDEX IF NOT EXISTS call_legs_b_idx ON call_legs (b_uuid)`,
+	`CREATE TABLE IF NOT EXISTS switch_events (
+		id                 BIGSERIAL PRIMARY KEY,
+		node               TEXT NOT NULL,
+		event_type         TEXT NOT NULL,
+		core_uuid          TEXT NOT NULL,
+		previous_core_uuid TEXT,
+		closed_calls       BIGINT NOT NULL DEFAULT 0,
+		occurred_at        TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS switch_events_node_idx ON switch_events (node, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS campaigns (
+		id                BIGSERIAL PRIMARY KEY,
+		name              TEXT NOT NULL,
+		endpoint_template TEXT NOT NULL,
+		destination       TEXT NOT NULL DEFAULT '',
+		caller_id_number  TEXT NOT NULL DEFAULT '',
+		caller_id_name    TEXT NOT NULL DEFAULT '',
+		concurrency       INT NOT NULL,
+		interval_ms       INT NOT NULL,
+		timeout_seconds   INT NOT NULL,
+		status            TEXT NOT NULL,
+		created_at        TIMESTAMPTZ(6) NOT NULL DEFAULT now(),
+		finished_at       TIMESTAMPTZ(6)
+	)`,
+	`CREATE TABLE IF NOT EXISTS campaign_attempts (
+		id          BIGSERIAL PRIMARY KEY,
+		campaign_id BIGINT NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+		number      TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		call_uuid   TEXT,
+		cause       TEXT,
+		started_at  TIMESTAMPTZ(6),
+		finished_at TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS campaign_attempts_campaign_idx ON campaign_attempts (campaign_id)`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS max_attempts INT NOT NULL DEFAULT 1`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS retry_interval_seconds INT NOT NULL DEFAULT 0`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS retry_causes TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS confirm_key TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS confirm_prompt TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS confirm_timeout_seconds INT NOT NULL DEFAULT 0`,
+	`ALTER TABLE campaign_attempts ADD COLUMN IF NOT EXISTS tries INT NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id          BIGSERIAL PRIMARY KEY,
+		action      TEXT NOT NULL,
+		actor       TEXT NOT NULL,
+		client_ip   TEXT NOT NULL,
+		target      TEXT NOT NULL,
+		details     JSONB,
+		occurred_at TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (target, occurred_at)`,
+	`CREATE TABLE IF NOT EXISTS dead_letter_events (
+		id              BIGSERIAL PRIMARY KEY,
+		node            TEXT NOT NULL,
+		event_name      TEXT NOT NULL,
+		uuid            TEXT NOT NULL DEFAULT '',
+		stage           TEXT NOT NULL,
+		error           TEXT NOT NULL,
+		headers         JSONB NOT NULL,
+		body            TEXT NOT NULL DEFAULT '',
+		attempts        INT NOT NULL,
+		failed_at       TIMESTAMPTZ(6) NOT NULL,
+		reprocessed_at  TIMESTAMPTZ(6),
+		reprocess_error TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS dead_letter_events_pending_idx ON dead_letter_events (failed_at) WHERE reprocessed_at IS NULL`,
+	`ALTER TABLE dead_letter_events ADD COLUMN IF NOT EXISTS applied_writes TEXT[]`, // Skipped when reprocessing
+	// API usage per key and calendar month (UTC); keys are stored as fingerprints, never in full
+	`CREATE TABLE IF NOT EXISTS api_key_usage (
+		key_id         TEXT NOT NULL,
+		role           TEXT NOT NULL,
+		month          DATE NOT NULL,
+		requests       BIGINT NOT NULL DEFAULT 0,
+		response_bytes BIGINT NOT NULL DEFAULT 0,
+		updated_at     TIMESTAMPTZ(6) NOT NULL DEFAULT now(),
+		PRIMARY KEY (key_id, month)
+	)`,
+	`CREATE TABLE IF NOT EXISTS parked_calls (
+		id           BIGSERIAL PRIMARY KEY,
+		uuid         TEXT NOT NULL,
+		lot          TEXT NOT NULL,
+		slot         INT NOT NULL,
+		status       TEXT NOT NULL,
+		parked_at    TIMESTAMPTZ(6) NOT NULL,
+		released_at  TIMESTAMPTZ(6),
+		retrieved_to TEXT
+	)`,
+	// A slot holds one call, and a call sits in one slot, at a time
+	`CREATE UNIQUE INDEX IF NOT EXISTS parked_calls_active_slot_idx ON parked_calls (lot, slot) WHERE status = 'parked'`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS parked_calls_active_uuid_idx ON parked_calls (uuid) WHERE status = 'parked'`,
+	`CREATE TABLE IF NOT EXISTS queue_calls (
+		uuid        TEXT PRIMARY KEY,
+		queue       TEXT NOT NULL,
+		agent       TEXT,
+		joined_at   TIMESTAMPTZ(6) NOT NULL,
+		answered_at TIMESTAMPTZ(6),
+		left_at     TIMESTAMPTZ(6) NOT NULL,
+		outcome     TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS queue_calls_joined_idx ON queue_calls (joined_at, queue)`,
+	`ALTER TABLE queue_calls ALTER COLUMN left_at DROP NOT NULL`, // NULL while the member is waiting
+	`ALTER TABLE queue_calls ADD COLUMN IF NOT EXISTS caller_number TEXT`,
+	`ALTER TABLE queue_calls ADD COLUMN IF NOT EXISTS caller_name TEXT`,
+	`CREATE TABLE IF NOT EXISTS queues (
+		name           TEXT PRIMARY KEY,
+		reported_count INTEGER,
+		updated_at     TIMESTAMPTZ(6)
+	)`,
+	`CREATE TABLE IF NOT EXISTS agents (
+		name              TEXT PRIMARY KEY,
+		status            TEXT,
+		status_changed_at TIMESTAMPTZ(6),
+		state             TEXT,
+		state_changed_at  TIMESTAMPTZ(6)
+	)`,
+	`CREATE TABLE IF NOT EXISTS agent_transitions (
+		id         BIGSERIAL PRIMARY KEY,
+		agent      TEXT NOT NULL,
+		kind       TEXT NOT NULL,
+		from_value TEXT,
+		to_value   TEXT NOT NULL,
+		event_time TIMESTAMPTZ(6) NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS agent_transitions_agent_idx ON agent_transitions (agent, event_time)`,
+	`CREATE TABLE IF NOT EXISTS callbacks (
+		id                  BIGSERIAL PRIMARY KEY,
+		original_uuid       TEXT UNIQUE NOT NULL,
+		number              TEXT NOT NULL,
+		queue               TEXT,
+		status              TEXT NOT NULL,
+		offered_at          TIMESTAMPTZ(6),
+		accepted_at         TIMESTAMPTZ(6),
+		callback_uuid       TEXT,
+		callback_started_at TIMESTAMPTZ(6)
+	)`,
+	`CREATE INDEX IF NOT EXISTS callbacks_pending_idx ON callbacks (number, accepted_at) WHERE callback_uuid IS NULL`,
+	`CREATE TABLE IF NOT EXISTS extension_features (
+		extension         TEXT NOT NULL,
+		domain            TEXT NOT NULL DEFAULT '',
+		dnd               BOOLEAN NOT NULL DEFAULT false,
+		forward_always    TEXT NOT NULL DEFAULT '',
+		forward_busy      TEXT NOT NULL DEFAULT '',
+		forward_no_answer TEXT NOT NULL DEFAULT '',
+		updated_at        TIMESTAMPTZ(6) NOT NULL,
+		PRIMARY KEY (extension, domain)
+	)`,
+}
+
+// SetLegacyTimeZone sets the IANA zone the TIMESTAMP values of older databases were written in, which
+// InitSchema converts to TIMESTAMPTZ once. It must be called before InitSchema.
+func (s *Store) SetLegacyTimeZone(zone string) {
+	s.legacyTimeZone = zone
+}
+
+// timestampSchemaStatements convert the TIMESTAMP columns of older databases, which held wall-clock values
+// of the legacy zone, so every column holds an absolute instant, then add the columns generated from them
+func (s *Store) timestampSchemaStatements() []string {
+	zone := s.legacyTimeZone
+	if zone == "" {
+		zone = "UTC"
+	}
+	return []string{
+		`DO $$
+		DECLARE col record;
+		BEGIN
+			-- The generated timing columns depend on the columns converted below; they are added back after
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'calls'
+					AND data_type = 'timestamp without time zone'
+			) THEN
+				ALTER TABLE calls DROP COLUMN IF EXISTS pdd, DROP COLUMN IF EXISTS ring_time;
+			END IF;
+			FOR col IN
+				SELECT table_name, column_name
+				FROM information_schema.columns
+				WHERE table_schema = current_schema()
+					AND table_name IN ('calls', 'call_transitions', 'switch_events')
+					AND data_type = 'timestamp without time zone'
+			LOOP
+				EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ(6) USING %I AT TIME ZONE %L',
+					col.table_name, col.column_name, col.column_name, '` + strings.ReplaceAll(zone, "'", "''") + `');
+			END LOOP;
+		END $$`,
+		// Post-dial delay and ring time, generated from the call timestamps once they are all TIMESTAMPTZ
+		`ALTER TABLE calls ADD COLUMN IF NOT EXISTS pdd DOUBLE PRECISION
+			GENERATED ALWAYS AS (EXTRACT(EPOCH FROM (ringing_time - start_time))::float8) STORED`,
+		`ALTER TABLE calls ADD COLUMN IF NOT EXISTS ring_time DOUBLE PRECISION
+			GENERATED ALWAYS AS (EXTRACT(EPOCH FROM (COALESCE(answered_time, end_time) - ringing_time))::float8) STORED`,
+	}
+}
 
+// InitSchema creates the calls table if it doesn't exist and applies additive column changes.
+// This is a basic implementation; for production, use migrations.
+func (s *Store) InitSchema(ctx context.Context) error {
 	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	_, err := s.db.Exec(ctxTimeout, query)
-	if err != nil {
-		s.log.WithError(err).Error("Error initializing database schema")
-		return err
+	for _, stmt := range slices.Concat(schemaStatements, s.timestampSchemaStatements(), s.customSchemaStatements(), s.rlsSchemaStatements()) {
+		if _, err := s.db.Exec(ctxTimeout, stmt); err != nil {
+			s.log.WithError(err).Error("Error initializing database schema")
+			return err
+		}
 	}
 	s.log.Info("Database schema initialized (calls table ensured)")
 	return nil
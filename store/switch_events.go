@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Switch event types recorded in switch_events
+const (
+	SwitchEventConnected = "connected"  // Connected to a switch whose Core-UUID matched the last one seen
+	SwitchEventFirstSeen = "first_seen" // First connection to a node with no recorded Core-UUID
+	SwitchEventRestart   = "restart"    // Core-UUID changed, FreeSWITCH restarted
+)
+
+// SwitchEvent records a connection to, or restart of, a FreeSWITCH node
+type SwitchEvent struct {
+	ID               int64     `json:"id"`
+	Node             string    `json:"node"`
+	EventType        string    `json:"event_type"`
+	CoreUUID         string    `json:"core_uuid"`
+	PreviousCoreUUID *string   `json:"previous_core_uuid,omitempty"`
+	ClosedCalls      int64     `json:"closed_calls"`
+	OccurredAt       time.Time `json:"occurred_at"`
+}
+
+// AddSwitchEvent records a switch connection or restart
+func (s *Store) AddSwitchEvent(ctx context.Context, e *SwitchEvent) error {
+	return s.write(ctx, writeOp{
+		name: "add_switch_event",
+		query: `
+			INSERT INTO switch_events (node, event_type, core_uuid, previous_core_uuid, closed_calls, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+		args: []any{e.Node, e.EventType, e.CoreUUID, e.PreviousCoreUUID, e.ClosedCalls, e.OccurredAt},
+	})
+}
+
+// GetLastCoreUUID returns the most recently recorded Core-UUID of node, or "" if none is known
+func (s *Store) GetLastCoreUUID(ctx context.Context, node string) (string, error) {
+	query := `
+		SELECT core_uuid
+		FROM switch_events
+		WHERE node = $1
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT 1`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var coreUUID string
+	err := s.db.QueryRow(ctxTimeout, query, node).Scan(&coreUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("node", node).Error("Error getting last Core-UUID")
+		return "", err
+	}
+	return coreUUID, nil
+}
+
+// GetSwitchEvents returns the most recent switch events, newest first
+func (s *Store) GetSwitchEvents(ctx context.Context, limit, offset int) ([]SwitchEvent, error) {
+	query := `
+		SELECT id, node, event_type, core_uuid, previous_core_uuid, closed_calls, occurred_at
+		FROM switch_events
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $1 OFFSET $2`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting switch events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []SwitchEvent
+	for rows.Next() {
+		var e SwitchEvent
+		if err := rows.Scan(&e.ID, &e.Node, &e.EventType, &e.CoreUUID, &e.PreviousCoreUUID, &e.ClosedCalls, &e.OccurredAt); err != nil {
+			s.log.WithError(err).Error("Error scanning switch event row")
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating switch event rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"limit": limit,
+		"count": len(events),
+	}).Info("Retrieved switch events")
+	return events, nil
+}
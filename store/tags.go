@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TagFilter selects the calls a bulk tag operation applies to. Calls started in [From, To) that match
+// CallFilter and the optional number prefixes and direction are tagged.
+type TagFilter struct {
+	CallFilter
+	From, To     time.Time
+	CallerPrefix string
+	CalleePrefix string
+	Direction    string
+}
+
+// where builds the WHERE clause selecting the filtered calls, appending bind values to args
+func (f TagFilter) where(args []any) (string, []any) {
+	where, args := f.CallFilter.where(args)
+	if where == "" {
+		where = "WHERE TRUE"
+	}
+	args = append(args, f.From, f.To)
+	where += fmt.Sprintf(" AND start_time >= $%d AND start_time < $%d", len(args)-1, len(args))
+	if f.CallerPrefix != "" {
+		args = append(args, f.CallerPrefix)
+		where += fmt.Sprintf(" AND starts_with(caller, $%d)", len(args))
+	}
+	if f.CalleePrefix != "" {
+		args = append(args, f.CalleePrefix)
+		where += fmt.Sprintf(" AND starts_with(callee, $%d)", len(args))
+	}
+	if f.Direction != "" {
+		args = append(args, f.Direction)
+		where += fmt.Sprintf(" AND direction = $%d", len(args))
+	}
+	return where, args
+}
+
+// TagCalls adds tags to every call matching filter, batchSize calls per statement so a large range does
+// not hold one long transaction. Calls that already carry all the tags are left untouched. It returns the
+// number of calls matched and the number changed, or ErrEncryptedField for number prefixes while numbers
+// are encrypted.
+func (s *Store) TagCalls(ctx context.Context, filter TagFilter, tags []string, batchSize int) (matched, tagged int64, err error) {
+	if (filter.CallerPrefix != "" || filter.CalleePrefix != "") && s.cipher != nil {
+		return 0, 0, ErrEncryptedField // Prefixes of ciphertext mean nothing
+	}
+	where, args := filter.where(nil)
+	args = append(args, tags, 0, batchSize)
+	tagsArg, cursorArg, limitArg := len(args)-2, len(args)-1, len(args)
+	query := fmt.Sprintf(`
+		WITH batch AS (
+			SELECT id, tags FROM calls
+			%s AND id > $%[3]d
+			ORDER BY id
+			LIMIT $%[4]d
+		), updated AS (
+			UPDATE calls
+			SET tags = ARRAY(SELECT DISTINCT t FROM unnest(calls.tags || $%[2]d::text[]) AS t ORDER BY t)
+			FROM batch
+			WHERE calls.id = batch.id AND NOT batch.tags @> $%[2]d::text[]
+			RETURNING calls.id
+		)
+		SELECT (SELECT max(id) FROM batch), (SELECT count(*) FROM batch), (SELECT count(*) FROM updated)`,
+		where, tagsArg, cursorArg, limitArg)
+
+	for {
+		var lastID *int
+		var n, changed int64
+		ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err = s.db.QueryRow(ctxTimeout, query, args...).Scan(&lastID, &n, &changed)
+		cancel()
+		if err != nil {
+			s.log.WithError(err).Error("Error tagging calls")
+			return matched, tagged, err
+		}
+		if lastID == nil {
+			break
+		}
+		matched += n
+		tagged += changed
+		args[cursorArg-1] = *lastID
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"tags":    tags,
+		"from":    filter.From,
+		"to":      filter.To,
+		"matched": matched,
+		"tagged":  tagged,
+	}).Info("Tagged calls")
+	return matched, tagged, nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TenantSetting is the session setting (GUC) holding the tenant domain API reads are restricted to
+const TenantSetting = "app.tenant"
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose queries only see the rows of tenant (a call domain) when row-level
+// security is enabled
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set on ctx by WithTenant
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// ScopeConnections makes the pool set TenantSetting on every connection it hands out, from the tenant of
+// the acquiring context, or to empty for unrestricted access. A connection the setting cannot be applied
+// to is discarded.
+func ScopeConnections(cfg *pgxpool.Config) {
+	cfg.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+		tenant, _ := TenantFrom(ctx)
+		_, err := conn.Exec(ctx, `SELECT set_config($1, $2, false)`, TenantSetting, tenant)
+		return err == nil
+	}
+}
+
+// SetRowLevelSecurity configures whether InitSchema enables the tenant row-level security policies. It
+// must be called before InitSchema.
+func (s *Store) SetRowLevelSecurity(enabled bool) {
+	s.rowLevelSecurity = enabled
+}
+
+// ErrRowLevelSecurityBypassed is returned by CheckRowLevelSecurity when the database role ignores policies
+var ErrRowLevelSecurityBypassed = errors.New("database role is a superuser or has BYPASSRLS, so row-level security does not apply to it")
+
+// CheckRowLevelSecurity verifies that the policies apply to the role the store connects as. Superusers and
+// BYPASSRLS roles skip them, so tenant-scoped reads would silently see every tenant.
+func (s *Store) CheckRowLevelSecurity(ctx context.Context) error {
+	var super, bypass bool
+	err := s.db.QueryRow(ctx, `SELECT rolsuper, rolbypassrls FROM pg_roles WHERE rolname = current_user`).Scan(&super, &bypass)
+	if err != nil {
+		return err
+	}
+	if super || bypass {
+		return ErrRowLevelSecurityBypassed
+	}
+	return nil
+}
+
+// tenantTables returns the tables keyed by call UUID, each with the columns that may hold one
+func tenantTables() (tables []string, columns map[string][]string) {
+	columns = make(map[string][]string)
+	for _, ref := range append(slices.Clone(callReferences), callReference{"call_events", "uuid", false}) {
+		if ref.table == "calls" {
+			continue // Links between calls; calls has its own policy
+		}
+		if _, ok := columns[ref.table]; !ok {
+			tables = append(tables, ref.table)
+		}
+		columns[ref.table] = append(columns[ref.table], ref.column)
+	}
+	return tables, columns
+}
+
+// rlsSchemaStatements returns the DDL enabling row-level security on calls and the tables keyed by them.
+// Two permissive policies apply to each table:
+//   - tenant_read lets roles listed in tenant_roles read the calls of their domain, so database users
+//     handed to tenants cannot widen their view by changing TenantSetting
+//   - service_access gives the role running the migration full access while TenantSetting is empty, and
+//     read access to that tenant's calls while it is set (API requests with a tenant key)
+//
+// Security is forced so the owning service role is subject to it too; superusers and BYPASSRLS roles
+// still see everything.
+func (s *Store) rlsSchemaStatements() []string {
+	if !s.rowLevelSecurity {
+		return nil
+	}
+	const (
+		tenant   = `NULLIF(current_setting('` + TenantSetting + `', true), '')`
+		isTenant = `EXISTS (SELECT 1 FROM tenant_roles WHERE role_name = current_user)`
+	)
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS tenant_roles (
+			role_name TEXT PRIMARY KEY,
+			domain    TEXT NOT NULL
+		)`,
+	}
+	policies := func(table, tenantRead, serviceRead string) []string {
+		service := fmt.Sprintf(`CREATE POLICY service_access ON %s TO %%I USING (%s IS NULL OR %s) WITH CHECK (%s IS NULL)`,
+			table, tenant, serviceRead, tenant)
+		return []string{
+			fmt.Sprintf(`ALTER TABLE %s ENABLE ROW LEVEL SECURITY`, table),
+			fmt.Sprintf(`ALTER TABLE %s FORCE ROW LEVEL SECURITY`, table),
+			fmt.Sprintf(`DROP POLICY IF EXISTS tenant_read ON %s`, table),
+			fmt.Sprintf(`CREATE POLICY tenant_read ON %s FOR SELECT USING (%s)`, table, tenantRead),
+			fmt.Sprintf(`DROP POLICY IF EXISTS service_access ON %s`, table),
+			// Policies name roles literally, so the service role is filled in server-side
+			fmt.Sprintf(`DO $$ BEGIN EXECUTE format('%s', current_user); END $$`, strings.ReplaceAll(service, "'", "''")),
+		}
+	}
+	stmts = append(stmts, policies("calls",
+		`domain IN (SELECT domain FROM tenant_roles WHERE role_name = current_user)`,
+		`domain = `+tenant)...)
+
+	tables, columns := tenantTables()
+	for _, table := range tables {
+		// The calls subquery is itself filtered by the calls policies
+		var refs []string
+		for _, col := range columns[table] {
+			refs = append(refs, fmt.Sprintf(`%s IN (SELECT uuid FROM calls)`, col))
+		}
+		scoped := "(" + strings.Join(refs, " OR ") + ")"
+		stmts = append(stmts, policies(table, isTenant+" AND "+scoped, scoped)...)
+	}
+	return stmts
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key, which replays of already stored events run into
+const uniqueViolation = "23505"
+
+type trackingKey struct{}
+
+// writeTracker collects the failures of the writes made with a tracking context
+type writeTracker struct {
+	mu          sync.Mutex
+	unavailable bool
+	rejected    error           // First error PostgreSQL returned
+	counts      map[string]int  // Writes made so far by operation name, numbering each one
+	applied     map[string]bool // Writes that went through, on this or an earlier attempt at the event
+}
+
+// WithWriteTracking returns a context that remembers how synchronous writes made with it failed. Event
+// handlers run many writes without returning their errors; the caller checks WriteUnavailable and
+// WriteRejected afterwards to hold the event for replay or set it aside instead of losing it.
+//
+// applied lists the writes that went through on earlier attempts at the same event (see AppliedWrites).
+// They are skipped, so handling an event again only makes the writes that failed, rather than inserting
+// the rows of the others a second time.
+func WithWriteTracking(ctx context.Context, applied []string) context.Context {
+	t := &writeTracker{counts: make(map[string]int), applied: make(map[string]bool, len(applied))}
+	for _, key := range applied {
+		t.applied[key] = true
+	}
+	return context.WithValue(ctx, trackingKey{}, t)
+}
+
+// AppliedWrites returns the writes made with ctx, a context from WithWriteTracking, that went through,
+// including those of earlier attempts. Each is named by its operation and how many writes of that
+// operation the event made before it, e.g. "add_transition#2".
+func AppliedWrites(ctx context.Context) []string {
+	t, ok := ctx.Value(trackingKey{}).(*writeTracker)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.applied))
+	for key := range t.applied {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// startWrite numbers a write of operation name made with ctx and reports whether an earlier attempt at
+// the event already applied it. The key is empty without a tracking context.
+func startWrite(ctx context.Context, name string) (key string, applied bool) {
+	t, ok := ctx.Value(trackingKey{}).(*writeTracker)
+	if !ok {
+		return "", false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[name]++
+	key = name + "#" + strconv.Itoa(t.counts[name])
+	return key, t.applied[key]
+}
+
+// WriteUnavailable reports whether a write made with ctx, a context from WithWriteTracking, failed
+// because the database was unavailable
+func WriteUnavailable(ctx context.Context) bool {
+	t, ok := ctx.Value(trackingKey{}).(*writeTracker)
+	if !ok {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.unavailable
+}
+
+// WriteRejected returns the first error PostgreSQL returned for a write made with ctx, a context from
+// WithWriteTracking. Duplicate keys are not reported.
+func WriteRejected(ctx context.Context) error {
+	t, ok := ctx.Value(trackingKey{}).(*writeTracker)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rejected
+}
+
+// IsUnavailable reports whether err means the database could not be reached, as opposed to PostgreSQL
+// rejecting the statement
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	return errors.Is(err, ErrCircuitOpen) || !errors.As(err, &pgErr)
+}
+
+// IsTransient reports whether PostgreSQL rejected a statement for a reason that may not recur, such as a
+// deadlock, a serialization failure, a lock timeout or a cancelled statement, rather than for what it wrote
+func IsTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "40") || pgErr.Code == "55P03" || pgErr.Code == "57014"
+}
+
+// noteWriteResult records the outcome of the write numbered key by startWrite on a tracking context
+func noteWriteResult(ctx context.Context, key string, err error) {
+	t, ok := ctx.Value(trackingKey{}).(*writeTracker)
+	if !ok {
+		return
+	}
+	var pgErr *pgconn.PgError
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case err == nil:
+		t.applied[key] = true
+	case IsUnavailable(err):
+		t.unavailable = true
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		t.applied[key] = true // Stored before
+	case t.rejected == nil:
+		t.rejected = err
+	}
+}
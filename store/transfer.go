@@ -0,0 +1,342 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// RelatedRows are the rows of other tables belonging to an exported call, by table. Each row is the JSON
+// object of its columns, without the table's own id.
+type RelatedRows map[string][]json.RawMessage
+
+// transferTable is a table exported with its calls, by the column naming the call each row belongs to.
+// Rows linking two calls, such as legs and transfers, go with one of them.
+type transferTable struct {
+	table, column string
+	sealed        map[string]sealedColumn // Encrypted columns, exported in plaintext
+}
+
+// sealedColumn is how a column of a transferTable is encrypted
+type sealedColumn int
+
+const (
+	sealedDigit   sealedColumn = iota // A DTMF digit, with a random nonce
+	sealedNumber                      // A phone number, deterministically
+	sealedHeaders                     // The headers of an ESL event (see SealHeaders)
+)
+
+// transferTables are the tables export and import carry with each call, besides its transitions
+var transferTables = []transferTable{
+	{table: "call_legs", column: "a_uuid"},
+	{table: "call_transfers", column: "transferor_uuid"},
+	{table: "call_events", column: "uuid"},
+	{table: "raw_events", column: "uuid", sealed: map[string]sealedColumn{"headers": sealedHeaders}},
+	{table: "call_dtmf", column: "uuid", sealed: map[string]sealedColumn{"digit": sealedDigit}},
+	{table: "hold_intervals", column: "uuid"},
+	{table: "park_intervals", column: "uuid"},
+	{table: "call_applications", column: "uuid"},
+	{table: "recordings", column: "uuid"},
+	{table: "faxes", column: "uuid"},
+	{table: "call_quality", column: "uuid"},
+	{table: "voicemail_events", column: "call_uuid", sealed: map[string]sealedColumn{"caller_number": sealedNumber}},
+}
+
+// GetRelatedRowsForCalls returns the rows of transferTables belonging to each of uuids, keyed by call UUID
+func (s *Store) GetRelatedRowsForCalls(ctx context.Context, uuids []string) (map[string]RelatedRows, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	related := make(map[string]RelatedRows)
+	for _, t := range transferTables {
+		rows, err := s.db.Query(ctxTimeout, fmt.Sprintf(`
+			SELECT %[2]s, to_jsonb(t) - 'id'
+			FROM %[1]s t
+			WHERE %[2]s = ANY($1)
+			ORDER BY %[2]s`, t.table, t.column), uuids)
+		if err != nil {
+			s.log.WithError(err).WithField("table", t.table).Error("Error getting related rows for batch")
+			return nil, err
+		}
+		for rows.Next() {
+			var uuid string
+			var row json.RawMessage
+			if err := rows.Scan(&uuid, &row); err != nil {
+				rows.Close()
+				s.log.WithError(err).WithField("table", t.table).Error("Error scanning related row")
+				return nil, err
+			}
+			if len(t.sealed) > 0 && s.cipher != nil {
+				if row, err = s.convertSealed(ctx, t, row, false); err != nil {
+					rows.Close()
+					return nil, err
+				}
+			}
+			if related[uuid] == nil {
+				related[uuid] = make(RelatedRows)
+			}
+			related[uuid][t.table] = append(related[uuid][t.table], row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			s.log.WithError(err).WithField("table", t.table).Error("Error iterating related rows")
+			return nil, err
+		}
+	}
+	return related, nil
+}
+
+// convertSealed decrypts (seal false) or encrypts the sealed columns of t in a JSON row. NULL and missing
+// columns are left alone.
+func (s *Store) convertSealed(ctx context.Context, t transferTable, row json.RawMessage, seal bool) (json.RawMessage, error) {
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal(row, &columns); err != nil {
+		return nil, err
+	}
+	for column, kind := range t.sealed {
+		var converted any
+		if kind == sealedHeaders {
+			var headers map[string]string
+			if json.Unmarshal(columns[column], &headers) != nil || headers == nil {
+				continue
+			}
+			if seal {
+				converted = s.SealHeaders(headers)
+			} else {
+				converted = s.openHeaders(ctx, headers)
+			}
+		} else {
+			var value string
+			if json.Unmarshal(columns[column], &value) != nil {
+				continue
+			}
+			switch {
+			case !seal:
+				converted = s.openField(ctx, value)
+			case kind == sealedNumber:
+				converted = s.sealNumber(value)
+			default:
+				converted = s.cipher.seal(value, false)
+			}
+		}
+		encoded, err := json.Marshal(converted)
+		if err != nil {
+			return nil, err
+		}
+		columns[column] = encoded
+	}
+	return json.Marshal(columns)
+}
+
+// importRelated replaces the rows of transferTables belonging to uuid with related, within tx
+func (s *Store) importRelated(ctx context.Context, tx pgx.Tx, uuid string, related RelatedRows) error {
+	for table := range related {
+		if !slices.ContainsFunc(transferTables, func(t transferTable) bool { return t.table == table }) {
+			return fmt.Errorf("cannot import rows of table %q", table)
+		}
+	}
+	for _, t := range transferTables {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, t.table, t.column), uuid); err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{"uuid": uuid, "table": t.table}).Error("Error clearing related rows for import")
+			return err
+		}
+		rows := related[t.table]
+		if len(rows) == 0 {
+			continue
+		}
+
+		// The rows' keys are the table's columns; the owning column is forced to the imported call
+		columnSet := make(map[string]bool)
+		for i, row := range rows {
+			if len(t.sealed) > 0 && s.cipher != nil {
+				var err error
+				if row, err = s.convertSealed(ctx, t, row, true); err != nil {
+					return fmt.Errorf("%s row %d: %w", t.table, i+1, err)
+				}
+			}
+			var columns map[string]json.RawMessage
+			if err := json.Unmarshal(row, &columns); err != nil {
+				return fmt.Errorf("%s row %d: %w", t.table, i+1, err)
+			}
+			for c := range columns {
+				columnSet[c] = true
+			}
+			columns[t.column], _ = json.Marshal(uuid)
+			encoded, err := json.Marshal(columns)
+			if err != nil {
+				return err
+			}
+			rows[i] = encoded
+		}
+		columnSet[t.column] = true
+		columns := make([]string, 0, len(columnSet))
+		for c := range columnSet {
+			columns = append(columns, pgx.Identifier{c}.Sanitize())
+		}
+		slices.Sort(columns)
+		list := strings.Join(columns, ", ")
+
+		payload, err := json.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s (%[2]s)
+			SELECT %[2]s FROM jsonb_populate_recordset(NULL::%[1]s, $1::jsonb)`, t.table, list), payload); err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{"uuid": uuid, "table": t.table}).Error("Error importing related rows")
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTransitionsForCalls returns the transition history of each of uuids, keyed by call UUID
+func (s *Store) GetTransitionsForCalls(ctx context.Context, uuids []string) (map[string][]Transition, error) {
+	query := `
+		SELECT id, uuid, kind, from_state, to_state, event_time
+		FROM call_transitions
+		WHERE uuid = ANY($1)
+		ORDER BY uuid, event_time, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuids)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting call transitions for batch")
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := make(map[string][]Transition)
+	for rows.Next() {
+		var t Transition
+		if err := rows.Scan(&t.ID, &t.UUID, &t.Kind, &t.FromState, &t.ToState, &t.EventTime); err != nil {
+			s.log.WithError(err).Error("Error scanning call transition row")
+			return nil, err
+		}
+		transitions[t.UUID] = append(transitions[t.UUID], t)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call transition rows")
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// ImportCall inserts call, its transitions and its related rows in one transaction, preserving the call UUID.
+// When a call with the same UUID exists it is left untouched unless overwrite is set, in which case the call,
+// its transition history and its related rows are replaced. imported reports whether anything was written.
+func (s *Store) ImportCall(ctx context.Context, call *Call, transitions []Transition, related RelatedRows, overwrite bool) (imported bool, err error) {
+	onConflict := `DO NOTHING`
+	if overwrite {
+		onConflict = `DO UPDATE SET
+			direction = EXCLUDED.direction, caller = EXCLUDED.caller, caller_name = EXCLUDED.caller_name,
+			callee = EXCLUDED.callee, callee_name = EXCLUDED.callee_name, start_time = EXCLUDED.start_time,
+			ringing_time = EXCLUDED.ringing_time, early_media_time = EXCLUDED.early_media_time, answered_time = EXCLUDED.answered_time,
+			bridged_time = EXCLUDED.bridged_time, end_time = EXCLUDED.end_time, status = EXCLUDED.status,
+			context = EXCLUDED.context, sip_profile = EXCLUDED.sip_profile, domain = EXCLUDED.domain,
+			account_code = EXCLUDED.account_code, user_id = EXCLUDED.user_id, gateway = EXCLUDED.gateway,
+			node = EXCLUDED.node, sip_call_id = EXCLUDED.sip_call_id,
+			destination_country = EXCLUDED.destination_country, destination_group = EXCLUDED.destination_group,
+			derived = EXCLUDED.derived,
+			billsec = EXCLUDED.billsec, duration = EXCLUDED.duration, progresssec = EXCLUDED.progresssec, cost = EXCLUDED.cost,
+			hold_seconds = EXCLUDED.hold_seconds, hold_count = EXCLUDED.hold_count, created_at = EXCLUDED.created_at,
+			transferred_to = EXCLUDED.transferred_to, transferred_by = EXCLUDED.transferred_by, tags = EXCLUDED.tags,
+			late_corrections = EXCLUDED.late_corrections, last_corrected_at = EXCLUDED.last_corrected_at,
+			sip_headers = EXCLUDED.sip_headers, caller_id_campaign = EXCLUDED.caller_id_campaign,
+			business_hours = EXCLUDED.business_hours, outcome = EXCLUDED.outcome,
+			outcome_source = EXCLUDED.outcome_source, classified_at = EXCLUDED.classified_at,
+			hangup_cause_q850 = EXCLUDED.hangup_cause_q850, sip_term_status = EXCLUDED.sip_term_status,
+			sip_invite_failure_status = EXCLUDED.sip_invite_failure_status, variables = EXCLUDED.variables,
+			verto_session_id = EXCLUDED.verto_session_id, verto_client_address = EXCLUDED.verto_client_address,
+			verto_user_agent = EXCLUDED.verto_user_agent, retry_of = EXCLUDED.retry_of, retry_attempt = EXCLUDED.retry_attempt,
+			parked_seconds = EXCLUDED.parked_seconds, park_count = EXCLUDED.park_count,
+			retrieved_by = EXCLUDED.retrieved_by, retrieved_by_uuid = EXCLUDED.retrieved_by_uuid`
+	}
+	query := `
+		INSERT INTO calls (uuid, direction, caller, caller_name, callee, callee_name,
+			start_time, ringing_time, early_media_time, answered_time, bridged_time, end_time, status,
+			context, sip_profile, domain, account_code, user_id, gateway, node, sip_call_id,
+			destination_country, destination_group, derived,
+			billsec, duration, progresssec, cost, hold_seconds, hold_count, created_at,
+			transferred_to, transferred_by, tags, late_corrections, last_corrected_at, sip_headers, caller_id_campaign,
+			business_hours, outcome, outcome_source, classified_at,
+			hangup_cause_q850, sip_term_status, sip_invite_failure_status, variables,
+			verto_session_id, verto_client_address, verto_user_agent, retry_of, retry_attempt,
+			parked_seconds, park_count, retrieved_by, retrieved_by_uuid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22,
+			$23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, COALESCE($34, '{}'::text[]), $35, $36, $37, $38, $39,
+			$40, $41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52, $53, $54, $55)
+		ON CONFLICT (uuid) ` + onConflict + `
+		RETURNING id`
+
+	retrievedBy := call.RetrievedBy
+	if retrievedBy != nil {
+		v := s.sealNumber(*retrievedBy)
+		retrievedBy = &v
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctxTimeout)
+	if err != nil {
+		s.log.WithError(err).Error("Error starting import transaction")
+		return false, err
+	}
+	defer tx.Rollback(ctxTimeout) // No-op after Commit
+
+	var id int
+	err = tx.QueryRow(ctxTimeout, query,
+		call.UUID, call.Direction, s.sealNumber(call.Caller), call.CallerName, s.sealNumber(call.Callee), call.CalleeName,
+		call.StartTime, call.RingingTime, call.EarlyMedia, call.AnsweredTime, call.BridgedTime, call.EndTime, call.Status,
+		call.Context, call.SIPProfile, call.Domain, call.AccountCode, call.UserID, call.Gateway, call.Node, call.SIPCallID,
+		call.DestCountry, call.DestGroup, call.Derived,
+		call.Billsec, call.Duration, call.Progresssec, call.Cost, call.HoldSeconds, call.HoldCount, call.CreatedAt,
+		call.TransferredTo, call.TransferredBy, call.Tags, call.LateCorrections, call.LastCorrectedAt,
+		call.SIPHeaders, call.CallerIDCampaign, call.BusinessHours, call.Outcome, call.OutcomeSource, call.ClassifiedAt,
+		call.HangupCauseQ850, call.SIPTermStatus, call.SIPInviteFailureStatus, call.Variables,
+		call.VertoSessionID, call.VertoClientAddress, call.VertoUserAgent, call.RetryOf, call.RetryAttempt,
+		call.ParkedSeconds, call.ParkCount, retrievedBy, call.RetrievedByUUID,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil // Existing call kept
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", call.UUID).Error("Error importing call")
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctxTimeout, `DELETE FROM call_transitions WHERE uuid = $1`, call.UUID); err != nil {
+		s.log.WithError(err).WithField("uuid", call.UUID).Error("Error clearing call transitions for import")
+		return false, err
+	}
+	for _, t := range transitions {
+		if _, err := tx.Exec(ctxTimeout, `
+			INSERT INTO call_transitions (uuid, kind, from_state, to_state, event_time)
+			VALUES ($1, $2, $3, $4, $5)`,
+			call.UUID, t.Kind, t.FromState, t.ToState, t.EventTime); err != nil {
+			s.log.WithError(err).WithField("uuid", call.UUID).Error("Error importing call transition")
+			return false, err
+		}
+	}
+
+	if err := s.importRelated(ctxTimeout, tx, call.UUID, related); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctxTimeout); err != nil {
+		s.log.WithError(err).WithField("uuid", call.UUID).Error("Error committing imported call")
+		return false, err
+	}
+	return true, nil
+}
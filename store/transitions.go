@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Transition kinds recorded in call_transitions
+const (
+	TransitionState     = "state"     // CHANNEL_STATE (CS_*)
+	TransitionCallState = "callstate" // CHANNEL_CALLSTATE (EARLY, ACTIVE, ...)
+)
+
+// Transition is a single channel state change
+type Transition struct {
+	ID        int64     `json:"id"`
+	UUID      string    `json:"uuid"`
+	Kind      string    `json:"kind"`
+	FromState *string   `json:"from_state,omitempty"`
+	ToState   string    `json:"to_state"`
+	EventTime time.Time `json:"event_time"`
+}
+
+// StuckCall is a call whose latest call state has not changed for longer than a threshold
+type StuckCall struct {
+	UUID      string    `json:"uuid"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// AddTransition appends a state transition for a call
+func (s *Store) AddTransition(ctx context.Context, t *Transition) error {
+	return s.write(ctx, writeOp{
+		name: "add_transition",
+		uuid: t.UUID,
+		query: `
+			INSERT INTO call_transitions (uuid, kind, from_state, to_state, event_time)
+			VALUES ($1, $2, $3, $4, $5)`,
+		args: []any{t.UUID, t.Kind, t.FromState, t.ToState, t.EventTime},
+	})
+}
+
+// GetTransitions returns the transition history of a call in event order
+func (s *Store) GetTransitions(ctx context.Context, uuid string) ([]Transition, error) {
+	query := `
+		SELECT id, uuid, kind, from_state, to_state, event_time
+		FROM call_transitions
+		WHERE uuid = $1
+		ORDER BY event_time, id`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, uuid)
+	if err != nil {
+		s.log.WithError(err).WithField("uuid", uuid).Error("Error getting call transitions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []Transition
+	for rows.Next() {
+		var t Transition
+		if err := rows.Scan(&t.ID, &t.UUID, &t.Kind, &t.FromState, &t.ToState, &t.EventTime); err != nil {
+			s.log.WithError(err).Error("Error scanning call transition row")
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating call transition rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"uuid":  uuid,
+		"count": len(transitions),
+	}).Info("Retrieved call transitions")
+	return transitions, nil
+}
+
+// GetStuckCalls returns unfinished calls whose latest call state equals state and has not changed for at least minAge
+func (s *Store) GetStuckCalls(ctx context.Context, state string, minAge time.Duration, limit int) ([]StuckCall, error) {
+	query := `
+		SELECT t.uuid, t.to_state, t.event_time, c.start_time
+		FROM (
+			SELECT DISTINCT ON (uuid) uuid, to_state, event_time
+			FROM call_transitions
+			WHERE kind = $1
+			ORDER BY uuid, event_time DESC, id DESC
+		) t
+		JOIN calls c ON c.uuid = t.uuid
+		WHERE c.end_time IS NULL AND t.to_state = $2 AND t.event_time < $3
+		ORDER BY t.event_time
+		LIMIT $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, TransitionCallState, state, time.Now().Add(-minAge), limit)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting stuck calls")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stuck []StuckCall
+	for rows.Next() {
+		var sc StuckCall
+		if err := rows.Scan(&sc.UUID, &sc.State, &sc.Since, &sc.StartTime); err != nil {
+			s.log.WithError(err).Error("Error scanning stuck call row")
+			return nil, err
+		}
+		stuck = append(stuck, sc)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating stuck call rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"state": state,
+		"count": len(stuck),
+	}).Info("Retrieved stuck calls")
+	return stuck, nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// APIUsage is the API traffic of one key in one calendar month
+type APIUsage struct {
+	KeyID         string    `json:"key_id"` // Role and fingerprint of the key, or "anonymous"
+	Role          string    `json:"role"`
+	Month         time.Time `json:"month"` // First day of the month, UTC
+	Requests      int64     `json:"requests"`
+	ResponseBytes int64     `json:"response_bytes"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// In converts the usage's update time to loc; Month stays a UTC date
+func (u *APIUsage) In(loc *time.Location) {
+	u.UpdatedAt = u.UpdatedAt.In(loc)
+}
+
+// UsageMonth returns the first instant of t's calendar month in UTC, the month API usage is counted in
+func UsageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// AddAPIUsage adds the counts in usage to the keys' totals for month and returns the new totals, which
+// include traffic recorded by other instances
+func (s *Store) AddAPIUsage(ctx context.Context, month time.Time, usage []APIUsage) ([]APIUsage, error) {
+	if len(usage) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(usage))
+	roles := make([]string, len(usage))
+	requests := make([]int64, len(usage))
+	sizes := make([]int64, len(usage))
+	for i, u := range usage {
+		keys[i], roles[i], requests[i], sizes[i] = u.KeyID, u.Role, u.Requests, u.ResponseBytes
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, `
+		INSERT INTO api_key_usage (key_id, role, month, requests, response_bytes)
+		SELECT key_id, role, $1::date, requests, response_bytes
+		FROM unnest($2::text[], $3::text[], $4::bigint[], $5::bigint[]) AS u(key_id, role, requests, response_bytes)
+		ON CONFLICT (key_id, month) DO UPDATE SET
+			role           = EXCLUDED.role,
+			requests       = api_key_usage.requests + EXCLUDED.requests,
+			response_bytes = api_key_usage.response_bytes + EXCLUDED.response_bytes,
+			updated_at     = now()
+		RETURNING key_id, role, month, requests, response_bytes, updated_at`,
+		month, keys, roles, requests, sizes)
+	if err != nil {
+		s.log.WithError(err).Error("Error recording API usage")
+		return nil, err
+	}
+	totals, err := scanAPIUsage(rows)
+	if err != nil {
+		s.log.WithError(err).Error("Error recording API usage")
+		return nil, err
+	}
+	return totals, nil
+}
+
+// GetAPIUsage returns every key's usage for month, busiest first
+func (s *Store) GetAPIUsage(ctx context.Context, month time.Time) ([]APIUsage, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, `
+		SELECT key_id, role, month, requests, response_bytes, updated_at
+		FROM api_key_usage
+		WHERE month = $1
+		ORDER BY requests DESC, key_id`, month)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting API usage")
+		return nil, err
+	}
+	usage, err := scanAPIUsage(rows)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting API usage")
+		return nil, err
+	}
+	return usage, nil
+}
+
+// scanAPIUsage reads and closes rows of api_key_usage
+func scanAPIUsage(rows pgx.Rows) ([]APIUsage, error) {
+	defer rows.Close()
+	var usage []APIUsage
+	for rows.Next() {
+		var u APIUsage
+		if err := rows.Scan(&u.KeyID, &u.Role, &u.Month, &u.Requests, &u.ResponseBytes, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		u.Month = u.Month.UTC()
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// VertoSession is a mod_verto (WebRTC) client connection, opened by verto::client_connect after a
+// successful login and closed by verto::client_disconnect
+type VertoSession struct {
+	ID             int64      `json:"id"`
+	SessionID      *string    `json:"session_id,omitempty"` // Client sessid, when the event carries it
+	Login          string     `json:"login"`
+	ClientAddress  *string    `json:"client_address,omitempty"`
+	UserAgent      *string    `json:"user_agent,omitempty"`
+	Node           *string    `json:"node,omitempty"`
+	ConnectedAt    time.Time  `json:"connected_at"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+}
+
+// VertoSessionFilter narrows GetVertoSessions results. Empty fields are ignored.
+type VertoSessionFilter struct {
+	Login      string
+	ActiveOnly bool // Only sessions not yet disconnected
+}
+
+// vertoSessionColumns is the column list shared by verto session queries
+const vertoSessionColumns = `id, session_id, login, client_address, user_agent, node, connected_at, disconnected_at`
+
+// scanVertoSession scans a row selected with vertoSessionColumns into v
+func scanVertoSession(row pgx.Row, v *VertoSession) error {
+	return row.Scan(&v.ID, &v.SessionID, &v.Login, &v.ClientAddress, &v.UserAgent, &v.Node, &v.ConnectedAt, &v.DisconnectedAt)
+}
+
+// OpenVertoSession records a verto client connection. Sessions left open for the same login and client
+// address (a missed disconnect) are closed at the new connection time.
+func (s *Store) OpenVertoSession(ctx context.Context, v *VertoSession) error {
+	return s.write(ctx, writeOp{
+		name: "open_verto_session",
+		uuid: v.Login,
+		query: `
+			WITH closed AS (
+				UPDATE verto_sessions
+				SET disconnected_at = $6
+				WHERE login = $2 AND client_address IS NOT DISTINCT FROM $3 AND disconnected_at IS NULL
+			)
+			INSERT INTO verto_sessions (session_id, login, client_address, user_agent, node, connected_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+		args: []any{v.SessionID, v.Login, v.ClientAddress, v.UserAgent, v.Node, v.ConnectedAt},
+	})
+}
+
+// CloseVertoSession marks the open sessions of login from clientAddress as disconnected at at
+func (s *Store) CloseVertoSession(ctx context.Context, login string, clientAddress *string, at time.Time) error {
+	return s.write(ctx, writeOp{
+		name: "close_verto_session",
+		uuid: login,
+		query: `
+			UPDATE verto_sessions
+			SET disconnected_at = $1
+			WHERE login = $2 AND client_address IS NOT DISTINCT FROM $3 AND disconnected_at IS NULL`,
+		args:         []any{at, login, clientAddress},
+		warnIfNoRows: true,
+	})
+}
+
+// SetCallVerto records the verto metadata of a call. Without a user agent from the channel, the one of
+// the caller's open session (matched by login and client address) is used.
+func (s *Store) SetCallVerto(ctx context.Context, uuid string, sessionID, clientAddress, userAgent *string, login string) error {
+	return s.write(ctx, writeOp{
+		name: "set_call_verto",
+		uuid: uuid,
+		query: `
+			UPDATE calls
+			SET verto_session_id = $1, verto_client_address = $2,
+				verto_user_agent = COALESCE($3, (
+					SELECT user_agent FROM verto_sessions
+					WHERE login = $4 AND client_address IS NOT DISTINCT FROM $2 AND disconnected_at IS NULL
+					ORDER BY connected_at DESC
+					LIMIT 1
+				))
+			WHERE uuid = $5`,
+		args:         []any{sessionID, clientAddress, userAgent, login, uuid},
+		warnIfNoRows: true,
+	})
+}
+
+// GetVertoSessions returns verto sessions matching filter, most recently connected first
+func (s *Store) GetVertoSessions(ctx context.Context, filter VertoSessionFilter, limit, offset int) ([]VertoSession, error) {
+	query := `
+		SELECT ` + vertoSessionColumns + `
+		FROM verto_sessions
+		WHERE ($1 = '' OR login = $1) AND (NOT $2 OR disconnected_at IS NULL)
+		ORDER BY connected_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, filter.Login, filter.ActiveOnly, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting verto sessions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []VertoSession
+	for rows.Next() {
+		var v VertoSession
+		if err := scanVertoSession(rows, &v); err != nil {
+			s.log.WithError(err).Error("Error scanning verto session row")
+			return nil, err
+		}
+		sessions = append(sessions, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating verto session rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"login":      filter.Login,
+		"activeOnly": filter.ActiveOnly,
+		"count":      len(sessions),
+	}).Info("Retrieved verto sessions")
+	return sessions, nil
+}
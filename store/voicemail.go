@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of voicemail activity derived from vm::maintenance events
+const (
+	VoicemailLeft = "left" // A caller left a message (leave-message)
+	VoicemailRead = "read" // The mailbox's new-message count dropped (folder-summary)
+)
+
+// VoicemailEvent is one vm::maintenance event for a mailbox
+type VoicemailEvent struct {
+	ID           int64     `json:"id"`
+	Mailbox      string    `json:"mailbox"`
+	Domain       string    `json:"domain"`
+	Action       string    `json:"action"`         // VM-Action as sent by mod_voicemail
+	Kind         *string   `json:"kind,omitempty"` // left or read; nil for other actions
+	MessageUUID  *string   `json:"message_uuid,omitempty"`
+	CallUUID     *string   `json:"call_uuid,omitempty"`
+	CallerNumber *string   `json:"caller_number,omitempty"`
+	CallerName   *string   `json:"caller_name,omitempty"`
+	DurationSec  *int      `json:"duration_seconds,omitempty"`
+	NewMessages  *int      `json:"new_messages,omitempty"` // Folder counts, when the event reports them
+	SavedMsgs    *int      `json:"saved_messages,omitempty"`
+	ReadCount    int       `json:"read_count,omitempty"` // Messages read since the previous count, for read events
+	EventTime    time.Time `json:"event_time"`
+}
+
+// VoicemailFilter narrows voicemail event queries; empty fields match everything
+type VoicemailFilter struct {
+	Mailbox  string
+	Domain   string
+	Kind     string
+	CallUUID string
+}
+
+// MailboxStats summarises a mailbox's voicemail activity over a time range
+type MailboxStats struct {
+	Mailbox     string     `json:"mailbox"`
+	Domain      string     `json:"domain"`
+	Left        int64      `json:"left"`
+	Read        int64      `json:"read"`
+	LastLeftAt  *time.Time `json:"last_left_at,omitempty"`
+	NewMessages *int       `json:"new_messages,omitempty"` // Latest reported folder counts
+	SavedMsgs   *int       `json:"saved_messages,omitempty"`
+}
+
+// AddVoicemailEvent records a vm::maintenance event. An event reporting folder counts becomes a read event
+// when its new-message count is below the previous count plus the messages left since, which is how
+// listening to messages shows up.
+func (s *Store) AddVoicemailEvent(ctx context.Context, e *VoicemailEvent) error {
+	return s.write(ctx, writeOp{
+		name: "add_voicemail_event",
+		uuid: e.Mailbox + "@" + e.Domain,
+		query: `
+			WITH prev AS (
+				SELECT new_messages, event_time
+				FROM voicemail_events
+				WHERE mailbox = $1 AND domain = $2 AND new_messages IS NOT NULL AND event_time <= $12
+				ORDER BY event_time DESC, id DESC
+				LIMIT 1
+			), expected AS (
+				SELECT prev.new_messages + (
+					SELECT COUNT(*) FROM voicemail_events v
+					WHERE v.mailbox = $1 AND v.domain = $2 AND v.kind = 'left'
+						AND v.event_time > prev.event_time AND v.event_time <= $12
+				)::int AS new_messages
+				FROM prev
+			), read AS (
+				SELECT GREATEST(COALESCE((SELECT new_messages FROM expected) - $10::int, 0), 0) AS n
+			)
+			INSERT INTO voicemail_events (mailbox, domain, action, kind, message_uuid, call_uuid, caller_number,
+				caller_name, duration_sec, new_messages, saved_messages, read_count, event_time)
+			SELECT $1, $2, $3, CASE WHEN read.n > 0 THEN 'read' ELSE $4 END, $5, $6, $7, $8, $9, $10, $11, read.n, $12
+			FROM read`,
+		args: []any{e.Mailbox, e.Domain, e.Action, e.Kind, e.MessageUUID, e.CallUUID, s.sealOptionalNumber(e.CallerNumber),
+			e.CallerName, e.DurationSec, e.NewMessages, e.SavedMsgs, e.EventTime},
+	})
+}
+
+// voicemailColumns is the column list shared by voicemail event queries
+const voicemailColumns = `id, mailbox, domain, action, kind, message_uuid, call_uuid, caller_number, caller_name,
+	duration_sec, new_messages, saved_messages, read_count, event_time`
+
+// scanVoicemailEvent scans a row selected with voicemailColumns into e
+func scanVoicemailEvent(row pgx.Row, e *VoicemailEvent) error {
+	return row.Scan(&e.ID, &e.Mailbox, &e.Domain, &e.Action, &e.Kind, &e.MessageUUID, &e.CallUUID, &e.CallerNumber,
+		&e.CallerName, &e.DurationSec, &e.NewMessages, &e.SavedMsgs, &e.ReadCount, &e.EventTime)
+}
+
+// GetVoicemailEvents returns voicemail events in [from, to) matching filter, newest first
+func (s *Store) GetVoicemailEvents(ctx context.Context, filter VoicemailFilter, from, to time.Time, limit, offset int) ([]VoicemailEvent, error) {
+	query := `
+		SELECT ` + voicemailColumns + `
+		FROM voicemail_events
+		WHERE event_time >= $1 AND event_time < $2
+			AND ($3 = '' OR mailbox = $3) AND ($4 = '' OR domain = $4)
+			AND ($5 = '' OR kind = $5) AND ($6 = '' OR call_uuid = $6)
+		ORDER BY event_time DESC, id DESC
+		LIMIT $7 OFFSET $8`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, filter.Mailbox, filter.Domain, filter.Kind, filter.CallUUID, limit, offset)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting voicemail events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []VoicemailEvent
+	for rows.Next() {
+		var e VoicemailEvent
+		if err := scanVoicemailEvent(rows, &e); err != nil {
+			s.log.WithError(err).Error("Error scanning voicemail event row")
+			return nil, err
+		}
+		e.CallerNumber = s.openOptionalField(ctx, e.CallerNumber)
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating voicemail event rows")
+		return nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"mailbox": filter.Mailbox,
+		"domain":  filter.Domain,
+		"count":   len(events),
+	}).Info("Retrieved voicemail events")
+	return events, nil
+}
+
+// GetMailboxStats returns per-mailbox message left and read counts in [from, to) with the latest reported
+// folder counts, busiest mailboxes first. domain narrows the result when set.
+func (s *Store) GetMailboxStats(ctx context.Context, from, to time.Time, domain string) ([]MailboxStats, error) {
+	query := `
+		SELECT mailbox, domain,
+			COUNT(*) FILTER (WHERE kind = 'left'),
+			COALESCE(SUM(read_count), 0),
+			MAX(event_time) FILTER (WHERE kind = 'left'),
+			(ARRAY_AGG(new_messages ORDER BY event_time DESC, id DESC) FILTER (WHERE new_messages IS NOT NULL))[1],
+			(ARRAY_AGG(saved_messages ORDER BY event_time DESC, id DESC) FILTER (WHERE saved_messages IS NOT NULL))[1]
+		FROM voicemail_events
+		WHERE event_time >= $1 AND event_time < $2 AND ($3 = '' OR domain = $3)
+		GROUP BY mailbox, domain
+		ORDER BY 3 DESC, mailbox, domain`
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := s.db.Query(ctxTimeout, query, from, to, domain)
+	if err != nil {
+		s.log.WithError(err).Error("Error getting mailbox stats")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []MailboxStats
+	for rows.Next() {
+		var m MailboxStats
+		if err := rows.Scan(&m.Mailbox, &m.Domain, &m.Left, &m.Read, &m.LastLeftAt, &m.NewMessages, &m.SavedMsgs); err != nil {
+			s.log.WithError(err).Error("Error scanning mailbox stats row")
+			return nil, err
+		}
+		stats = append(stats, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		s.log.WithError(err).Error("Error iterating mailbox stats rows")
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,294 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gofreeswitchesl/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrWriterClosed is returned when a write is queued after the Writer has been closed
+var ErrWriterClosed = errors.New("store writer closed")
+
+var (
+	writerQueueDepth   = metrics.NewGaugeVec("store_writer_queue_depth", "Write operations waiting in the async store writer queue.")
+	writerBatchSize    = metrics.NewHistogramVec("store_writer_batch_size", "Number of write operations flushed per batch.", []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000})
+	writerFlushSeconds = metrics.NewHistogramVec("store_writer_flush_seconds", "Time taken to flush a batch to the database.", metrics.DefaultLagBuckets)
+	writerFailures     = metrics.NewCounterVec("store_writer_failed_ops_total", "Write operations that failed to apply.", "op")
+	writeSeconds       = metrics.NewHistogramVec("store_write_seconds", "Time taken by a synchronous write, by operation.", metrics.DefaultLagBuckets, "op")
+)
+
+// writeOp is a single ingest-path write. All event-driven writes are expressed as writeOps so they
+// can run either synchronously or through the async Writer.
+type writeOp struct {
+	name         string // Short operation name for logs and metrics
+	uuid         string
+	query        string
+	args         []any
+	warnIfNoRows bool   // Log a warning if the statement matched no rows (e.g. update for an unknown call)
+	traceID      string // Trace of the request that caused the write, recorded as a latency exemplar
+}
+
+// write executes op immediately, or queues it when an async Writer is attached
+func (s *Store) write(ctx context.Context, op writeOp) error {
+	op.traceID = metrics.TraceID(ctx)
+	if s.writer != nil {
+		return s.writer.enqueue(ctx, op)
+	}
+	key, applied := startWrite(ctx, op.name)
+	if applied {
+		return nil // Went through on an earlier attempt at the event
+	}
+	if s.breaker.Open() {
+		noteWriteResult(ctx, key, ErrCircuitOpen)
+		return ErrCircuitOpen
+	}
+	if err := s.acquire(ctx); err != nil {
+		noteWriteResult(ctx, key, err)
+		return err
+	}
+	defer s.release()
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	cmdTag, err := s.db.Exec(ctxTimeout, op.query, op.args...)
+	writeSeconds.With(op.name).ObserveWithExemplar(time.Since(start).Seconds(), op.traceID)
+	s.recordResult(err)
+	noteWriteResult(ctx, key, err)
+	s.logWriteResult(op, cmdTag.RowsAffected(), err)
+	return err
+}
+
+// logWriteResult logs the outcome of a write operation
+func (s *Store) logWriteResult(op writeOp, rowsAffected int64, err error) {
+	fields := logrus.Fields{
+		"op":   op.name,
+		"uuid": op.uuid,
+	}
+	if err != nil {
+		writerFailures.With(op.name).Inc()
+		s.log.WithError(err).WithFields(fields).Error("Error executing store write")
+		return
+	}
+	if op.warnIfNoRows && rowsAffected == 0 {
+		s.log.WithFields(fields).Warn("Store write matched no rows")
+	}
+}
+
+// Writer batches ingest writes and flushes them to the database on a size or time trigger,
+// decoupling ESL event handlers from database latency.
+type Writer struct {
+	store     *Store
+	ops       chan writeOp
+	batchSize int
+	interval  time.Duration
+	log       *logrus.Logger
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+	abandon   context.Context    // Done once Close gives up waiting, ending waits for a store slot
+	giveUp    context.CancelFunc // Cancels abandon
+}
+
+// NewWriter creates a Writer and attaches it to s, so subsequent ingest writes on s are queued.
+// Call Run to start flushing and Close to drain on shutdown.
+func NewWriter(s *Store, queueSize, batchSize int, interval time.Duration) *Writer {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	abandon, giveUp := context.WithCancel(context.Background())
+	w := &Writer{
+		store:     s,
+		ops:       make(chan writeOp, queueSize),
+		batchSize: batchSize,
+		interval:  interval,
+		log:       s.log,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		abandon:   abandon,
+		giveUp:    giveUp,
+	}
+	s.writer = w
+	return w
+}
+
+// enqueue queues op, blocking while the queue is full until ctx is done
+func (w *Writer) enqueue(ctx context.Context, op writeOp) error {
+	select {
+	case <-w.stop:
+		return ErrWriterClosed
+	default:
+	}
+
+	select {
+	case w.ops <- op:
+		writerQueueDepth.With().Set(float64(len(w.ops)))
+		return nil
+	case <-w.stop:
+		return ErrWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run flushes queued writes until Close is called. It is meant to run in its own goroutine.
+func (w *Writer) Run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := make([]writeOp, 0, w.batchSize)
+	for {
+		// While the circuit breaker is open a full batch is held rather than flushed, and no more ops are
+		// taken off the queue, so producers see backpressure instead of losing writes.
+		in := w.ops
+		if len(batch) >= w.batchSize {
+			in = nil
+		}
+
+		select {
+		case op := <-in:
+			batch = append(batch, op)
+			if len(batch) >= w.batchSize && !w.store.breaker.Open() {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 && !w.store.breaker.Open() {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-w.stop:
+			// Drain whatever is still queued before exiting
+			for {
+				select {
+				case op := <-w.ops:
+					batch = append(batch, op)
+					if len(batch) >= w.batchSize {
+						w.flush(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						w.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// batchTraceID returns the first trace ID among the batch's operations, to serve as the flush exemplar
+func batchTraceID(batch []writeOp) string {
+	for _, op := range batch {
+		if op.traceID != "" {
+			return op.traceID
+		}
+	}
+	return ""
+}
+
+// flush sends a batch as a single pipelined round trip. A pgx batch runs in an implicit transaction,
+// so if any statement fails the batch is retried one statement at a time to isolate the bad write.
+func (w *Writer) flush(batch []writeOp) {
+	start := time.Now()
+	defer func() {
+		writerFlushSeconds.With().ObserveWithExemplar(time.Since(start).Seconds(), batchTraceID(batch))
+		writerBatchSize.With().Observe(float64(len(batch)))
+		writerQueueDepth.With().Set(float64(len(w.ops)))
+	}()
+
+	if !w.acquireSlot(len(batch)) {
+		for _, op := range batch {
+			writerFailures.With(op.name).Inc()
+		}
+		w.log.WithField("ops", len(batch)).Error("Store writer closed while waiting for a store slot; dropping the write batch")
+		return
+	}
+	defer w.store.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pgxBatch := &pgx.Batch{}
+	for _, op := range batch {
+		pgxBatch.Queue(op.query, op.args...)
+	}
+
+	results := w.store.db.SendBatch(ctx, pgxBatch)
+	affected := make([]int64, len(batch))
+	var batchErr error
+	for i := range batch {
+		cmdTag, err := results.Exec()
+		if err != nil {
+			batchErr = err
+			break
+		}
+		affected[i] = cmdTag.RowsAffected()
+	}
+	if err := results.Close(); err != nil && batchErr == nil {
+		batchErr = err
+	}
+	w.store.recordResult(batchErr)
+
+	if batchErr == nil {
+		for i, op := range batch {
+			w.store.logWriteResult(op, affected[i], nil)
+		}
+		w.log.WithField("ops", len(batch)).Debug("Flushed store write batch")
+		return
+	}
+
+	w.log.WithError(batchErr).WithField("ops", len(batch)).Warn("Store write batch failed, retrying individually")
+	for _, op := range batch {
+		cmdTag, err := w.store.db.Exec(ctx, op.query, op.args...)
+		w.store.recordResult(err)
+		w.store.logWriteResult(op, cmdTag.RowsAffected(), err)
+	}
+}
+
+// acquireSlot waits for an in-flight store slot for a batch of n ops, however long it takes, so the batch
+// is not lost. Meanwhile no more ops are taken off the queue and producers see backpressure. It gives up,
+// returning false, only once Close has stopped waiting for the writer.
+func (w *Writer) acquireSlot(n int) bool {
+	for {
+		ctx, cancel := context.WithTimeout(w.abandon, 30*time.Second)
+		err := w.store.acquire(ctx)
+		cancel()
+		if err == nil {
+			return true
+		}
+		if w.abandon.Err() != nil {
+			return false
+		}
+		w.log.WithField("ops", n).Warn("Still waiting for a store slot to flush the write batch")
+	}
+}
+
+// Close stops accepting writes, flushes everything queued and waits for the flush to finish or ctx to
+// expire. When ctx expires first, a flush still waiting for a store slot drops its batch.
+func (w *Writer) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.giveUp()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gofreeswitchesl/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	transferBatchSize     = 1000
+	transferProgressEvery = 1000
+	maxImportLineBytes    = 16 << 20
+)
+
+// transferRecord is one line of a jsonl export: a call with its related rows
+type transferRecord struct {
+	Call        store.Call         `json:"call"`
+	Transitions []store.Transition `json:"transitions,omitempty"`
+	Related     store.RelatedRows  `json:"related,omitempty"` // Legs, events, DTMF, holds, recordings, ... by table
+}
+
+// checkTransferFormat rejects formats other than jsonl
+func checkTransferFormat(format string) error {
+	if format != "jsonl" {
+		return fmt.Errorf("unsupported format %q (supported: jsonl)", format)
+	}
+	return nil
+}
+
+// runExport writes calls with their transition history and related rows as JSON lines
+func runExport(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "jsonl", "output format (supported: jsonl)")
+	out := fs.String("out", "-", "output file, - for stdout")
+	from := fs.String("from", "", "only calls started at or after this RFC3339 time (default: all)")
+	to := fs.String("to", "", "only calls started before this RFC3339 time (default: now)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkTransferFormat(*format); err != nil {
+		return err
+	}
+	fromTime, toTime, err := parseCommandRange(*from, *to)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, appStore, closeStore, err := openCommandStore(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	var exported int64
+	lastID := 0
+	for {
+		calls, err := appStore.GetCallsAfter(ctx, lastID, fromTime, toTime, false, transferBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(calls) == 0 {
+			break
+		}
+		lastID = calls[len(calls)-1].ID
+
+		uuids := make([]string, len(calls))
+		for i := range calls {
+			uuids[i] = calls[i].UUID
+		}
+		transitions, err := appStore.GetTransitionsForCalls(ctx, uuids)
+		if err != nil {
+			return err
+		}
+		related, err := appStore.GetRelatedRowsForCalls(ctx, uuids)
+		if err != nil {
+			return err
+		}
+
+		for i := range calls {
+			record := transferRecord{
+				Call:        calls[i],
+				Transitions: transitions[calls[i].UUID],
+				Related:     related[calls[i].UUID],
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		exported += int64(len(calls))
+		logger.WithFields(logrus.Fields{
+			"exported": exported,
+			"lastID":   lastID,
+		}).Info("Export progress")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	logger.WithField("exported", exported).Info("Export complete")
+	return nil
+}
+
+// runImport reads a jsonl export and inserts its calls, preserving UUIDs
+func runImport(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "jsonl", "input format (supported: jsonl)")
+	in := fs.String("in", "-", "input file, - for stdin")
+	onConflict := fs.String("on-conflict", "skip", "what to do when a call UUID already exists: skip or overwrite")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkTransferFormat(*format); err != nil {
+		return err
+	}
+	if *onConflict != "skip" && *onConflict != "overwrite" {
+		return fmt.Errorf("on-conflict must be skip or overwrite")
+	}
+	overwrite := *onConflict == "overwrite"
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	_, appStore, closeStore, err := openCommandStore(ctx, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	var line, imported, skipped int64
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record transferRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Call.UUID == "" {
+			return fmt.Errorf("line %d: call has no uuid", line)
+		}
+		if record.Call.CreatedAt.IsZero() {
+			record.Call.CreatedAt = time.Now().UTC()
+		}
+
+		ok, err := appStore.ImportCall(ctx, &record.Call, record.Transitions, record.Related, overwrite)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			imported++
+		} else {
+			skipped++
+		}
+		if line%transferProgressEvery == 0 {
+			logger.WithFields(logrus.Fields{
+				"lines":    line,
+				"imported": imported,
+				"skipped":  skipped,
+			}).Info("Import progress")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"lines":    line,
+		"imported": imported,
+		"skipped":  skipped,
+	}).Info("Import complete")
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package vault reads secrets from a HashiCorp Vault key/value engine over its HTTP API
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrFieldNotFound is returned when the secret exists but has no such field
+var ErrFieldNotFound = errors.New("vault secret field not found")
+
+// ReadField returns one field of the secret at path, e.g. "secret/data/fslogger" for a KV version 2
+// engine mounted at secret/ or "kv/fslogger" for version 1. Only string fields are supported.
+func ReadField(ctx context.Context, addr, token, path, field string) (string, error) {
+	url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctxTimeout, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	// KV version 2 nests the secret under data.data; version 1 returns it as data
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	data := body.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	return value, nil
+}